@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"x.localhost/rvabot/internal/errors"
+	"x.localhost/rvabot/internal/timefmt"
 )
 
 // Config содержит конфигурацию приложения
@@ -15,6 +16,8 @@ type Config struct {
 	Bot      BotConfig
 	Logging  LoggingConfig
 	Server   ServerConfig
+	Booking  BookingConfig
+	Academy  AcademyConfig
 }
 
 // TelegramConfig содержит настройки Telegram API
@@ -46,6 +49,20 @@ type ServerConfig struct {
 	WriteTimeout time.Duration
 }
 
+// BookingConfig содержит настройки записи на тренировки
+type BookingConfig struct {
+	WaitlistOfferTTL   time.Duration // Сколько действует предложенное из листа ожидания место
+	CancellationCutoff time.Duration // За сколько до начала отмена записи считается поздней
+	NoShowLimit        int           // Сколько неявок за NoShowWindow ограничивают запись; 0 - правило отключено
+	NoShowWindow       time.Duration // Период, за который считаются неявки
+	SurveyDelay        time.Duration // Через сколько после окончания тренировки участникам приходит опрос
+}
+
+// AcademyConfig содержит общие настройки академии
+type AcademyConfig struct {
+	Timezone string // IANA-имя часового пояса по умолчанию для трасс и пользователей
+}
+
 // Load загружает конфигурацию из переменных окружения
 func Load() (*Config, error) {
 	config := &Config{}
@@ -95,9 +112,57 @@ func Load() (*Config, error) {
 	}
 	config.Server.WriteTimeout = time.Duration(writeTimeout) * time.Second
 
+	// Booking конфигурация
+	offerMinutesStr := getEnv("WAITLIST_OFFER_MINUTES", "120")
+	offerMinutes, err := strconv.Atoi(offerMinutesStr)
+	if err != nil {
+		return nil, errors.NewValidationError("Неверный WAITLIST_OFFER_MINUTES", "Время действия предложения должно быть числом минут")
+	}
+	config.Booking.WaitlistOfferTTL = time.Duration(offerMinutes) * time.Minute
+
+	cutoffHoursStr := getEnv("CANCELLATION_CUTOFF_HOURS", "24")
+	cutoffHours, err := strconv.Atoi(cutoffHoursStr)
+	if err != nil {
+		return nil, errors.NewValidationError("Неверный CANCELLATION_CUTOFF_HOURS", "Срок отмены должен быть числом часов")
+	}
+	config.Booking.CancellationCutoff = time.Duration(cutoffHours) * time.Hour
+
+	noShowLimitStr := getEnv("NO_SHOW_LIMIT", "2")
+	noShowLimit, err := strconv.Atoi(noShowLimitStr)
+	if err != nil {
+		return nil, errors.NewValidationError("Неверный NO_SHOW_LIMIT", "Лимит неявок должен быть числом")
+	}
+	config.Booking.NoShowLimit = noShowLimit
+
+	noShowDaysStr := getEnv("NO_SHOW_WINDOW_DAYS", "60")
+	noShowDays, err := strconv.Atoi(noShowDaysStr)
+	if err != nil {
+		return nil, errors.NewValidationError("Неверный NO_SHOW_WINDOW_DAYS", "Период подсчета неявок должен быть числом дней")
+	}
+	config.Booking.NoShowWindow = time.Duration(noShowDays) * 24 * time.Hour
+
+	surveyDelayStr := getEnv("SURVEY_DELAY_HOURS", "3")
+	surveyDelay, err := strconv.Atoi(surveyDelayStr)
+	if err != nil {
+		return nil, errors.NewValidationError("Неверный SURVEY_DELAY_HOURS", "Задержка опроса должна быть числом часов")
+	}
+	config.Booking.SurveyDelay = time.Duration(surveyDelay) * time.Hour
+
+	// Academy конфигурация
+	config.Academy.Timezone = getEnv("ACADEMY_TIMEZONE", timefmt.DefaultAcademyTimezone)
+
 	return config, nil
 }
 
+// LoadDatabase загружает только настройки базы данных и часового пояса
+// академии. Их достаточно подкоманде migrate, поэтому токен бота не нужен.
+func LoadDatabase() *Config {
+	config := &Config{}
+	config.Database.FilePath = getEnv("DB_FILE_PATH", "/data/rva_bot.db")
+	config.Academy.Timezone = getEnv("ACADEMY_TIMEZONE", timefmt.DefaultAcademyTimezone)
+	return config
+}
+
 // getEnv получает переменную окружения с значением по умолчанию
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -183,6 +248,40 @@ func (c *Config) Validate() error {
 		return errors.NewValidationError("Слишком большой таймаут записи", "SERVER_WRITE_TIMEOUT не должен превышать 60 секунд")
 	}
 
+	// Booking конфигурация
+	if c.Booking.WaitlistOfferTTL < time.Minute {
+		return errors.NewValidationError("Неверное время предложения места", "WAITLIST_OFFER_MINUTES должен быть не меньше 1")
+	}
+
+	if c.Booking.WaitlistOfferTTL > 7*24*time.Hour {
+		return errors.NewValidationError("Слишком долгое время предложения места", "WAITLIST_OFFER_MINUTES не должен превышать 10080 (7 дней)")
+	}
+
+	if c.Booking.CancellationCutoff < 0 {
+		return errors.NewValidationError("Неверный срок отмены", "CANCELLATION_CUTOFF_HOURS не может быть отрицательным")
+	}
+
+	if c.Booking.CancellationCutoff > 14*24*time.Hour {
+		return errors.NewValidationError("Слишком большой срок отмены", "CANCELLATION_CUTOFF_HOURS не должен превышать 336 (14 дней)")
+	}
+
+	if c.Booking.NoShowLimit < 0 {
+		return errors.NewValidationError("Неверный лимит неявок", "NO_SHOW_LIMIT не может быть отрицательным")
+	}
+
+	if c.Booking.NoShowWindow < 24*time.Hour || c.Booking.NoShowWindow > 365*24*time.Hour {
+		return errors.NewValidationError("Неверный период подсчета неявок", "NO_SHOW_WINDOW_DAYS должен быть от 1 до 365")
+	}
+
+	if c.Booking.SurveyDelay < 0 || c.Booking.SurveyDelay > 72*time.Hour {
+		return errors.NewValidationError("Неверная задержка опроса", "SURVEY_DELAY_HOURS должен быть от 0 до 72")
+	}
+
+	// Academy конфигурация
+	if _, err := timefmt.LoadLocation(c.Academy.Timezone); err != nil {
+		return errors.NewValidationError("Неверный часовой пояс академии", "ACADEMY_TIMEZONE должен быть IANA-именем, например Europe/Moscow")
+	}
+
 	return nil
 }
 
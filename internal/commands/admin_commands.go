@@ -7,9 +7,11 @@ import (
 	"time"
 
 	"x.localhost/rvabot/internal/database"
+	"x.localhost/rvabot/internal/errors"
 	"x.localhost/rvabot/internal/logger"
 	"x.localhost/rvabot/internal/states"
 	"x.localhost/rvabot/internal/telegram"
+	"x.localhost/rvabot/internal/timefmt"
 	"x.localhost/rvabot/internal/validation"
 )
 
@@ -32,9 +34,7 @@ func Admin(botUrl string, chatId int, repo database.ContentRepositoryInterface)
 }
 
 func CreateTrainer(botUrl string, chatId int, messageId int) states.State {
-	telegram.EditMessage(botUrl, chatId, messageId, "👨‍🏫 <b>Добавление нового тренера</b>\n\n"+
-		"📝 <b>Шаг 1 из 3:</b> Введите ФИО тренера\n\n"+
-		"💡 <i>Пример: Иванов Иван Иванович</i>", telegram.CreateBackToTrainersMenuKeyboard())
+	promptTrainerName(botUrl, chatId, messageId)
 
 	tempData := &states.TempTrainerData{}
 	state := states.SetEnterTrainerName(0)
@@ -49,16 +49,14 @@ func SetTrainerName(botUrl string, chatId int, update telegram.Update, repo data
 	result := validator.ValidateTrainerName(name)
 	if !result.IsValid {
 		errorMsg := strings.Join(result.GetErrorMessages(), "\n")
-		telegram.SendMessage(botUrl, chatId, "❌ <b>Ошибка валидации</b>\n\n"+errorMsg+"\n\n🔄 Попробуйте еще раз:", telegram.CreateCancelKeyboard())
+		telegram.SendMessage(botUrl, chatId, "❌ <b>Ошибка валидации</b>\n\n"+errorMsg+"\n\n🔄 Попробуйте еще раз:", telegram.CreateStepKeyboard())
 		return state
 	}
 
 	tempData := state.GetTempTrainerData()
 	tempData.Name = name
 
-	telegram.SendMessage(botUrl, chatId, "👨‍🏫 <b>Добавление нового тренера</b>\n\n"+
-		"📱 <b>Шаг 2 из 4:</b> Введите Telegram ID тренера\n"+
-		"💡 <i>Пример: @username или 123456789</i>", telegram.CreateCancelKeyboard())
+	promptTrainerTgId(botUrl, chatId, 0)
 
 	newState := states.SetEnterTrainerTgId(0)
 	return newState.SetTempTrainerData(tempData)
@@ -72,16 +70,14 @@ func SetTrainerTgId(botUrl string, chatId int, update telegram.Update, repo data
 	result := validator.ValidateTelegramID(tgid)
 	if !result.IsValid {
 		errorMsg := strings.Join(result.GetErrorMessages(), "\n")
-		telegram.SendMessage(botUrl, chatId, "❌ <b>Ошибка валидации</b>\n\n"+errorMsg+"\n\n🔄 Попробуйте еще раз:", telegram.CreateCancelKeyboard())
+		telegram.SendMessage(botUrl, chatId, "❌ <b>Ошибка валидации</b>\n\n"+errorMsg+"\n\n🔄 Попробуйте еще раз:", telegram.CreateStepKeyboard())
 		return state
 	}
 
 	tempData := state.GetTempTrainerData()
 	tempData.TgId = tgid
 
-	telegram.SendMessage(botUrl, chatId, "👨‍🏫 <b>Добавление нового тренера</b>\n\n"+
-		"💬 <b>Шаг 3 из 4:</b> Введите Chat ID тренера\n"+
-		"💡 <i>Пример: 123456789 (числовой ID чата)</i>", telegram.CreateCancelKeyboard())
+	promptTrainerChatId(botUrl, chatId, 0)
 
 	newState := states.SetEnterTrainerChatId(0)
 	return newState.SetTempTrainerData(tempData)
@@ -95,7 +91,7 @@ func SetTrainerChatId(botUrl string, chatId int, update telegram.Update, repo da
 	result := validator.ValidateChatID(chatIdStr)
 	if !result.IsValid {
 		errorMsg := strings.Join(result.GetErrorMessages(), "\n")
-		telegram.SendMessage(botUrl, chatId, "❌ <b>Ошибка валидации</b>\n\n"+errorMsg+"\n\n🔄 Попробуйте еще раз:", telegram.CreateCancelKeyboard())
+		telegram.SendMessage(botUrl, chatId, "❌ <b>Ошибка валидации</b>\n\n"+errorMsg+"\n\n🔄 Попробуйте еще раз:", telegram.CreateStepKeyboard())
 		return state
 	}
 
@@ -104,9 +100,7 @@ func SetTrainerChatId(botUrl string, chatId int, update telegram.Update, repo da
 	tempData := state.GetTempTrainerData()
 	tempData.ChatId = trainerChatId
 
-	telegram.SendMessage(botUrl, chatId, "👨‍🏫 <b>Добавление нового тренера</b>\n\n"+
-		"📝 <b>Шаг 4 из 4:</b> Введите информацию о тренере\n"+
-		"💡 <i>Пример: Опытный тренер по бегу, стаж 5 лет</i>", telegram.CreateCancelKeyboard())
+	promptTrainerInfo(botUrl, chatId, 0)
 
 	newState := states.SetEnterTrainerInfo(0)
 	return newState.SetTempTrainerData(tempData)
@@ -120,7 +114,7 @@ func SetTrainerInfo(botUrl string, chatId int, update telegram.Update, repo data
 	result := validator.ValidateTrainerInfo(info)
 	if !result.IsValid {
 		errorMsg := strings.Join(result.GetErrorMessages(), "\n")
-		telegram.SendMessage(botUrl, chatId, "❌ <b>Ошибка валидации</b>\n\n"+errorMsg+"\n\n🔄 Попробуйте еще раз:", telegram.CreateCancelKeyboard())
+		telegram.SendMessage(botUrl, chatId, "❌ <b>Ошибка валидации</b>\n\n"+errorMsg+"\n\n🔄 Попробуйте еще раз:", telegram.CreateStepKeyboard())
 		return state
 	}
 
@@ -141,6 +135,34 @@ func SetTrainerInfo(botUrl string, chatId int, update telegram.Update, repo data
 	return newState.SetTempTrainerData(tempData)
 }
 
+// promptTrainerName показывает шаг ввода ФИО тренера
+func promptTrainerName(botUrl string, chatId int, messageId int) {
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, "👨‍🏫 <b>Добавление нового тренера</b>\n\n"+
+		"📝 <b>Шаг 1 из 4:</b> Введите ФИО тренера\n\n"+
+		"💡 <i>Пример: Иванов Иван Иванович</i>", telegram.CreateStepKeyboard())
+}
+
+// promptTrainerTgId показывает шаг ввода Telegram ID тренера
+func promptTrainerTgId(botUrl string, chatId int, messageId int) {
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, "👨‍🏫 <b>Добавление нового тренера</b>\n\n"+
+		"📱 <b>Шаг 2 из 4:</b> Введите Telegram ID тренера\n"+
+		"💡 <i>Пример: @username или 123456789</i>", telegram.CreateStepKeyboard())
+}
+
+// promptTrainerChatId показывает шаг ввода Chat ID тренера
+func promptTrainerChatId(botUrl string, chatId int, messageId int) {
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, "👨‍🏫 <b>Добавление нового тренера</b>\n\n"+
+		"💬 <b>Шаг 3 из 4:</b> Введите Chat ID тренера\n"+
+		"💡 <i>Пример: 123456789 (числовой ID чата)</i>", telegram.CreateStepKeyboard())
+}
+
+// promptTrainerInfo показывает шаг ввода информации о тренере
+func promptTrainerInfo(botUrl string, chatId int, messageId int) {
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, "👨‍🏫 <b>Добавление нового тренера</b>\n\n"+
+		"📝 <b>Шаг 4 из 4:</b> Введите информацию о тренере\n"+
+		"💡 <i>Пример: Опытный тренер по бегу, стаж 5 лет</i>", telegram.CreateStepKeyboard())
+}
+
 func ConfirmTrainerCreation(botUrl string, chatId int, messageId int, repo database.ContentRepositoryInterface, tempData *states.TempTrainerData) states.State {
 	logger.AdminInfo(chatId, "Создание тренера: %s", tempData.Name)
 
@@ -209,7 +231,7 @@ func formatTrainersListForAdmin(trainers []database.Trainer) string {
 			builder.WriteString(fmt.Sprintf("📄 <b>Информация:</b> %s\n", trainer.Info))
 		}
 
-		builder.WriteString(fmt.Sprintf("📅 <b>Добавлен:</b> %s\n", trainer.CreatedAt.Format("02.01.2006")))
+		builder.WriteString(fmt.Sprintf("📅 <b>Добавлен:</b> %s\n", timefmt.Date(trainer.CreatedAt, nil)))
 		builder.WriteString("\n")
 	}
 
@@ -332,7 +354,8 @@ func ConfirmTrainerDeletion(botUrl string, chatId int, messageId int, trainerId
 		"👤 <b>Тренер:</b> %s\n"+
 		"📱 <b>Telegram ID:</b> %s\n"+
 		"📄 <b>Информация:</b> %s\n\n"+
-		"🚨 <b>ВНИМАНИЕ!</b> Это действие нельзя отменить!\n\n"+
+		"🗄 Тренер будет перенесен в архив: история тренировок сохранится.\n"+
+		"🚫 Тренера с будущими тренировками удалить нельзя.\n\n"+
 		"❓ <b>Вы уверены, что хотите удалить этого тренера?</b>",
 		trainer.Name, trainer.TgId, trainer.Info)
 
@@ -350,8 +373,9 @@ func ExecuteTrainerDeletion(botUrl string, chatId int, messageId int, trainerId
 
 	err = repo.DeleteTrainer(trainerId)
 	if err != nil {
+		logger.AdminError(chatId, "Удаление тренера %d: %v", trainerId, err)
 		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Ошибка удаления тренера</b>\n\n"+
-			"Попробуйте позже.", telegram.CreateBackToTrainersMenuKeyboard())
+			errors.HandleError(err), telegram.CreateBackToTrainersMenuKeyboard())
 		return states.SetAdminKeyboard()
 	}
 
@@ -360,12 +384,23 @@ func ExecuteTrainerDeletion(botUrl string, chatId int, messageId int, trainerId
 }
 
 func CreateTrack(botUrl string, chatId int, messageId int) states.State {
-	telegram.EditMessage(botUrl, chatId, messageId, "🏁 <b>Создание новой трассы</b>\n\n"+
-		"📝 Введите название трассы:\n\n"+
-		"💡 <i>Пример: Трасса №1 - Легкая</i>", telegram.CreateBackToTracksMenuKeyboard())
+	promptTrackName(botUrl, chatId, messageId)
 	return states.SetEnterTrackName(0)
 }
 
+// promptTrackName показывает шаг ввода названия трассы
+func promptTrackName(botUrl string, chatId int, messageId int) {
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, "🏁 <b>Создание новой трассы</b>\n\n"+
+		"📝 Введите название трассы:\n\n"+
+		"💡 <i>Пример: Трасса №1 - Легкая</i>", telegram.CreateStepKeyboard())
+}
+
+// promptTrackInfo показывает шаг ввода описания трассы
+func promptTrackInfo(botUrl string, chatId int, messageId int) {
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, "📋 Введите описание трассы:\n\n"+
+		"💡 <i>Пример: Легкая трасса для начинающих, длина 1 км</i>", telegram.CreateStepKeyboard())
+}
+
 func SetTrackName(botUrl string, chatId int, update telegram.Update, repo database.ContentRepositoryInterface, state states.State) states.State {
 	name := update.Message.Text
 	logger.AdminInfo(chatId, "Название трека: %s", name)
@@ -373,8 +408,7 @@ func SetTrackName(botUrl string, chatId int, update telegram.Update, repo databa
 	tempData := &states.TempTrackData{Name: name}
 	newState := states.SetEnterTrackInfo(0).SetTempTrackData(tempData)
 
-	telegram.SendMessage(botUrl, chatId, "📋 Введите описание трассы:\n\n"+
-		"💡 <i>Пример: Легкая трасса для начинающих, длина 1 км</i>", telegram.CreateBackToTracksMenuKeyboard())
+	promptTrackInfo(botUrl, chatId, 0)
 	return newState
 }
 
@@ -458,7 +492,7 @@ func ViewSchedule(botUrl string, chatId int, messageId int, repo database.Conten
 	}
 
 	message := "📅 <b>Расписание тренировок:</b>\n\n"
-	message += formatTrainingsListForAdmin(trainings, repo)
+	message += formatTrainingsListForAdmin(trainings, chatId, repo)
 
 	telegram.EditMessage(botUrl, chatId, messageId, message, telegram.CreateBackToScheduleMenuKeyboard())
 	return states.SetAdminKeyboard()
@@ -479,62 +513,112 @@ func EditSchedule(botUrl string, chatId int, messageId int, repo database.Conten
 	}
 
 	message := "✏️ <b>Выберите тренировку для редактирования:</b>\n\n"
-	message += formatTrainingsListForAdmin(trainings, repo)
+	message += formatTrainingsListForAdmin(trainings, chatId, repo)
 
 	telegram.EditMessage(botUrl, chatId, messageId, message, telegram.CreateTrainingEditKeyboard(0))
 	return states.SetAdminKeyboard()
 }
 
 func CreateTraining(botUrl string, chatId int, messageId int, repo database.ContentRepositoryInterface) states.State {
+	if !showTrainingTrackSelection(botUrl, chatId, messageId, repo) {
+		return states.SetAdminKeyboard()
+	}
+	return states.SetSetTrainingTrack(0)
+}
+
+// showTrainingTrackSelection показывает выбор трассы для новой тренировки
+func showTrainingTrackSelection(botUrl string, chatId int, messageId int, repo database.ContentRepositoryInterface) bool {
 	tracks, err := repo.GetTracks()
 	if err != nil {
-		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Ошибка загрузки трасс</b>\n\n"+
+		telegram.SendOrEditMessage(botUrl, chatId, messageId, "❌ <b>Ошибка загрузки трасс</b>\n\n"+
 			"Попробуйте позже.", telegram.CreateBackToScheduleMenuKeyboard())
-		return states.SetAdminKeyboard()
+		return false
 	}
 
 	if len(tracks) == 0 {
-		telegram.EditMessage(botUrl, chatId, messageId, "📭 <b>Трассы не найдены</b>\n\n"+
+		telegram.SendOrEditMessage(botUrl, chatId, messageId, "📭 <b>Трассы не найдены</b>\n\n"+
 			"Сначала создайте трассы.", telegram.CreateBackToScheduleMenuKeyboard())
-		return states.SetAdminKeyboard()
+		return false
 	}
 
 	message := "🏁 <b>Выберите трассу для тренировки:</b>\n\n"
 	message += formatTracksListForAdmin(tracks)
 
-	telegram.EditMessage(botUrl, chatId, messageId, message, telegram.CreateTrackSelectionForTrainingKeyboard(tracks))
-	return states.SetSetTrainingTrack(0)
-}
-
-func SetTrainingTrainer(botUrl string, chatId int, messageId int, trainerId uint, repo database.ContentRepositoryInterface, state states.State) states.State {
-	telegram.EditMessage(botUrl, chatId, messageId, "🕐 Введите время начала тренировки:\n\n"+
-		"💡 <i>Пример: 2024-01-15 18:00</i>", telegram.CreateBackToScheduleMenuKeyboard())
-
-	// Сохраняем данные в состоянии
-	newState := states.SetSetTrainingStartTime(0)
-	newState.Data["trackId"] = state.Data["trackId"]
-	newState.Data["trainerId"] = trainerId
-	return newState
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, message, telegram.CreateTrackSelectionForTrainingKeyboard(tracks))
+	return true
 }
 
-func SetTrainingTrack(botUrl string, chatId int, messageId int, trackId uint, repo database.ContentRepositoryInterface, state states.State) states.State {
+// showTrainingTrainerSelection показывает выбор тренера для новой тренировки
+func showTrainingTrainerSelection(botUrl string, chatId int, messageId int, repo database.ContentRepositoryInterface) bool {
 	trainers, err := repo.GetTrainers()
 	if err != nil {
-		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Ошибка загрузки тренеров</b>\n\n"+
+		telegram.SendOrEditMessage(botUrl, chatId, messageId, "❌ <b>Ошибка загрузки тренеров</b>\n\n"+
 			"Попробуйте позже.", telegram.CreateBackToScheduleMenuKeyboard())
-		return states.SetAdminKeyboard()
+		return false
 	}
 
 	if len(trainers) == 0 {
-		telegram.EditMessage(botUrl, chatId, messageId, "📭 <b>Тренеры не найдены</b>\n\n"+
+		telegram.SendOrEditMessage(botUrl, chatId, messageId, "📭 <b>Тренеры не найдены</b>\n\n"+
 			"Сначала создайте тренеров.", telegram.CreateBackToScheduleMenuKeyboard())
-		return states.SetAdminKeyboard()
+		return false
 	}
 
 	message := "👨‍🏫 <b>Выберите тренера для тренировки:</b>\n\n"
 	message += formatTrainersListForAdmin(trainers)
 
-	telegram.EditMessage(botUrl, chatId, messageId, message, telegram.CreateTrainerSelectionForTrainingKeyboard(trainers))
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, message, telegram.CreateTrainerSelectionForTrainingKeyboard(trainers))
+	return true
+}
+
+// promptTrainingStartTime показывает шаг ввода времени начала тренировки
+// в часовом поясе трассы loc
+func promptTrainingStartTime(botUrl string, chatId int, messageId int, loc *time.Location) {
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, "🕐 Введите время начала тренировки:\n\n"+
+		"🌍 Местное время трассы: "+timefmt.ZoneLabel(loc)+"\n"+
+		"💡 <i>Пример: 2024-01-15 18:00</i>", telegram.CreateStepKeyboard())
+}
+
+// promptTrainingEndTime показывает шаг ввода времени окончания тренировки
+func promptTrainingEndTime(botUrl string, chatId int, messageId int) {
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, "🕕 Введите время окончания тренировки:\n\n"+
+		"💡 <i>Пример: 2024-01-15 20:00</i>", telegram.CreateStepKeyboard())
+}
+
+// promptTrainingMaxParticipants показывает шаг ввода лимита участников
+func promptTrainingMaxParticipants(botUrl string, chatId int, messageId int) {
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, "👥 Введите максимальное количество участников:\n\n"+
+		"💡 <i>Пример: 10</i>", telegram.CreateStepKeyboard())
+}
+
+// showTrainingCarCategorySelection показывает шаг выбора категории машин из справочника
+func showTrainingCarCategorySelection(botUrl string, chatId int, messageId int, repo database.ContentRepositoryInterface) bool {
+	categories, err := repo.GetCarCategories()
+	if err != nil {
+		telegram.SendOrEditMessage(botUrl, chatId, messageId, "❌ <b>Ошибка загрузки категорий</b>", telegram.CreateBackToScheduleMenuKeyboard())
+		return false
+	}
+
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, "🚗 <b>Выберите категорию машин</b>\n\n"+
+		"💡 Новые категории добавляются в админ-панели в разделе «Категории машин».",
+		telegram.CreateTrainingCarCategoryKeyboard(categories))
+	return true
+}
+
+func SetTrainingTrainer(botUrl string, chatId int, messageId int, trainerId uint, repo database.ContentRepositoryInterface, state states.State) states.State {
+	trackId, _ := state.Data["trackId"].(uint)
+	promptTrainingStartTime(botUrl, chatId, messageId, repo.GetTrackLocation(trackId))
+
+	// Сохраняем данные в состоянии
+	newState := states.SetSetTrainingStartTime(0)
+	newState.Data["trackId"] = state.Data["trackId"]
+	newState.Data["trainerId"] = trainerId
+	return newState
+}
+
+func SetTrainingTrack(botUrl string, chatId int, messageId int, trackId uint, repo database.ContentRepositoryInterface, state states.State) states.State {
+	if !showTrainingTrainerSelection(botUrl, chatId, messageId, repo) {
+		return states.SetAdminKeyboard()
+	}
 
 	// Сохраняем trackId в состоянии
 	newState := states.SetSetTrainingTrainer(0)
@@ -555,7 +639,7 @@ func SetTrainingStartTime(botUrl string, chatId int, update telegram.Update, rep
 		}
 		errorMsg += "\n💡 <i>Пример: 2024-01-15 20:00</i>"
 
-		telegram.SendMessage(botUrl, chatId, errorMsg, telegram.CreateBackToScheduleMenuKeyboard())
+		telegram.SendMessage(botUrl, chatId, errorMsg, telegram.CreateStepKeyboard())
 		// Сохраняем данные из текущего состояния
 		newState := states.SetSetTrainingStartTime(0)
 		newState.Data["trackId"] = state.Data["trackId"]
@@ -563,8 +647,7 @@ func SetTrainingStartTime(botUrl string, chatId int, update telegram.Update, rep
 		return newState
 	}
 
-	telegram.SendMessage(botUrl, chatId, "🕕 Введите время окончания тренировки:\n\n"+
-		"💡 <i>Пример: 2024-01-15 20:00</i>", telegram.CreateBackToScheduleMenuKeyboard())
+	promptTrainingEndTime(botUrl, chatId, 0)
 
 	// Сохраняем данные в состоянии
 	newState := states.SetSetTrainingEndTime(0)
@@ -587,7 +670,7 @@ func SetTrainingEndTime(botUrl string, chatId int, update telegram.Update, repo
 		}
 		errorMsg += "\n💡 <i>Пример: 2024-01-15 20:00</i>"
 
-		telegram.SendMessage(botUrl, chatId, errorMsg, telegram.CreateBackToScheduleMenuKeyboard())
+		telegram.SendMessage(botUrl, chatId, errorMsg, telegram.CreateStepKeyboard())
 		// Сохраняем данные из текущего состояния
 		newState := states.SetSetTrainingEndTime(0)
 		newState.Data["trackId"] = state.Data["trackId"]
@@ -599,14 +682,16 @@ func SetTrainingEndTime(botUrl string, chatId int, update telegram.Update, repo
 	// Проверяем, что время окончания после времени начала
 	startTimeStr, ok := state.Data["startTime"].(string)
 	if ok {
-		startTime, err1 := time.Parse("2006-01-02 15:04", startTimeStr)
-		endTimeParsed, err2 := time.Parse("2006-01-02 15:04", endTime)
+		trackId, _ := state.Data["trackId"].(uint)
+		loc := repo.GetTrackLocation(trackId)
+		startTime, err1 := timefmt.ParseDateTime(startTimeStr, loc)
+		endTimeParsed, err2 := timefmt.ParseDateTime(endTime, loc)
 
 		if err1 == nil && err2 == nil {
 			if endTimeParsed.Before(startTime) || endTimeParsed.Equal(startTime) {
 				telegram.SendMessage(botUrl, chatId, "❌ <b>Неверное время окончания</b>\n\n"+
 					"Время окончания должно быть после времени начала.\n"+
-					"💡 <i>Пример: 2024-01-15 20:00</i>", telegram.CreateBackToScheduleMenuKeyboard())
+					"💡 <i>Пример: 2024-01-15 20:00</i>", telegram.CreateStepKeyboard())
 				// Сохраняем данные из текущего состояния
 				newState := states.SetSetTrainingEndTime(0)
 				newState.Data["trackId"] = state.Data["trackId"]
@@ -617,8 +702,7 @@ func SetTrainingEndTime(botUrl string, chatId int, update telegram.Update, repo
 		}
 	}
 
-	telegram.SendMessage(botUrl, chatId, "👥 Введите максимальное количество участников:\n\n"+
-		"💡 <i>Пример: 10</i>", telegram.CreateBackToScheduleMenuKeyboard())
+	promptTrainingMaxParticipants(botUrl, chatId, 0)
 
 	// Сохраняем данные в состоянии
 	newState := states.SetSetTrainingMaxParticipants(0)
@@ -641,7 +725,7 @@ func SetTrainingMaxParticipants(botUrl string, chatId int, update telegram.Updat
 		}
 		errorMsg += "\n💡 <i>Пример: 10</i>"
 
-		telegram.SendMessage(botUrl, chatId, errorMsg, telegram.CreateBackToScheduleMenuKeyboard())
+		telegram.SendMessage(botUrl, chatId, errorMsg, telegram.CreateStepKeyboard())
 		// Сохраняем данные из текущего состояния
 		newState := states.SetSetTrainingMaxParticipants(0)
 		newState.Data["trackId"] = state.Data["trackId"]
@@ -654,7 +738,7 @@ func SetTrainingMaxParticipants(botUrl string, chatId int, update telegram.Updat
 	maxParticipants, err := strconv.Atoi(maxParticipantsStr)
 	if err != nil {
 		telegram.SendMessage(botUrl, chatId, "❌ <b>Неверный формат числа</b>\n\n"+
-			"Введите число участников:", telegram.CreateBackToScheduleMenuKeyboard())
+			"Введите число участников:", telegram.CreateStepKeyboard())
 		// Сохраняем данные из текущего состояния
 		newState := states.SetSetTrainingMaxParticipants(0)
 		newState.Data["trackId"] = state.Data["trackId"]
@@ -664,9 +748,10 @@ func SetTrainingMaxParticipants(botUrl string, chatId int, update telegram.Updat
 		return newState
 	}
 
-	// Переходим к сбору категории машины
-	telegram.SendMessage(botUrl, chatId, "🚗 Введите категорию машин (например: KZ, OK, Rotax)\n\n"+
-		"💡 <i>Оставьте пустым для 'N/A'</i>", telegram.CreateBackToScheduleMenuKeyboard())
+	// Переходим к выбору категории машины
+	if !showTrainingCarCategorySelection(botUrl, chatId, 0, repo) {
+		return states.SetAdminKeyboard()
+	}
 
 	newState := states.SetSetTrainingCarCategory(0)
 	newState.Data["trackId"] = state.Data["trackId"]
@@ -677,10 +762,16 @@ func SetTrainingMaxParticipants(botUrl string, chatId int, update telegram.Updat
 	return newState
 }
 
-func SetTrainingCarCategory(botUrl string, chatId int, update telegram.Update, repo database.ContentRepositoryInterface, state states.State) states.State {
-	carCategory := strings.TrimSpace(update.Message.Text)
-	if carCategory == "" {
-		carCategory = "N/A"
+// SelectTrainingCarCategory сохраняет категорию из справочника (0 - без категории)
+// и переходит к выбору повторения
+func SelectTrainingCarCategory(botUrl string, chatId int, messageId int, categoryId uint, repo database.ContentRepositoryInterface, state states.State) states.State {
+	if state.Type != states.StateSetTrainingCarCategory {
+		return state
+	}
+
+	carCategoryID, ok := selectedCarCategory(botUrl, chatId, messageId, categoryId, repo)
+	if !ok {
+		return states.SetAdminKeyboard()
 	}
 
 	// Получаем данные из состояния
@@ -701,12 +792,34 @@ func SetTrainingCarCategory(botUrl string, chatId int, update telegram.Update, r
 		StartTime:       startTime,
 		EndTime:         endTime,
 		MaxParticipants: maxParticipants,
-		CarCategory:     carCategory,
+		CarCategoryID:   carCategoryID,
 	}
 
+	promptTrainingRecurrence(botUrl, chatId, messageId)
+	return states.SetSetTrainingRecurrence().SetTempTrainingData(tempData)
+}
+
+// selectedCarCategory проверяет, что выбранная категория есть в справочнике,
+// и возвращает ссылку на нее; nil для categoryId 0 - без категории
+func selectedCarCategory(botUrl string, chatId int, messageId int, categoryId uint, repo database.ContentRepositoryInterface) (*uint, bool) {
+	if categoryId == 0 {
+		return nil, true
+	}
+
+	category, _ := repo.GetCarCategoryByID(categoryId)
+	if category == nil {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Категория не найдена</b>\n\n"+
+			"Возможно, ее удалили из справочника.", telegram.CreateBackToScheduleMenuKeyboard())
+		return nil, false
+	}
+	return &category.ID, true
+}
+
+// showTrainingCreationConfirmation показывает итоговые данные тренировки или серии перед созданием
+func showTrainingCreationConfirmation(botUrl string, chatId int, messageId int, repo database.ContentRepositoryInterface, tempData *states.TempTrainingData) {
 	// Получаем информацию о тренере и трассе для отображения
-	trainer, _ := repo.GetTrainerByID(trainerId)
-	track, _ := repo.GetTrackByID(trackId)
+	trainer, _ := repo.GetTrainerByID(tempData.TrainerID)
+	track, _ := repo.GetTrackByID(tempData.TrackID)
 
 	trainerName := "Неизвестный тренер"
 	if trainer != nil {
@@ -718,23 +831,53 @@ func SetTrainingCarCategory(botUrl string, chatId int, update telegram.Update, r
 		trackName = track.Name
 	}
 
+	// Время вводится и показывается в местном времени трассы
+	loc := repo.GetTrackLocation(tempData.TrackID)
+
 	message := fmt.Sprintf("📅 <b>Подтверждение создания тренировки</b>\n\n"+
 		"👨‍🏫 <b>Тренер:</b> %s\n"+
 		"🏁 <b>Трасса:</b> %s\n"+
 		"🚗 <b>Категория:</b> %s\n"+
 		"🕐 <b>Начало:</b> %s\n"+
 		"🕕 <b>Окончание:</b> %s\n"+
-		"👥 <b>Макс. участников:</b> %d\n\n"+
-		"❓ <b>Создать тренировку?</b>",
-		trainerName, trackName, carCategory, startTime, endTime, maxParticipants)
+		"🌍 <b>Часовой пояс:</b> %s\n"+
+		"👥 <b>Макс. участников:</b> %d\n",
+		trainerName, trackName, carCategoryName(tempData.CarCategoryID, repo), tempData.StartTime, tempData.EndTime, timefmt.ZoneLabel(loc), tempData.MaxParticipants)
 
-	telegram.SendMessage(botUrl, chatId, message, telegram.CreateConfirmationKeyboard())
-	return states.SetConfirmTrainingCreation().SetTempTrainingData(tempData)
+	if tempData.Recurring {
+		message += formatRecurrenceSummary(tempData, loc)
+	}
+
+	// Пересечения по тренеру и закрытия трассы блокируют создание, занятая трасса - только предупреждение
+	conflicts, err := checkTrainingConflicts(repo, tempData)
+	if err != nil {
+		logger.AdminError(chatId, "Проверка пересечений расписания: %v", err)
+	} else if !conflicts.IsEmpty() {
+		message += formatScheduleConflicts(conflicts, loc, repo)
+		if conflicts.IsBlocking() {
+			message += "\n🚫 <b>Создание невозможно.</b> Вернитесь назад и измените время."
+			telegram.SendOrEditMessage(botUrl, chatId, messageId, message, telegram.CreateStepKeyboard())
+			return
+		}
+	}
+
+	if tempData.Recurring {
+		message += "\n❓ <b>Создать серию тренировок?</b>"
+	} else {
+		message += "\n❓ <b>Создать тренировку?</b>"
+	}
+
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, message, telegram.CreateConfirmationKeyboard())
 }
 
 func ConfirmTrainingCreation(botUrl string, chatId int, messageId int, repo database.ContentRepositoryInterface, tempData *states.TempTrainingData) states.State {
-	// Парсим время начала и окончания
-	startTime, err := time.Parse("2006-01-02 15:04", tempData.StartTime)
+	if tempData.Recurring {
+		return ConfirmTrainingSeriesCreation(botUrl, chatId, messageId, repo, tempData)
+	}
+
+	// Парсим время начала и окончания в поясе трассы
+	loc := repo.GetTrackLocation(tempData.TrackID)
+	startTime, err := timefmt.ParseDateTime(tempData.StartTime, loc)
 	if err != nil {
 		logger.AdminError(chatId, "Парсинг времени начала: %v", err)
 		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Ошибка создания тренировки</b>\n\n"+
@@ -743,7 +886,7 @@ func ConfirmTrainingCreation(botUrl string, chatId int, messageId int, repo data
 		return states.SetAdminKeyboard()
 	}
 
-	endTime, err := time.Parse("2006-01-02 15:04", tempData.EndTime)
+	endTime, err := timefmt.ParseDateTime(tempData.EndTime, loc)
 	if err != nil {
 		logger.AdminError(chatId, "Парсинг времени окончания: %v", err)
 		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Ошибка создания тренировки</b>\n\n"+
@@ -768,13 +911,17 @@ func ConfirmTrainingCreation(botUrl string, chatId int, messageId int, repo data
 		return states.SetAdminKeyboard()
 	}
 
+	if rejectScheduleConflicts(botUrl, chatId, messageId, repo, tempData) {
+		return states.SetAdminKeyboard()
+	}
+
 	training := &database.Training{
 		TrainerID:       tempData.TrainerID,
 		TrackID:         tempData.TrackID,
 		StartTime:       startTime,
 		EndTime:         endTime,
 		MaxParticipants: tempData.MaxParticipants,
-		CarCategory:     tempData.CarCategory,
+		CarCategoryID:   tempData.CarCategoryID,
 		IsActive:        true,
 	}
 
@@ -789,8 +936,8 @@ func ConfirmTrainingCreation(botUrl string, chatId int, messageId int, repo data
 
 	logger.AdminInfo(chatId, "Тренировка создана: %d", training.ID)
 	telegram.EditMessage(botUrl, chatId, messageId, "✅ <b>Тренировка создана!</b>\n\n"+
-		"🕐 Начало: "+training.StartTime.Format("2006-01-02 15:04")+"\n"+
-		"🕕 Окончание: "+training.EndTime.Format("2006-01-02 15:04"), telegram.CreateBackToScheduleMenuKeyboard())
+		"🕐 Начало: "+timefmt.DateTime(training.StartTime, loc)+"\n"+
+		"🕕 Окончание: "+timefmt.DateTime(training.EndTime, loc), telegram.CreateBackToScheduleMenuKeyboard())
 	return states.SetAdminKeyboard()
 }
 
@@ -875,7 +1022,8 @@ func ConfirmTrackDeletion(botUrl string, chatId int, messageId int, trackId uint
 	message := fmt.Sprintf("⚠️ <b>Подтверждение удаления трассы</b>\n\n"+
 		"🏁 <b>Трасса:</b> %s\n"+
 		"📄 <b>Описание:</b> %s\n\n"+
-		"🚨 <b>ВНИМАНИЕ!</b> Это действие нельзя отменить!\n\n"+
+		"🗄 Трасса будет перенесена в архив: история тренировок сохранится.\n"+
+		"🚫 Трассу с будущими тренировками удалить нельзя.\n\n"+
 		"❓ <b>Вы уверены, что хотите удалить эту трассу?</b>",
 		track.Name, track.Info)
 
@@ -893,8 +1041,9 @@ func ExecuteTrackDeletion(botUrl string, chatId int, messageId int, trackId uint
 
 	err = repo.DeleteTrack(trackId)
 	if err != nil {
+		logger.AdminError(chatId, "Удаление трассы %d: %v", trackId, err)
 		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Ошибка удаления трассы</b>\n\n"+
-			"Попробуйте позже.", telegram.CreateBackToTracksMenuKeyboard())
+			errors.HandleError(err), telegram.CreateBackToTracksMenuKeyboard())
 		return states.SetAdminKeyboard()
 	}
 
@@ -910,45 +1059,199 @@ func EditTraining(botUrl string, chatId int, messageId int, trainingId uint, rep
 		return states.SetAdminKeyboard()
 	}
 
+	loc := repo.GetViewerLocation(chatId, training.TrackID)
 	message := fmt.Sprintf("✏️ <b>Редактирование тренировки</b>\n\n"+
 		"📅 <b>Дата:</b> %s\n"+
 		"🚗 <b>Категория:</b> %s\n"+
-		"👥 <b>Макс. участников:</b> %d\n"+
+		"👥 <b>Макс. участников:</b> %s\n"+
+		"💰 <b>Цена:</b> %s\n"+
+		"🎓 <b>Уровень:</b> %s\n"+
 		"🔄 <b>Статус:</b> %s\n\n"+
 		"🎯 <b>Доступные действия:</b>",
-		training.StartTime.Format("2006-01-02 15:04"), training.CarCategory, training.MaxParticipants,
+		timefmt.DateTime(training.StartTime, loc), carCategoryName(training.CarCategoryID, repo), formatTrainingCapacity(training, repo),
+		formatTrainingPrice(training, repo), formatSkillRequirement(training),
 		map[bool]string{true: "Активна", false: "Неактивна"}[training.IsActive])
 
+	if training.SeriesID != nil {
+		if series, _ := repo.GetTrainingSeriesByID(*training.SeriesID); series != nil {
+			message = strings.Replace(message, "\n\n🎯", fmt.Sprintf("\n🔁 <b>Серия:</b> еженедельно, %s\n\n🎯", formatWeekdays(series.Weekdays)), 1)
+		}
+	}
+
 	telegram.EditMessage(botUrl, chatId, messageId, message, telegram.CreateTrainingEditKeyboard(trainingId))
 	return states.SetAdminKeyboard()
 }
 
 func EditTrainingCategory(botUrl string, chatId int, messageId int, trainingId uint, repo database.ContentRepositoryInterface) states.State {
-	telegram.EditMessage(botUrl, chatId, messageId, "🚗 <b>Редактирование категории машин</b>\n\n"+
-		"📝 Введите новую категорию (пример: KZ, OK, Rotax).\n"+
-		"💡 Оставьте пустым для 'N/A'", telegram.CreateBackToScheduleMenuKeyboard())
+	training, err := repo.GetTrainingById(trainingId)
+	if err != nil || training == nil {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Тренировка не найдена</b>", telegram.CreateBackToScheduleMenuKeyboard())
+		return states.SetAdminKeyboard()
+	}
+
+	if training.SeriesID != nil {
+		return showSeriesScopeSelection(botUrl, chatId, messageId, training, seriesActionCategory, repo)
+	}
+
+	if !promptEditTrainingCategory(botUrl, chatId, messageId, training, repo) {
+		return states.SetAdminKeyboard()
+	}
 	return states.SetEditTrainingCarCategory(trainingId)
 }
 
-func SetEditTrainingCategory(botUrl string, chatId int, update telegram.Update, repo database.ContentRepositoryInterface, trainingId uint) states.State {
-	newCategory := strings.TrimSpace(update.Message.Text)
-	if newCategory == "" {
-		newCategory = "N/A"
+// promptEditTrainingCategory показывает выбор новой категории машин из справочника
+func promptEditTrainingCategory(botUrl string, chatId int, messageId int, training *database.Training, repo database.ContentRepositoryInterface) bool {
+	categories, err := repo.GetCarCategories()
+	if err != nil {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Ошибка загрузки категорий</b>", telegram.CreateBackToScheduleMenuKeyboard())
+		return false
 	}
 
+	telegram.EditMessage(botUrl, chatId, messageId, fmt.Sprintf("🚗 <b>Редактирование категории машин</b>\n\n"+
+		"📊 Сейчас: %s\n"+
+		"👇 Выберите новую категорию:", telegram.EscapeHTML(carCategoryName(training.CarCategoryID, repo))),
+		telegram.CreateEditTrainingCarCategoryKeyboard(categories))
+	return true
+}
+
+// SelectEditTrainingCategory меняет категорию тренировки или занятий серии
+// на выбранную из справочника (0 - без категории)
+func SelectEditTrainingCategory(botUrl string, chatId int, messageId int, categoryId uint, repo database.ContentRepositoryInterface, state states.State) states.State {
+	if state.Type != states.StateEditTrainingCarCategory {
+		return state
+	}
+
+	newCategory, ok := selectedCarCategory(botUrl, chatId, messageId, categoryId, repo)
+	if !ok {
+		return states.SetAdminKeyboard()
+	}
+
+	training, err := repo.GetTrainingById(state.GetID())
+	if err != nil || training == nil {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Тренировка не найдена</b>", telegram.CreateBackToScheduleMenuKeyboard())
+		return states.SetAdminKeyboard()
+	}
+
+	trainings, err := repo.GetSeriesTrainings(training, state.GetSeriesScope())
+	if err != nil {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Ошибка загрузки занятий серии</b>", telegram.CreateBackToScheduleMenuKeyboard())
+		return states.SetAdminKeyboard()
+	}
+
+	for _, t := range trainings {
+		if err := repo.SetTrainingCarCategory(t.ID, newCategory); err != nil {
+			telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Ошибка сохранения</b>", telegram.CreateBackToScheduleMenuKeyboard())
+			return states.SetAdminKeyboard()
+		}
+	}
+
+	message := "✅ <b>Категория обновлена</b>"
+	if training.SeriesID != nil {
+		message += fmt.Sprintf("\n\n🔁 Изменено: %s (%d)", formatSeriesScope(state.GetSeriesScope()), len(trainings))
+	}
+	telegram.EditMessage(botUrl, chatId, messageId, message, telegram.CreateBackToScheduleMenuKeyboard())
+	return states.SetAdminKeyboard()
+}
+
+func EditTrainingMaxParticipants(botUrl string, chatId int, messageId int, trainingId uint, repo database.ContentRepositoryInterface) states.State {
 	training, err := repo.GetTrainingById(trainingId)
+	if err != nil || training == nil {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Тренировка не найдена</b>", telegram.CreateBackToScheduleMenuKeyboard())
+		return states.SetAdminKeyboard()
+	}
+
+	if training.SeriesID != nil {
+		return showSeriesScopeSelection(botUrl, chatId, messageId, training, seriesActionParticipants, repo)
+	}
+
+	promptEditTrainingMaxParticipants(botUrl, chatId, messageId, training)
+	return states.SetEditTrainingMaxParticipants(trainingId)
+}
+
+// promptEditTrainingMaxParticipants показывает ввод нового лимита участников
+func promptEditTrainingMaxParticipants(botUrl string, chatId int, messageId int, training *database.Training) {
+	telegram.EditMessage(botUrl, chatId, messageId, fmt.Sprintf("👥 <b>Редактирование количества участников</b>\n\n"+
+		"📊 Сейчас: %d\n"+
+		"📝 Введите новое максимальное количество участников.\n"+
+		"💡 При увеличении свободные места будут предложены листу ожидания.\n"+
+		"🏎 Если на трассе заведен парк картов, мест не больше исправных картов категории.", training.MaxParticipants), telegram.CreateBackToScheduleMenuKeyboard())
+}
+
+func SetEditTrainingMaxParticipants(botUrl string, chatId int, update telegram.Update, repo database.ContentRepositoryInterface, state states.State) states.State {
+	maxParticipantsStr := strings.TrimSpace(update.Message.Text)
+
+	validator := validation.NewValidator()
+	if result := validator.ValidateMaxParticipants(maxParticipantsStr); !result.IsValid {
+		errorMsg := "❌ <b>Неверное количество участников</b>\n\n"
+		for _, err := range result.Errors {
+			errorMsg += fmt.Sprintf("• %s\n", err.Error())
+		}
+		errorMsg += "\n💡 <i>Пример: 10</i>"
+
+		telegram.SendMessage(botUrl, chatId, errorMsg, telegram.CreateBackToScheduleMenuKeyboard())
+		return state
+	}
+
+	maxParticipants, _ := strconv.Atoi(maxParticipantsStr)
+
+	training, err := repo.GetTrainingById(state.GetID())
 	if err != nil || training == nil {
 		telegram.SendMessage(botUrl, chatId, "❌ <b>Тренировка не найдена</b>", telegram.CreateBackToScheduleMenuKeyboard())
 		return states.SetAdminKeyboard()
 	}
 
-	training.CarCategory = newCategory
-	if err := repo.UpdateTraining(trainingId, training); err != nil {
-		telegram.SendMessage(botUrl, chatId, "❌ <b>Ошибка сохранения</b>", telegram.CreateBackToScheduleMenuKeyboard())
+	trainings, err := repo.GetSeriesTrainings(training, state.GetSeriesScope())
+	if err != nil {
+		telegram.SendMessage(botUrl, chatId, "❌ <b>Ошибка загрузки занятий серии</b>", telegram.CreateBackToScheduleMenuKeyboard())
 		return states.SetAdminKeyboard()
 	}
 
-	telegram.SendMessage(botUrl, chatId, "✅ <b>Категория обновлена</b>", telegram.CreateBackToScheduleMenuKeyboard())
+	updated := 0
+	var skipped []string
+	for i := range trainings {
+		t := &trainings[i]
+
+		takenSeats, err := repo.CountActiveRegistrations(t.ID)
+		if err != nil {
+			telegram.SendMessage(botUrl, chatId, "❌ <b>Ошибка загрузки регистраций</b>", telegram.CreateBackToScheduleMenuKeyboard())
+			return states.SetAdminKeyboard()
+		}
+
+		// Лимит нельзя опустить ниже числа уже занятых мест
+		if maxParticipants < int(takenSeats) {
+			if len(trainings) == 1 {
+				telegram.SendMessage(botUrl, chatId, fmt.Sprintf("❌ <b>Слишком мало мест</b>\n\n"+
+					"👥 На тренировку уже записано: %d\n"+
+					"📝 Введите число не меньше этого значения.", takenSeats), telegram.CreateBackToScheduleMenuKeyboard())
+				return state
+			}
+			skipped = append(skipped, fmt.Sprintf("%s (записано %d)", timefmt.Short(t.StartTime, repo.GetViewerLocation(chatId, t.TrackID)), takenSeats))
+			continue
+		}
+
+		increased := maxParticipants > t.MaxParticipants
+		t.MaxParticipants = maxParticipants
+		if err := repo.UpdateTraining(t.ID, t); err != nil {
+			telegram.SendMessage(botUrl, chatId, "❌ <b>Ошибка сохранения</b>", telegram.CreateBackToScheduleMenuKeyboard())
+			return states.SetAdminKeyboard()
+		}
+		updated++
+
+		if increased {
+			promoteWaitlist(botUrl, t.ID, repo)
+		}
+	}
+
+	logger.AdminInfo(chatId, "Количество участников тренировки %d: %d (занятий %d)", training.ID, maxParticipants, updated)
+
+	message := "✅ <b>Количество участников обновлено</b>"
+	if training.SeriesID != nil {
+		message += fmt.Sprintf("\n\n🔁 Изменено: %s (%d)", formatSeriesScope(state.GetSeriesScope()), updated)
+	}
+	if len(skipped) > 0 {
+		message += "\n\n⚠️ <b>Пропущены занятия, где записано больше участников:</b>\n• " + strings.Join(skipped, "\n• ")
+	}
+	telegram.SendMessage(botUrl, chatId, message, telegram.CreateBackToScheduleMenuKeyboard())
 	return states.SetAdminKeyboard()
 }
 
@@ -968,9 +1271,13 @@ func ToggleTrainingStatus(botUrl string, chatId int, messageId int, trainingId u
 		return states.SetAdminKeyboard()
 	}
 
+	if training.IsActive {
+		promoteWaitlist(botUrl, trainingId, repo)
+	}
+
 	status := map[bool]string{true: "активна", false: "неактивна"}[training.IsActive]
 	telegram.EditMessage(botUrl, chatId, messageId, fmt.Sprintf("✅ <b>Тренировка %s</b>\n\n"+
-		"📅 Дата: %s", status, training.StartTime.Format("2006-01-02 15:04")), telegram.CreateBackToScheduleMenuKeyboard())
+		"📅 Дата: %s", status, timefmt.DateTime(training.StartTime, repo.GetViewerLocation(chatId, training.TrackID))), telegram.CreateBackToScheduleMenuKeyboard())
 	return states.SetAdminKeyboard()
 }
 
@@ -982,6 +1289,22 @@ func ConfirmTrainingDeletion(botUrl string, chatId int, messageId int, trainingI
 		return states.SetAdminKeyboard()
 	}
 
+	if training.SeriesID != nil {
+		return showSeriesScopeSelection(botUrl, chatId, messageId, training, seriesActionDelete, repo)
+	}
+
+	return showTrainingDeletionConfirmation(botUrl, chatId, messageId, trainingId, database.SeriesScopeOne, repo)
+}
+
+// showTrainingDeletionConfirmation запрашивает подтверждение удаления занятий в области scope
+func showTrainingDeletionConfirmation(botUrl string, chatId int, messageId int, trainingId uint, scope string, repo database.ContentRepositoryInterface) states.State {
+	training, err := repo.GetTrainingById(trainingId)
+	if err != nil {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Тренировка не найдена</b>\n\n"+
+			"🔍 Возможно, тренировка уже была удалена.", telegram.CreateBackToScheduleMenuKeyboard())
+		return states.SetAdminKeyboard()
+	}
+
 	// Получаем информацию о тренере и трассе
 	trainer, _ := repo.GetTrainerByID(training.TrainerID)
 	track, _ := repo.GetTrackByID(training.TrackID)
@@ -996,22 +1319,31 @@ func ConfirmTrainingDeletion(botUrl string, chatId int, messageId int, trainingI
 		trackName = track.Name
 	}
 
+	seriesInfo := ""
+	if training.SeriesID != nil {
+		trainings, err := repo.GetSeriesTrainings(training, scope)
+		if err == nil {
+			seriesInfo = fmt.Sprintf("🔁 <b>Будет удалено:</b> %s (%d)\n", formatSeriesScope(scope), len(trainings))
+		}
+	}
+
 	message := fmt.Sprintf("⚠️ <b>Подтверждение удаления тренировки</b>\n\n"+
 		"📅 <b>Дата и время:</b> %s\n"+
 		"👨‍🏫 <b>Тренер:</b> %s\n"+
 		"🏁 <b>Трасса:</b> %s\n"+
 		"👥 <b>Макс. участников:</b> %d\n"+
-		"🔄 <b>Статус:</b> %s\n\n"+
-		"🚨 <b>ВНИМАНИЕ!</b> Это действие нельзя отменить!\n\n"+
+		"🔄 <b>Статус:</b> %s\n"+
+		"%s\n"+
+		"🚨 <b>ВНИМАНИЕ!</b> Все активные записи будут отменены, участники получат уведомление.\n\n"+
 		"❓ <b>Вы уверены, что хотите удалить эту тренировку?</b>",
-		training.StartTime.Format("2006-01-02 15:04"), trainerName, trackName, training.MaxParticipants,
-		map[bool]string{true: "Активна", false: "Неактивна"}[training.IsActive])
+		timefmt.DateTime(training.StartTime, repo.GetViewerLocation(chatId, training.TrackID)), trainerName, trackName, training.MaxParticipants,
+		map[bool]string{true: "Активна", false: "Неактивна"}[training.IsActive], seriesInfo)
 
 	telegram.EditMessage(botUrl, chatId, messageId, message, telegram.CreateTrainingDeletionConfirmationKeyboard(trainingId))
-	return states.SetConfirmTrainingDelete(trainingId)
+	return states.SetConfirmTrainingDelete(trainingId).WithSeriesScope(scope)
 }
 
-func ExecuteTrainingDeletion(botUrl string, chatId int, messageId int, trainingId uint, repo database.ContentRepositoryInterface) states.State {
+func ExecuteTrainingDeletion(botUrl string, chatId int, messageId int, trainingId uint, scope string, repo database.ContentRepositoryInterface) states.State {
 	training, err := repo.GetTrainingById(trainingId)
 	if err != nil {
 		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Тренировка не найдена</b>\n\n"+
@@ -1019,18 +1351,80 @@ func ExecuteTrainingDeletion(botUrl string, chatId int, messageId int, trainingI
 		return states.SetAdminKeyboard()
 	}
 
-	err = repo.DeleteTraining(trainingId)
+	trainings, err := repo.GetSeriesTrainings(training, scope)
 	if err != nil {
-		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Ошибка удаления тренировки</b>\n\n"+
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Ошибка загрузки занятий серии</b>\n\n"+
 			"Попробуйте позже.", telegram.CreateBackToScheduleMenuKeyboard())
 		return states.SetAdminKeyboard()
 	}
 
-	telegram.EditMessage(botUrl, chatId, messageId, fmt.Sprintf("🗑️ <b>Тренировка удалена</b>\n\n"+
-		"📅 Дата: %s", training.StartTime.Format("2006-01-02 15:04")), telegram.CreateBackToScheduleMenuKeyboard())
+	cancelledTotal := 0
+	for i := range trainings {
+		t := &trainings[i]
+		cancelled, err := repo.DeleteTraining(t.ID)
+		if err != nil {
+			logger.AdminError(chatId, "Удаление тренировки %d: %v", t.ID, err)
+			telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Ошибка удаления тренировки</b>\n\n"+
+				"Попробуйте позже.", telegram.CreateBackToScheduleMenuKeyboard())
+			return states.SetAdminKeyboard()
+		}
+
+		for _, reg := range cancelled {
+			if credit, err := repo.RefundCredit(reg.ID, "тренировка отменена"); err != nil {
+				logger.AdminError(chatId, "Возврат кредита за запись %d: %v", reg.ID, err)
+			} else if credit != nil {
+				refundCreditPayment(chatId, reg.ID, repo)
+			}
+		}
+
+		notifyTrainingCancelled(botUrl, t, cancelled, repo)
+		cancelledTotal += len(cancelled)
+	}
+
+	if training.SeriesID != nil && scope == database.SeriesScopeAll {
+		if err := repo.DeleteTrainingSeries(*training.SeriesID); err != nil {
+			logger.AdminError(chatId, "Удаление серии %d: %v", *training.SeriesID, err)
+		}
+	}
+
+	message := fmt.Sprintf("🗑️ <b>Тренировка удалена</b>\n\n"+
+		"📅 Дата: %s\n"+
+		"🚫 Отменено записей: %d", timefmt.DateTime(training.StartTime, repo.GetViewerLocation(chatId, training.TrackID)), cancelledTotal)
+	if training.SeriesID != nil {
+		message += fmt.Sprintf("\n🔁 Удалено: %s (%d)", formatSeriesScope(scope), len(trainings))
+	}
+
+	telegram.EditMessage(botUrl, chatId, messageId, message, telegram.CreateBackToScheduleMenuKeyboard())
 	return states.SetAdminKeyboard()
 }
 
+// notifyTrainingCancelled сообщает участникам об отмене тренировки
+func notifyTrainingCancelled(botUrl string, training *database.Training, registrations []database.TrainingRegistration, repo database.ContentRepositoryInterface) {
+	trackName := "Неизвестная трасса"
+	trackTimezone := ""
+	if track, _ := repo.GetTrackByID(training.TrackID); track != nil {
+		trackName = track.Name
+		trackTimezone = track.Timezone
+	}
+
+	for _, reg := range registrations {
+		user, err := repo.GetUserByID(reg.UserID)
+		if err != nil || user == nil {
+			continue
+		}
+
+		message := fmt.Sprintf("🚫 <b>Тренировка отменена</b>\n\n"+
+			"%s"+
+			"🏃‍♂️ <b>Тренировка:</b> %s\n"+
+			"🚗 <b>Категория:</b> %s\n"+
+			"📅 <b>Дата и время:</b> %s\n\n"+
+			"😔 Ваша запись отменена. Приносим извинения!\n"+
+			"💡 Выберите другую тренировку в главном меню.",
+			formatRegistrationParticipant(&reg, repo), trackName, carCategoryName(training.CarCategoryID, repo), timefmt.DateTime(training.StartTime, timefmt.Resolve(user.Timezone, trackTimezone)))
+		telegram.SendMessage(botUrl, user.ChatId, message, telegram.CreateBaseKeyboard())
+	}
+}
+
 func formatTracksListForAdmin(tracks []database.Track) string {
 	if len(tracks) == 0 {
 		return "📭 Трассы не найдены"
@@ -1039,18 +1433,22 @@ func formatTracksListForAdmin(tracks []database.Track) string {
 	var builder strings.Builder
 	for i, track := range tracks {
 		builder.WriteString(fmt.Sprintf("%d. 🏁 <b>%s</b>\n", i+1, track.Name))
+		if track.Timezone != "" {
+			builder.WriteString(fmt.Sprintf("   🕒 %s\n", timefmt.ZoneLabel(timefmt.Resolve(track.Timezone))))
+		}
 		builder.WriteString(fmt.Sprintf("   📄 %s\n\n", track.Info))
 	}
 
 	return builder.String()
 }
 
-func formatTrainingsListForAdmin(trainings []database.Training, repo database.ContentRepositoryInterface) string {
+func formatTrainingsListForAdmin(trainings []database.Training, chatId int, repo database.ContentRepositoryInterface) string {
 	if len(trainings) == 0 {
 		return "📭 Тренировки не найдены"
 	}
 
 	var builder strings.Builder
+	categoryName := carCategoryNames(repo)
 	for i, training := range trainings {
 		// Получаем информацию о тренере
 		trainer, err := repo.GetTrainerByID(training.TrainerID)
@@ -1072,16 +1470,22 @@ func formatTrainingsListForAdmin(trainings []database.Training, repo database.Co
 			statusIcon = "🔴"
 		}
 
-		// Форматируем дату и время
-		dateStr := training.StartTime.Format("02.01")
-		startTimeStr := training.StartTime.Format("15:04")
-		endTimeStr := training.EndTime.Format("15:04")
+		// Форматируем дату и время в поясе администратора или трассы
+		loc := repo.GetViewerLocation(chatId, training.TrackID)
+		dateStr := timefmt.DayMonth(training.StartTime, loc)
+		startTimeStr := timefmt.Clock(training.StartTime, loc)
+		endTimeStr := timefmt.Clock(training.EndTime, loc)
 
 		// Создаем компактную запись
-		builder.WriteString(fmt.Sprintf("%d. %s <b>%s %s-%s</b>\n",
-			i+1, statusIcon, dateStr, startTimeStr, endTimeStr))
+		seriesMark := ""
+		if training.SeriesID != nil {
+			seriesMark = " 🔁"
+		}
+
+		builder.WriteString(fmt.Sprintf("%d. %s <b>%s %s-%s</b>%s\n",
+			i+1, statusIcon, dateStr, startTimeStr, endTimeStr, seriesMark))
 		builder.WriteString(fmt.Sprintf("   👨‍🏫 %s | 🏁 %s | 🚗 %s | 👥 %d\n\n",
-			trainerName, trackName, training.CarCategory, training.MaxParticipants))
+			trainerName, trackName, categoryName(training.CarCategoryID), training.MaxParticipants))
 	}
 
 	return builder.String()
@@ -1147,7 +1551,7 @@ func formatTrainingRequestsList(requests []database.TrainingRequest, repo databa
 		}
 
 		// Форматируем дату
-		dateStr := request.CreatedAt.Format("02.01 15:04")
+		dateStr := timefmt.Short(request.CreatedAt, nil)
 
 		builder.WriteString(fmt.Sprintf("%d. 👤 <b>%s</b> (%s)\n",
 			i+1, userName, dateStr))
@@ -1162,11 +1566,21 @@ func ViewTrainingRegistrations(botUrl string, chatId int, messageId int, trainin
 	// Получаем информацию о тренировке
 	training, err := repo.GetTrainingById(trainingId)
 	if err != nil || training == nil {
-		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Тренировка не найдена</b>\n\n"+
+		telegram.SendOrEditMessage(botUrl, chatId, messageId, "❌ <b>Тренировка не найдена</b>\n\n"+
 			"🔍 Возможно, тренировка была удалена.", telegram.CreateBackToScheduleMenuKeyboard())
 		return states.SetAdminKeyboard()
 	}
 
+	// Тренер открывает список из отметки посещаемости, чтобы отметить оплаты
+	isAdmin := database.IsAdmin(chatId, repo)
+	if !isAdmin && !isTrainingTrainer(chatId, training, repo) {
+		return SendAccessDeniedMessage(botUrl, chatId, messageId)
+	}
+	back := "scheduleMenu"
+	if !isAdmin {
+		back = fmt.Sprintf("attendanceChecklist_%d", trainingId)
+	}
+
 	// Получаем информацию о тренере и трассе
 	trainer, _ := repo.GetTrainerByID(training.TrainerID)
 	track, _ := repo.GetTrackByID(training.TrackID)
@@ -1184,34 +1598,81 @@ func ViewTrainingRegistrations(botUrl string, chatId int, messageId int, trainin
 	// Получаем регистрации
 	registrations, err := repo.GetTrainingRegistrationsByTrainingID(trainingId)
 	if err != nil {
-		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Ошибка загрузки регистраций</b>\n\n"+
-			"Попробуйте позже.", telegram.CreateBackToScheduleMenuKeyboard())
+		telegram.SendOrEditMessage(botUrl, chatId, messageId, "❌ <b>Ошибка загрузки регистраций</b>\n\n"+
+			"Попробуйте позже.", telegram.CreateBackToMenuKeyboard(back))
 		return states.SetAdminKeyboard()
 	}
 
+	price, err := repo.GetTrainingPrice(training)
+	if err != nil {
+		price = 0
+	}
+
+	takenSeats, err := repo.CountActiveRegistrations(trainingId)
+	if err != nil {
+		takenSeats = int64(len(registrations))
+	}
+
+	capacity, err := repo.GetTrainingCapacity(training)
+	if err != nil {
+		capacity = training.MaxParticipants
+	}
+
 	// Формируем сообщение
+	loc := repo.GetViewerLocation(chatId, training.TrackID)
 	message := fmt.Sprintf("👥 <b>Зарегистрированные на тренировку</b>\n\n"+
 		"🏃‍♂️ <b>Тренировка:</b> %s\n"+
 		"🚗 <b>Категория:</b> %s\n"+
 		"👨‍🏫 <b>Тренер:</b> %s\n"+
 		"📅 <b>Дата:</b> %s\n"+
 		"⏰ <b>Время:</b> %s - %s\n"+
-		"👥 <b>Мест:</b> %d/%d\n\n",
-		trackName, training.CarCategory, trainerName,
-		training.StartTime.Format("02.01.2006"),
-		training.StartTime.Format("15:04"), training.EndTime.Format("15:04"),
-		len(registrations), training.MaxParticipants)
+		"👥 <b>Мест:</b> %d/%d\n"+
+		"💰 <b>Цена:</b> %s\n\n",
+		trackName, carCategoryName(training.CarCategoryID, repo), trainerName,
+		timefmt.Date(training.StartTime, loc),
+		timefmt.Clock(training.StartTime, loc), timefmt.Clock(training.EndTime, loc),
+		takenSeats, capacity, formatPrice(price))
 
 	if len(registrations) == 0 {
 		message += "📭 <b>Нет зарегистрированных участников</b>"
 	} else {
 		message += formatTrainingRegistrationsList(registrations, repo)
+		if isAdmin {
+			message += "💡 Нажмите на участника, чтобы открыть его историю посещений.\n"
+		}
+		message += "💵 Отметьте оплату наличными или переводом кнопками под участником."
 	}
 
-	telegram.EditMessage(botUrl, chatId, messageId, message, telegram.CreateBackToScheduleMenuKeyboard())
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, message,
+		telegram.CreateTrainingRegistrationsKeyboard(trainingId, registrations, registrationNames(registrations, repo), isAdmin, back))
+	if !isAdmin {
+		return states.SetStartKeyboard()
+	}
 	return states.SetAdminKeyboard()
 }
 
+// formatRegistrationStatus возвращает иконку и название статуса записи
+func formatRegistrationStatus(status string) (string, string) {
+	switch status {
+	case database.RegistrationStatusConfirmed:
+		return "✅", "Подтвержден"
+	case database.RegistrationStatusRejected:
+		return "❌", "Отклонен"
+	case database.RegistrationStatusCancelled:
+		return "🚫", "Отменен"
+	case database.RegistrationStatusWaitlisted:
+		return "📝", "Лист ожидания"
+	case database.RegistrationStatusOffered:
+		return "🔔", "Предложено место"
+	case database.RegistrationStatusAttended:
+		return "🏁", "Был на тренировке"
+	case database.RegistrationStatusNoShow:
+		return "👻", "Не пришел"
+	default:
+		return "⏳", "Ожидает"
+	}
+}
+
 // formatTrainingRegistrationsList - форматирование списка регистраций
 func formatTrainingRegistrationsList(registrations []database.TrainingRegistration, repo database.ContentRepositoryInterface) string {
 	if len(registrations) == 0 {
@@ -1229,34 +1690,38 @@ func formatTrainingRegistrationsList(registrations []database.TrainingRegistrati
 			userTgId = user.TgId
 		}
 
-		// Определяем статус и иконку
-		statusIcon := "⏳"
-		statusText := "Ожидает"
-		switch reg.Status {
-		case "confirmed":
-			statusIcon = "✅"
-			statusText = "Подтвержден"
-		case "rejected":
-			statusIcon = "❌"
-			statusText = "Отклонен"
-		case "pending":
-			statusIcon = "⏳"
-			statusText = "Ожидает"
-		}
+		statusIcon, statusText := formatRegistrationStatus(reg.Status)
 
 		// Форматируем дату регистрации
-		dateStr := reg.CreatedAt.Format("02.01 15:04")
+		dateStr := timefmt.Short(reg.CreatedAt, nil)
 
 		// Создаем запись
-		builder.WriteString(fmt.Sprintf("%d. %s <b>%s</b>\n",
-			i+1, statusIcon, userName))
+		// За участника под аккаунтом показывается его имя, пользователь - как представитель
+		if reg.ParticipantID != 0 {
+			builder.WriteString(fmt.Sprintf("%d. %s <b>%s</b>\n",
+				i+1, statusIcon, telegram.EscapeHTML(registrationName(&reg, repo))))
+			builder.WriteString(fmt.Sprintf("   👤 Представитель: %s\n", userName))
+		} else {
+			builder.WriteString(fmt.Sprintf("%d. %s <b>%s</b>\n",
+				i+1, statusIcon, userName))
+		}
 
 		if userTgId != "" {
 			builder.WriteString(fmt.Sprintf("   📱 %s\n", userTgId))
 		}
 
-		builder.WriteString(fmt.Sprintf("   📊 %s | 📅 %s\n\n",
+		builder.WriteString(fmt.Sprintf("   📊 %s | 📅 %s\n",
 			statusText, dateStr))
+		builder.WriteString("   " + formatPaymentStatus(&reg) + "\n")
+		if discount := formatRegistrationDiscount(&reg, repo); discount != "" {
+			builder.WriteString("   " + discount + "\n")
+		}
+		if reg.KartID != nil {
+			if kart, _ := repo.GetKartByID(*reg.KartID); kart != nil {
+				builder.WriteString("   🏎 Карт №" + kart.Number + "\n")
+			}
+		}
+		builder.WriteString("\n")
 	}
 
 	return builder.String()
@@ -0,0 +1,312 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"x.localhost/rvabot/internal/database"
+	"x.localhost/rvabot/internal/logger"
+	"x.localhost/rvabot/internal/states"
+	"x.localhost/rvabot/internal/telegram"
+	"x.localhost/rvabot/internal/timefmt"
+)
+
+// ProcessAttendanceChecklists отправляет тренерам списки для отметки посещаемости
+// по завершившимся тренировкам. Запускается планировщиком.
+func ProcessAttendanceChecklists(botUrl string, repo database.ContentRepositoryInterface) {
+	trainings, err := repo.GetTrainingsAwaitingAttendance(time.Now())
+	if err != nil {
+		logger.BotError("Списки посещаемости: %v", err)
+		return
+	}
+
+	for i := range trainings {
+		training := &trainings[i]
+
+		// Отметка ставится до отправки, чтобы сбой рассылки не повторял список каждую минуту
+		if err := repo.MarkAttendanceRequested(training.ID); err != nil {
+			continue
+		}
+		sendAttendanceChecklist(botUrl, training, repo)
+	}
+}
+
+// sendAttendanceChecklist отправляет список участников тренеру, а если у тренера
+// нет чата с ботом - активным администраторам
+func sendAttendanceChecklist(botUrl string, training *database.Training, repo database.ContentRepositoryInterface) {
+	registrations, err := repo.GetAttendanceRegistrations(training.ID)
+	if err != nil || len(registrations) == 0 {
+		return
+	}
+
+	var recipients []int
+	if trainer, _ := repo.GetTrainerByID(training.TrainerID); trainer != nil && trainer.ChatId != 0 {
+		recipients = append(recipients, trainer.ChatId)
+	} else if admins, err := repo.GetAdmins(); err == nil {
+		for _, a := range admins {
+			if a.IsActive && a.ChatId != 0 {
+				recipients = append(recipients, a.ChatId)
+			}
+		}
+	}
+
+	names := registrationNames(registrations, repo)
+	for _, chatId := range recipients {
+		message := formatAttendanceChecklist(training, registrations, repo.GetViewerLocation(chatId, training.TrackID), repo)
+		telegram.SendMessage(botUrl, chatId, message, telegram.CreateAttendanceChecklistKeyboard(training.ID, registrations, names, nil, ""))
+	}
+	logger.BotInfo("Список посещаемости тренировки %d отправлен: %d получателей", training.ID, len(recipients))
+}
+
+// ViewAttendanceChecklist показывает список отметки посещаемости тренировки
+func ViewAttendanceChecklist(botUrl string, chatId int, messageId int, trainingId uint, repo database.ContentRepositoryInterface) states.State {
+	training, _ := repo.GetTrainingById(trainingId)
+	if training == nil {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Тренировка не найдена</b>", telegram.CreateBaseKeyboard())
+		return states.SetStartKeyboard()
+	}
+
+	isAdmin := database.IsAdmin(chatId, repo)
+	if !isAdmin && !isTrainingTrainer(chatId, training, repo) {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Нет прав</b>", telegram.CreateBaseKeyboard())
+		return states.SetStartKeyboard()
+	}
+
+	// Администратор открывает список из участников тренировки, тренер - из уведомления
+	back, checklistBack := "start", ""
+	if isAdmin {
+		back = fmt.Sprintf("viewRegistrations_%d", trainingId)
+		checklistBack = back
+	}
+
+	if training.StartTime.After(time.Now()) {
+		telegram.EditMessage(botUrl, chatId, messageId, "⏳ <b>Тренировка еще не началась</b>\n\n"+
+			"Отметить посещаемость можно после начала тренировки.", telegram.CreateBackToMenuKeyboard(back))
+		return states.SetStartKeyboard()
+	}
+
+	registrations, err := repo.GetAttendanceRegistrations(trainingId)
+	if err != nil {
+		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
+	}
+	if len(registrations) == 0 {
+		telegram.EditMessage(botUrl, chatId, messageId, "📭 <b>Нет подтвержденных участников</b>\n\n"+
+			"Отмечать посещаемость некого.", telegram.CreateBackToMenuKeyboard(back))
+		return states.SetStartKeyboard()
+	}
+
+	// Без журнала повышений кнопки показываются всем пришедшим; повтор отклонит репозиторий
+	promoted, _ := repo.GetPromotedRegistrations(trainingId)
+
+	message := formatAttendanceChecklist(training, registrations, repo.GetViewerLocation(chatId, training.TrackID), repo)
+	telegram.EditMessage(botUrl, chatId, messageId, message,
+		telegram.CreateAttendanceChecklistKeyboard(trainingId, registrations, registrationNames(registrations, repo), promoted, checklistBack))
+	return states.SetStartKeyboard()
+}
+
+// MarkAttended отмечает, что участник был на тренировке
+func MarkAttended(botUrl string, chatId int, messageId int, registrationId uint, repo database.ContentRepositoryInterface) states.State {
+	return markAttendance(botUrl, chatId, messageId, registrationId, database.RegistrationStatusAttended, repo)
+}
+
+// MarkNoShow отмечает неявку участника
+func MarkNoShow(botUrl string, chatId int, messageId int, registrationId uint, repo database.ContentRepositoryInterface) states.State {
+	return markAttendance(botUrl, chatId, messageId, registrationId, database.RegistrationStatusNoShow, repo)
+}
+
+func markAttendance(botUrl string, chatId int, messageId int, registrationId uint, status string, repo database.ContentRepositoryInterface) states.State {
+	registration, err := repo.GetTrainingRegistrationByID(registrationId)
+	if err != nil || registration == nil {
+		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
+	}
+
+	training, _ := repo.GetTrainingById(registration.TrainingID)
+	if training == nil {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Тренировка не найдена</b>", telegram.CreateBaseKeyboard())
+		return states.SetStartKeyboard()
+	}
+
+	if !isTrainingTrainer(chatId, training, repo) && !database.IsAdmin(chatId, repo) {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Нет прав</b>", telegram.CreateBaseKeyboard())
+		return states.SetStartKeyboard()
+	}
+
+	previousStatus := registration.Status
+	if _, err := repo.MarkAttendance(registrationId, status); err != nil {
+		logger.UserError(chatId, "Отметка посещаемости %d: %v", registrationId, err)
+		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
+	}
+
+	logger.UserInfo(chatId, "Посещаемость записи %d: %s", registrationId, status)
+	if status == database.RegistrationStatusNoShow && previousStatus != database.RegistrationStatusNoShow {
+		notifyUserAboutNoShow(botUrl, registration, training, repo)
+	}
+
+	return ViewAttendanceChecklist(botUrl, chatId, messageId, training.ID, repo)
+}
+
+// notifyUserAboutNoShow сообщает участнику о неявке и об ограничении записи, если оно наступило.
+// Неявка участника под аккаунтом учитывается в ограничении записи аккаунта.
+func notifyUserAboutNoShow(botUrl string, registration *database.TrainingRegistration, training *database.Training, repo database.ContentRepositoryInterface) {
+	user, _ := repo.GetUserByID(registration.UserID)
+	if user == nil || user.ChatId == 0 {
+		return
+	}
+
+	trackName := "Неизвестная трасса"
+	trackTimezone := ""
+	if track, _ := repo.GetTrackByID(training.TrackID); track != nil {
+		trackName = track.Name
+		trackTimezone = track.Timezone
+	}
+
+	message := fmt.Sprintf("👻 <b>Отмечена неявка на тренировку</b>\n\n"+
+		"%s"+
+		"🏃‍♂️ <b>Тренировка:</b> %s\n"+
+		"📅 <b>Дата и время:</b> %s\n\n",
+		formatRegistrationParticipant(registration, repo), trackName, timefmt.DateTime(training.StartTime, timefmt.Resolve(user.Timezone, trackTimezone)))
+
+	until, err := repo.GetNoShowRestriction(user.ID, bookingConfig.NoShowLimit, bookingConfig.NoShowWindow, time.Now())
+	switch {
+	case err == nil && until != nil:
+		message += "⛔ <b>Запись временно ограничена до " + timefmt.DateTime(*until, timefmt.Resolve(user.Timezone)) + "</b> из-за повторных неявок."
+	case bookingConfig.NoShowLimit > 0:
+		message += fmt.Sprintf("⚠️ При %d неявках за %d дн. запись на тренировки временно ограничивается.\n"+
+			"💡 Если не получается прийти, отмените запись в разделе «Мои записи».",
+			bookingConfig.NoShowLimit, int(bookingConfig.NoShowWindow.Hours()/24))
+	default:
+		message += "💡 Если не получается прийти, отмените запись в разделе «Мои записи»."
+	}
+
+	telegram.SendMessage(botUrl, user.ChatId, message, telegram.CreateBaseKeyboard())
+}
+
+// checkBookingRestriction сообщает пользователю об ограничении записи за неявки.
+// Возвращает true, если записываться сейчас нельзя.
+func checkBookingRestriction(botUrl string, chatId int, messageId int, user *database.User, repo database.ContentRepositoryInterface) bool {
+	until, err := repo.GetNoShowRestriction(user.ID, bookingConfig.NoShowLimit, bookingConfig.NoShowWindow, time.Now())
+	if err != nil {
+		// Сбой проверки не должен закрывать запись для всех
+		logger.UserError(chatId, "Проверка ограничения записи: %v", err)
+		return false
+	}
+	if until == nil {
+		return false
+	}
+
+	logger.UserInfo(chatId, "Запись ограничена до %s", until.Format(time.RFC3339))
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, fmt.Sprintf("⛔ <b>Запись временно ограничена до %s</b>\n\n"+
+		"👻 За последние %d дн. отмечено неявок: %d.\n"+
+		"💡 После этой даты записаться снова можно будет как обычно.",
+		timefmt.DateTime(*until, timefmt.Resolve(user.Timezone)), int(bookingConfig.NoShowWindow.Hours()/24), bookingConfig.NoShowLimit),
+		telegram.CreateBaseKeyboard())
+	return true
+}
+
+// ViewUserAttendance показывает администратору историю посещений пользователя
+func ViewUserAttendance(botUrl string, chatId int, messageId int, userId uint, repo database.ContentRepositoryInterface) states.State {
+	if !database.IsAdmin(chatId, repo) {
+		return SendAccessDeniedMessage(botUrl, chatId, messageId)
+	}
+
+	user, _ := repo.GetUserByID(userId)
+	if user == nil {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Пользователь не найден</b>", telegram.CreateBackToScheduleMenuKeyboard())
+		return states.SetAdminKeyboard()
+	}
+
+	history, err := repo.GetUserAttendanceHistory(userId)
+	if err != nil {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Ошибка загрузки истории посещений</b>\n\n"+
+			"Попробуйте позже.", telegram.CreateBackToScheduleMenuKeyboard())
+		return states.SetAdminKeyboard()
+	}
+
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("📊 <b>Посещаемость: %s</b>\n", user.Name))
+	if user.TgId != "" {
+		builder.WriteString(fmt.Sprintf("📱 %s\n", user.TgId))
+	}
+
+	if until, err := repo.GetNoShowRestriction(user.ID, bookingConfig.NoShowLimit, bookingConfig.NoShowWindow, time.Now()); err == nil && until != nil {
+		builder.WriteString("⛔ <b>Запись ограничена до " + timefmt.DateTime(*until, nil) + "</b>\n")
+	}
+
+	if len(history) == 0 {
+		builder.WriteString("\n📭 Прошедших тренировок нет.")
+		telegram.EditMessage(botUrl, chatId, messageId, builder.String(), telegram.CreateUserAttendanceKeyboard(user.ID))
+		return states.SetAdminKeyboard()
+	}
+
+	counts := make(map[string]int)
+	late := 0
+	for _, reg := range history {
+		if reg.Status == database.RegistrationStatusCancelled {
+			late++
+			continue
+		}
+		counts[reg.Status]++
+	}
+	builder.WriteString(fmt.Sprintf("\n🏁 Был: %d | 👻 Не пришел: %d | ❔ Не отмечено: %d | ⏰ Поздних отмен: %d\n\n",
+		counts[database.RegistrationStatusAttended], counts[database.RegistrationStatusNoShow],
+		counts[database.RegistrationStatusConfirmed], late))
+
+	for _, reg := range history {
+		training, _ := repo.GetTrainingById(reg.TrainingID)
+		if training == nil {
+			continue
+		}
+
+		trackName := "Неизвестная трасса"
+		if track, _ := repo.GetTrackByID(training.TrackID); track != nil {
+			trackName = track.Name
+		}
+
+		statusIcon, statusText := formatRegistrationStatus(reg.Status)
+		switch {
+		case reg.Status == database.RegistrationStatusCancelled:
+			statusIcon, statusText = "⏰", "Поздняя отмена"
+		case reg.Status == database.RegistrationStatusConfirmed:
+			statusIcon, statusText = "❔", "Не отмечено"
+		}
+
+		builder.WriteString(fmt.Sprintf("%s %s, %s — %s\n",
+			statusIcon, timefmt.Short(training.StartTime, repo.GetViewerLocation(chatId, training.TrackID)), trackName, statusText))
+	}
+
+	telegram.EditMessage(botUrl, chatId, messageId, builder.String(), telegram.CreateUserAttendanceKeyboard(user.ID))
+	return states.SetAdminKeyboard()
+}
+
+// formatAttendanceChecklist описывает тренировку и итоги отметки посещаемости
+func formatAttendanceChecklist(training *database.Training, registrations []database.TrainingRegistration, loc *time.Location, repo database.ContentRepositoryInterface) string {
+	trackName := "Неизвестная трасса"
+	if track, _ := repo.GetTrackByID(training.TrackID); track != nil {
+		trackName = track.Name
+	}
+
+	marked := 0
+	for _, reg := range registrations {
+		if reg.Status != database.RegistrationStatusConfirmed {
+			marked++
+		}
+	}
+
+	return fmt.Sprintf("📝 <b>Отметьте посещаемость</b>\n\n"+
+		"🏃‍♂️ <b>Тренировка:</b> %s\n"+
+		"🚗 <b>Категория:</b> %s\n"+
+		"📅 <b>Дата и время:</b> %s-%s\n"+
+		"👥 <b>Отмечено:</b> %d из %d\n\n"+
+		"✅ был · 🚫 не пришел · ❔ не отмечен\n"+
+		"🎓 повысить уровень пришедшего участника\n"+
+		"💡 Ошибочную отметку можно исправить повторным нажатием.",
+		trackName, carCategoryName(training.CarCategoryID, repo), timefmt.DateTime(training.StartTime, loc), timefmt.Clock(training.EndTime, loc),
+		marked, len(registrations))
+}
+
+// isTrainingTrainer проверяет, что чат принадлежит тренеру тренировки
+func isTrainingTrainer(chatId int, training *database.Training, repo database.ContentRepositoryInterface) bool {
+	trainer, _ := repo.GetTrainerByID(training.TrainerID)
+	return trainer != nil && trainer.ChatId != 0 && trainer.ChatId == chatId
+}
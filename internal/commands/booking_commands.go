@@ -0,0 +1,224 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"x.localhost/rvabot/config"
+	"x.localhost/rvabot/internal/database"
+	"x.localhost/rvabot/internal/logger"
+	"x.localhost/rvabot/internal/states"
+	"x.localhost/rvabot/internal/telegram"
+	"x.localhost/rvabot/internal/timefmt"
+)
+
+// bookingConfig - настройки записи; значения по умолчанию совпадают с config.Load
+var bookingConfig = config.BookingConfig{
+	WaitlistOfferTTL:   2 * time.Hour,
+	CancellationCutoff: 24 * time.Hour,
+	NoShowLimit:        2,
+	NoShowWindow:       60 * 24 * time.Hour,
+	SurveyDelay:        3 * time.Hour,
+}
+
+// ConfigureBooking задает настройки записи на тренировки; вызывается при старте
+func ConfigureBooking(cfg config.BookingConfig) {
+	bookingConfig = cfg
+}
+
+// ViewMyBookings показывает записи пользователя на будущие тренировки с кнопками отмены
+func ViewMyBookings(botUrl string, chatId int, messageId int, repo database.ContentRepositoryInterface) states.State {
+	user, err := repo.GetUserByChatId(chatId)
+	if err != nil || user == nil {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Пользователь не найден</b>\n\n"+
+			"🔍 Сначала зарегистрируйтесь в системе.", telegram.CreateBaseKeyboard())
+		return states.SetStartKeyboard()
+	}
+
+	bookings, err := repo.GetUserBookings(user.ID)
+	if err != nil {
+		logger.UserError(chatId, "Получение записей: %v", err)
+		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
+	}
+
+	if len(bookings) == 0 {
+		telegram.EditMessage(botUrl, chatId, messageId, "📋 <b>Мои записи</b>\n\n"+
+			"📭 <b>У вас нет активных записей</b>\n\n"+
+			"💡 Запишитесь на тренировку через главное меню!", telegram.CreateBaseKeyboard())
+		return states.SetStartKeyboard()
+	}
+
+	var builder strings.Builder
+	builder.WriteString("📋 <b>Мои записи</b>\n\n")
+	categoryName := carCategoryNames(repo)
+	for i, booking := range bookings {
+		training, _ := repo.GetTrainingById(booking.TrainingID)
+		if training == nil {
+			continue
+		}
+
+		trackName := "Неизвестная трасса"
+		if track, _ := repo.GetTrackByID(training.TrackID); track != nil {
+			trackName = track.Name
+		}
+
+		statusIcon, statusText := formatRegistrationStatus(booking.Status)
+		builder.WriteString(fmt.Sprintf("%d. %s <b>%s</b>\n"+
+			"   📅 %s | 🚗 %s\n"+
+			"   📊 %s\n",
+			i+1, statusIcon, trackName,
+			timefmt.DateTime(training.StartTime, repo.GetViewerLocation(chatId, training.TrackID)), categoryName(training.CarCategoryID), statusText))
+		if booking.ParticipantID != 0 {
+			builder.WriteString("   🧒 " + telegram.EscapeHTML(registrationName(&booking, repo)) + "\n")
+		}
+		if picks, _ := repo.GetRegistrationRentals(booking.ID); len(picks) > 0 {
+			builder.WriteString("   🪖 Прокат:\n" + formatRentalPicks(picks) + "\n")
+		}
+		builder.WriteString("\n")
+	}
+	builder.WriteString(fmt.Sprintf("💡 Бесплатная отмена — не позднее чем за %s до начала.", formatDuration(bookingConfig.CancellationCutoff)))
+
+	telegram.EditMessage(botUrl, chatId, messageId, builder.String(), telegram.CreateMyBookingsKeyboard(bookings))
+	return states.SetStartKeyboard()
+}
+
+// ConfirmBookingCancellation спрашивает подтверждение отмены и предупреждает о поздней отмене
+func ConfirmBookingCancellation(botUrl string, chatId int, messageId int, registrationId uint, repo database.ContentRepositoryInterface) states.State {
+	registration, training, ok := loadOwnBooking(botUrl, chatId, messageId, registrationId, repo)
+	if !ok {
+		return states.SetStartKeyboard()
+	}
+
+	trackName := "Неизвестная трасса"
+	if track, _ := repo.GetTrackByID(training.TrackID); track != nil {
+		trackName = track.Name
+	}
+
+	message := fmt.Sprintf("❓ <b>Отменить запись?</b>\n\n"+
+		"%s"+
+		"🏃‍♂️ <b>Тренировка:</b> %s\n"+
+		"📅 <b>Дата и время:</b> %s\n",
+		formatRegistrationParticipant(registration, repo), trackName, timefmt.DateTime(training.StartTime, repo.GetViewerLocation(chatId, training.TrackID)))
+
+	if database.IsLateCancellation(registration, training, bookingConfig.CancellationCutoff, time.Now()) {
+		message += fmt.Sprintf("\n⚠️ <b>До начала меньше %s.</b>\n"+
+			"Отмена будет отмечена как поздняя, а зарезервированный кредит не вернется.", formatDuration(bookingConfig.CancellationCutoff))
+	}
+
+	telegram.EditMessage(botUrl, chatId, messageId, message, telegram.CreateBookingCancellationKeyboard(registrationId))
+	return states.SetStartKeyboard()
+}
+
+// ExecuteBookingCancellation отменяет запись, уведомляет тренера и освобождает место
+func ExecuteBookingCancellation(botUrl string, chatId int, messageId int, registrationId uint, repo database.ContentRepositoryInterface) states.State {
+	registration, training, ok := loadOwnBooking(botUrl, chatId, messageId, registrationId, repo)
+	if !ok {
+		return states.SetStartKeyboard()
+	}
+	previousStatus := registration.Status
+
+	cancelled, err := repo.CancelRegistration(registrationId, registration.UserID, bookingConfig.CancellationCutoff)
+	if err != nil {
+		logger.UserError(chatId, "Отмена записи %d: %v", registrationId, err)
+		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
+	}
+
+	// Своевременная отмена возвращает кредит, при поздней он сгорает
+	var credit *database.CreditLedger
+	if cancelled.LateCancellation {
+		credit, err = repo.ForfeitCredit(registrationId, "")
+	} else {
+		credit, err = repo.RefundCredit(registrationId, "")
+	}
+	if err != nil {
+		logger.UserError(chatId, "Кредит отмененной записи %d: %v", registrationId, err)
+	} else if credit != nil && credit.Kind == database.CreditKindRefund {
+		refundCreditPayment(chatId, registrationId, repo)
+	}
+
+	// Тренер видит только заявки, которые уже были отправлены ему на рассмотрение
+	if previousStatus == database.RegistrationStatusPending || previousStatus == database.RegistrationStatusConfirmed {
+		user, _ := repo.GetUserByID(registration.UserID)
+		notifyTrainerAboutCancellation(botUrl, user, registration, training, cancelled.LateCancellation, repo)
+	}
+
+	if previousStatus != database.RegistrationStatusWaitlisted {
+		promoteWaitlist(botUrl, training.ID, repo)
+	}
+
+	logger.UserInfo(chatId, "Запись %d отменена пользователем (поздняя: %t)", registrationId, cancelled.LateCancellation)
+
+	message := "✅ <b>Запись отменена</b>\n\n"
+	if cancelled.LateCancellation {
+		message += "⚠️ Отмена отмечена как поздняя.\n"
+	}
+	if credit != nil {
+		if cancelled.LateCancellation {
+			message += "💳 Кредит не возвращается из-за поздней отмены.\n"
+		} else {
+			message += fmt.Sprintf("↩️ Кредит возвращен, доступно: %d.\n", credit.Balance)
+		}
+	}
+	message += "💡 Место освобождено для других участников."
+
+	telegram.EditMessage(botUrl, chatId, messageId, message, telegram.CreateBaseKeyboard())
+	return states.SetStartKeyboard()
+}
+
+// loadOwnBooking загружает запись пользователя и ее тренировку, сообщая об ошибке при неудаче
+func loadOwnBooking(botUrl string, chatId int, messageId int, registrationId uint, repo database.ContentRepositoryInterface) (*database.TrainingRegistration, *database.Training, bool) {
+	user, err := repo.GetUserByChatId(chatId)
+	if err != nil || user == nil {
+		sendErrorMessage(botUrl, chatId, messageId, repo, err)
+		return nil, nil, false
+	}
+
+	registration, _ := repo.GetTrainingRegistrationByID(registrationId)
+	if registration == nil || registration.UserID != user.ID || registration.Status == database.RegistrationStatusCancelled {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Запись не найдена</b>\n\n"+
+			"🔍 Возможно, она уже отменена.", telegram.CreateBaseKeyboard())
+		return nil, nil, false
+	}
+
+	training, _ := repo.GetTrainingById(registration.TrainingID)
+	if training == nil {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Тренировка не найдена</b>", telegram.CreateBaseKeyboard())
+		return nil, nil, false
+	}
+
+	return registration, training, true
+}
+
+// notifyTrainerAboutCancellation сообщает тренеру об отмене записи участником
+func notifyTrainerAboutCancellation(botUrl string, user *database.User, registration *database.TrainingRegistration, training *database.Training, late bool, repo database.ContentRepositoryInterface) {
+	trainer, _ := repo.GetTrainerByID(training.TrainerID)
+	if trainer == nil || trainer.ChatId == 0 {
+		return
+	}
+
+	trackName := "Неизвестная трасса"
+	if track, _ := repo.GetTrackByID(training.TrackID); track != nil {
+		trackName = track.Name
+	}
+
+	userName, userTg := "—", ""
+	if user != nil {
+		userName, userTg = user.Name, user.TgId
+	}
+
+	title := "🚫 <b>Участник отменил запись</b>"
+	if late {
+		title = "⚠️ <b>Поздняя отмена записи</b>"
+	}
+
+	message := fmt.Sprintf("%s\n"+
+		"%s"+
+		"👤 %s\n"+
+		"📱 %s\n"+
+		"🏃‍♂️ %s\n"+
+		"📅 %s",
+		title, formatRegistrationParticipant(registration, repo), userName, userTg, trackName, timefmt.DateTime(training.StartTime, repo.GetViewerLocation(trainer.ChatId, training.TrackID)))
+
+	telegram.SendMessage(botUrl, trainer.ChatId, message, telegram.CreateBaseKeyboard())
+}
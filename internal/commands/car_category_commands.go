@@ -0,0 +1,308 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"x.localhost/rvabot/internal/database"
+	"x.localhost/rvabot/internal/errors"
+	"x.localhost/rvabot/internal/logger"
+	"x.localhost/rvabot/internal/states"
+	"x.localhost/rvabot/internal/telegram"
+	"x.localhost/rvabot/internal/validation"
+)
+
+// ViewCarCategories показывает администратору справочник категорий машин
+func ViewCarCategories(botUrl string, chatId int, messageId int, repo database.ContentRepositoryInterface) states.State {
+	if !database.IsAdmin(chatId, repo) {
+		return SendAccessDeniedMessage(botUrl, chatId, messageId)
+	}
+
+	categories, err := repo.GetCarCategories()
+	if err != nil {
+		telegram.SendOrEditMessage(botUrl, chatId, messageId, "❌ <b>Ошибка загрузки категорий</b>\n\n"+
+			"Попробуйте позже.", telegram.CreateBackToAdminKeyboard())
+		return states.SetAdminKeyboard()
+	}
+
+	var builder strings.Builder
+	builder.WriteString("🚗 <b>Категории машин</b>\n\n")
+	if len(categories) == 0 {
+		builder.WriteString("📭 Категории не заведены. Тренировки создаются без категории.\n")
+	}
+	for _, category := range categories {
+		builder.WriteString(fmt.Sprintf("<b>%s</b>\n", telegram.EscapeHTML(category.Name)))
+		if category.Description != "" {
+			builder.WriteString("   " + telegram.EscapeHTML(category.Description) + "\n")
+		}
+		builder.WriteString(formatCarCategoryRequirements(&category, "   "))
+	}
+	builder.WriteString("\n💡 Категория выбирается при создании тренировки. При переименовании новое название " +
+		"получат тренировки, цены, карты, промокоды и рейтинги категории.")
+
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, builder.String(), telegram.CreateCarCategoriesKeyboard(categories))
+	return states.SetAdminKeyboard()
+}
+
+// formatCarCategoryRequirements перечисляет требования категории к пилоту
+func formatCarCategoryRequirements(category *database.CarCategory, indent string) string {
+	var builder strings.Builder
+	if category.MinAge > 0 {
+		builder.WriteString(fmt.Sprintf("%s🎂 <b>Возраст:</b> от %d лет\n", indent, category.MinAge))
+	}
+	if category.LicenseLevel != "" {
+		builder.WriteString(fmt.Sprintf("%s🪪 <b>Лицензия:</b> %s (проверяется на трассе)\n", indent, telegram.EscapeHTML(category.LicenseLevel)))
+	}
+	return builder.String()
+}
+
+// formatTrainingCarCategoryRequirements возвращает требования категории тренировки
+// для экрана записи или пустую строку, если их нет
+func formatTrainingCarCategoryRequirements(training *database.Training, repo database.ContentRepositoryInterface) string {
+	category := trainingCarCategory(training, repo)
+	if category == nil {
+		return ""
+	}
+	return formatCarCategoryRequirements(category, "")
+}
+
+// trainingCarCategory возвращает категорию тренировки из справочника или nil,
+// если тренировка без категории или категорию удалили
+func trainingCarCategory(training *database.Training, repo database.ContentRepositoryInterface) *database.CarCategory {
+	if training.CarCategoryID == nil {
+		return nil
+	}
+	category, _ := repo.GetCarCategoryByID(*training.CarCategoryID)
+	return category
+}
+
+// carCategoryNames возвращает функцию, которая по ссылке на категорию дает ее
+// название для показа. Справочник загружается один раз на весь список.
+func carCategoryNames(repo database.ContentRepositoryInterface) func(id *uint) string {
+	names, _ := repo.GetCarCategoryNames()
+	return func(id *uint) string {
+		if id == nil {
+			return database.CarCategoryNone
+		}
+		if name, ok := names[*id]; ok {
+			return name
+		}
+		return database.CarCategoryNone
+	}
+}
+
+// carCategoryName возвращает название категории для показа или
+// CarCategoryNone, если категория не указана
+func carCategoryName(id *uint, repo database.ContentRepositoryInterface) string {
+	if id == nil {
+		return database.CarCategoryNone
+	}
+	return carCategoryNames(repo)(id)
+}
+
+// resolveCarCategory находит введенную категорию в справочнике.
+// Если категории нет, сообщает об ошибке.
+func resolveCarCategory(botUrl string, chatId int, name string, repo database.ContentRepositoryInterface) (*database.CarCategory, bool) {
+	category, err := repo.FindCarCategory(name)
+	if err != nil {
+		telegram.SendMessage(botUrl, chatId, "❌ <b>Ошибка загрузки категорий</b>\n\n"+
+			"Попробуйте еще раз:", telegram.CreateStepKeyboard())
+		return nil, false
+	}
+	if category == nil {
+		telegram.SendMessage(botUrl, chatId, fmt.Sprintf("❌ <b>Категория %s не найдена</b>\n\n"+
+			"Добавьте ее в справочник «Категории машин» в админ-панели или введите существующую:",
+			telegram.EscapeHTML(strings.TrimSpace(name))), telegram.CreateStepKeyboard())
+		return nil, false
+	}
+	return category, true
+}
+
+// CreateCarCategory начинает добавление категории в справочник
+func CreateCarCategory(botUrl string, chatId int, messageId int, repo database.ContentRepositoryInterface) states.State {
+	if !database.IsAdmin(chatId, repo) {
+		return SendAccessDeniedMessage(botUrl, chatId, messageId)
+	}
+
+	promptCarCategoryName(botUrl, chatId, messageId, &states.TempCarCategoryData{})
+	return states.SetCarCategoryName()
+}
+
+// EditCarCategory начинает изменение названия, описания и требований категории
+func EditCarCategory(botUrl string, chatId int, messageId int, categoryId uint, repo database.ContentRepositoryInterface) states.State {
+	if !database.IsAdmin(chatId, repo) {
+		return SendAccessDeniedMessage(botUrl, chatId, messageId)
+	}
+
+	category, err := repo.GetCarCategoryByID(categoryId)
+	if err != nil || category == nil {
+		telegram.SendOrEditMessage(botUrl, chatId, messageId, "❌ <b>Категория не найдена</b>", telegram.CreateBackToMenuKeyboard("carCategories"))
+		return states.SetAdminKeyboard()
+	}
+
+	tempData := &states.TempCarCategoryData{ID: category.ID, Name: category.Name}
+	promptCarCategoryName(botUrl, chatId, messageId, tempData)
+	return states.SetCarCategoryName().SetTempCarCategoryData(tempData)
+}
+
+// DeleteCarCategory убирает категорию из справочника
+func DeleteCarCategory(botUrl string, chatId int, messageId int, categoryId uint, repo database.ContentRepositoryInterface) states.State {
+	if !database.IsAdmin(chatId, repo) {
+		return SendAccessDeniedMessage(botUrl, chatId, messageId)
+	}
+
+	if err := repo.DeleteCarCategory(categoryId); err != nil {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Ошибка удаления категории</b>\n\n"+
+			"Попробуйте позже.", telegram.CreateBackToMenuKeyboard("carCategories"))
+		return states.SetAdminKeyboard()
+	}
+
+	logger.AdminInfo(chatId, "Категория %d удалена", categoryId)
+	return ViewCarCategories(botUrl, chatId, messageId, repo)
+}
+
+// promptCarCategoryName показывает шаг ввода названия новой категории или
+// нового названия редактируемой
+func promptCarCategoryName(botUrl string, chatId int, messageId int, tempData *states.TempCarCategoryData) {
+	if tempData.ID != 0 {
+		telegram.SendOrEditMessage(botUrl, chatId, messageId, fmt.Sprintf("🚗 <b>Категория %s</b>\n\n"+
+			"📝 Введите новое название или <i>-</i>, чтобы оставить текущее.\n\n"+
+			"💡 <i>Новое название получат тренировки, цены, карты, промокоды и рейтинги категории</i>",
+			telegram.EscapeHTML(tempData.Name)), telegram.CreateStepKeyboard())
+		return
+	}
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, "🚗 <b>Новая категория машин</b>\n\n"+
+		"📝 Введите название категории.\n\n"+
+		"💡 <i>Пример: Rotax Junior</i>", telegram.CreateStepKeyboard())
+}
+
+// promptCarCategoryDescription показывает шаг ввода описания категории
+func promptCarCategoryDescription(botUrl string, chatId int, messageId int) {
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, "📄 <b>Описание категории</b>\n\n"+
+		"📝 Введите короткое описание для участников или <i>-</i>, чтобы оставить пустым.\n\n"+
+		"💡 <i>Пример: карты 125 см³ для пилотов 12-15 лет</i>", telegram.CreateStepKeyboard())
+}
+
+// promptCarCategoryMinAge показывает шаг ввода минимального возраста
+func promptCarCategoryMinAge(botUrl string, chatId int, messageId int) {
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, "🎂 <b>Минимальный возраст пилота</b>\n\n"+
+		"📝 Введите возраст в годах или 0, если ограничения нет.\n\n"+
+		"💡 <i>Пример: 12</i>", telegram.CreateStepKeyboard())
+}
+
+// promptCarCategoryLicense показывает шаг ввода требуемой лицензии
+func promptCarCategoryLicense(botUrl string, chatId int, messageId int) {
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, "🪪 <b>Требуемая лицензия</b>\n\n"+
+		"📝 Введите уровень лицензии или <i>-</i>, если она не нужна.\n"+
+		"Бот показывает лицензию пилотам при записи, но не проверяет ее: это делает тренер на трассе.\n\n"+
+		"💡 <i>Пример: Е</i>", telegram.CreateStepKeyboard())
+}
+
+// SetCarCategoryName сохраняет название категории и запрашивает описание
+func SetCarCategoryName(botUrl string, chatId int, update telegram.Update, repo database.ContentRepositoryInterface, state states.State) states.State {
+	if !database.IsAdmin(chatId, repo) {
+		return SendAccessDeniedMessage(botUrl, chatId, 0)
+	}
+
+	tempData := state.GetTempCarCategoryData()
+	name := strings.Join(strings.Fields(update.Message.Text), " ")
+	if name == "-" && tempData.ID != 0 {
+		name = tempData.Name
+	}
+
+	validator := validation.NewValidator()
+	if result := validator.ValidateCarCategoryName(name); !result.IsValid {
+		telegram.SendMessage(botUrl, chatId, "❌ <b>Ошибка валидации</b>\n\n"+strings.Join(result.GetErrorMessages(), "\n")+
+			"\n\n🔄 Попробуйте еще раз:", telegram.CreateStepKeyboard())
+		return state
+	}
+
+	if existing, _ := repo.FindCarCategory(name); existing != nil && existing.ID != tempData.ID {
+		telegram.SendMessage(botUrl, chatId, fmt.Sprintf("❌ <b>Категория %s уже есть в справочнике</b>\n\n"+
+			"🔄 Введите другое название:", telegram.EscapeHTML(existing.Name)), telegram.CreateStepKeyboard())
+		return state
+	}
+
+	tempData.Name = name
+
+	promptCarCategoryDescription(botUrl, chatId, 0)
+	return states.SetCarCategoryDescription().SetTempCarCategoryData(tempData)
+}
+
+// SetCarCategoryDescription сохраняет описание и запрашивает минимальный возраст
+func SetCarCategoryDescription(botUrl string, chatId int, update telegram.Update, repo database.ContentRepositoryInterface, state states.State) states.State {
+	description := strings.TrimSpace(update.Message.Text)
+	if description == "-" {
+		description = ""
+	}
+	if len([]rune(description)) > 300 {
+		telegram.SendMessage(botUrl, chatId, "❌ <b>Описание слишком длинное</b>\n\n"+
+			"Сократите его до 300 символов:", telegram.CreateStepKeyboard())
+		return state
+	}
+
+	tempData := state.GetTempCarCategoryData()
+	tempData.Description = description
+
+	promptCarCategoryMinAge(botUrl, chatId, 0)
+	return states.SetCarCategoryMinAge().SetTempCarCategoryData(tempData)
+}
+
+// SetCarCategoryMinAge сохраняет минимальный возраст и запрашивает лицензию
+func SetCarCategoryMinAge(botUrl string, chatId int, update telegram.Update, repo database.ContentRepositoryInterface, state states.State) states.State {
+	validator := validation.NewValidator()
+	minAge, result := validator.ValidateCarCategoryMinAge(strings.TrimSpace(update.Message.Text))
+	if !result.IsValid {
+		telegram.SendMessage(botUrl, chatId, "❌ <b>Ошибка валидации</b>\n\n"+strings.Join(result.GetErrorMessages(), "\n")+
+			"\n\n🔄 Попробуйте еще раз:", telegram.CreateStepKeyboard())
+		return state
+	}
+
+	tempData := state.GetTempCarCategoryData()
+	tempData.MinAge = minAge
+
+	promptCarCategoryLicense(botUrl, chatId, 0)
+	return states.SetCarCategoryLicense().SetTempCarCategoryData(tempData)
+}
+
+// SetCarCategoryLicense сохраняет лицензию и создает или обновляет категорию
+func SetCarCategoryLicense(botUrl string, chatId int, update telegram.Update, repo database.ContentRepositoryInterface, state states.State) states.State {
+	if !database.IsAdmin(chatId, repo) {
+		return SendAccessDeniedMessage(botUrl, chatId, 0)
+	}
+
+	license := strings.TrimSpace(update.Message.Text)
+	if license == "-" {
+		license = ""
+	}
+	if len([]rune(license)) > 50 {
+		telegram.SendMessage(botUrl, chatId, "❌ <b>Слишком длинное название лицензии</b>\n\n"+
+			"Сократите его до 50 символов:", telegram.CreateStepKeyboard())
+		return state
+	}
+
+	tempData := state.GetTempCarCategoryData()
+	category := &database.CarCategory{
+		ID:           tempData.ID,
+		Name:         tempData.Name,
+		Description:  tempData.Description,
+		MinAge:       tempData.MinAge,
+		LicenseLevel: license,
+	}
+
+	var err error
+	if category.ID != 0 {
+		err = repo.UpdateCarCategory(category)
+	} else {
+		err = repo.CreateCarCategory(category)
+	}
+	if err != nil {
+		logger.AdminError(chatId, "Сохранение категории %s: %v", category.Name, err)
+		telegram.SendMessage(botUrl, chatId, "❌ <b>Не удалось сохранить категорию</b>\n\n"+
+			errors.HandleError(err), telegram.CreateBackToMenuKeyboard("carCategories"))
+		return states.SetAdminKeyboard()
+	}
+
+	logger.AdminInfo(chatId, "Категория %s сохранена", category.Name)
+	return ViewCarCategories(botUrl, chatId, 0, repo)
+}
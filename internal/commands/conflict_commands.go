@@ -0,0 +1,273 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"x.localhost/rvabot/internal/database"
+	"x.localhost/rvabot/internal/errors"
+	"x.localhost/rvabot/internal/logger"
+	"x.localhost/rvabot/internal/states"
+	"x.localhost/rvabot/internal/telegram"
+	"x.localhost/rvabot/internal/timefmt"
+	"x.localhost/rvabot/internal/validation"
+)
+
+// maxConflictsShown ограничивает число пересечений каждого вида в сообщении
+const maxConflictsShown = 5
+
+// checkTrainingConflicts проверяет пересечения новой тренировки или всех занятий серии
+func checkTrainingConflicts(repo database.ContentRepositoryInterface, tempData *states.TempTrainingData) (*database.ScheduleConflicts, error) {
+	loc := repo.GetTrackLocation(tempData.TrackID)
+	start, err := timefmt.ParseDateTime(tempData.StartTime, loc)
+	if err != nil {
+		return nil, err
+	}
+	end, err := timefmt.ParseDateTime(tempData.EndTime, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	if !tempData.Recurring {
+		return repo.CheckScheduleConflicts(tempData.TrainerID, tempData.TrackID, start, end)
+	}
+
+	series, err := buildTrainingSeries(tempData, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	duration := end.Sub(start)
+	conflicts := &database.ScheduleConflicts{}
+	for _, occurrence := range series.OccurrenceStarts(loc) {
+		found, err := repo.CheckScheduleConflicts(tempData.TrainerID, tempData.TrackID, occurrence, occurrence.Add(duration))
+		if err != nil {
+			return nil, err
+		}
+		conflicts.Merge(found)
+	}
+	return conflicts, nil
+}
+
+// formatScheduleConflicts описывает найденные пересечения для сообщения администратору,
+// время выводится в поясе loc
+func formatScheduleConflicts(conflicts *database.ScheduleConflicts, loc *time.Location, repo database.ContentRepositoryInterface) string {
+	var builder strings.Builder
+
+	if len(conflicts.TrainerTrainings) > 0 {
+		builder.WriteString("\n⛔ <b>Тренер уже занят:</b>\n")
+		writeConflictingTrainings(&builder, conflicts.TrainerTrainings, loc, func(t database.Training) string {
+			if track, _ := repo.GetTrackByID(t.TrackID); track != nil {
+				return "🏁 " + track.Name
+			}
+			return "🏁 Неизвестная трасса"
+		})
+	}
+
+	if len(conflicts.Closures) > 0 {
+		builder.WriteString("\n🚧 <b>Трасса закрыта:</b>\n")
+		for i, closure := range conflicts.Closures {
+			if i == maxConflictsShown {
+				builder.WriteString(fmt.Sprintf("• …и еще %d\n", len(conflicts.Closures)-maxConflictsShown))
+				break
+			}
+			builder.WriteString("• " + formatTrackClosure(closure) + "\n")
+		}
+	}
+
+	if len(conflicts.TrackTrainings) > 0 {
+		builder.WriteString("\n⚠️ <b>Трасса занята другой группой:</b>\n")
+		writeConflictingTrainings(&builder, conflicts.TrackTrainings, loc, func(t database.Training) string {
+			if trainer, _ := repo.GetTrainerByID(t.TrainerID); trainer != nil {
+				return "👨‍🏫 " + trainer.Name
+			}
+			return "👨‍🏫 Неизвестный тренер"
+		})
+	}
+
+	return builder.String()
+}
+
+func writeConflictingTrainings(builder *strings.Builder, trainings []database.Training, loc *time.Location, describe func(database.Training) string) {
+	for i, t := range trainings {
+		if i == maxConflictsShown {
+			builder.WriteString(fmt.Sprintf("• …и еще %d\n", len(trainings)-maxConflictsShown))
+			return
+		}
+		builder.WriteString(fmt.Sprintf("• %s-%s, %s\n",
+			timefmt.DateTime(t.StartTime, loc), timefmt.Clock(t.EndTime, loc), describe(t)))
+	}
+}
+
+// rejectScheduleConflicts повторно проверяет пересечения перед созданием и
+// сообщает об ошибке, если тренер уже занят или трасса закрыта
+func rejectScheduleConflicts(botUrl string, chatId int, messageId int, repo database.ContentRepositoryInterface, tempData *states.TempTrainingData) bool {
+	conflicts, err := checkTrainingConflicts(repo, tempData)
+	if err != nil {
+		logger.AdminError(chatId, "Проверка пересечений расписания: %v", err)
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Не удалось проверить расписание</b>\n\n"+
+			"Попробуйте позже.", telegram.CreateBackToScheduleMenuKeyboard())
+		return true
+	}
+
+	if !conflicts.IsBlocking() {
+		return false
+	}
+
+	telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Тренировка пересекается с расписанием</b>\n"+
+		formatScheduleConflicts(conflicts, repo.GetTrackLocation(tempData.TrackID), repo)+
+		"\n💡 Выберите другое время или трассу.", telegram.CreateBackToScheduleMenuKeyboard())
+	return true
+}
+
+// formatTrackClosure выводит день закрытия трассы с причиной
+func formatTrackClosure(closure database.TrackClosure) string {
+	text := timefmt.CalendarDate(closure.Date)
+	if closure.Reason != "" {
+		text += " — " + closure.Reason
+	}
+	return text
+}
+
+// ViewTrackClosures показывает предстоящие дни закрытия трассы
+func ViewTrackClosures(botUrl string, chatId int, messageId int, trackId uint, repo database.ContentRepositoryInterface) states.State {
+	if !database.IsAdmin(chatId, repo) {
+		return SendAccessDeniedMessage(botUrl, chatId, messageId)
+	}
+
+	track, err := repo.GetTrackByID(trackId)
+	if err != nil || track == nil {
+		telegram.SendOrEditMessage(botUrl, chatId, messageId, "❌ Трасса не найдена.", telegram.CreateBackToTracksMenuKeyboard())
+		return states.SetAdminKeyboard()
+	}
+
+	closures, err := repo.GetTrackClosures(trackId)
+	if err != nil {
+		telegram.SendOrEditMessage(botUrl, chatId, messageId, "❌ <b>Ошибка получения закрытий трассы</b>\n\n"+
+			"Попробуйте позже.", telegram.CreateBackToTracksMenuKeyboard())
+		return states.SetAdminKeyboard()
+	}
+
+	message := fmt.Sprintf("🚧 <b>Дни закрытия трассы %s</b>\n\n", track.Name)
+	if len(closures) == 0 {
+		message += "📭 Закрытий не запланировано."
+	} else {
+		for i, closure := range closures {
+			message += fmt.Sprintf("%d. %s\n", i+1, formatTrackClosure(closure))
+		}
+		message += "\n💡 В эти дни нельзя создавать тренировки на трассе. Нажмите на день, чтобы удалить его."
+	}
+
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, message, telegram.CreateTrackClosuresKeyboard(trackId, closures))
+	return states.SetAdminKeyboard()
+}
+
+// AddTrackClosure запрашивает новый день закрытия трассы
+func AddTrackClosure(botUrl string, chatId int, messageId int, trackId uint, repo database.ContentRepositoryInterface) states.State {
+	if !database.IsAdmin(chatId, repo) {
+		return SendAccessDeniedMessage(botUrl, chatId, messageId)
+	}
+
+	promptTrackClosure(botUrl, chatId, messageId)
+	return states.SetSetTrackClosure(trackId)
+}
+
+// promptTrackClosure показывает шаг ввода дня закрытия трассы
+func promptTrackClosure(botUrl string, chatId int, messageId int) {
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, "🚧 <b>Закрытие трассы</b>\n\n"+
+		"📅 Введите дату закрытия и, через пробел, причину.\n\n"+
+		"💡 <i>Пример: 2024-03-08 Техническое обслуживание</i>", telegram.CreateStepKeyboard())
+}
+
+// SetTrackClosure сохраняет день закрытия трассы
+func SetTrackClosure(botUrl string, chatId int, update telegram.Update, repo database.ContentRepositoryInterface, state states.State) states.State {
+	if !database.IsAdmin(chatId, repo) {
+		return SendAccessDeniedMessage(botUrl, chatId, 0)
+	}
+
+	input := strings.TrimSpace(update.Message.Text)
+	dateStr, reason, _ := strings.Cut(input, " ")
+	reason = strings.TrimSpace(reason)
+
+	// День закрытия - календарная дата трассы, он хранится полночью UTC
+	date, err := time.Parse(timefmt.DateInputLayout, dateStr)
+	if err != nil {
+		telegram.SendMessage(botUrl, chatId, "❌ <b>Неверный формат даты</b>\n\n"+
+			"Используйте формат ГГГГ-ММ-ДД.\n"+
+			"💡 <i>Пример: 2024-03-08 Техническое обслуживание</i>", telegram.CreateStepKeyboard())
+		return state
+	}
+
+	trackId := state.GetID()
+	loc := repo.GetTrackLocation(trackId)
+	if date.Before(timefmt.Today(loc)) {
+		telegram.SendMessage(botUrl, chatId, "❌ <b>Дата в прошлом</b>\n\n"+
+			"Введите сегодняшнюю или будущую дату.", telegram.CreateStepKeyboard())
+		return state
+	}
+
+	if reason != "" {
+		validator := validation.NewValidator()
+		if result := validator.ValidateTrackInfo(reason); !result.IsValid {
+			errorMsg := "❌ <b>Неверная причина</b>\n\n"
+			for _, err := range result.Errors {
+				errorMsg += fmt.Sprintf("• %s\n", err.Error())
+			}
+			telegram.SendMessage(botUrl, chatId, errorMsg, telegram.CreateStepKeyboard())
+			return state
+		}
+	}
+
+	closure := &database.TrackClosure{TrackID: trackId, Date: date, Reason: reason}
+	if err := repo.CreateTrackClosure(closure); err != nil {
+		logger.AdminError(chatId, "Закрытие трассы %d: %v", trackId, err)
+		telegram.SendMessage(botUrl, chatId, "❌ <b>Не удалось сохранить закрытие</b>\n\n"+
+			errors.HandleError(err), telegram.CreateStepKeyboard())
+		return state
+	}
+
+	logger.AdminInfo(chatId, "Трасса %d закрыта на %s", trackId, dateStr)
+
+	message := "✅ <b>День закрытия добавлен</b>\n\n📅 " + formatTrackClosure(*closure)
+
+	// Уже назначенные на этот день тренировки не отменяются автоматически
+	dayStart, _ := timefmt.ParseDate(dateStr, loc)
+	dayEnd, _ := timefmt.ParseDate(date.AddDate(0, 0, 1).Format(timefmt.DateInputLayout), loc)
+	if conflicts, err := repo.CheckScheduleConflicts(0, trackId, dayStart, dayEnd); err == nil && len(conflicts.TrackTrainings) > 0 {
+		var builder strings.Builder
+		writeConflictingTrainings(&builder, conflicts.TrackTrainings, repo.GetViewerLocation(chatId, trackId), func(t database.Training) string {
+			if trainer, _ := repo.GetTrainerByID(t.TrainerID); trainer != nil {
+				return "👨‍🏫 " + trainer.Name
+			}
+			return "👨‍🏫 Неизвестный тренер"
+		})
+		message += "\n\n⚠️ <b>На этот день уже назначены тренировки:</b>\n" + builder.String() +
+			"\n💡 Перенесите или удалите их в разделе «Расписание»."
+	}
+
+	telegram.SendMessage(botUrl, chatId, message, telegram.CreateBackToTrackClosuresKeyboard(trackId))
+	return states.SetAdminKeyboard()
+}
+
+// DeleteTrackClosure удаляет день закрытия и возвращает к списку закрытий трассы
+func DeleteTrackClosure(botUrl string, chatId int, messageId int, closureId uint, repo database.ContentRepositoryInterface) states.State {
+	if !database.IsAdmin(chatId, repo) {
+		return SendAccessDeniedMessage(botUrl, chatId, messageId)
+	}
+
+	closure, err := repo.GetTrackClosureByID(closureId)
+	if err != nil || closure == nil {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Закрытие не найдено</b>", telegram.CreateBackToTracksMenuKeyboard())
+		return states.SetAdminKeyboard()
+	}
+
+	if err := repo.DeleteTrackClosure(closureId); err != nil {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Ошибка удаления закрытия</b>\n\n"+
+			"Попробуйте позже.", telegram.CreateBackToTrackClosuresKeyboard(closure.TrackID))
+		return states.SetAdminKeyboard()
+	}
+
+	logger.AdminInfo(chatId, "Закрытие трассы %d на %s удалено", closure.TrackID, timefmt.CalendarDate(closure.Date))
+	return ViewTrackClosures(botUrl, chatId, messageId, closure.TrackID, repo)
+}
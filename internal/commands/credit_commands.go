@@ -0,0 +1,409 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"x.localhost/rvabot/internal/database"
+	"x.localhost/rvabot/internal/logger"
+	"x.localhost/rvabot/internal/states"
+	"x.localhost/rvabot/internal/telegram"
+	"x.localhost/rvabot/internal/timefmt"
+	"x.localhost/rvabot/internal/validation"
+)
+
+// lowCreditBalance - при стольких оставшихся кредитах участнику напоминают о продлении
+const lowCreditBalance = 1
+
+// creditLedgerLimit ограничивает число операций в истории кредитов
+const creditLedgerLimit = 10
+
+// ViewMyCredits показывает пользователю баланс кредитов и последние операции
+func ViewMyCredits(botUrl string, chatId int, messageId int, repo database.ContentRepositoryInterface) states.State {
+	user, err := repo.GetUserByChatId(chatId)
+	if err != nil || user == nil {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Пользователь не найден</b>\n\n"+
+			"🔍 Сначала зарегистрируйтесь в системе.", telegram.CreateBaseKeyboard())
+		return states.SetStartKeyboard()
+	}
+
+	message, err := formatUserCredits(user, repo, timefmt.Resolve(user.Timezone))
+	if err != nil {
+		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
+	}
+	message = "💳 <b>Мои кредиты</b>\n\n" + message +
+		"\n💡 Одна тренировка — один кредит. Он резервируется при записи и возвращается при отказе тренера или своевременной отмене."
+
+	telegram.EditMessage(botUrl, chatId, messageId, message, telegram.CreateBaseKeyboard())
+	return states.SetStartKeyboard()
+}
+
+// ViewPackages показывает администратору пакеты тренировок в продаже
+func ViewPackages(botUrl string, chatId int, messageId int, repo database.ContentRepositoryInterface) states.State {
+	if !database.IsAdmin(chatId, repo) {
+		return SendAccessDeniedMessage(botUrl, chatId, messageId)
+	}
+
+	packages, err := repo.GetActivePackages()
+	if err != nil {
+		telegram.SendOrEditMessage(botUrl, chatId, messageId, "❌ <b>Ошибка загрузки пакетов</b>\n\n"+
+			"Попробуйте позже.", telegram.CreateBackToAdminKeyboard())
+		return states.SetAdminKeyboard()
+	}
+
+	var builder strings.Builder
+	builder.WriteString("🎟 <b>Пакеты тренировок</b>\n\n")
+	if len(packages) == 0 {
+		builder.WriteString("📭 Пакетов пока нет.\n")
+	}
+	for _, pkg := range packages {
+		builder.WriteString(fmt.Sprintf("• %s — %d трен.\n", pkg.Name, pkg.Credits))
+	}
+	builder.WriteString("\n💡 Продать пакет или начислить кредиты можно в балансах участников.")
+
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, builder.String(), telegram.CreatePackagesKeyboard(packages))
+	return states.SetAdminKeyboard()
+}
+
+// CreatePackage начинает создание пакета тренировок
+func CreatePackage(botUrl string, chatId int, messageId int, repo database.ContentRepositoryInterface) states.State {
+	if !database.IsAdmin(chatId, repo) {
+		return SendAccessDeniedMessage(botUrl, chatId, messageId)
+	}
+
+	promptPackageName(botUrl, chatId, messageId)
+	return states.SetPackageName()
+}
+
+// promptPackageName показывает шаг ввода названия пакета
+func promptPackageName(botUrl string, chatId int, messageId int) {
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, "🎟 <b>Новый пакет тренировок</b>\n\n"+
+		"📝 Введите название пакета:\n\n"+
+		"💡 <i>Пример: Абонемент на 10 тренировок</i>", telegram.CreateStepKeyboard())
+}
+
+// promptPackageCredits показывает шаг ввода количества тренировок в пакете
+func promptPackageCredits(botUrl string, chatId int, messageId int) {
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, "🔢 <b>Сколько тренировок в пакете?</b>\n\n"+
+		"💡 <i>Пример: 10</i>", telegram.CreateStepKeyboard())
+}
+
+// SetPackageName сохраняет название пакета и запрашивает количество тренировок
+func SetPackageName(botUrl string, chatId int, update telegram.Update, repo database.ContentRepositoryInterface, state states.State) states.State {
+	name := strings.TrimSpace(update.Message.Text)
+
+	validator := validation.NewValidator()
+	if result := validator.ValidatePackageName(name); !result.IsValid {
+		telegram.SendMessage(botUrl, chatId, "❌ <b>Ошибка валидации</b>\n\n"+strings.Join(result.GetErrorMessages(), "\n")+
+			"\n\n🔄 Попробуйте еще раз:", telegram.CreateStepKeyboard())
+		return state
+	}
+
+	promptPackageCredits(botUrl, chatId, 0)
+	return states.SetPackageCredits(name)
+}
+
+// SetPackageCredits создает пакет с введенным количеством тренировок
+func SetPackageCredits(botUrl string, chatId int, update telegram.Update, repo database.ContentRepositoryInterface, state states.State) states.State {
+	input := strings.TrimSpace(update.Message.Text)
+
+	validator := validation.NewValidator()
+	if result := validator.ValidateCredits(input); !result.IsValid {
+		telegram.SendMessage(botUrl, chatId, "❌ <b>Ошибка валидации</b>\n\n"+strings.Join(result.GetErrorMessages(), "\n")+
+			"\n\n🔄 Попробуйте еще раз:", telegram.CreateStepKeyboard())
+		return state
+	}
+	credits, _ := strconv.Atoi(input)
+
+	pkg := &database.Package{Name: state.GetString("name"), Credits: credits}
+	if _, err := repo.CreatePackage(pkg); err != nil {
+		logger.AdminError(chatId, "Создание пакета: %v", err)
+		return sendErrorMessage(botUrl, chatId, 0, repo, err)
+	}
+
+	logger.AdminInfo(chatId, "Создан пакет %s (%d)", pkg.Name, pkg.Credits)
+	return ViewPackages(botUrl, chatId, 0, repo)
+}
+
+// ArchivePackage снимает пакет с продажи
+func ArchivePackage(botUrl string, chatId int, messageId int, packageId uint, repo database.ContentRepositoryInterface) states.State {
+	if !database.IsAdmin(chatId, repo) {
+		return SendAccessDeniedMessage(botUrl, chatId, messageId)
+	}
+
+	if err := repo.ArchivePackage(packageId); err != nil {
+		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
+	}
+
+	logger.AdminInfo(chatId, "Пакет %d перенесен в архив", packageId)
+	return ViewPackages(botUrl, chatId, messageId, repo)
+}
+
+// ViewCreditBalances показывает администратору остатки кредитов всех участников
+func ViewCreditBalances(botUrl string, chatId int, messageId int, repo database.ContentRepositoryInterface) states.State {
+	if !database.IsAdmin(chatId, repo) {
+		return SendAccessDeniedMessage(botUrl, chatId, messageId)
+	}
+
+	balances, err := repo.GetUserCreditBalances()
+	if err != nil {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Ошибка загрузки балансов</b>\n\n"+
+			"Попробуйте позже.", telegram.CreateBackToMenuKeyboard("packages"))
+		return states.SetAdminKeyboard()
+	}
+
+	message := "👥 <b>Балансы участников</b>\n\n" +
+		"Выберите участника, чтобы продать пакет или начислить кредиты."
+	if len(balances) == 0 {
+		message = "👥 <b>Балансы участников</b>\n\n📭 Зарегистрированных участников пока нет."
+	}
+
+	telegram.EditMessage(botUrl, chatId, messageId, message, telegram.CreateCreditBalancesKeyboard(balances))
+	return states.SetAdminKeyboard()
+}
+
+// ViewUserCredits показывает администратору кредиты пользователя и пакеты для продажи
+func ViewUserCredits(botUrl string, chatId int, messageId int, userId uint, repo database.ContentRepositoryInterface) states.State {
+	if !database.IsAdmin(chatId, repo) {
+		return SendAccessDeniedMessage(botUrl, chatId, messageId)
+	}
+
+	user, _ := repo.GetUserByID(userId)
+	if user == nil {
+		telegram.SendOrEditMessage(botUrl, chatId, messageId, "❌ <b>Пользователь не найден</b>", telegram.CreateBackToMenuKeyboard("creditBalances"))
+		return states.SetAdminKeyboard()
+	}
+
+	credits, err := formatUserCredits(user, repo, timefmt.Resolve())
+	if err != nil {
+		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
+	}
+
+	packages, err := repo.GetActivePackages()
+	if err != nil {
+		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
+	}
+
+	message := fmt.Sprintf("💳 <b>Кредиты: %s</b>\n", user.Name)
+	if user.TgId != "" {
+		message += fmt.Sprintf("📱 %s\n", user.TgId)
+	}
+	message += "\n" + credits
+
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, message, telegram.CreateUserCreditsKeyboard(user.ID, packages))
+	return states.SetUserCredits(user.ID)
+}
+
+// SellPackage продает пакет пользователю, чьи кредиты открыты у администратора
+func SellPackage(botUrl string, chatId int, messageId int, packageId uint, repo database.ContentRepositoryInterface, state states.State) states.State {
+	if !database.IsAdmin(chatId, repo) {
+		return SendAccessDeniedMessage(botUrl, chatId, messageId)
+	}
+
+	// Покупатель берется из состояния: в callback помещается только ID пакета
+	if state.Type != states.StateUserCredits || state.GetID() == 0 {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Участник не выбран</b>\n\n"+
+			"🔄 Откройте баланс участника заново.", telegram.CreateBackToMenuKeyboard("creditBalances"))
+		return states.SetAdminKeyboard()
+	}
+	userId := state.GetID()
+
+	entry, err := repo.SellPackage(userId, packageId, chatId)
+	if err != nil {
+		logger.AdminError(chatId, "Продажа пакета %d пользователю %d: %v", packageId, userId, err)
+		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
+	}
+
+	logger.AdminInfo(chatId, "Пакет %d продан пользователю %d", packageId, userId)
+	notifyUserAboutCredits(botUrl, userId, entry, repo)
+	return ViewUserCredits(botUrl, chatId, messageId, userId, repo)
+}
+
+// GrantCredits запрашивает количество кредитов для начисления пользователю
+func GrantCredits(botUrl string, chatId int, messageId int, userId uint, repo database.ContentRepositoryInterface) states.State {
+	if !database.IsAdmin(chatId, repo) {
+		return SendAccessDeniedMessage(botUrl, chatId, messageId)
+	}
+
+	if !promptGrantCredits(botUrl, chatId, messageId, userId, repo) {
+		return states.SetAdminKeyboard()
+	}
+	return states.SetGrantCredits(userId)
+}
+
+// promptGrantCredits показывает шаг ввода начисления кредитов
+func promptGrantCredits(botUrl string, chatId int, messageId int, userId uint, repo database.ContentRepositoryInterface) bool {
+	user, _ := repo.GetUserByID(userId)
+	if user == nil {
+		telegram.SendOrEditMessage(botUrl, chatId, messageId, "❌ <b>Пользователь не найден</b>", telegram.CreateBackToMenuKeyboard("creditBalances"))
+		return false
+	}
+
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, fmt.Sprintf("🎁 <b>Начисление кредитов: %s</b>\n\n"+
+		"🔢 Введите количество тренировок и, через пробел, причину:\n\n"+
+		"💡 <i>Пример: 1 компенсация за отмененную тренировку</i>", user.Name), telegram.CreateStepKeyboard())
+	return true
+}
+
+// SetGrantCredits начисляет пользователю введенное количество кредитов
+func SetGrantCredits(botUrl string, chatId int, update telegram.Update, repo database.ContentRepositoryInterface, state states.State) states.State {
+	input := strings.TrimSpace(update.Message.Text)
+	amount, note, _ := strings.Cut(input, " ")
+	note = strings.TrimSpace(note)
+
+	validator := validation.NewValidator()
+	result := validator.ValidateCredits(amount)
+	if result.IsValid {
+		result = validator.ValidateFeedbackNote(note)
+	}
+	if !result.IsValid {
+		telegram.SendMessage(botUrl, chatId, "❌ <b>Ошибка валидации</b>\n\n"+strings.Join(result.GetErrorMessages(), "\n")+
+			"\n\n🔄 Попробуйте еще раз:", telegram.CreateStepKeyboard())
+		return state
+	}
+	credits, _ := strconv.Atoi(amount)
+
+	userId := state.GetID()
+	entry, err := repo.GrantCredits(userId, credits, chatId, note)
+	if err != nil {
+		logger.AdminError(chatId, "Начисление кредитов пользователю %d: %v", userId, err)
+		return sendErrorMessage(botUrl, chatId, 0, repo, err)
+	}
+
+	logger.AdminInfo(chatId, "Пользователю %d начислено кредитов: %d", userId, credits)
+	notifyUserAboutCredits(botUrl, userId, entry, repo)
+	return ViewUserCredits(botUrl, chatId, 0, userId, repo)
+}
+
+// reserveRegistrationCredit удерживает кредит за новой заявкой. Сбой журнала
+// не мешает записи: тренер увидит заявку как оплату на месте.
+func reserveRegistrationCredit(chatId int, userId, registrationId uint, repo database.ContentRepositoryInterface) *database.CreditLedger {
+	credit, err := repo.ReserveCredit(userId, registrationId)
+	if err != nil {
+		logger.UserError(chatId, "Резерв кредита за запись %d: %v", registrationId, err)
+		return nil
+	}
+	return credit
+}
+
+// formatReservedCredit дополняет подтверждение записи сведениями о кредите
+func formatReservedCredit(credit *database.CreditLedger) string {
+	if credit == nil {
+		return ""
+	}
+	return fmt.Sprintf("\n\n💳 <b>Зарезервирован 1 кредит, доступно:</b> %d", credit.Balance)
+}
+
+// notifyUserAboutCredits сообщает пользователю о покупке пакета или начислении кредитов
+func notifyUserAboutCredits(botUrl string, userId uint, entry *database.CreditLedger, repo database.ContentRepositoryInterface) {
+	user, _ := repo.GetUserByID(userId)
+	if user == nil || user.ChatId == 0 {
+		return
+	}
+
+	message := fmt.Sprintf("💳 <b>Начислено тренировок: %d</b>\n\n", entry.Delta)
+	if entry.Note != "" {
+		message += "📝 " + telegram.EscapeHTML(entry.Note) + "\n"
+	}
+	message += fmt.Sprintf("💰 <b>Доступно:</b> %d\n\n"+
+		"💡 Кредит списывается автоматически при записи на тренировку.", entry.Balance)
+
+	telegram.SendMessage(botUrl, user.ChatId, message, telegram.CreateBaseKeyboard())
+}
+
+// notifyLowCreditBalance напоминает участнику о продлении, когда кредиты заканчиваются
+func notifyLowCreditBalance(botUrl string, userId uint, balance int, repo database.ContentRepositoryInterface) {
+	if balance > lowCreditBalance {
+		return
+	}
+
+	user, _ := repo.GetUserByID(userId)
+	if user == nil || user.ChatId == 0 {
+		return
+	}
+
+	message := "⚠️ <b>Кредиты закончились</b>\n\n" +
+		"Следующие тренировки можно будет оплатить на месте или купить новый пакет у администратора."
+	if balance > 0 {
+		message = fmt.Sprintf("⏳ <b>Осталось тренировок: %d</b>\n\n"+
+			"💡 Чтобы не прерывать занятия, купите новый пакет у администратора.", balance)
+	}
+
+	telegram.SendMessage(botUrl, user.ChatId, message, telegram.CreateBaseKeyboard())
+}
+
+// formatUserCredits описывает баланс пользователя и последние операции
+func formatUserCredits(user *database.User, repo database.ContentRepositoryInterface, loc *time.Location) (string, error) {
+	balance, err := repo.GetCreditBalance(user.ID)
+	if err != nil {
+		return "", err
+	}
+
+	ledger, err := repo.GetCreditLedger(user.ID, creditLedgerLimit)
+	if err != nil {
+		return "", err
+	}
+
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("💰 <b>Доступно:</b> %d\n", balance.Available))
+	if balance.Reserved > 0 {
+		builder.WriteString(fmt.Sprintf("🔒 <b>В резерве по заявкам:</b> %d\n", balance.Reserved))
+	}
+
+	if len(ledger) == 0 {
+		builder.WriteString("\n📭 Операций с кредитами пока не было.\n")
+		return builder.String(), nil
+	}
+
+	builder.WriteString("\n📜 <b>Последние операции:</b>\n")
+	for _, entry := range ledger {
+		builder.WriteString(fmt.Sprintf("%s %s — %s → %d\n",
+			timefmt.Short(entry.CreatedAt, loc), formatCreditDelta(entry.Delta), formatCreditEntry(&entry, loc, repo), entry.Balance))
+	}
+
+	return builder.String(), nil
+}
+
+// formatCreditEntry описывает операцию журнала кредитов
+func formatCreditEntry(entry *database.CreditLedger, loc *time.Location, repo database.ContentRepositoryInterface) string {
+	var text string
+	switch entry.Kind {
+	case database.CreditKindPurchase:
+		text = "🎟 Покупка пакета"
+	case database.CreditKindGrant:
+		text = "🎁 Начисление"
+	case database.CreditKindReserve:
+		text = "🔒 Резерв за заявку"
+	case database.CreditKindConsume:
+		text = "✅ Тренировка"
+	case database.CreditKindRefund:
+		text = "↩️ Возврат"
+	case database.CreditKindForfeit:
+		text = "⏰ Поздняя отмена"
+	default:
+		text = entry.Kind
+	}
+
+	if entry.RegistrationID != nil {
+		if registration, _ := repo.GetTrainingRegistrationByID(*entry.RegistrationID); registration != nil {
+			if training, _ := repo.GetTrainingById(registration.TrainingID); training != nil {
+				text += " " + timefmt.Short(training.StartTime, loc)
+			}
+		}
+	}
+	if entry.Note != "" {
+		text += " (" + telegram.EscapeHTML(entry.Note) + ")"
+	}
+
+	return text
+}
+
+// formatCreditDelta выводит изменение баланса со знаком
+func formatCreditDelta(delta int) string {
+	if delta > 0 {
+		return fmt.Sprintf("+%d", delta)
+	}
+	return strconv.Itoa(delta)
+}
@@ -0,0 +1,271 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"x.localhost/rvabot/internal/database"
+	"x.localhost/rvabot/internal/logger"
+	"x.localhost/rvabot/internal/states"
+	"x.localhost/rvabot/internal/telegram"
+	"x.localhost/rvabot/internal/timefmt"
+	"x.localhost/rvabot/internal/validation"
+)
+
+// skipFeedbackInput - ввод, пропускающий необязательный шаг разбора
+const skipFeedbackInput = "-"
+
+// WriteFeedback начинает разбор тренировки для участника
+func WriteFeedback(botUrl string, chatId int, messageId int, registrationId uint, repo database.ContentRepositoryInterface) states.State {
+	if !promptFeedbackStrengths(botUrl, chatId, messageId, registrationId, repo) {
+		return states.SetStartKeyboard()
+	}
+	return states.SetFeedbackStrengths(registrationId)
+}
+
+// promptFeedbackStrengths показывает первый шаг разбора: что получилось
+func promptFeedbackStrengths(botUrl string, chatId int, messageId int, registrationId uint, repo database.ContentRepositoryInterface) bool {
+	registration, training, ok := loadParticipantEntry(botUrl, chatId, messageId, registrationId, repo)
+	if !ok {
+		return false
+	}
+
+	userName := "Неизвестный"
+	if user, _ := repo.GetUserByID(registration.UserID); user != nil {
+		userName = user.Name
+	}
+
+	trackName := "Неизвестная трасса"
+	if track, _ := repo.GetTrackByID(training.TrackID); track != nil {
+		trackName = track.Name
+	}
+
+	message := fmt.Sprintf("📝 <b>Разбор тренировки</b>\n\n"+
+		"👤 <b>Участник:</b> %s\n"+
+		"🏁 <b>Трасса:</b> %s\n"+
+		"📅 <b>Дата и время:</b> %s\n",
+		userName, trackName, timefmt.DateTime(training.StartTime, repo.GetViewerLocation(chatId, training.TrackID)))
+
+	if existing, _ := repo.GetFeedbackByRegistration(registration.ID); existing != nil {
+		message += "\nℹ️ Разбор уже написан — новый заменит его и будет отправлен участнику снова.\n"
+	}
+
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, message+"\n"+
+		"✅ <b>Шаг 1 из 3:</b> Что получилось у участника?\n\n"+
+		"💡 <i>Введите «-», чтобы пропустить.</i>", telegram.CreateStepKeyboard())
+	return true
+}
+
+// promptFeedbackImprovements показывает второй шаг разбора: над чем работать
+func promptFeedbackImprovements(botUrl string, chatId int, messageId int) {
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, "📝 <b>Разбор тренировки</b>\n\n"+
+		"🎯 <b>Шаг 2 из 3:</b> Над чем участнику стоит поработать?\n\n"+
+		"💡 <i>Введите «-», чтобы пропустить.</i>", telegram.CreateStepKeyboard())
+}
+
+// promptFeedbackVideo показывает последний шаг разбора: ссылка на видео
+func promptFeedbackVideo(botUrl string, chatId int, messageId int) {
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, "📝 <b>Разбор тренировки</b>\n\n"+
+		"🎬 <b>Шаг 3 из 3:</b> Отправьте ссылку на видеоразбор.\n\n"+
+		"💡 <i>Введите «-», если видео нет.</i>", telegram.CreateStepKeyboard())
+}
+
+// SetFeedbackStrengths сохраняет, что получилось у участника, и переходит к следующему шагу
+func SetFeedbackStrengths(botUrl string, chatId int, update telegram.Update, repo database.ContentRepositoryInterface, state states.State) states.State {
+	strengths, ok := readFeedbackNote(botUrl, chatId, update.Message.Text)
+	if !ok {
+		return state
+	}
+
+	promptFeedbackImprovements(botUrl, chatId, 0)
+	return states.SetFeedbackImprovements(state.GetID()).SetString("strengths", strengths)
+}
+
+// SetFeedbackImprovements сохраняет, над чем работать участнику, и запрашивает видео
+func SetFeedbackImprovements(botUrl string, chatId int, update telegram.Update, repo database.ContentRepositoryInterface, state states.State) states.State {
+	improvements, ok := readFeedbackNote(botUrl, chatId, update.Message.Text)
+	if !ok {
+		return state
+	}
+
+	if improvements == "" && state.GetString("strengths") == "" {
+		telegram.SendMessage(botUrl, chatId, "❌ <b>Разбор пуст</b>\n\n"+
+			"Опишите, что получилось или над чем работать, хотя бы в одном из шагов.", telegram.CreateStepKeyboard())
+		return state
+	}
+
+	promptFeedbackVideo(botUrl, chatId, 0)
+	return states.SetFeedbackVideo(state.GetID()).
+		SetString("strengths", state.GetString("strengths")).
+		SetString("improvements", improvements)
+}
+
+// SetFeedbackVideo сохраняет разбор со ссылкой на видео и отправляет его участнику
+func SetFeedbackVideo(botUrl string, chatId int, update telegram.Update, repo database.ContentRepositoryInterface, state states.State) states.State {
+	videoURL := strings.TrimSpace(update.Message.Text)
+	if videoURL == skipFeedbackInput {
+		videoURL = ""
+	} else if result := validation.NewValidator().ValidateVideoURL(videoURL); !result.IsValid {
+		telegram.SendMessage(botUrl, chatId, "❌ <b>Ошибка валидации</b>\n\n"+strings.Join(result.GetErrorMessages(), "\n")+
+			"\n\n🔄 Попробуйте еще раз:", telegram.CreateStepKeyboard())
+		return state
+	}
+
+	registration, training, ok := loadParticipantEntry(botUrl, chatId, 0, state.GetID(), repo)
+	if !ok {
+		return states.SetStartKeyboard()
+	}
+
+	feedback := &database.TrainingFeedback{
+		RegistrationID: registration.ID,
+		Strengths:      state.GetString("strengths"),
+		Improvements:   state.GetString("improvements"),
+		VideoURL:       videoURL,
+	}
+	if err := repo.SaveTrainingFeedback(feedback); err != nil {
+		logger.UserError(chatId, "Разбор записи %d: %v", registration.ID, err)
+		return sendErrorMessage(botUrl, chatId, 0, repo, err)
+	}
+
+	message := "✅ <b>Разбор сохранен и отправлен участнику</b>"
+	if !deliverTrainingFeedback(botUrl, feedback, registration, training, repo) {
+		message = "✅ <b>Разбор сохранен</b>\n\n" +
+			"📭 Отправить его участнику не удалось — он увидит разбор в разделе «Разборы тренировок»."
+	}
+
+	logger.UserInfo(chatId, "Разбор записи %d сохранен", registration.ID)
+	telegram.SendMessage(botUrl, chatId, message, telegram.CreateBackToMenuKeyboard(fmt.Sprintf("attendanceChecklist_%d", training.ID)))
+	return states.SetStartKeyboard()
+}
+
+// readFeedbackNote читает заметку разбора; «-» означает пропуск шага.
+// При ошибке сообщение уже отправлено.
+func readFeedbackNote(botUrl string, chatId int, text string) (string, bool) {
+	note := strings.TrimSpace(text)
+	if note == skipFeedbackInput {
+		return "", true
+	}
+
+	if result := validation.NewValidator().ValidateFeedbackNote(note); !result.IsValid {
+		telegram.SendMessage(botUrl, chatId, "❌ <b>Ошибка валидации</b>\n\n"+strings.Join(result.GetErrorMessages(), "\n")+
+			"\n\n🔄 Попробуйте еще раз:", telegram.CreateStepKeyboard())
+		return "", false
+	}
+	return note, true
+}
+
+// deliverTrainingFeedback отправляет разбор участнику и отмечает доставку
+func deliverTrainingFeedback(botUrl string, feedback *database.TrainingFeedback, registration *database.TrainingRegistration, training *database.Training, repo database.ContentRepositoryInterface) bool {
+	user, _ := repo.GetUserByID(registration.UserID)
+	if user == nil || user.ChatId == 0 {
+		return false
+	}
+
+	message := "📝 <b>Тренер прислал разбор тренировки</b>\n\n" + formatTrainingFeedback(feedback, training, user.ChatId, repo)
+	if err := telegram.SendMessage(botUrl, user.ChatId, message, telegram.CreateBaseKeyboard()); err != nil {
+		logger.UserError(user.ChatId, "Отправка разбора %d: %v", feedback.ID, err)
+		return false
+	}
+
+	if err := repo.MarkFeedbackDelivered(feedback.ID); err != nil {
+		logger.UserError(user.ChatId, "Отметка отправки разбора %d: %v", feedback.ID, err)
+	}
+	return true
+}
+
+// ViewMyFeedback показывает разборы прошедших тренировок пользователя
+func ViewMyFeedback(botUrl string, chatId int, messageId int, repo database.ContentRepositoryInterface) states.State {
+	user, err := repo.GetUserByChatId(chatId)
+	if err != nil || user == nil {
+		telegram.SendOrEditMessage(botUrl, chatId, messageId, "❌ <b>Пользователь не найден</b>\n\n"+
+			"🔍 Сначала зарегистрируйтесь в системе.", telegram.CreateBaseKeyboard())
+		return states.SetStartKeyboard()
+	}
+
+	feedback, err := repo.GetUserFeedback(user.ID)
+	if err != nil {
+		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
+	}
+
+	if len(feedback) == 0 {
+		telegram.SendOrEditMessage(botUrl, chatId, messageId, "📝 <b>Разборы тренировок</b>\n\n"+
+			"📭 Пока нет разборов.\n"+
+			"🏁 Тренер пишет разбор после тренировки.", telegram.CreateBaseKeyboard())
+		return states.SetStartKeyboard()
+	}
+
+	labels := make(map[uint]string)
+	for _, f := range feedback {
+		labels[f.ID] = "Тренировка"
+		registration, _ := repo.GetTrainingRegistrationByID(f.RegistrationID)
+		if registration == nil {
+			continue
+		}
+		if training, _ := repo.GetTrainingById(registration.TrainingID); training != nil {
+			label := timefmt.Date(training.StartTime, repo.GetViewerLocation(chatId, training.TrackID))
+			if track, _ := repo.GetTrackByID(training.TrackID); track != nil {
+				label += ", " + track.Name
+			}
+			labels[f.ID] = label
+		}
+	}
+
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, fmt.Sprintf("📝 <b>Разборы тренировок</b>\n\n"+
+		"📋 Всего разборов: %d\n\n"+
+		"💡 Выберите тренировку, чтобы открыть разбор.", len(feedback)), telegram.CreateMyFeedbackKeyboard(feedback, labels))
+	return states.SetStartKeyboard()
+}
+
+// ViewFeedback показывает пользователю один разбор тренировки
+func ViewFeedback(botUrl string, chatId int, messageId int, feedbackId uint, repo database.ContentRepositoryInterface) states.State {
+	feedback, _ := repo.GetTrainingFeedbackByID(feedbackId)
+	var registration *database.TrainingRegistration
+	if feedback != nil {
+		registration, _ = repo.GetTrainingRegistrationByID(feedback.RegistrationID)
+	}
+	user, _ := repo.GetUserByChatId(chatId)
+	if registration == nil || user == nil || registration.UserID != user.ID {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Разбор не найден</b>", telegram.CreateBackToMenuKeyboard("myFeedback"))
+		return states.SetStartKeyboard()
+	}
+
+	training, _ := repo.GetTrainingById(registration.TrainingID)
+	if training == nil {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Тренировка не найдена</b>", telegram.CreateBackToMenuKeyboard("myFeedback"))
+		return states.SetStartKeyboard()
+	}
+
+	telegram.EditMessage(botUrl, chatId, messageId, "📝 <b>Разбор тренировки</b>\n\n"+formatTrainingFeedback(feedback, training, chatId, repo),
+		telegram.CreateBackToMenuKeyboard("myFeedback"))
+	return states.SetStartKeyboard()
+}
+
+// formatTrainingFeedback выводит разбор с данными тренировки
+func formatTrainingFeedback(feedback *database.TrainingFeedback, training *database.Training, chatId int, repo database.ContentRepositoryInterface) string {
+	trackName := "Неизвестная трасса"
+	if track, _ := repo.GetTrackByID(training.TrackID); track != nil {
+		trackName = track.Name
+	}
+
+	trainerName := "Неизвестный тренер"
+	if trainer, _ := repo.GetTrainerByID(training.TrainerID); trainer != nil {
+		trainerName = trainer.Name
+	}
+
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("🏁 <b>Трасса:</b> %s\n"+
+		"👨‍🏫 <b>Тренер:</b> %s\n"+
+		"📅 <b>Дата и время:</b> %s\n",
+		trackName, trainerName, timefmt.DateTime(training.StartTime, repo.GetViewerLocation(chatId, training.TrackID))))
+
+	if feedback.Strengths != "" {
+		builder.WriteString("\n✅ <b>Что получилось:</b>\n" + telegram.EscapeHTML(feedback.Strengths) + "\n")
+	}
+	if feedback.Improvements != "" {
+		builder.WriteString("\n🎯 <b>Над чем работать:</b>\n" + telegram.EscapeHTML(feedback.Improvements) + "\n")
+	}
+	if feedback.VideoURL != "" {
+		builder.WriteString(fmt.Sprintf("\n🎬 <a href=\"%s\">Видеоразбор</a>\n", telegram.EscapeHTML(feedback.VideoURL)))
+	}
+	return builder.String()
+}
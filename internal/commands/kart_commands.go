@@ -0,0 +1,394 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"x.localhost/rvabot/internal/database"
+	"x.localhost/rvabot/internal/errors"
+	"x.localhost/rvabot/internal/logger"
+	"x.localhost/rvabot/internal/states"
+	"x.localhost/rvabot/internal/telegram"
+	"x.localhost/rvabot/internal/timefmt"
+	"x.localhost/rvabot/internal/validation"
+)
+
+// kartServiceInterval - после такой наработки карт пора отправлять на ТО
+const kartServiceInterval = 20 * time.Hour
+
+// ViewTrackKarts показывает парк картов трассы с наработкой
+func ViewTrackKarts(botUrl string, chatId int, messageId int, trackId uint, repo database.ContentRepositoryInterface) states.State {
+	if !database.IsAdmin(chatId, repo) {
+		return SendAccessDeniedMessage(botUrl, chatId, messageId)
+	}
+
+	track, err := repo.GetTrackByID(trackId)
+	if err != nil || track == nil {
+		telegram.SendOrEditMessage(botUrl, chatId, messageId, "❌ Трасса не найдена.", telegram.CreateBackToTracksMenuKeyboard())
+		return states.SetAdminKeyboard()
+	}
+
+	karts, err := repo.GetTrackKarts(trackId)
+	if err != nil {
+		telegram.SendOrEditMessage(botUrl, chatId, messageId, "❌ <b>Ошибка загрузки парка картов</b>\n\n"+
+			"Попробуйте позже.", telegram.CreateBackToTracksMenuKeyboard())
+		return states.SetAdminKeyboard()
+	}
+
+	usage, err := repo.GetKartUsage(trackId)
+	if err != nil {
+		usage = map[uint]database.KartUsage{}
+	}
+
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("🏎 <b>Парк картов трассы %s</b>\n\n", track.Name))
+	if len(karts) == 0 {
+		builder.WriteString("📭 Карты не добавлены. Пока парк пуст, число мест задается лимитом тренировки.\n")
+	}
+
+	categoryName := carCategoryNames(repo)
+	for i, kart := range karts {
+		if i == 0 || !database.SameCarCategory(kart.CarCategoryID, karts[i-1].CarCategoryID) {
+			if i > 0 {
+				builder.WriteString("\n")
+			}
+			builder.WriteString(fmt.Sprintf("🚗 <b>%s</b> — в строю %d из %d\n", telegram.EscapeHTML(categoryName(kart.CarCategoryID)),
+				countKartsInService(karts, kart.CarCategoryID), countKarts(karts, kart.CarCategoryID)))
+		}
+		builder.WriteString("   " + formatKart(kart, usage[kart.ID]) + "\n")
+	}
+
+	if len(karts) > 0 {
+		builder.WriteString("\n💡 Мест на тренировке не больше исправных картов ее категории. Возврат из ремонта отмечается как ТО.")
+	}
+
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, builder.String(), telegram.CreateTrackKartsKeyboard(trackId, karts))
+	return states.SetAdminKeyboard()
+}
+
+// formatTrainingCapacity выводит лимит тренировки и, если он меньше, число мест по картам
+func formatTrainingCapacity(training *database.Training, repo database.ContentRepositoryInterface) string {
+	text := fmt.Sprintf("%d", training.MaxParticipants)
+	if capacity, err := repo.GetTrainingCapacity(training); err == nil && capacity < training.MaxParticipants {
+		text += fmt.Sprintf(" (по исправным картам: %d)", capacity)
+	}
+	return text
+}
+
+// formatKart описывает карт: статус, общую наработку и наработку после ТО
+func formatKart(kart database.Kart, usage database.KartUsage) string {
+	if kart.Status == database.KartStatusMaintenance {
+		return fmt.Sprintf("🔧 №%s — в ремонте, наработка %s", kart.Number, formatKartHours(usage.Total))
+	}
+
+	text := fmt.Sprintf("✅ №%s — наработка %s, после ТО %s", kart.Number, formatKartHours(usage.Total), formatKartHours(usage.SinceService))
+	if usage.SinceService >= kartServiceInterval {
+		text += " ⚠️ пора на ТО"
+	}
+	return text
+}
+
+// formatKartHours выводит наработку в часах
+func formatKartHours(d time.Duration) string {
+	return fmt.Sprintf("%.1f ч", d.Hours())
+}
+
+// countKarts возвращает число картов категории в парке
+func countKarts(karts []database.Kart, carCategoryID *uint) int {
+	count := 0
+	for _, kart := range karts {
+		if database.SameCarCategory(kart.CarCategoryID, carCategoryID) {
+			count++
+		}
+	}
+	return count
+}
+
+// countKartsInService возвращает число исправных картов категории
+func countKartsInService(karts []database.Kart, carCategoryID *uint) int {
+	count := 0
+	for _, kart := range karts {
+		if database.SameCarCategory(kart.CarCategoryID, carCategoryID) && kart.Status == database.KartStatusOK {
+			count++
+		}
+	}
+	return count
+}
+
+// AddKart запрашивает категорию и номер нового карта
+func AddKart(botUrl string, chatId int, messageId int, trackId uint, repo database.ContentRepositoryInterface) states.State {
+	if !database.IsAdmin(chatId, repo) {
+		return SendAccessDeniedMessage(botUrl, chatId, messageId)
+	}
+
+	promptKart(botUrl, chatId, messageId)
+	return states.SetSetKart(trackId)
+}
+
+// promptKart показывает шаг ввода нового карта
+func promptKart(botUrl string, chatId int, messageId int) {
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, "🏎 <b>Новый карт</b>\n\n"+
+		"📝 Введите категорию и бортовой номер через пробел.\n"+
+		"Категория выбирается из справочника «Категории машин».\n\n"+
+		"💡 <i>Пример: KZ 7</i>", telegram.CreateStepKeyboard())
+}
+
+// SetKart добавляет карт в парк трассы
+func SetKart(botUrl string, chatId int, update telegram.Update, repo database.ContentRepositoryInterface, state states.State) states.State {
+	if !database.IsAdmin(chatId, repo) {
+		return SendAccessDeniedMessage(botUrl, chatId, 0)
+	}
+
+	fields := strings.Fields(update.Message.Text)
+	if len(fields) < 2 {
+		telegram.SendMessage(botUrl, chatId, "❌ <b>Неверный формат</b>\n\n"+
+			"📝 Введите категорию и номер через пробел.\n"+
+			"💡 <i>Пример: KZ 7</i>", telegram.CreateStepKeyboard())
+		return state
+	}
+	category, ok := resolveCarCategory(botUrl, chatId, strings.Join(fields[:len(fields)-1], " "), repo)
+	if !ok {
+		return state
+	}
+	number := fields[len(fields)-1]
+
+	validator := validation.NewValidator()
+	if result := validator.ValidateKartNumber(number); !result.IsValid {
+		telegram.SendMessage(botUrl, chatId, "❌ <b>Ошибка валидации</b>\n\n"+strings.Join(result.GetErrorMessages(), "\n")+
+			"\n\n🔄 Попробуйте еще раз:", telegram.CreateStepKeyboard())
+		return state
+	}
+
+	trackId := state.GetID()
+	kart := &database.Kart{TrackID: trackId, CarCategoryID: &category.ID, Number: number}
+	if err := repo.CreateKart(kart); err != nil {
+		logger.AdminError(chatId, "Добавление карта %s на трассу %d: %v", number, trackId, err)
+		telegram.SendMessage(botUrl, chatId, "❌ <b>Не удалось добавить карт</b>\n\n"+
+			errors.HandleError(err), telegram.CreateStepKeyboard())
+		return state
+	}
+
+	logger.AdminInfo(chatId, "Карт %s (%s) добавлен на трассу %d", number, category.Name, trackId)
+	refreshFleetWaitlists(botUrl, trackId, kart.CarCategoryID, repo)
+	return ViewTrackKarts(botUrl, chatId, 0, trackId, repo)
+}
+
+// ToggleKartStatus переводит карт в ремонт или возвращает в строй после ТО
+func ToggleKartStatus(botUrl string, chatId int, messageId int, kartId uint, repo database.ContentRepositoryInterface) states.State {
+	if !database.IsAdmin(chatId, repo) {
+		return SendAccessDeniedMessage(botUrl, chatId, messageId)
+	}
+
+	kart, err := repo.GetKartByID(kartId)
+	if err != nil || kart == nil {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Карт не найден</b>", telegram.CreateBackToTracksMenuKeyboard())
+		return states.SetAdminKeyboard()
+	}
+
+	status := database.KartStatusMaintenance
+	if kart.Status == database.KartStatusMaintenance {
+		status = database.KartStatusOK
+	}
+
+	if _, err := repo.SetKartStatus(kartId, status); err != nil {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Ошибка изменения статуса карта</b>\n\n"+
+			"Попробуйте позже.", telegram.CreateBackToTrackKartsKeyboard(kart.TrackID))
+		return states.SetAdminKeyboard()
+	}
+
+	logger.AdminInfo(chatId, "Карт %d (№%s): %s", kartId, kart.Number, status)
+	refreshFleetWaitlists(botUrl, kart.TrackID, kart.CarCategoryID, repo)
+	return ViewTrackKarts(botUrl, chatId, messageId, kart.TrackID, repo)
+}
+
+// DeleteKart списывает карт из парка трассы
+func DeleteKart(botUrl string, chatId int, messageId int, kartId uint, repo database.ContentRepositoryInterface) states.State {
+	if !database.IsAdmin(chatId, repo) {
+		return SendAccessDeniedMessage(botUrl, chatId, messageId)
+	}
+
+	kart, err := repo.GetKartByID(kartId)
+	if err != nil || kart == nil {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Карт не найден</b>", telegram.CreateBackToTracksMenuKeyboard())
+		return states.SetAdminKeyboard()
+	}
+
+	if err := repo.DeleteKart(kartId); err != nil {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Ошибка списания карта</b>\n\n"+
+			"Попробуйте позже.", telegram.CreateBackToTrackKartsKeyboard(kart.TrackID))
+		return states.SetAdminKeyboard()
+	}
+
+	logger.AdminInfo(chatId, "Карт %d (№%s) списан с трассы %d", kartId, kart.Number, kart.TrackID)
+	// Без картов категории места снова задаются лимитом тренировки
+	refreshFleetWaitlists(botUrl, kart.TrackID, kart.CarCategoryID, repo)
+	return ViewTrackKarts(botUrl, chatId, messageId, kart.TrackID, repo)
+}
+
+// refreshFleetWaitlists предлагает места листу ожидания, если изменение
+// парка освободило места на предстоящих тренировках
+func refreshFleetWaitlists(botUrl string, trackId uint, carCategoryID *uint, repo database.ContentRepositoryInterface) {
+	trainings, err := repo.GetUpcomingFleetTrainings(trackId, carCategoryID)
+	if err != nil {
+		return
+	}
+
+	for _, training := range trainings {
+		promoteWaitlist(botUrl, training.ID, repo)
+	}
+}
+
+// ViewKartAssignments показывает подтвержденных участников тренировки и их карты
+func ViewKartAssignments(botUrl string, chatId int, messageId int, trainingId uint, repo database.ContentRepositoryInterface) states.State {
+	training, _ := repo.GetTrainingById(trainingId)
+	if training == nil {
+		telegram.SendOrEditMessage(botUrl, chatId, messageId, "❌ <b>Тренировка не найдена</b>", telegram.CreateBaseKeyboard())
+		return states.SetStartKeyboard()
+	}
+	if !database.IsAdmin(chatId, repo) && !isTrainingTrainer(chatId, training, repo) {
+		return SendAccessDeniedMessage(botUrl, chatId, messageId)
+	}
+
+	registrations, err := repo.GetTrainingRegistrationsByTrainingID(trainingId)
+	if err != nil {
+		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
+	}
+	var confirmed []database.TrainingRegistration
+	for _, reg := range registrations {
+		if database.CanAssignKart(reg.Status) {
+			confirmed = append(confirmed, reg)
+		}
+	}
+
+	karts, err := repo.GetTrackKarts(training.TrackID)
+	if err != nil {
+		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
+	}
+	kartNumbers := make(map[uint]string, len(karts))
+	inMaintenance := make(map[uint]bool)
+	for _, kart := range karts {
+		kartNumbers[kart.ID] = kart.Number
+		inMaintenance[kart.ID] = kart.Status == database.KartStatusMaintenance
+	}
+
+	trackName := "❓ Неизвестная"
+	if track, _ := repo.GetTrackByID(training.TrackID); track != nil {
+		trackName = track.Name
+	}
+
+	var builder strings.Builder
+	builder.WriteString("🏎 <b>Карты участников</b>\n\n")
+	builder.WriteString(fmt.Sprintf("🏁 <b>Трасса:</b> %s\n", trackName))
+	builder.WriteString(fmt.Sprintf("🚗 <b>Категория:</b> %s\n", telegram.EscapeHTML(carCategoryName(training.CarCategoryID, repo))))
+	builder.WriteString(fmt.Sprintf("📅 <b>Дата:</b> %s\n\n", timefmt.DateTime(training.StartTime, repo.GetViewerLocation(chatId, training.TrackID))))
+
+	if countKarts(karts, training.CarCategoryID) == 0 {
+		builder.WriteString("ℹ️ Для этой категории на трассе не заведены карты. Их добавляет администратор в разделе «Трассы».\n\n")
+	}
+
+	if len(confirmed) == 0 {
+		builder.WriteString("📭 <b>Нет подтвержденных участников</b>")
+	}
+
+	names := registrationNames(confirmed, repo)
+	for i, reg := range confirmed {
+		kart := "не назначен"
+		if reg.KartID != nil {
+			kart = "списан"
+			if number, ok := kartNumbers[*reg.KartID]; ok {
+				kart = "№" + number
+				if inMaintenance[*reg.KartID] {
+					kart += " ⚠️ в ремонте"
+				}
+			}
+		}
+		builder.WriteString(fmt.Sprintf("%d. %s — %s\n", i+1, names[reg.ID], kart))
+	}
+	if len(confirmed) > 0 {
+		builder.WriteString("\n💡 Нажмите на участника, чтобы назначить карт.")
+	}
+
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, builder.String(),
+		telegram.CreateKartAssignmentsKeyboard(trainingId, confirmed, names, kartNumbers))
+	return states.SetStartKeyboard()
+}
+
+// SelectKartAssignment показывает свободные карты для участника
+func SelectKartAssignment(botUrl string, chatId int, messageId int, registrationId uint, repo database.ContentRepositoryInterface) states.State {
+	registration, training, ok := loadKartRegistration(botUrl, chatId, messageId, registrationId, repo)
+	if !ok {
+		return states.SetStartKeyboard()
+	}
+
+	karts, err := repo.GetAvailableKarts(training, registrationId)
+	if err != nil {
+		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
+	}
+
+	userName := registrationName(registration, repo)
+
+	current := "не назначен"
+	if registration.KartID != nil {
+		current = "списан"
+		if kart, _ := repo.GetKartByID(*registration.KartID); kart != nil {
+			current = "№" + kart.Number
+		}
+	}
+
+	message := fmt.Sprintf("🏎 <b>Карт для %s</b>\n\n"+
+		"📌 <b>Сейчас:</b> %s\n\n", userName, current)
+	if len(karts) == 0 {
+		message += "📭 Свободных исправных картов нет."
+	} else {
+		message += "👇 Выберите свободный карт:"
+	}
+
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, message,
+		telegram.CreateKartSelectionKeyboard(training.ID, karts, registration.KartID != nil))
+	return states.SetAssignKart(registrationId)
+}
+
+// AssignKart назначает выбранный карт участнику из состояния; kartId 0 снимает назначение
+func AssignKart(botUrl string, chatId int, messageId int, kartId uint, repo database.ContentRepositoryInterface, state states.State) states.State {
+	if state.Type != states.StateAssignKart || state.GetID() == 0 {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Участник не выбран</b>\n\n"+
+			"Откройте список картов тренировки заново.", telegram.CreateBaseKeyboard())
+		return states.SetStartKeyboard()
+	}
+
+	registration, _, ok := loadKartRegistration(botUrl, chatId, messageId, state.GetID(), repo)
+	if !ok {
+		return states.SetStartKeyboard()
+	}
+
+	if _, err := repo.AssignKart(registration.ID, kartId); err != nil {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ "+errors.HandleError(err),
+			telegram.CreateBackToMenuKeyboard(fmt.Sprintf("kartAssignments_%d", registration.TrainingID)))
+		return states.SetStartKeyboard()
+	}
+
+	logger.UserInfo(chatId, "Запись %d: назначен карт %d", registration.ID, kartId)
+	return ViewKartAssignments(botUrl, chatId, messageId, registration.TrainingID, repo)
+}
+
+// loadKartRegistration загружает запись и тренировку для назначения карта
+// и проверяет, что это делает администратор или тренер тренировки
+func loadKartRegistration(botUrl string, chatId int, messageId int, registrationId uint, repo database.ContentRepositoryInterface) (*database.TrainingRegistration, *database.Training, bool) {
+	registration, _ := repo.GetTrainingRegistrationByID(registrationId)
+	var training *database.Training
+	if registration != nil {
+		training, _ = repo.GetTrainingById(registration.TrainingID)
+	}
+	if registration == nil || training == nil {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Запись не найдена</b>", telegram.CreateBaseKeyboard())
+		return nil, nil, false
+	}
+
+	if !database.IsAdmin(chatId, repo) && !isTrainingTrainer(chatId, training, repo) {
+		SendAccessDeniedMessage(botUrl, chatId, messageId)
+		return nil, nil, false
+	}
+
+	return registration, training, true
+}
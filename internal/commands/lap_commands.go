@@ -0,0 +1,352 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"x.localhost/rvabot/internal/database"
+	"x.localhost/rvabot/internal/logger"
+	"x.localhost/rvabot/internal/states"
+	"x.localhost/rvabot/internal/telegram"
+	"x.localhost/rvabot/internal/timefmt"
+	"x.localhost/rvabot/internal/validation"
+)
+
+// maxLapsPerMessage ограничивает число кругов в одном сообщении тренера
+const maxLapsPerMessage = 50
+
+// maxProgressShown ограничивает число тренировок в истории прогресса на трассе
+const maxProgressShown = 10
+
+// EnterLapTimes запрашивает у тренера время кругов участника тренировки
+func EnterLapTimes(botUrl string, chatId int, messageId int, registrationId uint, repo database.ContentRepositoryInterface) states.State {
+	if !promptLapTimes(botUrl, chatId, messageId, registrationId, repo) {
+		return states.SetStartKeyboard()
+	}
+	return states.SetEnterLapTimes(registrationId)
+}
+
+// promptLapTimes показывает шаг ввода времени кругов с уже введенными кругами
+func promptLapTimes(botUrl string, chatId int, messageId int, registrationId uint, repo database.ContentRepositoryInterface) bool {
+	registration, training, ok := loadParticipantEntry(botUrl, chatId, messageId, registrationId, repo)
+	if !ok {
+		return false
+	}
+
+	trackName := "Неизвестная трасса"
+	if track, _ := repo.GetTrackByID(training.TrackID); track != nil {
+		trackName = track.Name
+	}
+
+	message := fmt.Sprintf("⏱ <b>Время кругов</b>\n\n"+
+		"👤 <b>Участник:</b> %s\n"+
+		"🏁 <b>Трасса:</b> %s\n"+
+		"🚗 <b>Категория:</b> %s\n"+
+		"📅 <b>Дата и время:</b> %s\n",
+		telegram.EscapeHTML(registrationName(registration, repo)), trackName, carCategoryName(training.CarCategoryID, repo), timefmt.DateTime(training.StartTime, repo.GetViewerLocation(chatId, training.TrackID)))
+
+	if laps, err := repo.GetTrainingLapRecords(training.ID, registration.UserID, registration.ParticipantID); err == nil && len(laps) > 0 {
+		message += fmt.Sprintf("📋 <b>Уже введено кругов:</b> %d, лучший %s\n", len(laps), timefmt.LapTime(bestLapTime(laps)))
+	}
+
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, message+"\n"+
+		"📝 Введите время кругов в формате М:СС.ммм через пробел или каждый с новой строки.\n"+
+		"Круги нумеруются по порядку вслед за уже введенными.\n\n"+
+		"💡 <i>Пример: 1:02.345 1:01.987</i>", telegram.CreateStepKeyboard())
+	return true
+}
+
+// SetLapTimes сохраняет время кругов, введенное тренером
+func SetLapTimes(botUrl string, chatId int, update telegram.Update, repo database.ContentRepositoryInterface, state states.State) states.State {
+	inputs := strings.Fields(update.Message.Text)
+	if len(inputs) > maxLapsPerMessage {
+		telegram.SendMessage(botUrl, chatId, fmt.Sprintf("❌ <b>Слишком много кругов</b>\n\n"+
+			"За одно сообщение можно ввести не больше %d кругов.", maxLapsPerMessage), telegram.CreateStepKeyboard())
+		return state
+	}
+
+	validator := validation.NewValidator()
+	var lapTimes []int
+	var errorMsg strings.Builder
+	for _, input := range inputs {
+		lapTime, result := validator.ParseLapTime(input)
+		if !result.IsValid {
+			for _, err := range result.Errors {
+				errorMsg.WriteString(fmt.Sprintf("• %s\n", err.Message))
+			}
+			continue
+		}
+		lapTimes = append(lapTimes, lapTime)
+	}
+
+	if len(inputs) == 0 || errorMsg.Len() > 0 {
+		telegram.SendMessage(botUrl, chatId, "❌ <b>Неверное время кругов</b>\n\n"+errorMsg.String()+
+			"\n💡 <i>Пример: 1:02.345 1:01.987</i>", telegram.CreateStepKeyboard())
+		return state
+	}
+
+	registration, training, ok := loadParticipantEntry(botUrl, chatId, 0, state.GetID(), repo)
+	if !ok {
+		return states.SetStartKeyboard()
+	}
+
+	previousBest := personalBestOnTrack(registration, training, repo)
+	records, err := repo.AddLapRecords(training.ID, registration.UserID, registration.ParticipantID, lapTimes)
+	if err != nil {
+		logger.UserError(chatId, "Время кругов записи %d: %v", registration.ID, err)
+		return sendErrorMessage(botUrl, chatId, 0, repo, err)
+	}
+
+	// Участник с введенными кругами точно был на тренировке
+	if registration.Status == database.RegistrationStatusConfirmed {
+		if _, err := repo.MarkAttendance(registration.ID, database.RegistrationStatusAttended); err != nil {
+			logger.UserError(chatId, "Отметка посещения записи %d по кругам: %v", registration.ID, err)
+		}
+	}
+
+	sessionBest := bestLapTime(records)
+	message := fmt.Sprintf("✅ <b>Сохранено кругов: %d</b>\n\n"+
+		"🔢 <b>Круги:</b> %d-%d\n"+
+		"⚡ <b>Лучший из введенных:</b> %s\n",
+		len(records), records[0].LapNumber, records[len(records)-1].LapNumber, timefmt.LapTime(sessionBest))
+
+	switch {
+	case previousBest == 0:
+		message += "\n🏁 Первый результат участника на трассе в этой категории."
+	case sessionBest < previousBest:
+		message += fmt.Sprintf("\n🏆 <b>Личный рекорд участника на трассе!</b>\nБыло %s (%s)",
+			timefmt.LapTime(previousBest), formatLapDelta(sessionBest-previousBest))
+		notifyUserAboutPersonalBest(botUrl, registration, training, sessionBest, previousBest, repo)
+	}
+
+	logger.UserInfo(chatId, "Время кругов: запись %d, кругов %d", registration.ID, len(records))
+	telegram.SendMessage(botUrl, chatId, message, telegram.CreateBackToMenuKeyboard(fmt.Sprintf("attendanceChecklist_%d", training.ID)))
+	return states.SetStartKeyboard()
+}
+
+// loadParticipantEntry загружает запись участника и тренировку для ввода кругов
+// или разбора, проверяя права и статус. При ошибке сообщение уже отправлено.
+func loadParticipantEntry(botUrl string, chatId int, messageId int, registrationId uint, repo database.ContentRepositoryInterface) (*database.TrainingRegistration, *database.Training, bool) {
+	registration, _ := repo.GetTrainingRegistrationByID(registrationId)
+	if registration == nil {
+		telegram.SendOrEditMessage(botUrl, chatId, messageId, "❌ <b>Запись не найдена</b>", telegram.CreateBaseKeyboard())
+		return nil, nil, false
+	}
+
+	training, _ := repo.GetTrainingById(registration.TrainingID)
+	if training == nil {
+		telegram.SendOrEditMessage(botUrl, chatId, messageId, "❌ <b>Тренировка не найдена</b>", telegram.CreateBaseKeyboard())
+		return nil, nil, false
+	}
+
+	if !isTrainingTrainer(chatId, training, repo) && !database.IsAdmin(chatId, repo) {
+		telegram.SendOrEditMessage(botUrl, chatId, messageId, "❌ <b>Нет прав</b>", telegram.CreateBaseKeyboard())
+		return nil, nil, false
+	}
+
+	if registration.Status != database.RegistrationStatusConfirmed && registration.Status != database.RegistrationStatusAttended {
+		telegram.SendOrEditMessage(botUrl, chatId, messageId, "ℹ️ <b>Участник не был на тренировке</b>\n\n"+
+			"Круги и разбор вводятся только для подтвержденных участников.",
+			telegram.CreateBackToMenuKeyboard(fmt.Sprintf("attendanceChecklist_%d", training.ID)))
+		return nil, nil, false
+	}
+
+	return registration, training, true
+}
+
+// personalBestOnTrack возвращает лучший круг участника записи на трассе и в
+// категории тренировки; 0, если результатов еще нет
+func personalBestOnTrack(registration *database.TrainingRegistration, training *database.Training, repo database.ContentRepositoryInterface) int {
+	bests, err := repo.GetPersonalBests(registration.UserID, registration.ParticipantID)
+	if err != nil {
+		return 0
+	}
+	for _, best := range bests {
+		if best.TrackID == training.TrackID && database.SameCarCategory(best.CarCategoryID, training.CarCategoryID) {
+			return best.LapTimeMs
+		}
+	}
+	return 0
+}
+
+// notifyUserAboutPersonalBest поздравляет участника с новым лучшим кругом на
+// трассе. Рекорд участника под аккаунтом приходит его представителю.
+func notifyUserAboutPersonalBest(botUrl string, registration *database.TrainingRegistration, training *database.Training, lapTime int, previousBest int, repo database.ContentRepositoryInterface) {
+	user, _ := repo.GetUserByID(registration.UserID)
+	if user == nil || user.ChatId == 0 {
+		return
+	}
+
+	trackName := "Неизвестная трасса"
+	if track, _ := repo.GetTrackByID(training.TrackID); track != nil {
+		trackName = track.Name
+	}
+
+	message := fmt.Sprintf("🏆 <b>Новый личный рекорд!</b>\n\n"+
+		"%s"+
+		"🏁 <b>Трасса:</b> %s\n"+
+		"🚗 <b>Категория:</b> %s\n"+
+		"⏱ <b>Лучший круг:</b> %s\n"+
+		"📉 <b>Улучшение:</b> %s\n",
+		formatRegistrationParticipant(registration, repo), trackName, carCategoryName(training.CarCategoryID, repo), timefmt.LapTime(lapTime), formatLapDelta(lapTime-previousBest))
+
+	telegram.SendMessage(botUrl, user.ChatId, message+"\n📈 Все результаты — в разделе «Мои результаты».", telegram.CreateBaseKeyboard())
+}
+
+// ViewMyResults показывает личные рекорды пользователя по трассам
+func ViewMyResults(botUrl string, chatId int, messageId int, repo database.ContentRepositoryInterface) states.State {
+	user, err := repo.GetUserByChatId(chatId)
+	if err != nil || user == nil {
+		telegram.SendOrEditMessage(botUrl, chatId, messageId, "❌ <b>Пользователь не найден</b>\n\n"+
+			"🔍 Сначала зарегистрируйтесь в системе.", telegram.CreateBaseKeyboard())
+		return states.SetStartKeyboard()
+	}
+
+	bests, err := repo.GetPersonalBests(user.ID, 0)
+	if err != nil {
+		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
+	}
+
+	ratings, err := repo.GetUserRatings(user.ID)
+	if err != nil {
+		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
+	}
+
+	if len(bests) == 0 && len(ratings) == 0 {
+		telegram.SendOrEditMessage(botUrl, chatId, messageId, "📈 <b>Мои результаты</b>\n\n"+
+			formatUserSkillLevel(user, repo)+"\n"+
+			"📭 Пока нет результатов.\n"+
+			"⏱ Время кругов и результаты заездов вводит тренер после тренировки.", telegram.CreateMyResultsKeyboard(nil))
+		return states.SetStartKeyboard()
+	}
+
+	var builder strings.Builder
+	builder.WriteString("📈 <b>Мои результаты</b>\n\n")
+	builder.WriteString(formatUserSkillLevel(user, repo) + "\n")
+	if len(ratings) > 0 {
+		builder.WriteString(formatUserRatings(user, ratings, repo) + "\n")
+	}
+	if len(bests) > 0 {
+		builder.WriteString("🏆 <b>Личные рекорды:</b>\n")
+	}
+
+	var tracks []database.Track
+	var lastTrackId uint
+	for _, best := range bests {
+		if best.TrackID != lastTrackId {
+			lastTrackId = best.TrackID
+			track, _ := repo.GetTrackByID(best.TrackID)
+			if track == nil {
+				track = &database.Track{ID: best.TrackID, Name: "Неизвестная трасса"}
+			}
+			tracks = append(tracks, *track)
+			builder.WriteString(fmt.Sprintf("\n🏁 <b>%s</b>\n", track.Name))
+		}
+		builder.WriteString(fmt.Sprintf("🚗 %s: <b>%s</b> (%s), кругов: %d\n",
+			best.CarCategory, timefmt.LapTime(best.LapTimeMs),
+			timefmt.Date(best.SetAt, repo.GetViewerLocation(chatId, best.TrackID)), best.Laps))
+	}
+	if len(tracks) > 0 {
+		builder.WriteString("\n💡 Выберите трассу, чтобы посмотреть прогресс по тренировкам.")
+	}
+
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, builder.String(), telegram.CreateMyResultsKeyboard(tracks))
+	return states.SetStartKeyboard()
+}
+
+// ViewTrackResults показывает прогресс пользователя на трассе по тренировкам
+func ViewTrackResults(botUrl string, chatId int, messageId int, trackId uint, repo database.ContentRepositoryInterface) states.State {
+	user, err := repo.GetUserByChatId(chatId)
+	if err != nil || user == nil {
+		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
+	}
+
+	progress, err := repo.GetTrackLapProgress(user.ID, trackId)
+	if err != nil {
+		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
+	}
+
+	trackName := "Неизвестная трасса"
+	if track, _ := repo.GetTrackByID(trackId); track != nil {
+		trackName = track.Name
+	}
+
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("📊 <b>Прогресс: %s</b>\n", trackName))
+
+	if len(progress) == 0 {
+		builder.WriteString("\n📭 На этой трассе пока нет результатов.")
+		telegram.EditMessage(botUrl, chatId, messageId, builder.String(), telegram.CreateBackToMenuKeyboard("myResults"))
+		return states.SetStartKeyboard()
+	}
+
+	// Итог по каждой категории: первая тренировка, последняя и лучший круг
+	first := make(map[string]int)
+	last := make(map[string]int)
+	best := make(map[string]int)
+	var categories []string
+	for _, p := range progress {
+		if _, ok := first[p.CarCategory]; !ok {
+			first[p.CarCategory] = p.BestLapMs
+			best[p.CarCategory] = p.BestLapMs
+			categories = append(categories, p.CarCategory)
+		}
+		last[p.CarCategory] = p.BestLapMs
+		if p.BestLapMs < best[p.CarCategory] {
+			best[p.CarCategory] = p.BestLapMs
+		}
+	}
+	for _, category := range categories {
+		builder.WriteString(fmt.Sprintf("\n🚗 <b>%s</b>: 🏆 %s\n", category, timefmt.LapTime(best[category])))
+		if first[category] != last[category] {
+			builder.WriteString(fmt.Sprintf("📉 %s → %s (%s)\n",
+				timefmt.LapTime(first[category]), timefmt.LapTime(last[category]), formatLapDelta(last[category]-first[category])))
+		}
+	}
+
+	shown := progress
+	if len(shown) > maxProgressShown {
+		shown = shown[len(shown)-maxProgressShown:]
+	}
+
+	loc := repo.GetViewerLocation(chatId, trackId)
+	builder.WriteString("\n📅 <b>Лучший круг по тренировкам:</b>\n")
+	previous := make(map[string]int)
+	for _, p := range progress[:len(progress)-len(shown)] {
+		previous[p.CarCategory] = p.BestLapMs
+	}
+	for _, p := range shown {
+		delta := ""
+		if prev, ok := previous[p.CarCategory]; ok {
+			delta = " (" + formatLapDelta(p.BestLapMs-prev) + ")"
+		}
+		previous[p.CarCategory] = p.BestLapMs
+
+		builder.WriteString(fmt.Sprintf("• %s, %s — %s%s, кругов: %d\n",
+			timefmt.Date(p.StartTime, loc), p.CarCategory, timefmt.LapTime(p.BestLapMs), delta, p.Laps))
+	}
+
+	telegram.EditMessage(botUrl, chatId, messageId, builder.String(), telegram.CreateBackToMenuKeyboard("myResults"))
+	return states.SetStartKeyboard()
+}
+
+// bestLapTime возвращает лучшее время среди кругов
+func bestLapTime(laps []database.LapRecord) int {
+	best := 0
+	for _, lap := range laps {
+		if best == 0 || lap.LapTimeMs < best {
+			best = lap.LapTimeMs
+		}
+	}
+	return best
+}
+
+// formatLapDelta выводит разницу времени кругов со знаком, например "−0.450"
+func formatLapDelta(deltaMs int) string {
+	sign := "+"
+	if deltaMs < 0 {
+		sign = "−"
+		deltaMs = -deltaMs
+	}
+	return fmt.Sprintf("%s%d.%03d", sign, deltaMs/1000, deltaMs%1000)
+}
@@ -0,0 +1,388 @@
+package commands
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"x.localhost/rvabot/internal/database"
+	"x.localhost/rvabot/internal/errors"
+	"x.localhost/rvabot/internal/laptiming"
+	"x.localhost/rvabot/internal/logger"
+	"x.localhost/rvabot/internal/states"
+	"x.localhost/rvabot/internal/telegram"
+	"x.localhost/rvabot/internal/timefmt"
+	"x.localhost/rvabot/internal/validation"
+)
+
+// maxLapFileSize - предельный размер CSV-файла логгера
+const maxLapFileSize = 1 << 20
+
+// ImportLapFile запрашивает у тренера CSV-файл логгера с кругами тренировки
+func ImportLapFile(botUrl string, chatId int, messageId int, trainingId uint, repo database.ContentRepositoryInterface) states.State {
+	if !promptLapFile(botUrl, chatId, messageId, trainingId, repo) {
+		return states.SetStartKeyboard()
+	}
+	return states.SetImportLapFile(trainingId)
+}
+
+// promptLapFile показывает шаг загрузки файла логгера
+func promptLapFile(botUrl string, chatId int, messageId int, trainingId uint, repo database.ContentRepositoryInterface) bool {
+	training, ok := loadResultsTraining(botUrl, chatId, messageId, trainingId, repo)
+	if !ok {
+		return false
+	}
+
+	trackName := "Неизвестная трасса"
+	if track, _ := repo.GetTrackByID(training.TrackID); track != nil {
+		trackName = track.Name
+	}
+
+	var formats strings.Builder
+	for _, format := range laptiming.Formats() {
+		formats.WriteString("• " + format + "\n")
+	}
+
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, fmt.Sprintf("📥 <b>Загрузка кругов из логгера</b>\n\n"+
+		"🏁 <b>Трасса:</b> %s\n"+
+		"📅 <b>Дата и время:</b> %s\n\n"+
+		"📎 Отправьте CSV-файл, выгруженный из логгера, документом.\n"+
+		"Пилоты сопоставляются с участниками по имени или Telegram ID, "+
+		"уже введенные круги сопоставленных участников заменяются.\n\n"+
+		"📋 <b>Поддерживаемые форматы:</b>\n%s",
+		trackName, timefmt.DateTime(training.StartTime, repo.GetViewerLocation(chatId, training.TrackID)), formats.String()),
+		telegram.CreateStepKeyboard())
+	return true
+}
+
+// ProcessLapFile скачивает CSV-файл логгера, сопоставляет пилотов с участниками
+// тренировки и сохраняет их круги
+func ProcessLapFile(botUrl string, chatId int, update telegram.Update, repo database.ContentRepositoryInterface, state states.State) states.State {
+	document := update.Message.Document
+	if document == nil {
+		telegram.SendMessage(botUrl, chatId, "📎 <b>Ожидается файл</b>\n\n"+
+			"Отправьте CSV-файл логгера документом.", telegram.CreateStepKeyboard())
+		return state
+	}
+
+	if !strings.HasSuffix(strings.ToLower(document.FileName), ".csv") && document.MimeType != "text/csv" {
+		telegram.SendMessage(botUrl, chatId, "❌ <b>Нужен CSV-файл</b>\n\n"+
+			"Выгрузите сессию из логгера в формате CSV.", telegram.CreateStepKeyboard())
+		return state
+	}
+
+	if document.FileSize > maxLapFileSize {
+		telegram.SendMessage(botUrl, chatId, fmt.Sprintf("❌ <b>Файл слишком большой</b>\n\n"+
+			"Размер файла не должен превышать %d КБ.", maxLapFileSize/1024), telegram.CreateStepKeyboard())
+		return state
+	}
+
+	training, ok := loadResultsTraining(botUrl, chatId, 0, state.GetID(), repo)
+	if !ok {
+		return states.SetStartKeyboard()
+	}
+
+	file, err := telegram.GetFile(botUrl, document.FileId)
+	if err == nil {
+		var data []byte
+		if data, err = telegram.DownloadFile(botUrl, file, maxLapFileSize); err == nil {
+			return importLapFile(botUrl, chatId, data, training, repo, state)
+		}
+	}
+
+	logger.UserError(chatId, "Скачивание файла логгера: %v", err)
+	telegram.SendMessage(botUrl, chatId, "❌ <b>Не удалось скачать файл</b>\n\n"+
+		errors.HandleError(err)+"\nПопробуйте отправить файл еще раз.", telegram.CreateStepKeyboard())
+	return state
+}
+
+// importLapFile разбирает содержимое файла логгера и сохраняет круги
+func importLapFile(botUrl string, chatId int, data []byte, training *database.Training, repo database.ContentRepositoryInterface, state states.State) states.State {
+	format, laps, err := laptiming.Parse(data)
+	if err != nil {
+		message := "❌ <b>Не удалось разобрать файл</b>\n\n" + telegram.EscapeHTML(err.Error()) + "\n\n📋 <b>Поддерживаемые форматы:</b>\n"
+		for _, f := range laptiming.Formats() {
+			message += "• " + f + "\n"
+		}
+		telegram.SendMessage(botUrl, chatId, message, telegram.CreateStepKeyboard())
+		return state
+	}
+
+	registrations, err := repo.GetAttendanceRegistrations(training.ID)
+	if err != nil {
+		return sendErrorMessage(botUrl, chatId, 0, repo, err)
+	}
+
+	// Пилот из файла сопоставляется с записью: ребенок и его представитель на
+	// одной тренировке - разные пилоты. Telegram ID есть только у самого пользователя.
+	names := registrationNames(registrations, repo)
+	entrants := make([]lapEntrant, 0, len(registrations))
+	registrationById := make(map[uint]*database.TrainingRegistration, len(registrations))
+	for i, reg := range registrations {
+		entrant := lapEntrant{RegistrationID: reg.ID, Name: names[reg.ID]}
+		if reg.ParticipantID == 0 {
+			if user, _ := repo.GetUserByID(reg.UserID); user != nil {
+				entrant.TgId = user.TgId
+			}
+		}
+		entrants = append(entrants, entrant)
+		registrationById[reg.ID] = &registrations[i]
+	}
+
+	// Логгер может перечислять круги не по порядку; номера из файла задают порядок
+	sort.SliceStable(laps, func(i, j int) bool { return laps[i].Number < laps[j].Number })
+
+	var drivers []string
+	seen := make(map[string]bool)
+	for _, lap := range laps {
+		if !seen[lap.Driver] {
+			seen[lap.Driver] = true
+			drivers = append(drivers, lap.Driver)
+		}
+	}
+	matches := matchDrivers(drivers, entrants)
+
+	validator := validation.NewValidator()
+	var records []database.LapRecord
+	skipped := 0
+	for _, lap := range laps {
+		registrationId, ok := matches[lap.Driver]
+		if !ok {
+			continue
+		}
+		// Выезды с пит-лейна и остановки дают круги вне разумного диапазона
+		if !validator.ValidateLapTimeMs(lap.TimeMs).IsValid {
+			skipped++
+			continue
+		}
+		records = append(records, database.LapRecord{
+			UserID:        registrationById[registrationId].UserID,
+			ParticipantID: registrationById[registrationId].ParticipantID,
+			LapTimeMs:     lap.TimeMs,
+			SectorsMs:     database.EncodeSectors(lap.SectorsMs),
+		})
+	}
+
+	var unmatched []string
+	for _, driver := range drivers {
+		if _, ok := matches[driver]; !ok {
+			unmatched = append(unmatched, telegram.EscapeHTML(driver))
+		}
+	}
+
+	if len(records) == 0 {
+		message := "❌ <b>Нет кругов для сохранения</b>\n\n"
+		if len(unmatched) > 0 {
+			message += "👤 Пилоты из файла не найдены среди участников: " + strings.Join(unmatched, ", ") + "\n\n" +
+				"💡 Имена в логгере должны совпадать с именами участников или их Telegram ID."
+		} else {
+			message += "Все круги в файле вне допустимого диапазона времени."
+		}
+		telegram.SendMessage(botUrl, chatId, message, telegram.CreateStepKeyboard())
+		return state
+	}
+
+	previousBests := make(map[uint]int)
+	for _, registrationId := range matches {
+		previousBests[registrationId] = personalBestOnTrack(registrationById[registrationId], training, repo)
+	}
+
+	if err := repo.ImportLapRecords(training.ID, records); err != nil {
+		logger.UserError(chatId, "Импорт кругов тренировки %d: %v", training.ID, err)
+		return sendErrorMessage(botUrl, chatId, 0, repo, err)
+	}
+
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("✅ <b>Круги загружены</b>\n\n"+
+		"📋 <b>Формат:</b> %s\n"+
+		"🔢 <b>Кругов:</b> %d\n\n", format, len(records)))
+
+	for i := range registrations {
+		reg := &registrations[i]
+		userRecords := filterLapRecords(records, reg)
+		if len(userRecords) == 0 {
+			continue
+		}
+
+		// Участник с кругами в логгере точно был на тренировке
+		if reg.Status != database.RegistrationStatusAttended {
+			if _, err := repo.MarkAttendance(reg.ID, database.RegistrationStatusAttended); err != nil {
+				logger.UserError(chatId, "Отметка посещения записи %d по логгеру: %v", reg.ID, err)
+			}
+		}
+
+		best := bestLapTime(userRecords)
+		builder.WriteString(fmt.Sprintf("👤 <b>%s</b>: кругов %d, лучший %s", telegram.EscapeHTML(names[reg.ID]), len(userRecords), timefmt.LapTime(best)))
+		if ideal := idealLapTime(userRecords); ideal != 0 && ideal < best {
+			builder.WriteString(", идеальный " + timefmt.LapTime(ideal))
+		}
+		if previous := previousBests[reg.ID]; previous != 0 && best < previous {
+			builder.WriteString(" 🏆")
+			notifyUserAboutPersonalBest(botUrl, reg, training, best, previous, repo)
+		}
+		builder.WriteString("\n")
+	}
+
+	if skipped > 0 {
+		builder.WriteString(fmt.Sprintf("\n⏭ Пропущено кругов вне допустимого времени: %d", skipped))
+	}
+	if len(unmatched) > 0 {
+		builder.WriteString("\n⚠️ Не найдены среди участников: " + strings.Join(unmatched, ", "))
+	}
+
+	logger.UserInfo(chatId, "Импорт логгера: тренировка %d, формат %q, кругов %d", training.ID, format, len(records))
+	telegram.SendMessage(botUrl, chatId, builder.String(), telegram.CreateBackToMenuKeyboard(fmt.Sprintf("attendanceChecklist_%d", training.ID)))
+	return states.SetStartKeyboard()
+}
+
+// loadResultsTraining загружает тренировку для ввода результатов, проверяя
+// права и что тренировка уже началась. При ошибке сообщение уже отправлено.
+func loadResultsTraining(botUrl string, chatId int, messageId int, trainingId uint, repo database.ContentRepositoryInterface) (*database.Training, bool) {
+	training, _ := repo.GetTrainingById(trainingId)
+	if training == nil {
+		telegram.SendOrEditMessage(botUrl, chatId, messageId, "❌ <b>Тренировка не найдена</b>", telegram.CreateBaseKeyboard())
+		return nil, false
+	}
+
+	if !isTrainingTrainer(chatId, training, repo) && !database.IsAdmin(chatId, repo) {
+		telegram.SendOrEditMessage(botUrl, chatId, messageId, "❌ <b>Нет прав</b>", telegram.CreateBaseKeyboard())
+		return nil, false
+	}
+
+	if training.StartTime.After(time.Now()) {
+		telegram.SendOrEditMessage(botUrl, chatId, messageId, "ℹ️ <b>Тренировка еще не началась</b>\n\n"+
+			"Результаты вводятся после начала тренировки.", telegram.CreateBaseKeyboard())
+		return nil, false
+	}
+
+	return training, true
+}
+
+// lapEntrant - запись участника тренировки, с которой сопоставляется пилот из файла
+type lapEntrant struct {
+	RegistrationID uint
+	Name           string
+	TgId           string // пусто у участников под аккаунтом
+}
+
+// matchDrivers сопоставляет пилотов из файла с записями участников тренировки:
+// сначала по полному имени в любом порядке слов или Telegram ID, затем по
+// фамилии или инициалам, если они подходят ровно одному участнику. Каждая
+// запись сопоставляется не больше одного раза. Возвращает ID записей.
+func matchDrivers(drivers []string, entrants []lapEntrant) map[string]uint {
+	matches := make(map[string]uint)
+	taken := make(map[uint]bool)
+
+	for _, driver := range drivers {
+		key := nameKey(driver)
+		for _, entrant := range entrants {
+			if taken[entrant.RegistrationID] {
+				continue
+			}
+			tgId := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(entrant.TgId)), "@")
+			if key == nameKey(entrant.Name) || (tgId != "" && strings.TrimPrefix(key, "@") == tgId) {
+				matches[driver] = entrant.RegistrationID
+				taken[entrant.RegistrationID] = true
+				break
+			}
+		}
+	}
+
+	for _, driver := range drivers {
+		if _, ok := matches[driver]; ok {
+			continue
+		}
+
+		var candidate uint
+		candidates := 0
+		for _, entrant := range entrants {
+			if !taken[entrant.RegistrationID] && nameTokensMatch(nameTokens(driver), nameTokens(entrant.Name)) {
+				candidate = entrant.RegistrationID
+				candidates++
+			}
+		}
+		if candidates == 1 {
+			matches[driver] = candidate
+			taken[candidate] = true
+		}
+	}
+
+	return matches
+}
+
+// nameTokens разбивает имя на слова в нижнем регистре, "ё" считается "е"
+func nameTokens(name string) []string {
+	return strings.Fields(strings.ReplaceAll(strings.ToLower(name), "ё", "е"))
+}
+
+// nameKey - имя без учета регистра и порядка слов
+func nameKey(name string) string {
+	tokens := nameTokens(name)
+	sort.Strings(tokens)
+	return strings.Join(tokens, " ")
+}
+
+// nameTokensMatch проверяет, что каждое слово пилота - слово имени участника
+// или инициал ("И." или "И") одного из них
+func nameTokensMatch(driverTokens, userTokens []string) bool {
+	if len(driverTokens) == 0 {
+		return false
+	}
+
+	for _, token := range driverTokens {
+		initial := strings.TrimSuffix(token, ".")
+		found := false
+		for _, userToken := range userTokens {
+			if token == userToken || (len([]rune(initial)) == 1 && strings.HasPrefix(userToken, initial)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// filterLapRecords возвращает круги участника записи
+func filterLapRecords(records []database.LapRecord, registration *database.TrainingRegistration) []database.LapRecord {
+	var filtered []database.LapRecord
+	for _, record := range records {
+		if record.UserID == registration.UserID && record.ParticipantID == registration.ParticipantID {
+			filtered = append(filtered, record)
+		}
+	}
+	return filtered
+}
+
+// idealLapTime складывает лучшие времена секторов; 0, если секторы не загружены
+// или их число различается между кругами
+func idealLapTime(records []database.LapRecord) int {
+	var best []int
+	for _, record := range records {
+		sectors := record.Sectors()
+		if len(sectors) == 0 {
+			return 0
+		}
+		if best == nil {
+			best = append([]int(nil), sectors...)
+			continue
+		}
+		if len(sectors) != len(best) {
+			return 0
+		}
+		for i, sector := range sectors {
+			if sector < best[i] {
+				best[i] = sector
+			}
+		}
+	}
+
+	ideal := 0
+	for _, sector := range best {
+		ideal += sector
+	}
+	return ideal
+}
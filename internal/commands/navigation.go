@@ -0,0 +1,190 @@
+package commands
+
+import (
+	"x.localhost/rvabot/internal/database"
+	"x.localhost/rvabot/internal/logger"
+	"x.localhost/rvabot/internal/states"
+	"x.localhost/rvabot/internal/telegram"
+)
+
+// GoBack возвращает пользователя на предыдущий шаг диалога со всеми введенными данными
+func GoBack(botUrl string, chatId int, messageId int, repo database.ContentRepositoryInterface, state states.State) states.State {
+	if !states.IsDialogState(state.Type) {
+		telegram.SendOrEditMessage(botUrl, chatId, messageId, "ℹ️ <b>Нет активного диалога</b>\n\n"+
+			"💡 Возвращаться некуда.", telegram.CreateStartKeyboard(chatId, repo))
+		return states.SetStartKeyboard()
+	}
+
+	prev, ok := state.Back()
+	if !ok {
+		return CancelDialog(botUrl, chatId, messageId, state)
+	}
+
+	logger.UserInfo(chatId, "Возврат: %s -> %s", state.Type, prev.Type)
+	if !showDialogStep(botUrl, chatId, messageId, repo, prev) {
+		return CancelDialog(botUrl, chatId, messageId, prev)
+	}
+
+	return prev
+}
+
+// CancelDialog прерывает любой диалог и возвращает в меню, из которого он был начат
+func CancelDialog(botUrl string, chatId int, messageId int, state states.State) states.State {
+	logger.UserInfo(chatId, "Отмена диалога в состоянии %s", state.Type)
+
+	switch states.DialogMenu(state.Type) {
+	case "trainersMenu":
+		return SendOperationCancelledWithTrainersMenu(botUrl, chatId, messageId)
+	case "tracksMenu":
+		return SendOperationCancelledWithTracksMenu(botUrl, chatId, messageId)
+	case "scheduleMenu":
+		return SendOperationCancelledWithScheduleMenu(botUrl, chatId, messageId)
+	case "admin":
+		return SendOperationCancelledWithAdminMenu(botUrl, chatId, messageId)
+	default:
+		return SendOperationCancelledMessage(botUrl, chatId, messageId)
+	}
+}
+
+// showDialogStep повторно показывает приглашение шага, на который вернулся пользователь
+func showDialogStep(botUrl string, chatId int, messageId int, repo database.ContentRepositoryInterface, state states.State) bool {
+	switch state.Type {
+	case states.StateSetTrainerName:
+		promptTrainerName(botUrl, chatId, messageId)
+	case states.StateSetTrainerTgId:
+		promptTrainerTgId(botUrl, chatId, messageId)
+	case states.StateSetTrainerChatId:
+		promptTrainerChatId(botUrl, chatId, messageId)
+	case states.StateSetTrainerInfo:
+		promptTrainerInfo(botUrl, chatId, messageId)
+	case states.StateSetTrackName:
+		promptTrackName(botUrl, chatId, messageId)
+	case states.StateSetTrackInfo:
+		promptTrackInfo(botUrl, chatId, messageId)
+	case states.StateSetTrackClosure:
+		promptTrackClosure(botUrl, chatId, messageId)
+	case states.StateEditTrackTimezone:
+		return promptTrackTimezone(botUrl, chatId, messageId, state.GetID(), repo)
+	case states.StateSetTrainingTrack:
+		return showTrainingTrackSelection(botUrl, chatId, messageId, repo)
+	case states.StateSetTrainingTrainer:
+		return showTrainingTrainerSelection(botUrl, chatId, messageId, repo)
+	case states.StateSetTrainingStartTime:
+		trackId, _ := state.Data["trackId"].(uint)
+		promptTrainingStartTime(botUrl, chatId, messageId, repo.GetTrackLocation(trackId))
+	case states.StateSetTrainingEndTime:
+		promptTrainingEndTime(botUrl, chatId, messageId)
+	case states.StateSetTrainingMaxParticipants:
+		promptTrainingMaxParticipants(botUrl, chatId, messageId)
+	case states.StateSetTrainingCarCategory:
+		return showTrainingCarCategorySelection(botUrl, chatId, messageId, repo)
+	case states.StateSetTrainingRecurrence:
+		promptTrainingRecurrence(botUrl, chatId, messageId)
+	case states.StateSetTrainingWeekdays:
+		showTrainingWeekdays(botUrl, chatId, messageId, state.GetTempTrainingData())
+	case states.StateSetTrainingRecurrenceEnd:
+		promptTrainingRecurrenceEnd(botUrl, chatId, messageId)
+	case states.StateSetTrainingCloneDate:
+		promptTrainingCloneDate(botUrl, chatId, messageId, repo, state.GetTempTrainingData())
+	case states.StateSetTemplateName:
+		promptTemplateName(botUrl, chatId, messageId)
+	case states.StateSetUserDataConsent:
+		promptDataConsent(botUrl, chatId, messageId)
+	case states.StateSetUserName:
+		promptUserName(botUrl, chatId, messageId)
+	case states.StateSetUserTgId:
+		promptUserTgId(botUrl, chatId, messageId)
+	case states.StateSetUserTimezone:
+		return showUserTimezone(botUrl, chatId, messageId, repo)
+	case states.StateEnterLapTimes:
+		return promptLapTimes(botUrl, chatId, messageId, state.GetID(), repo)
+	case states.StateImportLapFile:
+		return promptLapFile(botUrl, chatId, messageId, state.GetID(), repo)
+	case states.StateEnterHeatResults:
+		return promptHeatResults(botUrl, chatId, messageId, state.GetID(), repo)
+	case states.StateSetFeedbackStrengths:
+		return promptFeedbackStrengths(botUrl, chatId, messageId, state.GetID(), repo)
+	case states.StateSetFeedbackImprovements:
+		promptFeedbackImprovements(botUrl, chatId, messageId)
+	case states.StateSetFeedbackVideo:
+		promptFeedbackVideo(botUrl, chatId, messageId)
+	case states.StateSetSurveyComment:
+		promptSurveyComment(botUrl, chatId, messageId)
+	case states.StateSetPackageName:
+		promptPackageName(botUrl, chatId, messageId)
+	case states.StateSetPackageCredits:
+		promptPackageCredits(botUrl, chatId, messageId)
+	case states.StateGrantCredits:
+		return promptGrantCredits(botUrl, chatId, messageId, state.GetID(), repo)
+	case states.StateSetCategoryPrice:
+		promptCategoryPrice(botUrl, chatId, messageId)
+	case states.StateSetPromoCode:
+		promptPromoCode(botUrl, chatId, messageId)
+	case states.StateSetPromoDiscount:
+		promptPromoDiscount(botUrl, chatId, messageId)
+	case states.StateSetPromoValidity:
+		promptPromoValidity(botUrl, chatId, messageId)
+	case states.StateSetPromoMaxUses:
+		promptPromoMaxUses(botUrl, chatId, messageId)
+	case states.StateSetPromoTrack:
+		return showPromoTrackSelection(botUrl, chatId, messageId, repo)
+	case states.StateSetPromoCarCategory:
+		return showPromoCarCategorySelection(botUrl, chatId, messageId, repo)
+	case states.StateEnterPromoCode:
+		promptPromoCodeEntry(botUrl, chatId, messageId)
+	case states.StateSetKart:
+		promptKart(botUrl, chatId, messageId)
+	case states.StateSetRentalStock:
+		promptRentalStock(botUrl, chatId, messageId)
+	case states.StateSetCarCategoryName:
+		promptCarCategoryName(botUrl, chatId, messageId, state.GetTempCarCategoryData())
+	case states.StateSetCarCategoryDescription:
+		promptCarCategoryDescription(botUrl, chatId, messageId)
+	case states.StateSetCarCategoryMinAge:
+		promptCarCategoryMinAge(botUrl, chatId, messageId)
+	case states.StateSetCarCategoryLicense:
+		promptCarCategoryLicense(botUrl, chatId, messageId)
+	case states.StateSelectAvailabilityTrack:
+		return showAvailabilityTrackSelection(botUrl, chatId, messageId, repo)
+	case states.StateSetAvailability:
+		promptAvailability(botUrl, chatId, messageId, repo.GetTrackLocation(state.GetID()))
+	case states.StateSelectSessionTrack:
+		user, err := repo.GetUserByChatId(chatId)
+		if err != nil || user == nil {
+			return false
+		}
+		return showSessionTrackSelection(botUrl, chatId, messageId, repo, user)
+	case states.StateSelectSessionWindow:
+		return showSessionWindowSelection(botUrl, chatId, messageId, repo, state.GetID())
+	case states.StateSetSessionSlot:
+		return promptSessionSlot(botUrl, chatId, messageId, state.GetID(), repo)
+	case states.StateSetParticipantName:
+		promptParticipantName(botUrl, chatId, messageId)
+	case states.StateSetParticipantBirthDate:
+		promptParticipantBirthDate(botUrl, chatId, messageId)
+	case states.StateConfirmParticipantConsent:
+		promptGuardianConsent(botUrl, chatId, messageId, state.GetTempParticipantData())
+	case states.StateSelectRegistrationParticipant:
+		trainingId, _ := state.Data["trainingId"].(uint)
+		return showRegistrationParticipantSelection(botUrl, chatId, messageId, trainingId, repo)
+	case states.StateConfirmTrainingRegistration:
+		return showRegistrationConfirmationStep(botUrl, chatId, messageId, state, repo)
+	case states.StateSelectTrackForRegistration:
+		user, err := repo.GetUserByChatId(chatId)
+		if err != nil || user == nil {
+			return false
+		}
+		return showRegistrationTrackSelection(botUrl, chatId, messageId, repo, user)
+	case states.StateSelectTrainerForRegistration:
+		tempData := state.GetTempRegistrationData()
+		return showRegistrationTrainerSelection(botUrl, chatId, messageId, repo, tempData.TrackID)
+	case states.StateSelectTrainingTimeForRegistration:
+		tempData := state.GetTempRegistrationData()
+		return showRegistrationTimeSelection(botUrl, chatId, messageId, repo, tempData.TrackID, tempData.TrainerID)
+	default:
+		logger.UserError(chatId, "Нет приглашения для шага %s", state.Type)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,299 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"x.localhost/rvabot/internal/database"
+	"x.localhost/rvabot/internal/errors"
+	"x.localhost/rvabot/internal/logger"
+	"x.localhost/rvabot/internal/states"
+	"x.localhost/rvabot/internal/telegram"
+	"x.localhost/rvabot/internal/timefmt"
+	"x.localhost/rvabot/internal/validation"
+)
+
+// registrationName возвращает имя того, кто записан: участника под аккаунтом
+// или самого пользователя
+func registrationName(registration *database.TrainingRegistration, repo database.ContentRepositoryInterface) string {
+	if registration.ParticipantID != 0 {
+		if participant, _ := repo.GetParticipantByID(registration.ParticipantID); participant != nil {
+			return participant.Name
+		}
+		return "Участник"
+	}
+	if user, _ := repo.GetUserByID(registration.UserID); user != nil {
+		return user.Name
+	}
+	return "Участник"
+}
+
+// registrationNames возвращает имена записанных по ID записи
+func registrationNames(registrations []database.TrainingRegistration, repo database.ContentRepositoryInterface) map[uint]string {
+	names := make(map[uint]string, len(registrations))
+	for i := range registrations {
+		names[registrations[i].ID] = registrationName(&registrations[i], repo)
+	}
+	return names
+}
+
+// formatRegistrationParticipant добавляет в уведомление строку с участником,
+// если запись сделана не за самого пользователя
+func formatRegistrationParticipant(registration *database.TrainingRegistration, repo database.ContentRepositoryInterface) string {
+	if registration == nil || registration.ParticipantID == 0 {
+		return ""
+	}
+	return "🧒 <b>Участник:</b> " + telegram.EscapeHTML(registrationName(registration, repo)) + "\n"
+}
+
+// ViewParticipants показывает участников, которых пользователь может записывать на тренировки
+func ViewParticipants(botUrl string, chatId int, messageId int, repo database.ContentRepositoryInterface) states.State {
+	user, err := repo.GetUserByChatId(chatId)
+	if err != nil || user == nil {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Пользователь не найден</b>\n\n"+
+			"🔍 Сначала зарегистрируйтесь в системе.", telegram.CreateBaseKeyboard())
+		return states.SetStartKeyboard()
+	}
+
+	participants, err := repo.GetParticipants(user.ID)
+	if err != nil {
+		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
+	}
+
+	var builder strings.Builder
+	builder.WriteString("👨‍👩‍👧 <b>Мои участники</b>\n\n")
+	if len(participants) == 0 {
+		builder.WriteString("📭 Вы пока не добавили участников.\n")
+	}
+
+	today := time.Now()
+	for _, participant := range participants {
+		builder.WriteString(fmt.Sprintf("🧒 <b>%s</b> — %d лет, %s, ⭐ %d\n",
+			telegram.EscapeHTML(participant.Name), database.AgeOn(participant.BirthDate, today), formatSkillLevel(participant.SkillLevel),
+			participant.EloRating))
+	}
+
+	builder.WriteString("\n💡 Добавьте ребенка или другого участника, чтобы записывать его на тренировки со своего аккаунта. " +
+		"Уведомления о его записях будут приходить вам.")
+	if len(participants) > 0 {
+		builder.WriteString("\n🗑️ Нажмите на участника, чтобы удалить его профиль.")
+	}
+
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, builder.String(), telegram.CreateParticipantsKeyboard(participants))
+	return states.SetStartKeyboard()
+}
+
+// AddParticipant начинает добавление участника под аккаунтом пользователя
+func AddParticipant(botUrl string, chatId int, messageId int, repo database.ContentRepositoryInterface) states.State {
+	user, err := repo.GetUserByChatId(chatId)
+	if err != nil || user == nil {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Пользователь не найден</b>\n\n"+
+			"🔍 Сначала зарегистрируйтесь в системе.", telegram.CreateBaseKeyboard())
+		return states.SetStartKeyboard()
+	}
+
+	promptParticipantName(botUrl, chatId, messageId)
+	return states.SetSetParticipantName()
+}
+
+// promptParticipantName показывает шаг ввода имени участника
+func promptParticipantName(botUrl string, chatId int, messageId int) {
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, "🧒 <b>Новый участник</b>\n\n"+
+		"📝 Введите ФИО участника.\n\n"+
+		"💡 <i>Пример: Иванов Петр Иванович</i>", telegram.CreateStepKeyboard())
+}
+
+// promptParticipantBirthDate показывает шаг ввода даты рождения участника
+func promptParticipantBirthDate(botUrl string, chatId int, messageId int) {
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, "🎂 <b>Дата рождения участника</b>\n\n"+
+		"📝 Введите дату в формате YYYY-MM-DD.\n\n"+
+		"💡 <i>Пример: 2014-05-21</i>\n"+
+		"Возраст проверяется по требованиям категории машин при записи.", telegram.CreateStepKeyboard())
+}
+
+// promptGuardianConsent показывает данные участника и запрашивает согласие представителя
+func promptGuardianConsent(botUrl string, chatId int, messageId int, tempData *states.TempParticipantData) {
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, fmt.Sprintf("📋 <b>Согласие представителя</b>\n\n"+
+		"🧒 <b>Участник:</b> %s\n"+
+		"🎂 <b>Дата рождения:</b> %s\n\n"+
+		"<i>Нажимая \"Даю согласие\", вы подтверждаете, что являетесь законным представителем участника, "+
+		"даете согласие на обработку его персональных данных и на его участие в тренировках по картингу.</i>",
+		telegram.EscapeHTML(tempData.Name), tempData.BirthDate.Format(timefmt.DateInputLayout)),
+		telegram.CreateGuardianConsentKeyboard())
+}
+
+// SetParticipantName сохраняет имя участника и запрашивает дату рождения
+func SetParticipantName(botUrl string, chatId int, update telegram.Update, repo database.ContentRepositoryInterface, state states.State) states.State {
+	name := strings.Join(strings.Fields(update.Message.Text), " ")
+
+	validator := validation.NewValidator()
+	if result := validator.ValidateUserName(name); !result.IsValid {
+		telegram.SendMessage(botUrl, chatId, "❌ <b>Ошибка валидации</b>\n\n"+strings.Join(result.GetErrorMessages(), "\n")+
+			"\n\n🔄 Попробуйте еще раз:", telegram.CreateStepKeyboard())
+		return state
+	}
+
+	tempData := state.GetTempParticipantData()
+	tempData.Name = name
+
+	promptParticipantBirthDate(botUrl, chatId, 0)
+	return states.SetSetParticipantBirthDate().SetTempParticipantData(tempData)
+}
+
+// SetParticipantBirthDate сохраняет дату рождения и запрашивает согласие представителя
+func SetParticipantBirthDate(botUrl string, chatId int, update telegram.Update, repo database.ContentRepositoryInterface, state states.State) states.State {
+	validator := validation.NewValidator()
+	birthDate, result := validator.ValidateBirthDate(strings.TrimSpace(update.Message.Text))
+	if !result.IsValid {
+		telegram.SendMessage(botUrl, chatId, "❌ <b>Ошибка валидации</b>\n\n"+strings.Join(result.GetErrorMessages(), "\n")+
+			"\n\n🔄 Попробуйте еще раз:", telegram.CreateStepKeyboard())
+		return state
+	}
+
+	tempData := state.GetTempParticipantData()
+	tempData.BirthDate = birthDate
+
+	promptGuardianConsent(botUrl, chatId, 0, tempData)
+	return states.SetConfirmParticipantConsent().SetTempParticipantData(tempData)
+}
+
+// ConfirmGuardianConsent сохраняет участника после согласия представителя
+func ConfirmGuardianConsent(botUrl string, chatId int, messageId int, repo database.ContentRepositoryInterface, state states.State) states.State {
+	if state.Type != states.StateConfirmParticipantConsent {
+		logger.UserError(chatId, "Неверное состояние для согласия представителя: %s", state.Type)
+		return states.SetError()
+	}
+
+	user, err := repo.GetUserByChatId(chatId)
+	if err != nil || user == nil {
+		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
+	}
+
+	tempData := state.GetTempParticipantData()
+	participant := &database.Participant{
+		UserID:            user.ID,
+		Name:              tempData.Name,
+		BirthDate:         tempData.BirthDate,
+		SkillLevel:        database.SkillLevelBeginner,
+		GuardianConsentAt: time.Now(),
+	}
+	if err := repo.CreateParticipant(participant); err != nil {
+		logger.UserError(chatId, "Добавление участника: %v", err)
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Не удалось добавить участника</b>\n\n"+
+			errors.HandleError(err), telegram.CreateBackToMenuKeyboard("myParticipants"))
+		return states.SetStartKeyboard()
+	}
+
+	logger.UserInfo(chatId, "Добавлен участник: ID=%d", participant.ID)
+	return ViewParticipants(botUrl, chatId, messageId, repo)
+}
+
+// DeleteParticipant удаляет профиль участника пользователя
+func DeleteParticipant(botUrl string, chatId int, messageId int, participantId uint, repo database.ContentRepositoryInterface) states.State {
+	user, err := repo.GetUserByChatId(chatId)
+	if err != nil || user == nil {
+		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
+	}
+
+	if err := repo.DeleteParticipant(participantId, user.ID); err != nil {
+		logger.UserError(chatId, "Удаление участника %d: %v", participantId, err)
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Не удалось удалить участника</b>\n\n"+
+			errors.HandleError(err), telegram.CreateBackToMenuKeyboard("myParticipants"))
+		return states.SetStartKeyboard()
+	}
+
+	logger.UserInfo(chatId, "Участник %d удален", participantId)
+	return ViewParticipants(botUrl, chatId, messageId, repo)
+}
+
+// showRegistrationParticipantSelection предлагает выбрать, за кого записаться на тренировку
+func showRegistrationParticipantSelection(botUrl string, chatId int, messageId int, trainingId uint, repo database.ContentRepositoryInterface) bool {
+	user, _ := repo.GetUserByChatId(chatId)
+	if user == nil {
+		return false
+	}
+
+	participants, err := repo.GetParticipants(user.ID)
+	if err != nil {
+		return false
+	}
+
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, "🏃‍♂️ <b>Запись на тренировку</b>\n\n"+
+		"👥 <b>Кого записать?</b>\n\n"+
+		"💡 Уведомления о записи участника будут приходить вам.",
+		telegram.CreateRegistrationParticipantKeyboard(user.Name, participants))
+	return true
+}
+
+// SelectRegistrationParticipant продолжает запись за выбранного участника;
+// 0 - запись за самого пользователя
+func SelectRegistrationParticipant(botUrl string, chatId int, messageId int, participantId uint, repo database.ContentRepositoryInterface, state states.State) states.State {
+	if state.Type != states.StateSelectRegistrationParticipant {
+		return state
+	}
+
+	trainingId, _ := state.Data["trainingId"].(uint)
+	training, err := repo.GetTrainingById(trainingId)
+	if err != nil || training == nil {
+		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
+	}
+
+	user, err := repo.GetUserByChatId(chatId)
+	if err != nil || user == nil {
+		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
+	}
+
+	var participant *database.Participant
+	if participantId != 0 {
+		participant, _ = repo.GetParticipantByID(participantId)
+		if participant == nil || participant.UserID != user.ID || participant.DeletedAt.Valid {
+			telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Участник не найден</b>", telegram.CreateBaseKeyboard())
+			return states.SetStartKeyboard()
+		}
+	}
+
+	return continueTrainingRegistration(botUrl, chatId, messageId, training, user, participant, repo)
+}
+
+// registrationParticipantID возвращает участника, выбранного при записи на
+// тренировку; 0 - запись за самого пользователя
+func registrationParticipantID(state states.State, trainingId uint) uint {
+	if trainingIdInState, _ := state.Data["trainingId"].(uint); trainingIdInState != trainingId {
+		return 0
+	}
+	return state.GetParticipantID()
+}
+
+// registrationParticipant загружает участника, выбранного при записи на тренировку
+func registrationParticipant(state states.State, trainingId uint, repo database.ContentRepositoryInterface) *database.Participant {
+	participantId := registrationParticipantID(state, trainingId)
+	if participantId == 0 {
+		return nil
+	}
+	participant, _ := repo.GetParticipantByID(participantId)
+	return participant
+}
+
+// checkParticipantAge сообщает, что участник младше минимального возраста
+// категории машин тренировки. Возвращает true, если записываться нельзя.
+func checkParticipantAge(botUrl string, chatId int, messageId int, participant *database.Participant, training *database.Training, repo database.ContentRepositoryInterface) bool {
+	category := trainingCarCategory(training, repo)
+	if category == nil || category.MinAge == 0 {
+		return false
+	}
+
+	age := database.AgeOn(participant.BirthDate, training.StartTime.In(repo.GetTrackLocation(training.TrackID)))
+	if age >= category.MinAge {
+		return false
+	}
+
+	logger.UserInfo(chatId, "Участник %d младше %d лет для тренировки %d", participant.ID, category.MinAge, training.ID)
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, fmt.Sprintf("🎂 <b>Участник слишком молод для этой категории</b>\n\n"+
+		"🧒 <b>Участник:</b> %s\n"+
+		"🚗 <b>Категория:</b> %s — от %d лет\n"+
+		"📅 <b>Возраст на дату тренировки:</b> %d\n\n"+
+		"💡 Выберите тренировку другой категории.",
+		telegram.EscapeHTML(participant.Name), telegram.EscapeHTML(category.Name), category.MinAge, age), telegram.CreateBaseKeyboard())
+	return true
+}
@@ -0,0 +1,372 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"x.localhost/rvabot/internal/database"
+	"x.localhost/rvabot/internal/logger"
+	"x.localhost/rvabot/internal/states"
+	"x.localhost/rvabot/internal/telegram"
+	"x.localhost/rvabot/internal/timefmt"
+	"x.localhost/rvabot/internal/validation"
+)
+
+// formatPrice форматирует цену в рублях; 0 - цена не задана
+func formatPrice(price int) string {
+	if price == 0 {
+		return "не задана"
+	}
+	return fmt.Sprintf("%d ₽", price)
+}
+
+// formatTrainingPrice описывает цену тренировки и ее источник
+func formatTrainingPrice(training *database.Training, repo database.ContentRepositoryInterface) string {
+	price, err := repo.GetTrainingPrice(training)
+	if err != nil {
+		return "—"
+	}
+	if training.Price == 0 && price > 0 {
+		return formatPrice(price) + " (цена категории)"
+	}
+	return formatPrice(price)
+}
+
+// formatPaymentMethod возвращает название способа оплаты
+func formatPaymentMethod(method string) string {
+	switch method {
+	case database.PaymentMethodCash:
+		return "наличными"
+	case database.PaymentMethodTransfer:
+		return "переводом"
+	case database.PaymentMethodCredit:
+		return "кредитом из пакета"
+	default:
+		return method
+	}
+}
+
+// formatPaymentStatus описывает оплату записи
+func formatPaymentStatus(reg *database.TrainingRegistration) string {
+	switch reg.PaymentStatus {
+	case database.PaymentStatusPaid:
+		text := "💰 Оплачено " + formatPaymentMethod(reg.PaymentMethod)
+		if reg.PaymentAmount > 0 {
+			text += fmt.Sprintf(", %d ₽", reg.PaymentAmount)
+		}
+		return text
+	case database.PaymentStatusRefunded:
+		return "↩️ Оплата возвращена"
+	default:
+		return "💵 Не оплачено"
+	}
+}
+
+// MarkPaymentCash отмечает оплату записи наличными
+func MarkPaymentCash(botUrl string, chatId int, messageId int, registrationId uint, repo database.ContentRepositoryInterface) states.State {
+	return markPayment(botUrl, chatId, messageId, registrationId, database.PaymentMethodCash, repo)
+}
+
+// MarkPaymentTransfer отмечает оплату записи переводом
+func MarkPaymentTransfer(botUrl string, chatId int, messageId int, registrationId uint, repo database.ContentRepositoryInterface) states.State {
+	return markPayment(botUrl, chatId, messageId, registrationId, database.PaymentMethodTransfer, repo)
+}
+
+// loadPaymentRegistration загружает запись и тренировку и проверяет, что оплату
+// отмечает администратор или тренер этой тренировки
+func loadPaymentRegistration(botUrl string, chatId int, messageId int, registrationId uint, repo database.ContentRepositoryInterface) (*database.TrainingRegistration, *database.Training, bool) {
+	registration, _ := repo.GetTrainingRegistrationByID(registrationId)
+	if registration == nil {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Запись не найдена</b>", telegram.CreateBaseKeyboard())
+		return nil, nil, false
+	}
+
+	training, _ := repo.GetTrainingById(registration.TrainingID)
+	if training == nil {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Тренировка не найдена</b>", telegram.CreateBaseKeyboard())
+		return nil, nil, false
+	}
+
+	if !database.IsAdmin(chatId, repo) && !isTrainingTrainer(chatId, training, repo) {
+		SendAccessDeniedMessage(botUrl, chatId, messageId)
+		return nil, nil, false
+	}
+
+	return registration, training, true
+}
+
+// markPayment отмечает оплату записи по текущей цене тренировки с учетом скидки
+func markPayment(botUrl string, chatId int, messageId int, registrationId uint, method string, repo database.ContentRepositoryInterface) states.State {
+	registration, training, ok := loadPaymentRegistration(botUrl, chatId, messageId, registrationId, repo)
+	if !ok {
+		return states.SetStartKeyboard()
+	}
+
+	price, err := repo.GetTrainingPrice(training)
+	if err != nil {
+		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
+	}
+	price = database.DiscountedPrice(price, registration)
+
+	if _, err := repo.MarkRegistrationPaid(registrationId, method, price); err != nil {
+		logger.UserError(chatId, "Оплата записи %d: %v", registrationId, err)
+		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
+	}
+
+	logger.UserInfo(chatId, "Запись %d оплачена %s: %d", registrationId, method, price)
+	return ViewTrainingRegistrations(botUrl, chatId, messageId, training.ID, repo)
+}
+
+// RefundPayment отмечает возврат оплаты записи
+func RefundPayment(botUrl string, chatId int, messageId int, registrationId uint, repo database.ContentRepositoryInterface) states.State {
+	_, training, ok := loadPaymentRegistration(botUrl, chatId, messageId, registrationId, repo)
+	if !ok {
+		return states.SetStartKeyboard()
+	}
+
+	if _, err := repo.RefundRegistrationPayment(registrationId); err != nil {
+		logger.UserError(chatId, "Возврат оплаты записи %d: %v", registrationId, err)
+		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
+	}
+
+	logger.UserInfo(chatId, "Оплата записи %d возвращена", registrationId)
+	return ViewTrainingRegistrations(botUrl, chatId, messageId, training.ID, repo)
+}
+
+// markCreditPayment отмечает запись оплаченной списанным кредитом
+func markCreditPayment(chatId int, registration *database.TrainingRegistration, training *database.Training, repo database.ContentRepositoryInterface) {
+	price, _ := repo.GetTrainingPrice(training)
+	price = database.DiscountedPrice(price, registration)
+	if _, err := repo.MarkRegistrationPaid(registration.ID, database.PaymentMethodCredit, price); err != nil {
+		logger.UserError(chatId, "Оплата записи %d кредитом: %v", registration.ID, err)
+	}
+}
+
+// refundCreditPayment отмечает возврат оплаты, если запись была оплачена
+// вернувшимся кредитом. Наличные и переводы возвращает администратор вручную.
+func refundCreditPayment(chatId int, registrationId uint, repo database.ContentRepositoryInterface) {
+	registration, _ := repo.GetTrainingRegistrationByID(registrationId)
+	if registration == nil || registration.PaymentStatus != database.PaymentStatusPaid ||
+		registration.PaymentMethod != database.PaymentMethodCredit {
+		return
+	}
+
+	if _, err := repo.RefundRegistrationPayment(registrationId); err != nil {
+		logger.UserError(chatId, "Возврат оплаты кредитом за запись %d: %v", registrationId, err)
+	}
+}
+
+// ViewOutstandingPayments показывает администратору неоплаченные записи на прошедшие тренировки
+func ViewOutstandingPayments(botUrl string, chatId int, messageId int, repo database.ContentRepositoryInterface) states.State {
+	if !database.IsAdmin(chatId, repo) {
+		return SendAccessDeniedMessage(botUrl, chatId, messageId)
+	}
+
+	payments, err := repo.GetOutstandingPayments()
+	if err != nil {
+		telegram.SendOrEditMessage(botUrl, chatId, messageId, "❌ <b>Ошибка загрузки оплат</b>\n\n"+
+			"Попробуйте позже.", telegram.CreateBackToAdminKeyboard())
+		return states.SetAdminKeyboard()
+	}
+
+	var builder strings.Builder
+	builder.WriteString("💸 <b>Неоплаченные тренировки</b>\n\n")
+	if len(payments) == 0 {
+		builder.WriteString("✅ Все прошедшие тренировки оплачены.")
+	}
+
+	total, unpriced := 0, 0
+	for i, p := range payments {
+		userName := "❓ Неизвестный"
+		if user, _ := repo.GetUserByID(p.UserID); user != nil {
+			userName = user.Name
+		}
+		trackName := "❓ Неизвестная"
+		if track, _ := repo.GetTrackByID(p.TrackID); track != nil {
+			trackName = track.Name
+		}
+
+		builder.WriteString(fmt.Sprintf("%d. <b>%s</b> — %s, %s — %s\n",
+			i+1, telegram.EscapeHTML(userName), trackName,
+			timefmt.Short(p.StartTime, repo.GetViewerLocation(chatId, p.TrackID)), formatPrice(p.Amount)))
+
+		total += p.Amount
+		if p.Amount == 0 {
+			unpriced++
+		}
+	}
+
+	if len(payments) > 0 {
+		builder.WriteString(fmt.Sprintf("\n💰 <b>Итого к оплате:</b> %d ₽", total))
+		if unpriced > 0 {
+			builder.WriteString(fmt.Sprintf("\n⚠️ Без цены: %d", unpriced))
+		}
+		builder.WriteString("\n\n💡 Отметить оплату можно в списке участников тренировки.")
+	}
+
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, builder.String(), telegram.CreateBackToAdminKeyboard())
+	return states.SetAdminKeyboard()
+}
+
+// EditTrainingPrice начинает изменение собственной цены тренировки
+func EditTrainingPrice(botUrl string, chatId int, messageId int, trainingId uint, repo database.ContentRepositoryInterface) states.State {
+	if !database.IsAdmin(chatId, repo) {
+		return SendAccessDeniedMessage(botUrl, chatId, messageId)
+	}
+
+	training, err := repo.GetTrainingById(trainingId)
+	if err != nil || training == nil {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Тренировка не найдена</b>", telegram.CreateBackToScheduleMenuKeyboard())
+		return states.SetAdminKeyboard()
+	}
+
+	if training.SeriesID != nil {
+		return showSeriesScopeSelection(botUrl, chatId, messageId, training, seriesActionPrice, repo)
+	}
+
+	promptEditTrainingPrice(botUrl, chatId, messageId, training, repo)
+	return states.SetEditTrainingPrice(trainingId)
+}
+
+// promptEditTrainingPrice показывает ввод цены тренировки
+func promptEditTrainingPrice(botUrl string, chatId int, messageId int, training *database.Training, repo database.ContentRepositoryInterface) {
+	categoryPrice, _ := repo.GetTrainingPrice(&database.Training{CarCategoryID: training.CarCategoryID})
+
+	telegram.EditMessage(botUrl, chatId, messageId, fmt.Sprintf("💰 <b>Цена тренировки</b>\n\n"+
+		"📊 Сейчас: %s\n"+
+		"🚗 Цена категории %s: %s\n\n"+
+		"📝 Введите цену в рублях.\n"+
+		"💡 Введите 0, чтобы использовать цену категории.",
+		formatPrice(training.Price), carCategoryName(training.CarCategoryID, repo), formatPrice(categoryPrice)), telegram.CreateBackToScheduleMenuKeyboard())
+}
+
+// SetEditTrainingPrice сохраняет цену тренировки для выбранных занятий серии
+func SetEditTrainingPrice(botUrl string, chatId int, update telegram.Update, repo database.ContentRepositoryInterface, state states.State) states.State {
+	if !database.IsAdmin(chatId, repo) {
+		return SendAccessDeniedMessage(botUrl, chatId, 0)
+	}
+
+	priceStr := strings.TrimSpace(update.Message.Text)
+
+	validator := validation.NewValidator()
+	if result := validator.ValidatePrice(priceStr); !result.IsValid {
+		telegram.SendMessage(botUrl, chatId, "❌ <b>Ошибка валидации</b>\n\n"+strings.Join(result.GetErrorMessages(), "\n")+
+			"\n\n🔄 Попробуйте еще раз:", telegram.CreateBackToScheduleMenuKeyboard())
+		return state
+	}
+	price, _ := strconv.Atoi(priceStr)
+
+	training, err := repo.GetTrainingById(state.GetID())
+	if err != nil || training == nil {
+		telegram.SendMessage(botUrl, chatId, "❌ <b>Тренировка не найдена</b>", telegram.CreateBackToScheduleMenuKeyboard())
+		return states.SetAdminKeyboard()
+	}
+
+	trainings, err := repo.GetSeriesTrainings(training, state.GetSeriesScope())
+	if err != nil {
+		telegram.SendMessage(botUrl, chatId, "❌ <b>Ошибка загрузки занятий серии</b>", telegram.CreateBackToScheduleMenuKeyboard())
+		return states.SetAdminKeyboard()
+	}
+
+	for _, t := range trainings {
+		if err := repo.SetTrainingPrice(t.ID, price); err != nil {
+			telegram.SendMessage(botUrl, chatId, "❌ <b>Ошибка сохранения</b>", telegram.CreateBackToScheduleMenuKeyboard())
+			return states.SetAdminKeyboard()
+		}
+	}
+
+	logger.AdminInfo(chatId, "Цена тренировки %d: %d (занятий %d)", training.ID, price, len(trainings))
+
+	message := "✅ <b>Цена обновлена</b>"
+	if price == 0 {
+		message = "✅ <b>Тренировка использует цену категории</b>"
+	}
+	if training.SeriesID != nil {
+		message += fmt.Sprintf("\n\n🔁 Изменено: %s (%d)", formatSeriesScope(state.GetSeriesScope()), len(trainings))
+	}
+	telegram.SendMessage(botUrl, chatId, message, telegram.CreateBackToScheduleMenuKeyboard())
+	return states.SetAdminKeyboard()
+}
+
+// ViewCategoryPrices показывает цены категорий машин
+func ViewCategoryPrices(botUrl string, chatId int, messageId int, repo database.ContentRepositoryInterface) states.State {
+	if !database.IsAdmin(chatId, repo) {
+		return SendAccessDeniedMessage(botUrl, chatId, messageId)
+	}
+
+	prices, err := repo.GetCategoryPrices()
+	if err != nil {
+		telegram.SendOrEditMessage(botUrl, chatId, messageId, "❌ <b>Ошибка загрузки цен</b>\n\n"+
+			"Попробуйте позже.", telegram.CreateBackToScheduleMenuKeyboard())
+		return states.SetAdminKeyboard()
+	}
+
+	var builder strings.Builder
+	builder.WriteString("💰 <b>Цены категорий</b>\n\n")
+	if len(prices) == 0 {
+		builder.WriteString("📭 Цены пока не заданы.\n")
+	}
+	categoryName := carCategoryNames(repo)
+	for _, p := range prices {
+		builder.WriteString(fmt.Sprintf("• %s — %s\n", telegram.EscapeHTML(categoryName(p.CarCategoryID)), formatPrice(p.Price)))
+	}
+	builder.WriteString("\n💡 Цена категории действует для тренировок без собственной цены.")
+
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, builder.String(), telegram.CreateCategoryPricesKeyboard())
+	return states.SetAdminKeyboard()
+}
+
+// StartSetCategoryPrice начинает ввод цены категории
+func StartSetCategoryPrice(botUrl string, chatId int, messageId int, repo database.ContentRepositoryInterface) states.State {
+	if !database.IsAdmin(chatId, repo) {
+		return SendAccessDeniedMessage(botUrl, chatId, messageId)
+	}
+
+	promptCategoryPrice(botUrl, chatId, messageId)
+	return states.SetCategoryPrice()
+}
+
+// promptCategoryPrice показывает ввод цены категории
+func promptCategoryPrice(botUrl string, chatId int, messageId int) {
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, "💰 <b>Цена категории</b>\n\n"+
+		"📝 Введите категорию и цену в рублях через пробел.\n"+
+		"💡 Цена 0 удаляет цену категории.\n\n"+
+		"💡 <i>Пример: KZ 2500</i>", telegram.CreateStepKeyboard())
+}
+
+// SetCategoryPrice сохраняет цену категории
+func SetCategoryPrice(botUrl string, chatId int, update telegram.Update, repo database.ContentRepositoryInterface, state states.State) states.State {
+	if !database.IsAdmin(chatId, repo) {
+		return SendAccessDeniedMessage(botUrl, chatId, 0)
+	}
+
+	fields := strings.Fields(update.Message.Text)
+	if len(fields) < 2 {
+		telegram.SendMessage(botUrl, chatId, "❌ <b>Неверный формат</b>\n\n"+
+			"📝 Введите категорию и цену через пробел.\n"+
+			"💡 <i>Пример: KZ 2500</i>", telegram.CreateStepKeyboard())
+		return state
+	}
+	category, ok := resolveCarCategory(botUrl, chatId, strings.Join(fields[:len(fields)-1], " "), repo)
+	if !ok {
+		return state
+	}
+	priceStr := fields[len(fields)-1]
+
+	validator := validation.NewValidator()
+	if result := validator.ValidatePrice(priceStr); !result.IsValid {
+		telegram.SendMessage(botUrl, chatId, "❌ <b>Ошибка валидации</b>\n\n"+strings.Join(result.GetErrorMessages(), "\n")+
+			"\n\n🔄 Попробуйте еще раз:", telegram.CreateStepKeyboard())
+		return state
+	}
+	price, _ := strconv.Atoi(priceStr)
+
+	if err := repo.SetCategoryPrice(category.ID, price); err != nil {
+		logger.AdminError(chatId, "Цена категории %s: %v", category.Name, err)
+		return sendErrorMessage(botUrl, chatId, 0, repo, err)
+	}
+
+	logger.AdminInfo(chatId, "Цена категории %s: %d", category.Name, price)
+	return ViewCategoryPrices(botUrl, chatId, 0, repo)
+}
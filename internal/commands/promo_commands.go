@@ -0,0 +1,442 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"x.localhost/rvabot/internal/database"
+	"x.localhost/rvabot/internal/errors"
+	"x.localhost/rvabot/internal/logger"
+	"x.localhost/rvabot/internal/states"
+	"x.localhost/rvabot/internal/telegram"
+	"x.localhost/rvabot/internal/timefmt"
+	"x.localhost/rvabot/internal/validation"
+)
+
+// ViewPromoCodes показывает администратору действующие промокоды
+func ViewPromoCodes(botUrl string, chatId int, messageId int, repo database.ContentRepositoryInterface) states.State {
+	if !database.IsAdmin(chatId, repo) {
+		return SendAccessDeniedMessage(botUrl, chatId, messageId)
+	}
+
+	promos, err := repo.GetActivePromoCodes()
+	if err != nil {
+		telegram.SendOrEditMessage(botUrl, chatId, messageId, "❌ <b>Ошибка загрузки промокодов</b>\n\n"+
+			"Попробуйте позже.", telegram.CreateBackToAdminKeyboard())
+		return states.SetAdminKeyboard()
+	}
+
+	var builder strings.Builder
+	builder.WriteString("🏷 <b>Промокоды</b>\n\n")
+	if len(promos) == 0 {
+		builder.WriteString("📭 Действующих промокодов нет.\n")
+	}
+	for i := range promos {
+		builder.WriteString(formatPromoCode(&promos[i], repo))
+	}
+	builder.WriteString("\n💡 Участник вводит промокод при подтверждении записи на тренировку.")
+
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, builder.String(), telegram.CreatePromoCodesKeyboard(promos))
+	return states.SetAdminKeyboard()
+}
+
+// formatPromoCode описывает промокод для списка администратора
+func formatPromoCode(promo *database.PromoCode, repo database.ContentRepositoryInterface) string {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("• <b>%s</b> — %s\n", promo.Code, formatPromoDiscount(promo)))
+
+	if promo.ValidFrom != nil || promo.ValidUntil != nil {
+		builder.WriteString("   📅 " + formatPromoValidity(promo) + "\n")
+	}
+
+	uses, _ := repo.CountPromoCodeUses(promo.ID)
+	if promo.MaxUses > 0 {
+		builder.WriteString(fmt.Sprintf("   🔢 Использовано: %d из %d\n", uses, promo.MaxUses))
+	} else {
+		builder.WriteString(fmt.Sprintf("   🔢 Использовано: %d\n", uses))
+	}
+
+	var restrictions []string
+	if promo.TrackID != nil {
+		trackName := "❓ Неизвестная"
+		if track, _ := repo.GetTrackByID(*promo.TrackID); track != nil {
+			trackName = track.Name
+		}
+		restrictions = append(restrictions, "трасса "+trackName)
+	}
+	if promo.CarCategoryID != nil {
+		restrictions = append(restrictions, "категория "+telegram.EscapeHTML(carCategoryName(promo.CarCategoryID, repo)))
+	}
+	if len(restrictions) > 0 {
+		builder.WriteString("   🎯 Только: " + strings.Join(restrictions, ", ") + "\n")
+	}
+
+	return builder.String()
+}
+
+// formatPromoDiscount описывает скидку промокода
+func formatPromoDiscount(promo *database.PromoCode) string {
+	if promo.DiscountType == database.DiscountTypePercent {
+		return fmt.Sprintf("скидка %d%%", promo.DiscountValue)
+	}
+	return fmt.Sprintf("скидка %d ₽", promo.DiscountValue)
+}
+
+// formatPromoValidity описывает срок действия промокода в часовом поясе академии
+func formatPromoValidity(promo *database.PromoCode) string {
+	var parts []string
+	if promo.ValidFrom != nil {
+		parts = append(parts, "с "+timefmt.Date(*promo.ValidFrom, nil))
+	}
+	if promo.ValidUntil != nil {
+		// Хранится начало следующего дня, показывается последний день действия
+		parts = append(parts, "по "+timefmt.Date(promo.ValidUntil.Add(-time.Second), nil))
+	}
+	return strings.Join(parts, " ")
+}
+
+// DeactivatePromoCode отключает промокод
+func DeactivatePromoCode(botUrl string, chatId int, messageId int, promoCodeId uint, repo database.ContentRepositoryInterface) states.State {
+	if !database.IsAdmin(chatId, repo) {
+		return SendAccessDeniedMessage(botUrl, chatId, messageId)
+	}
+
+	if err := repo.DeactivatePromoCode(promoCodeId); err != nil {
+		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
+	}
+
+	logger.AdminInfo(chatId, "Промокод %d отключен", promoCodeId)
+	return ViewPromoCodes(botUrl, chatId, messageId, repo)
+}
+
+// CreatePromoCode начинает создание промокода
+func CreatePromoCode(botUrl string, chatId int, messageId int, repo database.ContentRepositoryInterface) states.State {
+	if !database.IsAdmin(chatId, repo) {
+		return SendAccessDeniedMessage(botUrl, chatId, messageId)
+	}
+
+	promptPromoCode(botUrl, chatId, messageId)
+	return states.SetPromoCode()
+}
+
+// promptPromoCode показывает шаг ввода кода
+func promptPromoCode(botUrl string, chatId int, messageId int) {
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, "🏷 <b>Новый промокод</b>\n\n"+
+		"📝 <b>Шаг 1/6:</b> Введите код из латинских букв и цифр.\n\n"+
+		"💡 <i>Пример: SPRING25</i>", telegram.CreateStepKeyboard())
+}
+
+// promptPromoDiscount показывает шаг ввода скидки
+func promptPromoDiscount(botUrl string, chatId int, messageId int) {
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, "💸 <b>Шаг 2/6:</b> Введите скидку.\n\n"+
+		"• процент: <i>15%</i>\n"+
+		"• рубли: <i>500</i>", telegram.CreateStepKeyboard())
+}
+
+// promptPromoValidity показывает шаг ввода срока действия
+func promptPromoValidity(botUrl string, chatId int, messageId int) {
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, "📅 <b>Шаг 3/6:</b> Введите срок действия.\n\n"+
+		"• период: <i>2024-03-01 2024-03-31</i>\n"+
+		"• только последний день: <i>2024-03-31</i>\n"+
+		"• без ограничения: <i>-</i>", telegram.CreateStepKeyboard())
+}
+
+// promptPromoMaxUses показывает шаг ввода лимита использований
+func promptPromoMaxUses(botUrl string, chatId int, messageId int) {
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, "🔢 <b>Шаг 4/6:</b> Сколько раз можно использовать промокод?\n\n"+
+		"💡 Введите 0, чтобы не ограничивать.\n"+
+		"Отмененные и отклоненные записи лимит не расходуют.", telegram.CreateStepKeyboard())
+}
+
+// showPromoTrackSelection показывает выбор трассы, на которой действует промокод
+func showPromoTrackSelection(botUrl string, chatId int, messageId int, repo database.ContentRepositoryInterface) bool {
+	tracks, err := repo.GetTracks()
+	if err != nil {
+		telegram.SendOrEditMessage(botUrl, chatId, messageId, "❌ <b>Ошибка загрузки трасс</b>", telegram.CreateBackToAdminKeyboard())
+		return false
+	}
+
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, "🏁 <b>Шаг 5/6:</b> На какой трассе действует промокод?",
+		telegram.CreatePromoTrackKeyboard(tracks))
+	return true
+}
+
+// showPromoCarCategorySelection показывает выбор категории машин, для которой действует промокод
+func showPromoCarCategorySelection(botUrl string, chatId int, messageId int, repo database.ContentRepositoryInterface) bool {
+	categories, err := repo.GetCarCategories()
+	if err != nil {
+		telegram.SendOrEditMessage(botUrl, chatId, messageId, "❌ <b>Ошибка загрузки категорий</b>", telegram.CreateBackToAdminKeyboard())
+		return false
+	}
+
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, "🚗 <b>Шаг 6/6:</b> Для какой категории машин действует промокод?",
+		telegram.CreatePromoCarCategoryKeyboard(categories))
+	return true
+}
+
+// sendPromoValidationError сообщает об ошибке ввода на шаге создания промокода
+func sendPromoValidationError(botUrl string, chatId int, result *validation.ValidationResult) {
+	telegram.SendMessage(botUrl, chatId, "❌ <b>Ошибка валидации</b>\n\n"+strings.Join(result.GetErrorMessages(), "\n")+
+		"\n\n🔄 Попробуйте еще раз:", telegram.CreateStepKeyboard())
+}
+
+// SetPromoCode сохраняет код и запрашивает скидку
+func SetPromoCode(botUrl string, chatId int, update telegram.Update, repo database.ContentRepositoryInterface, state states.State) states.State {
+	code := database.NormalizePromoCode(update.Message.Text)
+
+	validator := validation.NewValidator()
+	if result := validator.ValidatePromoCode(code); !result.IsValid {
+		sendPromoValidationError(botUrl, chatId, result)
+		return state
+	}
+
+	tempData := state.GetTempPromoCodeData()
+	tempData.Code = code
+
+	promptPromoDiscount(botUrl, chatId, 0)
+	return states.SetPromoDiscount().SetTempPromoCodeData(tempData)
+}
+
+// SetPromoDiscount сохраняет скидку и запрашивает срок действия
+func SetPromoDiscount(botUrl string, chatId int, update telegram.Update, repo database.ContentRepositoryInterface, state states.State) states.State {
+	validator := validation.NewValidator()
+	percent, value, result := validator.ParseDiscount(strings.TrimSpace(update.Message.Text))
+	if !result.IsValid {
+		sendPromoValidationError(botUrl, chatId, result)
+		return state
+	}
+
+	tempData := state.GetTempPromoCodeData()
+	tempData.DiscountType = database.DiscountTypeFixed
+	if percent {
+		tempData.DiscountType = database.DiscountTypePercent
+	}
+	tempData.DiscountValue = value
+
+	promptPromoValidity(botUrl, chatId, 0)
+	return states.SetPromoValidity().SetTempPromoCodeData(tempData)
+}
+
+// SetPromoValidity сохраняет срок действия и запрашивает лимит использований
+func SetPromoValidity(botUrl string, chatId int, update telegram.Update, repo database.ContentRepositoryInterface, state states.State) states.State {
+	input := strings.TrimSpace(update.Message.Text)
+
+	from, until := "", ""
+	if input != "-" {
+		fields := strings.Fields(input)
+		switch len(fields) {
+		case 1:
+			until = fields[0]
+		case 2:
+			from, until = fields[0], fields[1]
+		}
+
+		validFrom, errFrom := time.Parse(timefmt.DateInputLayout, from)
+		validUntil, errUntil := time.Parse(timefmt.DateInputLayout, until)
+		if len(fields) == 0 || len(fields) > 2 || errUntil != nil || (from != "" && errFrom != nil) {
+			telegram.SendMessage(botUrl, chatId, "❌ <b>Неверный формат срока</b>\n\n"+
+				"Используйте даты в формате ГГГГ-ММ-ДД.\n"+
+				"💡 <i>Пример: 2024-03-01 2024-03-31</i>", telegram.CreateStepKeyboard())
+			return state
+		}
+		if (from != "" && validUntil.Before(validFrom)) || validUntil.Before(timefmt.Today(nil)) {
+			telegram.SendMessage(botUrl, chatId, "❌ <b>Неверный срок</b>\n\n"+
+				"Последний день действия должен быть не раньше первого и не в прошлом.", telegram.CreateStepKeyboard())
+			return state
+		}
+	}
+
+	tempData := state.GetTempPromoCodeData()
+	tempData.ValidFrom = from
+	tempData.ValidUntil = until
+
+	promptPromoMaxUses(botUrl, chatId, 0)
+	return states.SetPromoMaxUses().SetTempPromoCodeData(tempData)
+}
+
+// SetPromoMaxUses сохраняет лимит использований и предлагает выбрать трассу
+func SetPromoMaxUses(botUrl string, chatId int, update telegram.Update, repo database.ContentRepositoryInterface, state states.State) states.State {
+	maxUses, err := strconv.Atoi(strings.TrimSpace(update.Message.Text))
+	if err != nil || maxUses < 0 || maxUses > 100000 {
+		telegram.SendMessage(botUrl, chatId, "❌ <b>Неверный лимит</b>\n\n"+
+			"Введите целое число от 0 до 100000.\n"+
+			"💡 <i>Пример: 50</i>", telegram.CreateStepKeyboard())
+		return state
+	}
+
+	tempData := state.GetTempPromoCodeData()
+	tempData.MaxUses = maxUses
+
+	if !showPromoTrackSelection(botUrl, chatId, 0, repo) {
+		return states.SetAdminKeyboard()
+	}
+	return states.SetPromoTrack().SetTempPromoCodeData(tempData)
+}
+
+// SelectPromoTrack сохраняет трассу промокода (0 - любая) и запрашивает категорию
+func SelectPromoTrack(botUrl string, chatId int, messageId int, trackId uint, repo database.ContentRepositoryInterface, state states.State) states.State {
+	if state.Type != states.StateSetPromoTrack {
+		return state
+	}
+
+	tempData := state.GetTempPromoCodeData()
+	tempData.TrackID = trackId
+
+	if !showPromoCarCategorySelection(botUrl, chatId, messageId, repo) {
+		return states.SetAdminKeyboard()
+	}
+	return states.SetPromoCarCategory().SetTempPromoCodeData(tempData)
+}
+
+// SelectPromoCarCategory сохраняет категорию промокода (0 - любая) и создает промокод
+func SelectPromoCarCategory(botUrl string, chatId int, messageId int, categoryId uint, repo database.ContentRepositoryInterface, state states.State) states.State {
+	if state.Type != states.StateSetPromoCarCategory {
+		return state
+	}
+
+	var category *uint
+	if categoryId != 0 {
+		carCategory, _ := repo.GetCarCategoryByID(categoryId)
+		if carCategory == nil {
+			telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Категория не найдена</b>", telegram.CreateBackToAdminKeyboard())
+			return states.SetAdminKeyboard()
+		}
+		category = &carCategory.ID
+	}
+
+	tempData := state.GetTempPromoCodeData()
+	promo := &database.PromoCode{
+		Code:          tempData.Code,
+		DiscountType:  tempData.DiscountType,
+		DiscountValue: tempData.DiscountValue,
+		MaxUses:       tempData.MaxUses,
+		CarCategoryID: category,
+	}
+	if tempData.TrackID != 0 {
+		promo.TrackID = &tempData.TrackID
+	}
+	// Срок действия задается днями академии: с начала первого дня до конца последнего
+	if tempData.ValidFrom != "" {
+		if validFrom, err := timefmt.ParseDate(tempData.ValidFrom, nil); err == nil {
+			promo.ValidFrom = &validFrom
+		}
+	}
+	if tempData.ValidUntil != "" {
+		if validUntil, err := timefmt.ParseDate(tempData.ValidUntil, nil); err == nil {
+			validUntil = validUntil.AddDate(0, 0, 1)
+			promo.ValidUntil = &validUntil
+		}
+	}
+
+	if err := repo.CreatePromoCode(promo); err != nil {
+		logger.AdminError(chatId, "Создание промокода: %v", err)
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Не удалось создать промокод</b>\n\n"+
+			errors.HandleError(err), telegram.CreateBackToAdminKeyboard())
+		return states.SetAdminKeyboard()
+	}
+
+	logger.AdminInfo(chatId, "Создан промокод %s", promo.Code)
+	return ViewPromoCodes(botUrl, chatId, messageId, repo)
+}
+
+// EnterPromoCode предлагает ввести промокод на шаге подтверждения записи
+func EnterPromoCode(botUrl string, chatId int, messageId int, trainingId uint, state states.State) states.State {
+	promptPromoCodeEntry(botUrl, chatId, messageId)
+	return states.SetEnterPromoCode(trainingId).WithParticipant(registrationParticipantID(state, trainingId))
+}
+
+// promptPromoCodeEntry показывает ввод промокода пользователем
+func promptPromoCodeEntry(botUrl string, chatId int, messageId int) {
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, "🎟 <b>Промокод</b>\n\n"+
+		"📝 Введите промокод:", telegram.CreateStepKeyboard())
+}
+
+// SetEnteredPromoCode проверяет промокод и возвращает к подтверждению записи со скидкой
+func SetEnteredPromoCode(botUrl string, chatId int, update telegram.Update, repo database.ContentRepositoryInterface, state states.State) states.State {
+	trainingId, _ := state.Data["trainingId"].(uint)
+	training, _ := repo.GetTrainingById(trainingId)
+	if training == nil {
+		telegram.SendMessage(botUrl, chatId, "❌ <b>Тренировка не найдена</b>", telegram.CreateBaseKeyboard())
+		return states.SetStartKeyboard()
+	}
+
+	promo, err := repo.CheckPromoCode(update.Message.Text, training)
+	if err != nil {
+		telegram.SendMessage(botUrl, chatId, "❌ "+errors.HandleError(err)+"\n\n"+
+			"🔄 Введите другой промокод или вернитесь назад.", telegram.CreateStepKeyboard())
+		return state
+	}
+
+	logger.UserInfo(chatId, "Промокод %s для тренировки %d", promo.Code, trainingId)
+	participant := registrationParticipant(state, trainingId, repo)
+	showTrainingRegistrationConfirmation(botUrl, chatId, 0, training, participant, promo, repo)
+	return states.SetConfirmTrainingRegistration(trainingId).WithPromoCode(promo.ID).WithParticipant(state.GetParticipantID())
+}
+
+// showRegistrationConfirmationStep повторно показывает подтверждение записи с выбранным промокодом
+func showRegistrationConfirmationStep(botUrl string, chatId int, messageId int, state states.State, repo database.ContentRepositoryInterface) bool {
+	trainingId, _ := state.Data["trainingId"].(uint)
+	training, _ := repo.GetTrainingById(trainingId)
+	if training == nil {
+		return false
+	}
+
+	var promo *database.PromoCode
+	if promoCodeId := state.GetPromoCodeID(); promoCodeId != 0 {
+		promo, _ = repo.GetPromoCodeByID(promoCodeId)
+	}
+
+	showTrainingRegistrationConfirmation(botUrl, chatId, messageId, training, registrationParticipant(state, trainingId, repo), promo, repo)
+	return true
+}
+
+// formatRegistrationPrice описывает цену тренировки и скидку промокода на экране подтверждения
+func formatRegistrationPrice(training *database.Training, promo *database.PromoCode, repo database.ContentRepositoryInterface) string {
+	price, _ := repo.GetTrainingPrice(training)
+
+	var builder strings.Builder
+	if price > 0 {
+		builder.WriteString(fmt.Sprintf("💰 <b>Цена:</b> %d ₽\n", price))
+	}
+	if promo != nil {
+		if price > 0 {
+			discount := database.PromoDiscount(promo, price)
+			builder.WriteString(fmt.Sprintf("🎟 <b>Промокод %s:</b> −%d ₽\n", promo.Code, discount))
+			builder.WriteString(fmt.Sprintf("💳 <b>К оплате:</b> %d ₽\n", price-discount))
+		} else {
+			builder.WriteString(fmt.Sprintf("🎟 <b>Промокод %s:</b> %s\n", promo.Code, formatPromoDiscount(promo)))
+		}
+	}
+
+	return builder.String()
+}
+
+// applyRegistrationPromoCode применяет промокод к созданной записи и
+// возвращает строку для сообщения пользователю
+func applyRegistrationPromoCode(chatId int, registrationId uint, promoCodeId uint, repo database.ContentRepositoryInterface) string {
+	registration, err := repo.ApplyPromoCode(registrationId, promoCodeId)
+	if err != nil {
+		logger.UserError(chatId, "Промокод %d для записи %d: %v", promoCodeId, registrationId, err)
+		return "\n\n⚠️ " + errors.HandleError(err)
+	}
+
+	if registration.DiscountAmount > 0 {
+		return fmt.Sprintf("\n\n🎟 <b>Промокод применен, скидка:</b> %d ₽", registration.DiscountAmount)
+	}
+	return "\n\n🎟 <b>Промокод применен</b>"
+}
+
+// formatRegistrationDiscount описывает промокод записи для администратора
+func formatRegistrationDiscount(reg *database.TrainingRegistration, repo database.ContentRepositoryInterface) string {
+	if reg.PromoCodeID == nil {
+		return ""
+	}
+
+	code := "❓"
+	if promo, _ := repo.GetPromoCodeByID(*reg.PromoCodeID); promo != nil {
+		code = promo.Code
+	}
+	return fmt.Sprintf("🎟 %s: −%d ₽", code, reg.DiscountAmount)
+}
@@ -0,0 +1,308 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"x.localhost/rvabot/internal/database"
+	"x.localhost/rvabot/internal/logger"
+	"x.localhost/rvabot/internal/states"
+	"x.localhost/rvabot/internal/telegram"
+)
+
+// ratingTrendHeats - число последних заездов, по которым показывается динамика рейтинга
+const ratingTrendHeats = 5
+
+// leaderboardSize ограничивает число участников в таблице рейтинга
+const leaderboardSize = 20
+
+// EnterHeatResults запрашивает у тренера порядок финиша заезда на тренировке
+func EnterHeatResults(botUrl string, chatId int, messageId int, trainingId uint, repo database.ContentRepositoryInterface) states.State {
+	if !promptHeatResults(botUrl, chatId, messageId, trainingId, repo) {
+		return states.SetStartKeyboard()
+	}
+	return states.SetEnterHeatResults(trainingId)
+}
+
+// promptHeatResults показывает шаг ввода порядка финиша с пронумерованными участниками
+func promptHeatResults(botUrl string, chatId int, messageId int, trainingId uint, repo database.ContentRepositoryInterface) bool {
+	training, ok := loadResultsTraining(botUrl, chatId, messageId, trainingId, repo)
+	if !ok {
+		return false
+	}
+
+	participants, err := heatParticipants(training.ID, repo)
+	if err != nil {
+		sendErrorMessage(botUrl, chatId, messageId, repo, err)
+		return false
+	}
+	if len(participants) < 2 {
+		telegram.SendOrEditMessage(botUrl, chatId, messageId, "ℹ️ <b>Недостаточно участников</b>\n\n"+
+			"Для заезда нужны хотя бы два подтвержденных участника.",
+			telegram.CreateBackToMenuKeyboard(fmt.Sprintf("attendanceChecklist_%d", training.ID)))
+		return false
+	}
+
+	heats, _ := repo.CountTrainingHeats(training.ID)
+
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("🏆 <b>Результаты заезда %d</b>\n\n", heats+1))
+	builder.WriteString(fmt.Sprintf("🚗 <b>Категория:</b> %s\n\n👥 <b>Участники:</b>\n", carCategoryName(training.CarCategoryID, repo)))
+	names := registrationNames(participants, repo)
+	for i, reg := range participants {
+		builder.WriteString(fmt.Sprintf("%d. %s\n", i+1, telegram.EscapeHTML(names[reg.ID])))
+	}
+	builder.WriteString("\n📝 Введите номера участников в порядке финиша через пробел.\n" +
+		"Не участвовавших в заезде пропустите.\n\n" +
+		"💡 <i>Пример: 3 1 2</i>")
+
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, builder.String(), telegram.CreateStepKeyboard())
+	return true
+}
+
+// SetHeatResults сохраняет порядок финиша заезда и пересчитывает рейтинги
+func SetHeatResults(botUrl string, chatId int, update telegram.Update, repo database.ContentRepositoryInterface, state states.State) states.State {
+	training, ok := loadResultsTraining(botUrl, chatId, 0, state.GetID(), repo)
+	if !ok {
+		return states.SetStartKeyboard()
+	}
+
+	participants, err := heatParticipants(training.ID, repo)
+	if err != nil {
+		return sendErrorMessage(botUrl, chatId, 0, repo, err)
+	}
+
+	order, errorMsg := parseFinishingOrder(update.Message.Text, len(participants))
+	if errorMsg != "" {
+		telegram.SendMessage(botUrl, chatId, "❌ <b>Неверный порядок финиша</b>\n\n"+errorMsg+
+			"\n\n💡 <i>Пример: 3 1 2</i>", telegram.CreateStepKeyboard())
+		return state
+	}
+
+	finishers := make([]database.TrainingRegistration, len(order))
+	registrationIds := make([]uint, len(order))
+	for i, number := range order {
+		finishers[i] = participants[number-1]
+		registrationIds[i] = finishers[i].ID
+	}
+
+	results, err := repo.RecordHeatResults(training.ID, registrationIds)
+	if err != nil {
+		logger.UserError(chatId, "Результаты заезда тренировки %d: %v", training.ID, err)
+		return sendErrorMessage(botUrl, chatId, 0, repo, err)
+	}
+
+	// Участник заезда точно был на тренировке
+	for _, reg := range finishers {
+		if reg.Status == database.RegistrationStatusConfirmed {
+			if _, err := repo.MarkAttendance(reg.ID, database.RegistrationStatusAttended); err != nil {
+				logger.UserError(chatId, "Отметка посещения записи %d по заезду: %v", reg.ID, err)
+			}
+		}
+	}
+
+	// Результаты идут в порядке финиша, как и записи finishers
+	names := registrationNames(finishers, repo)
+
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("✅ <b>Заезд %d сохранен</b>\n\n🚗 <b>Категория:</b> %s\n\n",
+		results[0].HeatNumber, carCategoryName(training.CarCategoryID, repo)))
+	for i, result := range results {
+		builder.WriteString(fmt.Sprintf("%s %s — ⭐ %d (%s)\n", formatHeatPosition(result.Position), telegram.EscapeHTML(names[finishers[i].ID]),
+			result.RatingAfter, formatRatingDelta(result.RatingAfter-result.RatingBefore)))
+		notifyUserAboutHeatResult(botUrl, result, &finishers[i], training, repo)
+	}
+
+	logger.UserInfo(chatId, "Заезд %d тренировки %d: участников %d", results[0].HeatNumber, training.ID, len(results))
+	telegram.SendMessage(botUrl, chatId, builder.String(), telegram.CreateHeatSavedKeyboard(training.ID))
+	return states.SetStartKeyboard()
+}
+
+// heatParticipants возвращает записи подтвержденных участников тренировки,
+// которые могут быть в заезде, в порядке записи
+func heatParticipants(trainingId uint, repo database.ContentRepositoryInterface) ([]database.TrainingRegistration, error) {
+	registrations, err := repo.GetAttendanceRegistrations(trainingId)
+	if err != nil {
+		return nil, err
+	}
+
+	var participants []database.TrainingRegistration
+	for _, reg := range registrations {
+		if reg.Status != database.RegistrationStatusNoShow {
+			participants = append(participants, reg)
+		}
+	}
+	return participants, nil
+}
+
+// parseFinishingOrder разбирает номера участников в порядке финиша. Возвращает
+// текст ошибки, если номера не подходят.
+func parseFinishingOrder(text string, participants int) ([]int, string) {
+	fields := strings.FieldsFunc(text, func(r rune) bool { return r == ' ' || r == ',' || r == '\n' || r == '\t' })
+	if len(fields) < 2 {
+		return nil, "В заезде должно быть хотя бы два участника."
+	}
+
+	seen := make(map[int]bool)
+	order := make([]int, 0, len(fields))
+	for _, field := range fields {
+		number, err := strconv.Atoi(field)
+		if err != nil || number < 1 || number > participants {
+			return nil, fmt.Sprintf("«%s» — не номер участника от 1 до %d.", telegram.EscapeHTML(field), participants)
+		}
+		if seen[number] {
+			return nil, fmt.Sprintf("Участник %d указан дважды.", number)
+		}
+		seen[number] = true
+		order = append(order, number)
+	}
+	return order, ""
+}
+
+// notifyUserAboutHeatResult сообщает участнику место в заезде и новый рейтинг.
+// Результат участника под аккаунтом приходит его представителю.
+func notifyUserAboutHeatResult(botUrl string, result database.HeatResult, registration *database.TrainingRegistration, training *database.Training, repo database.ContentRepositoryInterface) {
+	user, _ := repo.GetUserByID(result.UserID)
+	if user == nil || user.ChatId == 0 {
+		return
+	}
+
+	trackName := "Неизвестная трасса"
+	if track, _ := repo.GetTrackByID(training.TrackID); track != nil {
+		trackName = track.Name
+	}
+
+	message := fmt.Sprintf("🏁 <b>Результат заезда</b>\n\n"+
+		"%s"+
+		"🏁 <b>Трасса:</b> %s\n"+
+		"🚗 <b>Категория:</b> %s\n"+
+		"%s <b>Место:</b> %d из %d\n"+
+		"⭐ <b>Рейтинг:</b> %d (%s)\n",
+		formatRegistrationParticipant(registration, repo), trackName, carCategoryName(training.CarCategoryID, repo), formatHeatPosition(result.Position), result.Position, result.Participants,
+		result.RatingAfter, formatRatingDelta(result.RatingAfter-result.RatingBefore))
+
+	telegram.SendMessage(botUrl, user.ChatId, message+"\n📈 Рейтинг и его динамика — в разделе «Мои результаты».", telegram.CreateBaseKeyboard())
+}
+
+// formatUserRatings выводит рейтинги пользователя с динамикой за последние заезды
+func formatUserRatings(user *database.User, ratings []database.UserRating, repo database.ContentRepositoryInterface) string {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("⭐ <b>Рейтинг:</b> %d\n", user.EloRating))
+
+	categoryName := carCategoryNames(repo)
+	for _, ur := range ratings {
+		builder.WriteString(fmt.Sprintf("🚗 %s: <b>%d</b>, заездов: %d", categoryName(ur.CarCategoryID), ur.Rating, ur.Heats))
+		if history, err := repo.GetRatingHistory(user.ID, 0, ur.CarCategoryID, ratingTrendHeats); err == nil && len(history) > 0 {
+			oldest := history[len(history)-1]
+			builder.WriteString(fmt.Sprintf(" %s %s", ratingTrendIcon(ur.Rating-oldest.RatingBefore), formatRatingDelta(ur.Rating-oldest.RatingBefore)))
+		}
+		builder.WriteString("\n")
+	}
+	return builder.String()
+}
+
+// ViewRatingCategories показывает категории карта, по которым есть таблицы рейтинга
+func ViewRatingCategories(botUrl string, chatId int, messageId int, repo database.ContentRepositoryInterface) states.State {
+	categories, err := repo.GetRatingCategories()
+	if err != nil {
+		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
+	}
+
+	if len(categories) == 0 {
+		telegram.EditMessage(botUrl, chatId, messageId, "🏆 <b>Таблицы рейтинга</b>\n\n"+
+			"📭 Пока нет результатов заездов.", telegram.CreateBackToMenuKeyboard("myResults"))
+		return states.SetStartKeyboard()
+	}
+
+	telegram.EditMessage(botUrl, chatId, messageId, "🏆 <b>Таблицы рейтинга</b>\n\n"+
+		"Выберите категорию карта:", telegram.CreateRatingCategoriesKeyboard(categories))
+	return states.SetStartKeyboard()
+}
+
+// ViewRatingLeaderboard показывает таблицу рейтинга категории, к которой
+// относится рейтинг ratingId
+func ViewRatingLeaderboard(botUrl string, chatId int, messageId int, ratingId uint, repo database.ContentRepositoryInterface) states.State {
+	ref, err := repo.GetUserRatingByID(ratingId)
+	if err != nil || ref == nil {
+		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
+	}
+
+	leaders, err := repo.GetRatingLeaderboard(ref.CarCategoryID, leaderboardSize)
+	if err != nil {
+		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
+	}
+
+	viewer, _ := repo.GetUserByChatId(chatId)
+
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("🏆 <b>Рейтинг: %s</b>\n\n", carCategoryName(ref.CarCategoryID, repo)))
+	viewerShown := false
+	for i, ur := range leaders {
+		name := "Неизвестный"
+		if ur.ParticipantID != 0 {
+			if participant, _ := repo.GetParticipantByID(ur.ParticipantID); participant != nil {
+				name = participant.Name
+			}
+		} else if user, _ := repo.GetUserByID(ur.UserID); user != nil {
+			name = user.Name
+		}
+		line := fmt.Sprintf("%s %s — <b>%d</b> (заездов: %d)", formatHeatPosition(i+1), telegram.EscapeHTML(name), ur.Rating, ur.Heats)
+		if viewer != nil && ur.UserID == viewer.ID && ur.ParticipantID == 0 {
+			line = "👉 " + line
+			viewerShown = true
+		}
+		builder.WriteString(line + "\n")
+	}
+
+	if viewer != nil && !viewerShown {
+		if ratings, err := repo.GetUserRatings(viewer.ID); err == nil {
+			for _, ur := range ratings {
+				if database.SameCarCategory(ur.CarCategoryID, ref.CarCategoryID) {
+					builder.WriteString(fmt.Sprintf("\n👉 <b>Ваш рейтинг:</b> %d (заездов: %d)\n", ur.Rating, ur.Heats))
+				}
+			}
+		}
+	}
+
+	telegram.EditMessage(botUrl, chatId, messageId, builder.String(), telegram.CreateBackToMenuKeyboard("ratingCategories"))
+	return states.SetStartKeyboard()
+}
+
+// formatHeatPosition выводит место медалью для призеров и номером для остальных
+func formatHeatPosition(position int) string {
+	switch position {
+	case 1:
+		return "🥇"
+	case 2:
+		return "🥈"
+	case 3:
+		return "🥉"
+	default:
+		return fmt.Sprintf("%d.", position)
+	}
+}
+
+// formatRatingDelta выводит изменение рейтинга со знаком
+func formatRatingDelta(delta int) string {
+	switch {
+	case delta > 0:
+		return fmt.Sprintf("+%d", delta)
+	case delta < 0:
+		return fmt.Sprintf("−%d", -delta)
+	default:
+		return "±0"
+	}
+}
+
+// ratingTrendIcon - значок направления изменения рейтинга
+func ratingTrendIcon(delta int) string {
+	switch {
+	case delta > 0:
+		return "📈"
+	case delta < 0:
+		return "📉"
+	default:
+		return "➖"
+	}
+}
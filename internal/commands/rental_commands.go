@@ -0,0 +1,358 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"x.localhost/rvabot/internal/database"
+	"x.localhost/rvabot/internal/errors"
+	"x.localhost/rvabot/internal/logger"
+	"x.localhost/rvabot/internal/states"
+	"x.localhost/rvabot/internal/telegram"
+	"x.localhost/rvabot/internal/timefmt"
+	"x.localhost/rvabot/internal/validation"
+)
+
+// rentalKindNames - названия видов экипировки для сообщений и кнопок
+var rentalKindNames = map[string]string{
+	database.RentalKindHelmet: "🪖 Шлем",
+	database.RentalKindSuit:   "🥋 Комбинезон",
+	database.RentalKindGloves: "🧤 Перчатки",
+}
+
+// rentalKindAliases - как администратор может назвать вид экипировки при вводе остатка
+var rentalKindAliases = map[string]string{
+	"шлем":       database.RentalKindHelmet,
+	"helmet":     database.RentalKindHelmet,
+	"комбинезон": database.RentalKindSuit,
+	"suit":       database.RentalKindSuit,
+	"перчатки":   database.RentalKindGloves,
+	"gloves":     database.RentalKindGloves,
+}
+
+// ViewTrackRentalStock показывает склад прокатной экипировки трассы
+func ViewTrackRentalStock(botUrl string, chatId int, messageId int, trackId uint, repo database.ContentRepositoryInterface) states.State {
+	if !database.IsAdmin(chatId, repo) {
+		return SendAccessDeniedMessage(botUrl, chatId, messageId)
+	}
+
+	track, err := repo.GetTrackByID(trackId)
+	if err != nil || track == nil {
+		telegram.SendOrEditMessage(botUrl, chatId, messageId, "❌ Трасса не найдена.", telegram.CreateBackToTracksMenuKeyboard())
+		return states.SetAdminKeyboard()
+	}
+
+	items, err := repo.GetTrackRentalStock(trackId)
+	if err != nil {
+		telegram.SendOrEditMessage(botUrl, chatId, messageId, "❌ <b>Ошибка загрузки склада экипировки</b>\n\n"+
+			"Попробуйте позже.", telegram.CreateBackToTracksMenuKeyboard())
+		return states.SetAdminKeyboard()
+	}
+
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("🪖 <b>Прокат экипировки: %s</b>\n\n", track.Name))
+	if len(items) == 0 {
+		builder.WriteString("📭 Экипировка не заведена. Пока склад пуст, участники не могут заказать прокат.\n")
+	}
+
+	for _, kind := range database.RentalKinds {
+		var sizes []string
+		for _, item := range items {
+			if item.Kind == kind {
+				sizes = append(sizes, fmt.Sprintf("%s — %d шт.", item.Size, item.Quantity))
+			}
+		}
+		if len(sizes) > 0 {
+			builder.WriteString(fmt.Sprintf("<b>%s:</b> %s\n", rentalKindNames[kind], strings.Join(sizes, ", ")))
+		}
+	}
+
+	builder.WriteString("\n💡 Экипировка бронируется, когда тренер подтверждает запись, и освобождается при отмене.")
+
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, builder.String(), telegram.CreateTrackRentalStockKeyboard(trackId))
+	return states.SetAdminKeyboard()
+}
+
+// AddRentalStock запрашивает вид, размер и остаток экипировки
+func AddRentalStock(botUrl string, chatId int, messageId int, trackId uint, repo database.ContentRepositoryInterface) states.State {
+	if !database.IsAdmin(chatId, repo) {
+		return SendAccessDeniedMessage(botUrl, chatId, messageId)
+	}
+
+	promptRentalStock(botUrl, chatId, messageId)
+	return states.SetSetRentalStock(trackId)
+}
+
+// promptRentalStock показывает шаг ввода остатка экипировки
+func promptRentalStock(botUrl string, chatId int, messageId int) {
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, "🪖 <b>Остаток экипировки</b>\n\n"+
+		"📝 Введите вид, размер и число штук через пробел.\n"+
+		"Виды: шлем, комбинезон, перчатки. Ноль убирает размер из проката.\n\n"+
+		"💡 <i>Пример: шлем M 4</i>", telegram.CreateStepKeyboard())
+}
+
+// SetRentalStock задает остаток экипировки на складе трассы
+func SetRentalStock(botUrl string, chatId int, update telegram.Update, repo database.ContentRepositoryInterface, state states.State) states.State {
+	if !database.IsAdmin(chatId, repo) {
+		return SendAccessDeniedMessage(botUrl, chatId, 0)
+	}
+
+	fields := strings.Fields(update.Message.Text)
+	kind, ok := "", false
+	if len(fields) == 3 {
+		kind, ok = rentalKindAliases[strings.ToLower(fields[0])]
+	}
+	if !ok {
+		telegram.SendMessage(botUrl, chatId, "❌ <b>Неверный формат</b>\n\n"+
+			"📝 Введите вид (шлем, комбинезон или перчатки), размер и число штук.\n"+
+			"💡 <i>Пример: шлем M 4</i>", telegram.CreateStepKeyboard())
+		return state
+	}
+	size := strings.ToUpper(fields[1])
+
+	validator := validation.NewValidator()
+	quantity, result := validator.ValidateRentalStock(size, fields[2])
+	if !result.IsValid {
+		telegram.SendMessage(botUrl, chatId, "❌ <b>Ошибка валидации</b>\n\n"+strings.Join(result.GetErrorMessages(), "\n")+
+			"\n\n🔄 Попробуйте еще раз:", telegram.CreateStepKeyboard())
+		return state
+	}
+
+	trackId := state.GetID()
+	if err := repo.SetRentalStock(trackId, kind, size, quantity); err != nil {
+		logger.AdminError(chatId, "Остаток экипировки %s %s на трассе %d: %v", kind, size, trackId, err)
+		telegram.SendMessage(botUrl, chatId, "❌ <b>Не удалось сохранить остаток</b>\n\n"+
+			errors.HandleError(err), telegram.CreateStepKeyboard())
+		return state
+	}
+
+	logger.AdminInfo(chatId, "Экипировка %s %s на трассе %d: %d шт.", kind, size, trackId, quantity)
+	return ViewTrackRentalStock(botUrl, chatId, 0, trackId, repo)
+}
+
+// ViewRegistrationRentals показывает экипировку, которую можно заказать к записи
+func ViewRegistrationRentals(botUrl string, chatId int, messageId int, registrationId uint, repo database.ContentRepositoryInterface) states.State {
+	registration, training, ok := loadOwnBooking(botUrl, chatId, messageId, registrationId, repo)
+	if !ok {
+		return states.SetStartKeyboard()
+	}
+
+	if !database.CanRequestRental(registration.Status) {
+		telegram.SendOrEditMessage(botUrl, chatId, messageId, "ℹ️ <b>Экипировку можно заказать только к ожидающей или подтвержденной записи</b>",
+			telegram.CreateBackToMenuKeyboard("myBookings"))
+		return states.SetStartKeyboard()
+	}
+
+	items, err := repo.GetRentalAvailability(training, registrationId)
+	if err != nil {
+		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
+	}
+	picks, err := repo.GetRegistrationRentals(registrationId)
+	if err != nil {
+		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
+	}
+
+	if len(items) == 0 && len(picks) == 0 {
+		telegram.SendOrEditMessage(botUrl, chatId, messageId, "🪖 <b>Прокат экипировки</b>\n\n"+
+			"📭 На этой трассе экипировка в прокат не выдается.", telegram.CreateBackToMenuKeyboard("myBookings"))
+		return states.SetStartKeyboard()
+	}
+
+	trackName := "Неизвестная трасса"
+	if track, _ := repo.GetTrackByID(training.TrackID); track != nil {
+		trackName = track.Name
+	}
+
+	var builder strings.Builder
+	builder.WriteString("🪖 <b>Прокат экипировки</b>\n\n")
+	builder.WriteString(fmt.Sprintf("🏁 <b>Трасса:</b> %s\n", trackName))
+	builder.WriteString(fmt.Sprintf("📅 <b>Дата:</b> %s\n\n", timefmt.DateTime(training.StartTime, repo.GetViewerLocation(chatId, training.TrackID))))
+
+	selected := make(map[uint]bool, len(picks))
+	if len(picks) == 0 {
+		builder.WriteString("📭 Вы пока ничего не заказали.\n")
+	} else {
+		builder.WriteString("<b>Ваш заказ:</b>\n")
+		for _, pick := range picks {
+			selected[pick.RentalItemID] = true
+			builder.WriteString(fmt.Sprintf("   %s %s — %s\n", rentalKindNames[pick.Kind], pick.Size, formatRentalStatus(pick.Status)))
+		}
+	}
+
+	builder.WriteString("\n💡 Выберите по одному размеру каждого вида, повторное нажатие отменяет заказ. " +
+		"К подтвержденной записи экипировка бронируется сразу, к ожидающей — после подтверждения тренером.")
+
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, builder.String(),
+		telegram.CreateRentalItemsKeyboard(items, selected, rentalKindNames))
+	return states.SetSelectRental(registrationId)
+}
+
+// ToggleRentalItem заказывает размер экипировки к записи из состояния или отменяет заказ
+func ToggleRentalItem(botUrl string, chatId int, messageId int, rentalItemId uint, repo database.ContentRepositoryInterface, state states.State) states.State {
+	if state.Type != states.StateSelectRental || state.GetID() == 0 {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Запись не выбрана</b>\n\n"+
+			"Откройте прокат из раздела «Мои записи».", telegram.CreateBaseKeyboard())
+		return states.SetStartKeyboard()
+	}
+
+	registration, _, ok := loadOwnBooking(botUrl, chatId, messageId, state.GetID(), repo)
+	if !ok {
+		return states.SetStartKeyboard()
+	}
+
+	picks, err := repo.GetRegistrationRentals(registration.ID)
+	if err != nil {
+		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
+	}
+	for _, pick := range picks {
+		if pick.RentalItemID != rentalItemId {
+			continue
+		}
+		if err := repo.CancelRentalRequest(registration.ID, rentalItemId); err != nil {
+			return sendErrorMessage(botUrl, chatId, messageId, repo, err)
+		}
+		logger.UserInfo(chatId, "Запись %d: отказ от экипировки %d", registration.ID, rentalItemId)
+		return ViewRegistrationRentals(botUrl, chatId, messageId, registration.ID, repo)
+	}
+
+	if _, err := repo.RequestRental(registration.ID, rentalItemId); err != nil {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ "+errors.HandleError(err),
+			telegram.CreateBackToMenuKeyboard(fmt.Sprintf("rentals_%d", registration.ID)))
+		return states.SetStartKeyboard()
+	}
+
+	logger.UserInfo(chatId, "Запись %d: заказана экипировка %d", registration.ID, rentalItemId)
+	return ViewRegistrationRentals(botUrl, chatId, messageId, registration.ID, repo)
+}
+
+// ViewTrainingRentalPickList показывает тренеру, какую экипировку подготовить к тренировке
+func ViewTrainingRentalPickList(botUrl string, chatId int, messageId int, trainingId uint, repo database.ContentRepositoryInterface) states.State {
+	training, _ := repo.GetTrainingById(trainingId)
+	if training == nil {
+		telegram.SendOrEditMessage(botUrl, chatId, messageId, "❌ <b>Тренировка не найдена</b>", telegram.CreateBaseKeyboard())
+		return states.SetStartKeyboard()
+	}
+	if !database.IsAdmin(chatId, repo) && !isTrainingTrainer(chatId, training, repo) {
+		return SendAccessDeniedMessage(botUrl, chatId, messageId)
+	}
+
+	picks, err := repo.GetTrainingRentals(trainingId)
+	if err != nil {
+		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
+	}
+
+	names := make(map[uint]string)
+	for _, pick := range picks {
+		if _, ok := names[pick.UserID]; ok {
+			continue
+		}
+		names[pick.UserID] = "Участник"
+		if user, _ := repo.GetUserByID(pick.UserID); user != nil {
+			names[pick.UserID] = user.Name
+		}
+	}
+
+	trackName := "❓ Неизвестная"
+	if track, _ := repo.GetTrackByID(training.TrackID); track != nil {
+		trackName = track.Name
+	}
+
+	var builder strings.Builder
+	builder.WriteString("🪖 <b>Экипировка к выдаче</b>\n\n")
+	builder.WriteString(fmt.Sprintf("🏁 <b>Трасса:</b> %s\n", trackName))
+	builder.WriteString(fmt.Sprintf("📅 <b>Дата:</b> %s\n\n", timefmt.DateTime(training.StartTime, repo.GetViewerLocation(chatId, training.TrackID))))
+
+	if len(picks) == 0 {
+		builder.WriteString("📭 <b>Участники не заказывали экипировку</b>")
+	} else {
+		writeRentalPickSection(&builder, "✅ <b>Забронировано:</b>", picks, database.RentalStatusReserved, names)
+		writeRentalPickSection(&builder, "⏳ <b>Ждет подтверждения записи:</b>", picks, database.RentalStatusRequested, names)
+		writeRentalPickSection(&builder, "⚠️ <b>Не хватило на складе:</b>", picks, database.RentalStatusUnavailable, names)
+	}
+
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, builder.String(),
+		telegram.CreateBackToMenuKeyboard(fmt.Sprintf("viewRegistrations_%d", trainingId)))
+	return states.SetStartKeyboard()
+}
+
+// writeRentalPickSection выводит экипировку с одним статусом, сгруппированную
+// по виду и размеру, с именами участников
+func writeRentalPickSection(builder *strings.Builder, title string, picks []database.RentalPick, status string, names map[uint]string) {
+	var group []database.RentalPick
+	for _, pick := range picks {
+		if pick.Status == status {
+			group = append(group, pick)
+		}
+	}
+	if len(group) == 0 {
+		return
+	}
+
+	builder.WriteString(title + "\n")
+	var participants []string
+	for i, pick := range group {
+		participants = append(participants, names[pick.UserID])
+		// Заказы отсортированы по виду и размеру, размер заканчивается на смене ID
+		if i == len(group)-1 || group[i+1].RentalItemID != pick.RentalItemID {
+			builder.WriteString(fmt.Sprintf("   %s %s × %d — %s\n", rentalKindNames[pick.Kind], pick.Size,
+				len(participants), strings.Join(participants, ", ")))
+			participants = nil
+		}
+	}
+	builder.WriteString("\n")
+}
+
+// reserveRegistrationRentals бронирует заказанную экипировку при подтверждении записи
+// и возвращает строки для сообщения участнику
+func reserveRegistrationRentals(chatId int, registrationId uint, repo database.ContentRepositoryInterface) string {
+	picks, err := repo.ReserveRentals(registrationId)
+	if err != nil {
+		logger.UserError(chatId, "Бронь экипировки записи %d: %v", registrationId, err)
+		return ""
+	}
+	if len(picks) == 0 {
+		return ""
+	}
+
+	return "\n\n🪖 <b>Экипировка:</b>\n" + formatRentalPicks(picks)
+}
+
+// formatRentalPicks перечисляет экипировку записи со статусами
+func formatRentalPicks(picks []database.RentalPick) string {
+	lines := make([]string, 0, len(picks))
+	for _, pick := range picks {
+		lines = append(lines, fmt.Sprintf("   %s %s — %s", rentalKindNames[pick.Kind], pick.Size, formatRentalStatus(pick.Status)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatRentalStatus возвращает описание статуса заказа экипировки
+func formatRentalStatus(status string) string {
+	switch status {
+	case database.RentalStatusReserved:
+		return "забронировано"
+	case database.RentalStatusUnavailable:
+		return "⚠️ не хватило на складе"
+	default:
+		return "ждет подтверждения записи"
+	}
+}
+
+// trackHasRentals проверяет, выдается ли на трассе тренировки экипировка в прокат
+func trackHasRentals(trainingId uint, repo database.ContentRepositoryInterface) bool {
+	training, _ := repo.GetTrainingById(trainingId)
+	if training == nil {
+		return false
+	}
+
+	items, err := repo.GetTrackRentalStock(training.TrackID)
+	if err != nil {
+		return false
+	}
+	for _, item := range items {
+		if item.Quantity > 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,367 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"x.localhost/rvabot/internal/database"
+	"x.localhost/rvabot/internal/errors"
+	"x.localhost/rvabot/internal/logger"
+	"x.localhost/rvabot/internal/states"
+	"x.localhost/rvabot/internal/telegram"
+	"x.localhost/rvabot/internal/timefmt"
+)
+
+// weekdayNames - короткие названия дней недели по time.Weekday
+var weekdayNames = map[time.Weekday]string{
+	time.Monday:    "Пн",
+	time.Tuesday:   "Вт",
+	time.Wednesday: "Ср",
+	time.Thursday:  "Чт",
+	time.Friday:    "Пт",
+	time.Saturday:  "Сб",
+	time.Sunday:    "Вс",
+}
+
+// Действия над тренировкой, для которых у серии спрашивается область применения
+const (
+	seriesActionCategory     = "category"
+	seriesActionParticipants = "participants"
+	seriesActionDelete       = "delete"
+	seriesActionPrice        = "price"
+	seriesActionSkillLevel   = "skillLevel"
+)
+
+// promptTrainingRecurrence показывает шаг выбора повторения тренировки
+func promptTrainingRecurrence(botUrl string, chatId int, messageId int) {
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, "🔁 <b>Повторять тренировку?</b>\n\n"+
+		"💡 Еженедельная серия создаст занятия в выбранные дни недели.", telegram.CreateTrainingRecurrenceKeyboard())
+}
+
+// showTrainingWeekdays показывает шаг выбора дней недели серии
+func showTrainingWeekdays(botUrl string, chatId int, messageId int, tempData *states.TempTrainingData) {
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, "📆 <b>Дни недели</b>\n\n"+
+		"Отметьте дни, по которым повторяется тренировка, и нажмите «Далее».", telegram.CreateWeekdaysKeyboard(tempData.Weekdays))
+}
+
+// promptTrainingRecurrenceEnd показывает шаг ввода окончания серии
+func promptTrainingRecurrenceEnd(botUrl string, chatId int, messageId int) {
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, fmt.Sprintf("🏁 <b>Окончание серии</b>\n\n"+
+		"Введите дату последнего занятия или количество занятий (не больше %d).\n\n"+
+		"💡 <i>Пример: 2024-03-01 или 8</i>", database.MaxSeriesOccurrences), telegram.CreateStepKeyboard())
+}
+
+// copyTempTrainingData копирует данные шага, чтобы история навигации не менялась
+func copyTempTrainingData(tempData *states.TempTrainingData) *states.TempTrainingData {
+	copied := *tempData
+	copied.Weekdays = append([]int(nil), tempData.Weekdays...)
+	return &copied
+}
+
+// SetTrainingRecurrence обрабатывает выбор однократной или еженедельной тренировки
+func SetTrainingRecurrence(botUrl string, chatId int, messageId int, repo database.ContentRepositoryInterface, state states.State, recurring bool) states.State {
+	if state.Type != states.StateSetTrainingRecurrence {
+		return state
+	}
+
+	tempData := copyTempTrainingData(state.GetTempTrainingData())
+	tempData.Recurring = recurring
+
+	if !recurring {
+		showTrainingCreationConfirmation(botUrl, chatId, messageId, repo, tempData)
+		return states.SetConfirmTrainingCreation().SetTempTrainingData(tempData)
+	}
+
+	// По умолчанию серия повторяется в день недели первого занятия
+	if len(tempData.Weekdays) == 0 {
+		if start, err := time.Parse(timefmt.InputLayout, tempData.StartTime); err == nil {
+			tempData.Weekdays = []int{int(start.Weekday())}
+		}
+	}
+
+	showTrainingWeekdays(botUrl, chatId, messageId, tempData)
+	return states.SetSetTrainingWeekdays().SetTempTrainingData(tempData)
+}
+
+// ToggleTrainingWeekday отмечает или снимает день недели серии
+func ToggleTrainingWeekday(botUrl string, chatId int, messageId int, day int, state states.State) states.State {
+	if state.Type != states.StateSetTrainingWeekdays || day < 0 || day > 6 {
+		return state
+	}
+
+	tempData := copyTempTrainingData(state.GetTempTrainingData())
+
+	var weekdays []int
+	found := false
+	for _, d := range tempData.Weekdays {
+		if d == day {
+			found = true
+			continue
+		}
+		weekdays = append(weekdays, d)
+	}
+	if !found {
+		weekdays = append(weekdays, day)
+	}
+	tempData.Weekdays = weekdays
+
+	showTrainingWeekdays(botUrl, chatId, messageId, tempData)
+	return states.SetSetTrainingWeekdays().SetTempTrainingData(tempData)
+}
+
+// SetTrainingWeekdaysDone завершает выбор дней недели
+func SetTrainingWeekdaysDone(botUrl string, chatId int, messageId int, state states.State) states.State {
+	if state.Type != states.StateSetTrainingWeekdays {
+		return state
+	}
+
+	tempData := state.GetTempTrainingData()
+	if len(tempData.Weekdays) == 0 {
+		telegram.SendOrEditMessage(botUrl, chatId, messageId, "⚠️ <b>Не выбрано ни одного дня</b>\n\n"+
+			"Отметьте хотя бы один день недели.", telegram.CreateWeekdaysKeyboard(nil))
+		return state
+	}
+
+	promptTrainingRecurrenceEnd(botUrl, chatId, messageId)
+	return states.SetSetTrainingRecurrenceEnd().SetTempTrainingData(copyTempTrainingData(tempData))
+}
+
+// SetTrainingRecurrenceEnd принимает дату окончания или количество занятий серии
+func SetTrainingRecurrenceEnd(botUrl string, chatId int, update telegram.Update, repo database.ContentRepositoryInterface, state states.State) states.State {
+	input := strings.TrimSpace(update.Message.Text)
+	tempData := copyTempTrainingData(state.GetTempTrainingData())
+	tempData.RepeatUntil = ""
+	tempData.RepeatCount = 0
+
+	if count, err := strconv.Atoi(input); err == nil {
+		if count < 1 || count > database.MaxSeriesOccurrences {
+			telegram.SendMessage(botUrl, chatId, fmt.Sprintf("❌ <b>Неверное количество занятий</b>\n\n"+
+				"Введите число от 1 до %d.", database.MaxSeriesOccurrences), telegram.CreateStepKeyboard())
+			return state
+		}
+		tempData.RepeatCount = count
+	} else {
+		// Даты сравниваются по местному времени трассы, в котором они введены
+		until, err := time.Parse(timefmt.DateInputLayout, input)
+		start, startErr := time.Parse(timefmt.InputLayout, tempData.StartTime)
+		if err != nil {
+			telegram.SendMessage(botUrl, chatId, "❌ <b>Неверный формат</b>\n\n"+
+				"Введите дату (ГГГГ-ММ-ДД) или количество занятий.\n"+
+				"💡 <i>Пример: 2024-03-01 или 8</i>", telegram.CreateStepKeyboard())
+			return state
+		}
+		if startErr == nil && until.Before(start.Truncate(24*time.Hour)) {
+			telegram.SendMessage(botUrl, chatId, "❌ <b>Дата окончания раньше первого занятия</b>\n\n"+
+				"Введите более позднюю дату.", telegram.CreateStepKeyboard())
+			return state
+		}
+		tempData.RepeatUntil = input
+	}
+
+	loc := repo.GetTrackLocation(tempData.TrackID)
+	series, err := buildTrainingSeries(tempData, loc)
+	if err != nil || len(series.OccurrenceStarts(loc)) == 0 {
+		telegram.SendMessage(botUrl, chatId, "❌ <b>Нет ни одного занятия</b>\n\n"+
+			"В выбранный период нет отмеченных дней недели. Введите другую дату или количество.", telegram.CreateStepKeyboard())
+		return state
+	}
+
+	showTrainingCreationConfirmation(botUrl, chatId, 0, repo, tempData)
+	return states.SetConfirmTrainingCreation().SetTempTrainingData(tempData)
+}
+
+// buildTrainingSeries собирает серию из данных диалога создания тренировки,
+// введенных в часовом поясе трассы loc
+func buildTrainingSeries(tempData *states.TempTrainingData, loc *time.Location) (*database.TrainingSeries, error) {
+	start, err := timefmt.ParseDateTime(tempData.StartTime, loc)
+	if err != nil {
+		return nil, err
+	}
+	end, err := timefmt.ParseDateTime(tempData.EndTime, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	weekdays := make([]time.Weekday, 0, len(tempData.Weekdays))
+	for _, d := range tempData.Weekdays {
+		weekdays = append(weekdays, time.Weekday(d))
+	}
+
+	series := &database.TrainingSeries{
+		TrainerID:       tempData.TrainerID,
+		TrackID:         tempData.TrackID,
+		Weekdays:        database.FormatWeekdays(weekdays),
+		FirstStart:      start,
+		DurationMinutes: int(end.Sub(start).Minutes()),
+		Occurrences:     tempData.RepeatCount,
+		MaxParticipants: tempData.MaxParticipants,
+		CarCategoryID:   tempData.CarCategoryID,
+	}
+
+	if tempData.RepeatUntil != "" {
+		until, err := timefmt.ParseDate(tempData.RepeatUntil, loc)
+		if err != nil {
+			return nil, err
+		}
+		// Последний день серии включается целиком
+		until = until.In(loc).AddDate(0, 0, 1).Add(-time.Minute).UTC()
+		series.Until = &until
+	}
+
+	return series, nil
+}
+
+// formatWeekdays выводит дни недели серии, например "Пн, Ср, Пт"
+func formatWeekdays(value string) string {
+	var names []string
+	for _, d := range database.ParseWeekdays(value) {
+		names = append(names, weekdayNames[d])
+	}
+	return strings.Join(names, ", ")
+}
+
+// formatRecurrenceSummary описывает повторение для подтверждения создания
+func formatRecurrenceSummary(tempData *states.TempTrainingData, loc *time.Location) string {
+	series, err := buildTrainingSeries(tempData, loc)
+	if err != nil {
+		return ""
+	}
+
+	starts := series.OccurrenceStarts(loc)
+	summary := fmt.Sprintf("🔁 <b>Повтор:</b> еженедельно, %s\n", formatWeekdays(series.Weekdays))
+	if len(starts) > 0 {
+		summary += fmt.Sprintf("📆 <b>Занятий:</b> %d (последнее %s)\n", len(starts), timefmt.Date(starts[len(starts)-1], loc))
+	}
+	return summary
+}
+
+// ConfirmTrainingSeriesCreation создает серию и все ее занятия
+func ConfirmTrainingSeriesCreation(botUrl string, chatId int, messageId int, repo database.ContentRepositoryInterface, tempData *states.TempTrainingData) states.State {
+	series, err := buildTrainingSeries(tempData, repo.GetTrackLocation(tempData.TrackID))
+	if err != nil {
+		logger.AdminError(chatId, "Данные серии: %v", err)
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Ошибка создания серии</b>\n\n"+
+			"Неверный формат времени.", telegram.CreateBackToScheduleMenuKeyboard())
+		return states.SetAdminKeyboard()
+	}
+
+	if series.DurationMinutes <= 0 {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Ошибка создания серии</b>\n\n"+
+			"Время окончания должно быть после времени начала.", telegram.CreateBackToScheduleMenuKeyboard())
+		return states.SetAdminKeyboard()
+	}
+
+	if series.FirstStart.Before(time.Now()) {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Ошибка создания серии</b>\n\n"+
+			"Время начала тренировки не может быть в прошлом.", telegram.CreateBackToScheduleMenuKeyboard())
+		return states.SetAdminKeyboard()
+	}
+
+	if rejectScheduleConflicts(botUrl, chatId, messageId, repo, tempData) {
+		return states.SetAdminKeyboard()
+	}
+
+	trainings, err := repo.CreateTrainingSeries(series)
+	if err != nil {
+		logger.AdminError(chatId, "Создание серии: %v", err)
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Ошибка создания серии</b>\n\n"+
+			errors.HandleError(err), telegram.CreateBackToScheduleMenuKeyboard())
+		return states.SetAdminKeyboard()
+	}
+
+	last := trainings[len(trainings)-1]
+	loc := repo.GetViewerLocation(chatId, series.TrackID)
+	logger.AdminInfo(chatId, "Серия создана: %d, занятий %d", series.ID, len(trainings))
+	telegram.EditMessage(botUrl, chatId, messageId, fmt.Sprintf("✅ <b>Серия тренировок создана!</b>\n\n"+
+		"🔁 %s\n"+
+		"📆 Занятий: %d\n"+
+		"🕐 Первое: %s\n"+
+		"🕕 Последнее: %s",
+		formatWeekdays(series.Weekdays), len(trainings),
+		timefmt.DateTime(trainings[0].StartTime, loc), timefmt.DateTime(last.StartTime, loc)), telegram.CreateBackToScheduleMenuKeyboard())
+	return states.SetAdminKeyboard()
+}
+
+// showSeriesScopeSelection спрашивает, к каким занятиям серии применить действие
+func showSeriesScopeSelection(botUrl string, chatId int, messageId int, training *database.Training, action string, repo database.ContentRepositoryInterface) states.State {
+	weekdays := ""
+	if series, _ := repo.GetTrainingSeriesByID(*training.SeriesID); series != nil {
+		weekdays = " (" + formatWeekdays(series.Weekdays) + ")"
+	}
+
+	telegram.EditMessage(botUrl, chatId, messageId, fmt.Sprintf("🔁 <b>Тренировка входит в серию%s</b>\n\n"+
+		"📅 <b>Занятие:</b> %s\n\n"+
+		"❓ К каким занятиям применить изменение?",
+		weekdays, timefmt.DateTime(training.StartTime, repo.GetViewerLocation(chatId, training.TrackID))), telegram.CreateSeriesScopeKeyboard())
+	return states.SetSelectSeriesScope(training.ID, action)
+}
+
+// SelectSeriesScope продолжает отложенное действие с выбранной областью серии
+func SelectSeriesScope(botUrl string, chatId int, messageId int, scope string, repo database.ContentRepositoryInterface, state states.State) states.State {
+	if state.Type != states.StateSelectSeriesScope {
+		return state
+	}
+
+	if !database.IsAdmin(chatId, repo) {
+		return SendAccessDeniedMessage(botUrl, chatId, messageId)
+	}
+
+	trainingId := state.GetID()
+	action, _ := state.Data["action"].(string)
+
+	switch action {
+	case seriesActionCategory:
+		training, err := repo.GetTrainingById(trainingId)
+		if err != nil || training == nil {
+			telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Тренировка не найдена</b>", telegram.CreateBackToScheduleMenuKeyboard())
+			return states.SetAdminKeyboard()
+		}
+		if !promptEditTrainingCategory(botUrl, chatId, messageId, training, repo) {
+			return states.SetAdminKeyboard()
+		}
+		return states.SetEditTrainingCarCategory(trainingId).WithSeriesScope(scope)
+	case seriesActionParticipants:
+		training, err := repo.GetTrainingById(trainingId)
+		if err != nil || training == nil {
+			telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Тренировка не найдена</b>", telegram.CreateBackToScheduleMenuKeyboard())
+			return states.SetAdminKeyboard()
+		}
+		promptEditTrainingMaxParticipants(botUrl, chatId, messageId, training)
+		return states.SetEditTrainingMaxParticipants(trainingId).WithSeriesScope(scope)
+	case seriesActionPrice:
+		training, err := repo.GetTrainingById(trainingId)
+		if err != nil || training == nil {
+			telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Тренировка не найдена</b>", telegram.CreateBackToScheduleMenuKeyboard())
+			return states.SetAdminKeyboard()
+		}
+		promptEditTrainingPrice(botUrl, chatId, messageId, training, repo)
+		return states.SetEditTrainingPrice(trainingId).WithSeriesScope(scope)
+	case seriesActionSkillLevel:
+		training, err := repo.GetTrainingById(trainingId)
+		if err != nil || training == nil {
+			telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Тренировка не найдена</b>", telegram.CreateBackToScheduleMenuKeyboard())
+			return states.SetAdminKeyboard()
+		}
+		promptEditTrainingSkillLevel(botUrl, chatId, messageId, training)
+		return states.SetEditTrainingSkillLevel(trainingId).WithSeriesScope(scope)
+	case seriesActionDelete:
+		return showTrainingDeletionConfirmation(botUrl, chatId, messageId, trainingId, scope, repo)
+	}
+
+	logger.AdminError(chatId, "Неизвестное действие над серией: %s", action)
+	return states.SetAdminKeyboard()
+}
+
+// formatSeriesScope описывает выбранную область изменений
+func formatSeriesScope(scope string) string {
+	switch scope {
+	case database.SeriesScopeFollowing:
+		return "это и следующие занятия серии"
+	case database.SeriesScopeAll:
+		return "все будущие занятия серии"
+	default:
+		return "только это занятие"
+	}
+}
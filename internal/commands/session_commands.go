@@ -0,0 +1,535 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"x.localhost/rvabot/internal/database"
+	"x.localhost/rvabot/internal/errors"
+	"x.localhost/rvabot/internal/logger"
+	"x.localhost/rvabot/internal/states"
+	"x.localhost/rvabot/internal/telegram"
+	"x.localhost/rvabot/internal/timefmt"
+	"x.localhost/rvabot/internal/validation"
+)
+
+// sessionRequestStatusNames - статусы заявок на занятия для пользователя
+var sessionRequestStatusNames = map[string]string{
+	database.SessionRequestStatusPending:  "⏳ ждет ответа тренера",
+	database.SessionRequestStatusAccepted: "✅ занятие назначено",
+	database.SessionRequestStatusDeclined: "❌ тренер отказал",
+}
+
+// formatWindowTime выводит интервал окна или занятия, например "15.01.2024 10:00-14:00"
+func formatWindowTime(start, end time.Time, loc *time.Location) string {
+	return timefmt.DateTime(start, loc) + "-" + timefmt.Clock(end, loc)
+}
+
+// trackName возвращает название трассы или заглушку, если трасса удалена
+func trackName(trackId uint, repo database.ContentRepositoryInterface) string {
+	if track, _ := repo.GetTrackByID(trackId); track != nil {
+		return track.Name
+	}
+	return "Неизвестная трасса"
+}
+
+// loadTrainer возвращает тренера, которому принадлежит чат, или сообщает об отказе в доступе
+func loadTrainer(botUrl string, chatId int, messageId int, repo database.ContentRepositoryInterface) (*database.Trainer, bool) {
+	trainer, _ := repo.GetTrainerByChatId(chatId)
+	if trainer == nil {
+		SendAccessDeniedMessage(botUrl, chatId, messageId)
+		return nil, false
+	}
+	return trainer, true
+}
+
+// ViewTrainerAvailability показывает тренеру его окна и заявки на занятия, ожидающие ответа
+func ViewTrainerAvailability(botUrl string, chatId int, messageId int, repo database.ContentRepositoryInterface) states.State {
+	trainer, ok := loadTrainer(botUrl, chatId, messageId, repo)
+	if !ok {
+		return states.SetStartKeyboard()
+	}
+
+	windows, err := repo.GetTrainerAvailability(trainer.ID)
+	if err != nil {
+		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
+	}
+	requests, err := repo.GetPendingSessionRequests(trainer.ID)
+	if err != nil {
+		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
+	}
+
+	var builder strings.Builder
+	builder.WriteString("🗓 <b>Окна для индивидуальных занятий</b>\n\n")
+	if len(windows) == 0 {
+		builder.WriteString("📭 Окон нет. Добавьте время, когда готовы провести занятие.\n")
+	}
+	for _, w := range windows {
+		builder.WriteString(fmt.Sprintf("• %s, 🏁 %s\n",
+			formatWindowTime(w.StartTime, w.EndTime, repo.GetViewerLocation(chatId, w.TrackID)), telegram.EscapeHTML(trackName(w.TrackID, repo))))
+	}
+
+	if len(requests) > 0 {
+		builder.WriteString("\n🔔 <b>Заявки на занятия:</b>\n")
+		for _, r := range requests {
+			userName := "Неизвестный"
+			if user, _ := repo.GetUserByID(r.UserID); user != nil {
+				userName = user.Name
+			}
+			builder.WriteString(fmt.Sprintf("• %s, 👤 %s\n",
+				formatWindowTime(r.StartTime, r.EndTime, repo.GetViewerLocation(chatId, r.TrackID)), telegram.EscapeHTML(userName)))
+		}
+	}
+
+	builder.WriteString("\n💡 Пользователи предлагают время внутри окна. Принятая заявка становится тренировкой на одного участника.")
+
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, builder.String(),
+		telegram.CreateTrainerAvailabilityKeyboard(windows, requests, viewerLocation(chatId, repo)))
+	return states.SetStartKeyboard()
+}
+
+// AddAvailability начинает добавление окна: тренер выбирает трассу
+func AddAvailability(botUrl string, chatId int, messageId int, repo database.ContentRepositoryInterface) states.State {
+	if _, ok := loadTrainer(botUrl, chatId, messageId, repo); !ok {
+		return states.SetStartKeyboard()
+	}
+
+	if !showAvailabilityTrackSelection(botUrl, chatId, messageId, repo) {
+		return states.SetStartKeyboard()
+	}
+	return states.SetSelectAvailabilityTrack()
+}
+
+// showAvailabilityTrackSelection показывает шаг выбора трассы окна
+func showAvailabilityTrackSelection(botUrl string, chatId int, messageId int, repo database.ContentRepositoryInterface) bool {
+	tracks, err := repo.GetTracks()
+	if err != nil || len(tracks) == 0 {
+		telegram.SendOrEditMessage(botUrl, chatId, messageId, "❌ <b>Трассы не найдены</b>\n\n"+
+			"Попросите администратора добавить трассу.", telegram.CreateBackToAvailabilityKeyboard())
+		return false
+	}
+
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, "🏁 <b>Новое окно</b>\n\n"+
+		"Выберите трассу:", telegram.CreateAvailabilityTrackKeyboard(tracks))
+	return true
+}
+
+// SelectAvailabilityTrack сохраняет трассу окна и запрашивает время
+func SelectAvailabilityTrack(botUrl string, chatId int, messageId int, trackId uint, repo database.ContentRepositoryInterface, state states.State) states.State {
+	if state.Type != states.StateSelectAvailabilityTrack {
+		return ViewTrainerAvailability(botUrl, chatId, messageId, repo)
+	}
+
+	promptAvailability(botUrl, chatId, messageId, repo.GetTrackLocation(trackId))
+	return states.SetSetAvailability(trackId)
+}
+
+// promptAvailability показывает шаг ввода окна в часовом поясе трассы loc
+func promptAvailability(botUrl string, chatId int, messageId int, loc *time.Location) {
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, "🗓 <b>Время окна</b>\n\n"+
+		"📝 Введите дату и интервал, когда готовы провести занятие.\n\n"+
+		"🌍 Местное время трассы: "+timefmt.ZoneLabel(loc)+"\n"+
+		"💡 <i>Пример: 2024-01-15 10:00-14:00</i>", telegram.CreateStepKeyboard())
+}
+
+// parseDayRange разбирает дату и интервал времени "2006-01-02 15:04-18:00" в поясе loc
+func parseDayRange(date string, clockRange string, loc *time.Location) (time.Time, time.Time, *validation.ValidationResult) {
+	validator := validation.NewValidator()
+	from, to, result := validator.ValidateClockRange(clockRange)
+	if !result.IsValid {
+		return time.Time{}, time.Time{}, result
+	}
+
+	start, err := timefmt.ParseDateTime(date+" "+from, loc)
+	if err != nil {
+		result.AddError("date", "неверная дата. Используйте YYYY-MM-DD")
+		return time.Time{}, time.Time{}, result
+	}
+	end, _ := timefmt.ParseDateTime(date+" "+to, loc)
+	return start, end, result
+}
+
+// SetAvailability сохраняет окно тренера на выбранной трассе
+func SetAvailability(botUrl string, chatId int, update telegram.Update, repo database.ContentRepositoryInterface, state states.State) states.State {
+	trainer, _ := repo.GetTrainerByChatId(chatId)
+	if trainer == nil {
+		return SendAccessDeniedMessage(botUrl, chatId, 0)
+	}
+
+	trackId := state.GetID()
+	loc := repo.GetTrackLocation(trackId)
+
+	date, clockRange, _ := strings.Cut(strings.TrimSpace(update.Message.Text), " ")
+	start, end, result := parseDayRange(date, clockRange, loc)
+	if !result.IsValid {
+		telegram.SendMessage(botUrl, chatId, "❌ <b>Ошибка валидации</b>\n\n"+strings.Join(result.GetErrorMessages(), "\n")+
+			"\n\n💡 <i>Пример: 2024-01-15 10:00-14:00</i>", telegram.CreateStepKeyboard())
+		return state
+	}
+	if !start.After(time.Now()) {
+		telegram.SendMessage(botUrl, chatId, "❌ <b>Время в прошлом</b>\n\n"+
+			"Введите будущее время.", telegram.CreateStepKeyboard())
+		return state
+	}
+
+	conflicts, err := repo.CheckScheduleConflicts(trainer.ID, trackId, start, end)
+	if err != nil {
+		return sendErrorMessage(botUrl, chatId, 0, repo, err)
+	}
+	if len(conflicts.Closures) > 0 {
+		telegram.SendMessage(botUrl, chatId, "❌ <b>Трасса закрыта</b>\n\n"+
+			formatTrackClosure(conflicts.Closures[0])+"\n\n🔄 Выберите другой день:", telegram.CreateStepKeyboard())
+		return state
+	}
+
+	window := &database.TrainerAvailability{TrainerID: trainer.ID, TrackID: trackId, StartTime: start, EndTime: end}
+	if err := repo.CreateAvailability(window); err != nil {
+		logger.UserError(chatId, "Окно тренера %d: %v", trainer.ID, err)
+		telegram.SendMessage(botUrl, chatId, "❌ <b>Окно не добавлено</b>\n\n"+
+			errors.HandleError(err)+"\n\n🔄 Введите другое время:", telegram.CreateStepKeyboard())
+		return state
+	}
+
+	logger.UserInfo(chatId, "Тренер %d открыл окно %d", trainer.ID, window.ID)
+	return ViewTrainerAvailability(botUrl, chatId, 0, repo)
+}
+
+// DeleteAvailability закрывает окно тренера
+func DeleteAvailability(botUrl string, chatId int, messageId int, windowId uint, repo database.ContentRepositoryInterface) states.State {
+	trainer, ok := loadTrainer(botUrl, chatId, messageId, repo)
+	if !ok {
+		return states.SetStartKeyboard()
+	}
+
+	window, _ := repo.GetAvailabilityByID(windowId)
+	if window == nil || window.TrainerID != trainer.ID {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Окно не найдено</b>", telegram.CreateBackToAvailabilityKeyboard())
+		return states.SetStartKeyboard()
+	}
+
+	if err := repo.DeleteAvailability(windowId); err != nil {
+		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
+	}
+
+	logger.UserInfo(chatId, "Тренер %d закрыл окно %d", trainer.ID, windowId)
+	return ViewTrainerAvailability(botUrl, chatId, messageId, repo)
+}
+
+// StartSessionRequest показывает заявки пользователя на индивидуальные занятия
+// и трассы, на которых тренеры открыли окна
+func StartSessionRequest(botUrl string, chatId int, messageId int, repo database.ContentRepositoryInterface) states.State {
+	user, err := repo.GetUserByChatId(chatId)
+	if err != nil || user == nil {
+		telegram.SendOrEditMessage(botUrl, chatId, messageId, "❌ <b>Пользователь не найден</b>\n\n"+
+			"🔍 Сначала запишитесь на тренировку, чтобы зарегистрироваться в системе.", telegram.CreateBaseKeyboard())
+		return states.SetStartKeyboard()
+	}
+
+	if checkBookingRestriction(botUrl, chatId, messageId, user, repo) {
+		return states.SetStartKeyboard()
+	}
+
+	if !showSessionTrackSelection(botUrl, chatId, messageId, repo, user) {
+		return states.SetStartKeyboard()
+	}
+	return states.SetSelectSessionTrack()
+}
+
+// showSessionTrackSelection показывает заявки пользователя и шаг выбора трассы
+func showSessionTrackSelection(botUrl string, chatId int, messageId int, repo database.ContentRepositoryInterface, user *database.User) bool {
+	var builder strings.Builder
+	builder.WriteString("🎯 <b>Индивидуальное занятие</b>\n\n")
+
+	if requests, _ := repo.GetUserSessionRequests(user.ID); len(requests) > 0 {
+		builder.WriteString("📋 <b>Ваши заявки:</b>\n")
+		for _, r := range requests {
+			builder.WriteString(fmt.Sprintf("• %s, 🏁 %s - %s\n",
+				formatWindowTime(r.StartTime, r.EndTime, repo.GetViewerLocation(chatId, r.TrackID)),
+				telegram.EscapeHTML(trackName(r.TrackID, repo)), sessionRequestStatusNames[r.Status]))
+		}
+		builder.WriteString("\n")
+	}
+
+	tracks, err := repo.GetTracksWithAvailability()
+	if err != nil {
+		telegram.SendOrEditMessage(botUrl, chatId, messageId, "❌ <b>Ошибка загрузки окон тренеров</b>\n\n"+
+			"Попробуйте позже.", telegram.CreateBaseKeyboard())
+		return false
+	}
+	if len(tracks) == 0 {
+		builder.WriteString("📭 Сейчас у тренеров нет свободных окон. Загляните позже или запишитесь на групповую тренировку.")
+		telegram.SendOrEditMessage(botUrl, chatId, messageId, builder.String(), telegram.CreateBaseKeyboard())
+		return false
+	}
+
+	builder.WriteString("Выберите трассу, затем окно тренера и удобное вам время внутри окна.")
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, builder.String(), telegram.CreateSessionTrackKeyboard(tracks))
+	return true
+}
+
+// SelectSessionTrack показывает окна тренеров на выбранной трассе
+func SelectSessionTrack(botUrl string, chatId int, messageId int, trackId uint, repo database.ContentRepositoryInterface, state states.State) states.State {
+	if !showSessionWindowSelection(botUrl, chatId, messageId, repo, trackId) {
+		return states.SetStartKeyboard()
+	}
+	return states.SetSelectSessionWindow(trackId)
+}
+
+// showSessionWindowSelection показывает шаг выбора окна тренера на трассе
+func showSessionWindowSelection(botUrl string, chatId int, messageId int, repo database.ContentRepositoryInterface, trackId uint) bool {
+	windows, err := repo.GetTrackAvailability(trackId)
+	if err != nil || len(windows) == 0 {
+		telegram.SendOrEditMessage(botUrl, chatId, messageId, "📭 <b>Свободных окон на трассе нет</b>\n\n"+
+			"💡 Выберите другую трассу или загляните позже.", telegram.CreateBaseKeyboard())
+		return false
+	}
+
+	trainerNames := make(map[uint]string)
+	for _, w := range windows {
+		if _, ok := trainerNames[w.TrainerID]; ok {
+			continue
+		}
+		trainerNames[w.TrainerID] = "Неизвестный тренер"
+		if trainer, _ := repo.GetTrainerByID(w.TrainerID); trainer != nil {
+			trainerNames[w.TrainerID] = trainer.Name
+		}
+	}
+
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, fmt.Sprintf("🗓 <b>Окна тренеров: %s</b>\n\n"+
+		"Выберите окно, в котором хотите позаниматься:", telegram.EscapeHTML(trackName(trackId, repo))),
+		telegram.CreateSessionWindowKeyboard(windows, trainerNames, repo.GetViewerLocation(chatId, trackId)))
+	return true
+}
+
+// SelectSessionWindow запрашивает предлагаемое время занятия в выбранном окне
+func SelectSessionWindow(botUrl string, chatId int, messageId int, windowId uint, repo database.ContentRepositoryInterface, state states.State) states.State {
+	if !promptSessionSlot(botUrl, chatId, messageId, windowId, repo) {
+		return states.SetStartKeyboard()
+	}
+	return states.SetSetSessionSlot(windowId)
+}
+
+// promptSessionSlot показывает окно, уже занятое время тренера и шаг ввода интервала.
+// Интервал вводится в поясе трассы: в нем же тренер публиковал окно.
+func promptSessionSlot(botUrl string, chatId int, messageId int, windowId uint, repo database.ContentRepositoryInterface) bool {
+	window, _ := repo.GetAvailabilityByID(windowId)
+	if window == nil || !window.EndTime.After(time.Now()) {
+		telegram.SendOrEditMessage(botUrl, chatId, messageId, "❌ <b>Окно больше недоступно</b>\n\n"+
+			"💡 Выберите другое окно.", telegram.CreateBaseKeyboard())
+		return false
+	}
+
+	loc := repo.GetTrackLocation(window.TrackID)
+	trainerName := "Неизвестный тренер"
+	if trainer, _ := repo.GetTrainerByID(window.TrainerID); trainer != nil {
+		trainerName = trainer.Name
+	}
+
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("🎯 <b>Окно тренера %s</b>\n\n"+
+		"🏁 %s\n"+
+		"📅 %s\n",
+		telegram.EscapeHTML(trainerName), telegram.EscapeHTML(trackName(window.TrackID, repo)),
+		formatWindowTime(window.StartTime, window.EndTime, loc)))
+
+	if conflicts, err := repo.CheckScheduleConflicts(window.TrainerID, window.TrackID, window.StartTime, window.EndTime); err == nil && len(conflicts.TrainerTrainings) > 0 {
+		builder.WriteString("\n⛔ <b>Тренер уже занят:</b>\n")
+		for _, t := range conflicts.TrainerTrainings {
+			builder.WriteString(fmt.Sprintf("• %s-%s\n", timefmt.Clock(t.StartTime, loc), timefmt.Clock(t.EndTime, loc)))
+		}
+	}
+
+	builder.WriteString("\n📝 Введите удобный интервал внутри окна.\n\n" +
+		"🌍 Местное время трассы: " + timefmt.ZoneLabel(loc) + "\n" +
+		"💡 <i>Пример: " + timefmt.Clock(window.StartTime, loc) + "-" + timefmt.Clock(window.StartTime.Add(time.Hour), loc) + "</i>")
+
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, builder.String(), telegram.CreateStepKeyboard())
+	return true
+}
+
+// SetSessionSlot создает заявку на индивидуальное занятие и отправляет ее тренеру
+func SetSessionSlot(botUrl string, chatId int, update telegram.Update, repo database.ContentRepositoryInterface, state states.State) states.State {
+	user, err := repo.GetUserByChatId(chatId)
+	if err != nil || user == nil {
+		return sendErrorMessage(botUrl, chatId, 0, repo, err)
+	}
+
+	window, _ := repo.GetAvailabilityByID(state.GetID())
+	if window == nil {
+		telegram.SendMessage(botUrl, chatId, "❌ <b>Окно больше недоступно</b>\n\n"+
+			"💡 Выберите другое окно.", telegram.CreateBaseKeyboard())
+		return states.SetStartKeyboard()
+	}
+
+	// Окно занимает один день: дата занятия берется из него
+	loc := repo.GetTrackLocation(window.TrackID)
+	start, end, result := parseDayRange(window.StartTime.In(loc).Format(timefmt.DateInputLayout), update.Message.Text, loc)
+	if !result.IsValid {
+		telegram.SendMessage(botUrl, chatId, "❌ <b>Ошибка валидации</b>\n\n"+strings.Join(result.GetErrorMessages(), "\n")+
+			"\n\n🔄 Попробуйте еще раз:", telegram.CreateStepKeyboard())
+		return state
+	}
+
+	conflicts, err := repo.CheckScheduleConflicts(window.TrainerID, window.TrackID, start, end)
+	if err != nil {
+		return sendErrorMessage(botUrl, chatId, 0, repo, err)
+	}
+	if len(conflicts.Closures) > 0 {
+		telegram.SendMessage(botUrl, chatId, "❌ <b>Трасса закрыта</b>\n\n"+
+			formatTrackClosure(conflicts.Closures[0]), telegram.CreateBaseKeyboard())
+		return states.SetStartKeyboard()
+	}
+
+	request := &database.SessionRequest{UserID: user.ID, AvailabilityID: window.ID, StartTime: start, EndTime: end}
+	if err := repo.CreateSessionRequest(request); err != nil {
+		logger.UserError(chatId, "Заявка на занятие в окне %d: %v", window.ID, err)
+		telegram.SendMessage(botUrl, chatId, "❌ <b>Заявка не отправлена</b>\n\n"+
+			errors.HandleError(err)+"\n\n🔄 Введите другой интервал:", telegram.CreateStepKeyboard())
+		return state
+	}
+
+	notifyTrainerAboutSessionRequest(botUrl, user, request, repo)
+
+	logger.UserInfo(chatId, "Заявка на занятие создана: ID=%d", request.ID)
+	telegram.SendMessage(botUrl, chatId, fmt.Sprintf("🎉 <b>Заявка отправлена тренеру!</b>\n\n"+
+		"🏁 %s\n"+
+		"📅 %s\n\n"+
+		"📱 <b>Вы получите уведомление о решении тренера.</b>",
+		telegram.EscapeHTML(trackName(request.TrackID, repo)),
+		formatWindowTime(start, end, repo.GetViewerLocation(chatId, request.TrackID))), telegram.CreateBaseKeyboard())
+	return states.SetStartKeyboard()
+}
+
+// notifyTrainerAboutSessionRequest отправляет тренеру заявку на индивидуальное занятие
+func notifyTrainerAboutSessionRequest(botUrl string, user *database.User, request *database.SessionRequest, repo database.ContentRepositoryInterface) {
+	trainer, _ := repo.GetTrainerByID(request.TrainerID)
+	if trainer == nil || trainer.ChatId == 0 {
+		return
+	}
+
+	telegram.SendMessage(botUrl, trainer.ChatId, fmt.Sprintf("🔔 <b>Заявка на индивидуальное занятие</b>\n"+
+		"👤 %s\n"+
+		"📱 %s\n"+
+		"🏁 %s\n"+
+		"📅 %s",
+		telegram.EscapeHTML(user.Name), telegram.EscapeHTML(user.TgId), telegram.EscapeHTML(trackName(request.TrackID, repo)),
+		formatWindowTime(request.StartTime, request.EndTime, repo.GetViewerLocation(trainer.ChatId, request.TrackID))),
+		telegram.CreateSessionRequestKeyboard(request.ID))
+}
+
+// loadTrainerSessionRequest возвращает заявку, адресованную тренеру этого чата
+func loadTrainerSessionRequest(botUrl string, chatId int, messageId int, requestId uint, repo database.ContentRepositoryInterface) (*database.SessionRequest, bool) {
+	trainer, ok := loadTrainer(botUrl, chatId, messageId, repo)
+	if !ok {
+		return nil, false
+	}
+
+	request, _ := repo.GetSessionRequestByID(requestId)
+	if request == nil || request.TrainerID != trainer.ID {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Заявка не найдена</b>", telegram.CreateBackToAvailabilityKeyboard())
+		return nil, false
+	}
+	return request, true
+}
+
+// AcceptSessionRequest принимает заявку: назначает тренировку на одного участника
+// с подтвержденной записью и сообщает пользователю и администраторам
+func AcceptSessionRequest(botUrl string, chatId int, messageId int, requestId uint, repo database.ContentRepositoryInterface) states.State {
+	request, ok := loadTrainerSessionRequest(botUrl, chatId, messageId, requestId, repo)
+	if !ok {
+		return states.SetStartKeyboard()
+	}
+
+	conflicts, err := repo.CheckScheduleConflicts(request.TrainerID, request.TrackID, request.StartTime, request.EndTime)
+	if err != nil {
+		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
+	}
+	if len(conflicts.Closures) > 0 {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Трасса закрыта</b>\n\n"+
+			formatTrackClosure(conflicts.Closures[0])+"\n\n💡 Отклоните заявку или предложите пользователю другой день.",
+			telegram.CreateSessionRequestKeyboard(requestId))
+		return states.SetStartKeyboard()
+	}
+
+	request, registration, err := repo.AcceptSessionRequest(requestId)
+	if err != nil {
+		logger.UserError(chatId, "Принятие заявки на занятие %d: %v", requestId, err)
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Занятие не назначено</b>\n\n"+
+			errors.HandleError(err), telegram.CreateBackToAvailabilityKeyboard())
+		return states.SetStartKeyboard()
+	}
+
+	name := trackName(request.TrackID, repo)
+	user, _ := repo.GetUserByID(request.UserID)
+	if user != nil {
+		telegram.SendMessage(botUrl, user.ChatId, fmt.Sprintf("🎉 <b>Индивидуальное занятие назначено!</b>\n\n"+
+			"✅ <b>Тренер принял вашу заявку.</b>\n\n"+
+			"🏁 <b>Трасса:</b> %s\n"+
+			"📅 <b>Дата и время:</b> %s\n\n"+
+			"💡 Занятие появилось в «Моих записях», там же можно заказать прокат экипировки.",
+			telegram.EscapeHTML(name), formatWindowTime(request.StartTime, request.EndTime, repo.GetViewerLocation(user.ChatId, request.TrackID))),
+			telegram.CreateBaseKeyboard())
+	}
+
+	if admins, err := repo.GetAdmins(); err == nil {
+		trainerName := "—"
+		if trainer, _ := repo.GetTrainerByID(request.TrainerID); trainer != nil {
+			trainerName = trainer.Name
+		}
+		userName := "—"
+		if user != nil {
+			userName = user.Name
+		}
+
+		adminMessage := fmt.Sprintf("🎯 <b>Назначено индивидуальное занятие</b>\n\n"+
+			"🏁 <b>Трасса:</b> %s\n"+
+			"👨‍🏫 <b>Тренер:</b> %s\n"+
+			"📅 <b>Дата и время:</b> %s\n\n"+
+			"👤 <b>Пользователь:</b> %s",
+			telegram.EscapeHTML(name), telegram.EscapeHTML(trainerName),
+			formatWindowTime(request.StartTime, request.EndTime, repo.GetTrackLocation(request.TrackID)), telegram.EscapeHTML(userName))
+		for _, a := range admins {
+			if a.IsActive && a.ChatId != 0 {
+				telegram.SendMessage(botUrl, a.ChatId, adminMessage, telegram.CreateBackToAdminKeyboard())
+			}
+		}
+	}
+
+	logger.UserInfo(chatId, "Заявка на занятие %d принята: тренировка %d", requestId, registration.TrainingID)
+	telegram.EditMessage(botUrl, chatId, messageId, fmt.Sprintf("✅ <b>Занятие назначено</b>\n\n"+
+		"📅 %s\n"+
+		"💡 Тренировка на одного участника добавлена в расписание.",
+		formatWindowTime(request.StartTime, request.EndTime, repo.GetViewerLocation(chatId, request.TrackID))),
+		telegram.CreateBackToAvailabilityKeyboard())
+	return states.SetStartKeyboard()
+}
+
+// DeclineSessionRequest отклоняет заявку и сообщает пользователю
+func DeclineSessionRequest(botUrl string, chatId int, messageId int, requestId uint, repo database.ContentRepositoryInterface) states.State {
+	if _, ok := loadTrainerSessionRequest(botUrl, chatId, messageId, requestId, repo); !ok {
+		return states.SetStartKeyboard()
+	}
+
+	request, err := repo.DeclineSessionRequest(requestId)
+	if err != nil {
+		logger.UserError(chatId, "Отклонение заявки на занятие %d: %v", requestId, err)
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Заявка не отклонена</b>\n\n"+
+			errors.HandleError(err), telegram.CreateBackToAvailabilityKeyboard())
+		return states.SetStartKeyboard()
+	}
+
+	if user, _ := repo.GetUserByID(request.UserID); user != nil {
+		telegram.SendMessage(botUrl, user.ChatId, fmt.Sprintf("❌ <b>Тренер не сможет провести занятие</b>\n\n"+
+			"🏁 %s\n"+
+			"📅 %s\n\n"+
+			"💡 Выберите другое время или окно в разделе «Индивидуальное занятие».",
+			telegram.EscapeHTML(trackName(request.TrackID, repo)),
+			formatWindowTime(request.StartTime, request.EndTime, repo.GetViewerLocation(user.ChatId, request.TrackID))),
+			telegram.CreateBaseKeyboard())
+	}
+
+	logger.UserInfo(chatId, "Заявка на занятие %d отклонена", requestId)
+	telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Заявка отклонена</b>", telegram.CreateBackToAvailabilityKeyboard())
+	return states.SetStartKeyboard()
+}
@@ -0,0 +1,239 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"x.localhost/rvabot/internal/database"
+	"x.localhost/rvabot/internal/errors"
+	"x.localhost/rvabot/internal/logger"
+	"x.localhost/rvabot/internal/states"
+	"x.localhost/rvabot/internal/telegram"
+	"x.localhost/rvabot/internal/timefmt"
+)
+
+// skillLevelNames - названия уровней подготовки
+var skillLevelNames = map[int]string{
+	database.SkillLevelBeginner:     "🟢 Новичок",
+	database.SkillLevelIntermediate: "🟡 Средний",
+	database.SkillLevelAdvanced:     "🔴 Продвинутый",
+}
+
+// formatSkillLevel возвращает название уровня подготовки
+func formatSkillLevel(level int) string {
+	if name, ok := skillLevelNames[level]; ok {
+		return name
+	}
+	return fmt.Sprintf("уровень %d", level)
+}
+
+// formatSkillRequirement описывает требования тренировки к уровню участников
+func formatSkillRequirement(training *database.Training) string {
+	minLevel, maxLevel := training.MinSkillLevel, training.MaxSkillLevel
+	switch {
+	case minLevel == 0 && maxLevel == 0:
+		return "любой"
+	case minLevel == maxLevel:
+		return "только " + formatSkillLevel(minLevel)
+	case maxLevel == 0:
+		return "от " + formatSkillLevel(minLevel)
+	case minLevel == 0:
+		return "до " + formatSkillLevel(maxLevel)
+	default:
+		return formatSkillLevel(minLevel) + " – " + formatSkillLevel(maxLevel)
+	}
+}
+
+// skillMismatchReason объясняет, почему уровень пользователя не подходит
+func skillMismatchReason(level int, training *database.Training) string {
+	if training.MinSkillLevel > 0 && level < training.MinSkillLevel {
+		return "💡 Тренировка рассчитана на более подготовленных пилотов. " +
+			"Тренер повышает уровень по итогам посещенных тренировок."
+	}
+	return "💡 Тренировка рассчитана на менее опытных пилотов. " +
+		"Выберите тренировку для вашего уровня."
+}
+
+// checkSkillRequirement сообщает пользователю, что тренировка не подходит
+// для его уровня или уровня участника, за которого он записывается.
+// Возвращает true, если записываться нельзя.
+func checkSkillRequirement(botUrl string, chatId int, messageId int, user *database.User, participant *database.Participant, training *database.Training) bool {
+	level, levelLabel := user.SkillLevel, "Ваш уровень"
+	if participant != nil {
+		level, levelLabel = participant.SkillLevel, "Уровень участника"
+	}
+	if database.QualifiesForTraining(level, training) {
+		return false
+	}
+
+	logger.UserInfo(chatId, "Тренировка %d не подходит по уровню %d", training.ID, level)
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, fmt.Sprintf("🎓 <b>Тренировка не подходит по уровню подготовки</b>\n\n"+
+		"🎯 <b>Требуемый уровень:</b> %s\n"+
+		"👤 <b>%s:</b> %s\n\n%s",
+		formatSkillRequirement(training), levelLabel, formatSkillLevel(level), skillMismatchReason(level, training)),
+		telegram.CreateBaseKeyboard())
+	return true
+}
+
+// EditTrainingSkillLevel начинает изменение требований тренировки к уровню участников
+func EditTrainingSkillLevel(botUrl string, chatId int, messageId int, trainingId uint, repo database.ContentRepositoryInterface) states.State {
+	if !database.IsAdmin(chatId, repo) {
+		return SendAccessDeniedMessage(botUrl, chatId, messageId)
+	}
+
+	training, err := repo.GetTrainingById(trainingId)
+	if err != nil || training == nil {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Тренировка не найдена</b>", telegram.CreateBackToScheduleMenuKeyboard())
+		return states.SetAdminKeyboard()
+	}
+
+	if training.SeriesID != nil {
+		return showSeriesScopeSelection(botUrl, chatId, messageId, training, seriesActionSkillLevel, repo)
+	}
+
+	promptEditTrainingSkillLevel(botUrl, chatId, messageId, training)
+	return states.SetEditTrainingSkillLevel(trainingId)
+}
+
+// promptEditTrainingSkillLevel показывает выбор требований к уровню участников
+func promptEditTrainingSkillLevel(botUrl string, chatId int, messageId int, training *database.Training) {
+	telegram.EditMessage(botUrl, chatId, messageId, fmt.Sprintf("🎓 <b>Уровень участников</b>\n\n"+
+		"📊 Сейчас: %s\n\n"+
+		"💡 Пользователи с другим уровнем не увидят тренировку при записи.\n"+
+		"👇 Выберите, для кого тренировка:", formatSkillRequirement(training)),
+		telegram.CreateEditTrainingSkillLevelKeyboard())
+}
+
+// SelectEditTrainingSkillLevel сохраняет выбранные требования к уровню
+// у тренировки или занятий серии. Код варианта - минимальный уровень * 10
+// + максимальный, 0 - без ограничения.
+func SelectEditTrainingSkillLevel(botUrl string, chatId int, messageId int, code uint, repo database.ContentRepositoryInterface, state states.State) states.State {
+	if !database.IsAdmin(chatId, repo) {
+		return SendAccessDeniedMessage(botUrl, chatId, messageId)
+	}
+
+	if state.Type != states.StateEditTrainingSkillLevel {
+		return state
+	}
+
+	minLevel, maxLevel := int(code/10), int(code%10)
+	if minLevel > database.SkillLevelAdvanced || maxLevel > database.SkillLevelAdvanced || (maxLevel > 0 && minLevel > maxLevel) {
+		logger.AdminError(chatId, "Неизвестный вариант уровня: %d", code)
+		return state
+	}
+
+	training, err := repo.GetTrainingById(state.GetID())
+	if err != nil || training == nil {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Тренировка не найдена</b>", telegram.CreateBackToScheduleMenuKeyboard())
+		return states.SetAdminKeyboard()
+	}
+
+	trainings, err := repo.GetSeriesTrainings(training, state.GetSeriesScope())
+	if err != nil {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Ошибка загрузки занятий серии</b>", telegram.CreateBackToScheduleMenuKeyboard())
+		return states.SetAdminKeyboard()
+	}
+
+	for _, t := range trainings {
+		if err := repo.SetTrainingSkillLevels(t.ID, minLevel, maxLevel); err != nil {
+			telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Ошибка сохранения</b>", telegram.CreateBackToScheduleMenuKeyboard())
+			return states.SetAdminKeyboard()
+		}
+	}
+
+	training.MinSkillLevel, training.MaxSkillLevel = minLevel, maxLevel
+	logger.AdminInfo(chatId, "Уровень тренировки %d: %s", training.ID, formatSkillRequirement(training))
+
+	message := "✅ <b>Уровень участников обновлен</b>\n\n🎓 " + formatSkillRequirement(training)
+	if training.SeriesID != nil {
+		message += fmt.Sprintf("\n🔁 Изменено: %s (%d)", formatSeriesScope(state.GetSeriesScope()), len(trainings))
+	}
+	message += "\n\n💡 Уже записанные участники остаются в списке."
+	telegram.EditMessage(botUrl, chatId, messageId, message, telegram.CreateBackToScheduleMenuKeyboard())
+	return states.SetAdminKeyboard()
+}
+
+// PromoteUser повышает уровень участника по записи на посещенную тренировку
+// и сообщает ему об этом
+func PromoteUser(botUrl string, chatId int, messageId int, registrationId uint, repo database.ContentRepositoryInterface) states.State {
+	registration, err := repo.GetTrainingRegistrationByID(registrationId)
+	if err != nil || registration == nil {
+		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
+	}
+
+	training, _ := repo.GetTrainingById(registration.TrainingID)
+	if training == nil {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Тренировка не найдена</b>", telegram.CreateBaseKeyboard())
+		return states.SetStartKeyboard()
+	}
+
+	if !isTrainingTrainer(chatId, training, repo) && !database.IsAdmin(chatId, repo) {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Нет прав</b>", telegram.CreateBaseKeyboard())
+		return states.SetStartKeyboard()
+	}
+
+	change, err := repo.PromoteUser(registrationId, chatId)
+	if err != nil {
+		logger.UserError(chatId, "Повышение уровня по записи %d: %v", registrationId, err)
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Уровень не повышен</b>\n\n"+errors.HandleError(err),
+			telegram.CreateBackToMenuKeyboard(fmt.Sprintf("attendanceChecklist_%d", training.ID)))
+		return states.SetStartKeyboard()
+	}
+
+	logger.UserInfo(chatId, "Уровень пользователя %d (участник %d) повышен: %d -> %d", change.UserID, change.ParticipantID, change.FromLevel, change.ToLevel)
+	notifyUserAboutPromotion(botUrl, change, training, repo)
+
+	return ViewAttendanceChecklist(botUrl, chatId, messageId, training.ID, repo)
+}
+
+// notifyUserAboutPromotion сообщает участнику о новом уровне
+func notifyUserAboutPromotion(botUrl string, change *database.SkillLevelChange, training *database.Training, repo database.ContentRepositoryInterface) {
+	user, _ := repo.GetUserByID(change.UserID)
+	if user == nil || user.ChatId == 0 {
+		return
+	}
+
+	trainerName := "тренер"
+	if trainer, _ := repo.GetTrainerByID(change.TrainerID); trainer != nil {
+		trainerName = trainer.Name
+	}
+
+	title := "🎓 <b>Ваш уровень повышен!</b>\n\n"
+	if change.ParticipantID != 0 {
+		registration, _ := repo.GetTrainingRegistrationByID(change.RegistrationID)
+		title = "🎓 <b>Уровень участника повышен!</b>\n\n" + formatRegistrationParticipant(registration, repo)
+	}
+
+	telegram.SendMessage(botUrl, user.ChatId, fmt.Sprintf("%s"+
+		"📊 %s → <b>%s</b>\n"+
+		"👨‍🏫 <b>Тренер:</b> %s\n"+
+		"📅 <b>По тренировке:</b> %s\n\n"+
+		"💡 Теперь вам доступны тренировки нового уровня.",
+		title, formatSkillLevel(change.FromLevel), formatSkillLevel(change.ToLevel), trainerName,
+		timefmt.DateTime(training.StartTime, repo.GetViewerLocation(user.ChatId, training.TrackID))), telegram.CreateBaseKeyboard())
+}
+
+// formatUserSkillLevel описывает уровень пользователя и последние повышения
+func formatUserSkillLevel(user *database.User, repo database.ContentRepositoryInterface) string {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("🎓 <b>Уровень:</b> %s\n", formatSkillLevel(user.SkillLevel)))
+
+	changes, _ := repo.GetSkillLevelChanges(user.ID)
+	for _, change := range changes {
+		trainerName := "тренер"
+		if trainer, _ := repo.GetTrainerByID(change.TrainerID); trainer != nil {
+			trainerName = trainer.Name
+		}
+		who := ""
+		if change.ParticipantID != 0 {
+			if participant, _ := repo.GetParticipantByID(change.ParticipantID); participant != nil {
+				who = telegram.EscapeHTML(participant.Name) + ": "
+			}
+		}
+		builder.WriteString(fmt.Sprintf("  ⬆️ %s: %s%s → %s (%s)\n",
+			timefmt.Date(change.CreatedAt, timefmt.Resolve(user.Timezone)), who,
+			formatSkillLevel(change.FromLevel), formatSkillLevel(change.ToLevel), trainerName))
+	}
+
+	return builder.String()
+}
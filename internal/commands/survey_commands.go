@@ -0,0 +1,197 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"x.localhost/rvabot/internal/database"
+	"x.localhost/rvabot/internal/logger"
+	"x.localhost/rvabot/internal/states"
+	"x.localhost/rvabot/internal/telegram"
+	"x.localhost/rvabot/internal/timefmt"
+	"x.localhost/rvabot/internal/validation"
+)
+
+// surveyReportPeriod - за какой период администратор видит сводку опросов
+const surveyReportPeriod = 30 * 24 * time.Hour
+
+// lowSurveyRating - оценки не выше этой требуют внимания администратора
+const lowSurveyRating = 3
+
+// lowSurveyLimit ограничивает число низких оценок в сводке
+const lowSurveyLimit = 10
+
+// ProcessTrainingSurveys отправляет опросы участникам тренировок,
+// закончившихся не меньше чем SurveyDelay назад
+func ProcessTrainingSurveys(botUrl string, repo database.ContentRepositoryInterface) {
+	// Опрос создается до отправки, чтобы сбой рассылки не повторял его каждую минуту
+	surveys, err := repo.CreatePendingSurveys(time.Now().Add(-bookingConfig.SurveyDelay))
+	if err != nil {
+		logger.BotError("Опросы после тренировок: %v", err)
+		return
+	}
+
+	for i := range surveys {
+		sendTrainingSurvey(botUrl, &surveys[i], repo)
+	}
+}
+
+// sendTrainingSurvey отправляет участнику опрос об одной тренировке
+func sendTrainingSurvey(botUrl string, survey *database.TrainingSurvey, repo database.ContentRepositoryInterface) {
+	user, _ := repo.GetUserByID(survey.UserID)
+	training, _ := repo.GetTrainingById(survey.TrainingID)
+	if user == nil || user.ChatId == 0 || training == nil {
+		return
+	}
+
+	trackName := "Неизвестная трасса"
+	if track, _ := repo.GetTrackByID(training.TrackID); track != nil {
+		trackName = track.Name
+	}
+
+	trainerName := "Неизвестный тренер"
+	if trainer, _ := repo.GetTrainerByID(training.TrainerID); trainer != nil {
+		trainerName = trainer.Name
+	}
+
+	message := fmt.Sprintf("⭐ <b>Как прошла тренировка?</b>\n\n"+
+		"🏁 <b>Трасса:</b> %s\n"+
+		"👨‍🏫 <b>Тренер:</b> %s\n"+
+		"📅 <b>Дата и время:</b> %s\n\n"+
+		"Оцените тренировку от 1 до 5 — это поможет нам стать лучше.",
+		trackName, trainerName, timefmt.DateTime(training.StartTime, repo.GetViewerLocation(user.ChatId, training.TrackID)))
+
+	if err := telegram.SendMessage(botUrl, user.ChatId, message, telegram.CreateSurveyRatingKeyboard(survey.ID)); err != nil {
+		logger.UserError(user.ChatId, "Отправка опроса %d: %v", survey.ID, err)
+	}
+}
+
+// RateSurvey сохраняет оценку тренировки и предлагает оставить комментарий
+func RateSurvey(botUrl string, chatId int, messageId int, surveyId uint, rating int, repo database.ContentRepositoryInterface) states.State {
+	user, err := repo.GetUserByChatId(chatId)
+	if err != nil || user == nil {
+		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
+	}
+
+	survey, err := repo.RateSurvey(surveyId, user.ID, rating)
+	if err != nil {
+		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
+	}
+
+	message := fmt.Sprintf("🙏 <b>Спасибо за оценку!</b>\n\n"+
+		"%s\n\n", formatSurveyStars(survey.Rating))
+	if survey.Rating <= lowSurveyRating {
+		message += "😔 Жаль, что тренировка не оправдала ожиданий. Расскажите, что пошло не так, — мы разберемся."
+	} else {
+		message += "💬 Можно добавить комментарий: что понравилось или что улучшить."
+	}
+
+	logger.UserInfo(chatId, "Опрос %d: оценка %d", surveyId, rating)
+	telegram.EditMessage(botUrl, chatId, messageId, message, telegram.CreateSurveyRatedKeyboard(surveyId))
+	return states.SetStartKeyboard()
+}
+
+// CommentSurvey запрашивает комментарий к оценке тренировки
+func CommentSurvey(botUrl string, chatId int, messageId int, surveyId uint, repo database.ContentRepositoryInterface) states.State {
+	promptSurveyComment(botUrl, chatId, messageId)
+	return states.SetSurveyComment(surveyId)
+}
+
+// promptSurveyComment показывает шаг ввода комментария к опросу
+func promptSurveyComment(botUrl string, chatId int, messageId int) {
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, "💬 <b>Комментарий к тренировке</b>\n\n"+
+		"📝 Напишите, что понравилось или что стоит улучшить.", telegram.CreateStepKeyboard())
+}
+
+// SetSurveyComment сохраняет комментарий к опросу
+func SetSurveyComment(botUrl string, chatId int, update telegram.Update, repo database.ContentRepositoryInterface, state states.State) states.State {
+	comment := strings.TrimSpace(update.Message.Text)
+
+	validator := validation.NewValidator()
+	if result := validator.ValidateFeedbackNote(comment); !result.IsValid {
+		telegram.SendMessage(botUrl, chatId, "❌ <b>Ошибка валидации</b>\n\n"+strings.Join(result.GetErrorMessages(), "\n")+
+			"\n\n🔄 Попробуйте еще раз:", telegram.CreateStepKeyboard())
+		return state
+	}
+
+	user, err := repo.GetUserByChatId(chatId)
+	if err != nil || user == nil {
+		return sendErrorMessage(botUrl, chatId, 0, repo, err)
+	}
+
+	if err := repo.SetSurveyComment(state.GetID(), user.ID, comment); err != nil {
+		return sendErrorMessage(botUrl, chatId, 0, repo, err)
+	}
+
+	logger.UserInfo(chatId, "Опрос %d: оставлен комментарий", state.GetID())
+	telegram.SendMessage(botUrl, chatId, "✅ <b>Спасибо за отзыв!</b>\n\n"+
+		"Мы обязательно его прочитаем.", telegram.CreateBaseKeyboard())
+	return states.SetStartKeyboard()
+}
+
+// ViewSurveyReport показывает администратору средние оценки тренеров и трасс
+// и последние низкие оценки с комментариями
+func ViewSurveyReport(botUrl string, chatId int, messageId int, repo database.ContentRepositoryInterface) states.State {
+	if !database.IsAdmin(chatId, repo) {
+		return SendAccessDeniedMessage(botUrl, chatId, messageId)
+	}
+
+	report, err := repo.GetSurveyReport(time.Now().Add(-surveyReportPeriod), lowSurveyRating, lowSurveyLimit)
+	if err != nil {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Ошибка загрузки оценок</b>\n\n"+
+			"Попробуйте позже.", telegram.CreateBackToAdminKeyboard())
+		return states.SetAdminKeyboard()
+	}
+
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("⭐ <b>Оценки тренировок за %d дней</b>\n\n"+
+		"📨 <b>Отправлено опросов:</b> %d\n"+
+		"✅ <b>Ответов:</b> %d\n", int(surveyReportPeriod.Hours()/24), report.Sent, report.Answered))
+
+	if report.Answered == 0 {
+		builder.WriteString("\n📭 Пока нет оценок.")
+		telegram.EditMessage(botUrl, chatId, messageId, builder.String(), telegram.CreateBackToAdminKeyboard())
+		return states.SetAdminKeyboard()
+	}
+
+	builder.WriteString("\n👨‍🏫 <b>Тренеры:</b>\n")
+	for _, score := range report.Trainers {
+		builder.WriteString(fmt.Sprintf("• %s — %.1f ⭐ (%d)\n", score.Name, score.Average, score.Responses))
+	}
+
+	builder.WriteString("\n🏁 <b>Трассы:</b>\n")
+	for _, score := range report.Tracks {
+		builder.WriteString(fmt.Sprintf("• %s — %.1f ⭐ (%d)\n", score.Name, score.Average, score.Responses))
+	}
+
+	if len(report.LowScores) > 0 {
+		builder.WriteString(fmt.Sprintf("\n⚠️ <b>Оценки %d и ниже:</b>\n", lowSurveyRating))
+		for _, survey := range report.LowScores {
+			userName := "Неизвестный"
+			userTgId := ""
+			if user, _ := repo.GetUserByID(survey.UserID); user != nil {
+				userName = user.Name
+				userTgId = user.TgId
+			}
+
+			trainingInfo := ""
+			if training, _ := repo.GetTrainingById(survey.TrainingID); training != nil {
+				trainingInfo = timefmt.Short(training.StartTime, repo.GetViewerLocation(chatId, training.TrackID))
+			}
+
+			builder.WriteString(fmt.Sprintf("\n%s %s %s, %s\n", formatSurveyStars(survey.Rating), userName, userTgId, trainingInfo))
+			if survey.Comment != "" {
+				builder.WriteString("💬 " + telegram.EscapeHTML(survey.Comment) + "\n")
+			}
+		}
+	}
+
+	telegram.EditMessage(botUrl, chatId, messageId, builder.String(), telegram.CreateBackToAdminKeyboard())
+	return states.SetAdminKeyboard()
+}
+
+// formatSurveyStars выводит оценку звездами, например ★★★☆☆
+func formatSurveyStars(rating int) string {
+	return strings.Repeat("★", rating) + strings.Repeat("☆", database.MaxSurveyRating-rating)
+}
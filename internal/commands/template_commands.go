@@ -0,0 +1,283 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"x.localhost/rvabot/internal/database"
+	"x.localhost/rvabot/internal/errors"
+	"x.localhost/rvabot/internal/logger"
+	"x.localhost/rvabot/internal/states"
+	"x.localhost/rvabot/internal/telegram"
+	"x.localhost/rvabot/internal/timefmt"
+	"x.localhost/rvabot/internal/validation"
+)
+
+// DuplicateTraining заполняет данные новой тренировки по существующей и спрашивает только дату
+func DuplicateTraining(botUrl string, chatId int, messageId int, trainingId uint, repo database.ContentRepositoryInterface) states.State {
+	if !database.IsAdmin(chatId, repo) {
+		return SendAccessDeniedMessage(botUrl, chatId, messageId)
+	}
+
+	training, err := repo.GetTrainingById(trainingId)
+	if err != nil || training == nil {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Тренировка не найдена</b>", telegram.CreateBackToScheduleMenuKeyboard())
+		return states.SetAdminKeyboard()
+	}
+
+	tempData := &states.TempTrainingData{
+		TrainerID:       training.TrainerID,
+		TrackID:         training.TrackID,
+		MaxParticipants: training.MaxParticipants,
+		CarCategoryID:   training.CarCategoryID,
+		TimeOfDay:       timefmt.Clock(training.StartTime, repo.GetTrackLocation(training.TrackID)),
+		DurationMinutes: int(training.EndTime.Sub(training.StartTime).Minutes()),
+	}
+
+	return startTrainingCopy(botUrl, chatId, messageId, repo, tempData, "scheduleMenu")
+}
+
+// UseTrainingTemplate начинает создание тренировки по сохраненному шаблону
+func UseTrainingTemplate(botUrl string, chatId int, messageId int, templateId uint, repo database.ContentRepositoryInterface) states.State {
+	if !database.IsAdmin(chatId, repo) {
+		return SendAccessDeniedMessage(botUrl, chatId, messageId)
+	}
+
+	template, err := repo.GetTrainingTemplateByID(templateId)
+	if err != nil || template == nil {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Шаблон не найден</b>", telegram.CreateBackToTemplatesKeyboard())
+		return states.SetAdminKeyboard()
+	}
+
+	tempData := &states.TempTrainingData{
+		TrainerID:       template.TrainerID,
+		TrackID:         template.TrackID,
+		MaxParticipants: template.MaxParticipants,
+		CarCategoryID:   template.CarCategoryID,
+		TimeOfDay:       template.TimeOfDay,
+		DurationMinutes: template.DurationMinutes,
+	}
+
+	return startTrainingCopy(botUrl, chatId, messageId, repo, tempData, "trainingTemplates")
+}
+
+// startTrainingCopy проверяет, что тренер и трасса не в архиве, и переходит к вводу даты
+func startTrainingCopy(botUrl string, chatId int, messageId int, repo database.ContentRepositoryInterface, tempData *states.TempTrainingData, backMenu string) states.State {
+	trainer, _ := repo.GetTrainerByID(tempData.TrainerID)
+	track, _ := repo.GetTrackByID(tempData.TrackID)
+	if trainer == nil || track == nil {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Нельзя создать копию</b>\n\n"+
+			"🗄 Тренер или трасса перенесены в архив.", telegram.CreateBackToMenuKeyboard(backMenu))
+		return states.SetAdminKeyboard()
+	}
+
+	promptTrainingCloneDate(botUrl, chatId, messageId, repo, tempData)
+	return states.SetSetTrainingCloneDate().SetTempTrainingData(tempData)
+}
+
+// promptTrainingCloneDate показывает параметры копии и шаг ввода даты
+func promptTrainingCloneDate(botUrl string, chatId int, messageId int, repo database.ContentRepositoryInterface, tempData *states.TempTrainingData) {
+	trainerName := "Неизвестный тренер"
+	if trainer, _ := repo.GetTrainerByID(tempData.TrainerID); trainer != nil {
+		trainerName = trainer.Name
+	}
+
+	trackName := "Неизвестная трасса"
+	if track, _ := repo.GetTrackByID(tempData.TrackID); track != nil {
+		trackName = track.Name
+	}
+
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, fmt.Sprintf("📋 <b>Новая тренировка по образцу</b>\n\n"+
+		"👨‍🏫 <b>Тренер:</b> %s\n"+
+		"🏁 <b>Трасса:</b> %s\n"+
+		"🚗 <b>Категория:</b> %s\n"+
+		"🕐 <b>Время:</b> %s, %s\n"+
+		"👥 <b>Макс. участников:</b> %d\n\n"+
+		"📅 Введите дату новой тренировки. Чтобы изменить время начала, укажите его после даты.\n\n"+
+		"💡 <i>Пример: 2024-01-22 или 2024-01-22 19:00</i>",
+		trainerName, trackName, carCategoryName(tempData.CarCategoryID, repo), tempData.TimeOfDay, formatDuration(time.Duration(tempData.DurationMinutes)*time.Minute),
+		tempData.MaxParticipants), telegram.CreateStepKeyboard())
+}
+
+// SetTrainingCloneDate принимает дату копии и переходит к подтверждению создания
+func SetTrainingCloneDate(botUrl string, chatId int, update telegram.Update, repo database.ContentRepositoryInterface, state states.State) states.State {
+	if !database.IsAdmin(chatId, repo) {
+		return SendAccessDeniedMessage(botUrl, chatId, 0)
+	}
+
+	input := strings.TrimSpace(update.Message.Text)
+	tempData := copyTempTrainingData(state.GetTempTrainingData())
+
+	// Дата без времени берет время начала из образца
+	if !strings.Contains(input, " ") {
+		input += " " + tempData.TimeOfDay
+	}
+
+	validator := validation.NewValidator()
+	if result := validator.ValidateDateTime(input); !result.IsValid {
+		errorMsg := "❌ <b>Неверный формат даты</b>\n\n"
+		for _, err := range result.Errors {
+			errorMsg += fmt.Sprintf("• %s\n", err.Error())
+		}
+		errorMsg += "\n💡 <i>Пример: 2024-01-22 или 2024-01-22 19:00</i>"
+
+		telegram.SendMessage(botUrl, chatId, errorMsg, telegram.CreateStepKeyboard())
+		return state
+	}
+
+	loc := repo.GetTrackLocation(tempData.TrackID)
+	start, _ := timefmt.ParseDateTime(input, loc)
+	if start.Before(time.Now()) {
+		telegram.SendMessage(botUrl, chatId, "❌ <b>Дата в прошлом</b>\n\n"+
+			"Введите будущую дату.", telegram.CreateStepKeyboard())
+		return state
+	}
+
+	end := start.Add(time.Duration(tempData.DurationMinutes) * time.Minute)
+	tempData.StartTime = timefmt.Input(start, loc)
+	tempData.EndTime = timefmt.Input(end, loc)
+	tempData.Recurring = false
+
+	showTrainingCreationConfirmation(botUrl, chatId, 0, repo, tempData)
+	return states.SetConfirmTrainingCreation().SetTempTrainingData(tempData)
+}
+
+// ViewTrainingTemplates показывает сохраненные шаблоны тренировок
+func ViewTrainingTemplates(botUrl string, chatId int, messageId int, repo database.ContentRepositoryInterface) states.State {
+	if !database.IsAdmin(chatId, repo) {
+		return SendAccessDeniedMessage(botUrl, chatId, messageId)
+	}
+
+	templates, err := repo.GetTrainingTemplates()
+	if err != nil {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Ошибка получения шаблонов</b>\n\n"+
+			"Попробуйте позже.", telegram.CreateBackToScheduleMenuKeyboard())
+		return states.SetAdminKeyboard()
+	}
+
+	message := "📑 <b>Шаблоны тренировок</b>\n\n"
+	if len(templates) == 0 {
+		message += "📭 <b>Шаблонов пока нет</b>\n\n" +
+			"💡 Откройте тренировку в расписании и нажмите «💾 В шаблоны»."
+	} else {
+		message += formatTrainingTemplatesList(templates, repo) +
+			"\n💡 Нажмите на шаблон, чтобы создать тренировку, указав только дату."
+	}
+
+	telegram.EditMessage(botUrl, chatId, messageId, message, telegram.CreateTrainingTemplatesKeyboard(templates))
+	return states.SetAdminKeyboard()
+}
+
+func formatTrainingTemplatesList(templates []database.TrainingTemplate, repo database.ContentRepositoryInterface) string {
+	var builder strings.Builder
+
+	categoryName := carCategoryNames(repo)
+	for i, template := range templates {
+		trainerName := "Неизвестный тренер"
+		if trainer, _ := repo.GetTrainerByID(template.TrainerID); trainer != nil {
+			trainerName = trainer.Name
+		}
+
+		trackName := "Неизвестная трасса"
+		if track, _ := repo.GetTrackByID(template.TrackID); track != nil {
+			trackName = track.Name
+		}
+
+		builder.WriteString(fmt.Sprintf("%d. <b>%s</b>\n", i+1, template.Name))
+		builder.WriteString(fmt.Sprintf("   👨‍🏫 %s | 🏁 %s\n", trainerName, trackName))
+		builder.WriteString(fmt.Sprintf("   🕐 %s, %s | 👥 %d | 🚗 %s\n\n",
+			template.TimeOfDay, formatDuration(time.Duration(template.DurationMinutes)*time.Minute),
+			template.MaxParticipants, categoryName(template.CarCategoryID)))
+	}
+
+	return builder.String()
+}
+
+// SaveTrainingTemplate запрашивает название шаблона для тренировки
+func SaveTrainingTemplate(botUrl string, chatId int, messageId int, trainingId uint, repo database.ContentRepositoryInterface) states.State {
+	if !database.IsAdmin(chatId, repo) {
+		return SendAccessDeniedMessage(botUrl, chatId, messageId)
+	}
+
+	if training, err := repo.GetTrainingById(trainingId); err != nil || training == nil {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Тренировка не найдена</b>", telegram.CreateBackToScheduleMenuKeyboard())
+		return states.SetAdminKeyboard()
+	}
+
+	promptTemplateName(botUrl, chatId, messageId)
+	return states.SetSetTemplateName(trainingId)
+}
+
+// promptTemplateName показывает шаг ввода названия шаблона
+func promptTemplateName(botUrl string, chatId int, messageId int) {
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, "💾 <b>Сохранение шаблона</b>\n\n"+
+		"📝 Введите название шаблона.\n"+
+		"В шаблон попадут тренер, трасса, время начала, длительность, количество участников и категория.\n\n"+
+		"💡 <i>Пример: Вечерняя KZ по средам</i>", telegram.CreateStepKeyboard())
+}
+
+// SetTemplateName сохраняет шаблон из тренировки под введенным названием
+func SetTemplateName(botUrl string, chatId int, update telegram.Update, repo database.ContentRepositoryInterface, state states.State) states.State {
+	if !database.IsAdmin(chatId, repo) {
+		return SendAccessDeniedMessage(botUrl, chatId, 0)
+	}
+
+	name := strings.TrimSpace(update.Message.Text)
+	if name == "" || len([]rune(name)) > database.MaxTemplateNameLength {
+		telegram.SendMessage(botUrl, chatId, fmt.Sprintf("❌ <b>Неверное название</b>\n\n"+
+			"Название должно быть от 1 до %d символов.", database.MaxTemplateNameLength), telegram.CreateStepKeyboard())
+		return state
+	}
+
+	training, err := repo.GetTrainingById(state.GetID())
+	if err != nil || training == nil {
+		telegram.SendMessage(botUrl, chatId, "❌ <b>Тренировка не найдена</b>", telegram.CreateBackToScheduleMenuKeyboard())
+		return states.SetAdminKeyboard()
+	}
+
+	template := database.NewTrainingTemplate(name, training, repo.GetTrackLocation(training.TrackID))
+	if err := repo.CreateTrainingTemplate(template); err != nil {
+		logger.AdminError(chatId, "Создание шаблона: %v", err)
+		telegram.SendMessage(botUrl, chatId, "❌ <b>Ошибка сохранения шаблона</b>\n\n"+
+			errors.HandleError(err), telegram.CreateBackToScheduleMenuKeyboard())
+		return states.SetAdminKeyboard()
+	}
+
+	logger.AdminInfo(chatId, "Шаблон создан: %d из тренировки %d", template.ID, training.ID)
+	telegram.SendMessage(botUrl, chatId, fmt.Sprintf("✅ <b>Шаблон «%s» сохранен</b>", template.Name), telegram.CreateBackToTemplatesKeyboard())
+	return states.SetAdminKeyboard()
+}
+
+func ConfirmTemplateDeletion(botUrl string, chatId int, messageId int, templateId uint, repo database.ContentRepositoryInterface) states.State {
+	if !database.IsAdmin(chatId, repo) {
+		return SendAccessDeniedMessage(botUrl, chatId, messageId)
+	}
+
+	template, err := repo.GetTrainingTemplateByID(templateId)
+	if err != nil || template == nil {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Шаблон не найден</b>", telegram.CreateBackToTemplatesKeyboard())
+		return states.SetAdminKeyboard()
+	}
+
+	telegram.EditMessage(botUrl, chatId, messageId, fmt.Sprintf("⚠️ <b>Подтверждение удаления шаблона</b>\n\n"+
+		"📑 <b>Шаблон:</b> %s\n\n"+
+		"💡 Созданные по шаблону тренировки не изменятся.\n\n"+
+		"❓ <b>Удалить шаблон?</b>", template.Name), telegram.CreateTemplateDeletionConfirmationKeyboard(templateId))
+	return states.SetConfirmTemplateDelete(templateId)
+}
+
+func ExecuteTemplateDeletion(botUrl string, chatId int, messageId int, templateId uint, repo database.ContentRepositoryInterface) states.State {
+	if !database.IsAdmin(chatId, repo) {
+		return SendAccessDeniedMessage(botUrl, chatId, messageId)
+	}
+
+	if err := repo.DeleteTrainingTemplate(templateId); err != nil {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Ошибка удаления шаблона</b>\n\n"+
+			"Попробуйте позже.", telegram.CreateBackToTemplatesKeyboard())
+		return states.SetAdminKeyboard()
+	}
+
+	logger.AdminInfo(chatId, "Шаблон удален: %d", templateId)
+	return ViewTrainingTemplates(botUrl, chatId, messageId, repo)
+}
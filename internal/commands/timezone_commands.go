@@ -0,0 +1,170 @@
+package commands
+
+import (
+	"strings"
+	"time"
+
+	"x.localhost/rvabot/internal/database"
+	"x.localhost/rvabot/internal/logger"
+	"x.localhost/rvabot/internal/states"
+	"x.localhost/rvabot/internal/telegram"
+	"x.localhost/rvabot/internal/timefmt"
+)
+
+// resetTimezoneInput - ввод, сбрасывающий собственный часовой пояс
+const resetTimezoneInput = "-"
+
+// viewerLocation возвращает выбор часового пояса для занятий на трассе в чате chatId
+func viewerLocation(chatId int, repo database.ContentRepositoryInterface) func(trackId uint) *time.Location {
+	return func(trackId uint) *time.Location {
+		return repo.GetViewerLocation(chatId, trackId)
+	}
+}
+
+// EditTrackTimezone показывает часовой пояс трассы и запрашивает новый
+func EditTrackTimezone(botUrl string, chatId int, messageId int, trackId uint, repo database.ContentRepositoryInterface) states.State {
+	if !database.IsAdmin(chatId, repo) {
+		return SendAccessDeniedMessage(botUrl, chatId, messageId)
+	}
+
+	if !promptTrackTimezone(botUrl, chatId, messageId, trackId, repo) {
+		return states.SetAdminKeyboard()
+	}
+	return states.SetEditTrackTimezone(trackId)
+}
+
+// promptTrackTimezone показывает шаг ввода часового пояса трассы
+func promptTrackTimezone(botUrl string, chatId int, messageId int, trackId uint, repo database.ContentRepositoryInterface) bool {
+	track, err := repo.GetTrackByID(trackId)
+	if err != nil || track == nil {
+		telegram.SendOrEditMessage(botUrl, chatId, messageId, "❌ Трасса не найдена.", telegram.CreateBackToTracksMenuKeyboard())
+		return false
+	}
+
+	current := "как у академии, " + timefmt.ZoneLabel(nil)
+	if track.Timezone != "" {
+		current = timefmt.ZoneLabel(timefmt.Resolve(track.Timezone))
+	}
+
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, "🕒 <b>Часовой пояс трассы "+track.Name+"</b>\n\n"+
+		"🌍 <b>Сейчас:</b> "+current+"\n\n"+
+		"📝 Введите часовой пояс в формате IANA или «-», чтобы использовать пояс академии.\n"+
+		"Время тренировок на трассе вводится и показывается в этом поясе.\n\n"+
+		"💡 <i>Пример: Europe/Rome</i>", telegram.CreateStepKeyboard())
+	return true
+}
+
+// SetEditTrackTimezone сохраняет часовой пояс трассы
+func SetEditTrackTimezone(botUrl string, chatId int, update telegram.Update, repo database.ContentRepositoryInterface, state states.State) states.State {
+	if !database.IsAdmin(chatId, repo) {
+		return SendAccessDeniedMessage(botUrl, chatId, 0)
+	}
+
+	input := strings.TrimSpace(update.Message.Text)
+	trackId := state.GetID()
+
+	timezone := ""
+	if input != resetTimezoneInput {
+		loc, err := timefmt.LoadLocation(input)
+		if err != nil {
+			telegram.SendMessage(botUrl, chatId, "❌ <b>Неизвестный часовой пояс</b>\n\n"+
+				"Используйте название из базы IANA.\n"+
+				"💡 <i>Пример: Europe/Moscow, Europe/Rome, Asia/Dubai</i>", telegram.CreateStepKeyboard())
+			return state
+		}
+		timezone = loc.String()
+	}
+
+	if err := repo.SetTrackTimezone(trackId, timezone); err != nil {
+		telegram.SendMessage(botUrl, chatId, "❌ <b>Ошибка сохранения часового пояса</b>\n\n"+
+			"Попробуйте позже.", telegram.CreateBackToTracksMenuKeyboard())
+		return states.SetAdminKeyboard()
+	}
+
+	logger.AdminInfo(chatId, "Часовой пояс трассы %d: %q", trackId, timezone)
+	telegram.SendMessage(botUrl, chatId, "✅ <b>Часовой пояс трассы обновлен</b>\n\n"+
+		"🌍 "+timefmt.ZoneLabel(timefmt.Resolve(timezone))+"\n\n"+
+		"💡 Уже созданные тренировки не сдвигаются, их время будет показано в новом поясе.", telegram.CreateBackToTracksMenuKeyboard())
+	return states.SetAdminKeyboard()
+}
+
+// ViewUserTimezone показывает часовой пояс пользователя и предлагает выбрать другой
+func ViewUserTimezone(botUrl string, chatId int, messageId int, repo database.ContentRepositoryInterface) states.State {
+	if !showUserTimezone(botUrl, chatId, messageId, repo) {
+		return states.SetStartKeyboard()
+	}
+	return states.SetSetUserTimezone()
+}
+
+// showUserTimezone показывает текущий часовой пояс пользователя и кнопки выбора
+func showUserTimezone(botUrl string, chatId int, messageId int, repo database.ContentRepositoryInterface) bool {
+	user, err := repo.GetUserByChatId(chatId)
+	if err != nil || user == nil {
+		telegram.SendOrEditMessage(botUrl, chatId, messageId, "❌ <b>Пользователь не найден</b>\n\n"+
+			"🔍 Сначала зарегистрируйтесь в системе.", telegram.CreateBaseKeyboard())
+		return false
+	}
+
+	current := "не выбран, время показывается по местному времени трассы"
+	if user.Timezone != "" {
+		current = timefmt.ZoneLabel(timefmt.Resolve(user.Timezone))
+	}
+
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, "🕒 <b>Часовой пояс</b>\n\n"+
+		"🌍 <b>Сейчас:</b> "+current+"\n\n"+
+		"Выберите пояс, в котором показывать время тренировок, или введите его название.\n\n"+
+		"💡 <i>Пример: Europe/Moscow</i>", telegram.CreateUserTimezoneKeyboard(user.Timezone))
+	return true
+}
+
+// SelectUserTimezone сохраняет часовой пояс, выбранный кнопкой
+func SelectUserTimezone(botUrl string, chatId int, messageId int, index int, repo database.ContentRepositoryInterface) states.State {
+	if index < 0 || index >= len(timefmt.CommonZones) {
+		return ViewUserTimezone(botUrl, chatId, messageId, repo)
+	}
+	return saveUserTimezone(botUrl, chatId, messageId, timefmt.CommonZones[index], repo)
+}
+
+// ResetUserTimezone возвращает показ времени по поясу трассы
+func ResetUserTimezone(botUrl string, chatId int, messageId int, repo database.ContentRepositoryInterface) states.State {
+	return saveUserTimezone(botUrl, chatId, messageId, "", repo)
+}
+
+// SetUserTimezone принимает название часового пояса, введенное вручную
+func SetUserTimezone(botUrl string, chatId int, update telegram.Update, repo database.ContentRepositoryInterface, state states.State) states.State {
+	input := strings.TrimSpace(update.Message.Text)
+	if input == resetTimezoneInput {
+		return saveUserTimezone(botUrl, chatId, 0, "", repo)
+	}
+
+	loc, err := timefmt.LoadLocation(input)
+	if err != nil {
+		telegram.SendMessage(botUrl, chatId, "❌ <b>Неизвестный часовой пояс</b>\n\n"+
+			"Выберите пояс кнопкой или введите название из базы IANA.\n"+
+			"💡 <i>Пример: Europe/Moscow</i>", telegram.CreateBackToMenuKeyboard("userTimezone"))
+		return state
+	}
+	return saveUserTimezone(botUrl, chatId, 0, loc.String(), repo)
+}
+
+func saveUserTimezone(botUrl string, chatId int, messageId int, timezone string, repo database.ContentRepositoryInterface) states.State {
+	user, err := repo.GetUserByChatId(chatId)
+	if err != nil || user == nil {
+		telegram.SendOrEditMessage(botUrl, chatId, messageId, "❌ <b>Пользователь не найден</b>\n\n"+
+			"🔍 Сначала зарегистрируйтесь в системе.", telegram.CreateBaseKeyboard())
+		return states.SetStartKeyboard()
+	}
+
+	if err := repo.SetUserTimezone(user.ID, timezone); err != nil {
+		logger.UserError(chatId, "Часовой пояс: %v", err)
+		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
+	}
+
+	logger.UserInfo(chatId, "Часовой пояс: %q", timezone)
+	message := "✅ <b>Время тренировок будет показано по местному времени трассы</b>"
+	if timezone != "" {
+		message = "✅ <b>Часовой пояс сохранен</b>\n\n🌍 " + timefmt.ZoneLabel(timefmt.Resolve(timezone))
+	}
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, message, telegram.CreateBaseKeyboard())
+	return states.SetStartKeyboard()
+}
@@ -9,6 +9,7 @@ import (
 	"x.localhost/rvabot/internal/logger"
 	"x.localhost/rvabot/internal/states"
 	"x.localhost/rvabot/internal/telegram"
+	"x.localhost/rvabot/internal/timefmt"
 )
 
 func sendErrorMessage(botUrl string, chatId int, messageId int, repo database.ContentRepositoryInterface, err error) states.State {
@@ -26,12 +27,14 @@ func SendHelpMessage(botUrl string, chatId int, messageId int) states.State {
 		"📋 Команды:\n"+
 		"/start - главное меню\n"+
 		"/help - справка\n"+
+		"/back - шаг назад в диалоге\n"+
+		"/cancel - отменить текущий диалог\n"+
 		"/admin - админ-панель", telegram.CreateNavigationKeyboard())
 	return states.SetStartKeyboard()
 }
 
 func SendAccessDeniedMessage(botUrl string, chatId int, messageId int) states.State {
-	telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Доступ запрещен</b>\n"+
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, "❌ <b>Доступ запрещен</b>\n"+
 		"Нет прав администратора.", telegram.CreateBaseKeyboard())
 	return states.SetStartKeyboard()
 }
@@ -109,43 +112,51 @@ func SendScheduleMenuMessage(botUrl string, chatId int, messageId int, repo data
 		message += "📭 <b>Список тренировок пуст</b>\n\n" +
 			"📅 Добавьте первую тренировку через кнопку ниже."
 	} else {
-		message += formatTrainingsListForAdmin(trainings, repo)
+		message += formatTrainingsListForAdmin(trainings, chatId, repo)
 	}
 
-	telegram.EditMessage(botUrl, chatId, messageId, message, telegram.CreateTrainingsListWithActionsKeyboard(trainings))
+	telegram.EditMessage(botUrl, chatId, messageId, message, telegram.CreateTrainingsListWithActionsKeyboard(trainings, viewerLocation(chatId, repo), carCategoryNames(repo)))
 	return states.SetAdminKeyboard()
 }
 
 func SendOperationCancelledMessage(botUrl string, chatId int, messageId int) states.State {
-	telegram.EditMessage(botUrl, chatId, messageId, "🚫 <b>Операция отменена</b>\n\n"+
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, "🚫 <b>Операция отменена</b>\n\n"+
 		"💡 Вы можете повторить операцию позже.", telegram.CreateBaseKeyboard())
 	return states.SetStartKeyboard()
 }
 
 func SendOperationCancelledWithTrainersMenu(botUrl string, chatId int, messageId int) states.State {
-	telegram.EditMessage(botUrl, chatId, messageId, "🚫 <b>Операция отменена</b>\n\n"+
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, "🚫 <b>Операция отменена</b>\n\n"+
 		"💡 Вы можете повторить операцию позже.", telegram.CreateBackToTrainersMenuKeyboard())
 	return states.SetAdminKeyboard()
 }
 
 func SendOperationCancelledWithTracksMenu(botUrl string, chatId int, messageId int) states.State {
-	telegram.EditMessage(botUrl, chatId, messageId, "🚫 <b>Операция отменена</b>\n\n"+
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, "🚫 <b>Операция отменена</b>\n\n"+
 		"💡 Вы можете повторить операцию позже.", telegram.CreateBackToTracksMenuKeyboard())
 	return states.SetAdminKeyboard()
 }
 
 func SendOperationCancelledWithScheduleMenu(botUrl string, chatId int, messageId int) states.State {
-	telegram.EditMessage(botUrl, chatId, messageId, "🚫 <b>Операция отменена</b>\n\n"+
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, "🚫 <b>Операция отменена</b>\n\n"+
 		"💡 Вы можете повторить операцию позже.", telegram.CreateBackToScheduleMenuKeyboard())
 	return states.SetAdminKeyboard()
 }
 
+func SendOperationCancelledWithAdminMenu(botUrl string, chatId int, messageId int) states.State {
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, "🚫 <b>Операция отменена</b>\n\n"+
+		"💡 Вы можете повторить операцию позже.", telegram.CreateBackToAdminKeyboard())
+	return states.SetAdminKeyboard()
+}
+
 func Help(botUrl string, ChatId int) states.State {
 	telegram.SendMessage(botUrl, ChatId, "🎓 <b>Добро пожаловать в RVA Academy Bot!</b>\n\n"+
 		"🤖 Я помогу вам управлять тренировками и тренерами.\n\n"+
 		"📋 <b>Доступные команды:</b>\n"+
 		"🏠 /start - главное меню\n"+
 		"❓ /help - эта справка\n"+
+		"🔙 /back - вернуться на шаг назад\n"+
+		"🚫 /cancel - отменить текущее действие\n"+
 		"⚙️ /admin - панель администратора\n\n"+
 		"💡 <i>Используйте кнопки ниже для навигации</i>", telegram.CreateNavigationKeyboard())
 	return states.SetStartKeyboard()
@@ -226,8 +237,8 @@ func ViewScheduleUser(botUrl string, chatId int, messageId int, repo database.Co
 	}
 
 	message := "📅 <b>Ваше расписание тренировок</b>\n\n"
-	message += formatTrainingsListForUsers(trainings, repo)
-	telegram.EditMessage(botUrl, chatId, messageId, message, telegram.CreateBackToInfoKeyboard())
+	message += formatTrainingsListForUsers(trainings, chatId, repo)
+	telegram.EditMessage(botUrl, chatId, messageId, message, telegram.CreateUserScheduleKeyboard())
 	return states.SetStartKeyboard()
 }
 
@@ -238,17 +249,14 @@ func SetUserName(botUrl string, chatId int, update telegram.Update, repo databas
 	if len(name) < 2 {
 		telegram.SendMessage(botUrl, chatId, "❌ <b>Ошибка ввода</b>\n\n"+
 			"Имя должно содержать минимум 2 символа.\n"+
-			"Попробуйте еще раз:", telegram.CreateCancelKeyboard())
-		return states.SetEnterUserName()
+			"Попробуйте еще раз:", telegram.CreateStepKeyboard())
+		return state
 	}
 
 	tempData := state.GetTempUserData()
 	tempData.Name = name
 
-	message := "📱 <b>Введите ваш Telegram ID</b>\n\n" +
-		"<i>Пример: @username или user123</i>"
-
-	telegram.SendMessage(botUrl, chatId, message, telegram.CreateCancelKeyboard())
+	promptUserTgId(botUrl, chatId, 0)
 
 	newState := states.SetEnterUserTgId()
 	return newState.SetTempUserData(tempData)
@@ -261,8 +269,8 @@ func SetUserTgId(botUrl string, chatId int, update telegram.Update, repo databas
 	if len(tgId) < 3 {
 		telegram.SendMessage(botUrl, chatId, "❌ <b>Ошибка ввода</b>\n\n"+
 			"Telegram ID должен содержать минимум 3 символа.\n"+
-			"Попробуйте еще раз:", telegram.CreateCancelKeyboard())
-		return states.SetEnterUserTgId()
+			"Попробуйте еще раз:", telegram.CreateStepKeyboard())
+		return state
 	}
 
 	tempData := state.GetTempUserData()
@@ -317,36 +325,175 @@ func ConfirmUserRegistration(botUrl string, chatId int, messageId int, repo data
 func StartTrainingRegistration(botUrl string, chatId int, messageId int, repo database.ContentRepositoryInterface) states.State {
 	user, err := repo.GetUserByChatId(chatId)
 	if err != nil || user == nil {
-		telegram.EditMessage(botUrl, chatId, messageId, "🏃‍♂️ <b>Запись на тренировку</b>\n\n"+
-			"📋 <b>Согласие на обработку персональных данных</b>\n\n"+
-			"Для регистрации необходимо ваше согласие на обработку персональных данных.\n\n"+
-			"<i>Нажимая \"Согласен\", вы подтверждаете, что даете согласие на обработку ваших персональных данных в соответствии с политикой конфиденциальности.</i>",
-			telegram.CreateDataConsentKeyboard())
+		promptDataConsent(botUrl, chatId, messageId)
 
 		tempData := &states.TempUserData{}
 		state := states.SetUserDataConsent()
 		return state.SetTempUserData(tempData)
 	}
 
+	if checkBookingRestriction(botUrl, chatId, messageId, user, repo) {
+		return states.SetStartKeyboard()
+	}
+
+	if !showRegistrationTrackSelection(botUrl, chatId, messageId, repo, user) {
+		return states.SetStartKeyboard()
+	}
+
+	tempData := &states.TempRegistrationData{}
+	state := states.SetSelectTrackForRegistration()
+	return state.SetTempRegistrationData(tempData)
+}
+
+// promptDataConsent показывает запрос согласия на обработку персональных данных
+func promptDataConsent(botUrl string, chatId int, messageId int) {
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, "🏃‍♂️ <b>Запись на тренировку</b>\n\n"+
+		"📋 <b>Согласие на обработку персональных данных</b>\n\n"+
+		"Для регистрации необходимо ваше согласие на обработку персональных данных.\n\n"+
+		"<i>Нажимая \"Согласен\", вы подтверждаете, что даете согласие на обработку ваших персональных данных в соответствии с политикой конфиденциальности.</i>",
+		telegram.CreateDataConsentKeyboard())
+}
+
+// promptUserName показывает шаг ввода ФИО пользователя
+func promptUserName(botUrl string, chatId int, messageId int) {
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, "👤 <b>Введите ваше ФИО</b>\n\n"+
+		"<i>Пример: Иванов Иван Иванович</i>", telegram.CreateStepKeyboard())
+}
+
+// promptUserTgId показывает шаг ввода Telegram ID пользователя
+func promptUserTgId(botUrl string, chatId int, messageId int) {
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, "📱 <b>Введите ваш Telegram ID</b>\n\n"+
+		"<i>Пример: @username или user123</i>", telegram.CreateStepKeyboard())
+}
+
+// showRegistrationTrackSelection показывает шаг выбора трассы при записи
+func showRegistrationTrackSelection(botUrl string, chatId int, messageId int, repo database.ContentRepositoryInterface, user *database.User) bool {
 	tracks, err := repo.GetTracksWithActiveTrainings()
 	if err != nil {
 		logger.UserError(chatId, "Получение треков: %v", err)
-		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
+		sendErrorMessage(botUrl, chatId, messageId, repo, err)
+		return false
 	}
 
 	if len(tracks) == 0 {
-		telegram.EditMessage(botUrl, chatId, messageId, "🏁 <b>Нет доступных трасс</b>\n"+
+		telegram.SendOrEditMessage(botUrl, chatId, messageId, "🏁 <b>Нет доступных трасс</b>\n"+
 			"Нет активных тренировок.", telegram.CreateBaseKeyboard())
-		return states.SetStartKeyboard()
+		return false
 	}
 
-	telegram.EditMessage(botUrl, chatId, messageId, "🏃‍♂️ <b>Запись на тренировку</b>\n\n"+
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, "🏃‍♂️ <b>Запись на тренировку</b>\n\n"+
 		"👤 "+user.Name+"\n"+
 		"🏁 <b>Шаг 1/3:</b> Трасса", telegram.CreateTrackSelectionForRegistrationKeyboard(tracks))
+	return true
+}
 
-	tempData := &states.TempRegistrationData{}
-	state := states.SetSelectTrackForRegistration()
-	return state.SetTempRegistrationData(tempData)
+// showRegistrationTrainerSelection показывает шаг выбора тренера на выбранной трассе
+func showRegistrationTrainerSelection(botUrl string, chatId int, messageId int, repo database.ContentRepositoryInterface, trackId uint) bool {
+	track, err := repo.GetTrackByID(trackId)
+	if err != nil || track == nil {
+		telegram.SendOrEditMessage(botUrl, chatId, messageId, "❌ <b>Трасса не найдена</b>", telegram.CreateBaseKeyboard())
+		return false
+	}
+
+	trainers, err := repo.GetTrainersByTrack(trackId)
+	if err != nil {
+		logger.UserError(chatId, "Получение тренеров: %v", err)
+		sendErrorMessage(botUrl, chatId, messageId, repo, err)
+		return false
+	}
+
+	if len(trainers) == 0 {
+		telegram.SendOrEditMessage(botUrl, chatId, messageId, "👨‍🏫 <b>Нет тренеров</b>\n"+
+			"На трассе \""+track.Name+"\" нет тренировок.", telegram.CreateBaseKeyboard())
+		return false
+	}
+
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, "🏃‍♂️ <b>Запись на тренировку</b>\n\n"+
+		"✅ Трасса: "+track.Name+"\n"+
+		"👨‍🏫 <b>Шаг 2/3:</b> Тренер", telegram.CreateTrainerSelectionForRegistrationKeyboard(trainers))
+	return true
+}
+
+// showRegistrationTimeSelection показывает шаг выбора времени тренировки
+func showRegistrationTimeSelection(botUrl string, chatId int, messageId int, repo database.ContentRepositoryInterface, trackId uint, trainerId uint) bool {
+	trainer, err := repo.GetTrainerByID(trainerId)
+	if err != nil || trainer == nil {
+		sendErrorMessage(botUrl, chatId, messageId, repo, err)
+		return false
+	}
+
+	track, err := repo.GetTrackByID(trackId)
+	if err != nil || track == nil {
+		sendErrorMessage(botUrl, chatId, messageId, repo, err)
+		return false
+	}
+
+	trainings, err := repo.GetActiveTrainingsByTrackAndTrainer(trackId, trainerId)
+	if err != nil {
+		logger.UserError(chatId, "Получение тренировок: %v", err)
+		sendErrorMessage(botUrl, chatId, messageId, repo, err)
+		return false
+	}
+
+	if len(trainings) == 0 {
+		telegram.SendOrEditMessage(botUrl, chatId, messageId, "📅 <b>Нет доступных тренировок</b>\n\n"+
+			"🏃‍♂️ <b>Тренер:</b> "+trainer.Name+"\n"+
+			"🏁 <b>Трасса:</b> "+track.Name+"\n\n"+
+			"📝 <b>У выбранного тренера нет активных тренировок на этой трассе.</b>\n"+
+			"💡 Попробуйте выбрать другого тренера или трассу.", telegram.CreateBaseKeyboard())
+		return false
+	}
+
+	// Тренировки не по уровню пользователя скрываются, но о них сообщается.
+	// Тренировка остается в списке, если подходит самому пользователю или
+	// одному из его участников - за кого записаться, выбирается следующим шагом.
+	hiddenNote := ""
+	if user, _ := repo.GetUserByChatId(chatId); user != nil {
+		levels := []int{user.SkillLevel}
+		participants, _ := repo.GetParticipants(user.ID)
+		for _, participant := range participants {
+			levels = append(levels, participant.SkillLevel)
+		}
+
+		var qualified []database.Training
+		for _, t := range trainings {
+			for _, level := range levels {
+				if database.QualifiesForTraining(level, &t) {
+					qualified = append(qualified, t)
+					break
+				}
+			}
+		}
+
+		if hidden := len(trainings) - len(qualified); hidden > 0 {
+			if len(qualified) == 0 {
+				telegram.SendOrEditMessage(botUrl, chatId, messageId, fmt.Sprintf("🎓 <b>Нет тренировок для вашего уровня</b>\n\n"+
+					"🏃‍♂️ <b>Тренер:</b> %s\n"+
+					"🏁 <b>Трасса:</b> %s\n"+
+					"👤 <b>Ваш уровень:</b> %s\n\n"+
+					"📝 Все тренировки тренера на этой трассе рассчитаны на другой уровень подготовки (%d).\n"+
+					"💡 Тренер повышает уровень по итогам посещенных тренировок.",
+					trainer.Name, track.Name, formatSkillLevel(user.SkillLevel), hidden), telegram.CreateBaseKeyboard())
+				return false
+			}
+			hiddenNote = fmt.Sprintf("\n🎓 Скрыто тренировок не для вашего уровня (%s): %d", formatSkillLevel(user.SkillLevel), hidden)
+			trainings = qualified
+		}
+	}
+
+	for i := 0; i < len(trainings)-1; i++ {
+		for j := 0; j < len(trainings)-i-1; j++ {
+			if trainings[j].StartTime.After(trainings[j+1].StartTime) {
+				trainings[j], trainings[j+1] = trainings[j+1], trainings[j]
+			}
+		}
+	}
+
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, "🏃‍♂️ <b>Запись на тренировку</b>\n\n"+
+		"✅ Трасса: "+track.Name+"\n"+
+		"✅ Тренер: "+trainer.Name+"\n"+
+		"📅 <b>Шаг 3/3:</b> Время"+hiddenNote, telegram.CreateTrainingTimeSelectionKeyboard(trainings, viewerLocation(chatId, repo), carCategoryNames(repo)))
+	return true
 }
 
 func ConfirmTrainingRegistration(botUrl string, chatId int, messageId int, trainingId uint, repo database.ContentRepositoryInterface) states.State {
@@ -362,29 +509,64 @@ func ConfirmTrainingRegistration(botUrl string, chatId int, messageId int, train
 		return states.SetStartKeyboard()
 	}
 
-	existingRegistration, _ := repo.GetTrainingRegistrationByUserAndTraining(user.ID, trainingId)
-	if existingRegistration != nil {
-		telegram.EditMessage(botUrl, chatId, messageId, "⚠️ <b>Вы уже зарегистрированы</b>\n\n"+
-			"🏃‍♂️ Вы уже записаны на эту тренировку.\n"+
-			"📊 <b>Статус:</b> "+existingRegistration.Status, telegram.CreateBaseKeyboard())
-		return states.SetStartKeyboard()
+	// С участниками под аккаунтом сначала выбирается, кого записать
+	if participants, _ := repo.GetParticipants(user.ID); len(participants) > 0 {
+		showRegistrationParticipantSelection(botUrl, chatId, messageId, trainingId, repo)
+		return states.SetSelectRegistrationParticipant(trainingId)
 	}
 
-	registrations, _ := repo.GetTrainingRegistrationsByTrainingID(trainingId)
-	registeredCount := 0
-	for _, reg := range registrations {
-		if reg.Status == "confirmed" || reg.Status == "pending" {
-			registeredCount++
-		}
+	return continueTrainingRegistration(botUrl, chatId, messageId, training, user, nil, repo)
+}
+
+// continueTrainingRegistration проверяет, можно ли записать пользователя или
+// его участника, и показывает подтверждение записи. participant nil - запись за себя.
+func continueTrainingRegistration(botUrl string, chatId int, messageId int, training *database.Training, user *database.User, participant *database.Participant, repo database.ContentRepositoryInterface) states.State {
+	var participantId uint
+	if participant != nil {
+		participantId = participant.ID
 	}
 
-	if registeredCount >= training.MaxParticipants {
-		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Нет свободных мест</b>\n\n"+
-			"🏃‍♂️ На эту тренировку уже записалось максимальное количество участников.\n"+
-			"💡 Попробуйте выбрать другую тренировку.", telegram.CreateBaseKeyboard())
+	existingRegistration, _ := repo.GetTrainingRegistrationByUserAndTraining(user.ID, training.ID, participantId)
+	if existingRegistration != nil && existingRegistration.Status != database.RegistrationStatusCancelled {
+		return showExistingRegistration(botUrl, chatId, messageId, existingRegistration, repo)
+	}
+
+	if checkSkillRequirement(botUrl, chatId, messageId, user, participant, training) {
+		return states.SetStartKeyboard()
+	}
+
+	if participant != nil && checkParticipantAge(botUrl, chatId, messageId, participant, training, repo) {
 		return states.SetStartKeyboard()
 	}
 
+	registeredCount, err := repo.CountActiveRegistrations(training.ID)
+	if err != nil {
+		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
+	}
+
+	capacity, err := repo.GetTrainingCapacity(training)
+	if err != nil {
+		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
+	}
+
+	// Предварительная проверка для подсказки; окончательно места проверяются при записи
+	if int(registeredCount) >= capacity {
+		return offerWaitlist(botUrl, chatId, messageId, training.ID, participantId)
+	}
+
+	showTrainingRegistrationConfirmation(botUrl, chatId, messageId, training, participant, nil, repo)
+	return states.SetConfirmTrainingRegistration(training.ID).WithParticipant(participantId)
+}
+
+// showTrainingRegistrationConfirmation показывает детали тренировки, участника,
+// цену и скидку выбранного промокода перед подтверждением записи
+func showTrainingRegistrationConfirmation(botUrl string, chatId int, messageId int, training *database.Training, participant *database.Participant, promo *database.PromoCode, repo database.ContentRepositoryInterface) {
+	registeredCount, _ := repo.CountActiveRegistrations(training.ID)
+	capacity, err := repo.GetTrainingCapacity(training)
+	if err != nil {
+		capacity = training.MaxParticipants
+	}
+
 	trainer, _ := repo.GetTrainerByID(training.TrainerID)
 	track, _ := repo.GetTrackByID(training.TrackID)
 
@@ -398,21 +580,30 @@ func ConfirmTrainingRegistration(botUrl string, chatId int, messageId int, train
 		trackName = track.Name
 	}
 
+	participantLine := ""
+	if participant != nil {
+		participantLine = "🧒 <b>Участник:</b> " + telegram.EscapeHTML(participant.Name) + "\n"
+	}
+
 	message := fmt.Sprintf("✅ <b>Подтверждение записи на тренировку</b>\n\n"+
 		"📋 <b>Детали тренировки:</b>\n\n"+
+		"%s"+
 		"🏃‍♂️ <b>Тренировка:</b> %s\n"+
 		"🚗 <b>Категория:</b> %s\n"+
 		"👨‍🏫 <b>Тренер:</b> %s\n"+
 		"📅 <b>Дата и время:</b> %s\n"+
-		"👥 <b>Свободных мест:</b> %d\n\n"+
+		"👥 <b>Свободных мест:</b> %d\n"+
+		"%s%s\n"+
 		"❓ <b>Подтвердить запись на тренировку?</b>",
-		trackName, training.CarCategory, trainerName, training.StartTime.Format("02.01.2006 15:04"), training.MaxParticipants-registeredCount)
+		participantLine, trackName, carCategoryName(training.CarCategoryID, repo), trainerName, timefmt.DateTime(training.StartTime, repo.GetViewerLocation(chatId, training.TrackID)),
+		max(capacity-int(registeredCount), 0), formatTrainingCarCategoryRequirements(training, repo), formatRegistrationPrice(training, promo, repo))
 
-	telegram.EditMessage(botUrl, chatId, messageId, message, telegram.CreateTrainingRegistrationConfirmationKeyboard(trainingId))
-	return states.SetConfirmTrainingRegistration(trainingId)
+	telegram.SendOrEditMessage(botUrl, chatId, messageId, message, telegram.CreateTrainingRegistrationConfirmationKeyboard(training.ID))
 }
 
-func ExecuteTrainingRegistration(botUrl string, chatId int, messageId int, trainingId uint, repo database.ContentRepositoryInterface) states.State {
+// ExecuteTrainingRegistration записывает пользователя на тренировку. Промокод,
+// введенный на шаге подтверждения, берется из состояния.
+func ExecuteTrainingRegistration(botUrl string, chatId int, messageId int, trainingId uint, repo database.ContentRepositoryInterface, state states.State) states.State {
 	user, err := repo.GetUserByChatId(chatId)
 	if err != nil || user == nil {
 		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Пользователь не найден</b>\n\n"+
@@ -420,134 +611,89 @@ func ExecuteTrainingRegistration(botUrl string, chatId int, messageId int, train
 		return states.SetStartKeyboard()
 	}
 
-	registration := &database.TrainingRegistration{
-		TrainingID: trainingId,
-		UserID:     user.ID,
-		Status:     "pending",
+	if checkBookingRestriction(botUrl, chatId, messageId, user, repo) {
+		return states.SetStartKeyboard()
 	}
 
-	regId, err := repo.CreateTrainingRegistration(registration)
+	participantId := registrationParticipantID(state, trainingId)
+	registration, err := repo.RegisterForTraining(trainingId, user.ID, participantId)
+	if database.HasErrorCode(err, database.ErrCodeTrainingFull) {
+		// Последнее место заняли, пока пользователь подтверждал запись
+		return offerWaitlist(botUrl, chatId, messageId, trainingId, participantId)
+	}
 	if err != nil {
 		logger.UserError(chatId, "Создание регистрации: %v", err)
 		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
 	}
+	regId := registration.ID
 
-	training, _ := repo.GetTrainingById(trainingId)
-	trainer, _ := repo.GetTrainerByID(training.TrainerID)
-	track, _ := repo.GetTrackByID(training.TrackID)
-
-	if trainer != nil && trainer.ChatId != 0 {
-		trackName := "Неизвестная трасса"
-		if track != nil {
-			trackName = track.Name
-		}
+	promoMessage := ""
+	if trainingIdInState, _ := state.Data["trainingId"].(uint); trainingIdInState == trainingId && state.GetPromoCodeID() != 0 {
+		promoMessage = applyRegistrationPromoCode(chatId, regId, state.GetPromoCodeID(), repo)
+	}
 
-		notificationMessage := fmt.Sprintf("🔔 <b>Новая заявка</b>\n"+
-			"👤 %s\n"+
-			"📱 %s\n"+
-			"🏃‍♂️ %s\n"+
-			"📅 %s",
-			user.Name, user.TgId, trackName, training.StartTime.Format("02.01.2006 15:04"))
+	credit := reserveRegistrationCredit(chatId, user.ID, regId, repo)
+	notifyTrainerAboutRegistration(botUrl, user, trainingId, regId, repo)
 
-		telegram.SendMessage(botUrl, trainer.ChatId, notificationMessage, telegram.CreateTrainingApprovalKeyboard(regId))
+	keyboard := telegram.CreateBaseKeyboard()
+	if trackHasRentals(trainingId, repo) {
+		keyboard = telegram.CreateRegistrationRentalKeyboard(regId)
 	}
 
 	logger.UserInfo(chatId, "Регистрация создана: ID=%d, TrainingID=%d", regId, trainingId)
 	telegram.EditMessage(botUrl, chatId, messageId, "🎉 <b>Заявка на тренировку отправлена!</b>\n\n"+
+		formatRegistrationParticipant(registration, repo)+
 		"✅ <b>Ваша заявка принята и отправлена тренеру на рассмотрение.</b>\n\n"+
 		"📱 <b>Вы получите уведомление о решении тренера.</b>\n"+
-		"⏰ <b>Обычно рассмотрение занимает несколько часов.</b>", telegram.CreateBaseKeyboard())
+		"⏰ <b>Обычно рассмотрение занимает несколько часов.</b>"+promoMessage+formatReservedCredit(credit), keyboard)
 	return states.SetStartKeyboard()
 }
 
-func BackToTrackSelection(botUrl string, chatId int, messageId int, repo database.ContentRepositoryInterface, state states.State) states.State {
-	user, err := repo.GetUserByChatId(chatId)
-	if err != nil || user == nil {
-		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Пользователь не найден</b>", telegram.CreateBaseKeyboard())
-		return states.SetStartKeyboard()
-	}
-
-	tracks, err := repo.GetTracksWithActiveTrainings()
-	if err != nil {
-		logger.UserError(chatId, "Получение треков: %v", err)
-		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
-	}
-
-	if len(tracks) == 0 {
-		telegram.EditMessage(botUrl, chatId, messageId, "🏁 <b>Нет доступных трасс</b>\n"+
-			"Нет активных тренировок.", telegram.CreateBaseKeyboard())
-		return states.SetStartKeyboard()
+// notifyTrainerAboutRegistration отправляет тренеру новую заявку на рассмотрение
+func notifyTrainerAboutRegistration(botUrl string, user *database.User, trainingId uint, registrationId uint, repo database.ContentRepositoryInterface) {
+	training, _ := repo.GetTrainingById(trainingId)
+	if training == nil {
+		return
 	}
 
-	telegram.EditMessage(botUrl, chatId, messageId, "🏃‍♂️ <b>Запись на тренировку</b>\n\n"+
-		"👤 "+user.Name+"\n"+
-		"🏁 <b>Шаг 1/3:</b> Трасса", telegram.CreateTrackSelectionForRegistrationKeyboard(tracks))
-
-	tempData := &states.TempRegistrationData{}
-	newState := states.SetSelectTrackForRegistration()
-	return newState.SetTempRegistrationData(tempData)
-}
-
-func BackToTrainerSelection(botUrl string, chatId int, messageId int, repo database.ContentRepositoryInterface, state states.State) states.State {
-	tempData := state.GetTempRegistrationData()
-	if tempData.TrackID == 0 {
-		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Ошибка навигации</b>\n"+
-			"Начните заново.", telegram.CreateBaseKeyboard())
-		return states.SetStartKeyboard()
+	trainer, _ := repo.GetTrainerByID(training.TrainerID)
+	if trainer == nil || trainer.ChatId == 0 {
+		return
 	}
 
-	track, err := repo.GetTrackByID(tempData.TrackID)
-	if err != nil || track == nil {
-		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Трасса не найдена</b>", telegram.CreateBaseKeyboard())
-		return states.SetStartKeyboard()
+	trackName := "Неизвестная трасса"
+	if track, _ := repo.GetTrackByID(training.TrackID); track != nil {
+		trackName = track.Name
 	}
 
-	trainers, err := repo.GetTrainersByTrack(tempData.TrackID)
-	if err != nil {
-		logger.UserError(chatId, "Получение тренеров: %v", err)
-		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
+	payment := "💵 Оплата на месте"
+	if credit, _ := repo.GetRegistrationCredit(registrationId); credit != nil && credit.Kind == database.CreditKindReserve {
+		payment = "💳 Оплачено кредитом из пакета"
 	}
 
-	if len(trainers) == 0 {
-		telegram.EditMessage(botUrl, chatId, messageId, "👨‍🏫 <b>Нет тренеров</b>\n"+
-			"На трассе \""+track.Name+"\" нет тренировок.", telegram.CreateBaseKeyboard())
-		return states.SetStartKeyboard()
-	}
+	registration, _ := repo.GetTrainingRegistrationByID(registrationId)
 
-	telegram.EditMessage(botUrl, chatId, messageId, "🏃‍♂️ <b>Запись на тренировку</b>\n\n"+
-		"✅ Трасса: "+track.Name+"\n"+
-		"👨‍🏫 <b>Шаг 2/3:</b> Тренер", telegram.CreateTrainerSelectionForRegistrationKeyboard(trainers))
+	notificationMessage := fmt.Sprintf("🔔 <b>Новая заявка</b>\n"+
+		"%s"+
+		"👤 %s\n"+
+		"📱 %s\n"+
+		"🏃‍♂️ %s\n"+
+		"📅 %s\n"+
+		"%s",
+		formatRegistrationParticipant(registration, repo), user.Name, user.TgId, trackName,
+		timefmt.DateTime(training.StartTime, repo.GetViewerLocation(trainer.ChatId, training.TrackID)), payment)
 
-	newState := states.SetSelectTrainerForRegistration()
-	return newState.SetTempRegistrationData(tempData)
+	telegram.SendMessage(botUrl, trainer.ChatId, notificationMessage, telegram.CreateTrainingApprovalKeyboard(registrationId))
 }
 
 func SelectTrackForRegistration(botUrl string, chatId int, messageId int, trackId uint, repo database.ContentRepositoryInterface, state states.State) states.State {
 	tempData := state.GetTempRegistrationData()
 	tempData.TrackID = trackId
 
-	track, err := repo.GetTrackByID(trackId)
-	if err != nil || track == nil {
-		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Трасса не найдена</b>", telegram.CreateBaseKeyboard())
+	if !showRegistrationTrainerSelection(botUrl, chatId, messageId, repo, trackId) {
 		return states.SetStartKeyboard()
 	}
 
-	trainers, err := repo.GetTrainersByTrack(trackId)
-	if err != nil {
-		logger.UserError(chatId, "Получение тренеров: %v", err)
-		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
-	}
-
-	if len(trainers) == 0 {
-		telegram.EditMessage(botUrl, chatId, messageId, "👨‍🏫 <b>Нет тренеров</b>\n"+
-			"На трассе \""+track.Name+"\" нет тренировок.", telegram.CreateBaseKeyboard())
-		return states.SetStartKeyboard()
-	}
-
-	telegram.EditMessage(botUrl, chatId, messageId, "🏃‍♂️ <b>Запись на тренировку</b>\n\n"+
-		"✅ Трасса: "+track.Name+"\n"+
-		"👨‍🏫 <b>Шаг 2/3:</b> Тренер", telegram.CreateTrainerSelectionForRegistrationKeyboard(trainers))
-
 	newState := states.SetSelectTrainerForRegistration()
 	return newState.SetTempRegistrationData(tempData)
 }
@@ -556,44 +702,10 @@ func SelectTrainerForRegistration(botUrl string, chatId int, messageId int, trai
 	tempData := state.GetTempRegistrationData()
 	tempData.TrainerID = trainerId
 
-	trainer, err := repo.GetTrainerByID(trainerId)
-	if err != nil || trainer == nil {
-		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
-	}
-
-	track, err := repo.GetTrackByID(tempData.TrackID)
-	if err != nil || track == nil {
-		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
-	}
-
-	trainings, err := repo.GetActiveTrainingsByTrackAndTrainer(tempData.TrackID, trainerId)
-	if err != nil {
-		logger.UserError(chatId, "Получение тренировок: %v", err)
-		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
-	}
-
-	if len(trainings) == 0 {
-		telegram.EditMessage(botUrl, chatId, messageId, "📅 <b>Нет доступных тренировок</b>\n\n"+
-			"🏃‍♂️ <b>Тренер:</b> "+trainer.Name+"\n"+
-			"🏁 <b>Трасса:</b> "+track.Name+"\n\n"+
-			"📝 <b>У выбранного тренера нет активных тренировок на этой трассе.</b>\n"+
-			"💡 Попробуйте выбрать другого тренера или трассу.", telegram.CreateBaseKeyboard())
+	if !showRegistrationTimeSelection(botUrl, chatId, messageId, repo, tempData.TrackID, trainerId) {
 		return states.SetStartKeyboard()
 	}
 
-	for i := 0; i < len(trainings)-1; i++ {
-		for j := 0; j < len(trainings)-i-1; j++ {
-			if trainings[j].StartTime.After(trainings[j+1].StartTime) {
-				trainings[j], trainings[j+1] = trainings[j+1], trainings[j]
-			}
-		}
-	}
-
-	telegram.EditMessage(botUrl, chatId, messageId, "🏃‍♂️ <b>Запись на тренировку</b>\n\n"+
-		"✅ Трасса: "+track.Name+"\n"+
-		"✅ Тренер: "+trainer.Name+"\n"+
-		"📅 <b>Шаг 3/3:</b> Время", telegram.CreateTrainingTimeSelectionKeyboard(trainings))
-
 	newState := states.SetSelectTrainingTimeForRegistration()
 	return newState.SetTempRegistrationData(tempData)
 }
@@ -632,31 +744,53 @@ func ApproveTrainingRegistration(botUrl string, chatId int, messageId int, regis
 		return states.SetStartKeyboard()
 	}
 
-	registration.Status = "confirmed"
+	// Участник мог отменить заявку, пока она ждала решения тренера
+	if registration.Status != database.RegistrationStatusPending {
+		telegram.EditMessage(botUrl, chatId, messageId, "ℹ️ <b>Заявка уже обработана или отменена</b>", telegram.CreateBaseKeyboard())
+		return states.SetStartKeyboard()
+	}
+
+	registration.Status = database.RegistrationStatusConfirmed
 	err = repo.UpdateTrainingRegistration(registrationId, registration)
 	if err != nil {
 		logger.UserError(chatId, "Одобрение регистрации %d: %v", registrationId, err)
 		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
 	}
 
+	credit, err := repo.ConsumeCredit(registrationId, "")
+	if err != nil {
+		logger.UserError(chatId, "Списание кредита за запись %d: %v", registrationId, err)
+	} else if credit != nil {
+		markCreditPayment(chatId, registration, training, repo)
+	}
+
+	rentalMessage := reserveRegistrationRentals(chatId, registrationId, repo)
+
 	user, _ := repo.GetUserByID(registration.UserID)
 	track, _ := repo.GetTrackByID(training.TrackID)
 
 	trackName := "Неизвестная трасса"
+	trackTimezone := ""
 	if track != nil {
 		trackName = track.Name
+		trackTimezone = track.Timezone
 	}
 
 	if user != nil {
 		userMessage := fmt.Sprintf("🎉 <b>Заявка на тренировку одобрена!</b>\n\n"+
 			"✅ <b>Ваша заявка на тренировку была подтверждена тренером.</b>\n\n"+
+			"%s"+
 			"🏃‍♂️ <b>Тренировка:</b> %s\n"+
 			"🚗 <b>Категория:</b> %s\n"+
-			"📅 <b>Дата и время:</b> %s\n\n"+
+			"📅 <b>Дата и время:</b> %s%s\n\n"+
 			"💡 <b>До встречи на тренировке!</b>",
-			trackName, training.CarCategory, training.StartTime.Format("02.01.2006 15:04"))
+			formatRegistrationParticipant(registration, repo), trackName, carCategoryName(training.CarCategoryID, repo), timefmt.DateTime(training.StartTime, timefmt.Resolve(user.Timezone, trackTimezone)), rentalMessage)
 
 		telegram.SendMessage(botUrl, user.ChatId, userMessage, telegram.CreateBaseKeyboard())
+
+		if credit != nil {
+			notifyLowCreditBalance(botUrl, user.ID, credit.Balance, repo)
+		}
 	}
 
 	// Notify all active admins
@@ -679,10 +813,11 @@ func ApproveTrainingRegistration(botUrl string, chatId int, messageId int, regis
 			"🚗 <b>Категория:</b> %s\n"+
 			"👨‍🏫 <b>Тренер:</b> %s\n"+
 			"📅 <b>Дата и время:</b> %s\n\n"+
+			"%s"+
 			"👤 <b>Пользователь:</b> %s\n"+
 			"📱 <b>Telegram:</b> %s",
-			trackName, training.CarCategory, trainerName, training.StartTime.Format("02.01.2006 15:04"),
-			userName, userTg)
+			trackName, carCategoryName(training.CarCategoryID, repo), trainerName, timefmt.DateTime(training.StartTime, timefmt.Resolve(trackTimezone)),
+			formatRegistrationParticipant(registration, repo), userName, userTg)
 
 		for _, a := range admins {
 			if a.IsActive && a.ChatId != 0 {
@@ -714,41 +849,64 @@ func RejectTrainingRegistration(botUrl string, chatId int, messageId int, regist
 		return states.SetStartKeyboard()
 	}
 
-	registration.Status = "rejected"
+	// Участник мог отменить заявку, пока она ждала решения тренера
+	if registration.Status != database.RegistrationStatusPending {
+		telegram.EditMessage(botUrl, chatId, messageId, "ℹ️ <b>Заявка уже обработана или отменена</b>", telegram.CreateBaseKeyboard())
+		return states.SetStartKeyboard()
+	}
+
+	registration.Status = database.RegistrationStatusRejected
 	err = repo.UpdateTrainingRegistration(registrationId, registration)
 	if err != nil {
 		logger.UserError(chatId, "Отклонение регистрации %d: %v", registrationId, err)
 		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
 	}
 
+	credit, err := repo.RefundCredit(registrationId, "")
+	if err != nil {
+		logger.UserError(chatId, "Возврат кредита за запись %d: %v", registrationId, err)
+	} else if credit != nil {
+		refundCreditPayment(chatId, registrationId, repo)
+	}
+
 	user, _ := repo.GetUserByID(registration.UserID)
 	track, _ := repo.GetTrackByID(training.TrackID)
 
 	trackName := "Неизвестная трасса"
+	trackTimezone := ""
 	if track != nil {
 		trackName = track.Name
+		trackTimezone = track.Timezone
 	}
 
 	if user != nil {
 		userMessage := fmt.Sprintf("❌ <b>Заявка на тренировку отклонена</b>\n\n"+
+			"%s"+
 			"🏃‍♂️ <b>Тренировка:</b> %s\n"+
 			"📅 <b>Дата и время:</b> %s\n\n"+
 			"💡 <b>Попробуйте записаться на другую тренировку.</b>",
-			trackName, training.StartTime.Format("02.01.2006 15:04"))
+			formatRegistrationParticipant(registration, repo), trackName, timefmt.DateTime(training.StartTime, timefmt.Resolve(user.Timezone, trackTimezone)))
+		if credit != nil {
+			userMessage += fmt.Sprintf("\n\n↩️ Кредит возвращен, доступно: %d.", credit.Balance)
+		}
 
 		telegram.SendMessage(botUrl, user.ChatId, userMessage, telegram.CreateBaseKeyboard())
 	}
 
+	// Освободившееся место предлагаем следующему в листе ожидания
+	promoteWaitlist(botUrl, training.ID, repo)
+
 	logger.UserInfo(chatId, "Регистрация %d отклонена", registrationId)
 	telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Заявка отклонена</b>", telegram.CreateBaseKeyboard())
 	return states.SetStartKeyboard()
 }
 
-func formatTrainingsListForUsers(trainings []database.Training, repo database.ContentRepositoryInterface) string {
+func formatTrainingsListForUsers(trainings []database.Training, chatId int, repo database.ContentRepositoryInterface) string {
 
 	var builder strings.Builder
 	builder.WriteString("📅 <b>Расписание тренировок RVA Academy</b>\n\n")
 
+	categoryName := carCategoryNames(repo)
 	for i, training := range trainings {
 		trainer, _ := repo.GetTrainerByID(training.TrainerID)
 		trainerName := "Неизвестный тренер"
@@ -767,18 +925,18 @@ func formatTrainingsListForUsers(trainings []database.Training, repo database.Co
 		var confirmedUsers []string
 
 		for _, reg := range registrations {
-			if reg.Status == "confirmed" {
+			if reg.Status == database.RegistrationStatusConfirmed {
 				confirmedCount++
-				user, _ := repo.GetUserByID(reg.UserID)
-				userName := "Участник"
-				if user != nil {
-					userName = user.Name
-				}
-				confirmedUsers = append(confirmedUsers, userName)
+				confirmedUsers = append(confirmedUsers, registrationName(&reg, repo))
 			}
 		}
 
-		availableSpots := training.MaxParticipants - confirmedCount
+		capacity, err := repo.GetTrainingCapacity(&training)
+		if err != nil {
+			capacity = training.MaxParticipants
+		}
+
+		availableSpots := capacity - confirmedCount
 		spotsText := fmt.Sprintf("%d мест", availableSpots)
 		if availableSpots <= 0 {
 			spotsText = "❌ Мест нет"
@@ -787,10 +945,10 @@ func formatTrainingsListForUsers(trainings []database.Training, repo database.Co
 		}
 
 		builder.WriteString(fmt.Sprintf("🏃‍♂️ <b>%d. Тренировка</b>\n", i+1))
-		builder.WriteString(fmt.Sprintf("🚗 <b>Категория:</b> %s\n", training.CarCategory))
+		builder.WriteString(fmt.Sprintf("🚗 <b>Категория:</b> %s\n", categoryName(training.CarCategoryID)))
 		builder.WriteString(fmt.Sprintf("👨‍🏫 <b>Тренер:</b> %s\n", trainerName))
 		builder.WriteString(fmt.Sprintf("🏁 <b>Трасса:</b> %s\n", trackName))
-		builder.WriteString(fmt.Sprintf("📅 <b>Дата и время:</b> %s\n", training.StartTime.Format("02.01.2006 15:04")))
+		builder.WriteString(fmt.Sprintf("📅 <b>Дата и время:</b> %s\n", timefmt.DateTime(training.StartTime, repo.GetViewerLocation(chatId, training.TrackID))))
 		builder.WriteString(fmt.Sprintf("👥 <b>Свободно:</b> %s\n", spotsText))
 
 		if len(confirmedUsers) > 0 {
@@ -921,10 +1079,7 @@ func HandleDataConsentYes(botUrl string, chatId int, messageId int, repo databas
 	tempData := state.GetTempUserData()
 	tempData.DataConsent = true
 
-	message := "👤 <b>Введите ваше ФИО</b>\n\n" +
-		"<i>Пример: Иванов Иван Иванович</i>"
-
-	telegram.EditMessage(botUrl, chatId, messageId, message, telegram.CreateCancelKeyboard())
+	promptUserName(botUrl, chatId, messageId)
 
 	newState := states.SetEnterUserName()
 	return newState.SetTempUserData(tempData)
@@ -0,0 +1,225 @@
+package commands
+
+import (
+	"fmt"
+	"time"
+
+	"x.localhost/rvabot/internal/database"
+	"x.localhost/rvabot/internal/logger"
+	"x.localhost/rvabot/internal/states"
+	"x.localhost/rvabot/internal/telegram"
+	"x.localhost/rvabot/internal/timefmt"
+)
+
+// offerWaitlist сообщает, что мест нет, и предлагает встать в лист ожидания.
+// Участник, за которого идет запись, сохраняется в состоянии до нажатия кнопки.
+func offerWaitlist(botUrl string, chatId int, messageId int, trainingId uint, participantId uint) states.State {
+	telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Нет свободных мест</b>\n\n"+
+		"🏃‍♂️ На эту тренировку уже записалось максимальное количество участников.\n"+
+		"📝 Встаньте в лист ожидания — если место освободится, мы предложим его вам.", telegram.CreateWaitlistJoinKeyboard(trainingId))
+	return states.SetConfirmTrainingRegistration(trainingId).WithParticipant(participantId)
+}
+
+// showExistingRegistration показывает текущую запись пользователя на тренировку
+func showExistingRegistration(botUrl string, chatId int, messageId int, registration *database.TrainingRegistration, repo database.ContentRepositoryInterface) states.State {
+	switch registration.Status {
+	case database.RegistrationStatusWaitlisted:
+		position, err := repo.GetWaitlistPosition(registration.ID)
+		if err != nil {
+			return sendErrorMessage(botUrl, chatId, messageId, repo, err)
+		}
+		telegram.EditMessage(botUrl, chatId, messageId, fmt.Sprintf("📝 <b>Вы в листе ожидания</b>\n\n"+
+			"🔢 <b>Ваша позиция:</b> %d\n"+
+			"🔔 Мы сообщим, когда освободится место.", position), telegram.CreateBaseKeyboard())
+	case database.RegistrationStatusOffered:
+		var trackId uint
+		if training, _ := repo.GetTrainingById(registration.TrainingID); training != nil {
+			trackId = training.TrackID
+		}
+		telegram.EditMessage(botUrl, chatId, messageId, "🔔 <b>Вам предложено место</b>\n\n"+
+			"⏰ <b>Подтвердите до:</b> "+formatOfferDeadline(registration, repo.GetViewerLocation(chatId, trackId))+"\n"+
+			"❓ Занять место на тренировке?", telegram.CreateWaitlistOfferKeyboard(registration.ID))
+	default:
+		_, statusText := formatRegistrationStatus(registration.Status)
+		telegram.EditMessage(botUrl, chatId, messageId, "⚠️ <b>Вы уже зарегистрированы</b>\n\n"+
+			"🏃‍♂️ Вы уже записаны на эту тренировку.\n"+
+			"📊 <b>Статус:</b> "+statusText, telegram.CreateBaseKeyboard())
+	}
+	return states.SetStartKeyboard()
+}
+
+// JoinTrainingWaitlist ставит пользователя или выбранного при записи
+// участника в лист ожидания тренировки
+func JoinTrainingWaitlist(botUrl string, chatId int, messageId int, trainingId uint, repo database.ContentRepositoryInterface, state states.State) states.State {
+	user, err := repo.GetUserByChatId(chatId)
+	if err != nil || user == nil {
+		telegram.EditMessage(botUrl, chatId, messageId, "❌ <b>Пользователь не найден</b>\n\n"+
+			"🔍 Сначала зарегистрируйтесь в системе.", telegram.CreateBaseKeyboard())
+		return states.SetStartKeyboard()
+	}
+
+	if checkBookingRestriction(botUrl, chatId, messageId, user, repo) {
+		return states.SetStartKeyboard()
+	}
+
+	registration, err := repo.JoinWaitlist(trainingId, user.ID, registrationParticipantID(state, trainingId))
+	if err != nil {
+		logger.UserError(chatId, "Лист ожидания тренировки %d: %v", trainingId, err)
+		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
+	}
+
+	position, err := repo.GetWaitlistPosition(registration.ID)
+	if err != nil {
+		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
+	}
+
+	logger.UserInfo(chatId, "Лист ожидания: TrainingID=%d, позиция %d", trainingId, position)
+	telegram.EditMessage(botUrl, chatId, messageId, fmt.Sprintf("📝 <b>Вы в листе ожидания</b>\n\n"+
+		"%s"+
+		"🔢 <b>Ваша позиция:</b> %d\n"+
+		"🔔 Когда освободится место, мы пришлем предложение.\n"+
+		"⏰ На подтверждение будет %s.", formatRegistrationParticipant(registration, repo), position, formatDuration(bookingConfig.WaitlistOfferTTL)), telegram.CreateBaseKeyboard())
+
+	// Место могло освободиться, пока пользователь вставал в очередь
+	promoteWaitlist(botUrl, trainingId, repo)
+	return states.SetStartKeyboard()
+}
+
+// AcceptWaitlistOffer подтверждает место, предложенное из листа ожидания
+func AcceptWaitlistOffer(botUrl string, chatId int, messageId int, registrationId uint, repo database.ContentRepositoryInterface) states.State {
+	user, err := repo.GetUserByChatId(chatId)
+	if err != nil || user == nil {
+		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
+	}
+
+	registration, err := repo.AcceptWaitlistOffer(registrationId, user.ID)
+	if err != nil {
+		logger.UserError(chatId, "Принятие предложения %d: %v", registrationId, err)
+		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
+	}
+
+	credit := reserveRegistrationCredit(chatId, user.ID, registration.ID, repo)
+	notifyTrainerAboutRegistration(botUrl, user, registration.TrainingID, registration.ID, repo)
+
+	logger.UserInfo(chatId, "Предложение из листа ожидания принято: ID=%d", registrationId)
+	telegram.EditMessage(botUrl, chatId, messageId, "🎉 <b>Место за вами!</b>\n\n"+
+		formatRegistrationParticipant(registration, repo)+
+		"✅ <b>Заявка отправлена тренеру на рассмотрение.</b>\n\n"+
+		"📱 <b>Вы получите уведомление о решении тренера.</b>"+formatReservedCredit(credit), telegram.CreateBaseKeyboard())
+	return states.SetStartKeyboard()
+}
+
+// DeclineWaitlistOffer отказывается от места и передает его следующему в очереди
+func DeclineWaitlistOffer(botUrl string, chatId int, messageId int, registrationId uint, repo database.ContentRepositoryInterface) states.State {
+	user, err := repo.GetUserByChatId(chatId)
+	if err != nil || user == nil {
+		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
+	}
+
+	registration, err := repo.DeclineWaitlistOffer(registrationId, user.ID)
+	if err != nil {
+		logger.UserError(chatId, "Отказ от предложения %d: %v", registrationId, err)
+		return sendErrorMessage(botUrl, chatId, messageId, repo, err)
+	}
+
+	promoteWaitlist(botUrl, registration.TrainingID, repo)
+
+	logger.UserInfo(chatId, "Предложение из листа ожидания отклонено: ID=%d", registrationId)
+	telegram.EditMessage(botUrl, chatId, messageId, "👌 <b>Вы отказались от места</b>\n\n"+
+		"💡 Место передано следующему в листе ожидания.", telegram.CreateBaseKeyboard())
+	return states.SetStartKeyboard()
+}
+
+// ProcessWaitlistOffers снимает просроченные предложения и передает места дальше по очереди.
+// Запускается планировщиком.
+func ProcessWaitlistOffers(botUrl string, repo database.ContentRepositoryInterface) {
+	expired, err := repo.ExpireWaitlistOffers()
+	if err != nil {
+		logger.BotError("Обработка листа ожидания: %v", err)
+		return
+	}
+
+	trainingIds := make(map[uint]bool)
+	for _, reg := range expired {
+		trainingIds[reg.TrainingID] = true
+
+		user, _ := repo.GetUserByID(reg.UserID)
+		if user == nil || user.ChatId == 0 {
+			continue
+		}
+		telegram.SendMessage(botUrl, user.ChatId, "⌛ <b>Время на подтверждение истекло</b>\n\n"+
+			formatRegistrationParticipant(&reg, repo)+
+			"Предложенное место передано следующему в листе ожидания.", telegram.CreateBaseKeyboard())
+	}
+
+	for trainingId := range trainingIds {
+		promoteWaitlist(botUrl, trainingId, repo)
+	}
+}
+
+// promoteWaitlist предлагает свободные места тренировки следующим в очереди
+func promoteWaitlist(botUrl string, trainingId uint, repo database.ContentRepositoryInterface) {
+	offered, err := repo.PromoteFromWaitlist(trainingId, bookingConfig.WaitlistOfferTTL)
+	if err != nil {
+		logger.BotError("Продвижение листа ожидания тренировки %d: %v", trainingId, err)
+		return
+	}
+
+	for i := range offered {
+		sendWaitlistOffer(botUrl, &offered[i], repo)
+	}
+}
+
+// sendWaitlistOffer уведомляет пользователя о предложенном месте
+func sendWaitlistOffer(botUrl string, registration *database.TrainingRegistration, repo database.ContentRepositoryInterface) {
+	user, _ := repo.GetUserByID(registration.UserID)
+	if user == nil || user.ChatId == 0 {
+		return
+	}
+
+	training, _ := repo.GetTrainingById(registration.TrainingID)
+	if training == nil {
+		return
+	}
+
+	trackName := "Неизвестная трасса"
+	trackTimezone := ""
+	if track, _ := repo.GetTrackByID(training.TrackID); track != nil {
+		trackName = track.Name
+		trackTimezone = track.Timezone
+	}
+
+	loc := timefmt.Resolve(user.Timezone, trackTimezone)
+	message := fmt.Sprintf("🔔 <b>Освободилось место!</b>\n\n"+
+		"%s"+
+		"🏃‍♂️ <b>Тренировка:</b> %s\n"+
+		"🚗 <b>Категория:</b> %s\n"+
+		"📅 <b>Дата и время:</b> %s\n\n"+
+		"⏰ <b>Подтвердите до:</b> %s\n"+
+		"💡 Если не ответить, место перейдет следующему в очереди.",
+		formatRegistrationParticipant(registration, repo), trackName, carCategoryName(training.CarCategoryID, repo), timefmt.DateTime(training.StartTime, loc), formatOfferDeadline(registration, loc))
+
+	telegram.SendMessage(botUrl, user.ChatId, message, telegram.CreateWaitlistOfferKeyboard(registration.ID))
+}
+
+// formatOfferDeadline форматирует срок действия предложения места в поясе loc
+func formatOfferDeadline(registration *database.TrainingRegistration, loc *time.Location) string {
+	if registration.OfferExpiresAt == nil {
+		return "—"
+	}
+	return timefmt.DateTime(*registration.OfferExpiresAt, loc)
+}
+
+// formatDuration выводит длительность в часах и минутах
+func formatDuration(d time.Duration) string {
+	hours := int(d.Hours())
+	minutes := int(d.Minutes()) % 60
+	switch {
+	case hours > 0 && minutes > 0:
+		return fmt.Sprintf("%d ч %d мин", hours, minutes)
+	case hours > 0:
+		return fmt.Sprintf("%d ч", hours)
+	default:
+		return fmt.Sprintf("%d мин", minutes)
+	}
+}
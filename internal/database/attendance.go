@@ -0,0 +1,165 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"x.localhost/rvabot/internal/logger"
+
+	"gorm.io/gorm"
+)
+
+// attendanceRegistrationStatuses - записи, попадающие в список отметки посещаемости
+var attendanceRegistrationStatuses = []string{RegistrationStatusConfirmed, RegistrationStatusAttended, RegistrationStatusNoShow}
+
+// attendanceHistoryLimit ограничивает историю посещений, показываемую администратору
+const attendanceHistoryLimit = 30
+
+// GetTrainingsAwaitingAttendance возвращает завершившиеся тренировки, по которым
+// тренеру еще не отправлен список для отметки посещаемости
+func (r *ContentRepository) GetTrainingsAwaitingAttendance(now time.Time) ([]Training, error) {
+	now = now.UTC()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var trainings []Training
+	result := r.db.WithContext(ctx).
+		Where("is_active = ? AND end_time <= ? AND attendance_requested_at IS NULL", true, now).
+		Order("end_time").
+		Find(&trainings)
+	if result.Error != nil {
+		logger.DatabaseError("Тренировки без отметки посещаемости: %v", result.Error)
+		return nil, result.Error
+	}
+
+	return trainings, nil
+}
+
+// MarkAttendanceRequested отмечает, что список посещаемости по тренировке отправлен
+func (r *ContentRepository) MarkAttendanceRequested(trainingId uint) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result := r.db.WithContext(ctx).Model(&Training{}).
+		Where("id = ?", trainingId).
+		Update("attendance_requested_at", time.Now().UTC())
+	if result.Error != nil {
+		logger.DatabaseError("Отметка запроса посещаемости тренировки %d: %v", trainingId, result.Error)
+		return result.Error
+	}
+
+	return nil
+}
+
+// GetAttendanceRegistrations возвращает подтвержденных участников тренировки
+// вместе с уже отмеченными
+func (r *ContentRepository) GetAttendanceRegistrations(trainingId uint) ([]TrainingRegistration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var registrations []TrainingRegistration
+	result := r.db.WithContext(ctx).
+		Where("training_id = ? AND status IN ?", trainingId, attendanceRegistrationStatuses).
+		Order("created_at").
+		Find(&registrations)
+	if result.Error != nil {
+		logger.DatabaseError("Участники тренировки %d для посещаемости: %v", trainingId, result.Error)
+		return nil, result.Error
+	}
+
+	return registrations, nil
+}
+
+// MarkAttendance отмечает, был ли участник на тренировке. Отметку можно
+// ставить только после начала тренировки и исправлять повторной отметкой.
+func (r *ContentRepository) MarkAttendance(registrationId uint, status string) (*TrainingRegistration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if status != RegistrationStatusAttended && status != RegistrationStatusNoShow {
+		return nil, newAttendanceUnavailableError()
+	}
+
+	var registration TrainingRegistration
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now().UTC()
+
+		result := tx.Model(&TrainingRegistration{}).
+			Where("id = ? AND status IN ?", registrationId, attendanceRegistrationStatuses).
+			Where("EXISTS (SELECT 1 FROM trainings t WHERE t.id = training_registrations.training_id AND t.start_time <= ?)", now).
+			Updates(map[string]interface{}{"status": status, "updated_at": now})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return newAttendanceUnavailableError()
+		}
+
+		return tx.First(&registration, registrationId).Error
+	})
+	if err != nil {
+		logger.DatabaseError("Отметка посещаемости записи %d: %v", registrationId, err)
+		return nil, err
+	}
+
+	logger.DatabaseInfo("Посещаемость записи %d: %s", registrationId, status)
+	return &registration, nil
+}
+
+// GetNoShowRestriction проверяет правило неявок: если за window до now у
+// пользователя набралось limit неявок, запись ограничена до момента, когда
+// самая ранняя из них выйдет за окно. Возвращает nil, если ограничения нет;
+// limit 0 отключает правило.
+func (r *ContentRepository) GetNoShowRestriction(userId uint, limit int, window time.Duration, now time.Time) (*time.Time, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+	now = now.UTC()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var starts []time.Time
+	result := r.db.WithContext(ctx).Model(&TrainingRegistration{}).
+		Joins("INNER JOIN trainings ON trainings.id = training_registrations.training_id").
+		Where("training_registrations.user_id = ? AND training_registrations.status = ?", userId, RegistrationStatusNoShow).
+		Where("trainings.start_time > ?", now.Add(-window)).
+		Order("trainings.start_time DESC").
+		Limit(limit).
+		Pluck("trainings.start_time", &starts)
+	if result.Error != nil {
+		logger.DatabaseError("Неявки пользователя %d: %v", userId, result.Error)
+		return nil, result.Error
+	}
+
+	if len(starts) < limit {
+		return nil, nil
+	}
+
+	until := starts[len(starts)-1].Add(window)
+	return &until, nil
+}
+
+// GetUserAttendanceHistory возвращает записи пользователя на прошедшие
+// тренировки, включая поздние отмены, начиная с последней
+func (r *ContentRepository) GetUserAttendanceHistory(userId uint) ([]TrainingRegistration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var history []TrainingRegistration
+	result := r.db.WithContext(ctx).
+		Select("training_registrations.*").
+		Joins("INNER JOIN trainings ON trainings.id = training_registrations.training_id").
+		Where("training_registrations.user_id = ? AND trainings.start_time <= ?", userId, time.Now().UTC()).
+		Where("training_registrations.status IN ? OR (training_registrations.status = ? AND training_registrations.late_cancellation = ?)",
+			attendanceRegistrationStatuses, RegistrationStatusCancelled, true).
+		Order("trainings.start_time DESC").
+		Limit(attendanceHistoryLimit).
+		Find(&history)
+	if result.Error != nil {
+		logger.DatabaseError("История посещений пользователя %d: %v", userId, result.Error)
+		return nil, result.Error
+	}
+
+	return history, nil
+}
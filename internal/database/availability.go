@@ -0,0 +1,321 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"x.localhost/rvabot/internal/logger"
+
+	"gorm.io/gorm"
+)
+
+// CreateAvailability добавляет окно доступности тренера. Окна одного тренера
+// не пересекаются: на другой трассе в это время он занятие не проведет.
+func (r *ContentRepository) CreateAvailability(window *TrainerAvailability) error {
+	window.StartTime, window.EndTime = window.StartTime.UTC(), window.EndTime.UTC()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var overlapping int64
+		if err := tx.Model(&TrainerAvailability{}).
+			Where("trainer_id = ? AND start_time < ? AND end_time > ?", window.TrainerID, window.EndTime, window.StartTime).
+			Count(&overlapping).Error; err != nil {
+			return err
+		}
+		if overlapping > 0 {
+			return newAvailabilityOverlapError()
+		}
+		return mapConstraintError(tx.Create(window).Error)
+	})
+	if err != nil {
+		logger.DatabaseError("Окно тренера %d на трассе %d: %v", window.TrainerID, window.TrackID, err)
+		return err
+	}
+
+	logger.DatabaseInfo("Окно тренера %d на трассе %d добавлено: ID=%d", window.TrainerID, window.TrackID, window.ID)
+	return nil
+}
+
+// GetTrainerAvailability возвращает еще не закончившиеся окна тренера
+func (r *ContentRepository) GetTrainerAvailability(trainerId uint) ([]TrainerAvailability, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var windows []TrainerAvailability
+	if err := r.db.WithContext(ctx).Where("trainer_id = ? AND end_time > ?", trainerId, time.Now().UTC()).
+		Order("start_time").Find(&windows).Error; err != nil {
+		logger.DatabaseError("Окна тренера %d: %v", trainerId, err)
+		return nil, err
+	}
+
+	return windows, nil
+}
+
+// GetTrackAvailability возвращает еще не закончившиеся окна всех тренеров на трассе
+func (r *ContentRepository) GetTrackAvailability(trackId uint) ([]TrainerAvailability, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var windows []TrainerAvailability
+	if err := r.db.WithContext(ctx).
+		Joins("INNER JOIN trainers ON trainers.id = trainer_availabilities.trainer_id AND trainers.deleted_at IS NULL").
+		Where("trainer_availabilities.track_id = ? AND trainer_availabilities.end_time > ?", trackId, time.Now().UTC()).
+		Order("trainer_availabilities.start_time").Find(&windows).Error; err != nil {
+		logger.DatabaseError("Окна на трассе %d: %v", trackId, err)
+		return nil, err
+	}
+
+	return windows, nil
+}
+
+// GetTracksWithAvailability возвращает трассы, на которых есть будущие окна тренеров
+func (r *ContentRepository) GetTracksWithAvailability() ([]Track, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	db := r.db.WithContext(ctx)
+	windows := db.Model(&TrainerAvailability{}).Select("trainer_availabilities.track_id").
+		Joins("INNER JOIN trainers ON trainers.id = trainer_availabilities.trainer_id AND trainers.deleted_at IS NULL").
+		Where("trainer_availabilities.end_time > ?", time.Now().UTC())
+
+	var tracks []Track
+	if err := db.Session(&gorm.Session{}).Where("id IN (?)", windows).
+		Order("name").Find(&tracks).Error; err != nil {
+		logger.DatabaseError("Трассы с окнами тренеров: %v", err)
+		return nil, err
+	}
+
+	return tracks, nil
+}
+
+// GetAvailabilityByID возвращает окно по ID или nil, если его нет или оно удалено
+func (r *ContentRepository) GetAvailabilityByID(id uint) (*TrainerAvailability, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var window TrainerAvailability
+	if err := r.db.WithContext(ctx).Where("id = ?", id).Limit(1).Find(&window).Error; err != nil {
+		logger.DatabaseError("Окно %d: %v", id, err)
+		return nil, err
+	}
+	if window.ID == 0 {
+		return nil, nil
+	}
+
+	return &window, nil
+}
+
+// DeleteAvailability убирает окно из выбора. Уже отправленные заявки
+// остаются у тренера на рассмотрении.
+func (r *ContentRepository) DeleteAvailability(id uint) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := r.db.WithContext(ctx).Delete(&TrainerAvailability{}, id).Error; err != nil {
+		logger.DatabaseError("Удаление окна %d: %v", id, err)
+		return err
+	}
+
+	logger.DatabaseInfo("Окно удалено: %d", id)
+	return nil
+}
+
+// trainerBusy проверяет, есть ли у тренера активная тренировка, пересекающаяся с интервалом
+func trainerBusy(tx *gorm.DB, trainerId uint, start, end time.Time) (bool, error) {
+	var trainings int64
+	err := tx.Model(&Training{}).
+		Where("trainer_id = ? AND is_active = ? AND start_time < ? AND end_time > ?", trainerId, true, end, start).
+		Count(&trainings).Error
+	return trainings > 0, err
+}
+
+// CreateSessionRequest создает заявку на индивидуальное занятие. Предложенное
+// время должно лежать внутри окна и не пересекаться с тренировками тренера.
+func (r *ContentRepository) CreateSessionRequest(request *SessionRequest) error {
+	request.StartTime, request.EndTime = request.StartTime.UTC(), request.EndTime.UTC()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var window TrainerAvailability
+		if err := tx.Where("id = ?", request.AvailabilityID).Limit(1).Find(&window).Error; err != nil {
+			return err
+		}
+		if window.ID == 0 {
+			return newSessionUnavailableError("тренер закрыл это окно")
+		}
+		if !request.StartTime.Before(request.EndTime) {
+			return newSessionUnavailableError("время окончания должно быть позже начала")
+		}
+		if request.StartTime.Before(window.StartTime) || request.EndTime.After(window.EndTime) {
+			return newSessionUnavailableError("время должно быть внутри окна тренера")
+		}
+		if !request.StartTime.After(time.Now().UTC()) {
+			return newSessionUnavailableError("время уже прошло")
+		}
+
+		busy, err := trainerBusy(tx, window.TrainerID, request.StartTime, request.EndTime)
+		if err != nil {
+			return err
+		}
+		if busy {
+			return newSessionUnavailableError("тренер уже занят в это время")
+		}
+
+		var pending int64
+		if err := tx.Model(&SessionRequest{}).
+			Where("user_id = ? AND availability_id = ? AND status = ?", request.UserID, window.ID, SessionRequestStatusPending).
+			Count(&pending).Error; err != nil {
+			return err
+		}
+		if pending > 0 {
+			return newSessionUnavailableError("ваша заявка в это окно уже ждет ответа тренера")
+		}
+
+		request.TrainerID = window.TrainerID
+		request.TrackID = window.TrackID
+		request.Status = SessionRequestStatusPending
+		return mapConstraintError(tx.Create(request).Error)
+	})
+	if err != nil {
+		logger.DatabaseError("Заявка на занятие в окне %d: %v", request.AvailabilityID, err)
+		return err
+	}
+
+	logger.DatabaseInfo("Заявка на занятие создана: ID=%d, окно %d", request.ID, request.AvailabilityID)
+	return nil
+}
+
+// GetSessionRequestByID возвращает заявку на занятие или nil, если ее нет
+func (r *ContentRepository) GetSessionRequestByID(id uint) (*SessionRequest, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var request SessionRequest
+	if err := r.db.WithContext(ctx).Where("id = ?", id).Limit(1).Find(&request).Error; err != nil {
+		logger.DatabaseError("Заявка на занятие %d: %v", id, err)
+		return nil, err
+	}
+	if request.ID == 0 {
+		return nil, nil
+	}
+
+	return &request, nil
+}
+
+// GetPendingSessionRequests возвращает заявки тренеру, которые еще можно принять
+func (r *ContentRepository) GetPendingSessionRequests(trainerId uint) ([]SessionRequest, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var requests []SessionRequest
+	if err := r.db.WithContext(ctx).
+		Where("trainer_id = ? AND status = ? AND start_time > ?", trainerId, SessionRequestStatusPending, time.Now().UTC()).
+		Order("start_time").Find(&requests).Error; err != nil {
+		logger.DatabaseError("Заявки на занятия тренеру %d: %v", trainerId, err)
+		return nil, err
+	}
+
+	return requests, nil
+}
+
+// GetUserSessionRequests возвращает предстоящие заявки пользователя на занятия
+func (r *ContentRepository) GetUserSessionRequests(userId uint) ([]SessionRequest, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var requests []SessionRequest
+	if err := r.db.WithContext(ctx).Where("user_id = ? AND start_time > ?", userId, time.Now().UTC()).
+		Order("start_time").Find(&requests).Error; err != nil {
+		logger.DatabaseError("Заявки на занятия пользователя %d: %v", userId, err)
+		return nil, err
+	}
+
+	return requests, nil
+}
+
+// AcceptSessionRequest принимает заявку: создает активную тренировку на одного
+// участника и подтвержденную запись пользователя на нее
+func (r *ContentRepository) AcceptSessionRequest(requestId uint) (*SessionRequest, *TrainingRegistration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var request SessionRequest
+	var registration TrainingRegistration
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ?", requestId).Limit(1).Find(&request).Error; err != nil {
+			return err
+		}
+		if request.ID == 0 || request.Status != SessionRequestStatusPending {
+			return newSessionUnavailableError("заявка уже рассмотрена")
+		}
+		if !request.StartTime.After(time.Now().UTC()) {
+			return newSessionUnavailableError("предложенное время уже прошло")
+		}
+
+		busy, err := trainerBusy(tx, request.TrainerID, request.StartTime, request.EndTime)
+		if err != nil {
+			return err
+		}
+		if busy {
+			return newSessionUnavailableError("в это время у вас уже есть тренировка")
+		}
+
+		training := Training{
+			TrainerID:       request.TrainerID,
+			TrackID:         request.TrackID,
+			StartTime:       request.StartTime,
+			EndTime:         request.EndTime,
+			MaxParticipants: 1,
+			IsActive:        true,
+		}
+		if err := tx.Create(&training).Error; err != nil {
+			return mapConstraintError(err)
+		}
+
+		registration = TrainingRegistration{TrainingID: training.ID, UserID: request.UserID, Status: RegistrationStatusConfirmed}
+		if err := tx.Create(&registration).Error; err != nil {
+			return mapConstraintError(err)
+		}
+
+		request.Status = SessionRequestStatusAccepted
+		request.TrainingID = &training.ID
+		return tx.Model(&request).Updates(map[string]interface{}{"status": request.Status, "training_id": training.ID}).Error
+	})
+	if err != nil {
+		logger.DatabaseError("Принятие заявки на занятие %d: %v", requestId, err)
+		return nil, nil, err
+	}
+
+	logger.DatabaseInfo("Заявка на занятие %d принята: тренировка %d", requestId, registration.TrainingID)
+	return &request, &registration, nil
+}
+
+// DeclineSessionRequest отклоняет заявку, которая еще ждет ответа тренера
+func (r *ContentRepository) DeclineSessionRequest(requestId uint) (*SessionRequest, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var request SessionRequest
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ?", requestId).Limit(1).Find(&request).Error; err != nil {
+			return err
+		}
+		if request.ID == 0 || request.Status != SessionRequestStatusPending {
+			return newSessionUnavailableError("заявка уже рассмотрена")
+		}
+
+		request.Status = SessionRequestStatusDeclined
+		return tx.Model(&request).Update("status", request.Status).Error
+	})
+	if err != nil {
+		logger.DatabaseError("Отклонение заявки на занятие %d: %v", requestId, err)
+		return nil, err
+	}
+
+	logger.DatabaseInfo("Заявка на занятие %d отклонена", requestId)
+	return &request, nil
+}
@@ -0,0 +1,193 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"x.localhost/rvabot/internal/logger"
+
+	"gorm.io/gorm"
+)
+
+// CreateCarCategory добавляет категорию машин в справочник. Удаленная
+// категория с тем же названием восстанавливается: тренировки, рейтинги и
+// круги ссылаются на нее по ID и возвращаются вместе с ней.
+func (r *ContentRepository) CreateCarCategory(category *CarCategory) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	category.Name = strings.TrimSpace(category.Name)
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var archived CarCategory
+		if err := tx.Unscoped().Where("name = ? COLLATE NOCASE AND deleted_at IS NOT NULL", category.Name).
+			Order("id DESC").Limit(1).Find(&archived).Error; err != nil {
+			return err
+		}
+		if archived.ID == 0 {
+			return mapConstraintError(tx.Create(category).Error)
+		}
+
+		category.ID = archived.ID
+		return mapConstraintError(tx.Unscoped().Model(&CarCategory{}).Where("id = ?", archived.ID).Updates(map[string]interface{}{
+			"name":          category.Name,
+			"description":   category.Description,
+			"min_age":       category.MinAge,
+			"license_level": category.LicenseLevel,
+			"deleted_at":    nil,
+		}).Error)
+	})
+	if err != nil {
+		logger.DatabaseError("Создание категории %s: %v", category.Name, err)
+		return err
+	}
+
+	logger.DatabaseInfo("Создана категория %s: ID=%d", category.Name, category.ID)
+	return nil
+}
+
+// GetCarCategories возвращает справочник категорий машин по названию
+func (r *ContentRepository) GetCarCategories() ([]CarCategory, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var categories []CarCategory
+	if err := r.db.WithContext(ctx).Order("name COLLATE NOCASE").Find(&categories).Error; err != nil {
+		logger.DatabaseError("Справочник категорий: %v", err)
+		return nil, err
+	}
+
+	return categories, nil
+}
+
+// GetCarCategoryByID возвращает категорию по ID или nil, если ее нет
+func (r *ContentRepository) GetCarCategoryByID(id uint) (*CarCategory, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var category CarCategory
+	if err := r.db.WithContext(ctx).Where("id = ?", id).Limit(1).Find(&category).Error; err != nil {
+		logger.DatabaseError("Категория %d: %v", id, err)
+		return nil, err
+	}
+	if category.ID == 0 {
+		return nil, nil
+	}
+
+	return &category, nil
+}
+
+// GetCarCategoryNames возвращает названия категорий по ID вместе с
+// удаленными: они нужны для отображения истории тренировок и рейтингов
+func (r *ContentRepository) GetCarCategoryNames() (map[uint]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var categories []CarCategory
+	if err := r.db.WithContext(ctx).Unscoped().Select("id", "name").Find(&categories).Error; err != nil {
+		logger.DatabaseError("Названия категорий: %v", err)
+		return nil, err
+	}
+
+	names := make(map[uint]string, len(categories))
+	for _, category := range categories {
+		names[category.ID] = category.Name
+	}
+	return names, nil
+}
+
+// carCategoryName возвращает название категории в рамках переданного
+// подключения или транзакции; CarCategoryNone, если категория не указана
+func carCategoryName(db *gorm.DB, id *uint) string {
+	if id == nil {
+		return CarCategoryNone
+	}
+
+	var category CarCategory
+	if err := db.Unscoped().Where("id = ?", *id).Limit(1).Find(&category).Error; err != nil || category.ID == 0 {
+		return CarCategoryNone
+	}
+	return category.Name
+}
+
+// SameCarCategory проверяет, что ссылки указывают на одну категорию;
+// две ссылки nil означают тренировки без категории
+func SameCarCategory(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// FindCarCategory ищет категорию по названию без учета регистра или возвращает nil
+func (r *ContentRepository) FindCarCategory(name string) (*CarCategory, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var category CarCategory
+	if err := r.db.WithContext(ctx).Where("name = ? COLLATE NOCASE", strings.TrimSpace(name)).Limit(1).Find(&category).Error; err != nil {
+		logger.DatabaseError("Поиск категории %s: %v", name, err)
+		return nil, err
+	}
+	if category.ID == 0 {
+		return nil, nil
+	}
+
+	return &category, nil
+}
+
+// UpdateCarCategory сохраняет название, описание и требования категории.
+// Остальные таблицы ссылаются на категорию по ID, поэтому переименование
+// меняет только строку справочника.
+func (r *ContentRepository) UpdateCarCategory(category *CarCategory) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	category.Name = strings.TrimSpace(category.Name)
+	result := r.db.WithContext(ctx).Model(&CarCategory{}).Where("id = ?", category.ID).Updates(map[string]interface{}{
+		"name":          category.Name,
+		"description":   category.Description,
+		"min_age":       category.MinAge,
+		"license_level": category.LicenseLevel,
+	})
+	if result.Error != nil {
+		logger.DatabaseError("Обновление категории %d: %v", category.ID, result.Error)
+		return mapConstraintError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		logger.DatabaseError("Обновление категории %d: категория не найдена", category.ID)
+		return gorm.ErrRecordNotFound
+	}
+
+	logger.DatabaseInfo("Категория %d обновлена", category.ID)
+	return nil
+}
+
+// SetTrainingCarCategory меняет категорию тренировки; nil - без категории
+func (r *ContentRepository) SetTrainingCarCategory(trainingId uint, carCategoryID *uint) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := r.db.WithContext(ctx).Model(&Training{}).Where("id = ?", trainingId).Update("car_category_id", carCategoryID).Error; err != nil {
+		logger.DatabaseError("Категория тренировки %d: %v", trainingId, err)
+		return err
+	}
+
+	logger.DatabaseInfo("Категория тренировки %d изменена", trainingId)
+	return nil
+}
+
+// DeleteCarCategory убирает категорию из выбора. Тренировки, цены и
+// рейтинги с этим названием сохраняются.
+func (r *ContentRepository) DeleteCarCategory(id uint) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := r.db.WithContext(ctx).Delete(&CarCategory{}, id).Error; err != nil {
+		logger.DatabaseError("Удаление категории %d: %v", id, err)
+		return err
+	}
+
+	logger.DatabaseInfo("Категория удалена: %d", id)
+	return nil
+}
@@ -0,0 +1,158 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"x.localhost/rvabot/internal/logger"
+	"x.localhost/rvabot/internal/timefmt"
+)
+
+// ScheduleConflicts - пересечения нового занятия с расписанием и закрытиями трассы
+type ScheduleConflicts struct {
+	// TrainerTrainings - тренировки того же тренера в это время, блокируют создание
+	TrainerTrainings []Training
+	// Closures - дни закрытия трассы, блокируют создание
+	Closures []TrackClosure
+	// TrackTrainings - тренировки других тренеров на той же трассе, только предупреждение
+	TrackTrainings []Training
+}
+
+// IsBlocking сообщает, что занятие создавать нельзя
+func (c *ScheduleConflicts) IsBlocking() bool {
+	return len(c.TrainerTrainings) > 0 || len(c.Closures) > 0
+}
+
+// IsEmpty сообщает, что пересечений нет
+func (c *ScheduleConflicts) IsEmpty() bool {
+	return !c.IsBlocking() && len(c.TrackTrainings) == 0
+}
+
+// Merge добавляет пересечения other без повторов, например для занятий одной серии
+func (c *ScheduleConflicts) Merge(other *ScheduleConflicts) {
+	c.TrainerTrainings = appendMissingTrainings(c.TrainerTrainings, other.TrainerTrainings)
+	c.TrackTrainings = appendMissingTrainings(c.TrackTrainings, other.TrackTrainings)
+
+	seen := make(map[uint]bool, len(c.Closures))
+	for _, closure := range c.Closures {
+		seen[closure.ID] = true
+	}
+	for _, closure := range other.Closures {
+		if !seen[closure.ID] {
+			seen[closure.ID] = true
+			c.Closures = append(c.Closures, closure)
+		}
+	}
+}
+
+func appendMissingTrainings(list []Training, extra []Training) []Training {
+	seen := make(map[uint]bool, len(list))
+	for _, t := range list {
+		seen[t.ID] = true
+	}
+	for _, t := range extra {
+		if !seen[t.ID] {
+			seen[t.ID] = true
+			list = append(list, t)
+		}
+	}
+	return list
+}
+
+// CheckScheduleConflicts ищет активные тренировки, пересекающиеся с интервалом
+// [start, end) у того же тренера или на той же трассе, и закрытия трассы в эти дни.
+// Тренировки excludeIds не учитываются, например при изменении времени занятия.
+func (r *ContentRepository) CheckScheduleConflicts(trainerId, trackId uint, start, end time.Time, excludeIds ...uint) (*ScheduleConflicts, error) {
+	start, end = start.UTC(), end.UTC()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	overlapping := r.db.WithContext(ctx).
+		Where("is_active = ? AND start_time < ? AND end_time > ?", true, end, start)
+	if len(excludeIds) > 0 {
+		overlapping = overlapping.Where("id NOT IN ?", excludeIds)
+	}
+
+	var trainings []Training
+	if err := overlapping.Where("trainer_id = ? OR track_id = ?", trainerId, trackId).Order("start_time").Find(&trainings).Error; err != nil {
+		logger.DatabaseError("Проверка пересечений расписания: %v", err)
+		return nil, err
+	}
+
+	conflicts := &ScheduleConflicts{}
+	for _, t := range trainings {
+		if t.TrainerID == trainerId {
+			conflicts.TrainerTrainings = append(conflicts.TrainerTrainings, t)
+		} else {
+			conflicts.TrackTrainings = append(conflicts.TrackTrainings, t)
+		}
+	}
+
+	// Дни закрытия - календарные даты в поясе трассы. Занятие, заканчивающееся
+	// ровно в полночь, не задевает следующий день.
+	loc := r.GetTrackLocation(trackId)
+	firstDay := timefmt.CalendarDay(start, loc)
+	lastDay := timefmt.CalendarDay(end.Add(-time.Nanosecond), loc)
+	if err := r.db.WithContext(ctx).
+		Where("track_id = ? AND date >= ? AND date <= ?", trackId, firstDay, lastDay).
+		Order("date").Find(&conflicts.Closures).Error; err != nil {
+		logger.DatabaseError("Проверка закрытий трассы %d: %v", trackId, err)
+		return nil, err
+	}
+
+	return conflicts, nil
+}
+
+func (r *ContentRepository) CreateTrackClosure(closure *TrackClosure) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	closure.Date = timefmt.CalendarDay(closure.Date, time.UTC)
+	if err := r.db.WithContext(ctx).Create(closure).Error; err != nil {
+		logger.DatabaseError("Добавление закрытия трассы %d: %v", closure.TrackID, err)
+		return mapConstraintError(err)
+	}
+
+	logger.DatabaseInfo("Трасса %d закрыта на %s", closure.TrackID, timefmt.CalendarDate(closure.Date))
+	return nil
+}
+
+// GetTrackClosures возвращает предстоящие дни закрытия трассы
+func (r *ContentRepository) GetTrackClosures(trackId uint) ([]TrackClosure, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var closures []TrackClosure
+	if err := r.db.WithContext(ctx).
+		Where("track_id = ? AND date >= ?", trackId, timefmt.Today(r.GetTrackLocation(trackId))).
+		Order("date").Find(&closures).Error; err != nil {
+		logger.DatabaseError("Получение закрытий трассы %d: %v", trackId, err)
+		return nil, err
+	}
+	return closures, nil
+}
+
+func (r *ContentRepository) GetTrackClosureByID(id uint) (*TrackClosure, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var closure TrackClosure
+	if err := r.db.WithContext(ctx).First(&closure, id).Error; err != nil {
+		logger.DatabaseError("Получение закрытия трассы %d: %v", id, err)
+		return nil, err
+	}
+	return &closure, nil
+}
+
+func (r *ContentRepository) DeleteTrackClosure(id uint) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := r.db.WithContext(ctx).Delete(&TrackClosure{}, id).Error; err != nil {
+		logger.DatabaseError("Удаление закрытия трассы %d: %v", id, err)
+		return err
+	}
+
+	logger.DatabaseInfo("Закрытие трассы удалено: %d", id)
+	return nil
+}
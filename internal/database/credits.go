@@ -0,0 +1,347 @@
+package database
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"x.localhost/rvabot/internal/logger"
+
+	"gorm.io/gorm"
+)
+
+// CreditBalance - кредиты пользователя: свободные и удерживаемые за заявками
+type CreditBalance struct {
+	Available int
+	Reserved  int
+}
+
+// UserCreditBalance - пользователь и остаток его кредитов для списка администратора
+type UserCreditBalance struct {
+	UserID  uint
+	Name    string
+	TgId    string
+	Balance int
+}
+
+// latestCreditEntrySQL - последняя запись журнала по каждой заявке
+const latestCreditEntrySQL = "credit_ledgers.id = (SELECT MAX(l.id) FROM credit_ledgers l WHERE l.registration_id = credit_ledgers.registration_id)"
+
+// CreatePackage создает пакет тренировок
+func (r *ContentRepository) CreatePackage(pkg *Package) (uint, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	pkg.IsActive = true
+	if err := r.db.WithContext(ctx).Create(pkg).Error; err != nil {
+		logger.DatabaseError("Создание пакета %s: %v", pkg.Name, err)
+		return 0, err
+	}
+
+	logger.DatabaseInfo("Пакет создан: %s (%d кредитов), ID=%d", pkg.Name, pkg.Credits, pkg.ID)
+	return pkg.ID, nil
+}
+
+// GetActivePackages возвращает пакеты, которые можно продать
+func (r *ContentRepository) GetActivePackages() ([]Package, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var packages []Package
+	if err := r.db.WithContext(ctx).Where("is_active = ?", true).Order("credits, name").Find(&packages).Error; err != nil {
+		logger.DatabaseError("Пакеты: %v", err)
+		return nil, err
+	}
+
+	return packages, nil
+}
+
+// GetPackageByID возвращает пакет по ID, включая архивные
+func (r *ContentRepository) GetPackageByID(id uint) (*Package, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var pkg Package
+	result := r.db.WithContext(ctx).Where("id = ?", id).Limit(1).Find(&pkg)
+	if result.Error != nil {
+		logger.DatabaseError("Пакет %d: %v", id, result.Error)
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return &pkg, nil
+}
+
+// ArchivePackage снимает пакет с продажи; проданные кредиты остаются у пользователей
+func (r *ContentRepository) ArchivePackage(id uint) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := r.db.WithContext(ctx).Model(&Package{}).Where("id = ?", id).Update("is_active", false).Error; err != nil {
+		logger.DatabaseError("Архивирование пакета %d: %v", id, err)
+		return err
+	}
+
+	logger.DatabaseInfo("Пакет архивирован: %d", id)
+	return nil
+}
+
+// SellPackage начисляет пользователю кредиты проданного пакета
+func (r *ContentRepository) SellPackage(userId, packageId uint, adminChatId int) (*CreditLedger, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var entry *CreditLedger
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var pkg Package
+		if err := tx.Where("id = ? AND is_active = ?", packageId, true).Limit(1).Find(&pkg).Error; err != nil {
+			return err
+		}
+		if pkg.ID == 0 {
+			return newPackageUnavailableError()
+		}
+
+		var err error
+		entry, err = appendCreditEntry(tx, &CreditLedger{
+			UserID:      userId,
+			Kind:        CreditKindPurchase,
+			Delta:       pkg.Credits,
+			PackageID:   &pkg.ID,
+			AdminChatId: adminChatId,
+			Note:        pkg.Name,
+		})
+		return err
+	})
+	if err != nil {
+		logger.DatabaseError("Продажа пакета %d пользователю %d: %v", packageId, userId, err)
+		return nil, err
+	}
+
+	logger.DatabaseInfo("Пакет %d продан пользователю %d, баланс %d", packageId, userId, entry.Balance)
+	return entry, nil
+}
+
+// GrantCredits начисляет пользователю кредиты без пакета, например в качестве компенсации
+func (r *ContentRepository) GrantCredits(userId uint, credits int, adminChatId int, note string) (*CreditLedger, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var entry *CreditLedger
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		entry, err = appendCreditEntry(tx, &CreditLedger{
+			UserID:      userId,
+			Kind:        CreditKindGrant,
+			Delta:       credits,
+			AdminChatId: adminChatId,
+			Note:        note,
+		})
+		return err
+	})
+	if err != nil {
+		logger.DatabaseError("Начисление %d кредитов пользователю %d: %v", credits, userId, err)
+		return nil, err
+	}
+
+	logger.DatabaseInfo("Начислено %d кредитов пользователю %d, баланс %d", credits, userId, entry.Balance)
+	return entry, nil
+}
+
+// ReserveCredit удерживает кредит за заявкой на тренировку.
+// Без свободных кредитов возвращает nil: записаться можно и с оплатой на месте.
+func (r *ContentRepository) ReserveCredit(userId, registrationId uint) (*CreditLedger, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var entry *CreditLedger
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		last, err := lastCreditEntry(tx, registrationId)
+		if err != nil || last != nil && last.Kind == CreditKindReserve {
+			return err
+		}
+
+		balance, err := currentCreditBalance(tx, userId)
+		if err != nil || balance <= 0 {
+			return err
+		}
+
+		entry, err = appendCreditEntry(tx, &CreditLedger{
+			UserID:         userId,
+			Kind:           CreditKindReserve,
+			Delta:          -1,
+			RegistrationID: &registrationId,
+		})
+		return err
+	})
+	if err != nil {
+		logger.DatabaseError("Резерв кредита за запись %d: %v", registrationId, err)
+		return nil, err
+	}
+
+	if entry != nil {
+		logger.DatabaseInfo("Кредит зарезервирован за записью %d, баланс %d", registrationId, entry.Balance)
+	}
+	return entry, nil
+}
+
+// ConsumeCredit окончательно списывает удержанный за заявкой кредит.
+// Если кредит не удерживался, возвращает nil.
+func (r *ContentRepository) ConsumeCredit(registrationId uint, note string) (*CreditLedger, error) {
+	return r.settleCredit(registrationId, CreditKindConsume, 0, note, CreditKindReserve)
+}
+
+// RefundCredit возвращает пользователю кредит заявки: удержанный или уже
+// списанный при одобрении. Если кредит за заявку не брался, возвращает nil.
+func (r *ContentRepository) RefundCredit(registrationId uint, note string) (*CreditLedger, error) {
+	return r.settleCredit(registrationId, CreditKindRefund, 1, note, CreditKindReserve, CreditKindConsume)
+}
+
+// ForfeitCredit списывает кредит заявки без возврата при поздней отмене.
+// Если кредит за заявку не брался, возвращает nil.
+func (r *ContentRepository) ForfeitCredit(registrationId uint, note string) (*CreditLedger, error) {
+	return r.settleCredit(registrationId, CreditKindForfeit, 0, note, CreditKindReserve, CreditKindConsume)
+}
+
+// settleCredit дописывает операцию kind с изменением баланса delta, если
+// последняя операция по заявке - одна из from
+func (r *ContentRepository) settleCredit(registrationId uint, kind string, delta int, note string, from ...string) (*CreditLedger, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var entry *CreditLedger
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		last, err := lastCreditEntry(tx, registrationId)
+		if err != nil || last == nil || !slices.Contains(from, last.Kind) {
+			return err
+		}
+
+		entry, err = appendCreditEntry(tx, &CreditLedger{
+			UserID:         last.UserID,
+			Kind:           kind,
+			Delta:          delta,
+			RegistrationID: &registrationId,
+			Note:           note,
+		})
+		return err
+	})
+	if err != nil {
+		logger.DatabaseError("Кредит записи %d (%s): %v", registrationId, kind, err)
+		return nil, err
+	}
+
+	if entry != nil {
+		logger.DatabaseInfo("Кредит записи %d: %s, баланс %d", registrationId, kind, entry.Balance)
+	}
+	return entry, nil
+}
+
+// GetCreditBalance возвращает свободные и удерживаемые кредиты пользователя
+func (r *ContentRepository) GetCreditBalance(userId uint) (*CreditBalance, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	db := r.db.WithContext(ctx)
+	available, err := currentCreditBalance(db, userId)
+	if err != nil {
+		logger.DatabaseError("Баланс кредитов пользователя %d: %v", userId, err)
+		return nil, err
+	}
+
+	var reserved int64
+	if err := db.Model(&CreditLedger{}).
+		Where("user_id = ? AND kind = ? AND "+latestCreditEntrySQL, userId, CreditKindReserve).
+		Count(&reserved).Error; err != nil {
+		logger.DatabaseError("Резерв кредитов пользователя %d: %v", userId, err)
+		return nil, err
+	}
+
+	return &CreditBalance{Available: available, Reserved: int(reserved)}, nil
+}
+
+// GetCreditLedger возвращает последние операции с кредитами пользователя, начиная с новых
+func (r *ContentRepository) GetCreditLedger(userId uint, limit int) ([]CreditLedger, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var entries []CreditLedger
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userId).Order("id DESC").Limit(limit).Find(&entries).Error; err != nil {
+		logger.DatabaseError("Журнал кредитов пользователя %d: %v", userId, err)
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// GetRegistrationCredit возвращает последнюю операцию с кредитом заявки или nil
+func (r *ContentRepository) GetRegistrationCredit(registrationId uint) (*CreditLedger, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var entry CreditLedger
+	result := r.db.WithContext(ctx).Where("registration_id = ?", registrationId).Order("id DESC").Limit(1).Find(&entry)
+	if result.Error != nil {
+		logger.DatabaseError("Кредит записи %d: %v", registrationId, result.Error)
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return &entry, nil
+}
+
+// GetUserCreditBalances возвращает всех пользователей с остатком кредитов, по имени
+func (r *ContentRepository) GetUserCreditBalances() ([]UserCreditBalance, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var balances []UserCreditBalance
+	if err := r.db.WithContext(ctx).Model(&User{}).
+		Select("users.id AS user_id, users.name, users.tg_id, COALESCE(credit_ledgers.balance, 0) AS balance").
+		Joins("LEFT JOIN credit_ledgers ON credit_ledgers.id = (SELECT MAX(l.id) FROM credit_ledgers l WHERE l.user_id = users.id)").
+		Order("users.name").
+		Scan(&balances).Error; err != nil {
+		logger.DatabaseError("Балансы кредитов: %v", err)
+		return nil, err
+	}
+
+	return balances, nil
+}
+
+// currentCreditBalance - остаток после последней операции пользователя
+func currentCreditBalance(tx *gorm.DB, userId uint) (int, error) {
+	var last CreditLedger
+	if err := tx.Where("user_id = ?", userId).Order("id DESC").Limit(1).Find(&last).Error; err != nil {
+		return 0, err
+	}
+	return last.Balance, nil
+}
+
+// lastCreditEntry возвращает последнюю операцию по заявке или nil
+func lastCreditEntry(tx *gorm.DB, registrationId uint) (*CreditLedger, error) {
+	var last CreditLedger
+	result := tx.Where("registration_id = ?", registrationId).Order("id DESC").Limit(1).Find(&last)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &last, nil
+}
+
+// appendCreditEntry дописывает операцию в журнал, вычисляя остаток после нее
+func appendCreditEntry(tx *gorm.DB, entry *CreditLedger) (*CreditLedger, error) {
+	balance, err := currentCreditBalance(tx, entry.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	entry.Balance = balance + entry.Delta
+	if err := tx.Create(entry).Error; err != nil {
+		return nil, mapConstraintError(err)
+	}
+	return entry, nil
+}
@@ -3,11 +3,14 @@ package database
 import (
 	"context"
 	"fmt"
+	"reflect"
+	"strings"
 	"time"
 
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	_ "modernc.org/sqlite"
+	"x.localhost/rvabot/internal/database/migrations"
 )
 
 // Database представляет подключение к базе данных
@@ -15,22 +18,46 @@ type Database struct {
 	db *gorm.DB
 }
 
-// NewDatabase создает новое подключение к SQLite базе данных
+// NewDatabase создает новое подключение к SQLite базе данных и применяет миграции
 func NewDatabase(dsn string) (*Database, error) {
+	database, err := Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	// Выполняем миграции
+	if err := database.Migrate(); err != nil {
+		database.Close()
+		return nil, fmt.Errorf("ошибка миграции: %w", err)
+	}
+
+	return database, nil
+}
+
+// Open создает подключение к SQLite базе данных без применения миграций
+func Open(dsn string) (*Database, error) {
 	config := &gorm.Config{
 		// Отключаем логирование для продакшена (можно включить для отладки)
 		// Logger: logger.Default.LogMode(logger.Silent),
+
+		// Время хранится в UTC и сравнивается в SQLite как строка вместе со
+		// смещением, поэтому created_at/updated_at пишем в UTC
+		NowFunc: func() time.Time { return time.Now().UTC() },
 	}
 
 	// Настраиваем GORM для использования modernc.org/sqlite
 	db, err := gorm.Open(sqlite.Dialector{
 		DriverName: "sqlite",
-		DSN:        dsn,
+		DSN:        withPragmas(dsn),
 	}, config)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка подключения к БД: %w", err)
 	}
 
+	if err := registerUTCCallbacks(db); err != nil {
+		return nil, fmt.Errorf("ошибка регистрации callback: %w", err)
+	}
+
 	sqlDB, err := db.DB()
 	if err != nil {
 		return nil, fmt.Errorf("ошибка получения DB: %w", err)
@@ -47,40 +74,90 @@ func NewDatabase(dsn string) (*Database, error) {
 		return nil, fmt.Errorf("ошибка тестирования соединения: %w", err)
 	}
 
-	database := &Database{db: db}
+	return &Database{db: db}, nil
+}
 
-	// Выполняем миграции
-	if err := database.migrate(); err != nil {
-		return nil, fmt.Errorf("ошибка миграции: %w", err)
+// withPragmas включает проверку внешних ключей SQLite и ожидание блокировки
+// записи для каждого соединения пула
+func withPragmas(dsn string) string {
+	if strings.Contains(dsn, "_pragma=") {
+		return dsn
 	}
 
-	return database, nil
+	separator := "?"
+	if strings.Contains(dsn, "?") {
+		separator = "&"
+	}
+	return dsn + separator + "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"
 }
 
-// migrate выполняет миграции базы данных
-func (d *Database) migrate() error {
-	models := []interface{}{
-		&Track{},
-		&Trainer{},
-		&Admin{},
-		&Training{},
-		&User{},
-		&TrainingRegistration{},
-		&TrainingRequest{},
+// registerUTCCallbacks переводит в UTC поля времени моделей перед записью:
+// время, введенное в поясе трассы или академии, хранится с тем же смещением,
+// что и остальные значения, и строковое сравнение в SQLite остается верным
+func registerUTCCallbacks(db *gorm.DB) error {
+	if err := db.Callback().Create().Before("gorm:create").Register("rvabot:utc_times", utcTimesCallback); err != nil {
+		return err
 	}
+	return db.Callback().Update().Before("gorm:update").Register("rvabot:utc_times", utcTimesCallback)
+}
 
-	for _, model := range models {
-		if err := d.db.AutoMigrate(model); err != nil {
-			return fmt.Errorf("ошибка миграции %T: %w", model, err)
-		}
+// utcTimesCallback приводит к UTC время в модели и в значениях Updates
+func utcTimesCallback(db *gorm.DB) {
+	if db.Statement.Model != nil {
+		toUTC(reflect.ValueOf(db.Statement.Model))
+	}
+	if db.Statement.Dest != nil {
+		toUTC(reflect.ValueOf(db.Statement.Dest))
 	}
+}
 
-	// Backfill CarCategory for existing trainings to 'N/A'
-	if err := d.db.Model(&Training{}).Where("car_category IS NULL OR car_category = ''").Update("car_category", "N/A").Error; err != nil {
-		return fmt.Errorf("ошибка бэкфилла car_category: %w", err)
+// toUTC рекурсивно переводит в UTC значения time.Time в структурах, срезах и
+// map[string]interface{}; неадресуемые значения пропускаются
+func toUTC(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			toUTC(v.Elem())
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			toUTC(v.Index(i))
+		}
+	case reflect.Map:
+		for _, key := range v.MapKeys() {
+			switch value := v.MapIndex(key).Interface().(type) {
+			case time.Time:
+				v.SetMapIndex(key, reflect.ValueOf(value.UTC()))
+			case *time.Time:
+				if value != nil {
+					utc := value.UTC()
+					v.SetMapIndex(key, reflect.ValueOf(&utc))
+				}
+			}
+		}
+	case reflect.Struct:
+		if t, ok := v.Interface().(time.Time); ok {
+			if v.CanSet() {
+				v.Set(reflect.ValueOf(t.UTC()))
+			}
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				toUTC(v.Field(i))
+			}
+		}
 	}
+}
+
+// Migrate применяет все непримененные миграции схемы
+func (d *Database) Migrate() error {
+	return d.Migrations().Up()
+}
 
-	return nil
+// Migrations возвращает раннер версионированных миграций
+func (d *Database) Migrations() *migrations.Runner {
+	return migrations.NewRunner(d.db)
 }
 
 // GetDB возвращает экземпляр GORM DB
@@ -0,0 +1,146 @@
+package database
+
+import (
+	"strings"
+
+	apperrors "x.localhost/rvabot/internal/errors"
+)
+
+// Коды пользовательских ошибок записи на тренировку
+const (
+	ErrCodeAlreadyRegistered   = "already_registered"
+	ErrCodeTrainingFull        = "training_full"
+	ErrCodeTrainingUnavailable = "training_unavailable"
+	ErrCodeReferenceMissing    = "reference_missing"
+	ErrCodeOfferExpired        = "offer_expired"
+	ErrCodeBookingNotFound     = "booking_not_found"
+	ErrCodeAttendanceClosed    = "attendance_closed"
+	ErrCodeSurveyNotFound      = "survey_not_found"
+	ErrCodePackageUnavailable  = "package_unavailable"
+	ErrCodePaymentUnavailable  = "payment_unavailable"
+	ErrCodePromoCodeInvalid    = "promo_code_invalid"
+	ErrCodeKartUnavailable     = "kart_unavailable"
+	ErrCodeRentalUnavailable   = "rental_unavailable"
+	ErrCodeAvailabilityOverlap = "availability_overlap"
+	ErrCodeSessionUnavailable  = "session_unavailable"
+	ErrCodeSkillLevelMismatch  = "skill_level_mismatch"
+	ErrCodePromotionRejected   = "promotion_rejected"
+	ErrCodeParticipantInvalid  = "participant_invalid"
+)
+
+// newAlreadyRegisteredError - повторная запись того же пользователя
+func newAlreadyRegisteredError(inner error) *apperrors.AppError {
+	err := apperrors.NewUserError("Вы уже записаны на эту тренировку").WithCode(ErrCodeAlreadyRegistered)
+	err.Inner = inner
+	return err
+}
+
+// newTrainingFullError - на тренировке не осталось мест
+func newTrainingFullError() *apperrors.AppError {
+	return apperrors.NewUserError("На эту тренировку уже нет свободных мест").WithCode(ErrCodeTrainingFull)
+}
+
+// newTrainingUnavailableError - тренировка отменена, удалена или уже началась
+func newTrainingUnavailableError() *apperrors.AppError {
+	return apperrors.NewUserError("Тренировка больше недоступна для записи").WithCode(ErrCodeTrainingUnavailable)
+}
+
+// newOfferExpiredError - предложение места из листа ожидания истекло или уже обработано
+func newOfferExpiredError() *apperrors.AppError {
+	return apperrors.NewUserError("Предложение места истекло или уже недоступно").WithCode(ErrCodeOfferExpired)
+}
+
+// newBookingNotFoundError - запись не найдена, принадлежит другому пользователю или уже отменена
+func newBookingNotFoundError() *apperrors.AppError {
+	return apperrors.NewUserError("Запись не найдена или уже отменена").WithCode(ErrCodeBookingNotFound)
+}
+
+// newAttendanceUnavailableError - тренировка еще не началась или участник не был подтвержден
+func newAttendanceUnavailableError() *apperrors.AppError {
+	return apperrors.NewUserError("Отметить посещение можно только у подтвержденных участников после начала тренировки").WithCode(ErrCodeAttendanceClosed)
+}
+
+// newSurveyNotFoundError - опрос не найден или отправлен другому пользователю
+func newSurveyNotFoundError() *apperrors.AppError {
+	return apperrors.NewUserError("Опрос не найден").WithCode(ErrCodeSurveyNotFound)
+}
+
+// newPackageUnavailableError - пакет не найден или перенесен в архив
+func newPackageUnavailableError() *apperrors.AppError {
+	return apperrors.NewUserError("Пакет не найден или больше не продается").WithCode(ErrCodePackageUnavailable)
+}
+
+// newPaymentUnavailableError - запись отменена, отклонена или уже в нужном статусе оплаты
+func newPaymentUnavailableError() *apperrors.AppError {
+	return apperrors.NewUserError("Оплату этой записи изменить нельзя").WithCode(ErrCodePaymentUnavailable)
+}
+
+// newPromoCodeInvalidError - промокод не найден, не действует или не подходит к тренировке
+func newPromoCodeInvalidError(reason string) *apperrors.AppError {
+	return apperrors.NewUserError("Промокод не применен: " + reason).WithCode(ErrCodePromoCodeInvalid)
+}
+
+// newKartUnavailableError - карт нельзя назначить участнику
+func newKartUnavailableError(reason string) *apperrors.AppError {
+	return apperrors.NewUserError("Карт не назначен: " + reason).WithCode(ErrCodeKartUnavailable)
+}
+
+// newRentalUnavailableError - экипировку нельзя заказать к записи
+func newRentalUnavailableError(reason string) *apperrors.AppError {
+	return apperrors.NewUserError("Экипировка не заказана: " + reason).WithCode(ErrCodeRentalUnavailable)
+}
+
+// newAvailabilityOverlapError - окно пересекается с другим окном того же тренера
+func newAvailabilityOverlapError() *apperrors.AppError {
+	return apperrors.NewUserError("Окно пересекается с другим вашим окном").WithCode(ErrCodeAvailabilityOverlap)
+}
+
+// newSessionUnavailableError - индивидуальное занятие нельзя запросить или назначить
+func newSessionUnavailableError(reason string) *apperrors.AppError {
+	return apperrors.NewUserError("Индивидуальное занятие недоступно: " + reason).WithCode(ErrCodeSessionUnavailable)
+}
+
+// newSkillLevelMismatchError - уровень пользователя не подходит под требования тренировки
+func newSkillLevelMismatchError() *apperrors.AppError {
+	return apperrors.NewUserError("Тренировка не подходит для вашего уровня подготовки").WithCode(ErrCodeSkillLevelMismatch)
+}
+
+// newPromotionRejectedError - уровень участника нельзя повысить по этой записи
+func newPromotionRejectedError(reason string) *apperrors.AppError {
+	return apperrors.NewUserError("Уровень не повышен: " + reason).WithCode(ErrCodePromotionRejected)
+}
+
+// newParticipantInvalidError - профиль участника не найден, удален или занят записями
+func newParticipantInvalidError(reason string) *apperrors.AppError {
+	return apperrors.NewUserError("Участник недоступен: " + reason).WithCode(ErrCodeParticipantInvalid)
+}
+
+// mapConstraintError переводит нарушения ограничений SQLite в понятные ошибки.
+// Остальные ошибки возвращаются без изменений.
+func mapConstraintError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	message := err.Error()
+	switch {
+	case strings.Contains(message, "UNIQUE constraint failed: training_registrations"):
+		return newAlreadyRegisteredError(err)
+	case strings.Contains(message, "UNIQUE constraint failed"):
+		appErr := apperrors.NewUserError("Такая запись уже существует")
+		appErr.Inner = err
+		return appErr
+	case strings.Contains(message, "FOREIGN KEY constraint failed"):
+		appErr := apperrors.NewUserError("Связанная запись не найдена или была удалена").WithCode(ErrCodeReferenceMissing)
+		appErr.Inner = err
+		return appErr
+	}
+
+	return err
+}
+
+// HasErrorCode проверяет код пользовательской ошибки репозитория
+func HasErrorCode(err error, code string) bool {
+	appErr, ok := err.(*apperrors.AppError)
+	return ok && appErr.Code == code
+}
@@ -0,0 +1,126 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"x.localhost/rvabot/internal/logger"
+
+	"gorm.io/gorm"
+)
+
+// SaveTrainingFeedback сохраняет разбор тренировки для записи участника.
+// Повторный разбор той же записи заменяет прежний и снова ждет отправки.
+func (r *ContentRepository) SaveTrainingFeedback(feedback *TrainingFeedback) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var participants int64
+		if err := tx.Model(&TrainingRegistration{}).
+			Joins("INNER JOIN trainings ON trainings.id = training_registrations.training_id").
+			Where("training_registrations.id = ? AND training_registrations.status IN ? AND trainings.start_time <= ?",
+				feedback.RegistrationID, []string{RegistrationStatusConfirmed, RegistrationStatusAttended}, time.Now().UTC()).
+			Count(&participants).Error; err != nil {
+			return err
+		}
+		if participants == 0 {
+			return newAttendanceUnavailableError().WithUserMessage("Разбор можно написать только участнику тренировки после ее начала")
+		}
+
+		var existing TrainingFeedback
+		if err := tx.Where("registration_id = ?", feedback.RegistrationID).Limit(1).Find(&existing).Error; err != nil {
+			return err
+		}
+		if existing.ID == 0 {
+			feedback.DeliveredAt = nil
+			return mapConstraintError(tx.Create(feedback).Error)
+		}
+
+		feedback.ID = existing.ID
+		feedback.CreatedAt = existing.CreatedAt
+		return tx.Model(&existing).Updates(map[string]interface{}{
+			"strengths":    feedback.Strengths,
+			"improvements": feedback.Improvements,
+			"video_url":    feedback.VideoURL,
+			"delivered_at": nil,
+		}).Error
+	})
+	if err != nil {
+		logger.DatabaseError("Разбор записи %d: %v", feedback.RegistrationID, err)
+		return err
+	}
+
+	logger.DatabaseInfo("Разбор сохранен: ID=%d, RegistrationID=%d", feedback.ID, feedback.RegistrationID)
+	return nil
+}
+
+// MarkFeedbackDelivered отмечает, что разбор отправлен участнику
+func (r *ContentRepository) MarkFeedbackDelivered(id uint) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result := r.db.WithContext(ctx).Model(&TrainingFeedback{}).Where("id = ?", id).Update("delivered_at", time.Now().UTC())
+	if result.Error != nil {
+		logger.DatabaseError("Отметка отправки разбора %d: %v", id, result.Error)
+		return result.Error
+	}
+
+	return nil
+}
+
+// GetTrainingFeedbackByID возвращает разбор по ID
+func (r *ContentRepository) GetTrainingFeedbackByID(id uint) (*TrainingFeedback, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var feedback TrainingFeedback
+	result := r.db.WithContext(ctx).Where("id = ?", id).Limit(1).Find(&feedback)
+	if result.Error != nil {
+		logger.DatabaseError("Разбор %d: %v", id, result.Error)
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return &feedback, nil
+}
+
+// GetFeedbackByRegistration возвращает разбор записи участника; nil, если его нет
+func (r *ContentRepository) GetFeedbackByRegistration(registrationId uint) (*TrainingFeedback, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var feedback TrainingFeedback
+	result := r.db.WithContext(ctx).Where("registration_id = ?", registrationId).Limit(1).Find(&feedback)
+	if result.Error != nil {
+		logger.DatabaseError("Разбор записи %d: %v", registrationId, result.Error)
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return &feedback, nil
+}
+
+// GetUserFeedback возвращает разборы пользователя, начиная с последней тренировки
+func (r *ContentRepository) GetUserFeedback(userId uint) ([]TrainingFeedback, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var feedback []TrainingFeedback
+	result := r.db.WithContext(ctx).
+		Joins("INNER JOIN training_registrations ON training_registrations.id = training_feedbacks.registration_id").
+		Joins("INNER JOIN trainings ON trainings.id = training_registrations.training_id").
+		Where("training_registrations.user_id = ?", userId).
+		Order("trainings.start_time DESC").
+		Find(&feedback)
+	if result.Error != nil {
+		logger.DatabaseError("Разборы пользователя %d: %v", userId, result.Error)
+		return nil, result.Error
+	}
+
+	return feedback, nil
+}
@@ -0,0 +1,277 @@
+package database
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"x.localhost/rvabot/internal/logger"
+
+	"gorm.io/gorm"
+)
+
+// kartAssignedStatuses - записи, за которыми закреплен назначенный карт.
+// По ним же считается наработка карта.
+var kartAssignedStatuses = []string{RegistrationStatusConfirmed, RegistrationStatusAttended}
+
+// fleetKartsSQL - число картов трассы и категории тренировки t
+const fleetKartsSQL = "SELECT COUNT(*) FROM karts k WHERE k.track_id = t.track_id AND k.car_category_id = t.car_category_id AND k.deleted_at IS NULL"
+
+// trainingCapacitySQL - число мест на тренировке t: ее лимит, но не больше
+// исправных картов, если для трассы и категории заведен парк
+const trainingCapacitySQL = "(CASE WHEN (" + fleetKartsSQL + ") = 0 THEN t.max_participants " +
+	"ELSE MIN(t.max_participants, (" + fleetKartsSQL + " AND k.status = '" + KartStatusOK + "')) END)"
+
+// kartBusySQL - карт уже назначен другому участнику на пересекающейся по времени тренировке.
+// Параметры: registrationId, kartAssignedStatuses, end, start.
+const kartBusySQL = "EXISTS (SELECT 1 FROM training_registrations r INNER JOIN trainings t ON t.id = r.training_id " +
+	"WHERE r.kart_id = karts.id AND r.id <> ? AND r.status IN ? AND t.deleted_at IS NULL AND t.start_time < ? AND t.end_time > ?)"
+
+// KartUsage - наработка карта по завершенным тренировкам, на которых он был назначен
+type KartUsage struct {
+	Total        time.Duration
+	SinceService time.Duration
+}
+
+// CanAssignKart проверяет, можно ли назначить карт записи с таким статусом
+func CanAssignKart(status string) bool {
+	return slices.Contains(kartAssignedStatuses, status)
+}
+
+// CreateKart добавляет карт в парк трассы
+func (r *ContentRepository) CreateKart(kart *Kart) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	kart.Status = KartStatusOK
+	if err := r.db.WithContext(ctx).Create(kart).Error; err != nil {
+		logger.DatabaseError("Добавление карта %s на трассу %d: %v", kart.Number, kart.TrackID, err)
+		return mapConstraintError(err)
+	}
+
+	logger.DatabaseInfo("Добавлен карт %s на трассу %d: ID=%d", kart.Number, kart.TrackID, kart.ID)
+	return nil
+}
+
+// GetTrackKarts возвращает парк картов трассы по категориям
+func (r *ContentRepository) GetTrackKarts(trackId uint) ([]Kart, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var karts []Kart
+	if err := r.db.WithContext(ctx).
+		Joins("LEFT JOIN car_categories ON car_categories.id = karts.car_category_id").
+		Where("karts.track_id = ?", trackId).
+		Order("car_categories.name COLLATE NOCASE, karts.car_category_id, LENGTH(karts.number), karts.number").
+		Find(&karts).Error; err != nil {
+		logger.DatabaseError("Парк картов трассы %d: %v", trackId, err)
+		return nil, err
+	}
+
+	return karts, nil
+}
+
+// GetKartByID возвращает карт по ID или nil, если его нет
+func (r *ContentRepository) GetKartByID(id uint) (*Kart, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var kart Kart
+	if err := r.db.WithContext(ctx).Where("id = ?", id).Limit(1).Find(&kart).Error; err != nil {
+		logger.DatabaseError("Карт %d: %v", id, err)
+		return nil, err
+	}
+	if kart.ID == 0 {
+		return nil, nil
+	}
+
+	return &kart, nil
+}
+
+// SetKartStatus переводит карт в ремонт или возвращает в строй.
+// Возврат в строй считается обслуживанием и обнуляет наработку до ТО.
+func (r *ContentRepository) SetKartStatus(id uint, status string) (*Kart, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	updates := map[string]interface{}{"status": status}
+	if status == KartStatusOK {
+		updates["serviced_at"] = time.Now().UTC()
+	}
+
+	if err := r.db.WithContext(ctx).Model(&Kart{}).Where("id = ? AND status <> ?", id, status).Updates(updates).Error; err != nil {
+		logger.DatabaseError("Статус карта %d: %v", id, err)
+		return nil, err
+	}
+
+	logger.DatabaseInfo("Карт %d: %s", id, status)
+	return r.GetKartByID(id)
+}
+
+// DeleteKart списывает карт. Назначения на прошедших тренировках сохраняются.
+func (r *ContentRepository) DeleteKart(id uint) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := r.db.WithContext(ctx).Delete(&Kart{}, id).Error; err != nil {
+		logger.DatabaseError("Списание карта %d: %v", id, err)
+		return err
+	}
+
+	logger.DatabaseInfo("Карт списан: %d", id)
+	return nil
+}
+
+// GetKartUsage возвращает наработку картов трассы по ID карта
+func (r *ContentRepository) GetKartUsage(trackId uint) (map[uint]KartUsage, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var rows []struct {
+		KartID     uint
+		StartTime  time.Time
+		EndTime    time.Time
+		ServicedAt *time.Time
+	}
+	if err := r.db.WithContext(ctx).Model(&TrainingRegistration{}).
+		Select("training_registrations.kart_id, trainings.start_time, trainings.end_time, karts.serviced_at").
+		Joins("INNER JOIN trainings ON trainings.id = training_registrations.training_id").
+		Joins("INNER JOIN karts ON karts.id = training_registrations.kart_id").
+		Where("karts.track_id = ? AND training_registrations.status IN ?", trackId, kartAssignedStatuses).
+		Where("trainings.end_time <= ? AND trainings.deleted_at IS NULL", time.Now().UTC()).
+		Scan(&rows).Error; err != nil {
+		logger.DatabaseError("Наработка картов трассы %d: %v", trackId, err)
+		return nil, err
+	}
+
+	usage := make(map[uint]KartUsage)
+	for _, row := range rows {
+		duration := row.EndTime.Sub(row.StartTime)
+		u := usage[row.KartID]
+		u.Total += duration
+		if row.ServicedAt == nil || !row.StartTime.Before(*row.ServicedAt) {
+			u.SinceService += duration
+		}
+		usage[row.KartID] = u
+	}
+
+	return usage, nil
+}
+
+// GetTrainingCapacity возвращает число мест на тренировке с учетом парка картов
+func (r *ContentRepository) GetTrainingCapacity(training *Training) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return trainingCapacity(r.db.WithContext(ctx), training)
+}
+
+// trainingCapacity считает места тем же правилом, что и trainingCapacitySQL
+func trainingCapacity(db *gorm.DB, training *Training) (int, error) {
+	fleet := db.Model(&Kart{}).Where("track_id = ? AND car_category_id = ?", training.TrackID, training.CarCategoryID).Session(&gorm.Session{})
+
+	var total int64
+	if err := fleet.Count(&total).Error; err != nil {
+		logger.DatabaseError("Парк картов тренировки %d: %v", training.ID, err)
+		return 0, err
+	}
+	if total == 0 {
+		return training.MaxParticipants, nil
+	}
+
+	var available int64
+	if err := fleet.Where("status = ?", KartStatusOK).Count(&available).Error; err != nil {
+		logger.DatabaseError("Исправные карты тренировки %d: %v", training.ID, err)
+		return 0, err
+	}
+
+	return min(training.MaxParticipants, int(available)), nil
+}
+
+// GetAvailableKarts возвращает исправные карты трассы и категории тренировки,
+// не занятые другими участниками в это время
+func (r *ContentRepository) GetAvailableKarts(training *Training, registrationId uint) ([]Kart, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var karts []Kart
+	if err := availableKarts(r.db.WithContext(ctx), training, registrationId).
+		Order("LENGTH(number), number").Find(&karts).Error; err != nil {
+		logger.DatabaseError("Свободные карты тренировки %d: %v", training.ID, err)
+		return nil, err
+	}
+
+	return karts, nil
+}
+
+// availableKarts - запрос свободных картов тренировки для записи registrationId
+func availableKarts(db *gorm.DB, training *Training, registrationId uint) *gorm.DB {
+	return db.Model(&Kart{}).
+		Where("track_id = ? AND car_category_id = ? AND status = ?", training.TrackID, training.CarCategoryID, KartStatusOK).
+		Where("NOT "+kartBusySQL, registrationId, kartAssignedStatuses, training.EndTime, training.StartTime)
+}
+
+// GetUpcomingFleetTrainings возвращает предстоящие активные тренировки трассы
+// в категории, места на которых зависят от этого парка картов
+func (r *ContentRepository) GetUpcomingFleetTrainings(trackId uint, carCategoryID *uint) ([]Training, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var trainings []Training
+	if err := r.db.WithContext(ctx).
+		Where("track_id = ? AND car_category_id = ? AND is_active = ? AND start_time > ?", trackId, carCategoryID, true, time.Now().UTC()).
+		Order("start_time").Find(&trainings).Error; err != nil {
+		logger.DatabaseError("Тренировки парка трассы %d: %v", trackId, err)
+		return nil, err
+	}
+
+	return trainings, nil
+}
+
+// AssignKart назначает карт подтвержденному участнику; kartId 0 снимает назначение.
+// Свободность карта проверяется в той же транзакции, что и назначение.
+func (r *ContentRepository) AssignKart(registrationId, kartId uint) (*TrainingRegistration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var registration TrainingRegistration
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ?", registrationId).Limit(1).Find(&registration).Error; err != nil {
+			return err
+		}
+		if registration.ID == 0 || !CanAssignKart(registration.Status) {
+			return newKartUnavailableError("карт назначается только подтвержденному участнику")
+		}
+
+		if kartId == 0 {
+			registration.KartID = nil
+			return tx.Model(&registration).Update("kart_id", nil).Error
+		}
+
+		var training Training
+		if err := tx.Where("id = ?", registration.TrainingID).Limit(1).Find(&training).Error; err != nil {
+			return err
+		}
+		if training.ID == 0 {
+			return newTrainingUnavailableError()
+		}
+
+		var kart Kart
+		if err := availableKarts(tx, &training, registrationId).Where("id = ?", kartId).Limit(1).Find(&kart).Error; err != nil {
+			return err
+		}
+		if kart.ID == 0 {
+			return newKartUnavailableError("карт в ремонте или уже занят")
+		}
+
+		registration.KartID = &kart.ID
+		return tx.Model(&registration).Update("kart_id", kart.ID).Error
+	})
+	if err != nil {
+		logger.DatabaseError("Назначение карта %d записи %d: %v", kartId, registrationId, err)
+		return nil, err
+	}
+
+	logger.DatabaseInfo("Запись %d: карт %d", registrationId, kartId)
+	return &registration, nil
+}
@@ -0,0 +1,233 @@
+package database
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"x.localhost/rvabot/internal/logger"
+
+	"gorm.io/gorm"
+)
+
+// PersonalBest - лучший круг пользователя на трассе в категории карта
+type PersonalBest struct {
+	TrackID       uint
+	CarCategoryID *uint
+	CarCategory   string // название категории для показа
+	LapTimeMs     int
+	SetAt         time.Time // начало тренировки, на которой поставлен рекорд
+	Laps          int       // всего кругов на трассе в этой категории
+}
+
+// TrainingLapSummary - лучший круг пользователя на одной тренировке
+type TrainingLapSummary struct {
+	TrainingID  uint
+	StartTime   time.Time
+	CarCategory string // название категории для показа
+	BestLapMs   int
+	Laps        int
+}
+
+// AddLapRecords сохраняет круги пользователя или участника participantId под
+// его аккаунтом, продолжая нумерацию уже введенных кругов. Трасса и категория
+// берутся из тренировки.
+func (r *ContentRepository) AddLapRecords(trainingId, userId, participantId uint, lapTimesMs []int) ([]LapRecord, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var records []LapRecord
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var training Training
+		if err := tx.Where("id = ? AND start_time <= ?", trainingId, time.Now().UTC()).Limit(1).Find(&training).Error; err != nil {
+			return err
+		}
+		if training.ID == 0 {
+			return newTrainingUnavailableError().WithUserMessage("Время кругов можно вводить только после начала тренировки")
+		}
+
+		var lastLap int
+		if err := tx.Model(&LapRecord{}).
+			Where("training_id = ? AND user_id = ? AND participant_id = ?", trainingId, userId, participantId).
+			Select("COALESCE(MAX(lap_number), 0)").Scan(&lastLap).Error; err != nil {
+			return err
+		}
+
+		for i, lapTime := range lapTimesMs {
+			records = append(records, LapRecord{
+				UserID:        userId,
+				ParticipantID: participantId,
+				TrainingID:    trainingId,
+				TrackID:       training.TrackID,
+				LapNumber:     lastLap + i + 1,
+				LapTimeMs:     lapTime,
+				CarCategoryID: training.CarCategoryID,
+			})
+		}
+		return mapConstraintError(tx.Create(&records).Error)
+	})
+	if err != nil {
+		logger.DatabaseError("Сохранение кругов тренировки %d пользователя %d (участник %d): %v", trainingId, userId, participantId, err)
+		return nil, err
+	}
+
+	logger.DatabaseInfo("Сохранено кругов: %d, TrainingID=%d, UserID=%d, ParticipantID=%d", len(records), trainingId, userId, participantId)
+	return records, nil
+}
+
+// ImportLapRecords заменяет круги участников тренировки загруженными из файла
+// логгера. Круги каждого участника нумеруются заново в порядке следования;
+// участник определяется парой UserID и ParticipantID.
+func (r *ContentRepository) ImportLapRecords(trainingId uint, records []LapRecord) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var training Training
+		if err := tx.Where("id = ? AND start_time <= ?", trainingId, time.Now().UTC()).Limit(1).Find(&training).Error; err != nil {
+			return err
+		}
+		if training.ID == 0 {
+			return newTrainingUnavailableError().WithUserMessage("Время кругов можно загружать только после начала тренировки")
+		}
+
+		lapNumbers := make(map[resultOwner]int)
+		for i := range records {
+			owner := resultOwner{UserID: records[i].UserID, ParticipantID: records[i].ParticipantID}
+			lapNumbers[owner]++
+			records[i].TrainingID = trainingId
+			records[i].TrackID = training.TrackID
+			records[i].CarCategoryID = training.CarCategoryID
+			records[i].LapNumber = lapNumbers[owner]
+		}
+
+		for owner := range lapNumbers {
+			if err := tx.Where("training_id = ? AND user_id = ? AND participant_id = ?", trainingId, owner.UserID, owner.ParticipantID).
+				Delete(&LapRecord{}).Error; err != nil {
+				return err
+			}
+		}
+
+		return mapConstraintError(tx.Create(&records).Error)
+	})
+	if err != nil {
+		logger.DatabaseError("Импорт кругов тренировки %d: %v", trainingId, err)
+		return err
+	}
+
+	logger.DatabaseInfo("Импортировано кругов: %d, TrainingID=%d", len(records), trainingId)
+	return nil
+}
+
+// EncodeSectors сохраняет времена секторов в строку для LapRecord.SectorsMs
+func EncodeSectors(sectorsMs []int) string {
+	parts := make([]string, len(sectorsMs))
+	for i, sector := range sectorsMs {
+		parts[i] = strconv.Itoa(sector)
+	}
+	return strings.Join(parts, ",")
+}
+
+// Sectors возвращает времена секторов круга; nil, если они не загружались
+func (l LapRecord) Sectors() []int {
+	if l.SectorsMs == "" {
+		return nil
+	}
+
+	parts := strings.Split(l.SectorsMs, ",")
+	sectors := make([]int, 0, len(parts))
+	for _, part := range parts {
+		sector, err := strconv.Atoi(part)
+		if err != nil {
+			return nil
+		}
+		sectors = append(sectors, sector)
+	}
+	return sectors
+}
+
+// GetTrainingLapRecords возвращает круги пользователя или участника
+// participantId под его аккаунтом на тренировке по порядку
+func (r *ContentRepository) GetTrainingLapRecords(trainingId, userId, participantId uint) ([]LapRecord, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var records []LapRecord
+	result := r.db.WithContext(ctx).
+		Where("training_id = ? AND user_id = ? AND participant_id = ?", trainingId, userId, participantId).
+		Order("lap_number").
+		Find(&records)
+	if result.Error != nil {
+		logger.DatabaseError("Круги тренировки %d пользователя %d: %v", trainingId, userId, result.Error)
+		return nil, result.Error
+	}
+
+	return records, nil
+}
+
+// GetPersonalBests возвращает лучшие круги пользователя или участника
+// participantId под его аккаунтом по трассам и категориям карта
+func (r *ContentRepository) GetPersonalBests(userId, participantId uint) ([]PersonalBest, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var bests []PersonalBest
+	result := r.db.WithContext(ctx).Model(&LapRecord{}).
+		Select("lap_records.track_id, lap_records.car_category_id, COALESCE(car_categories.name, ?) AS car_category, "+
+			"MIN(lap_records.lap_time_ms) AS lap_time_ms, COUNT(*) AS laps", CarCategoryNone).
+		Joins("LEFT JOIN car_categories ON car_categories.id = lap_records.car_category_id").
+		Where("lap_records.user_id = ? AND lap_records.participant_id = ?", userId, participantId).
+		Group("lap_records.track_id, lap_records.car_category_id").
+		Order("lap_records.track_id, car_category COLLATE NOCASE").
+		Scan(&bests)
+	if result.Error != nil {
+		logger.DatabaseError("Личные рекорды пользователя %d: %v", userId, result.Error)
+		return nil, result.Error
+	}
+
+	// Дата рекорда - первая тренировка, на которой показано лучшее время
+	for i := range bests {
+		var setAt []time.Time
+		if err := r.db.WithContext(ctx).Model(&LapRecord{}).
+			Joins("INNER JOIN trainings ON trainings.id = lap_records.training_id").
+			Where("lap_records.user_id = ? AND lap_records.participant_id = ? AND lap_records.track_id = ? AND lap_records.car_category_id IS ? AND lap_records.lap_time_ms = ?",
+				userId, participantId, bests[i].TrackID, bests[i].CarCategoryID, bests[i].LapTimeMs).
+			Order("trainings.start_time").
+			Limit(1).
+			Pluck("trainings.start_time", &setAt).Error; err != nil {
+			logger.DatabaseError("Дата рекорда пользователя %d: %v", userId, err)
+			return nil, err
+		}
+		if len(setAt) > 0 {
+			bests[i].SetAt = setAt[0]
+		}
+	}
+
+	return bests, nil
+}
+
+// GetTrackLapProgress возвращает лучший круг пользователя на каждой
+// тренировке на трассе в хронологическом порядке без кругов участников
+// под его аккаунтом
+func (r *ContentRepository) GetTrackLapProgress(userId, trackId uint) ([]TrainingLapSummary, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var progress []TrainingLapSummary
+	result := r.db.WithContext(ctx).Model(&LapRecord{}).
+		Select("lap_records.training_id, trainings.start_time, COALESCE(car_categories.name, ?) AS car_category, "+
+			"MIN(lap_records.lap_time_ms) AS best_lap_ms, COUNT(*) AS laps", CarCategoryNone).
+		Joins("INNER JOIN trainings ON trainings.id = lap_records.training_id").
+		Joins("LEFT JOIN car_categories ON car_categories.id = lap_records.car_category_id").
+		Where("lap_records.user_id = ? AND lap_records.participant_id = 0 AND lap_records.track_id = ?", userId, trackId).
+		Group("lap_records.training_id, trainings.start_time, lap_records.car_category_id").
+		Order("trainings.start_time").
+		Scan(&progress)
+	if result.Error != nil {
+		logger.DatabaseError("Прогресс пользователя %d на трассе %d: %v", userId, trackId, result.Error)
+		return nil, result.Error
+	}
+
+	return progress, nil
+}
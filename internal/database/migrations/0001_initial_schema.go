@@ -0,0 +1,37 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// 0001 повторяет схему, которую раньше создавал AutoMigrate.
+// IF NOT EXISTS позволяет принять существующие базы без изменений.
+func init() {
+	register(Migration{
+		Version: 1,
+		Name:    "initial_schema",
+		Up: func(tx *gorm.DB) error {
+			return execAll(tx,
+				"CREATE TABLE IF NOT EXISTS `tracks` (`id` integer PRIMARY KEY AUTOINCREMENT,`name` text,`info` text,`created_at` datetime,`updated_at` datetime)",
+				"CREATE TABLE IF NOT EXISTS `trainers` (`id` integer PRIMARY KEY AUTOINCREMENT,`name` text,`tg_id` text,`chat_id` integer,`info` text,`created_at` datetime,`updated_at` datetime)",
+				"CREATE UNIQUE INDEX IF NOT EXISTS `idx_trainers_chat_id` ON `trainers`(`chat_id`)",
+				"CREATE TABLE IF NOT EXISTS `admins` (`id` integer PRIMARY KEY AUTOINCREMENT,`name` text,`tg_id` text,`chat_id` integer,`is_active` numeric,`created_at` datetime,`updated_at` datetime)",
+				"CREATE UNIQUE INDEX IF NOT EXISTS `idx_admins_chat_id` ON `admins`(`chat_id`)",
+				"CREATE TABLE IF NOT EXISTS `trainings` (`id` integer PRIMARY KEY AUTOINCREMENT,`trainer_id` integer,`track_id` integer,`start_time` datetime,`end_time` datetime,`max_participants` integer,`is_active` numeric,`created_at` datetime,`updated_at` datetime)",
+				"CREATE TABLE IF NOT EXISTS `users` (`id` integer PRIMARY KEY AUTOINCREMENT,`name` text,`tg_id` text,`chat_id` integer,`is_active` numeric,`created_at` datetime,`updated_at` datetime)",
+				"CREATE UNIQUE INDEX IF NOT EXISTS `idx_users_chat_id` ON `users`(`chat_id`)",
+				"CREATE TABLE IF NOT EXISTS `training_registrations` (`id` integer PRIMARY KEY AUTOINCREMENT,`training_id` integer,`user_id` integer,`status` text,`created_at` datetime,`updated_at` datetime)",
+				"CREATE TABLE IF NOT EXISTS `training_requests` (`id` integer PRIMARY KEY AUTOINCREMENT,`user_id` integer,`message` text,`is_reviewed` numeric,`created_at` datetime,`updated_at` datetime)",
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			return execAll(tx,
+				"DROP TABLE IF EXISTS `training_requests`",
+				"DROP TABLE IF EXISTS `training_registrations`",
+				"DROP TABLE IF EXISTS `users`",
+				"DROP TABLE IF EXISTS `trainings`",
+				"DROP TABLE IF EXISTS `admins`",
+				"DROP TABLE IF EXISTS `trainers`",
+				"DROP TABLE IF EXISTS `tracks`",
+			)
+		},
+	})
+}
@@ -0,0 +1,26 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// 0002 добавляет категорию машин к тренировкам и заполняет пустые значения
+func init() {
+	register(Migration{
+		Version: 2,
+		Name:    "training_car_category",
+		Up: func(tx *gorm.DB) error {
+			exists, err := hasColumn(tx, "trainings", "car_category")
+			if err != nil {
+				return err
+			}
+			if !exists {
+				if err := tx.Exec("ALTER TABLE `trainings` ADD COLUMN `car_category` text DEFAULT 'N/A'").Error; err != nil {
+					return err
+				}
+			}
+			return tx.Exec("UPDATE `trainings` SET `car_category` = 'N/A' WHERE `car_category` IS NULL OR `car_category` = ''").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Exec("ALTER TABLE `trainings` DROP COLUMN `car_category`").Error
+		},
+	})
+}
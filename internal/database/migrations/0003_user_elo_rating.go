@@ -0,0 +1,21 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// 0003 объявляет колонку elo_rating, в которую уже пишет UpdateUserELORating
+func init() {
+	register(Migration{
+		Version: 3,
+		Name:    "user_elo_rating",
+		Up: func(tx *gorm.DB) error {
+			exists, err := hasColumn(tx, "users", "elo_rating")
+			if err != nil || exists {
+				return err
+			}
+			return tx.Exec("ALTER TABLE `users` ADD COLUMN `elo_rating` integer NOT NULL DEFAULT 1000").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Exec("ALTER TABLE `users` DROP COLUMN `elo_rating`").Error
+		},
+	})
+}
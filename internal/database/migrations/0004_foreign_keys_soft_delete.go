@@ -0,0 +1,134 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+	"x.localhost/rvabot/internal/logger"
+)
+
+// 0004 вводит внешние ключи и мягкое удаление тренеров, трасс и тренировок.
+// SQLite не умеет добавлять ограничения к существующей таблице, поэтому
+// дочерние таблицы пересоздаются. Записи, ссылающиеся на уже удаленные
+// строки, не проходят внешние ключи - они переносятся в архивные таблицы
+// archived_* до переноса данных, откат возвращает их на место.
+func init() {
+	register(Migration{
+		Version: 4,
+		Name:    "foreign_keys_soft_delete",
+		Up: func(tx *gorm.DB) error {
+			if err := execAll(tx,
+				"ALTER TABLE `trainers` ADD COLUMN `deleted_at` datetime",
+				"CREATE INDEX IF NOT EXISTS `idx_trainers_deleted_at` ON `trainers`(`deleted_at`)",
+				"ALTER TABLE `tracks` ADD COLUMN `deleted_at` datetime",
+				"CREATE INDEX IF NOT EXISTS `idx_tracks_deleted_at` ON `tracks`(`deleted_at`)",
+			); err != nil {
+				return err
+			}
+
+			if err := archiveOrphans(tx); err != nil {
+				return err
+			}
+
+			return execAll(tx,
+				"CREATE TABLE `trainings__new` (`id` integer PRIMARY KEY AUTOINCREMENT,`trainer_id` integer,`track_id` integer,`start_time` datetime,`end_time` datetime,`max_participants` integer,`car_category` text DEFAULT 'N/A',`is_active` numeric,`created_at` datetime,`updated_at` datetime,`deleted_at` datetime,"+
+					"CONSTRAINT `fk_trainings_trainer` FOREIGN KEY (`trainer_id`) REFERENCES `trainers`(`id`) ON DELETE RESTRICT,"+
+					"CONSTRAINT `fk_trainings_track` FOREIGN KEY (`track_id`) REFERENCES `tracks`(`id`) ON DELETE RESTRICT)",
+				"INSERT INTO `trainings__new` (`id`,`trainer_id`,`track_id`,`start_time`,`end_time`,`max_participants`,`car_category`,`is_active`,`created_at`,`updated_at`) "+
+					"SELECT `id`,`trainer_id`,`track_id`,`start_time`,`end_time`,`max_participants`,`car_category`,`is_active`,`created_at`,`updated_at` FROM `trainings`",
+				"DROP TABLE `trainings`",
+				"ALTER TABLE `trainings__new` RENAME TO `trainings`",
+				"CREATE INDEX `idx_trainings_deleted_at` ON `trainings`(`deleted_at`)",
+				"CREATE INDEX `idx_trainings_trainer_id` ON `trainings`(`trainer_id`)",
+				"CREATE INDEX `idx_trainings_track_id` ON `trainings`(`track_id`)",
+
+				"CREATE TABLE `training_registrations__new` (`id` integer PRIMARY KEY AUTOINCREMENT,`training_id` integer,`user_id` integer,`status` text,`created_at` datetime,`updated_at` datetime,"+
+					"CONSTRAINT `fk_training_registrations_training` FOREIGN KEY (`training_id`) REFERENCES `trainings`(`id`) ON DELETE CASCADE,"+
+					"CONSTRAINT `fk_training_registrations_user` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`) ON DELETE CASCADE)",
+				"INSERT INTO `training_registrations__new` SELECT `id`,`training_id`,`user_id`,`status`,`created_at`,`updated_at` FROM `training_registrations`",
+				"DROP TABLE `training_registrations`",
+				"ALTER TABLE `training_registrations__new` RENAME TO `training_registrations`",
+				"CREATE INDEX `idx_training_registrations_training_id` ON `training_registrations`(`training_id`)",
+				"CREATE INDEX `idx_training_registrations_user_id` ON `training_registrations`(`user_id`)",
+
+				"CREATE TABLE `training_requests__new` (`id` integer PRIMARY KEY AUTOINCREMENT,`user_id` integer,`message` text,`is_reviewed` numeric,`created_at` datetime,`updated_at` datetime,"+
+					"CONSTRAINT `fk_training_requests_user` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`) ON DELETE CASCADE)",
+				"INSERT INTO `training_requests__new` SELECT `id`,`user_id`,`message`,`is_reviewed`,`created_at`,`updated_at` FROM `training_requests`",
+				"DROP TABLE `training_requests`",
+				"ALTER TABLE `training_requests__new` RENAME TO `training_requests`",
+			)
+		},
+		// Откат снимает ограничения; мягко удаленные и заархивированные строки снова становятся видимыми
+		Down: func(tx *gorm.DB) error {
+			if err := execAll(tx,
+				"CREATE TABLE `training_requests__old` (`id` integer PRIMARY KEY AUTOINCREMENT,`user_id` integer,`message` text,`is_reviewed` numeric,`created_at` datetime,`updated_at` datetime)",
+				"INSERT INTO `training_requests__old` SELECT `id`,`user_id`,`message`,`is_reviewed`,`created_at`,`updated_at` FROM `training_requests`",
+				"DROP TABLE `training_requests`",
+				"ALTER TABLE `training_requests__old` RENAME TO `training_requests`",
+
+				"CREATE TABLE `training_registrations__old` (`id` integer PRIMARY KEY AUTOINCREMENT,`training_id` integer,`user_id` integer,`status` text,`created_at` datetime,`updated_at` datetime)",
+				"INSERT INTO `training_registrations__old` SELECT `id`,`training_id`,`user_id`,`status`,`created_at`,`updated_at` FROM `training_registrations`",
+				"DROP TABLE `training_registrations`",
+				"ALTER TABLE `training_registrations__old` RENAME TO `training_registrations`",
+
+				"CREATE TABLE `trainings__old` (`id` integer PRIMARY KEY AUTOINCREMENT,`trainer_id` integer,`track_id` integer,`start_time` datetime,`end_time` datetime,`max_participants` integer,`car_category` text DEFAULT 'N/A',`is_active` numeric,`created_at` datetime,`updated_at` datetime)",
+				"INSERT INTO `trainings__old` SELECT `id`,`trainer_id`,`track_id`,`start_time`,`end_time`,`max_participants`,`car_category`,`is_active`,`created_at`,`updated_at` FROM `trainings`",
+				"DROP TABLE `trainings`",
+				"ALTER TABLE `trainings__old` RENAME TO `trainings`",
+
+				"DROP INDEX IF EXISTS `idx_tracks_deleted_at`",
+				"ALTER TABLE `tracks` DROP COLUMN `deleted_at`",
+				"DROP INDEX IF EXISTS `idx_trainers_deleted_at`",
+				"ALTER TABLE `trainers` DROP COLUMN `deleted_at`",
+			); err != nil {
+				return err
+			}
+
+			return restoreOrphans(tx)
+		},
+	})
+}
+
+// orphanArchives - таблицы с записями без родительской строки: колонки
+// на момент миграции и условие отбора. Порядок важен: записи на
+// заархивированные тренировки тоже попадают в архив.
+var orphanArchives = []struct {
+	table   string
+	columns string
+	where   string
+}{
+	{"trainings", "`id`,`trainer_id`,`track_id`,`start_time`,`end_time`,`max_participants`,`car_category`,`is_active`,`created_at`,`updated_at`",
+		"`trainer_id` NOT IN (SELECT `id` FROM `trainers`) OR `track_id` NOT IN (SELECT `id` FROM `tracks`)"},
+	{"training_registrations", "`id`,`training_id`,`user_id`,`status`,`created_at`,`updated_at`",
+		"`training_id` NOT IN (SELECT `id` FROM `trainings`) OR `user_id` NOT IN (SELECT `id` FROM `users`)"},
+	{"training_requests", "`id`,`user_id`,`message`,`is_reviewed`,`created_at`,`updated_at`",
+		"`user_id` NOT IN (SELECT `id` FROM `users`)"},
+}
+
+// archiveOrphans переносит записи без родительской строки в archived_<table>
+func archiveOrphans(tx *gorm.DB) error {
+	for _, a := range orphanArchives {
+		if err := tx.Exec("CREATE TABLE `archived_" + a.table + "` AS SELECT " + a.columns + " FROM `" + a.table + "` WHERE " + a.where).Error; err != nil {
+			return err
+		}
+		result := tx.Exec("DELETE FROM `" + a.table + "` WHERE " + a.where)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			logger.DatabaseInfo("Миграция 0004: записи %s без родительской строки перенесены в archived_%s: %d", a.table, a.table, result.RowsAffected)
+		}
+	}
+	return nil
+}
+
+// restoreOrphans возвращает заархивированные записи в таблицы без ограничений
+func restoreOrphans(tx *gorm.DB) error {
+	for _, a := range orphanArchives {
+		if err := execAll(tx,
+			"INSERT INTO `"+a.table+"` ("+a.columns+") SELECT "+a.columns+" FROM `archived_"+a.table+"`",
+			"DROP TABLE `archived_"+a.table+"`",
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// 0005 запрещает повторную запись пользователя на одну тренировку.
+// Существующие дубликаты схлопываются до самой ранней записи.
+func init() {
+	register(Migration{
+		Version: 5,
+		Name:    "registration_unique_index",
+		Up: func(tx *gorm.DB) error {
+			return execAll(tx,
+				"DELETE FROM `training_registrations` WHERE `id` NOT IN (SELECT MIN(`id`) FROM `training_registrations` GROUP BY `training_id`, `user_id`)",
+				"CREATE UNIQUE INDEX `idx_training_registrations_training_user` ON `training_registrations`(`training_id`, `user_id`)",
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Exec("DROP INDEX IF EXISTS `idx_training_registrations_training_user`").Error
+		},
+	})
+}
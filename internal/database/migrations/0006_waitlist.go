@@ -0,0 +1,25 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// 0006 добавляет лист ожидания: позицию в очереди и срок действия предложения места
+func init() {
+	register(Migration{
+		Version: 6,
+		Name:    "waitlist",
+		Up: func(tx *gorm.DB) error {
+			return execAll(tx,
+				"ALTER TABLE `training_registrations` ADD COLUMN `waitlist_position` integer NOT NULL DEFAULT 0",
+				"ALTER TABLE `training_registrations` ADD COLUMN `offer_expires_at` datetime",
+				"CREATE INDEX `idx_training_registrations_status` ON `training_registrations`(`status`)",
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			return execAll(tx,
+				"DROP INDEX IF EXISTS `idx_training_registrations_status`",
+				"ALTER TABLE `training_registrations` DROP COLUMN `offer_expires_at`",
+				"ALTER TABLE `training_registrations` DROP COLUMN `waitlist_position`",
+			)
+		},
+	})
+}
@@ -0,0 +1,23 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// 0007 добавляет время отмены записи и отметку поздней отмены
+func init() {
+	register(Migration{
+		Version: 7,
+		Name:    "registration_cancellation",
+		Up: func(tx *gorm.DB) error {
+			return execAll(tx,
+				"ALTER TABLE `training_registrations` ADD COLUMN `cancelled_at` datetime",
+				"ALTER TABLE `training_registrations` ADD COLUMN `late_cancellation` numeric NOT NULL DEFAULT false",
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			return execAll(tx,
+				"ALTER TABLE `training_registrations` DROP COLUMN `late_cancellation`",
+				"ALTER TABLE `training_registrations` DROP COLUMN `cancelled_at`",
+			)
+		},
+	})
+}
@@ -0,0 +1,33 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// 0008 добавляет повторяющиеся серии тренировок.
+// trainings.series_id не объявлен внешним ключом: серии удаляются только
+// мягко, а снятие ограничения при откате потребовало бы пересоздать
+// trainings, что каскадно удалило бы записи участников.
+func init() {
+	register(Migration{
+		Version: 8,
+		Name:    "training_series",
+		Up: func(tx *gorm.DB) error {
+			return execAll(tx,
+				"CREATE TABLE `training_series` (`id` integer PRIMARY KEY AUTOINCREMENT,`trainer_id` integer,`track_id` integer,`weekdays` text NOT NULL,"+
+					"`first_start` datetime,`duration_minutes` integer,`until` datetime,`occurrences` integer NOT NULL DEFAULT 0,`max_participants` integer,"+
+					"`car_category` text DEFAULT 'N/A',`created_at` datetime,`updated_at` datetime,`deleted_at` datetime,"+
+					"CONSTRAINT `fk_training_series_trainer` FOREIGN KEY (`trainer_id`) REFERENCES `trainers`(`id`) ON DELETE RESTRICT,"+
+					"CONSTRAINT `fk_training_series_track` FOREIGN KEY (`track_id`) REFERENCES `tracks`(`id`) ON DELETE RESTRICT)",
+				"CREATE INDEX `idx_training_series_deleted_at` ON `training_series`(`deleted_at`)",
+				"ALTER TABLE `trainings` ADD COLUMN `series_id` integer",
+				"CREATE INDEX `idx_trainings_series_id` ON `trainings`(`series_id`)",
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			return execAll(tx,
+				"DROP INDEX IF EXISTS `idx_trainings_series_id`",
+				"ALTER TABLE `trainings` DROP COLUMN `series_id`",
+				"DROP TABLE IF EXISTS `training_series`",
+			)
+		},
+	})
+}
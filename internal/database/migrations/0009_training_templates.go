@@ -0,0 +1,24 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// 0009 добавляет сохраненные шаблоны тренировок.
+func init() {
+	register(Migration{
+		Version: 9,
+		Name:    "training_templates",
+		Up: func(tx *gorm.DB) error {
+			return execAll(tx,
+				"CREATE TABLE `training_templates` (`id` integer PRIMARY KEY AUTOINCREMENT,`name` text NOT NULL,`trainer_id` integer,`track_id` integer,"+
+					"`time_of_day` text,`duration_minutes` integer,`max_participants` integer,`car_category` text DEFAULT 'N/A',"+
+					"`created_at` datetime,`updated_at` datetime,`deleted_at` datetime,"+
+					"CONSTRAINT `fk_training_templates_trainer` FOREIGN KEY (`trainer_id`) REFERENCES `trainers`(`id`) ON DELETE RESTRICT,"+
+					"CONSTRAINT `fk_training_templates_track` FOREIGN KEY (`track_id`) REFERENCES `tracks`(`id`) ON DELETE RESTRICT)",
+				"CREATE INDEX `idx_training_templates_deleted_at` ON `training_templates`(`deleted_at`)",
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			return execAll(tx, "DROP TABLE IF EXISTS `training_templates`")
+		},
+	})
+}
@@ -0,0 +1,25 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// 0010 добавляет дни закрытия трасс для проверки конфликтов расписания.
+func init() {
+	register(Migration{
+		Version: 10,
+		Name:    "track_closures",
+		Up: func(tx *gorm.DB) error {
+			return execAll(tx,
+				"CREATE TABLE `track_closures` (`id` integer PRIMARY KEY AUTOINCREMENT,`track_id` integer NOT NULL,`date` datetime NOT NULL,`reason` text,`created_at` datetime,"+
+					"CONSTRAINT `fk_track_closures_track` FOREIGN KEY (`track_id`) REFERENCES `tracks`(`id`) ON DELETE CASCADE)",
+				"CREATE UNIQUE INDEX `idx_track_closures_track_date` ON `track_closures`(`track_id`,`date`)",
+				"CREATE INDEX `idx_trainings_start_time` ON `trainings`(`start_time`)",
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			return execAll(tx,
+				"DROP INDEX IF EXISTS `idx_trainings_start_time`",
+				"DROP TABLE IF EXISTS `track_closures`",
+			)
+		},
+	})
+}
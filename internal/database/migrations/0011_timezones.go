@@ -0,0 +1,110 @@
+package migrations
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"x.localhost/rvabot/internal/timefmt"
+)
+
+// 0011 добавляет часовые пояса трасс и пользователей и переводит время
+// тренировок в UTC. Раньше местное время академии сохранялось как UTC,
+// поэтому значения сдвигаются на смещение ACADEMY_TIMEZONE на их дату.
+func init() {
+	register(Migration{
+		Version: 11,
+		Name:    "timezones",
+		Up: func(tx *gorm.DB) error {
+			if err := execAll(tx,
+				"ALTER TABLE `tracks` ADD COLUMN `timezone` text NOT NULL DEFAULT ''",
+				"ALTER TABLE `users` ADD COLUMN `timezone` text NOT NULL DEFAULT ''",
+			); err != nil {
+				return err
+			}
+			return shiftTrainingTimes(tx, wallClockToUTC)
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := shiftTrainingTimes(tx, utcToWallClock); err != nil {
+				return err
+			}
+			return execAll(tx,
+				"ALTER TABLE `users` DROP COLUMN `timezone`",
+				"ALTER TABLE `tracks` DROP COLUMN `timezone`",
+			)
+		},
+	})
+}
+
+// wallClockToUTC трактует показания t как местное время академии
+func wallClockToUTC(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), timefmt.Academy()).UTC()
+}
+
+// utcToWallClock возвращает местное время академии с меткой UTC, как до миграции
+func utcToWallClock(t time.Time) time.Time {
+	local := t.In(timefmt.Academy())
+	return time.Date(local.Year(), local.Month(), local.Day(), local.Hour(), local.Minute(), local.Second(), local.Nanosecond(), time.UTC)
+}
+
+// shiftTrainingTimes применяет shift ко времени занятий и серий, включая архивные
+func shiftTrainingTimes(tx *gorm.DB, shift func(time.Time) time.Time) error {
+	if err := shiftColumns(tx, "trainings", []string{"start_time", "end_time"}, shift); err != nil {
+		return err
+	}
+	return shiftColumns(tx, "training_series", []string{"first_start", "until"}, shift)
+}
+
+func shiftColumns(tx *gorm.DB, table string, columns []string, shift func(time.Time) time.Time) error {
+	quoted := make([]string, len(columns))
+	assignments := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = "`" + column + "`"
+		assignments[i] = quoted[i] + " = ?"
+	}
+
+	rows, err := tx.Raw(fmt.Sprintf("SELECT `id`, %s FROM `%s`", strings.Join(quoted, ", "), table)).Rows()
+	if err != nil {
+		return fmt.Errorf("чтение %s: %w", table, err)
+	}
+
+	type update struct {
+		id     uint
+		values []interface{}
+	}
+	var updates []update
+	for rows.Next() {
+		var id uint
+		values := make([]sql.NullTime, len(columns))
+		dest := []interface{}{&id}
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			rows.Close()
+			return fmt.Errorf("чтение %s: %w", table, err)
+		}
+
+		shifted := make([]interface{}, len(columns))
+		for i, v := range values {
+			if v.Valid {
+				shifted[i] = shift(v.Time)
+			}
+		}
+		updates = append(updates, update{id: id, values: shifted})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("чтение %s: %w", table, err)
+	}
+
+	query := fmt.Sprintf("UPDATE `%s` SET %s WHERE `id` = ?", table, strings.Join(assignments, ", "))
+	for _, u := range updates {
+		if err := tx.Exec(query, append(u.values, u.id)...).Error; err != nil {
+			return fmt.Errorf("обновление %s %d: %w", table, u.id, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 0012 добавляет отметку о запросе посещаемости у тренировок. Уже прошедшие
+// тренировки считаются обработанными, чтобы тренеры не получили списки за всю историю.
+func init() {
+	register(Migration{
+		Version: 12,
+		Name:    "attendance",
+		Up: func(tx *gorm.DB) error {
+			if err := execAll(tx,
+				"ALTER TABLE `trainings` ADD COLUMN `attendance_requested_at` datetime",
+				"CREATE INDEX `idx_training_registrations_user_status` ON `training_registrations`(`user_id`,`status`)",
+			); err != nil {
+				return err
+			}
+			return tx.Exec("UPDATE `trainings` SET `attendance_requested_at` = `end_time` WHERE `end_time` <= ?", time.Now().UTC()).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return execAll(tx,
+				"UPDATE `training_registrations` SET `status` = 'confirmed' WHERE `status` IN ('attended', 'no_show')",
+				"DROP INDEX IF EXISTS `idx_training_registrations_user_status`",
+				"ALTER TABLE `trainings` DROP COLUMN `attendance_requested_at`",
+			)
+		},
+	})
+}
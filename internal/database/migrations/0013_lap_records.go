@@ -0,0 +1,25 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// 0013 добавляет время кругов участников для личных рекордов и прогресса.
+func init() {
+	register(Migration{
+		Version: 13,
+		Name:    "lap_records",
+		Up: func(tx *gorm.DB) error {
+			return execAll(tx,
+				"CREATE TABLE `lap_records` (`id` integer PRIMARY KEY AUTOINCREMENT,`user_id` integer NOT NULL,`training_id` integer NOT NULL,`track_id` integer NOT NULL,"+
+					"`lap_number` integer NOT NULL,`lap_time_ms` integer NOT NULL,`car_category` text,`created_at` datetime,"+
+					"CONSTRAINT `fk_lap_records_user` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`) ON DELETE CASCADE,"+
+					"CONSTRAINT `fk_lap_records_training` FOREIGN KEY (`training_id`) REFERENCES `trainings`(`id`) ON DELETE CASCADE,"+
+					"CONSTRAINT `fk_lap_records_track` FOREIGN KEY (`track_id`) REFERENCES `tracks`(`id`) ON DELETE CASCADE)",
+				"CREATE UNIQUE INDEX `idx_lap_records_training_user_lap` ON `lap_records`(`training_id`,`user_id`,`lap_number`)",
+				"CREATE INDEX `idx_lap_records_user_track` ON `lap_records`(`user_id`,`track_id`)",
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			return execAll(tx, "DROP TABLE IF EXISTS `lap_records`")
+		},
+	})
+}
@@ -0,0 +1,21 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// 0014 добавляет времена секторов круга, загружаемые из файлов логгеров
+func init() {
+	register(Migration{
+		Version: 14,
+		Name:    "lap_sectors",
+		Up: func(tx *gorm.DB) error {
+			return execAll(tx,
+				"ALTER TABLE `lap_records` ADD COLUMN `sectors_ms` text NOT NULL DEFAULT ''",
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			return execAll(tx,
+				"ALTER TABLE `lap_records` DROP COLUMN `sectors_ms`",
+			)
+		},
+	})
+}
@@ -0,0 +1,34 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// 0015 добавляет рейтинги пользователей по категориям карта и результаты
+// заездов, по которым они рассчитываются.
+func init() {
+	register(Migration{
+		Version: 15,
+		Name:    "ratings",
+		Up: func(tx *gorm.DB) error {
+			return execAll(tx,
+				"CREATE TABLE `user_ratings` (`id` integer PRIMARY KEY AUTOINCREMENT,`user_id` integer NOT NULL,`car_category` text NOT NULL,"+
+					"`rating` integer NOT NULL DEFAULT 1000,`heats` integer NOT NULL DEFAULT 0,`updated_at` datetime,"+
+					"CONSTRAINT `fk_user_ratings_user` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`) ON DELETE CASCADE)",
+				"CREATE UNIQUE INDEX `idx_user_ratings_user_category` ON `user_ratings`(`user_id`,`car_category`)",
+				"CREATE INDEX `idx_user_ratings_category_rating` ON `user_ratings`(`car_category`,`rating`)",
+				"CREATE TABLE `heat_results` (`id` integer PRIMARY KEY AUTOINCREMENT,`training_id` integer NOT NULL,`heat_number` integer NOT NULL,"+
+					"`user_id` integer NOT NULL,`car_category` text NOT NULL,`position` integer NOT NULL,`participants` integer NOT NULL,"+
+					"`rating_before` integer NOT NULL,`rating_after` integer NOT NULL,`created_at` datetime,"+
+					"CONSTRAINT `fk_heat_results_user` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`) ON DELETE CASCADE,"+
+					"CONSTRAINT `fk_heat_results_training` FOREIGN KEY (`training_id`) REFERENCES `trainings`(`id`) ON DELETE CASCADE)",
+				"CREATE UNIQUE INDEX `idx_heat_results_training_heat_user` ON `heat_results`(`training_id`,`heat_number`,`user_id`)",
+				"CREATE INDEX `idx_heat_results_user_category` ON `heat_results`(`user_id`,`car_category`)",
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			return execAll(tx,
+				"DROP TABLE IF EXISTS `heat_results`",
+				"DROP TABLE IF EXISTS `user_ratings`",
+			)
+		},
+	})
+}
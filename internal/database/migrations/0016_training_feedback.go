@@ -0,0 +1,23 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// 0016 добавляет разборы тренировок, которые тренер пишет участникам.
+func init() {
+	register(Migration{
+		Version: 16,
+		Name:    "training_feedback",
+		Up: func(tx *gorm.DB) error {
+			return execAll(tx,
+				"CREATE TABLE `training_feedbacks` (`id` integer PRIMARY KEY AUTOINCREMENT,`registration_id` integer NOT NULL,"+
+					"`strengths` text NOT NULL DEFAULT '',`improvements` text NOT NULL DEFAULT '',`video_url` text NOT NULL DEFAULT '',"+
+					"`delivered_at` datetime,`created_at` datetime,`updated_at` datetime,"+
+					"CONSTRAINT `fk_training_feedbacks_registration` FOREIGN KEY (`registration_id`) REFERENCES `training_registrations`(`id`) ON DELETE CASCADE)",
+				"CREATE UNIQUE INDEX `idx_training_feedbacks_registration_id` ON `training_feedbacks`(`registration_id`)",
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			return execAll(tx, "DROP TABLE IF EXISTS `training_feedbacks`")
+		},
+	})
+}
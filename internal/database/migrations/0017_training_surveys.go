@@ -0,0 +1,26 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// 0017 добавляет опросы участников об удовлетворенности тренировкой.
+func init() {
+	register(Migration{
+		Version: 17,
+		Name:    "training_surveys",
+		Up: func(tx *gorm.DB) error {
+			return execAll(tx,
+				"CREATE TABLE `training_surveys` (`id` integer PRIMARY KEY AUTOINCREMENT,`registration_id` integer NOT NULL,"+
+					"`training_id` integer NOT NULL,`trainer_id` integer NOT NULL,`track_id` integer NOT NULL,`user_id` integer NOT NULL,"+
+					"`rating` integer NOT NULL DEFAULT 0,`comment` text NOT NULL DEFAULT '',`answered_at` datetime,`created_at` datetime,"+
+					"CONSTRAINT `fk_training_surveys_registration` FOREIGN KEY (`registration_id`) REFERENCES `training_registrations`(`id`) ON DELETE CASCADE,"+
+					"CONSTRAINT `fk_training_surveys_training` FOREIGN KEY (`training_id`) REFERENCES `trainings`(`id`) ON DELETE CASCADE)",
+				"CREATE UNIQUE INDEX `idx_training_surveys_registration_id` ON `training_surveys`(`registration_id`)",
+				"CREATE INDEX `idx_training_surveys_trainer` ON `training_surveys`(`trainer_id`)",
+				"CREATE INDEX `idx_training_surveys_track` ON `training_surveys`(`track_id`)",
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			return execAll(tx, "DROP TABLE IF EXISTS `training_surveys`")
+		},
+	})
+}
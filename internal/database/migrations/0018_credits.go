@@ -0,0 +1,31 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// 0018 добавляет пакеты тренировок и журнал кредитов пользователей.
+func init() {
+	register(Migration{
+		Version: 18,
+		Name:    "credits",
+		Up: func(tx *gorm.DB) error {
+			return execAll(tx,
+				"CREATE TABLE `packages` (`id` integer PRIMARY KEY AUTOINCREMENT,`name` text,`credits` integer,"+
+					"`is_active` numeric NOT NULL DEFAULT true,`created_at` datetime)",
+				"CREATE TABLE `credit_ledgers` (`id` integer PRIMARY KEY AUTOINCREMENT,`user_id` integer NOT NULL,`kind` text NOT NULL,"+
+					"`delta` integer NOT NULL,`balance` integer NOT NULL,`package_id` integer,`registration_id` integer,"+
+					"`admin_chat_id` integer NOT NULL DEFAULT 0,`note` text NOT NULL DEFAULT '',`created_at` datetime,"+
+					"CONSTRAINT `fk_credit_ledgers_user` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`) ON DELETE CASCADE,"+
+					"CONSTRAINT `fk_credit_ledgers_package` FOREIGN KEY (`package_id`) REFERENCES `packages`(`id`),"+
+					"CONSTRAINT `fk_credit_ledgers_registration` FOREIGN KEY (`registration_id`) REFERENCES `training_registrations`(`id`) ON DELETE SET NULL)",
+				"CREATE INDEX `idx_credit_ledgers_user_id` ON `credit_ledgers`(`user_id`)",
+				"CREATE INDEX `idx_credit_ledgers_registration_id` ON `credit_ledgers`(`registration_id`)",
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			return execAll(tx,
+				"DROP TABLE IF EXISTS `credit_ledgers`",
+				"DROP TABLE IF EXISTS `packages`",
+			)
+		},
+	})
+}
@@ -0,0 +1,32 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// 0019 добавляет цены тренировок и категорий и статус оплаты записей.
+func init() {
+	register(Migration{
+		Version: 19,
+		Name:    "payments",
+		Up: func(tx *gorm.DB) error {
+			return execAll(tx,
+				"ALTER TABLE `trainings` ADD COLUMN `price` integer NOT NULL DEFAULT 0",
+				"ALTER TABLE `training_registrations` ADD COLUMN `payment_status` text NOT NULL DEFAULT 'unpaid'",
+				"ALTER TABLE `training_registrations` ADD COLUMN `payment_method` text NOT NULL DEFAULT ''",
+				"ALTER TABLE `training_registrations` ADD COLUMN `payment_amount` integer NOT NULL DEFAULT 0",
+				"ALTER TABLE `training_registrations` ADD COLUMN `paid_at` datetime",
+				"CREATE TABLE `category_prices` (`id` integer PRIMARY KEY AUTOINCREMENT,`car_category` text NOT NULL,`price` integer NOT NULL,`updated_at` datetime)",
+				"CREATE UNIQUE INDEX `idx_category_prices_car_category` ON `category_prices`(`car_category`)",
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			return execAll(tx,
+				"DROP TABLE IF EXISTS `category_prices`",
+				"ALTER TABLE `training_registrations` DROP COLUMN `paid_at`",
+				"ALTER TABLE `training_registrations` DROP COLUMN `payment_amount`",
+				"ALTER TABLE `training_registrations` DROP COLUMN `payment_method`",
+				"ALTER TABLE `training_registrations` DROP COLUMN `payment_status`",
+				"ALTER TABLE `trainings` DROP COLUMN `price`",
+			)
+		},
+	})
+}
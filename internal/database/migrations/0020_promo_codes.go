@@ -0,0 +1,31 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// 0020 добавляет промокоды и скидку записи на тренировку.
+func init() {
+	register(Migration{
+		Version: 20,
+		Name:    "promo_codes",
+		Up: func(tx *gorm.DB) error {
+			return execAll(tx,
+				"CREATE TABLE `promo_codes` (`id` integer PRIMARY KEY AUTOINCREMENT,`code` text NOT NULL,`discount_type` text NOT NULL,"+
+					"`discount_value` integer NOT NULL,`valid_from` datetime,`valid_until` datetime,`max_uses` integer NOT NULL DEFAULT 0,"+
+					"`track_id` integer,`car_category` text NOT NULL DEFAULT '',`is_active` numeric NOT NULL DEFAULT true,`created_at` datetime,"+
+					"CONSTRAINT `fk_promo_codes_track` FOREIGN KEY (`track_id`) REFERENCES `tracks`(`id`) ON DELETE CASCADE)",
+				"CREATE UNIQUE INDEX `idx_promo_codes_code` ON `promo_codes`(`code`)",
+				"ALTER TABLE `training_registrations` ADD COLUMN `promo_code_id` integer REFERENCES `promo_codes`(`id`) ON DELETE SET NULL",
+				"ALTER TABLE `training_registrations` ADD COLUMN `discount_amount` integer NOT NULL DEFAULT 0",
+				"CREATE INDEX `idx_training_registrations_promo_code_id` ON `training_registrations`(`promo_code_id`)",
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			return execAll(tx,
+				"DROP INDEX IF EXISTS `idx_training_registrations_promo_code_id`",
+				"ALTER TABLE `training_registrations` DROP COLUMN `discount_amount`",
+				"ALTER TABLE `training_registrations` DROP COLUMN `promo_code_id`",
+				"DROP TABLE IF EXISTS `promo_codes`",
+			)
+		},
+	})
+}
@@ -0,0 +1,29 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// 0021 добавляет парк картов трасс и назначение карта участнику.
+func init() {
+	register(Migration{
+		Version: 21,
+		Name:    "karts",
+		Up: func(tx *gorm.DB) error {
+			return execAll(tx,
+				"CREATE TABLE `karts` (`id` integer PRIMARY KEY AUTOINCREMENT,`track_id` integer NOT NULL,`car_category` text NOT NULL,"+
+					"`number` text NOT NULL,`status` text NOT NULL DEFAULT 'ok',`serviced_at` datetime,`created_at` datetime,`updated_at` datetime,`deleted_at` datetime,"+
+					"CONSTRAINT `fk_karts_track` FOREIGN KEY (`track_id`) REFERENCES `tracks`(`id`) ON DELETE CASCADE)",
+				"CREATE INDEX `idx_karts_deleted_at` ON `karts`(`deleted_at`)",
+				"CREATE UNIQUE INDEX `idx_karts_track_number` ON `karts`(`track_id`, `number`) WHERE `deleted_at` IS NULL",
+				"ALTER TABLE `training_registrations` ADD COLUMN `kart_id` integer REFERENCES `karts`(`id`) ON DELETE SET NULL",
+				"CREATE INDEX `idx_training_registrations_kart_id` ON `training_registrations`(`kart_id`)",
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			return execAll(tx,
+				"DROP INDEX IF EXISTS `idx_training_registrations_kart_id`",
+				"ALTER TABLE `training_registrations` DROP COLUMN `kart_id`",
+				"DROP TABLE IF EXISTS `karts`",
+			)
+		},
+	})
+}
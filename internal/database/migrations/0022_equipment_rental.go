@@ -0,0 +1,31 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// 0022 добавляет склад прокатной экипировки трасс и запросы участников.
+func init() {
+	register(Migration{
+		Version: 22,
+		Name:    "equipment_rental",
+		Up: func(tx *gorm.DB) error {
+			return execAll(tx,
+				"CREATE TABLE `rental_items` (`id` integer PRIMARY KEY AUTOINCREMENT,`track_id` integer NOT NULL,`kind` text NOT NULL,"+
+					"`size` text NOT NULL,`quantity` integer NOT NULL DEFAULT 0,`updated_at` datetime,"+
+					"CONSTRAINT `fk_rental_items_track` FOREIGN KEY (`track_id`) REFERENCES `tracks`(`id`) ON DELETE CASCADE)",
+				"CREATE UNIQUE INDEX `idx_rental_items_track_kind_size` ON `rental_items`(`track_id`, `kind`, `size`)",
+				"CREATE TABLE `rental_requests` (`id` integer PRIMARY KEY AUTOINCREMENT,`registration_id` integer NOT NULL,`rental_item_id` integer NOT NULL,"+
+					"`kind` text NOT NULL,`status` text NOT NULL DEFAULT 'requested',`created_at` datetime,`updated_at` datetime,"+
+					"CONSTRAINT `fk_rental_requests_registration` FOREIGN KEY (`registration_id`) REFERENCES `training_registrations`(`id`) ON DELETE CASCADE,"+
+					"CONSTRAINT `fk_rental_requests_item` FOREIGN KEY (`rental_item_id`) REFERENCES `rental_items`(`id`) ON DELETE CASCADE)",
+				"CREATE UNIQUE INDEX `idx_rental_requests_registration_kind` ON `rental_requests`(`registration_id`, `kind`)",
+				"CREATE INDEX `idx_rental_requests_item` ON `rental_requests`(`rental_item_id`)",
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			return execAll(tx,
+				"DROP TABLE IF EXISTS `rental_requests`",
+				"DROP TABLE IF EXISTS `rental_items`",
+			)
+		},
+	})
+}
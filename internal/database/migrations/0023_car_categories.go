@@ -0,0 +1,103 @@
+package migrations
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// carCategoryTables - таблицы, в которых категория машин хранится названием
+var carCategoryTables = []string{
+	"trainings", "training_series", "training_templates", "karts", "category_prices",
+	"promo_codes", "lap_records", "heat_results", "user_ratings",
+}
+
+// carCategoryMerges - как слить строки другого написания (o) со строкой основного
+// в таблицах с уникальным индексом по категории: у рейтинга остается больший
+// рейтинг и суммируются заезды, у цены - цена, измененная последней
+var carCategoryMerges = map[string]string{
+	"category_prices": "`price` = COALESCE((SELECT o.`price` FROM `category_prices` o WHERE %[1]s " +
+		"AND o.`updated_at` > COALESCE(`category_prices`.`updated_at`, '') ORDER BY o.`updated_at` DESC LIMIT 1), `price`)",
+	"user_ratings": "`rating` = MAX(`rating`, COALESCE((SELECT MAX(o.`rating`) FROM `user_ratings` o " +
+		"WHERE o.`user_id` = `user_ratings`.`user_id` AND %[1]s), `rating`)), " +
+		"`heats` = `heats` + COALESCE((SELECT SUM(o.`heats`) FROM `user_ratings` o " +
+		"WHERE o.`user_id` = `user_ratings`.`user_id` AND %[1]s), 0)",
+}
+
+// 0023 добавляет справочник категорий машин. Категории заполняются названиями,
+// которые уже встречаются в данных; написания, отличающиеся только регистром
+// или пробелами по краям, сводятся к самому частому. Откат удаляет справочник,
+// но объединенные написания не восстанавливает.
+func init() {
+	register(Migration{
+		Version: 23,
+		Name:    "car_categories",
+		Up: func(tx *gorm.DB) error {
+			if err := execAll(tx,
+				"CREATE TABLE `car_categories` (`id` integer PRIMARY KEY AUTOINCREMENT,`name` text NOT NULL,"+
+					"`description` text NOT NULL DEFAULT '',`min_age` integer NOT NULL DEFAULT 0,`license_level` text NOT NULL DEFAULT '',"+
+					"`created_at` datetime,`updated_at` datetime,`deleted_at` datetime)",
+				"CREATE UNIQUE INDEX `idx_car_categories_name` ON `car_categories`(`name` COLLATE NOCASE) WHERE `deleted_at` IS NULL",
+				"CREATE INDEX `idx_car_categories_deleted_at` ON `car_categories`(`deleted_at`)",
+			); err != nil {
+				return err
+			}
+			if err := seedCarCategories(tx); err != nil {
+				return err
+			}
+			return mergeCarCategorySpellings(tx)
+		},
+		Down: func(tx *gorm.DB) error {
+			return execAll(tx, "DROP TABLE IF EXISTS `car_categories`")
+		},
+	})
+}
+
+// seedCarCategories создает категорию для каждого названия из данных, кроме 'N/A'.
+// SQLite берет name из строки с MAX(uses), поэтому побеждает самое частое написание.
+func seedCarCategories(tx *gorm.DB) error {
+	usages := make([]string, 0, len(carCategoryTables))
+	for _, table := range carCategoryTables {
+		usages = append(usages, fmt.Sprintf("SELECT TRIM(`car_category`) AS name FROM `%s`", table))
+	}
+
+	return tx.Exec("INSERT INTO `car_categories` (`name`,`created_at`,`updated_at`) " +
+		"SELECT name, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP FROM (" +
+		"SELECT name, MAX(uses) FROM (SELECT name, COUNT(*) AS uses FROM (" + strings.Join(usages, " UNION ALL ") + ") " +
+		"WHERE name IS NOT NULL AND name <> '' AND name <> 'N/A' GROUP BY name) GROUP BY LOWER(name) ORDER BY name)").Error
+}
+
+// mergeCarCategorySpellings заменяет написания категорий на названия из справочника
+func mergeCarCategorySpellings(tx *gorm.DB) error {
+	const canonical = "(SELECT c.`name` FROM `car_categories` c WHERE c.`name` = TRIM(%[1]s.`car_category`) COLLATE NOCASE)"
+	const misspelled = "%[1]s.`car_category` NOT IN (SELECT `name` FROM `car_categories`) AND " + canonical + " IS NOT NULL"
+
+	for _, table := range carCategoryTables {
+		quoted := "`" + table + "`"
+		merge, unique := carCategoryMerges[table]
+
+		update := "UPDATE"
+		if unique {
+			update = "UPDATE OR IGNORE"
+		}
+		if err := tx.Exec(fmt.Sprintf(update+" %[1]s SET `car_category` = "+canonical+" WHERE "+misspelled, quoted)).Error; err != nil {
+			return err
+		}
+		if !unique {
+			continue
+		}
+
+		// Оставшиеся строки другого написания уже имеют пару с основным
+		// написанием: сливаем их в эту строку и удаляем
+		duplicate := fmt.Sprintf(misspelled+" AND "+canonical+" = %[2]s.`car_category`", "o", quoted)
+		if err := tx.Exec(fmt.Sprintf("UPDATE %s SET %s WHERE `car_category` IN (SELECT `name` FROM `car_categories`)",
+			quoted, fmt.Sprintf(merge, duplicate))).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec(fmt.Sprintf("DELETE FROM %[1]s WHERE "+misspelled, quoted)).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
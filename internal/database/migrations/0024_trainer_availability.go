@@ -0,0 +1,38 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// 0024 добавляет окна доступности тренеров и заявки на индивидуальные занятия.
+func init() {
+	register(Migration{
+		Version: 24,
+		Name:    "trainer_availability",
+		Up: func(tx *gorm.DB) error {
+			return execAll(tx,
+				"CREATE TABLE `trainer_availabilities` (`id` integer PRIMARY KEY AUTOINCREMENT,`trainer_id` integer NOT NULL,`track_id` integer NOT NULL,"+
+					"`start_time` datetime NOT NULL,`end_time` datetime NOT NULL,`created_at` datetime,`deleted_at` datetime,"+
+					"CONSTRAINT `fk_trainer_availabilities_trainer` FOREIGN KEY (`trainer_id`) REFERENCES `trainers`(`id`) ON DELETE CASCADE,"+
+					"CONSTRAINT `fk_trainer_availabilities_track` FOREIGN KEY (`track_id`) REFERENCES `tracks`(`id`) ON DELETE CASCADE)",
+				"CREATE INDEX `idx_trainer_availabilities_deleted_at` ON `trainer_availabilities`(`deleted_at`)",
+				"CREATE INDEX `idx_trainer_availabilities_track_time` ON `trainer_availabilities`(`track_id`, `end_time`)",
+				"CREATE INDEX `idx_trainer_availabilities_trainer_time` ON `trainer_availabilities`(`trainer_id`, `end_time`)",
+				"CREATE TABLE `session_requests` (`id` integer PRIMARY KEY AUTOINCREMENT,`user_id` integer NOT NULL,`availability_id` integer NOT NULL,"+
+					"`trainer_id` integer NOT NULL,`track_id` integer NOT NULL,`start_time` datetime NOT NULL,`end_time` datetime NOT NULL,"+
+					"`status` text NOT NULL DEFAULT 'pending',`training_id` integer,`created_at` datetime,`updated_at` datetime,"+
+					"CONSTRAINT `fk_session_requests_user` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`) ON DELETE CASCADE,"+
+					"CONSTRAINT `fk_session_requests_availability` FOREIGN KEY (`availability_id`) REFERENCES `trainer_availabilities`(`id`) ON DELETE CASCADE,"+
+					"CONSTRAINT `fk_session_requests_trainer` FOREIGN KEY (`trainer_id`) REFERENCES `trainers`(`id`) ON DELETE CASCADE,"+
+					"CONSTRAINT `fk_session_requests_track` FOREIGN KEY (`track_id`) REFERENCES `tracks`(`id`) ON DELETE CASCADE,"+
+					"CONSTRAINT `fk_session_requests_training` FOREIGN KEY (`training_id`) REFERENCES `trainings`(`id`) ON DELETE SET NULL)",
+				"CREATE INDEX `idx_session_requests_trainer_status` ON `session_requests`(`trainer_id`, `status`)",
+				"CREATE INDEX `idx_session_requests_user` ON `session_requests`(`user_id`)",
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			return execAll(tx,
+				"DROP TABLE IF EXISTS `session_requests`",
+				"DROP TABLE IF EXISTS `trainer_availabilities`",
+			)
+		},
+	})
+}
@@ -0,0 +1,34 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// 0025 добавляет уровень подготовки пользователей, требования к уровню
+// у тренировок и журнал повышений уровня.
+func init() {
+	register(Migration{
+		Version: 25,
+		Name:    "skill_levels",
+		Up: func(tx *gorm.DB) error {
+			return execAll(tx,
+				"ALTER TABLE `users` ADD COLUMN `skill_level` integer NOT NULL DEFAULT 1",
+				"ALTER TABLE `trainings` ADD COLUMN `min_skill_level` integer NOT NULL DEFAULT 0",
+				"ALTER TABLE `trainings` ADD COLUMN `max_skill_level` integer NOT NULL DEFAULT 0",
+				"CREATE TABLE `skill_level_changes` (`id` integer PRIMARY KEY AUTOINCREMENT,`user_id` integer NOT NULL,"+
+					"`from_level` integer NOT NULL,`to_level` integer NOT NULL,`trainer_id` integer NOT NULL,`registration_id` integer NOT NULL,"+
+					"`changed_by` integer NOT NULL DEFAULT 0,`created_at` datetime,"+
+					"CONSTRAINT `fk_skill_level_changes_user` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`) ON DELETE CASCADE,"+
+					"CONSTRAINT `fk_skill_level_changes_registration` FOREIGN KEY (`registration_id`) REFERENCES `training_registrations`(`id`) ON DELETE CASCADE)",
+				"CREATE INDEX `idx_skill_level_changes_user_id` ON `skill_level_changes`(`user_id`)",
+				"CREATE UNIQUE INDEX `idx_skill_level_changes_registration` ON `skill_level_changes`(`registration_id`)",
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			return execAll(tx,
+				"DROP TABLE IF EXISTS `skill_level_changes`",
+				"ALTER TABLE `trainings` DROP COLUMN `max_skill_level`",
+				"ALTER TABLE `trainings` DROP COLUMN `min_skill_level`",
+				"ALTER TABLE `users` DROP COLUMN `skill_level`",
+			)
+		},
+	})
+}
@@ -0,0 +1,39 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// 0026 добавляет профили участников под аккаунтом пользователя и запись
+// на тренировку за участника. Уникальность записи теперь проверяется
+// по паре пользователь + участник.
+func init() {
+	register(Migration{
+		Version: 26,
+		Name:    "participants",
+		Up: func(tx *gorm.DB) error {
+			return execAll(tx,
+				"CREATE TABLE `participants` (`id` integer PRIMARY KEY AUTOINCREMENT,`user_id` integer NOT NULL,`name` text NOT NULL,"+
+					"`birth_date` datetime NOT NULL,`skill_level` integer NOT NULL DEFAULT 1,`guardian_consent_at` datetime NOT NULL,"+
+					"`created_at` datetime,`updated_at` datetime,`deleted_at` datetime,"+
+					"CONSTRAINT `fk_participants_user` FOREIGN KEY (`user_id`) REFERENCES `users`(`id`) ON DELETE CASCADE)",
+				"CREATE INDEX `idx_participants_user_id` ON `participants`(`user_id`)",
+				"CREATE INDEX `idx_participants_deleted_at` ON `participants`(`deleted_at`)",
+				"ALTER TABLE `training_registrations` ADD COLUMN `participant_id` integer NOT NULL DEFAULT 0",
+				"DROP INDEX IF EXISTS `idx_training_registrations_training_user`",
+				"CREATE UNIQUE INDEX `idx_training_registrations_training_user` ON `training_registrations`(`training_id`, `user_id`, `participant_id`)",
+				"ALTER TABLE `skill_level_changes` ADD COLUMN `participant_id` integer NOT NULL DEFAULT 0",
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			// Записи участников не помещаются в прежний уникальный индекс и удаляются
+			return execAll(tx,
+				"DELETE FROM `skill_level_changes` WHERE `participant_id` <> 0",
+				"ALTER TABLE `skill_level_changes` DROP COLUMN `participant_id`",
+				"DELETE FROM `training_registrations` WHERE `participant_id` <> 0",
+				"DROP INDEX IF EXISTS `idx_training_registrations_training_user`",
+				"ALTER TABLE `training_registrations` DROP COLUMN `participant_id`",
+				"CREATE UNIQUE INDEX `idx_training_registrations_training_user` ON `training_registrations`(`training_id`, `user_id`)",
+				"DROP TABLE IF EXISTS `participants`",
+			)
+		},
+	})
+}
@@ -0,0 +1,42 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// 0027 ведет рейтинги и результаты заездов отдельно для участников под
+// аккаунтом: участник входит в уникальные индексы, а общий рейтинг участника
+// хранится в его профиле.
+func init() {
+	register(Migration{
+		Version: 27,
+		Name:    "participant_ratings",
+		Up: func(tx *gorm.DB) error {
+			return execAll(tx,
+				"ALTER TABLE `participants` ADD COLUMN `elo_rating` integer NOT NULL DEFAULT 1000",
+				"ALTER TABLE `user_ratings` ADD COLUMN `participant_id` integer NOT NULL DEFAULT 0",
+				"DROP INDEX IF EXISTS `idx_user_ratings_user_category`",
+				"CREATE UNIQUE INDEX `idx_user_ratings_user_category` ON `user_ratings`(`user_id`,`participant_id`,`car_category`)",
+				"ALTER TABLE `heat_results` ADD COLUMN `participant_id` integer NOT NULL DEFAULT 0",
+				"DROP INDEX IF EXISTS `idx_heat_results_training_heat_user`",
+				"CREATE UNIQUE INDEX `idx_heat_results_training_heat_user` ON `heat_results`(`training_id`,`heat_number`,`user_id`,`participant_id`)",
+				"DROP INDEX IF EXISTS `idx_heat_results_user_category`",
+				"CREATE INDEX `idx_heat_results_user_category` ON `heat_results`(`user_id`,`participant_id`,`car_category`)",
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			// Результаты участников не помещаются в прежние уникальные индексы и удаляются
+			return execAll(tx,
+				"DELETE FROM `heat_results` WHERE `participant_id` <> 0",
+				"DROP INDEX IF EXISTS `idx_heat_results_user_category`",
+				"DROP INDEX IF EXISTS `idx_heat_results_training_heat_user`",
+				"ALTER TABLE `heat_results` DROP COLUMN `participant_id`",
+				"CREATE UNIQUE INDEX `idx_heat_results_training_heat_user` ON `heat_results`(`training_id`,`heat_number`,`user_id`)",
+				"CREATE INDEX `idx_heat_results_user_category` ON `heat_results`(`user_id`,`car_category`)",
+				"DELETE FROM `user_ratings` WHERE `participant_id` <> 0",
+				"DROP INDEX IF EXISTS `idx_user_ratings_user_category`",
+				"ALTER TABLE `user_ratings` DROP COLUMN `participant_id`",
+				"CREATE UNIQUE INDEX `idx_user_ratings_user_category` ON `user_ratings`(`user_id`,`car_category`)",
+				"ALTER TABLE `participants` DROP COLUMN `elo_rating`",
+			)
+		},
+	})
+}
@@ -0,0 +1,32 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// 0028 хранит время кругов отдельно для участников под аккаунтом: участник
+// входит в нумерацию кругов тренировки и в личные рекорды.
+func init() {
+	register(Migration{
+		Version: 28,
+		Name:    "participant_laps",
+		Up: func(tx *gorm.DB) error {
+			return execAll(tx,
+				"ALTER TABLE `lap_records` ADD COLUMN `participant_id` integer NOT NULL DEFAULT 0",
+				"DROP INDEX IF EXISTS `idx_lap_records_training_user_lap`",
+				"CREATE UNIQUE INDEX `idx_lap_records_training_user_lap` ON `lap_records`(`training_id`,`user_id`,`participant_id`,`lap_number`)",
+				"DROP INDEX IF EXISTS `idx_lap_records_user_track`",
+				"CREATE INDEX `idx_lap_records_user_track` ON `lap_records`(`user_id`,`participant_id`,`track_id`)",
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			// Круги участников не помещаются в прежний уникальный индекс и удаляются
+			return execAll(tx,
+				"DELETE FROM `lap_records` WHERE `participant_id` <> 0",
+				"DROP INDEX IF EXISTS `idx_lap_records_user_track`",
+				"DROP INDEX IF EXISTS `idx_lap_records_training_user_lap`",
+				"ALTER TABLE `lap_records` DROP COLUMN `participant_id`",
+				"CREATE UNIQUE INDEX `idx_lap_records_training_user_lap` ON `lap_records`(`training_id`,`user_id`,`lap_number`)",
+				"CREATE INDEX `idx_lap_records_user_track` ON `lap_records`(`user_id`,`track_id`)",
+			)
+		},
+	})
+}
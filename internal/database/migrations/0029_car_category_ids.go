@@ -0,0 +1,125 @@
+package migrations
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// carCategoryNoneNames - значение прежней текстовой колонки для строк без
+// категории при откате: промокод без категории действует для любой
+var carCategoryNoneNames = map[string]string{"promo_codes": ""}
+
+// carCategoryColumns - определение прежней текстовой колонки при откате.
+// SQLite не добавляет колонку NOT NULL без значения по умолчанию, поэтому
+// у таких колонок появляется DEFAULT ”.
+var carCategoryColumns = map[string]string{
+	"trainings":          "text DEFAULT 'N/A'",
+	"training_series":    "text DEFAULT 'N/A'",
+	"training_templates": "text DEFAULT 'N/A'",
+	"karts":              "text NOT NULL DEFAULT ''",
+	"category_prices":    "text NOT NULL DEFAULT ''",
+	"promo_codes":        "text NOT NULL DEFAULT ''",
+	"lap_records":        "text",
+	"heat_results":       "text NOT NULL DEFAULT ''",
+	"user_ratings":       "text NOT NULL DEFAULT ''",
+}
+
+// 0029 связывает тренировки, цены, карты, промокоды, круги и рейтинги
+// с категорией по ID вместо названия, чтобы переименование меняло одну строку
+// справочника. NULL - без категории (N/A). Названия, которых нет в
+// справочнике, добавляются в него удаленными категориями, чтобы история
+// сохранила их.
+func init() {
+	register(Migration{
+		Version: 29,
+		Name:    "car_category_ids",
+		Up: func(tx *gorm.DB) error {
+			if err := archiveUnknownCarCategories(tx); err != nil {
+				return err
+			}
+
+			for _, table := range carCategoryTables {
+				if err := execAll(tx,
+					fmt.Sprintf("ALTER TABLE `%s` ADD COLUMN `car_category_id` integer REFERENCES `car_categories`(`id`) ON DELETE RESTRICT", table),
+					// Из одноименных категорий берется действующая, иначе удаленная последней
+					fmt.Sprintf("UPDATE `%[1]s` SET `car_category_id` = (SELECT c.`id` FROM `car_categories` c "+
+						"WHERE c.`name` = TRIM(`%[1]s`.`car_category`) COLLATE NOCASE ORDER BY c.`deleted_at` IS NOT NULL, c.`id` DESC LIMIT 1)", table),
+				); err != nil {
+					return err
+				}
+			}
+
+			return execAll(tx,
+				"DROP INDEX IF EXISTS `idx_user_ratings_user_category`",
+				"DROP INDEX IF EXISTS `idx_user_ratings_category_rating`",
+				"DROP INDEX IF EXISTS `idx_heat_results_user_category`",
+				"DROP INDEX IF EXISTS `idx_category_prices_car_category`",
+				"ALTER TABLE `trainings` DROP COLUMN `car_category`",
+				"ALTER TABLE `training_series` DROP COLUMN `car_category`",
+				"ALTER TABLE `training_templates` DROP COLUMN `car_category`",
+				"ALTER TABLE `karts` DROP COLUMN `car_category`",
+				"ALTER TABLE `category_prices` DROP COLUMN `car_category`",
+				"ALTER TABLE `promo_codes` DROP COLUMN `car_category`",
+				"ALTER TABLE `lap_records` DROP COLUMN `car_category`",
+				"ALTER TABLE `heat_results` DROP COLUMN `car_category`",
+				"ALTER TABLE `user_ratings` DROP COLUMN `car_category`",
+				// Рейтинг без категории тоже должен быть единственным, а NULL в уникальном индексе не совпадают
+				"CREATE UNIQUE INDEX `idx_user_ratings_user_category` ON `user_ratings`(`user_id`,`participant_id`,IFNULL(`car_category_id`, 0))",
+				"CREATE INDEX `idx_user_ratings_category_rating` ON `user_ratings`(`car_category_id`,`rating`)",
+				"CREATE INDEX `idx_heat_results_user_category` ON `heat_results`(`user_id`,`participant_id`,`car_category_id`)",
+				"CREATE UNIQUE INDEX `idx_category_prices_car_category` ON `category_prices`(`car_category_id`)",
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			for _, table := range carCategoryTables {
+				none, ok := carCategoryNoneNames[table]
+				if !ok {
+					none = "N/A"
+				}
+				if err := execAll(tx,
+					fmt.Sprintf("ALTER TABLE `%s` ADD COLUMN `car_category` %s", table, carCategoryColumns[table]),
+					fmt.Sprintf("UPDATE `%[1]s` SET `car_category` = COALESCE((SELECT c.`name` FROM `car_categories` c "+
+						"WHERE c.`id` = `%[1]s`.`car_category_id`), '%[2]s')", table, none),
+				); err != nil {
+					return err
+				}
+			}
+
+			return execAll(tx,
+				"DROP INDEX IF EXISTS `idx_user_ratings_user_category`",
+				"DROP INDEX IF EXISTS `idx_user_ratings_category_rating`",
+				"DROP INDEX IF EXISTS `idx_heat_results_user_category`",
+				"DROP INDEX IF EXISTS `idx_category_prices_car_category`",
+				"ALTER TABLE `trainings` DROP COLUMN `car_category_id`",
+				"ALTER TABLE `training_series` DROP COLUMN `car_category_id`",
+				"ALTER TABLE `training_templates` DROP COLUMN `car_category_id`",
+				"ALTER TABLE `karts` DROP COLUMN `car_category_id`",
+				"ALTER TABLE `category_prices` DROP COLUMN `car_category_id`",
+				"ALTER TABLE `promo_codes` DROP COLUMN `car_category_id`",
+				"ALTER TABLE `lap_records` DROP COLUMN `car_category_id`",
+				"ALTER TABLE `heat_results` DROP COLUMN `car_category_id`",
+				"ALTER TABLE `user_ratings` DROP COLUMN `car_category_id`",
+				"CREATE UNIQUE INDEX `idx_user_ratings_user_category` ON `user_ratings`(`user_id`,`participant_id`,`car_category`)",
+				"CREATE INDEX `idx_user_ratings_category_rating` ON `user_ratings`(`car_category`,`rating`)",
+				"CREATE INDEX `idx_heat_results_user_category` ON `heat_results`(`user_id`,`participant_id`,`car_category`)",
+				"CREATE UNIQUE INDEX `idx_category_prices_car_category` ON `category_prices`(`car_category`)",
+			)
+		},
+	})
+}
+
+// archiveUnknownCarCategories добавляет удаленными категориями названия из
+// данных, которых нет в справочнике, кроме 'N/A'
+func archiveUnknownCarCategories(tx *gorm.DB) error {
+	usages := make([]string, 0, len(carCategoryTables))
+	for _, table := range carCategoryTables {
+		usages = append(usages, fmt.Sprintf("SELECT TRIM(`car_category`) AS name FROM `%s`", table))
+	}
+
+	return tx.Exec("INSERT INTO `car_categories` (`name`,`created_at`,`updated_at`,`deleted_at`) " +
+		"SELECT MIN(u.name), CURRENT_TIMESTAMP, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP FROM (" + strings.Join(usages, " UNION ALL ") + ") u " +
+		"WHERE u.name IS NOT NULL AND u.name <> '' AND u.name <> 'N/A' " +
+		"AND NOT EXISTS (SELECT 1 FROM `car_categories` c WHERE c.`name` = u.name COLLATE NOCASE) GROUP BY LOWER(u.name)").Error
+}
@@ -0,0 +1,193 @@
+package migrations
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"x.localhost/rvabot/internal/logger"
+)
+
+// Migration описывает одно версионированное изменение схемы
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// MigrationStatus - состояние миграции для команды status
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+}
+
+// schemaMigration - запись о примененной миграции
+type schemaMigration struct {
+	Version   int `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// registry - все известные миграции, заполняется из файлов NNNN_*.go
+var registry []Migration
+
+// register добавляет миграцию в реестр
+func register(m Migration) {
+	registry = append(registry, m)
+}
+
+// All возвращает миграции, упорядоченные по версии
+func All() []Migration {
+	sorted := make([]Migration, len(registry))
+	copy(sorted, registry)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	return sorted
+}
+
+// Runner применяет и откатывает миграции
+type Runner struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+// NewRunner создает раннер для всех зарегистрированных миграций
+func NewRunner(db *gorm.DB) *Runner {
+	return &Runner{db: db, migrations: All()}
+}
+
+// ensureTable создает таблицу schema_migrations при необходимости
+func (r *Runner) ensureTable() error {
+	return r.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at DATETIME NOT NULL
+	)`).Error
+}
+
+// applied возвращает примененные миграции по версиям
+func (r *Runner) applied() (map[int]schemaMigration, error) {
+	if err := r.ensureTable(); err != nil {
+		return nil, fmt.Errorf("ошибка создания schema_migrations: %w", err)
+	}
+
+	var rows []schemaMigration
+	if err := r.db.Order("version").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("ошибка чтения schema_migrations: %w", err)
+	}
+
+	result := make(map[int]schemaMigration, len(rows))
+	for _, row := range rows {
+		result[row.Version] = row
+	}
+	return result, nil
+}
+
+// Up применяет все непримененные миграции по порядку
+func (r *Runner) Up() error {
+	done, err := r.applied()
+	if err != nil {
+		return err
+	}
+
+	for _, m := range r.migrations {
+		if _, ok := done[m.Version]; ok {
+			continue
+		}
+
+		err := r.db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&schemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now().UTC()}).Error
+		})
+		if err != nil {
+			logger.DatabaseError("Миграция %04d_%s не применена: %v", m.Version, m.Name, err)
+			return fmt.Errorf("миграция %04d_%s: %w", m.Version, m.Name, err)
+		}
+
+		logger.DatabaseInfo("Миграция применена: %04d_%s", m.Version, m.Name)
+	}
+
+	return nil
+}
+
+// Down откатывает последние steps примененных миграций
+func (r *Runner) Down(steps int) error {
+	done, err := r.applied()
+	if err != nil {
+		return err
+	}
+
+	for i := len(r.migrations) - 1; i >= 0 && steps > 0; i-- {
+		m := r.migrations[i]
+		if _, ok := done[m.Version]; !ok {
+			continue
+		}
+
+		if m.Down == nil {
+			return fmt.Errorf("миграция %04d_%s не поддерживает откат", m.Version, m.Name)
+		}
+
+		err := r.db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&schemaMigration{}, m.Version).Error
+		})
+		if err != nil {
+			logger.DatabaseError("Откат миграции %04d_%s не выполнен: %v", m.Version, m.Name, err)
+			return fmt.Errorf("откат %04d_%s: %w", m.Version, m.Name, err)
+		}
+
+		logger.DatabaseInfo("Миграция откачена: %04d_%s", m.Version, m.Name)
+		steps--
+	}
+
+	return nil
+}
+
+// Status возвращает состояние всех известных миграций
+func (r *Runner) Status() ([]MigrationStatus, error) {
+	done, err := r.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(r.migrations))
+	for _, m := range r.migrations {
+		status := MigrationStatus{Version: m.Version, Name: m.Name}
+		if row, ok := done[m.Version]; ok {
+			appliedAt := row.AppliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// hasColumn проверяет наличие колонки в таблице SQLite
+func hasColumn(tx *gorm.DB, table, column string) (bool, error) {
+	var count int64
+	err := tx.Raw("SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?", table, column).Scan(&count).Error
+	return count > 0, err
+}
+
+// execAll выполняет SQL-выражения по порядку
+func execAll(tx *gorm.DB, statements ...string) error {
+	for _, stmt := range statements {
+		if err := tx.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -2,6 +2,61 @@ package database
 
 import (
 	"time"
+
+	"gorm.io/gorm"
+)
+
+// Статусы записи на тренировку
+const (
+	RegistrationStatusPending   = "pending"
+	RegistrationStatusConfirmed = "confirmed"
+	RegistrationStatusRejected  = "rejected"
+	RegistrationStatusCancelled = "cancelled"
+	// RegistrationStatusWaitlisted - пользователь стоит в листе ожидания
+	RegistrationStatusWaitlisted = "waitlisted"
+	// RegistrationStatusOffered - место предложено из листа ожидания и ждет подтверждения
+	RegistrationStatusOffered = "offered"
+	// RegistrationStatusAttended - участник был на тренировке, отмечается тренером после занятия
+	RegistrationStatusAttended = "attended"
+	// RegistrationStatusNoShow - подтвержденный участник не пришел на тренировку
+	RegistrationStatusNoShow = "no_show"
+)
+
+// Статусы оплаты записи
+const (
+	PaymentStatusUnpaid   = "unpaid"
+	PaymentStatusPaid     = "paid"
+	PaymentStatusRefunded = "refunded"
+)
+
+// Способы оплаты записи
+const (
+	PaymentMethodCash     = "cash"
+	PaymentMethodTransfer = "transfer"
+	PaymentMethodCredit   = "credit" // кредит из пакета тренировок
+)
+
+// Статусы карта
+const (
+	KartStatusOK          = "ok"
+	KartStatusMaintenance = "maintenance"
+)
+
+// CarCategoryNone - категория тренировки, для которой машины не указаны
+const CarCategoryNone = "N/A"
+
+// Виды прокатной экипировки
+const (
+	RentalKindHelmet = "helmet"
+	RentalKindSuit   = "suit"
+	RentalKindGloves = "gloves"
+)
+
+// Статусы запроса экипировки
+const (
+	RentalStatusRequested   = "requested" // ждет подтверждения записи тренером
+	RentalStatusReserved    = "reserved"
+	RentalStatusUnavailable = "unavailable" // при подтверждении записи размера не осталось
 )
 
 type Trainer struct {
@@ -12,6 +67,7 @@ type Trainer struct {
 	Info      string
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
 }
 
 type Admin struct {
@@ -25,21 +81,74 @@ type Admin struct {
 }
 
 type User struct {
-	ID        uint `gorm:"primaryKey"`
-	Name      string
-	TgId      string
-	ChatId    int `gorm:"uniqueIndex"`
-	IsActive  bool
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	ID         uint `gorm:"primaryKey"`
+	Name       string
+	TgId       string
+	ChatId     int `gorm:"uniqueIndex"`
+	IsActive   bool
+	EloRating  int    `gorm:"not null;default:1000"`
+	SkillLevel int    `gorm:"not null;default:1"`  // уровень подготовки, см. SkillLevel*
+	Timezone   string `gorm:"not null;default:''"` // IANA-имя; пусто - время показывается по трассе
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// Уровни подготовки пилота, от новичка до продвинутого
+const (
+	SkillLevelBeginner     = 1
+	SkillLevelIntermediate = 2
+	SkillLevelAdvanced     = 3
+)
+
+// SkillLevelChange - запись журнала повышений уровня пилота тренером
+// после посещенной тренировки
+type SkillLevelChange struct {
+	ID             uint `gorm:"primaryKey"`
+	UserID         uint `gorm:"index"`
+	FromLevel      int
+	ToLevel        int
+	TrainerID      uint // тренер посещенной тренировки
+	RegistrationID uint // запись, по которой повышен уровень
+	ParticipantID  uint // повышенный профиль участника; 0 - сам пользователь
+	ChangedBy      int  // чат тренера или администратора, повысившего уровень
+	CreatedAt      time.Time
+}
+
+// Participant - профиль участника под аккаунтом пользователя, например
+// ребенка, которого записывает родитель. Сообщения о записях участника
+// приходят в чат пользователя-представителя.
+type Participant struct {
+	ID         uint `gorm:"primaryKey"`
+	UserID     uint `gorm:"index"` // представитель
+	Name       string
+	BirthDate  time.Time // календарная дата полночью UTC
+	SkillLevel int       `gorm:"not null;default:1"`
+	EloRating  int       `gorm:"not null;default:1000"` // общий рейтинг участника по заездам
+	// GuardianConsentAt - когда представитель подтвердил согласие на участие
+	// и обработку данных участника
+	GuardianConsentAt time.Time
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	DeletedAt         gorm.DeletedAt `gorm:"index"`
 }
 
 type Track struct {
 	ID        uint `gorm:"primaryKey"`
 	Name      string
 	Info      string
+	Timezone  string `gorm:"not null;default:''"` // IANA-имя; пусто - пояс академии
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+// TrackClosure - день, когда трасса закрыта и тренировки на ней не проводятся
+type TrackClosure struct {
+	ID        uint `gorm:"primaryKey"`
+	TrackID   uint
+	Date      time.Time // календарная дата в поясе трассы, хранится полночью UTC
+	Reason    string
+	CreatedAt time.Time
 }
 
 type Training struct {
@@ -49,19 +158,295 @@ type Training struct {
 	StartTime       time.Time
 	EndTime         time.Time
 	MaxParticipants int
-	CarCategory     string `gorm:"type:text;default:N/A"`
+	CarCategoryID   *uint // категория из справочника; nil - без категории (N/A)
+	Price           int   `gorm:"not null;default:0"` // стоимость в рублях; 0 - по цене категории
+	MinSkillLevel   int   `gorm:"not null;default:0"` // минимальный уровень участника; 0 - без ограничения
+	MaxSkillLevel   int   `gorm:"not null;default:0"` // максимальный уровень участника; 0 - без ограничения
 	IsActive        bool
+	SeriesID        *uint `gorm:"index"`
+	// AttendanceRequestedAt - когда тренеру отправлен список для отметки посещаемости
+	AttendanceRequestedAt *time.Time
+	CreatedAt             time.Time
+	UpdatedAt             time.Time
+	DeletedAt             gorm.DeletedAt `gorm:"index"`
+}
+
+// TrainingSeries - повторяющаяся еженедельная тренировка; занятия серии
+// хранятся отдельными строками Training со ссылкой SeriesID
+type TrainingSeries struct {
+	ID              uint `gorm:"primaryKey"`
+	TrainerID       uint
+	TrackID         uint
+	Weekdays        string `gorm:"not null"` // дни недели через запятую, 0 - воскресенье
+	FirstStart      time.Time
+	DurationMinutes int
+	Until           *time.Time
+	Occurrences     int `gorm:"not null;default:0"`
+	MaxParticipants int
+	CarCategoryID   *uint // nil - без категории (N/A)
 	CreatedAt       time.Time
 	UpdatedAt       time.Time
+	DeletedAt       gorm.DeletedAt `gorm:"index"`
+}
+
+// TrainingTemplate - сохраненные параметры тренировки, из которых
+// новое занятие создается вводом одной даты
+type TrainingTemplate struct {
+	ID              uint   `gorm:"primaryKey"`
+	Name            string `gorm:"not null"`
+	TrainerID       uint
+	TrackID         uint
+	TimeOfDay       string // время начала в формате 15:04
+	DurationMinutes int
+	MaxParticipants int
+	CarCategoryID   *uint // nil - без категории (N/A)
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	DeletedAt       gorm.DeletedAt `gorm:"index"`
 }
 
 type TrainingRegistration struct {
-	ID         uint `gorm:"primaryKey"`
-	TrainingID uint
-	UserID     uint
-	Status     string
+	ID               uint `gorm:"primaryKey"`
+	TrainingID       uint
+	UserID           uint
+	Status           string
+	WaitlistPosition int
+	OfferExpiresAt   *time.Time
+	CancelledAt      *time.Time
+	LateCancellation bool `gorm:"not null;default:false"`
+	// Оплата отмечается администратором или тренером; при оплате кредитом - при одобрении
+	PaymentStatus string `gorm:"not null;default:unpaid"`
+	PaymentMethod string
+	PaymentAmount int // сумма в рублях
+	PaidAt        *time.Time
+	// Промокод, примененный при записи, и скидка в рублях по цене на момент записи
+	PromoCodeID    *uint
+	DiscountAmount int `gorm:"not null;default:0"`
+	// KartID - карт, назначенный участнику тренером
+	KartID *uint
+	// ParticipantID - профиль участника, за которого записался пользователь;
+	// 0 - пользователь записался сам
+	ParticipantID uint `gorm:"not null;default:0"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// Kart - карт парка академии. Исправные карты трассы и категории
+// ограничивают число мест на тренировке.
+type Kart struct {
+	ID            uint `gorm:"primaryKey"`
+	TrackID       uint
+	CarCategoryID *uint
+	Number        string // бортовой номер, уникален в пределах трассы
+	Status        string `gorm:"not null;default:ok"`
+	// ServicedAt - последнее обслуживание; наработка до ТО считается от него
+	ServicedAt *time.Time
 	CreatedAt  time.Time
 	UpdatedAt  time.Time
+	DeletedAt  gorm.DeletedAt `gorm:"index"`
+}
+
+// RentalItem - прокатная экипировка одного вида и размера на трассе
+type RentalItem struct {
+	ID        uint `gorm:"primaryKey"`
+	TrackID   uint
+	Kind      string
+	Size      string
+	Quantity  int // сколько штук есть на трассе
+	UpdatedAt time.Time
+}
+
+// RentalRequest - экипировка, которую участник берет в прокат на тренировку.
+// На одну запись приходится не больше одного предмета каждого вида.
+type RentalRequest struct {
+	ID             uint `gorm:"primaryKey"`
+	RegistrationID uint
+	RentalItemID   uint
+	Kind           string
+	Status         string `gorm:"not null;default:requested"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// TrainerAvailability - окно, в которое тренер готов провести индивидуальное
+// занятие на трассе. Окно не занимает тренера: занятие появляется в расписании
+// только после того, как тренер примет заявку.
+type TrainerAvailability struct {
+	ID        uint `gorm:"primaryKey"`
+	TrainerID uint
+	TrackID   uint
+	StartTime time.Time
+	EndTime   time.Time
+	CreatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+// Статусы заявки на индивидуальное занятие
+const (
+	SessionRequestStatusPending  = "pending"
+	SessionRequestStatusAccepted = "accepted"
+	SessionRequestStatusDeclined = "declined"
+)
+
+// SessionRequest - заявка пользователя на индивидуальное занятие в окне тренера
+// с предложенным временем. При принятии создается тренировка на одного участника.
+type SessionRequest struct {
+	ID             uint `gorm:"primaryKey"`
+	UserID         uint
+	AvailabilityID uint
+	TrainerID      uint
+	TrackID        uint
+	StartTime      time.Time
+	EndTime        time.Time
+	Status         string `gorm:"not null;default:pending"`
+	TrainingID     *uint  // тренировка, созданная при принятии заявки
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// CarCategory - категория машин из справочника. Тренировки, цены, парк
+// картов, промокоды и рейтинги ссылаются на нее по ID. Лицензия носит
+// справочный характер: бот показывает ее пилоту, а проверяет тренер на трассе.
+type CarCategory struct {
+	ID           uint   `gorm:"primaryKey"`
+	Name         string `gorm:"not null"`
+	Description  string `gorm:"not null;default:''"`
+	MinAge       int    `gorm:"not null;default:0"`  // минимальный возраст пилота; 0 - без ограничения
+	LicenseLevel string `gorm:"not null;default:''"` // требуемая лицензия для сведения; пусто - не нужна
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	DeletedAt    gorm.DeletedAt `gorm:"index"`
+}
+
+// CategoryPrice - цена тренировки по умолчанию для категории машин
+type CategoryPrice struct {
+	ID            uint  `gorm:"primaryKey"`
+	CarCategoryID *uint `gorm:"uniqueIndex"`
+	Price         int   // в рублях
+	UpdatedAt     time.Time
+}
+
+// LapRecord - время одного круга участника, введенное тренером после тренировки
+type LapRecord struct {
+	ID            uint `gorm:"primaryKey"`
+	UserID        uint
+	ParticipantID uint `gorm:"not null;default:0"` // 0 - сам пользователь
+	TrainingID    uint
+	TrackID       uint
+	LapNumber     int
+	LapTimeMs     int    // время круга в миллисекундах
+	SectorsMs     string `gorm:"not null;default:''"` // времена секторов в миллисекундах через запятую
+	CarCategoryID *uint
+	CreatedAt     time.Time
+}
+
+// UserRating - рейтинг Эло пользователя в категории карта
+type UserRating struct {
+	ID            uint `gorm:"primaryKey"`
+	UserID        uint
+	ParticipantID uint  `gorm:"not null;default:0"` // 0 - сам пользователь
+	CarCategoryID *uint // nil - тренировки без категории
+	Rating        int
+	Heats         int // число заездов, по которым рассчитан рейтинг
+	UpdatedAt     time.Time
+}
+
+// HeatResult - место участника в заезде на тренировке и изменение его рейтинга.
+// Записи заездов образуют историю рейтинга пользователя.
+type HeatResult struct {
+	ID            uint `gorm:"primaryKey"`
+	TrainingID    uint
+	HeatNumber    int
+	UserID        uint
+	ParticipantID uint `gorm:"not null;default:0"` // 0 - сам пользователь
+	CarCategoryID *uint
+	Position      int
+	Participants  int
+	RatingBefore  int
+	RatingAfter   int
+	CreatedAt     time.Time
+}
+
+// TrainingFeedback - разбор тренировки тренером для участника
+type TrainingFeedback struct {
+	ID             uint   `gorm:"primaryKey"`
+	RegistrationID uint   `gorm:"uniqueIndex"`
+	Strengths      string // что получилось
+	Improvements   string // над чем работать
+	VideoURL       string
+	DeliveredAt    *time.Time // когда разбор отправлен участнику
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// TrainingSurvey - опрос участника о тренировке: оценка от 1 до 5 и комментарий
+type TrainingSurvey struct {
+	ID             uint `gorm:"primaryKey"`
+	RegistrationID uint `gorm:"uniqueIndex"`
+	TrainingID     uint
+	TrainerID      uint
+	TrackID        uint
+	UserID         uint
+	Rating         int // 0 - участник еще не ответил
+	Comment        string
+	AnsweredAt     *time.Time
+	CreatedAt      time.Time // когда опрос отправлен
+}
+
+// Package - пакет тренировок, который администратор продает участникам
+type Package struct {
+	ID        uint `gorm:"primaryKey"`
+	Name      string
+	Credits   int  // сколько тренировок в пакете
+	IsActive  bool `gorm:"not null;default:true"` // архивные пакеты не продаются
+	CreatedAt time.Time
+}
+
+// Виды операций в журнале кредитов
+const (
+	CreditKindPurchase = "purchase" // покупка пакета
+	CreditKindGrant    = "grant"    // начисление администратором без пакета
+	CreditKindReserve  = "reserve"  // списание в резерв при записи на тренировку
+	CreditKindConsume  = "consume"  // кредит израсходован: запись одобрена тренером
+	CreditKindRefund   = "refund"   // кредит возвращен: отказ тренера, своевременная отмена или отмена тренировки
+	CreditKindForfeit  = "forfeit"  // кредит сгорел при поздней отмене
+)
+
+// CreditLedger - запись журнала кредитов пользователя. Журнал только дополняется;
+// Balance хранит остаток после операции, поэтому историю можно сверить по строкам.
+type CreditLedger struct {
+	ID             uint `gorm:"primaryKey"`
+	UserID         uint `gorm:"index"`
+	Kind           string
+	Delta          int
+	Balance        int
+	PackageID      *uint
+	RegistrationID *uint `gorm:"index"`
+	AdminChatId    int   // кто продал или начислил кредиты
+	Note           string
+	CreatedAt      time.Time
+}
+
+// Виды скидки промокода
+const (
+	DiscountTypePercent = "percent"
+	DiscountTypeFixed   = "fixed"
+)
+
+// PromoCode - промокод на скидку при записи на тренировку. Использованием
+// считается запись, которая не отменена и не отклонена.
+type PromoCode struct {
+	ID            uint   `gorm:"primaryKey"`
+	Code          string `gorm:"uniqueIndex"` // хранится в верхнем регистре
+	DiscountType  string
+	DiscountValue int        // процент или рубли
+	ValidFrom     *time.Time // nil - без ограничения
+	ValidUntil    *time.Time // не включительно; nil - без ограничения
+	MaxUses       int        // 0 - без ограничения
+	TrackID       *uint      // nil - любая трасса
+	CarCategoryID *uint      // nil - любая категория
+	IsActive      bool       `gorm:"not null;default:true"`
+	CreatedAt     time.Time
 }
 
 type TrainingRequest struct {
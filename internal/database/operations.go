@@ -3,8 +3,10 @@ package database
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
+	apperrors "x.localhost/rvabot/internal/errors"
 	"x.localhost/rvabot/internal/logger"
 
 	"gorm.io/gorm"
@@ -84,18 +86,46 @@ func (r *ContentRepository) UpdateTraining(id uint, training *Training) error {
 	return nil
 }
 
-func (r *ContentRepository) DeleteTraining(id uint) error {
+// DeleteTraining архивирует тренировку и отменяет активные записи на нее.
+// Возвращает отмененные записи, чтобы участников можно было уведомить.
+func (r *ContentRepository) DeleteTraining(id uint) ([]TrainingRegistration, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	result := r.db.WithContext(ctx).Delete(&Training{}, id)
-	if result.Error != nil {
-		logger.DatabaseError("Удаление тренировки %d: %v", id, result.Error)
-		return result.Error
+	var cancelled []TrainingRegistration
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("training_id = ? AND status IN ?", id, openRegistrationStatuses).Find(&cancelled).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&TrainingRegistration{}).
+			Where("training_id = ? AND status IN ?", id, openRegistrationStatuses).
+			Updates(map[string]interface{}{"status": RegistrationStatusCancelled, "cancelled_at": time.Now().UTC()}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&Training{}).Where("id = ?", id).Update("is_active", false).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(&Training{}, id).Error
+	})
+	if err != nil {
+		logger.DatabaseError("Удаление тренировки %d: %v", id, err)
+		return nil, err
 	}
 
-	logger.DatabaseInfo("Тренировка удалена: %d", id)
-	return nil
+	logger.DatabaseInfo("Тренировка архивирована: %d, отменено записей: %d", id, len(cancelled))
+	return cancelled, nil
+}
+
+// countFutureTrainings считает будущие активные тренировки по условию column = id
+func (r *ContentRepository) countFutureTrainings(ctx context.Context, column string, id uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&Training{}).
+		Where(column+" = ? AND is_active = ? AND start_time > ?", id, true, time.Now().UTC()).
+		Count(&count).Error
+	return count, err
 }
 
 func (r *ContentRepository) CreateTrainer(content *Trainer) (uint, error) {
@@ -118,8 +148,9 @@ func (r *ContentRepository) GetTrainerByID(ID uint) (*Trainer, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	// Unscoped: архивные тренеры нужны для отображения истории тренировок
 	var trainer Trainer
-	result := r.db.WithContext(ctx).First(&trainer, ID)
+	result := r.db.WithContext(ctx).Unscoped().First(&trainer, ID)
 	if result.Error != nil {
 		logger.DatabaseError("Не удалось получить тренера %d: %v", ID, result.Error)
 		return nil, result.Error
@@ -128,6 +159,23 @@ func (r *ContentRepository) GetTrainerByID(ID uint) (*Trainer, error) {
 	return &trainer, nil
 }
 
+// GetTrainerByChatId возвращает действующего тренера по чату или nil, если чат не тренерский
+func (r *ContentRepository) GetTrainerByChatId(chatId int) (*Trainer, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var trainer Trainer
+	if err := r.db.WithContext(ctx).Where("chat_id = ?", chatId).Limit(1).Find(&trainer).Error; err != nil {
+		logger.DatabaseError("Не удалось получить тренера по chat_id %d: %v", chatId, err)
+		return nil, err
+	}
+	if trainer.ID == 0 {
+		return nil, nil
+	}
+
+	return &trainer, nil
+}
+
 func (r *ContentRepository) GetTrainers() ([]Trainer, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -156,17 +204,28 @@ func (r *ContentRepository) UpdateTrainer(id uint, trainer *Trainer) error {
 	return nil
 }
 
+// DeleteTrainer архивирует тренера, если у него нет будущих тренировок
 func (r *ContentRepository) DeleteTrainer(id uint) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	future, err := r.countFutureTrainings(ctx, "trainer_id", id)
+	if err != nil {
+		logger.DatabaseError("Не удалось проверить тренировки тренера %d: %v", id, err)
+		return err
+	}
+	if future > 0 {
+		logger.DatabaseInfo("Удаление тренера %d заблокировано: будущих тренировок %d", id, future)
+		return apperrors.NewUserError(fmt.Sprintf("У тренера есть будущие тренировки (%d). Сначала удалите или перенесите их.", future))
+	}
+
 	result := r.db.WithContext(ctx).Delete(&Trainer{}, id)
 	if result.Error != nil {
 		logger.DatabaseError("Не удалось удалить тренера %d: %v", id, result.Error)
 		return result.Error
 	}
 
-	logger.DatabaseInfo("Тренер удален: ID=%d", id)
+	logger.DatabaseInfo("Тренер архивирован: ID=%d", id)
 	return nil
 }
 
@@ -190,8 +249,9 @@ func (r *ContentRepository) GetTrackByID(id uint) (*Track, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	// Unscoped: архивные трассы нужны для отображения истории тренировок
 	var track Track
-	result := r.db.WithContext(ctx).First(&track, id)
+	result := r.db.WithContext(ctx).Unscoped().First(&track, id)
 	if result.Error != nil {
 		logger.DatabaseError("Не удалось получить трек %d: %v", id, result.Error)
 		return nil, result.Error
@@ -228,17 +288,28 @@ func (r *ContentRepository) UpdateTrack(id uint, track *Track) error {
 	return nil
 }
 
+// DeleteTrack архивирует трассу, если на ней нет будущих тренировок
 func (r *ContentRepository) DeleteTrack(id uint) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	future, err := r.countFutureTrainings(ctx, "track_id", id)
+	if err != nil {
+		logger.DatabaseError("Не удалось проверить тренировки трассы %d: %v", id, err)
+		return err
+	}
+	if future > 0 {
+		logger.DatabaseInfo("Удаление трека %d заблокировано: будущих тренировок %d", id, future)
+		return apperrors.NewUserError(fmt.Sprintf("На трассе есть будущие тренировки (%d). Сначала удалите или перенесите их.", future))
+	}
+
 	result := r.db.WithContext(ctx).Delete(&Track{}, id)
 	if result.Error != nil {
 		logger.DatabaseError("Не удалось удалить трек %d: %v", id, result.Error)
 		return result.Error
 	}
 
-	logger.DatabaseInfo("Трек удален: ID=%d", id)
+	logger.DatabaseInfo("Трек архивирован: ID=%d", id)
 	return nil
 }
 
@@ -332,20 +403,6 @@ func (r *ContentRepository) DeleteUser(id uint) error {
 	return nil
 }
 
-func (r *ContentRepository) UpdateUserELORating(id uint, newRating int) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	result := r.db.WithContext(ctx).Model(&User{}).Where("id = ?", id).Update("elo_rating", newRating)
-	if result.Error != nil {
-		logger.DatabaseError("Не удалось обновить ELO рейтинг пользователя %d: %v", id, result.Error)
-		return result.Error
-	}
-
-	logger.DatabaseInfo("User ELO rating updated successfully: ID=%d, New Rating=%d", id, newRating)
-	return nil
-}
-
 func (r *ContentRepository) CreateTrainingRegistration(registration *TrainingRegistration) (uint, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -355,7 +412,7 @@ func (r *ContentRepository) CreateTrainingRegistration(registration *TrainingReg
 	result := r.db.WithContext(ctx).Create(registration)
 	if result.Error != nil {
 		logger.DatabaseError("Не удалось создать регистрацию на тренировку: %v", result.Error)
-		return 0, result.Error
+		return 0, mapConstraintError(result.Error)
 	}
 
 	logger.DatabaseInfo("Training registration created successfully: ID=%d", registration.ID)
@@ -432,12 +489,14 @@ func (r *ContentRepository) DeleteTrainingRegistration(id uint) error {
 	return nil
 }
 
-func (r *ContentRepository) GetTrainingRegistrationByUserAndTraining(userId uint, trainingId uint) (*TrainingRegistration, error) {
+// GetTrainingRegistrationByUserAndTraining возвращает запись пользователя на
+// тренировку за профиль участника participantId; 0 - собственная запись
+func (r *ContentRepository) GetTrainingRegistrationByUserAndTraining(userId uint, trainingId uint, participantId uint) (*TrainingRegistration, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
 	var registration TrainingRegistration
-	result := r.db.WithContext(ctx).Where("user_id = ? AND training_id = ?", userId, trainingId).First(&registration)
+	result := r.db.WithContext(ctx).Where("user_id = ? AND training_id = ? AND participant_id = ?", userId, trainingId, participantId).First(&registration)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			logger.DatabaseInfo("Training registration not found for user %d and training %d", userId, trainingId)
@@ -578,7 +637,7 @@ func (r *ContentRepository) GetTrainersByTrack(trackId uint) ([]Trainer, error)
 		Table("trainers").
 		Select("DISTINCT trainers.*").
 		Joins("INNER JOIN trainings ON trainers.id = trainings.trainer_id").
-		Where("trainings.track_id = ? AND trainings.is_active = ? AND trainings.start_time > ?", trackId, true, time.Now()).
+		Where("trainings.track_id = ? AND trainings.is_active = ? AND trainings.start_time > ?", trackId, true, time.Now().UTC()).
 		Find(&trainers)
 
 	if result.Error != nil {
@@ -598,7 +657,7 @@ func (r *ContentRepository) GetTracksWithActiveTrainings() ([]Track, error) {
 		Table("tracks").
 		Select("DISTINCT tracks.*").
 		Joins("INNER JOIN trainings ON tracks.id = trainings.track_id").
-		Where("trainings.is_active = ? AND trainings.start_time > ?", true, time.Now()).
+		Where("trainings.is_active = ? AND trainings.start_time > ?", true, time.Now().UTC()).
 		Find(&tracks)
 
 	if result.Error != nil {
@@ -618,7 +677,8 @@ func (r *ContentRepository) GetUserTrainings(userId uint) ([]Training, error) {
 		Table("trainings").
 		Select("DISTINCT trainings.*").
 		Joins("INNER JOIN training_registrations ON trainings.id = training_registrations.training_id").
-		Where("training_registrations.user_id = ? AND trainings.is_active = ? AND trainings.start_time > ?", userId, true, time.Now()).
+		Where("training_registrations.user_id = ? AND training_registrations.status IN ? AND trainings.is_active = ? AND trainings.start_time > ? AND trainings.deleted_at IS NULL",
+			userId, activeRegistrationStatuses, true, time.Now().UTC()).
 		Find(&trainings)
 
 	if result.Error != nil {
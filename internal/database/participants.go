@@ -0,0 +1,135 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"x.localhost/rvabot/internal/logger"
+
+	"gorm.io/gorm"
+)
+
+// MaxParticipantsPerUser - сколько профилей участников можно завести под одним аккаунтом
+const MaxParticipantsPerUser = 10
+
+// resultOwner - владелец кругов и рейтингов: пользователь или участник под его аккаунтом
+type resultOwner struct {
+	UserID        uint
+	ParticipantID uint
+}
+
+// AgeOn возвращает полное число лет на дату day для даты рождения birthDate
+func AgeOn(birthDate, day time.Time) int {
+	age := day.Year() - birthDate.Year()
+	if day.Month() < birthDate.Month() || (day.Month() == birthDate.Month() && day.Day() < birthDate.Day()) {
+		age--
+	}
+	return age
+}
+
+// CreateParticipant добавляет профиль участника под аккаунтом пользователя
+func (r *ContentRepository) CreateParticipant(participant *Participant) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&Participant{}).Where("user_id = ?", participant.UserID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count >= MaxParticipantsPerUser {
+			return newParticipantInvalidError("достигнут лимит профилей")
+		}
+		return mapConstraintError(tx.Create(participant).Error)
+	})
+	if err != nil {
+		logger.DatabaseError("Профиль участника пользователя %d: %v", participant.UserID, err)
+		return err
+	}
+
+	logger.DatabaseInfo("Профиль участника пользователя %d добавлен: ID=%d", participant.UserID, participant.ID)
+	return nil
+}
+
+// GetParticipants возвращает профили участников пользователя
+func (r *ContentRepository) GetParticipants(userId uint) ([]Participant, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var participants []Participant
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userId).Order("name").Find(&participants).Error; err != nil {
+		logger.DatabaseError("Профили участников пользователя %d: %v", userId, err)
+		return nil, err
+	}
+
+	return participants, nil
+}
+
+// GetParticipantByID возвращает профиль участника, включая удаленные, чтобы
+// по старым записям было видно, за кого они сделаны
+func (r *ContentRepository) GetParticipantByID(id uint) (*Participant, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var participant Participant
+	if err := r.db.WithContext(ctx).Unscoped().First(&participant, id).Error; err != nil {
+		logger.DatabaseError("Профиль участника %d: %v", id, err)
+		return nil, err
+	}
+
+	return &participant, nil
+}
+
+// DeleteParticipant архивирует профиль участника пользователя. Профиль с
+// незавершенными записями на будущие тренировки удалить нельзя.
+func (r *ContentRepository) DeleteParticipant(id, userId uint) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var open int64
+		if err := tx.Model(&TrainingRegistration{}).
+			Joins("INNER JOIN trainings ON trainings.id = training_registrations.training_id").
+			Where("training_registrations.participant_id = ? AND training_registrations.status IN ?", id, openRegistrationStatuses).
+			Where("trainings.start_time > ? AND trainings.deleted_at IS NULL", time.Now().UTC()).
+			Count(&open).Error; err != nil {
+			return err
+		}
+		if open > 0 {
+			return newParticipantInvalidError("сначала отмените записи участника на будущие тренировки")
+		}
+
+		result := tx.Where("id = ? AND user_id = ?", id, userId).Delete(&Participant{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return newParticipantInvalidError("профиль не найден")
+		}
+		return nil
+	})
+	if err != nil {
+		logger.DatabaseError("Удаление профиля участника %d: %v", id, err)
+		return err
+	}
+
+	logger.DatabaseInfo("Профиль участника %d удален", id)
+	return nil
+}
+
+// checkParticipant внутри транзакции записи проверяет, что профиль участника
+// принадлежит пользователю и не удален. participantId 0 - запись самого пользователя.
+func checkParticipant(tx *gorm.DB, userId, participantId uint) error {
+	if participantId == 0 {
+		return nil
+	}
+
+	var count int64
+	if err := tx.Model(&Participant{}).Where("id = ? AND user_id = ?", participantId, userId).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return newParticipantInvalidError("профиль не найден")
+	}
+	return nil
+}
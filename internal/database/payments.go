@@ -0,0 +1,193 @@
+package database
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"x.localhost/rvabot/internal/logger"
+
+	"gorm.io/gorm"
+)
+
+// payableRegistrationStatuses - записи, оплату которых можно отметить.
+// Предоплата принимается и до решения тренера.
+var payableRegistrationStatuses = []string{RegistrationStatusPending, RegistrationStatusConfirmed, RegistrationStatusAttended, RegistrationStatusNoShow}
+
+// owingRegistrationStatuses - записи, которые должны быть оплачены после начала тренировки
+var owingRegistrationStatuses = []string{RegistrationStatusConfirmed, RegistrationStatusAttended}
+
+// effectivePriceSQL - цена тренировки с учетом цены категории
+const effectivePriceSQL = "COALESCE(NULLIF(trainings.price, 0), category_prices.price, 0)"
+
+// IsPayableStatus проверяет, можно ли отметить оплату записи с таким статусом
+func IsPayableStatus(status string) bool {
+	return slices.Contains(payableRegistrationStatuses, status)
+}
+
+// OutstandingPayment - неоплаченная запись на прошедшую тренировку
+type OutstandingPayment struct {
+	RegistrationID uint
+	TrainingID     uint
+	UserID         uint
+	TrackID        uint
+	StartTime      time.Time
+	Amount         int // с учетом скидки; 0 - цена не задана
+}
+
+// GetTrainingPrice возвращает цену тренировки: собственную или цену ее категории.
+// 0 означает, что цена не задана.
+func (r *ContentRepository) GetTrainingPrice(training *Training) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return trainingPrice(r.db.WithContext(ctx), training)
+}
+
+// trainingPrice возвращает цену тренировки в рамках переданного подключения или транзакции
+func trainingPrice(db *gorm.DB, training *Training) (int, error) {
+	if training.Price > 0 {
+		return training.Price, nil
+	}
+
+	var categoryPrice CategoryPrice
+	if err := db.Where("car_category_id = ?", training.CarCategoryID).Limit(1).Find(&categoryPrice).Error; err != nil {
+		logger.DatabaseError("Цена категории тренировки %d: %v", training.ID, err)
+		return 0, err
+	}
+
+	return categoryPrice.Price, nil
+}
+
+// DiscountedPrice возвращает сумму к оплате с учетом скидки записи
+func DiscountedPrice(price int, registration *TrainingRegistration) int {
+	if registration.DiscountAmount >= price {
+		return 0
+	}
+	return price - registration.DiscountAmount
+}
+
+// SetTrainingPrice задает собственную цену тренировки; 0 - цена категории
+func (r *ContentRepository) SetTrainingPrice(trainingId uint, price int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := r.db.WithContext(ctx).Model(&Training{}).Where("id = ?", trainingId).Update("price", price).Error; err != nil {
+		logger.DatabaseError("Цена тренировки %d: %v", trainingId, err)
+		return err
+	}
+
+	logger.DatabaseInfo("Цена тренировки %d: %d", trainingId, price)
+	return nil
+}
+
+// GetCategoryPrices возвращает цены категорий машин по названию категории
+func (r *ContentRepository) GetCategoryPrices() ([]CategoryPrice, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var prices []CategoryPrice
+	if err := r.db.WithContext(ctx).
+		Joins("LEFT JOIN car_categories ON car_categories.id = category_prices.car_category_id").
+		Order("car_categories.name COLLATE NOCASE").
+		Find(&prices).Error; err != nil {
+		logger.DatabaseError("Цены категорий: %v", err)
+		return nil, err
+	}
+
+	return prices, nil
+}
+
+// SetCategoryPrice задает цену категории машин; 0 удаляет цену
+func (r *ContentRepository) SetCategoryPrice(carCategoryID uint, price int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if price == 0 {
+			return tx.Where("car_category_id = ?", carCategoryID).Delete(&CategoryPrice{}).Error
+		}
+
+		var existing CategoryPrice
+		if err := tx.Where("car_category_id = ?", carCategoryID).Limit(1).Find(&existing).Error; err != nil {
+			return err
+		}
+		if existing.ID == 0 {
+			return mapConstraintError(tx.Create(&CategoryPrice{CarCategoryID: &carCategoryID, Price: price}).Error)
+		}
+		return tx.Model(&existing).Update("price", price).Error
+	})
+	if err != nil {
+		logger.DatabaseError("Цена категории %d: %v", carCategoryID, err)
+		return err
+	}
+
+	logger.DatabaseInfo("Цена категории %d: %d", carCategoryID, price)
+	return nil
+}
+
+// MarkRegistrationPaid отмечает запись оплаченной указанным способом
+func (r *ContentRepository) MarkRegistrationPaid(registrationId uint, method string, amount int) (*TrainingRegistration, error) {
+	return r.setRegistrationPayment(registrationId, PaymentStatusPaid, map[string]interface{}{
+		"payment_status": PaymentStatusPaid,
+		"payment_method": method,
+		"payment_amount": amount,
+		"paid_at":        time.Now().UTC(),
+	})
+}
+
+// RefundRegistrationPayment отмечает возврат оплаты записи. Способ и сумма
+// сохраняются, чтобы было видно, что именно вернули.
+func (r *ContentRepository) RefundRegistrationPayment(registrationId uint) (*TrainingRegistration, error) {
+	return r.setRegistrationPayment(registrationId, PaymentStatusRefunded, map[string]interface{}{
+		"payment_status": PaymentStatusRefunded,
+	})
+}
+
+// setRegistrationPayment меняет статус оплаты записи: оплатить можно
+// неоплаченную или возвращенную запись, вернуть - только оплаченную
+func (r *ContentRepository) setRegistrationPayment(registrationId uint, status string, updates map[string]interface{}) (*TrainingRegistration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	query := r.db.WithContext(ctx).Model(&TrainingRegistration{}).Where("id = ?", registrationId)
+	if status == PaymentStatusPaid {
+		query = query.Where("status IN ? AND payment_status <> ?", payableRegistrationStatuses, PaymentStatusPaid)
+	} else {
+		query = query.Where("payment_status = ?", PaymentStatusPaid)
+	}
+
+	result := query.Updates(updates)
+	if result.Error != nil {
+		logger.DatabaseError("Оплата записи %d (%s): %v", registrationId, status, result.Error)
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, newPaymentUnavailableError()
+	}
+
+	logger.DatabaseInfo("Оплата записи %d: %s", registrationId, status)
+	return r.GetTrainingRegistrationByID(registrationId)
+}
+
+// GetOutstandingPayments возвращает неоплаченные записи на начавшиеся тренировки, начиная со старых
+func (r *ContentRepository) GetOutstandingPayments() ([]OutstandingPayment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var payments []OutstandingPayment
+	if err := r.db.WithContext(ctx).Model(&TrainingRegistration{}).
+		Select("training_registrations.id AS registration_id, training_registrations.training_id, training_registrations.user_id, "+
+			"trainings.track_id, trainings.start_time, MAX("+effectivePriceSQL+" - training_registrations.discount_amount, 0) AS amount").
+		Joins("INNER JOIN trainings ON trainings.id = training_registrations.training_id").
+		Joins("LEFT JOIN category_prices ON category_prices.car_category_id = trainings.car_category_id").
+		Where("training_registrations.status IN ? AND training_registrations.payment_status = ?", owingRegistrationStatuses, PaymentStatusUnpaid).
+		Where("trainings.start_time <= ? AND trainings.deleted_at IS NULL", time.Now().UTC()).
+		Order("trainings.start_time").
+		Scan(&payments).Error; err != nil {
+		logger.DatabaseError("Неоплаченные записи: %v", err)
+		return nil, err
+	}
+
+	return payments, nil
+}
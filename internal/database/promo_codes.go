@@ -0,0 +1,214 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"x.localhost/rvabot/internal/logger"
+
+	"gorm.io/gorm"
+)
+
+// promoUseStatuses - записи, которые считаются использованием промокода
+var promoUseStatuses = []string{RegistrationStatusPending, RegistrationStatusConfirmed, RegistrationStatusAttended, RegistrationStatusNoShow}
+
+// NormalizePromoCode приводит введенный промокод к виду, в котором он хранится
+func NormalizePromoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// PromoDiscount возвращает скидку промокода в рублях при указанной цене.
+// Без цены процентная скидка равна 0, а фиксированная сохраняется целиком.
+func PromoDiscount(promo *PromoCode, price int) int {
+	if promo.DiscountType == DiscountTypePercent {
+		return price * promo.DiscountValue / 100
+	}
+	if price > 0 && promo.DiscountValue > price {
+		return price
+	}
+	return promo.DiscountValue
+}
+
+// CreatePromoCode создает промокод
+func (r *ContentRepository) CreatePromoCode(promo *PromoCode) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	promo.Code = NormalizePromoCode(promo.Code)
+	promo.IsActive = true
+	if err := r.db.WithContext(ctx).Create(promo).Error; err != nil {
+		logger.DatabaseError("Создание промокода %s: %v", promo.Code, err)
+		return mapConstraintError(err)
+	}
+
+	logger.DatabaseInfo("Создан промокод %s: ID=%d", promo.Code, promo.ID)
+	return nil
+}
+
+// GetActivePromoCodes возвращает действующие промокоды, начиная с новых
+func (r *ContentRepository) GetActivePromoCodes() ([]PromoCode, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var promos []PromoCode
+	if err := r.db.WithContext(ctx).Where("is_active = ?", true).Order("created_at DESC").Find(&promos).Error; err != nil {
+		logger.DatabaseError("Промокоды: %v", err)
+		return nil, err
+	}
+
+	return promos, nil
+}
+
+// GetPromoCodeByID возвращает промокод по ID или nil, если его нет
+func (r *ContentRepository) GetPromoCodeByID(id uint) (*PromoCode, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var promo PromoCode
+	if err := r.db.WithContext(ctx).Where("id = ?", id).Limit(1).Find(&promo).Error; err != nil {
+		logger.DatabaseError("Промокод %d: %v", id, err)
+		return nil, err
+	}
+	if promo.ID == 0 {
+		return nil, nil
+	}
+
+	return &promo, nil
+}
+
+// DeactivatePromoCode отключает промокод. Уже примененные скидки сохраняются.
+func (r *ContentRepository) DeactivatePromoCode(id uint) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := r.db.WithContext(ctx).Model(&PromoCode{}).Where("id = ?", id).Update("is_active", false).Error; err != nil {
+		logger.DatabaseError("Отключение промокода %d: %v", id, err)
+		return err
+	}
+
+	logger.DatabaseInfo("Промокод %d отключен", id)
+	return nil
+}
+
+// CountPromoCodeUses возвращает число записей, использующих промокод
+func (r *ContentRepository) CountPromoCodeUses(id uint) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return countPromoCodeUses(r.db.WithContext(ctx), id, 0)
+}
+
+// CheckPromoCode находит промокод и проверяет, что его можно применить к тренировке
+func (r *ContentRepository) CheckPromoCode(code string, training *Training) (*PromoCode, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	db := r.db.WithContext(ctx)
+	var promo PromoCode
+	if err := db.Where("code = ?", NormalizePromoCode(code)).Limit(1).Find(&promo).Error; err != nil {
+		logger.DatabaseError("Поиск промокода: %v", err)
+		return nil, err
+	}
+	if promo.ID == 0 {
+		return nil, newPromoCodeInvalidError("промокод не найден")
+	}
+
+	if err := checkPromoCode(db, &promo, training, 0); err != nil {
+		return nil, err
+	}
+
+	return &promo, nil
+}
+
+// ApplyPromoCode сохраняет в записи промокод и скидку по текущей цене тренировки.
+// Промокод проверяется повторно: за время подтверждения его могли исчерпать или отключить.
+func (r *ContentRepository) ApplyPromoCode(registrationId uint, promoCodeId uint) (*TrainingRegistration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var registration TrainingRegistration
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&registration, registrationId).Error; err != nil {
+			return err
+		}
+
+		var promo PromoCode
+		if err := tx.Where("id = ?", promoCodeId).Limit(1).Find(&promo).Error; err != nil {
+			return err
+		}
+		if promo.ID == 0 {
+			return newPromoCodeInvalidError("промокод не найден")
+		}
+
+		var training Training
+		if err := tx.First(&training, registration.TrainingID).Error; err != nil {
+			return err
+		}
+
+		if err := checkPromoCode(tx, &promo, &training, registrationId); err != nil {
+			return err
+		}
+
+		price, err := trainingPrice(tx, &training)
+		if err != nil {
+			return err
+		}
+
+		registration.PromoCodeID = &promo.ID
+		registration.DiscountAmount = PromoDiscount(&promo, price)
+		return tx.Model(&registration).Updates(map[string]interface{}{
+			"promo_code_id":   promo.ID,
+			"discount_amount": registration.DiscountAmount,
+		}).Error
+	})
+	if err != nil {
+		logger.DatabaseError("Промокод %d для записи %d: %v", promoCodeId, registrationId, err)
+		return nil, err
+	}
+
+	logger.DatabaseInfo("Промокод %d применен к записи %d: скидка %d", promoCodeId, registrationId, registration.DiscountAmount)
+	return &registration, nil
+}
+
+// checkPromoCode проверяет срок действия, лимит использований и ограничения промокода.
+// Запись exceptRegistrationId не учитывается в лимите.
+func checkPromoCode(db *gorm.DB, promo *PromoCode, training *Training, exceptRegistrationId uint) error {
+	now := time.Now().UTC()
+	switch {
+	case !promo.IsActive:
+		return newPromoCodeInvalidError("промокод отключен")
+	case promo.ValidFrom != nil && now.Before(*promo.ValidFrom):
+		return newPromoCodeInvalidError("срок действия промокода еще не начался")
+	case promo.ValidUntil != nil && !now.Before(*promo.ValidUntil):
+		return newPromoCodeInvalidError("срок действия промокода истек")
+	case promo.TrackID != nil && *promo.TrackID != training.TrackID:
+		return newPromoCodeInvalidError("промокод действует на другой трассе")
+	case promo.CarCategoryID != nil && !SameCarCategory(promo.CarCategoryID, training.CarCategoryID):
+		return newPromoCodeInvalidError("промокод действует для категории " + carCategoryName(db, promo.CarCategoryID))
+	}
+
+	if promo.MaxUses > 0 {
+		uses, err := countPromoCodeUses(db, promo.ID, exceptRegistrationId)
+		if err != nil {
+			return err
+		}
+		if uses >= int64(promo.MaxUses) {
+			return newPromoCodeInvalidError("лимит использований исчерпан")
+		}
+	}
+
+	return nil
+}
+
+// countPromoCodeUses считает записи с промокодом, кроме отмененных и отклоненных
+func countPromoCodeUses(db *gorm.DB, id uint, exceptRegistrationId uint) (int64, error) {
+	var count int64
+	err := db.Model(&TrainingRegistration{}).
+		Where("promo_code_id = ? AND status IN ? AND id <> ?", id, promoUseStatuses, exceptRegistrationId).
+		Count(&count).Error
+	if err != nil {
+		logger.DatabaseError("Использования промокода %d: %v", id, err)
+	}
+	return count, err
+}
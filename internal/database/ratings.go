@@ -0,0 +1,262 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"x.localhost/rvabot/internal/logger"
+	"x.localhost/rvabot/internal/rating"
+
+	"gorm.io/gorm"
+)
+
+// RatingCategory - категория карта в рейтинге и число участников в ней
+type RatingCategory struct {
+	CarCategoryID *uint
+	CarCategory   string // название категории для показа
+	RatingID      uint   // рейтинг одного из участников категории для ссылки на таблицу
+	Players       int
+}
+
+// RecordHeatResults сохраняет итог заезда на тренировке и пересчитывает
+// рейтинги участников. registrationIds перечисляются в порядке финиша.
+// Меняются рейтинг в категории тренировки и общий рейтинг: у записи за
+// участника - его собственные, а не рейтинги представителя.
+func (r *ContentRepository) RecordHeatResults(trainingId uint, registrationIds []uint) ([]HeatResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var results []HeatResult
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var training Training
+		if err := tx.Where("id = ? AND start_time <= ?", trainingId, time.Now().UTC()).Limit(1).Find(&training).Error; err != nil {
+			return err
+		}
+		if training.ID == 0 {
+			return newTrainingUnavailableError().WithUserMessage("Результаты заезда можно вводить только после начала тренировки")
+		}
+
+		var registrations []TrainingRegistration
+		if err := tx.Where("training_id = ? AND id IN ? AND status IN ?", trainingId, registrationIds,
+			[]string{RegistrationStatusConfirmed, RegistrationStatusAttended}).
+			Find(&registrations).Error; err != nil {
+			return err
+		}
+		if len(registrations) != len(registrationIds) {
+			return newAttendanceUnavailableError().WithUserMessage("В заезде могут быть только подтвержденные участники тренировки")
+		}
+		byId := make(map[uint]TrainingRegistration, len(registrations))
+		userIds := make([]uint, 0, len(registrations))
+		var participantIds []uint
+		for _, reg := range registrations {
+			byId[reg.ID] = reg
+			userIds = append(userIds, reg.UserID)
+			if reg.ParticipantID != 0 {
+				participantIds = append(participantIds, reg.ParticipantID)
+			}
+		}
+		owners := make([]resultOwner, len(registrationIds))
+		for i, id := range registrationIds {
+			owners[i] = resultOwner{UserID: byId[id].UserID, ParticipantID: byId[id].ParticipantID}
+		}
+
+		var lastHeat int
+		if err := tx.Model(&HeatResult{}).Where("training_id = ?", trainingId).
+			Select("COALESCE(MAX(heat_number), 0)").Scan(&lastHeat).Error; err != nil {
+			return err
+		}
+
+		var existing []UserRating
+		if err := tx.Where("user_id IN ? AND car_category_id IS ?", userIds, training.CarCategoryID).Find(&existing).Error; err != nil {
+			return err
+		}
+		ratings := make(map[resultOwner]UserRating)
+		for _, ur := range existing {
+			ratings[resultOwner{UserID: ur.UserID, ParticipantID: ur.ParticipantID}] = ur
+		}
+
+		var users []User
+		if err := tx.Where("id IN ?", userIds).Find(&users).Error; err != nil {
+			return err
+		}
+		overall := make(map[resultOwner]int)
+		for _, u := range users {
+			overall[resultOwner{UserID: u.ID}] = u.EloRating
+		}
+		if len(participantIds) > 0 {
+			var participants []Participant
+			if err := tx.Unscoped().Where("id IN ?", participantIds).Find(&participants).Error; err != nil {
+				return err
+			}
+			for _, p := range participants {
+				overall[resultOwner{UserID: p.UserID, ParticipantID: p.ID}] = p.EloRating
+			}
+		}
+
+		categoryBefore := make([]int, len(owners))
+		overallBefore := make([]int, len(owners))
+		for i, owner := range owners {
+			categoryBefore[i] = rating.Initial
+			if ur, ok := ratings[owner]; ok {
+				categoryBefore[i] = ur.Rating
+			}
+			overallBefore[i] = overall[owner]
+		}
+		categoryAfter := rating.Update(categoryBefore)
+		overallAfter := rating.Update(overallBefore)
+
+		for i, owner := range owners {
+			ur, ok := ratings[owner]
+			if !ok {
+				ur = UserRating{UserID: owner.UserID, ParticipantID: owner.ParticipantID, CarCategoryID: training.CarCategoryID}
+			}
+			ur.Rating = categoryAfter[i]
+			ur.Heats++
+			if err := tx.Save(&ur).Error; err != nil {
+				return mapConstraintError(err)
+			}
+
+			if owner.ParticipantID != 0 {
+				if err := tx.Unscoped().Model(&Participant{}).Where("id = ?", owner.ParticipantID).Update("elo_rating", overallAfter[i]).Error; err != nil {
+					return err
+				}
+			} else if err := tx.Model(&User{}).Where("id = ?", owner.UserID).Update("elo_rating", overallAfter[i]).Error; err != nil {
+				return err
+			}
+
+			results = append(results, HeatResult{
+				TrainingID:    trainingId,
+				HeatNumber:    lastHeat + 1,
+				UserID:        owner.UserID,
+				ParticipantID: owner.ParticipantID,
+				CarCategoryID: training.CarCategoryID,
+				Position:      i + 1,
+				Participants:  len(owners),
+				RatingBefore:  categoryBefore[i],
+				RatingAfter:   categoryAfter[i],
+			})
+		}
+
+		return mapConstraintError(tx.Create(&results).Error)
+	})
+	if err != nil {
+		logger.DatabaseError("Результаты заезда тренировки %d: %v", trainingId, err)
+		return nil, err
+	}
+
+	logger.DatabaseInfo("Сохранен заезд %d тренировки %d: участников %d", results[0].HeatNumber, trainingId, len(results))
+	return results, nil
+}
+
+// CountTrainingHeats возвращает число заездов, введенных для тренировки
+func (r *ContentRepository) CountTrainingHeats(trainingId uint) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var heats int
+	if err := r.db.WithContext(ctx).Model(&HeatResult{}).Where("training_id = ?", trainingId).
+		Select("COALESCE(MAX(heat_number), 0)").Scan(&heats).Error; err != nil {
+		logger.DatabaseError("Число заездов тренировки %d: %v", trainingId, err)
+		return 0, err
+	}
+
+	return heats, nil
+}
+
+// GetUserRatings возвращает рейтинги пользователя по категориям карта без
+// рейтингов участников под его аккаунтом по названию категории
+func (r *ContentRepository) GetUserRatings(userId uint) ([]UserRating, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var ratings []UserRating
+	result := r.db.WithContext(ctx).
+		Joins("LEFT JOIN car_categories ON car_categories.id = user_ratings.car_category_id").
+		Where("user_ratings.user_id = ? AND user_ratings.participant_id = 0", userId).
+		Order("car_categories.name COLLATE NOCASE").
+		Find(&ratings)
+	if result.Error != nil {
+		logger.DatabaseError("Рейтинги пользователя %d: %v", userId, result.Error)
+		return nil, result.Error
+	}
+
+	return ratings, nil
+}
+
+// GetUserRatingByID возвращает рейтинг пользователя в категории по ID
+func (r *ContentRepository) GetUserRatingByID(id uint) (*UserRating, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var ur UserRating
+	result := r.db.WithContext(ctx).Where("id = ?", id).Limit(1).Find(&ur)
+	if result.Error != nil {
+		logger.DatabaseError("Рейтинг %d: %v", id, result.Error)
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return &ur, nil
+}
+
+// GetRatingHistory возвращает последние заезды пользователя или участника
+// participantId под его аккаунтом в категории, начиная с самого нового
+func (r *ContentRepository) GetRatingHistory(userId, participantId uint, carCategoryID *uint, limit int) ([]HeatResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var history []HeatResult
+	result := r.db.WithContext(ctx).
+		Where("user_id = ? AND participant_id = ? AND car_category_id IS ?", userId, participantId, carCategoryID).
+		Order("id DESC").
+		Limit(limit).
+		Find(&history)
+	if result.Error != nil {
+		logger.DatabaseError("История рейтинга пользователя %d: %v", userId, result.Error)
+		return nil, result.Error
+	}
+
+	return history, nil
+}
+
+// GetRatingCategories возвращает категории карта, в которых есть рейтинги
+func (r *ContentRepository) GetRatingCategories() ([]RatingCategory, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var categories []RatingCategory
+	result := r.db.WithContext(ctx).Model(&UserRating{}).
+		Select("user_ratings.car_category_id, COALESCE(car_categories.name, ?) AS car_category, "+
+			"MIN(user_ratings.id) AS rating_id, COUNT(*) AS players", CarCategoryNone).
+		Joins("LEFT JOIN car_categories ON car_categories.id = user_ratings.car_category_id").
+		Group("user_ratings.car_category_id").
+		Order("car_category COLLATE NOCASE").
+		Scan(&categories)
+	if result.Error != nil {
+		logger.DatabaseError("Категории рейтинга: %v", result.Error)
+		return nil, result.Error
+	}
+
+	return categories, nil
+}
+
+// GetRatingLeaderboard возвращает лучшие рейтинги в категории карта
+func (r *ContentRepository) GetRatingLeaderboard(carCategoryID *uint, limit int) ([]UserRating, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var ratings []UserRating
+	result := r.db.WithContext(ctx).
+		Where("car_category_id IS ?", carCategoryID).
+		Order("rating DESC, heats DESC, id").
+		Limit(limit).
+		Find(&ratings)
+	if result.Error != nil {
+		logger.DatabaseError("Таблица рейтинга: %v", result.Error)
+		return nil, result.Error
+	}
+
+	return ratings, nil
+}
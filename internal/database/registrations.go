@@ -0,0 +1,188 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"x.localhost/rvabot/internal/logger"
+
+	"gorm.io/gorm"
+)
+
+// activeRegistrationStatuses - статусы, занимающие место на тренировке.
+// Предложенное из листа ожидания место удерживается до ответа пользователя.
+var activeRegistrationStatuses = []string{RegistrationStatusPending, RegistrationStatusConfirmed, RegistrationStatusOffered}
+
+// openRegistrationStatuses - все незавершенные записи, включая лист ожидания
+var openRegistrationStatuses = []string{RegistrationStatusPending, RegistrationStatusConfirmed, RegistrationStatusOffered, RegistrationStatusWaitlisted}
+
+// seatAvailableSQL - условие "тренировка открыта для записи и на ней есть место"
+// с учетом исправных картов парка.
+// Параметры: trainingId, true, now, activeRegistrationStatuses.
+const seatAvailableSQL = "EXISTS (SELECT 1 FROM trainings t WHERE t.id = ? AND t.is_active = ? AND t.deleted_at IS NULL AND t.start_time > ? " +
+	"AND (SELECT COUNT(*) FROM training_registrations r WHERE r.training_id = t.id AND r.status IN ?) < " + trainingCapacitySQL + ")"
+
+// RegisterForTraining создает заявку на тренировку, проверяя свободные места
+// тем же SQL-выражением, что и вставку, поэтому одновременные запросы не
+// могут превысить лимит. Отмененная ранее запись переиспользуется.
+// participantId - профиль участника, за которого записывается пользователь;
+// 0 - пользователь записывается сам. Каждый участник занимает отдельное место.
+func (r *ContentRepository) RegisterForTraining(trainingId, userId, participantId uint) (*TrainingRegistration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	logger.DatabaseInfo("Запись на тренировку: TrainingID=%d, UserID=%d, ParticipantID=%d", trainingId, userId, participantId)
+
+	var registration TrainingRegistration
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now().UTC()
+
+		if err := checkParticipant(tx, userId, participantId); err != nil {
+			return err
+		}
+
+		var existing TrainingRegistration
+		if err := tx.Where("training_id = ? AND user_id = ? AND participant_id = ?", trainingId, userId, participantId).Limit(1).Find(&existing).Error; err != nil {
+			return err
+		}
+		if existing.ID != 0 && existing.Status != RegistrationStatusCancelled {
+			return newAlreadyRegisteredError(nil)
+		}
+
+		if err := checkSkillLevel(tx, trainingId, userId, participantId); err != nil {
+			return err
+		}
+
+		var result *gorm.DB
+		if existing.ID != 0 {
+			// Невозвращенная оплата отмененной записи засчитывается при повторной записи
+			result = tx.Exec("UPDATE training_registrations SET status = ?, cancelled_at = NULL, late_cancellation = false, "+
+				"payment_status = CASE WHEN payment_status = ? THEN ? ELSE payment_status END, promo_code_id = NULL, discount_amount = 0, kart_id = NULL, "+
+				"updated_at = ? WHERE id = ? AND "+seatAvailableSQL,
+				RegistrationStatusPending, PaymentStatusRefunded, PaymentStatusUnpaid, now, existing.ID, trainingId, true, now, activeRegistrationStatuses)
+		} else {
+			result = tx.Exec("INSERT INTO training_registrations (training_id, user_id, participant_id, status, created_at, updated_at) SELECT ?, ?, ?, ?, ?, ? WHERE "+seatAvailableSQL,
+				trainingId, userId, participantId, RegistrationStatusPending, now, now, trainingId, true, now, activeRegistrationStatuses)
+		}
+		if result.Error != nil {
+			return mapConstraintError(result.Error)
+		}
+
+		if result.RowsAffected == 0 {
+			var training Training
+			if err := tx.Where("id = ? AND is_active = ? AND start_time > ?", trainingId, true, now).Limit(1).Find(&training).Error; err != nil {
+				return err
+			}
+			if training.ID == 0 {
+				return newTrainingUnavailableError()
+			}
+			return newTrainingFullError()
+		}
+
+		// Экипировка отмененной записи заказывается заново
+		if existing.ID != 0 {
+			if err := tx.Where("registration_id = ?", existing.ID).Delete(&RentalRequest{}).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Where("training_id = ? AND user_id = ? AND participant_id = ?", trainingId, userId, participantId).First(&registration).Error
+	})
+	if err != nil {
+		logger.DatabaseError("Запись на тренировку %d пользователя %d: %v", trainingId, userId, err)
+		return nil, err
+	}
+
+	logger.DatabaseInfo("Запись создана: ID=%d", registration.ID)
+	return &registration, nil
+}
+
+// CountActiveRegistrations возвращает число занятых мест на тренировке
+func (r *ContentRepository) CountActiveRegistrations(trainingId uint) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var count int64
+	result := r.db.WithContext(ctx).Model(&TrainingRegistration{}).
+		Where("training_id = ? AND status IN ?", trainingId, activeRegistrationStatuses).
+		Count(&count)
+	if result.Error != nil {
+		logger.DatabaseError("Подсчет записей на тренировку %d: %v", trainingId, result.Error)
+		return 0, result.Error
+	}
+
+	return count, nil
+}
+
+// GetUserBookings возвращает незавершенные записи пользователя на будущие тренировки
+func (r *ContentRepository) GetUserBookings(userId uint) ([]TrainingRegistration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var bookings []TrainingRegistration
+	result := r.db.WithContext(ctx).
+		Select("training_registrations.*").
+		Joins("INNER JOIN trainings ON trainings.id = training_registrations.training_id").
+		Where("training_registrations.user_id = ? AND training_registrations.status IN ?", userId, openRegistrationStatuses).
+		Where("trainings.is_active = ? AND trainings.start_time > ? AND trainings.deleted_at IS NULL", true, time.Now().UTC()).
+		Order("trainings.start_time").
+		Find(&bookings)
+	if result.Error != nil {
+		logger.DatabaseError("Записи пользователя %d: %v", userId, result.Error)
+		return nil, result.Error
+	}
+
+	return bookings, nil
+}
+
+// IsLateCancellation проверяет, будет ли отмена записи в момент now поздней.
+// Поздней считается только отмена занятого места; выход из листа ожидания
+// и отказ от предложенного места ни на что не влияют.
+func IsLateCancellation(registration *TrainingRegistration, training *Training, cutoff time.Duration, now time.Time) bool {
+	heldSeat := registration.Status == RegistrationStatusPending || registration.Status == RegistrationStatusConfirmed
+	return heldSeat && now.After(training.StartTime.Add(-cutoff))
+}
+
+// CancelRegistration отменяет запись пользователя до начала тренировки.
+// Отмена позже чем за cutoff до начала отмечается как поздняя.
+func (r *ContentRepository) CancelRegistration(registrationId, userId uint, cutoff time.Duration) (*TrainingRegistration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var registration TrainingRegistration
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now().UTC()
+
+		if err := tx.Where("id = ? AND user_id = ? AND status IN ?", registrationId, userId, openRegistrationStatuses).
+			Limit(1).Find(&registration).Error; err != nil {
+			return err
+		}
+		if registration.ID == 0 {
+			return newBookingNotFoundError()
+		}
+
+		var training Training
+		if err := tx.Where("id = ? AND start_time > ?", registration.TrainingID, now).Limit(1).Find(&training).Error; err != nil {
+			return err
+		}
+		if training.ID == 0 {
+			return newTrainingUnavailableError().WithUserMessage("Тренировка уже началась, отменить запись нельзя")
+		}
+
+		late := IsLateCancellation(&registration, &training, cutoff, now)
+
+		registration.Status = RegistrationStatusCancelled
+		registration.CancelledAt = &now
+		registration.LateCancellation = late
+		registration.WaitlistPosition = 0
+		registration.OfferExpiresAt = nil
+		return tx.Save(&registration).Error
+	})
+	if err != nil {
+		logger.DatabaseError("Отмена записи %d пользователем %d: %v", registrationId, userId, err)
+		return nil, err
+	}
+
+	logger.DatabaseInfo("Запись %d отменена пользователем %d (поздняя: %t)", registrationId, userId, registration.LateCancellation)
+	return &registration, nil
+}
@@ -0,0 +1,308 @@
+package database
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"x.localhost/rvabot/internal/logger"
+
+	"gorm.io/gorm"
+)
+
+// RentalKinds - виды прокатной экипировки в порядке показа
+var RentalKinds = []string{RentalKindHelmet, RentalKindSuit, RentalKindGloves}
+
+// rentalRequestStatuses - записи, к которым можно заказать экипировку
+var rentalRequestStatuses = []string{RegistrationStatusPending, RegistrationStatusConfirmed}
+
+// rentalHoldStatuses - записи, бронь экипировки которых занимает склад.
+// Бронь отмененной или отклоненной записи освобождается сама собой.
+var rentalHoldStatuses = []string{RegistrationStatusConfirmed, RegistrationStatusAttended}
+
+// RentalAvailability - размер экипировки и число свободных штук на время тренировки
+type RentalAvailability struct {
+	RentalItem
+	Available int
+}
+
+// RentalPick - экипировка участника для списка выдачи
+type RentalPick struct {
+	RegistrationID uint
+	UserID         uint
+	RentalItemID   uint
+	Kind           string
+	Size           string
+	Status         string
+}
+
+// CanRequestRental проверяет, можно ли заказать экипировку к записи с таким статусом
+func CanRequestRental(status string) bool {
+	return slices.Contains(rentalRequestStatuses, status)
+}
+
+// GetTrackRentalStock возвращает склад экипировки трассы
+func (r *ContentRepository) GetTrackRentalStock(trackId uint) ([]RentalItem, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var items []RentalItem
+	if err := r.db.WithContext(ctx).Where("track_id = ?", trackId).
+		Order("kind, LENGTH(size), size").Find(&items).Error; err != nil {
+		logger.DatabaseError("Склад экипировки трассы %d: %v", trackId, err)
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// SetRentalStock задает число штук экипировки вида и размера на трассе.
+// Размер с нулевым остатком остается в истории запросов, но не предлагается.
+func (r *ContentRepository) SetRentalStock(trackId uint, kind, size string, quantity int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing RentalItem
+		if err := tx.Where("track_id = ? AND kind = ? AND size = ?", trackId, kind, size).Limit(1).Find(&existing).Error; err != nil {
+			return err
+		}
+		if existing.ID == 0 {
+			return mapConstraintError(tx.Create(&RentalItem{TrackID: trackId, Kind: kind, Size: size, Quantity: quantity}).Error)
+		}
+		return tx.Model(&existing).Update("quantity", quantity).Error
+	})
+	if err != nil {
+		logger.DatabaseError("Склад трассы %d, %s %s: %v", trackId, kind, size, err)
+		return err
+	}
+
+	logger.DatabaseInfo("Склад трассы %d, %s %s: %d", trackId, kind, size, quantity)
+	return nil
+}
+
+// GetRentalAvailability возвращает размеры экипировки на трассе тренировки
+// и свободные на время тренировки штуки без учета брони самой записи
+func (r *ContentRepository) GetRentalAvailability(training *Training, registrationId uint) ([]RentalAvailability, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	db := r.db.WithContext(ctx)
+
+	var items []RentalItem
+	if err := db.Where("track_id = ? AND quantity > 0", training.TrackID).
+		Order("kind, LENGTH(size), size").Find(&items).Error; err != nil {
+		logger.DatabaseError("Экипировка трассы %d: %v", training.TrackID, err)
+		return nil, err
+	}
+
+	reserved, err := reservedRentals(db, training, registrationId)
+	if err != nil {
+		logger.DatabaseError("Бронь экипировки на тренировку %d: %v", training.ID, err)
+		return nil, err
+	}
+
+	availability := make([]RentalAvailability, 0, len(items))
+	for _, item := range items {
+		availability = append(availability, RentalAvailability{RentalItem: item, Available: max(item.Quantity-reserved[item.ID], 0)})
+	}
+
+	return availability, nil
+}
+
+// reservedRentals возвращает число забронированных штук по ID размера на
+// тренировках, пересекающихся по времени с переданной, кроме записи registrationId
+func reservedRentals(db *gorm.DB, training *Training, registrationId uint) (map[uint]int, error) {
+	var rows []struct {
+		RentalItemID uint
+		Reserved     int
+	}
+	if err := db.Model(&RentalRequest{}).
+		Select("rental_requests.rental_item_id, COUNT(*) AS reserved").
+		Joins("INNER JOIN training_registrations r ON r.id = rental_requests.registration_id").
+		Joins("INNER JOIN trainings t ON t.id = r.training_id").
+		Where("rental_requests.status = ? AND r.status IN ? AND r.id <> ?", RentalStatusReserved, rentalHoldStatuses, registrationId).
+		Where("t.track_id = ? AND t.deleted_at IS NULL AND t.start_time < ? AND t.end_time > ?", training.TrackID, training.EndTime, training.StartTime).
+		Group("rental_requests.rental_item_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	reserved := make(map[uint]int, len(rows))
+	for _, row := range rows {
+		reserved[row.RentalItemID] = row.Reserved
+	}
+	return reserved, nil
+}
+
+// GetRegistrationRentals возвращает экипировку, заказанную к записи
+func (r *ContentRepository) GetRegistrationRentals(registrationId uint) ([]RentalPick, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var picks []RentalPick
+	if err := rentalPicks(r.db.WithContext(ctx)).
+		Where("rental_requests.registration_id = ?", registrationId).
+		Scan(&picks).Error; err != nil {
+		logger.DatabaseError("Экипировка записи %d: %v", registrationId, err)
+		return nil, err
+	}
+
+	return picks, nil
+}
+
+// GetTrainingRentals возвращает экипировку участников тренировки для выдачи
+func (r *ContentRepository) GetTrainingRentals(trainingId uint) ([]RentalPick, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var picks []RentalPick
+	if err := rentalPicks(r.db.WithContext(ctx)).
+		Where("training_registrations.training_id = ? AND training_registrations.status IN ?",
+			trainingId, append(slices.Clone(rentalRequestStatuses), RegistrationStatusAttended)).
+		Scan(&picks).Error; err != nil {
+		logger.DatabaseError("Экипировка тренировки %d: %v", trainingId, err)
+		return nil, err
+	}
+
+	return picks, nil
+}
+
+// rentalPicks - запрос экипировки участников с видом и размером
+func rentalPicks(db *gorm.DB) *gorm.DB {
+	return db.Model(&RentalRequest{}).
+		Select("rental_requests.registration_id, training_registrations.user_id, rental_requests.rental_item_id, " +
+			"rental_requests.kind, rental_items.size, rental_requests.status").
+		Joins("INNER JOIN training_registrations ON training_registrations.id = rental_requests.registration_id").
+		Joins("INNER JOIN rental_items ON rental_items.id = rental_requests.rental_item_id").
+		Order("rental_requests.kind, LENGTH(rental_items.size), rental_items.size, rental_requests.id")
+}
+
+// RequestRental заказывает экипировку к записи вместо ранее выбранного размера
+// того же вида. К подтвержденной записи экипировка бронируется сразу.
+func (r *ContentRepository) RequestRental(registrationId, rentalItemId uint) (*RentalRequest, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var request RentalRequest
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var registration TrainingRegistration
+		if err := tx.Where("id = ?", registrationId).Limit(1).Find(&registration).Error; err != nil {
+			return err
+		}
+		if registration.ID == 0 || !CanRequestRental(registration.Status) {
+			return newRentalUnavailableError("экипировку можно заказать только к ожидающей или подтвержденной записи")
+		}
+
+		var training Training
+		if err := tx.Where("id = ?", registration.TrainingID).Limit(1).Find(&training).Error; err != nil {
+			return err
+		}
+		if training.ID == 0 {
+			return newTrainingUnavailableError()
+		}
+
+		var item RentalItem
+		if err := tx.Where("id = ? AND track_id = ? AND quantity > 0", rentalItemId, training.TrackID).Limit(1).Find(&item).Error; err != nil {
+			return err
+		}
+		if item.ID == 0 {
+			return newRentalUnavailableError("такой экипировки нет на трассе")
+		}
+
+		reserved, err := reservedRentals(tx, &training, registrationId)
+		if err != nil {
+			return err
+		}
+		if reserved[item.ID] >= item.Quantity {
+			return newRentalUnavailableError("этот размер уже разобран")
+		}
+
+		if err := tx.Where("registration_id = ? AND kind = ?", registrationId, item.Kind).Delete(&RentalRequest{}).Error; err != nil {
+			return err
+		}
+
+		request = RentalRequest{RegistrationID: registrationId, RentalItemID: item.ID, Kind: item.Kind, Status: RentalStatusRequested}
+		if registration.Status == RegistrationStatusConfirmed {
+			request.Status = RentalStatusReserved
+		}
+		return mapConstraintError(tx.Create(&request).Error)
+	})
+	if err != nil {
+		logger.DatabaseError("Экипировка %d к записи %d: %v", rentalItemId, registrationId, err)
+		return nil, err
+	}
+
+	logger.DatabaseInfo("Экипировка %d к записи %d: %s", rentalItemId, registrationId, request.Status)
+	return &request, nil
+}
+
+// CancelRentalRequest отказывается от экипировки этого размера в записи
+func (r *ContentRepository) CancelRentalRequest(registrationId, rentalItemId uint) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := r.db.WithContext(ctx).Where("registration_id = ? AND rental_item_id = ?", registrationId, rentalItemId).
+		Delete(&RentalRequest{}).Error; err != nil {
+		logger.DatabaseError("Отказ от экипировки %d в записи %d: %v", rentalItemId, registrationId, err)
+		return err
+	}
+
+	logger.DatabaseInfo("Отказ от экипировки %d в записи %d", rentalItemId, registrationId)
+	return nil
+}
+
+// ReserveRentals бронирует экипировку при подтверждении записи. Размеры,
+// которые уже разобраны на это время, отмечаются как недоступные.
+func (r *ContentRepository) ReserveRentals(registrationId uint) ([]RentalPick, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var picks []RentalPick
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var requests []RentalRequest
+		if err := tx.Where("registration_id = ? AND status = ?", registrationId, RentalStatusRequested).Find(&requests).Error; err != nil {
+			return err
+		}
+
+		if len(requests) > 0 {
+			var registration TrainingRegistration
+			if err := tx.First(&registration, registrationId).Error; err != nil {
+				return err
+			}
+			var training Training
+			if err := tx.First(&training, registration.TrainingID).Error; err != nil {
+				return err
+			}
+
+			reserved, err := reservedRentals(tx, &training, registrationId)
+			if err != nil {
+				return err
+			}
+
+			for _, request := range requests {
+				var item RentalItem
+				if err := tx.First(&item, request.RentalItemID).Error; err != nil {
+					return err
+				}
+
+				status := RentalStatusUnavailable
+				if reserved[item.ID] < item.Quantity {
+					status = RentalStatusReserved
+					reserved[item.ID]++
+				}
+				if err := tx.Model(&request).Update("status", status).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		return rentalPicks(tx).Where("rental_requests.registration_id = ?", registrationId).Scan(&picks).Error
+	})
+	if err != nil {
+		logger.DatabaseError("Бронь экипировки записи %d: %v", registrationId, err)
+		return nil, err
+	}
+
+	return picks, nil
+}
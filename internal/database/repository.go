@@ -1,12 +1,15 @@
 package database
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
 type ContentRepositoryInterface interface {
 	CreateTrainer(content *Trainer) (uint, error)
 	GetTrainerByID(ID uint) (*Trainer, error)
+	GetTrainerByChatId(chatId int) (*Trainer, error)
 	GetTrainers() ([]Trainer, error)
 	UpdateTrainer(id uint, trainer *Trainer) error
 	DeleteTrainer(id uint) error
@@ -23,6 +26,8 @@ type ContentRepositoryInterface interface {
 	GetTracks() ([]Track, error)
 	UpdateTrack(id uint, track *Track) error
 	DeleteTrack(id uint) error
+	SetTrackTimezone(trackId uint, timezone string) error
+	GetTrackLocation(trackId uint) *time.Location
 
 	CreateUser(user *User) (uint, error)
 	GetUserByID(id uint) (*User, error)
@@ -30,13 +35,29 @@ type ContentRepositoryInterface interface {
 	GetUsers() ([]User, error)
 	UpdateUser(id uint, user *User) error
 	DeleteUser(id uint) error
+	SetUserTimezone(userId uint, timezone string) error
+	GetViewerLocation(chatId int, trackId uint) *time.Location
 
 	CreateTraining(content *Training) (uint, error)
 	GetTrainingById(id uint) (*Training, error)
 	GetTrainings() ([]Training, error)
 	GetActiveTrainings() ([]Training, error)
 	UpdateTraining(id uint, training *Training) error
-	DeleteTraining(id uint) error
+	DeleteTraining(id uint) ([]TrainingRegistration, error)
+
+	CreateTrainingSeries(series *TrainingSeries) ([]Training, error)
+	GetTrainingSeriesByID(id uint) (*TrainingSeries, error)
+	GetSeriesTrainings(training *Training, scope string) ([]Training, error)
+	DeleteTrainingSeries(id uint) error
+	CreateTrainingTemplate(template *TrainingTemplate) error
+	GetTrainingTemplates() ([]TrainingTemplate, error)
+	GetTrainingTemplateByID(id uint) (*TrainingTemplate, error)
+	DeleteTrainingTemplate(id uint) error
+	CheckScheduleConflicts(trainerId, trackId uint, start, end time.Time, excludeIds ...uint) (*ScheduleConflicts, error)
+	CreateTrackClosure(closure *TrackClosure) error
+	GetTrackClosures(trackId uint) ([]TrackClosure, error)
+	GetTrackClosureByID(id uint) (*TrackClosure, error)
+	DeleteTrackClosure(id uint) error
 
 	CreateTrainingRegistration(registration *TrainingRegistration) (uint, error)
 	GetTrainingRegistrationByID(id uint) (*TrainingRegistration, error)
@@ -44,7 +65,134 @@ type ContentRepositoryInterface interface {
 	GetTrainingRegistrationsByUserID(userId uint) ([]TrainingRegistration, error)
 	UpdateTrainingRegistration(id uint, registration *TrainingRegistration) error
 	DeleteTrainingRegistration(id uint) error
-	GetTrainingRegistrationByUserAndTraining(userId uint, trainingId uint) (*TrainingRegistration, error)
+	GetTrainingRegistrationByUserAndTraining(userId uint, trainingId uint, participantId uint) (*TrainingRegistration, error)
+	RegisterForTraining(trainingId, userId, participantId uint) (*TrainingRegistration, error)
+	CountActiveRegistrations(trainingId uint) (int64, error)
+	GetUserBookings(userId uint) ([]TrainingRegistration, error)
+	CancelRegistration(registrationId, userId uint, cutoff time.Duration) (*TrainingRegistration, error)
+
+	JoinWaitlist(trainingId, userId, participantId uint) (*TrainingRegistration, error)
+	GetWaitlistPosition(registrationId uint) (int, error)
+	PromoteFromWaitlist(trainingId uint, ttl time.Duration) ([]TrainingRegistration, error)
+	AcceptWaitlistOffer(registrationId, userId uint) (*TrainingRegistration, error)
+	DeclineWaitlistOffer(registrationId, userId uint) (*TrainingRegistration, error)
+	ExpireWaitlistOffers() ([]TrainingRegistration, error)
+
+	GetTrainingsAwaitingAttendance(now time.Time) ([]Training, error)
+	MarkAttendanceRequested(trainingId uint) error
+	GetAttendanceRegistrations(trainingId uint) ([]TrainingRegistration, error)
+	MarkAttendance(registrationId uint, status string) (*TrainingRegistration, error)
+	GetNoShowRestriction(userId uint, limit int, window time.Duration, now time.Time) (*time.Time, error)
+	GetUserAttendanceHistory(userId uint) ([]TrainingRegistration, error)
+
+	AddLapRecords(trainingId, userId, participantId uint, lapTimesMs []int) ([]LapRecord, error)
+	ImportLapRecords(trainingId uint, records []LapRecord) error
+	GetTrainingLapRecords(trainingId, userId, participantId uint) ([]LapRecord, error)
+	GetPersonalBests(userId, participantId uint) ([]PersonalBest, error)
+	GetTrackLapProgress(userId, trackId uint) ([]TrainingLapSummary, error)
+
+	RecordHeatResults(trainingId uint, registrationIds []uint) ([]HeatResult, error)
+	CountTrainingHeats(trainingId uint) (int, error)
+	GetUserRatings(userId uint) ([]UserRating, error)
+	GetUserRatingByID(id uint) (*UserRating, error)
+	GetRatingHistory(userId, participantId uint, carCategoryID *uint, limit int) ([]HeatResult, error)
+	GetRatingCategories() ([]RatingCategory, error)
+	GetRatingLeaderboard(carCategoryID *uint, limit int) ([]UserRating, error)
+
+	SaveTrainingFeedback(feedback *TrainingFeedback) error
+	MarkFeedbackDelivered(id uint) error
+	GetTrainingFeedbackByID(id uint) (*TrainingFeedback, error)
+	GetFeedbackByRegistration(registrationId uint) (*TrainingFeedback, error)
+	GetUserFeedback(userId uint) ([]TrainingFeedback, error)
+
+	CreatePendingSurveys(endedBefore time.Time) ([]TrainingSurvey, error)
+	GetTrainingSurveyByID(id uint) (*TrainingSurvey, error)
+	RateSurvey(surveyId, userId uint, rating int) (*TrainingSurvey, error)
+	SetSurveyComment(surveyId, userId uint, comment string) error
+	GetSurveyReport(since time.Time, lowRating int, lowLimit int) (*SurveyReport, error)
+
+	CreatePackage(pkg *Package) (uint, error)
+	GetActivePackages() ([]Package, error)
+	GetPackageByID(id uint) (*Package, error)
+	ArchivePackage(id uint) error
+	SellPackage(userId, packageId uint, adminChatId int) (*CreditLedger, error)
+	GrantCredits(userId uint, credits int, adminChatId int, note string) (*CreditLedger, error)
+	ReserveCredit(userId, registrationId uint) (*CreditLedger, error)
+	ConsumeCredit(registrationId uint, note string) (*CreditLedger, error)
+	RefundCredit(registrationId uint, note string) (*CreditLedger, error)
+	ForfeitCredit(registrationId uint, note string) (*CreditLedger, error)
+	GetCreditBalance(userId uint) (*CreditBalance, error)
+	GetCreditLedger(userId uint, limit int) ([]CreditLedger, error)
+	GetRegistrationCredit(registrationId uint) (*CreditLedger, error)
+	GetUserCreditBalances() ([]UserCreditBalance, error)
+
+	GetTrainingPrice(training *Training) (int, error)
+	SetTrainingPrice(trainingId uint, price int) error
+	GetCategoryPrices() ([]CategoryPrice, error)
+	SetCategoryPrice(carCategoryID uint, price int) error
+	MarkRegistrationPaid(registrationId uint, method string, amount int) (*TrainingRegistration, error)
+	RefundRegistrationPayment(registrationId uint) (*TrainingRegistration, error)
+	GetOutstandingPayments() ([]OutstandingPayment, error)
+
+	CreatePromoCode(promo *PromoCode) error
+	GetActivePromoCodes() ([]PromoCode, error)
+	GetPromoCodeByID(id uint) (*PromoCode, error)
+	DeactivatePromoCode(id uint) error
+	CountPromoCodeUses(id uint) (int64, error)
+	CheckPromoCode(code string, training *Training) (*PromoCode, error)
+	ApplyPromoCode(registrationId uint, promoCodeId uint) (*TrainingRegistration, error)
+
+	CreateKart(kart *Kart) error
+	GetTrackKarts(trackId uint) ([]Kart, error)
+	GetKartByID(id uint) (*Kart, error)
+	SetKartStatus(id uint, status string) (*Kart, error)
+	DeleteKart(id uint) error
+	GetKartUsage(trackId uint) (map[uint]KartUsage, error)
+	GetTrainingCapacity(training *Training) (int, error)
+	GetAvailableKarts(training *Training, registrationId uint) ([]Kart, error)
+	GetUpcomingFleetTrainings(trackId uint, carCategoryID *uint) ([]Training, error)
+	AssignKart(registrationId, kartId uint) (*TrainingRegistration, error)
+
+	GetTrackRentalStock(trackId uint) ([]RentalItem, error)
+	SetRentalStock(trackId uint, kind, size string, quantity int) error
+	GetRentalAvailability(training *Training, registrationId uint) ([]RentalAvailability, error)
+	GetRegistrationRentals(registrationId uint) ([]RentalPick, error)
+	GetTrainingRentals(trainingId uint) ([]RentalPick, error)
+	RequestRental(registrationId, rentalItemId uint) (*RentalRequest, error)
+	CancelRentalRequest(registrationId, rentalItemId uint) error
+	ReserveRentals(registrationId uint) ([]RentalPick, error)
+
+	CreateCarCategory(category *CarCategory) error
+	GetCarCategories() ([]CarCategory, error)
+	GetCarCategoryByID(id uint) (*CarCategory, error)
+	GetCarCategoryNames() (map[uint]string, error)
+	FindCarCategory(name string) (*CarCategory, error)
+	UpdateCarCategory(category *CarCategory) error
+	SetTrainingCarCategory(trainingId uint, carCategoryID *uint) error
+	DeleteCarCategory(id uint) error
+
+	CreateAvailability(window *TrainerAvailability) error
+	GetTrainerAvailability(trainerId uint) ([]TrainerAvailability, error)
+	GetTrackAvailability(trackId uint) ([]TrainerAvailability, error)
+	GetTracksWithAvailability() ([]Track, error)
+	GetAvailabilityByID(id uint) (*TrainerAvailability, error)
+	DeleteAvailability(id uint) error
+	CreateSessionRequest(request *SessionRequest) error
+	GetSessionRequestByID(id uint) (*SessionRequest, error)
+	GetPendingSessionRequests(trainerId uint) ([]SessionRequest, error)
+	GetUserSessionRequests(userId uint) ([]SessionRequest, error)
+	AcceptSessionRequest(requestId uint) (*SessionRequest, *TrainingRegistration, error)
+	DeclineSessionRequest(requestId uint) (*SessionRequest, error)
+
+	SetTrainingSkillLevels(trainingId uint, minLevel, maxLevel int) error
+	PromoteUser(registrationId uint, changedBy int) (*SkillLevelChange, error)
+	GetPromotedRegistrations(trainingId uint) (map[uint]bool, error)
+	GetSkillLevelChanges(userId uint) ([]SkillLevelChange, error)
+
+	CreateParticipant(participant *Participant) error
+	GetParticipants(userId uint) ([]Participant, error)
+	GetParticipantByID(id uint) (*Participant, error)
+	DeleteParticipant(id, userId uint) error
 
 	GetActiveTrainingsByTrackAndTrainer(trackId, trainerId uint) ([]Training, error)
 	GetTrainersByTrack(trackId uint) ([]Trainer, error)
@@ -0,0 +1,189 @@
+package database
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	apperrors "x.localhost/rvabot/internal/errors"
+	"x.localhost/rvabot/internal/logger"
+
+	"gorm.io/gorm"
+)
+
+// MaxSeriesOccurrences ограничивает число занятий, создаваемых одной серией
+const MaxSeriesOccurrences = 52
+
+// Область применения изменений к занятиям серии
+const (
+	SeriesScopeOne       = "one"
+	SeriesScopeFollowing = "following"
+	SeriesScopeAll       = "all"
+)
+
+func (TrainingSeries) TableName() string {
+	return "training_series"
+}
+
+// ParseWeekdays разбирает список дней недели вида "1,3,5"
+func ParseWeekdays(value string) []time.Weekday {
+	var days []time.Weekday
+	for _, part := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || n < 0 || n > 6 {
+			continue
+		}
+		days = append(days, time.Weekday(n))
+	}
+	return days
+}
+
+// FormatWeekdays сохраняет дни недели в порядке с понедельника
+func FormatWeekdays(days []time.Weekday) string {
+	sorted := make([]time.Weekday, len(days))
+	copy(sorted, days)
+	sort.Slice(sorted, func(i, j int) bool { return mondayFirst(sorted[i]) < mondayFirst(sorted[j]) })
+
+	parts := make([]string, 0, len(sorted))
+	for _, d := range sorted {
+		parts = append(parts, strconv.Itoa(int(d)))
+	}
+	return strings.Join(parts, ",")
+}
+
+// mondayFirst - номер дня в неделе, начинающейся с понедельника
+func mondayFirst(d time.Weekday) int {
+	return (int(d) + 6) % 7
+}
+
+// OccurrenceStarts рассчитывает время начала всех занятий серии: начиная с
+// даты FirstStart, в выбранные дни недели, до Until или Occurrences занятий.
+// Дни недели и время начала отсчитываются в часовом поясе трассы loc, поэтому
+// при переходе на летнее время занятия остаются в то же местное время.
+func (s *TrainingSeries) OccurrenceStarts(loc *time.Location) []time.Time {
+	selected := make(map[time.Weekday]bool)
+	for _, d := range ParseWeekdays(s.Weekdays) {
+		selected[d] = true
+	}
+	if len(selected) == 0 {
+		return nil
+	}
+
+	limit := MaxSeriesOccurrences
+	if s.Occurrences > 0 && s.Occurrences < limit {
+		limit = s.Occurrences
+	}
+
+	var starts []time.Time
+	first := s.FirstStart.In(loc)
+	day := first
+	for len(starts) < limit {
+		if s.Until != nil && day.After(*s.Until) {
+			break
+		}
+		if selected[day.Weekday()] {
+			starts = append(starts, day.UTC())
+		}
+		day = day.AddDate(0, 0, 1)
+
+		// Без ограничения по количеству серия не длиннее года
+		if day.Sub(first) > 366*24*time.Hour {
+			break
+		}
+	}
+	return starts
+}
+
+// CreateTrainingSeries создает серию и все ее занятия одной транзакцией
+func (r *ContentRepository) CreateTrainingSeries(series *TrainingSeries) ([]Training, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	starts := series.OccurrenceStarts(r.GetTrackLocation(series.TrackID))
+	if len(starts) == 0 {
+		return nil, apperrors.NewUserError("По выбранным дням недели не получилось ни одного занятия")
+	}
+
+	logger.DatabaseInfo("Создание серии: трасса %d, тренер %d, дни %s, занятий %d", series.TrackID, series.TrainerID, series.Weekdays, len(starts))
+
+	duration := time.Duration(series.DurationMinutes) * time.Minute
+	trainings := make([]Training, 0, len(starts))
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(series).Error; err != nil {
+			return mapConstraintError(err)
+		}
+
+		for _, start := range starts {
+			trainings = append(trainings, Training{
+				TrainerID:       series.TrainerID,
+				TrackID:         series.TrackID,
+				StartTime:       start,
+				EndTime:         start.Add(duration),
+				MaxParticipants: series.MaxParticipants,
+				CarCategoryID:   series.CarCategoryID,
+				IsActive:        true,
+				SeriesID:        &series.ID,
+			})
+		}
+		return mapConstraintError(tx.Create(&trainings).Error)
+	})
+	if err != nil {
+		logger.DatabaseError("Создание серии: %v", err)
+		return nil, err
+	}
+
+	logger.DatabaseInfo("Серия создана: ID=%d, занятий %d", series.ID, len(trainings))
+	return trainings, nil
+}
+
+// GetTrainingSeriesByID возвращает серию, включая архивные
+func (r *ContentRepository) GetTrainingSeriesByID(id uint) (*TrainingSeries, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var series TrainingSeries
+	if err := r.db.WithContext(ctx).Unscoped().First(&series, id).Error; err != nil {
+		logger.DatabaseError("Получение серии %d: %v", id, err)
+		return nil, err
+	}
+	return &series, nil
+}
+
+// GetSeriesTrainings возвращает будущие занятия, к которым применяется
+// изменение занятия training в выбранной области scope
+func (r *ContentRepository) GetSeriesTrainings(training *Training, scope string) ([]Training, error) {
+	if training.SeriesID == nil || scope == SeriesScopeOne || scope == "" {
+		return []Training{*training}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	query := r.db.WithContext(ctx).Where("series_id = ? AND start_time > ?", *training.SeriesID, time.Now().UTC())
+	if scope == SeriesScopeFollowing {
+		query = query.Where("start_time >= ?", training.StartTime)
+	}
+
+	var trainings []Training
+	if err := query.Order("start_time").Find(&trainings).Error; err != nil {
+		logger.DatabaseError("Занятия серии %d (%s): %v", *training.SeriesID, scope, err)
+		return nil, err
+	}
+	return trainings, nil
+}
+
+// DeleteTrainingSeries архивирует серию после отмены всех ее занятий
+func (r *ContentRepository) DeleteTrainingSeries(id uint) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := r.db.WithContext(ctx).Delete(&TrainingSeries{}, id).Error; err != nil {
+		logger.DatabaseError("Удаление серии %d: %v", id, err)
+		return err
+	}
+
+	logger.DatabaseInfo("Серия архивирована: %d", id)
+	return nil
+}
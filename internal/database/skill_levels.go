@@ -0,0 +1,196 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"x.localhost/rvabot/internal/logger"
+
+	"gorm.io/gorm"
+)
+
+// skillLevelHistoryLimit - сколько последних повышений уровня показывать
+const skillLevelHistoryLimit = 10
+
+// QualifiesForTraining проверяет, что уровень пользователя входит в диапазон
+// тренировки. Нулевая граница диапазона означает отсутствие ограничения.
+func QualifiesForTraining(level int, training *Training) bool {
+	if training.MinSkillLevel > 0 && level < training.MinSkillLevel {
+		return false
+	}
+	if training.MaxSkillLevel > 0 && level > training.MaxSkillLevel {
+		return false
+	}
+	return true
+}
+
+// checkSkillLevel внутри транзакции записи проверяет требования тренировки
+// к уровню пользователя или профиля участника, за которого он записывается
+func checkSkillLevel(tx *gorm.DB, trainingId, userId, participantId uint) error {
+	var training Training
+	if err := tx.Select("id", "min_skill_level", "max_skill_level").Where("id = ?", trainingId).Limit(1).Find(&training).Error; err != nil {
+		return err
+	}
+	if training.ID == 0 || (training.MinSkillLevel == 0 && training.MaxSkillLevel == 0) {
+		return nil
+	}
+
+	level, err := skillLevelOf(tx, userId, participantId)
+	if err != nil {
+		return err
+	}
+	if level != 0 && !QualifiesForTraining(level, &training) {
+		return newSkillLevelMismatchError()
+	}
+	return nil
+}
+
+// skillLevelOf возвращает уровень пользователя или профиля участника;
+// 0 - если запись не найдена
+func skillLevelOf(tx *gorm.DB, userId, participantId uint) (int, error) {
+	var levels []int
+	query := tx.Model(&User{}).Where("id = ?", userId)
+	if participantId != 0 {
+		query = tx.Model(&Participant{}).Unscoped().Where("id = ?", participantId)
+	}
+	if err := query.Limit(1).Pluck("skill_level", &levels).Error; err != nil {
+		return 0, err
+	}
+	if len(levels) == 0 {
+		return 0, nil
+	}
+	return levels[0], nil
+}
+
+// SetTrainingSkillLevels задает требования тренировки к уровню участников;
+// 0 снимает соответствующую границу
+func (r *ContentRepository) SetTrainingSkillLevels(trainingId uint, minLevel, maxLevel int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result := r.db.WithContext(ctx).Model(&Training{}).Where("id = ?", trainingId).
+		Updates(map[string]interface{}{"min_skill_level": minLevel, "max_skill_level": maxLevel})
+	if result.Error != nil {
+		logger.DatabaseError("Уровень тренировки %d: %v", trainingId, result.Error)
+		return result.Error
+	}
+
+	logger.DatabaseInfo("Уровень тренировки %d: %d-%d", trainingId, minLevel, maxLevel)
+	return nil
+}
+
+// PromoteUser повышает уровень участника на одну ступень по записи на
+// посещенную тренировку и сохраняет повышение в журнал. Для записи за
+// профиль участника повышается уровень профиля. По одной записи
+// уровень повышается только один раз.
+func (r *ContentRepository) PromoteUser(registrationId uint, changedBy int) (*SkillLevelChange, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var change SkillLevelChange
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var registration TrainingRegistration
+		if err := tx.Where("id = ?", registrationId).Limit(1).Find(&registration).Error; err != nil {
+			return err
+		}
+		if registration.ID == 0 || registration.Status != RegistrationStatusAttended {
+			return newPromotionRejectedError("участник не отмечен на тренировке")
+		}
+
+		var promoted int64
+		if err := tx.Model(&SkillLevelChange{}).Where("registration_id = ?", registrationId).Count(&promoted).Error; err != nil {
+			return err
+		}
+		if promoted > 0 {
+			return newPromotionRejectedError("по этой тренировке уровень уже повышен")
+		}
+
+		var training Training
+		if err := tx.Unscoped().Where("id = ?", registration.TrainingID).Limit(1).Find(&training).Error; err != nil {
+			return err
+		}
+
+		// Повышается тот, кто был на тренировке: сам пользователь или его участник
+		level, err := skillLevelOf(tx, registration.UserID, registration.ParticipantID)
+		if err != nil {
+			return err
+		}
+		if level == 0 {
+			return newPromotionRejectedError("участник не найден")
+		}
+		if level >= SkillLevelAdvanced {
+			return newPromotionRejectedError("у участника уже максимальный уровень")
+		}
+
+		// Условие на прежний уровень защищает от двойного повышения параллельными нажатиями
+		query := tx.Model(&User{}).Where("id = ?", registration.UserID)
+		if registration.ParticipantID != 0 {
+			query = tx.Model(&Participant{}).Unscoped().Where("id = ?", registration.ParticipantID)
+		}
+		result := query.Where("skill_level = ?", level).
+			Updates(map[string]interface{}{"skill_level": level + 1, "updated_at": time.Now().UTC()})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return newPromotionRejectedError("уровень участника уже изменен")
+		}
+
+		change = SkillLevelChange{
+			UserID:         registration.UserID,
+			FromLevel:      level,
+			ToLevel:        level + 1,
+			TrainerID:      training.TrainerID,
+			RegistrationID: registration.ID,
+			ParticipantID:  registration.ParticipantID,
+			ChangedBy:      changedBy,
+		}
+		return mapConstraintError(tx.Create(&change).Error)
+	})
+	if err != nil {
+		logger.DatabaseError("Повышение уровня по записи %d: %v", registrationId, err)
+		return nil, err
+	}
+
+	logger.DatabaseInfo("Уровень пользователя %d (участник %d) повышен: %d -> %d", change.UserID, change.ParticipantID, change.FromLevel, change.ToLevel)
+	return &change, nil
+}
+
+// GetPromotedRegistrations возвращает ID записей тренировки, по которым уже
+// повышен уровень участников
+func (r *ContentRepository) GetPromotedRegistrations(trainingId uint) (map[uint]bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var ids []uint
+	result := r.db.WithContext(ctx).Model(&SkillLevelChange{}).
+		Joins("INNER JOIN training_registrations ON training_registrations.id = skill_level_changes.registration_id").
+		Where("training_registrations.training_id = ?", trainingId).
+		Pluck("skill_level_changes.registration_id", &ids)
+	if result.Error != nil {
+		logger.DatabaseError("Повышения уровня на тренировке %d: %v", trainingId, result.Error)
+		return nil, result.Error
+	}
+
+	promoted := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		promoted[id] = true
+	}
+	return promoted, nil
+}
+
+// GetSkillLevelChanges возвращает последние повышения уровня пользователя
+// и профилей его участников
+func (r *ContentRepository) GetSkillLevelChanges(userId uint) ([]SkillLevelChange, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var changes []SkillLevelChange
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userId).
+		Order("created_at DESC").Limit(skillLevelHistoryLimit).Find(&changes).Error; err != nil {
+		logger.DatabaseError("Журнал уровня пользователя %d: %v", userId, err)
+		return nil, err
+	}
+
+	return changes, nil
+}
@@ -0,0 +1,186 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"x.localhost/rvabot/internal/logger"
+
+	"gorm.io/gorm"
+)
+
+// MaxSurveyRating - наибольшая оценка в опросе
+const MaxSurveyRating = 5
+
+// surveyLookback - по тренировкам, закончившимся раньше, опросы не отправляются,
+// чтобы после запуска опросов участники старых тренировок не получили их разом
+const surveyLookback = 7 * 24 * time.Hour
+
+// SurveyScore - средняя оценка тренера или трассы
+type SurveyScore struct {
+	ID        uint
+	Name      string
+	Average   float64
+	Responses int
+}
+
+// SurveyReport - сводка опросов за период
+type SurveyReport struct {
+	Sent      int
+	Answered  int
+	Trainers  []SurveyScore
+	Tracks    []SurveyScore
+	LowScores []TrainingSurvey // последние низкие оценки, начиная с новых
+}
+
+// CreatePendingSurveys создает опросы для посетивших тренировки, закончившиеся
+// до endedBefore, и возвращает их для отправки
+func (r *ContentRepository) CreatePendingSurveys(endedBefore time.Time) ([]TrainingSurvey, error) {
+	endedBefore = endedBefore.UTC()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var surveys []TrainingSurvey
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&TrainingRegistration{}).
+			Select("training_registrations.id AS registration_id, training_registrations.training_id, training_registrations.user_id, "+
+				"trainings.trainer_id, trainings.track_id").
+			Joins("INNER JOIN trainings ON trainings.id = training_registrations.training_id").
+			Where("training_registrations.status = ? AND trainings.end_time <= ? AND trainings.end_time > ?",
+				RegistrationStatusAttended, endedBefore, endedBefore.Add(-surveyLookback)).
+			Where("NOT EXISTS (SELECT 1 FROM training_surveys WHERE training_surveys.registration_id = training_registrations.id)").
+			Order("trainings.end_time").
+			Scan(&surveys).Error; err != nil {
+			return err
+		}
+		if len(surveys) == 0 {
+			return nil
+		}
+
+		return mapConstraintError(tx.Create(&surveys).Error)
+	})
+	if err != nil {
+		logger.DatabaseError("Создание опросов: %v", err)
+		return nil, err
+	}
+
+	if len(surveys) > 0 {
+		logger.DatabaseInfo("Создано опросов: %d", len(surveys))
+	}
+	return surveys, nil
+}
+
+// GetTrainingSurveyByID возвращает опрос по ID
+func (r *ContentRepository) GetTrainingSurveyByID(id uint) (*TrainingSurvey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var survey TrainingSurvey
+	result := r.db.WithContext(ctx).Where("id = ?", id).Limit(1).Find(&survey)
+	if result.Error != nil {
+		logger.DatabaseError("Опрос %d: %v", id, result.Error)
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	return &survey, nil
+}
+
+// RateSurvey сохраняет оценку пользователя в его опросе. Оценку можно изменить.
+func (r *ContentRepository) RateSurvey(surveyId, userId uint, rating int) (*TrainingSurvey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if rating < 1 || rating > MaxSurveyRating {
+		return nil, newSurveyNotFoundError().WithUserMessage("Оценка должна быть от 1 до 5")
+	}
+
+	result := r.db.WithContext(ctx).Model(&TrainingSurvey{}).
+		Where("id = ? AND user_id = ?", surveyId, userId).
+		Updates(map[string]interface{}{"rating": rating, "answered_at": time.Now().UTC()})
+	if result.Error != nil {
+		logger.DatabaseError("Оценка опроса %d: %v", surveyId, result.Error)
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, newSurveyNotFoundError()
+	}
+
+	logger.DatabaseInfo("Опрос %d: оценка %d", surveyId, rating)
+	return r.GetTrainingSurveyByID(surveyId)
+}
+
+// SetSurveyComment сохраняет комментарий пользователя к оцененному опросу
+func (r *ContentRepository) SetSurveyComment(surveyId, userId uint, comment string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result := r.db.WithContext(ctx).Model(&TrainingSurvey{}).
+		Where("id = ? AND user_id = ? AND rating > 0", surveyId, userId).
+		Update("comment", comment)
+	if result.Error != nil {
+		logger.DatabaseError("Комментарий опроса %d: %v", surveyId, result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return newSurveyNotFoundError()
+	}
+
+	return nil
+}
+
+// GetSurveyReport собирает средние оценки тренеров и трасс по опросам,
+// отправленным после since, и последние оценки не выше lowRating
+func (r *ContentRepository) GetSurveyReport(since time.Time, lowRating int, lowLimit int) (*SurveyReport, error) {
+	since = since.UTC()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	db := r.db.WithContext(ctx)
+	report := &SurveyReport{}
+
+	var sent, answered int64
+	if err := db.Model(&TrainingSurvey{}).Where("created_at >= ?", since).Count(&sent).Error; err != nil {
+		logger.DatabaseError("Отчет по опросам: %v", err)
+		return nil, err
+	}
+	if err := db.Model(&TrainingSurvey{}).Where("created_at >= ? AND rating > 0", since).Count(&answered).Error; err != nil {
+		logger.DatabaseError("Отчет по опросам: %v", err)
+		return nil, err
+	}
+	report.Sent, report.Answered = int(sent), int(answered)
+
+	if err := db.Model(&TrainingSurvey{}).
+		Select("trainers.id, trainers.name, AVG(training_surveys.rating) AS average, COUNT(*) AS responses").
+		Joins("INNER JOIN trainers ON trainers.id = training_surveys.trainer_id").
+		Where("training_surveys.created_at >= ? AND training_surveys.rating > 0", since).
+		Group("trainers.id, trainers.name").
+		Order("average DESC, responses DESC").
+		Scan(&report.Trainers).Error; err != nil {
+		logger.DatabaseError("Оценки тренеров: %v", err)
+		return nil, err
+	}
+
+	if err := db.Model(&TrainingSurvey{}).
+		Select("tracks.id, tracks.name, AVG(training_surveys.rating) AS average, COUNT(*) AS responses").
+		Joins("INNER JOIN tracks ON tracks.id = training_surveys.track_id").
+		Where("training_surveys.created_at >= ? AND training_surveys.rating > 0", since).
+		Group("tracks.id, tracks.name").
+		Order("average DESC, responses DESC").
+		Scan(&report.Tracks).Error; err != nil {
+		logger.DatabaseError("Оценки трасс: %v", err)
+		return nil, err
+	}
+
+	if err := db.Where("created_at >= ? AND rating > 0 AND rating <= ?", since, lowRating).
+		Order("answered_at DESC").
+		Limit(lowLimit).
+		Find(&report.LowScores).Error; err != nil {
+		logger.DatabaseError("Низкие оценки: %v", err)
+		return nil, err
+	}
+
+	return report, nil
+}
@@ -0,0 +1,82 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	apperrors "x.localhost/rvabot/internal/errors"
+	"x.localhost/rvabot/internal/logger"
+	"x.localhost/rvabot/internal/timefmt"
+)
+
+// MaxTemplateNameLength ограничивает длину названия шаблона
+const MaxTemplateNameLength = 50
+
+// NewTrainingTemplate собирает шаблон из параметров существующей тренировки;
+// время начала сохраняется по часам пояса трассы loc
+func NewTrainingTemplate(name string, training *Training, loc *time.Location) *TrainingTemplate {
+	return &TrainingTemplate{
+		Name:            strings.TrimSpace(name),
+		TrainerID:       training.TrainerID,
+		TrackID:         training.TrackID,
+		TimeOfDay:       timefmt.Clock(training.StartTime, loc),
+		DurationMinutes: int(training.EndTime.Sub(training.StartTime).Minutes()),
+		MaxParticipants: training.MaxParticipants,
+		CarCategoryID:   training.CarCategoryID,
+	}
+}
+
+func (r *ContentRepository) CreateTrainingTemplate(template *TrainingTemplate) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if template.Name == "" || len([]rune(template.Name)) > MaxTemplateNameLength {
+		return apperrors.NewUserError("Название шаблона должно быть от 1 до 50 символов")
+	}
+
+	if err := r.db.WithContext(ctx).Create(template).Error; err != nil {
+		logger.DatabaseError("Создание шаблона тренировки: %v", err)
+		return mapConstraintError(err)
+	}
+
+	logger.DatabaseInfo("Шаблон тренировки создан: %d", template.ID)
+	return nil
+}
+
+func (r *ContentRepository) GetTrainingTemplates() ([]TrainingTemplate, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var templates []TrainingTemplate
+	if err := r.db.WithContext(ctx).Order("name").Find(&templates).Error; err != nil {
+		logger.DatabaseError("Не удалось получить шаблоны тренировок: %v", err)
+		return nil, err
+	}
+	return templates, nil
+}
+
+func (r *ContentRepository) GetTrainingTemplateByID(id uint) (*TrainingTemplate, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var template TrainingTemplate
+	if err := r.db.WithContext(ctx).First(&template, id).Error; err != nil {
+		logger.DatabaseError("Получение шаблона %d: %v", id, err)
+		return nil, err
+	}
+	return &template, nil
+}
+
+func (r *ContentRepository) DeleteTrainingTemplate(id uint) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := r.db.WithContext(ctx).Delete(&TrainingTemplate{}, id).Error; err != nil {
+		logger.DatabaseError("Удаление шаблона %d: %v", id, err)
+		return err
+	}
+
+	logger.DatabaseInfo("Шаблон тренировки удален: %d", id)
+	return nil
+}
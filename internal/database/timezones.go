@@ -0,0 +1,64 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"x.localhost/rvabot/internal/logger"
+	"x.localhost/rvabot/internal/timefmt"
+)
+
+// SetTrackTimezone сохраняет IANA-пояс трассы; пустая строка - пояс академии
+func (r *ContentRepository) SetTrackTimezone(trackId uint, timezone string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := r.db.WithContext(ctx).Model(&Track{}).Where("id = ?", trackId).Update("timezone", timezone).Error; err != nil {
+		logger.DatabaseError("Часовой пояс трассы %d: %v", trackId, err)
+		return err
+	}
+
+	logger.DatabaseInfo("Часовой пояс трассы %d: %q", trackId, timezone)
+	return nil
+}
+
+// SetUserTimezone сохраняет IANA-пояс пользователя; пустая строка - пояс трассы тренировки
+func (r *ContentRepository) SetUserTimezone(userId uint, timezone string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := r.db.WithContext(ctx).Model(&User{}).Where("id = ?", userId).Update("timezone", timezone).Error; err != nil {
+		logger.DatabaseError("Часовой пояс пользователя %d: %v", userId, err)
+		return err
+	}
+
+	logger.DatabaseInfo("Часовой пояс пользователя %d: %q", userId, timezone)
+	return nil
+}
+
+// GetTrackLocation возвращает часовой пояс трассы, в котором вводится и
+// рассчитывается расписание; без своего пояса трасса работает по поясу академии
+func (r *ContentRepository) GetTrackLocation(trackId uint) *time.Location {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var track Track
+	if err := r.db.WithContext(ctx).Unscoped().Select("timezone").First(&track, trackId).Error; err != nil {
+		return timefmt.Academy()
+	}
+	return timefmt.Resolve(track.Timezone)
+}
+
+// GetViewerLocation возвращает часовой пояс, в котором показывать время
+// занятия на трассе trackId пользователю чата chatId: его собственный, если
+// задан, иначе пояс трассы
+func (r *ContentRepository) GetViewerLocation(chatId int, trackId uint) *time.Location {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var user User
+	if err := r.db.WithContext(ctx).Select("timezone").Where("chat_id = ?", chatId).Limit(1).Find(&user).Error; err == nil && user.Timezone != "" {
+		return timefmt.Resolve(user.Timezone)
+	}
+	return r.GetTrackLocation(trackId)
+}
@@ -0,0 +1,275 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"x.localhost/rvabot/internal/logger"
+
+	"gorm.io/gorm"
+)
+
+// JoinWaitlist ставит пользователя или его участника в конец листа ожидания
+// тренировки. Отмененная ранее запись переиспользуется, как и в RegisterForTraining.
+func (r *ContentRepository) JoinWaitlist(trainingId, userId, participantId uint) (*TrainingRegistration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	logger.DatabaseInfo("Лист ожидания: TrainingID=%d, UserID=%d, ParticipantID=%d", trainingId, userId, participantId)
+
+	var registration TrainingRegistration
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now().UTC()
+
+		var training Training
+		if err := tx.Where("id = ? AND is_active = ? AND start_time > ?", trainingId, true, now).Limit(1).Find(&training).Error; err != nil {
+			return err
+		}
+		if training.ID == 0 {
+			return newTrainingUnavailableError()
+		}
+
+		if err := checkParticipant(tx, userId, participantId); err != nil {
+			return err
+		}
+
+		var existing TrainingRegistration
+		if err := tx.Where("training_id = ? AND user_id = ? AND participant_id = ?", trainingId, userId, participantId).Limit(1).Find(&existing).Error; err != nil {
+			return err
+		}
+		if existing.ID != 0 && existing.Status != RegistrationStatusCancelled {
+			return newAlreadyRegisteredError(nil)
+		}
+
+		if err := checkSkillLevel(tx, trainingId, userId, participantId); err != nil {
+			return err
+		}
+
+		var lastPosition int
+		if err := tx.Model(&TrainingRegistration{}).
+			Where("training_id = ? AND status = ?", trainingId, RegistrationStatusWaitlisted).
+			Select("COALESCE(MAX(waitlist_position), 0)").Scan(&lastPosition).Error; err != nil {
+			return err
+		}
+
+		if existing.ID != 0 {
+			existing.Status = RegistrationStatusWaitlisted
+			existing.WaitlistPosition = lastPosition + 1
+			existing.OfferExpiresAt = nil
+			existing.CancelledAt = nil
+			existing.LateCancellation = false
+			if err := tx.Save(&existing).Error; err != nil {
+				return err
+			}
+			registration = existing
+			return nil
+		}
+
+		registration = TrainingRegistration{
+			TrainingID:       trainingId,
+			UserID:           userId,
+			ParticipantID:    participantId,
+			Status:           RegistrationStatusWaitlisted,
+			WaitlistPosition: lastPosition + 1,
+		}
+		return mapConstraintError(tx.Create(&registration).Error)
+	})
+	if err != nil {
+		logger.DatabaseError("Лист ожидания тренировки %d, пользователь %d: %v", trainingId, userId, err)
+		return nil, err
+	}
+
+	logger.DatabaseInfo("Пользователь %d в листе ожидания тренировки %d: ID=%d", userId, trainingId, registration.ID)
+	return &registration, nil
+}
+
+// GetWaitlistPosition возвращает место записи в очереди, начиная с 1.
+// Для записей вне листа ожидания возвращает 0.
+func (r *ContentRepository) GetWaitlistPosition(registrationId uint) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var registration TrainingRegistration
+	if err := r.db.WithContext(ctx).First(&registration, registrationId).Error; err != nil {
+		logger.DatabaseError("Позиция в листе ожидания для записи %d: %v", registrationId, err)
+		return 0, err
+	}
+	if registration.Status != RegistrationStatusWaitlisted {
+		return 0, nil
+	}
+
+	var ahead int64
+	err := r.db.WithContext(ctx).Model(&TrainingRegistration{}).
+		Where("training_id = ? AND status = ? AND (waitlist_position < ? OR (waitlist_position = ? AND id < ?))",
+			registration.TrainingID, RegistrationStatusWaitlisted,
+			registration.WaitlistPosition, registration.WaitlistPosition, registration.ID).
+		Count(&ahead).Error
+	if err != nil {
+		logger.DatabaseError("Позиция в листе ожидания для записи %d: %v", registrationId, err)
+		return 0, err
+	}
+
+	return int(ahead) + 1, nil
+}
+
+// PromoteFromWaitlist предлагает освободившиеся места первым в очереди.
+// Предложение действует ttl, но не дольше начала тренировки.
+func (r *ContentRepository) PromoteFromWaitlist(trainingId uint, ttl time.Duration) ([]TrainingRegistration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var offered []TrainingRegistration
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now().UTC()
+
+		var training Training
+		if err := tx.Where("id = ? AND is_active = ? AND start_time > ?", trainingId, true, now).Limit(1).Find(&training).Error; err != nil {
+			return err
+		}
+		if training.ID == 0 {
+			return nil
+		}
+
+		var taken int64
+		if err := tx.Model(&TrainingRegistration{}).
+			Where("training_id = ? AND status IN ?", trainingId, activeRegistrationStatuses).
+			Count(&taken).Error; err != nil {
+			return err
+		}
+
+		capacity, err := trainingCapacity(tx, &training)
+		if err != nil {
+			return err
+		}
+
+		free := capacity - int(taken)
+		if free <= 0 {
+			return nil
+		}
+
+		var candidates []TrainingRegistration
+		if err := tx.Where("training_id = ? AND status = ?", trainingId, RegistrationStatusWaitlisted).
+			Order("waitlist_position, id").Limit(free).Find(&candidates).Error; err != nil {
+			return err
+		}
+
+		expiresAt := now.Add(ttl)
+		if training.StartTime.Before(expiresAt) {
+			expiresAt = training.StartTime
+		}
+
+		for _, candidate := range candidates {
+			result := tx.Model(&TrainingRegistration{}).
+				Where("id = ? AND status = ?", candidate.ID, RegistrationStatusWaitlisted).
+				Updates(map[string]interface{}{
+					"status":           RegistrationStatusOffered,
+					"offer_expires_at": expiresAt,
+					"updated_at":       now,
+				})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				continue
+			}
+
+			candidate.Status = RegistrationStatusOffered
+			candidate.OfferExpiresAt = &expiresAt
+			offered = append(offered, candidate)
+		}
+
+		return nil
+	})
+	if err != nil {
+		logger.DatabaseError("Продвижение листа ожидания тренировки %d: %v", trainingId, err)
+		return nil, err
+	}
+
+	if len(offered) > 0 {
+		logger.DatabaseInfo("Лист ожидания тренировки %d: предложено мест %d", trainingId, len(offered))
+	}
+	return offered, nil
+}
+
+// AcceptWaitlistOffer подтверждает предложенное место, пока оно не истекло
+func (r *ContentRepository) AcceptWaitlistOffer(registrationId, userId uint) (*TrainingRegistration, error) {
+	return r.answerWaitlistOffer(registrationId, userId, RegistrationStatusPending)
+}
+
+// DeclineWaitlistOffer отказывается от предложенного места
+func (r *ContentRepository) DeclineWaitlistOffer(registrationId, userId uint) (*TrainingRegistration, error) {
+	return r.answerWaitlistOffer(registrationId, userId, RegistrationStatusCancelled)
+}
+
+// answerWaitlistOffer переводит действующее предложение в новый статус
+func (r *ContentRepository) answerWaitlistOffer(registrationId, userId uint, status string) (*TrainingRegistration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now().UTC()
+	result := r.db.WithContext(ctx).Model(&TrainingRegistration{}).
+		Where("id = ? AND user_id = ? AND status = ? AND offer_expires_at > ?", registrationId, userId, RegistrationStatusOffered, now).
+		Updates(map[string]interface{}{
+			"status":            status,
+			"waitlist_position": 0,
+			"offer_expires_at":  nil,
+			"updated_at":        now,
+		})
+	if result.Error != nil {
+		logger.DatabaseError("Ответ на предложение места %d: %v", registrationId, result.Error)
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, newOfferExpiredError()
+	}
+
+	var registration TrainingRegistration
+	if err := r.db.WithContext(ctx).First(&registration, registrationId).Error; err != nil {
+		logger.DatabaseError("Ответ на предложение места %d: %v", registrationId, err)
+		return nil, err
+	}
+
+	logger.DatabaseInfo("Предложение места %d: %s", registrationId, status)
+	return &registration, nil
+}
+
+// ExpireWaitlistOffers отменяет просроченные предложения и возвращает их
+func (r *ContentRepository) ExpireWaitlistOffers() ([]TrainingRegistration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var expired []TrainingRegistration
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now().UTC()
+
+		if err := tx.Where("status = ? AND offer_expires_at <= ?", RegistrationStatusOffered, now).Find(&expired).Error; err != nil {
+			return err
+		}
+		if len(expired) == 0 {
+			return nil
+		}
+
+		ids := make([]uint, 0, len(expired))
+		for _, reg := range expired {
+			ids = append(ids, reg.ID)
+		}
+
+		return tx.Model(&TrainingRegistration{}).
+			Where("id IN ? AND status = ?", ids, RegistrationStatusOffered).
+			Updates(map[string]interface{}{
+				"status":            RegistrationStatusCancelled,
+				"waitlist_position": 0,
+				"offer_expires_at":  nil,
+				"updated_at":        now,
+			}).Error
+	})
+	if err != nil {
+		logger.DatabaseError("Истечение предложений листа ожидания: %v", err)
+		return nil, err
+	}
+
+	if len(expired) > 0 {
+		logger.DatabaseInfo("Истекло предложений листа ожидания: %d", len(expired))
+	}
+	return expired, nil
+}
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"fmt"
 	"strconv"
 	"strings"
 
@@ -45,6 +46,8 @@ func (ch *CallbackHandler) HandleCallback(query *telegram.CallbackQuery, state s
 		return ch.handleConfirmAction(chatId, messageId, state)
 	case "cancel":
 		return ch.handleCancelAction(chatId, messageId, state)
+	case "back":
+		return commands.GoBack(ch.botUrl, chatId, messageId, ch.repo, state)
 	case "dataConsentYes":
 		return commands.HandleDataConsentYes(ch.botUrl, chatId, messageId, ch.repo, state)
 	}
@@ -110,7 +113,28 @@ func (ch *CallbackHandler) handlePrefixedCallback(prefix string, id int, chatId,
 			return commands.ConfirmTrainingRegistration(ch.botUrl, chatId, messageId, uint(id), ch.repo)
 		},
 		"confirmTrainingRegistration": func() states.State {
-			return commands.ExecuteTrainingRegistration(ch.botUrl, chatId, messageId, uint(id), ch.repo)
+			return commands.ExecuteTrainingRegistration(ch.botUrl, chatId, messageId, uint(id), ch.repo, state)
+		},
+		"joinWaitlist": func() states.State {
+			return commands.JoinTrainingWaitlist(ch.botUrl, chatId, messageId, uint(id), ch.repo, state)
+		},
+		"bookFor": func() states.State {
+			return commands.SelectRegistrationParticipant(ch.botUrl, chatId, messageId, uint(id), ch.repo, state)
+		},
+		"deleteParticipant": func() states.State {
+			return commands.DeleteParticipant(ch.botUrl, chatId, messageId, uint(id), ch.repo)
+		},
+		"acceptOffer": func() states.State {
+			return commands.AcceptWaitlistOffer(ch.botUrl, chatId, messageId, uint(id), ch.repo)
+		},
+		"declineOffer": func() states.State {
+			return commands.DeclineWaitlistOffer(ch.botUrl, chatId, messageId, uint(id), ch.repo)
+		},
+		"cancelBooking": func() states.State {
+			return commands.ConfirmBookingCancellation(ch.botUrl, chatId, messageId, uint(id), ch.repo)
+		},
+		"confirmCancelBooking": func() states.State {
+			return commands.ExecuteBookingCancellation(ch.botUrl, chatId, messageId, uint(id), ch.repo)
 		},
 		"approveRegistration": func() states.State {
 			return commands.ApproveTrainingRegistration(ch.botUrl, chatId, messageId, uint(id), ch.repo)
@@ -129,9 +153,159 @@ func (ch *CallbackHandler) handlePrefixedCallback(prefix string, id int, chatId,
 		"editTrainingCategory": func() states.State {
 			return commands.EditTrainingCategory(ch.botUrl, chatId, messageId, uint(id), ch.repo)
 		},
+		"editTrainingParticipants": func() states.State {
+			return commands.EditTrainingMaxParticipants(ch.botUrl, chatId, messageId, uint(id), ch.repo)
+		},
+		"editTrainingPrice": func() states.State {
+			return commands.EditTrainingPrice(ch.botUrl, chatId, messageId, uint(id), ch.repo)
+		},
+		"editTrainingSkillLevel": func() states.State {
+			return commands.EditTrainingSkillLevel(ch.botUrl, chatId, messageId, uint(id), ch.repo)
+		},
+		"editSkillLevel": func() states.State {
+			return commands.SelectEditTrainingSkillLevel(ch.botUrl, chatId, messageId, uint(id), ch.repo, state)
+		},
+		"payCash": func() states.State {
+			return commands.MarkPaymentCash(ch.botUrl, chatId, messageId, uint(id), ch.repo)
+		},
+		"payTransfer": func() states.State {
+			return commands.MarkPaymentTransfer(ch.botUrl, chatId, messageId, uint(id), ch.repo)
+		},
+		"refundPayment": func() states.State {
+			return commands.RefundPayment(ch.botUrl, chatId, messageId, uint(id), ch.repo)
+		},
+		"deactivatePromo": func() states.State {
+			return commands.DeactivatePromoCode(ch.botUrl, chatId, messageId, uint(id), ch.repo)
+		},
+		"promoTrack": func() states.State {
+			return commands.SelectPromoTrack(ch.botUrl, chatId, messageId, uint(id), ch.repo, state)
+		},
+		"enterPromo": func() states.State {
+			return commands.EnterPromoCode(ch.botUrl, chatId, messageId, uint(id), state)
+		},
+		"trackKarts": func() states.State {
+			return commands.ViewTrackKarts(ch.botUrl, chatId, messageId, uint(id), ch.repo)
+		},
+		"addKart": func() states.State {
+			return commands.AddKart(ch.botUrl, chatId, messageId, uint(id), ch.repo)
+		},
+		"toggleKart": func() states.State {
+			return commands.ToggleKartStatus(ch.botUrl, chatId, messageId, uint(id), ch.repo)
+		},
+		"deleteKart": func() states.State {
+			return commands.DeleteKart(ch.botUrl, chatId, messageId, uint(id), ch.repo)
+		},
+		"kartAssignments": func() states.State {
+			return commands.ViewKartAssignments(ch.botUrl, chatId, messageId, uint(id), ch.repo)
+		},
+		"assignKart": func() states.State {
+			return commands.SelectKartAssignment(ch.botUrl, chatId, messageId, uint(id), ch.repo)
+		},
+		"setKart": func() states.State {
+			return commands.AssignKart(ch.botUrl, chatId, messageId, uint(id), ch.repo, state)
+		},
+		"trackRentals": func() states.State {
+			return commands.ViewTrackRentalStock(ch.botUrl, chatId, messageId, uint(id), ch.repo)
+		},
+		"setRentalStock": func() states.State {
+			return commands.AddRentalStock(ch.botUrl, chatId, messageId, uint(id), ch.repo)
+		},
+		"rentals": func() states.State {
+			return commands.ViewRegistrationRentals(ch.botUrl, chatId, messageId, uint(id), ch.repo)
+		},
+		"rentItem": func() states.State {
+			return commands.ToggleRentalItem(ch.botUrl, chatId, messageId, uint(id), ch.repo, state)
+		},
+		"rentalPickList": func() states.State {
+			return commands.ViewTrainingRentalPickList(ch.botUrl, chatId, messageId, uint(id), ch.repo)
+		},
+		"editCarCategory": func() states.State {
+			return commands.EditCarCategory(ch.botUrl, chatId, messageId, uint(id), ch.repo)
+		},
+		"deleteCarCategory": func() states.State {
+			return commands.DeleteCarCategory(ch.botUrl, chatId, messageId, uint(id), ch.repo)
+		},
+		"trainingCategory": func() states.State {
+			return commands.SelectTrainingCarCategory(ch.botUrl, chatId, messageId, uint(id), ch.repo, state)
+		},
+		"editCategory": func() states.State {
+			return commands.SelectEditTrainingCategory(ch.botUrl, chatId, messageId, uint(id), ch.repo, state)
+		},
+		"promoCategory": func() states.State {
+			return commands.SelectPromoCarCategory(ch.botUrl, chatId, messageId, uint(id), ch.repo, state)
+		},
+		"availabilityTrack": func() states.State {
+			return commands.SelectAvailabilityTrack(ch.botUrl, chatId, messageId, uint(id), ch.repo, state)
+		},
+		"deleteAvailability": func() states.State {
+			return commands.DeleteAvailability(ch.botUrl, chatId, messageId, uint(id), ch.repo)
+		},
+		"acceptSession": func() states.State {
+			return commands.AcceptSessionRequest(ch.botUrl, chatId, messageId, uint(id), ch.repo)
+		},
+		"declineSession": func() states.State {
+			return commands.DeclineSessionRequest(ch.botUrl, chatId, messageId, uint(id), ch.repo)
+		},
+		"sessionTrack": func() states.State {
+			return commands.SelectSessionTrack(ch.botUrl, chatId, messageId, uint(id), ch.repo, state)
+		},
+		"sessionWindow": func() states.State {
+			return commands.SelectSessionWindow(ch.botUrl, chatId, messageId, uint(id), ch.repo, state)
+		},
 		"viewRegistrations": func() states.State {
 			return commands.ViewTrainingRegistrations(ch.botUrl, chatId, messageId, uint(id), ch.repo)
 		},
+		"attendanceChecklist": func() states.State {
+			return commands.ViewAttendanceChecklist(ch.botUrl, chatId, messageId, uint(id), ch.repo)
+		},
+		"markAttended": func() states.State {
+			return commands.MarkAttended(ch.botUrl, chatId, messageId, uint(id), ch.repo)
+		},
+		"markNoShow": func() states.State {
+			return commands.MarkNoShow(ch.botUrl, chatId, messageId, uint(id), ch.repo)
+		},
+		"promoteUser": func() states.State {
+			return commands.PromoteUser(ch.botUrl, chatId, messageId, uint(id), ch.repo)
+		},
+		"enterLaps": func() states.State {
+			return commands.EnterLapTimes(ch.botUrl, chatId, messageId, uint(id), ch.repo)
+		},
+		"importLaps": func() states.State {
+			return commands.ImportLapFile(ch.botUrl, chatId, messageId, uint(id), ch.repo)
+		},
+		"heatResults": func() states.State {
+			return commands.EnterHeatResults(ch.botUrl, chatId, messageId, uint(id), ch.repo)
+		},
+		"ratingLeaderboard": func() states.State {
+			return commands.ViewRatingLeaderboard(ch.botUrl, chatId, messageId, uint(id), ch.repo)
+		},
+		"writeFeedback": func() states.State {
+			return commands.WriteFeedback(ch.botUrl, chatId, messageId, uint(id), ch.repo)
+		},
+		"viewFeedback": func() states.State {
+			return commands.ViewFeedback(ch.botUrl, chatId, messageId, uint(id), ch.repo)
+		},
+		"surveyComment": func() states.State {
+			return commands.CommentSurvey(ch.botUrl, chatId, messageId, uint(id), ch.repo)
+		},
+		"archivePackage": func() states.State {
+			return commands.ArchivePackage(ch.botUrl, chatId, messageId, uint(id), ch.repo)
+		},
+		"userCredits": func() states.State {
+			return commands.ViewUserCredits(ch.botUrl, chatId, messageId, uint(id), ch.repo)
+		},
+		"sellPackage": func() states.State {
+			return commands.SellPackage(ch.botUrl, chatId, messageId, uint(id), ch.repo, state)
+		},
+		"grantCredits": func() states.State {
+			return commands.GrantCredits(ch.botUrl, chatId, messageId, uint(id), ch.repo)
+		},
+		"trackResults": func() states.State {
+			return commands.ViewTrackResults(ch.botUrl, chatId, messageId, uint(id), ch.repo)
+		},
+		"userAttendance": func() states.State {
+			return commands.ViewUserAttendance(ch.botUrl, chatId, messageId, uint(id), ch.repo)
+		},
 		"toggleTrainingStatus": func() states.State {
 			return commands.ToggleTrainingStatus(ch.botUrl, chatId, messageId, uint(id), ch.repo)
 		},
@@ -139,7 +313,11 @@ func (ch *CallbackHandler) handlePrefixedCallback(prefix string, id int, chatId,
 			return commands.ConfirmTrainingDeletion(ch.botUrl, chatId, messageId, uint(id), ch.repo)
 		},
 		"confirmDeleteTraining": func() states.State {
-			return commands.ExecuteTrainingDeletion(ch.botUrl, chatId, messageId, uint(id), ch.repo)
+			scope := database.SeriesScopeOne
+			if state.Type == states.StateConfirmTrainingDelete && state.GetID() == uint(id) && state.GetSeriesScope() != "" {
+				scope = state.GetSeriesScope()
+			}
+			return commands.ExecuteTrainingDeletion(ch.botUrl, chatId, messageId, uint(id), scope, ch.repo)
 		},
 		"selectTrackForRegistration": func() states.State {
 			return commands.SelectTrackForRegistration(ch.botUrl, chatId, messageId, uint(id), ch.repo, state)
@@ -150,11 +328,52 @@ func (ch *CallbackHandler) handlePrefixedCallback(prefix string, id int, chatId,
 		"selectTrainingTimeForRegistration": func() states.State {
 			return commands.SelectTrainingTimeForRegistration(ch.botUrl, chatId, messageId, uint(id), ch.repo, state)
 		},
+		"trackClosures": func() states.State {
+			return commands.ViewTrackClosures(ch.botUrl, chatId, messageId, uint(id), ch.repo)
+		},
+		"addTrackClosure": func() states.State {
+			return commands.AddTrackClosure(ch.botUrl, chatId, messageId, uint(id), ch.repo)
+		},
+		"deleteTrackClosure": func() states.State {
+			return commands.DeleteTrackClosure(ch.botUrl, chatId, messageId, uint(id), ch.repo)
+		},
+		"editTrackTimezone": func() states.State {
+			return commands.EditTrackTimezone(ch.botUrl, chatId, messageId, uint(id), ch.repo)
+		},
+		"setUserTimezone": func() states.State {
+			return commands.SelectUserTimezone(ch.botUrl, chatId, messageId, id, ch.repo)
+		},
+		"duplicateTraining": func() states.State {
+			return commands.DuplicateTraining(ch.botUrl, chatId, messageId, uint(id), ch.repo)
+		},
+		"saveTrainingTemplate": func() states.State {
+			return commands.SaveTrainingTemplate(ch.botUrl, chatId, messageId, uint(id), ch.repo)
+		},
+		"useTrainingTemplate": func() states.State {
+			return commands.UseTrainingTemplate(ch.botUrl, chatId, messageId, uint(id), ch.repo)
+		},
+		"deleteTrainingTemplate": func() states.State {
+			return commands.ConfirmTemplateDeletion(ch.botUrl, chatId, messageId, uint(id), ch.repo)
+		},
+		"confirmDeleteTrainingTemplate": func() states.State {
+			return commands.ExecuteTemplateDeletion(ch.botUrl, chatId, messageId, uint(id), ch.repo)
+		},
+		"toggleWeekday": func() states.State {
+			return commands.ToggleTrainingWeekday(ch.botUrl, chatId, messageId, int(id), state)
+		},
 		"markRequestReviewed": func() states.State {
 			return commands.MarkTrainingRequestAsReviewed(ch.botUrl, chatId, messageId, uint(id), ch.repo)
 		},
 	}
 
+	// Оценки опроса после тренировки: rateSurvey1_<id> … rateSurvey5_<id>
+	for stars := 1; stars <= database.MaxSurveyRating; stars++ {
+		rating := stars
+		callbackHandlers[fmt.Sprintf("rateSurvey%d", stars)] = func() states.State {
+			return commands.RateSurvey(ch.botUrl, chatId, messageId, uint(id), rating, ch.repo)
+		}
+	}
+
 	if handler, ok := callbackHandlers[prefix]; ok {
 		return handler()
 	}
@@ -173,29 +392,72 @@ func (ch *CallbackHandler) handleSimpleCallback(data string, chatId, messageId i
 			}
 			return commands.SendAdminPanelMessage(ch.botUrl, chatId, messageId)
 		},
-		"trainersMenu":     func() states.State { return commands.SendTrainersMenuMessage(ch.botUrl, chatId, messageId, ch.repo) },
-		"tracksMenu":       func() states.State { return commands.SendTracksMenuMessage(ch.botUrl, chatId, messageId, ch.repo) },
-		"scheduleMenu":     func() states.State { return commands.SendScheduleMenuMessage(ch.botUrl, chatId, messageId, ch.repo) },
-		"createTrainer":    func() states.State { return commands.CreateTrainer(ch.botUrl, chatId, messageId) },
-		"viewTrainers":     func() states.State { return commands.ViewTrainers(ch.botUrl, chatId, messageId, ch.repo) },
-		"createTrack":      func() states.State { return commands.CreateTrack(ch.botUrl, chatId, messageId) },
-		"viewTracks":       func() states.State { return commands.ViewTracks(ch.botUrl, chatId, messageId, ch.repo) },
-		"createSchedule":   func() states.State { return commands.CreateTraining(ch.botUrl, chatId, messageId, ch.repo) },
-		"viewSchedule":     func() states.State { return commands.ViewSchedule(ch.botUrl, chatId, messageId, ch.repo) },
-		"editSchedule":     func() states.State { return commands.EditSchedule(ch.botUrl, chatId, messageId, ch.repo) },
-		"BookTraining":     func() states.State { return commands.StartTrainingRegistration(ch.botUrl, chatId, messageId, ch.repo) },
-		"Info":             func() states.State { return commands.Info(ch.botUrl, chatId, messageId) },
-		"infoTrainer":      func() states.State { return commands.InfoTrainer(ch.botUrl, chatId, messageId, ch.repo) },
-		"infoTrack":        func() states.State { return commands.InfoTrack(ch.botUrl, chatId, messageId, ch.repo) },
-		"viewScheduleUser": func() states.State { return commands.ViewScheduleUser(ch.botUrl, chatId, messageId, ch.repo) },
-		"infoFormat":       func() states.State { return commands.InfoFormat(ch.botUrl, chatId, messageId) },
-		"suggestTraining":  func() states.State { return commands.SuggestTraining(ch.botUrl, chatId, messageId, ch.repo) },
-		"trainingRequests": func() states.State { return commands.ViewTrainingRequests(ch.botUrl, chatId, messageId, ch.repo) },
-		"backToTrackSelection": func() states.State {
-			return commands.BackToTrackSelection(ch.botUrl, chatId, messageId, ch.repo, state)
-		},
-		"backToTrainerSelection": func() states.State {
-			return commands.BackToTrainerSelection(ch.botUrl, chatId, messageId, ch.repo, state)
+		"trainersMenu":      func() states.State { return commands.SendTrainersMenuMessage(ch.botUrl, chatId, messageId, ch.repo) },
+		"tracksMenu":        func() states.State { return commands.SendTracksMenuMessage(ch.botUrl, chatId, messageId, ch.repo) },
+		"scheduleMenu":      func() states.State { return commands.SendScheduleMenuMessage(ch.botUrl, chatId, messageId, ch.repo) },
+		"createTrainer":     func() states.State { return commands.CreateTrainer(ch.botUrl, chatId, messageId) },
+		"viewTrainers":      func() states.State { return commands.ViewTrainers(ch.botUrl, chatId, messageId, ch.repo) },
+		"createTrack":       func() states.State { return commands.CreateTrack(ch.botUrl, chatId, messageId) },
+		"viewTracks":        func() states.State { return commands.ViewTracks(ch.botUrl, chatId, messageId, ch.repo) },
+		"createSchedule":    func() states.State { return commands.CreateTraining(ch.botUrl, chatId, messageId, ch.repo) },
+		"viewSchedule":      func() states.State { return commands.ViewSchedule(ch.botUrl, chatId, messageId, ch.repo) },
+		"editSchedule":      func() states.State { return commands.EditSchedule(ch.botUrl, chatId, messageId, ch.repo) },
+		"BookTraining":      func() states.State { return commands.StartTrainingRegistration(ch.botUrl, chatId, messageId, ch.repo) },
+		"myBookings":        func() states.State { return commands.ViewMyBookings(ch.botUrl, chatId, messageId, ch.repo) },
+		"myResults":         func() states.State { return commands.ViewMyResults(ch.botUrl, chatId, messageId, ch.repo) },
+		"ratingCategories":  func() states.State { return commands.ViewRatingCategories(ch.botUrl, chatId, messageId, ch.repo) },
+		"myFeedback":        func() states.State { return commands.ViewMyFeedback(ch.botUrl, chatId, messageId, ch.repo) },
+		"Info":              func() states.State { return commands.Info(ch.botUrl, chatId, messageId) },
+		"infoTrainer":       func() states.State { return commands.InfoTrainer(ch.botUrl, chatId, messageId, ch.repo) },
+		"infoTrack":         func() states.State { return commands.InfoTrack(ch.botUrl, chatId, messageId, ch.repo) },
+		"viewScheduleUser":  func() states.State { return commands.ViewScheduleUser(ch.botUrl, chatId, messageId, ch.repo) },
+		"infoFormat":        func() states.State { return commands.InfoFormat(ch.botUrl, chatId, messageId) },
+		"suggestTraining":   func() states.State { return commands.SuggestTraining(ch.botUrl, chatId, messageId, ch.repo) },
+		"trainingRequests":  func() states.State { return commands.ViewTrainingRequests(ch.botUrl, chatId, messageId, ch.repo) },
+		"surveyReport":      func() states.State { return commands.ViewSurveyReport(ch.botUrl, chatId, messageId, ch.repo) },
+		"packages":          func() states.State { return commands.ViewPackages(ch.botUrl, chatId, messageId, ch.repo) },
+		"createPackage":     func() states.State { return commands.CreatePackage(ch.botUrl, chatId, messageId, ch.repo) },
+		"creditBalances":    func() states.State { return commands.ViewCreditBalances(ch.botUrl, chatId, messageId, ch.repo) },
+		"myCredits":         func() states.State { return commands.ViewMyCredits(ch.botUrl, chatId, messageId, ch.repo) },
+		"myParticipants":    func() states.State { return commands.ViewParticipants(ch.botUrl, chatId, messageId, ch.repo) },
+		"addParticipant":    func() states.State { return commands.AddParticipant(ch.botUrl, chatId, messageId, ch.repo) },
+		"trainingTemplates": func() states.State { return commands.ViewTrainingTemplates(ch.botUrl, chatId, messageId, ch.repo) },
+		"userTimezone":      func() states.State { return commands.ViewUserTimezone(ch.botUrl, chatId, messageId, ch.repo) },
+		"resetUserTimezone": func() states.State { return commands.ResetUserTimezone(ch.botUrl, chatId, messageId, ch.repo) },
+		"carCategories":     func() states.State { return commands.ViewCarCategories(ch.botUrl, chatId, messageId, ch.repo) },
+		"createCarCategory": func() states.State { return commands.CreateCarCategory(ch.botUrl, chatId, messageId, ch.repo) },
+		"categoryPrices":    func() states.State { return commands.ViewCategoryPrices(ch.botUrl, chatId, messageId, ch.repo) },
+		"setCategoryPrice":  func() states.State { return commands.StartSetCategoryPrice(ch.botUrl, chatId, messageId, ch.repo) },
+		"promoCodes":        func() states.State { return commands.ViewPromoCodes(ch.botUrl, chatId, messageId, ch.repo) },
+		"createPromoCode":   func() states.State { return commands.CreatePromoCode(ch.botUrl, chatId, messageId, ch.repo) },
+		"guardianConsent": func() states.State {
+			return commands.ConfirmGuardianConsent(ch.botUrl, chatId, messageId, ch.repo, state)
+		},
+		"outstandingPayments": func() states.State {
+			return commands.ViewOutstandingPayments(ch.botUrl, chatId, messageId, ch.repo)
+		},
+		"individualSession": func() states.State {
+			return commands.StartSessionRequest(ch.botUrl, chatId, messageId, ch.repo)
+		},
+		"myAvailability": func() states.State {
+			return commands.ViewTrainerAvailability(ch.botUrl, chatId, messageId, ch.repo)
+		},
+		"addAvailability": func() states.State { return commands.AddAvailability(ch.botUrl, chatId, messageId, ch.repo) },
+		"recurrenceOnce": func() states.State {
+			return commands.SetTrainingRecurrence(ch.botUrl, chatId, messageId, ch.repo, state, false)
+		},
+		"recurrenceWeekly": func() states.State {
+			return commands.SetTrainingRecurrence(ch.botUrl, chatId, messageId, ch.repo, state, true)
+		},
+		"weekdaysDone": func() states.State { return commands.SetTrainingWeekdaysDone(ch.botUrl, chatId, messageId, state) },
+		"seriesScopeOne": func() states.State {
+			return commands.SelectSeriesScope(ch.botUrl, chatId, messageId, database.SeriesScopeOne, ch.repo, state)
+		},
+		"seriesScopeFollowing": func() states.State {
+			return commands.SelectSeriesScope(ch.botUrl, chatId, messageId, database.SeriesScopeFollowing, ch.repo, state)
+		},
+		"seriesScopeAll": func() states.State {
+			return commands.SelectSeriesScope(ch.botUrl, chatId, messageId, database.SeriesScopeAll, ch.repo, state)
 		},
 	}
 
@@ -231,7 +493,7 @@ func (ch *CallbackHandler) handleConfirmAction(chatId, messageId int, state stat
 		}
 	case states.StateConfirmTrainingRegistration:
 		if trainingId, ok := state.Data["trainingId"].(uint); ok {
-			return commands.ExecuteTrainingRegistration(ch.botUrl, chatId, messageId, uint(trainingId), ch.repo)
+			return commands.ExecuteTrainingRegistration(ch.botUrl, chatId, messageId, uint(trainingId), ch.repo, state)
 		}
 		logger.UserError(chatId, "Неверный тип trainingId в состоянии")
 		return states.SetError()
@@ -281,5 +543,5 @@ func (ch *CallbackHandler) handleCancelAction(chatId, messageId int, state state
 		return handler()
 	}
 
-	return commands.SendOperationCancelledMessage(ch.botUrl, chatId, messageId)
+	return commands.CancelDialog(ch.botUrl, chatId, messageId, state)
 }
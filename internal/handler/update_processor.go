@@ -119,37 +119,79 @@ func (up *UpdateProcessor) getUpdateType(update telegram.Update) string {
 
 // isCommand проверяет, является ли текст командой
 func isCommand(text string) bool {
-	return text == "/help" || text == "/start" || text == "/admin"
+	return text == "/help" || text == "/start" || text == "/admin" || text == "/cancel" || text == "/back"
 }
 
 // isTextInputState проверяет, является ли состояние состоянием ввода текста
 func isTextInputState(stateType states.StateType) bool {
 	textInputStates := map[states.StateType]bool{
-		states.StateSetTrainerName:             true,
-		states.StateSetTrainerTgId:             true,
-		states.StateSetTrainerChatId:           true,
-		states.StateSetTrainerInfo:             true,
-		states.StateEditTrainerName:            true,
-		states.StateEditTrainerTgId:            true,
-		states.StateEditTrainerInfo:            true,
-		states.StateSetTrackName:               true,
-		states.StateSetTrackInfo:               true,
-		states.StateEditTrackName:              true,
-		states.StateEditTrackInfo:              true,
-		states.StateSetUserName:                true,
-		states.StateSetUserTgId:                true,
-		states.StateSetTrainingStartTime:       true,
-		states.StateSetTrainingEndTime:         true,
-		states.StateSetTrainingMaxParticipants: true,
-		states.StateSetTrainingCarCategory:     true,
-		states.StateEditTrainingCarCategory:    true,
-		states.StateSuggestTraining:            true,
+		states.StateSetTrainerName:              true,
+		states.StateSetTrainerTgId:              true,
+		states.StateSetTrainerChatId:            true,
+		states.StateSetTrainerInfo:              true,
+		states.StateEditTrainerName:             true,
+		states.StateEditTrainerTgId:             true,
+		states.StateEditTrainerInfo:             true,
+		states.StateSetTrackName:                true,
+		states.StateSetTrackInfo:                true,
+		states.StateEditTrackName:               true,
+		states.StateEditTrackInfo:               true,
+		states.StateSetUserName:                 true,
+		states.StateSetUserTgId:                 true,
+		states.StateSetTrainingStartTime:        true,
+		states.StateSetTrainingEndTime:          true,
+		states.StateSetTrainingMaxParticipants:  true,
+		states.StateSetTrainingRecurrenceEnd:    true,
+		states.StateSetTrainingCloneDate:        true,
+		states.StateSetTrackClosure:             true,
+		states.StateEditTrackTimezone:           true,
+		states.StateSetUserTimezone:             true,
+		states.StateSetTemplateName:             true,
+		states.StateEditTrainingMaxParticipants: true,
+		states.StateSuggestTraining:             true,
+		states.StateEnterLapTimes:               true,
+		states.StateImportLapFile:               true,
+		states.StateEnterHeatResults:            true,
+		states.StateSetFeedbackStrengths:        true,
+		states.StateSetFeedbackImprovements:     true,
+		states.StateSetFeedbackVideo:            true,
+		states.StateSetSurveyComment:            true,
+		states.StateSetPackageName:              true,
+		states.StateSetPackageCredits:           true,
+		states.StateGrantCredits:                true,
+		states.StateEditTrainingPrice:           true,
+		states.StateSetCategoryPrice:            true,
+		states.StateSetPromoCode:                true,
+		states.StateSetPromoDiscount:            true,
+		states.StateSetPromoValidity:            true,
+		states.StateSetPromoMaxUses:             true,
+		states.StateEnterPromoCode:              true,
+		states.StateSetKart:                     true,
+		states.StateSetRentalStock:              true,
+		states.StateSetCarCategoryName:          true,
+		states.StateSetCarCategoryDescription:   true,
+		states.StateSetCarCategoryMinAge:        true,
+		states.StateSetCarCategoryLicense:       true,
+		states.StateSetAvailability:             true,
+		states.StateSetSessionSlot:              true,
+		states.StateSetParticipantName:          true,
+		states.StateSetParticipantBirthDate:     true,
 	}
 	return textInputStates[stateType]
 }
 
-// respond обрабатывает обновление и возвращает новое состояние
+// respond обрабатывает обновление и переносит стек навигации в новое состояние
 func (up *UpdateProcessor) respond(update telegram.Update, state states.State) states.State {
+	// Возврат назад восстанавливает состояние из стека, поэтому не проходит через Navigate
+	if update.CallbackQuery != nil && update.CallbackQuery.Data == "back" || update.Message.Text == "/back" {
+		return up.dispatch(update, state)
+	}
+
+	return states.Navigate(state, up.dispatch(update, state))
+}
+
+// dispatch передает обновление соответствующему обработчику
+func (up *UpdateProcessor) dispatch(update telegram.Update, state states.State) states.State {
 	var chatId int
 	if update.Message.Chat.ChatId != 0 {
 		chatId = update.Message.Chat.ChatId
@@ -163,11 +205,19 @@ func (up *UpdateProcessor) respond(update telegram.Update, state states.State) s
 		return callbackHandler.HandleCallback(update.CallbackQuery, state)
 	}
 
+	// Файлы принимаются только на шаге загрузки кругов из логгера
+	if update.Message.Document != nil {
+		if state.Type == states.StateImportLapFile {
+			return commands.ProcessLapFile(up.botUrl, chatId, update, up.repo, state)
+		}
+		return commands.Help(up.botUrl, chatId)
+	}
+
 	// Обрабатываем текстовые сообщения
 	if update.Message.Text != "" {
 		// Сначала проверяем, является ли это командой
 		if isCommand(update.Message.Text) {
-			return up.handleTextCommand(update, chatId)
+			return up.handleTextCommand(update, chatId, state)
 		}
 
 		// Если не команда, но есть состояние ввода текста - обрабатываем как ввод
@@ -183,7 +233,7 @@ func (up *UpdateProcessor) respond(update telegram.Update, state states.State) s
 }
 
 // handleTextCommand обрабатывает текстовые команды
-func (up *UpdateProcessor) handleTextCommand(update telegram.Update, chatId int) states.State {
+func (up *UpdateProcessor) handleTextCommand(update telegram.Update, chatId int, state states.State) states.State {
 	switch update.Message.Text {
 	case "/help":
 		return commands.Help(up.botUrl, chatId)
@@ -191,6 +241,10 @@ func (up *UpdateProcessor) handleTextCommand(update telegram.Update, chatId int)
 		return commands.Start(up.botUrl, chatId, up.repo)
 	case "/admin":
 		return commands.Admin(up.botUrl, chatId, up.repo)
+	case "/cancel":
+		return commands.CancelDialog(up.botUrl, chatId, 0, state)
+	case "/back":
+		return commands.GoBack(up.botUrl, chatId, 0, up.repo, state)
 	default:
 		// Неизвестная команда - показываем помощь
 		return commands.Help(up.botUrl, chatId)
@@ -228,11 +282,107 @@ func (up *UpdateProcessor) handleTextInput(update telegram.Update, chatId int, s
 		states.StateSetTrainingMaxParticipants: func() states.State {
 			return commands.SetTrainingMaxParticipants(up.botUrl, chatId, update, up.repo, state)
 		},
-		states.StateSetTrainingCarCategory: func() states.State {
-			return commands.SetTrainingCarCategory(up.botUrl, chatId, update, up.repo, state)
+		states.StateSetTrackClosure: func() states.State {
+			return commands.SetTrackClosure(up.botUrl, chatId, update, up.repo, state)
+		},
+		states.StateEditTrackTimezone: func() states.State {
+			return commands.SetEditTrackTimezone(up.botUrl, chatId, update, up.repo, state)
+		},
+		states.StateSetUserTimezone: func() states.State {
+			return commands.SetUserTimezone(up.botUrl, chatId, update, up.repo, state)
+		},
+		states.StateEnterLapTimes: func() states.State {
+			return commands.SetLapTimes(up.botUrl, chatId, update, up.repo, state)
+		},
+		states.StateImportLapFile: func() states.State {
+			return commands.ProcessLapFile(up.botUrl, chatId, update, up.repo, state)
+		},
+		states.StateEnterHeatResults: func() states.State {
+			return commands.SetHeatResults(up.botUrl, chatId, update, up.repo, state)
+		},
+		states.StateSetFeedbackStrengths: func() states.State {
+			return commands.SetFeedbackStrengths(up.botUrl, chatId, update, up.repo, state)
+		},
+		states.StateSetFeedbackImprovements: func() states.State {
+			return commands.SetFeedbackImprovements(up.botUrl, chatId, update, up.repo, state)
+		},
+		states.StateSetFeedbackVideo: func() states.State {
+			return commands.SetFeedbackVideo(up.botUrl, chatId, update, up.repo, state)
+		},
+		states.StateSetSurveyComment: func() states.State {
+			return commands.SetSurveyComment(up.botUrl, chatId, update, up.repo, state)
+		},
+		states.StateSetPackageName: func() states.State {
+			return commands.SetPackageName(up.botUrl, chatId, update, up.repo, state)
+		},
+		states.StateSetPackageCredits: func() states.State {
+			return commands.SetPackageCredits(up.botUrl, chatId, update, up.repo, state)
+		},
+		states.StateGrantCredits: func() states.State {
+			return commands.SetGrantCredits(up.botUrl, chatId, update, up.repo, state)
+		},
+		states.StateEditTrainingPrice: func() states.State {
+			return commands.SetEditTrainingPrice(up.botUrl, chatId, update, up.repo, state)
+		},
+		states.StateSetCategoryPrice: func() states.State {
+			return commands.SetCategoryPrice(up.botUrl, chatId, update, up.repo, state)
+		},
+		states.StateSetPromoCode: func() states.State {
+			return commands.SetPromoCode(up.botUrl, chatId, update, up.repo, state)
+		},
+		states.StateSetPromoDiscount: func() states.State {
+			return commands.SetPromoDiscount(up.botUrl, chatId, update, up.repo, state)
+		},
+		states.StateSetPromoValidity: func() states.State {
+			return commands.SetPromoValidity(up.botUrl, chatId, update, up.repo, state)
+		},
+		states.StateSetPromoMaxUses: func() states.State {
+			return commands.SetPromoMaxUses(up.botUrl, chatId, update, up.repo, state)
+		},
+		states.StateEnterPromoCode: func() states.State {
+			return commands.SetEnteredPromoCode(up.botUrl, chatId, update, up.repo, state)
+		},
+		states.StateSetKart: func() states.State {
+			return commands.SetKart(up.botUrl, chatId, update, up.repo, state)
+		},
+		states.StateSetRentalStock: func() states.State {
+			return commands.SetRentalStock(up.botUrl, chatId, update, up.repo, state)
+		},
+		states.StateSetCarCategoryName: func() states.State {
+			return commands.SetCarCategoryName(up.botUrl, chatId, update, up.repo, state)
+		},
+		states.StateSetCarCategoryDescription: func() states.State {
+			return commands.SetCarCategoryDescription(up.botUrl, chatId, update, up.repo, state)
+		},
+		states.StateSetCarCategoryMinAge: func() states.State {
+			return commands.SetCarCategoryMinAge(up.botUrl, chatId, update, up.repo, state)
+		},
+		states.StateSetCarCategoryLicense: func() states.State {
+			return commands.SetCarCategoryLicense(up.botUrl, chatId, update, up.repo, state)
+		},
+		states.StateSetAvailability: func() states.State {
+			return commands.SetAvailability(up.botUrl, chatId, update, up.repo, state)
+		},
+		states.StateSetSessionSlot: func() states.State {
+			return commands.SetSessionSlot(up.botUrl, chatId, update, up.repo, state)
+		},
+		states.StateSetParticipantName: func() states.State {
+			return commands.SetParticipantName(up.botUrl, chatId, update, up.repo, state)
+		},
+		states.StateSetParticipantBirthDate: func() states.State {
+			return commands.SetParticipantBirthDate(up.botUrl, chatId, update, up.repo, state)
+		},
+		states.StateSetTrainingCloneDate: func() states.State {
+			return commands.SetTrainingCloneDate(up.botUrl, chatId, update, up.repo, state)
+		},
+		states.StateSetTemplateName: func() states.State {
+			return commands.SetTemplateName(up.botUrl, chatId, update, up.repo, state)
+		},
+		states.StateSetTrainingRecurrenceEnd: func() states.State {
+			return commands.SetTrainingRecurrenceEnd(up.botUrl, chatId, update, up.repo, state)
 		},
-		states.StateEditTrainingCarCategory: func() states.State {
-			return commands.SetEditTrainingCategory(up.botUrl, chatId, update, up.repo, state.GetID())
+		states.StateEditTrainingMaxParticipants: func() states.State {
+			return commands.SetEditTrainingMaxParticipants(up.botUrl, chatId, update, up.repo, state)
 		},
 		states.StateSuggestTraining: func() states.State {
 			return commands.ProcessTrainingSuggestion(up.botUrl, chatId, update, up.repo, state)
@@ -0,0 +1,49 @@
+package laptiming
+
+import (
+	"regexp"
+	"sort"
+)
+
+// lapColumnRegex - колонки кругов: "lap1", "l2", "круг3"
+var lapColumnRegex = regexp.MustCompile(`^(?:l|lap|круг)(\d+)$`)
+
+// lapsPerRowParser - формат "пилот на строку": имя и времена кругов в колонках
+type lapsPerRowParser struct{}
+
+func (lapsPerRowParser) Name() string {
+	return "пилот на строку (пилот, круг 1, круг 2…)"
+}
+
+func (lapsPerRowParser) Detect(header []string) bool {
+	return findColumn(header, driverColumns...) >= 0 && len(numberedColumns(header, lapColumnRegex)) > 0
+}
+
+func (lapsPerRowParser) Parse(header []string, rows [][]string) ([]Lap, error) {
+	driverColumn := findColumn(header, driverColumns...)
+
+	columns := numberedColumns(header, lapColumnRegex)
+	numbers := make([]int, 0, len(columns))
+	for number := range columns {
+		numbers = append(numbers, number)
+	}
+	sort.Ints(numbers)
+
+	var laps []Lap
+	for _, row := range rows {
+		driver := cell(row, driverColumn)
+		if driver == "" {
+			continue
+		}
+
+		for _, number := range numbers {
+			lapTime, err := ParseTime(cell(row, columns[number]))
+			if err != nil {
+				continue
+			}
+			laps = append(laps, Lap{Driver: driver, Number: number, TimeMs: lapTime})
+		}
+	}
+
+	return laps, nil
+}
@@ -0,0 +1,200 @@
+// Package laptiming разбирает CSV-выгрузки логгеров времени круга.
+// Каждый формат выгрузки - отдельный Parser из списка parsers;
+// Parse сам находит строку заголовка и подходящий формат.
+package laptiming
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// headerSearchRows - сколько первых строк файла просматривается в поисках
+// заголовка: логгеры часто пишут перед таблицей сведения о сессии
+const headerSearchRows = 20
+
+// ErrUnknownFormat - ни один из поддерживаемых форматов не подошел
+var ErrUnknownFormat = errors.New("формат файла не распознан")
+
+// Lap - круг пилота из файла логгера
+type Lap struct {
+	Driver    string
+	Number    int   // номер круга в файле; 0, если формат его не содержит
+	TimeMs    int   // время круга в миллисекундах
+	SectorsMs []int // времена секторов; пусто, если формат их не содержит
+}
+
+// Parser разбирает выгрузку одного формата
+type Parser interface {
+	// Name - название формата для сообщений пользователю
+	Name() string
+	// Detect проверяет, что нормализованный заголовок относится к этому формату
+	Detect(header []string) bool
+	// Parse разбирает строки таблицы после заголовка
+	Parse(header []string, rows [][]string) ([]Lap, error)
+}
+
+// parsers - поддерживаемые форматы. Проверяются по порядку, поэтому более
+// узкие идут первыми: заголовок "пилот на строку" может содержать и колонку
+// времени (лучший круг), по которой его принял бы формат "круг на строку"
+var parsers = []Parser{
+	lapsPerRowParser{},
+	rowPerLapParser{},
+}
+
+// Formats возвращает названия поддерживаемых форматов
+func Formats() []string {
+	names := make([]string, len(parsers))
+	for i, parser := range parsers {
+		names[i] = parser.Name()
+	}
+	return names
+}
+
+// Parse находит заголовок таблицы, выбирает формат и возвращает его название и круги
+func Parse(data []byte) (string, []Lap, error) {
+	rows, err := readCSV(data)
+	if err != nil {
+		return "", nil, err
+	}
+
+	for i := 0; i < len(rows) && i < headerSearchRows; i++ {
+		header := normalizeHeader(rows[i])
+		for _, parser := range parsers {
+			if !parser.Detect(header) {
+				continue
+			}
+			laps, err := parser.Parse(header, rows[i+1:])
+			if err != nil {
+				return parser.Name(), nil, err
+			}
+			if len(laps) == 0 {
+				return parser.Name(), nil, errors.New("в файле нет кругов")
+			}
+			return parser.Name(), laps, nil
+		}
+	}
+
+	return "", nil, ErrUnknownFormat
+}
+
+// readCSV читает таблицу, определяя разделитель по первой непустой строке
+func readCSV(data []byte) ([][]string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = detectDelimiter(data)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать CSV: %w", err)
+	}
+	return rows, nil
+}
+
+// detectDelimiter выбирает самый частый из разделителей ";", "," и табуляции
+func detectDelimiter(data []byte) rune {
+	var line []byte
+	for _, l := range bytes.Split(data, []byte("\n")) {
+		if len(bytes.TrimSpace(l)) > 0 {
+			line = l
+			break
+		}
+	}
+
+	best, bestCount := ',', 0
+	for _, delimiter := range []rune{';', ',', '\t'} {
+		if count := bytes.Count(line, []byte(string(delimiter))); count > bestCount {
+			best, bestCount = delimiter, count
+		}
+	}
+	return best
+}
+
+// normalizeHeader приводит названия колонок к виду "laptime", "s1", "пилот"
+func normalizeHeader(row []string) []string {
+	replacer := strings.NewReplacer(" ", "", "_", "", "-", "", ".", "", "(", "", ")", "", "#", "")
+	header := make([]string, len(row))
+	for i, cell := range row {
+		header[i] = replacer.Replace(strings.ToLower(strings.TrimSpace(cell)))
+	}
+	return header
+}
+
+// findColumn возвращает индекс первой колонки с одним из названий или -1
+func findColumn(header []string, names ...string) int {
+	for i, cell := range header {
+		for _, name := range names {
+			if cell == name {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// numberedColumns находит колонки вида "s1", "lap2" и возвращает их индексы по номерам
+func numberedColumns(header []string, pattern *regexp.Regexp) map[int]int {
+	columns := make(map[int]int)
+	for i, cell := range header {
+		match := pattern.FindStringSubmatch(cell)
+		if match == nil {
+			continue
+		}
+		number, err := strconv.Atoi(match[1])
+		if err != nil || number < 1 {
+			continue
+		}
+		if _, ok := columns[number]; !ok {
+			columns[number] = i
+		}
+	}
+	return columns
+}
+
+// cell возвращает значение колонки или пустую строку, если строка короче
+func cell(row []string, index int) string {
+	if index < 0 || index >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[index])
+}
+
+// timeRegex - время вида "1:02.345", "62.345" или "62,3"
+var timeRegex = regexp.MustCompile(`^(?:(\d{1,2}):)?(\d{1,3})(?:[.,](\d{1,3}))?$`)
+
+// ParseTime разбирает время из файла логгера: минуты необязательны,
+// дробная часть секунд - от одной до трех цифр. Нулевое время - ошибка
+func ParseTime(value string) (int, error) {
+	match := timeRegex.FindStringSubmatch(strings.TrimSpace(value))
+	if match == nil {
+		return 0, fmt.Errorf("неверное время %q", value)
+	}
+
+	minutes := 0
+	if match[1] != "" {
+		minutes, _ = strconv.Atoi(match[1])
+	}
+	seconds, _ := strconv.Atoi(match[2])
+	if match[1] != "" && seconds > 59 {
+		return 0, fmt.Errorf("неверное время %q", value)
+	}
+
+	millis := 0
+	if fraction := match[3]; fraction != "" {
+		millis, _ = strconv.Atoi((fraction + "00")[:3])
+	}
+
+	lapTime := (minutes*60+seconds)*1000 + millis
+	if lapTime <= 0 {
+		return 0, fmt.Errorf("неверное время %q", value)
+	}
+	return lapTime, nil
+}
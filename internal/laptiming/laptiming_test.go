@@ -0,0 +1,142 @@
+package laptiming
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseTime(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{value: "1:02.345", want: 62345},
+		{value: "62.345", want: 62345},
+		{value: "62,3", want: 62300},
+		{value: "62.34", want: 62340},
+		{value: "62", want: 62000},
+		{value: " 0:59.999 ", want: 59999},
+		{value: "0.001", want: 1},
+		{value: "0", wantErr: true},
+		{value: "0.000", wantErr: true},
+		{value: "0:00.0", wantErr: true},
+		{value: "1:60.000", wantErr: true},
+		{value: "-1.5", wantErr: true},
+		{value: "", wantErr: true},
+		{value: "pit", wantErr: true},
+		{value: "1.2345", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseTime(tt.value)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseTime(%q) = %d, want error", tt.value, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseTime(%q) error: %v", tt.value, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseTime(%q) = %d, want %d", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       string
+		wantFormat string
+		wantLaps   []Lap
+		wantErr    error
+	}{
+		{
+			name: "row per lap with sectors",
+			data: "Driver;Lap;Lap Time;S1;S2\n" +
+				"Иванов;1;1:02.345;30.100;32.245\n" +
+				"Иванов;2;1:01.900;29.900;32.000\n",
+			wantFormat: rowPerLapParser{}.Name(),
+			wantLaps: []Lap{
+				{Driver: "Иванов", Number: 1, TimeMs: 62345, SectorsMs: []int{30100, 32245}},
+				{Driver: "Иванов", Number: 2, TimeMs: 61900, SectorsMs: []int{29900, 32000}},
+			},
+		},
+		{
+			name: "session info before header, pit and zero laps skipped",
+			data: "Session,Practice\nTrack,Kartodrom\n\n" +
+				"Pilot,Time\n" +
+				"Петров,58.1\n" +
+				"Петров,PIT\n" +
+				"Петров,0.000\n" +
+				",57.0\n",
+			wantFormat: rowPerLapParser{}.Name(),
+			wantLaps: []Lap{
+				{Driver: "Петров", TimeMs: 58100},
+			},
+		},
+		{
+			name: "laps per row",
+			data: "Пилот\tКруг 1\tКруг 2\n" +
+				"Сидоров\t59,5\t\n" +
+				"Козлов\t1:00.1\t59.9\n",
+			wantFormat: lapsPerRowParser{}.Name(),
+			wantLaps: []Lap{
+				{Driver: "Сидоров", Number: 1, TimeMs: 59500},
+				{Driver: "Козлов", Number: 1, TimeMs: 60100},
+				{Driver: "Козлов", Number: 2, TimeMs: 59900},
+			},
+		},
+		{
+			name: "laps per row with best time column",
+			data: "Driver,Best Time,Lap1,Lap2\n" +
+				"Smith,58.000,59.000,58.000\n",
+			wantFormat: lapsPerRowParser{}.Name(),
+			wantLaps: []Lap{
+				{Driver: "Smith", Number: 1, TimeMs: 59000},
+				{Driver: "Smith", Number: 2, TimeMs: 58000},
+			},
+		},
+		{
+			name:    "unknown format",
+			data:    "a,b,c\n1,2,3\n",
+			wantErr: ErrUnknownFormat,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, laps, err := Parse([]byte(tt.data))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Parse() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() error: %v", err)
+			}
+			if format != tt.wantFormat {
+				t.Errorf("Parse() format = %q, want %q", format, tt.wantFormat)
+			}
+			if !reflect.DeepEqual(laps, tt.wantLaps) {
+				t.Errorf("Parse() laps = %+v, want %+v", laps, tt.wantLaps)
+			}
+		})
+	}
+}
+
+func TestParseNoLaps(t *testing.T) {
+	format, _, err := Parse([]byte("Driver,Lap Time\nИванов,PIT\n"))
+	if err == nil {
+		t.Fatal("Parse() without laps must fail")
+	}
+	want := rowPerLapParser{}.Name()
+	if format != want {
+		t.Errorf("Parse() format = %q, want %q", format, want)
+	}
+}
@@ -0,0 +1,73 @@
+package laptiming
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// Названия колонок пилота и времени круга, общие для форматов
+var (
+	driverColumns  = []string{"driver", "name", "pilot", "competitor", "пилот", "гонщик", "участник", "имя"}
+	lapTimeColumns = []string{"laptime", "time", "времякруга", "время"}
+	lapColumns     = []string{"lap", "lapno", "lapnumber", "круг", "номеркруга"}
+)
+
+// sectorColumnRegex - колонки секторов: "s1", "sector2", "сектор3"
+var sectorColumnRegex = regexp.MustCompile(`^(?:s|sec|sector|сектор)(\d+)$`)
+
+// rowPerLapParser - формат "круг на строку": пилот, номер круга, время и секторы
+type rowPerLapParser struct{}
+
+func (rowPerLapParser) Name() string {
+	return "круг на строку (пилот, круг, время, секторы)"
+}
+
+func (rowPerLapParser) Detect(header []string) bool {
+	return findColumn(header, driverColumns...) >= 0 && findColumn(header, lapTimeColumns...) >= 0
+}
+
+func (rowPerLapParser) Parse(header []string, rows [][]string) ([]Lap, error) {
+	driverColumn := findColumn(header, driverColumns...)
+	timeColumn := findColumn(header, lapTimeColumns...)
+	lapColumn := findColumn(header, lapColumns...)
+
+	sectors := numberedColumns(header, sectorColumnRegex)
+	sectorNumbers := make([]int, 0, len(sectors))
+	for number := range sectors {
+		sectorNumbers = append(sectorNumbers, number)
+	}
+	sort.Ints(sectorNumbers)
+
+	var laps []Lap
+	for _, row := range rows {
+		driver := cell(row, driverColumn)
+		if driver == "" {
+			continue
+		}
+
+		// Строки без времени - пит-стопы, итоги и пояснения логгера
+		lapTime, err := ParseTime(cell(row, timeColumn))
+		if err != nil {
+			continue
+		}
+
+		lap := Lap{Driver: driver, TimeMs: lapTime}
+		if number, err := strconv.Atoi(cell(row, lapColumn)); err == nil && number > 0 {
+			lap.Number = number
+		}
+
+		for _, number := range sectorNumbers {
+			sectorTime, err := ParseTime(cell(row, sectors[number]))
+			if err != nil {
+				lap.SectorsMs = nil
+				break
+			}
+			lap.SectorsMs = append(lap.SectorsMs, sectorTime)
+		}
+
+		laps = append(laps, lap)
+	}
+
+	return laps, nil
+}
@@ -0,0 +1,45 @@
+// Package rating рассчитывает рейтинг Эло участников по итогам заездов.
+package rating
+
+import "math"
+
+// Initial - рейтинг участника без заездов
+const Initial = 1000
+
+// KFactor - максимальное изменение рейтинга за один заезд
+const KFactor = 32
+
+// Expected - ожидаемый результат встречи участника с рейтингом a против
+// участника с рейтингом b: от 0 (проигрыш) до 1 (победа)
+func Expected(a, b int) float64 {
+	return 1 / (1 + math.Pow(10, float64(b-a)/400))
+}
+
+// Update пересчитывает рейтинги участников заезда, переданные в порядке финиша.
+// Заезд раскладывается на попарные встречи: каждый участник выигрывает у всех,
+// кто финишировал позже. Изменение делится на число соперников, поэтому за
+// заезд рейтинг меняется не больше чем на KFactor независимо от числа участников.
+func Update(ratings []int) []int {
+	updated := make([]int, len(ratings))
+	copy(updated, ratings)
+	if len(ratings) < 2 {
+		return updated
+	}
+
+	opponents := float64(len(ratings) - 1)
+	for i, own := range ratings {
+		score := 0.0
+		for j, other := range ratings {
+			if i == j {
+				continue
+			}
+			actual := 0.0
+			if i < j {
+				actual = 1
+			}
+			score += actual - Expected(own, other)
+		}
+		updated[i] = own + int(math.Round(KFactor*score/opponents))
+	}
+	return updated
+}
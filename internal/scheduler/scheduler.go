@@ -0,0 +1,73 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"x.localhost/rvabot/internal/logger"
+	"x.localhost/rvabot/internal/recovery"
+)
+
+// Job описывает периодическую фоновую задачу
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func()
+}
+
+// Scheduler запускает зарегистрированные задачи по расписанию
+type Scheduler struct {
+	jobs     []Job
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+	once     sync.Once
+}
+
+// New создает новый планировщик
+func New() *Scheduler {
+	return &Scheduler{
+		stopChan: make(chan struct{}),
+	}
+}
+
+// Register добавляет задачу; вызывать до Start
+func (s *Scheduler) Register(name string, interval time.Duration, run func()) {
+	s.jobs = append(s.jobs, Job{Name: name, Interval: interval, Run: run})
+}
+
+// Start запускает все задачи, каждую в отдельной горутине
+func (s *Scheduler) Start() {
+	for _, job := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(job)
+		logger.BotInfo("Планировщик: задача %s запущена (интервал %s)", job.Name, job.Interval)
+	}
+}
+
+// loop выполняет задачу с заданным интервалом до остановки планировщика
+func (s *Scheduler) loop(job Job) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			// Паника в задаче не должна останавливать планировщик
+			recovery.RecoverFunc(context.Background(), "scheduler_"+job.Name, job.Run)
+		case <-s.stopChan:
+			return
+		}
+	}
+}
+
+// Stop останавливает планировщик и ждет завершения текущих задач
+func (s *Scheduler) Stop() {
+	s.once.Do(func() {
+		close(s.stopChan)
+	})
+	s.wg.Wait()
+	logger.BotInfo("Планировщик остановлен")
+}
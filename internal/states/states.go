@@ -1,5 +1,7 @@
 package states
 
+import "time"
+
 type StateType string
 
 const (
@@ -32,30 +34,305 @@ const (
 	StateSetUserDataConsent      = "StateSetUserDataConsent"
 	StateConfirmUserRegistration = "StateConfirmUserRegistration"
 
+	StateSetTrackClosure = "StateSetTrackClosure"
+
+	// Часовые пояса трасс и пользователей
+	StateEditTrackTimezone = "StateEditTrackTimezone"
+	StateSetUserTimezone   = "StateSetUserTimezone"
+
 	StateSetTrainingTrack           = "StateSetTrainingTrack"
 	StateSetTrainingTrainer         = "StateSetTrainingTrainer"
 	StateSetTrainingStartTime       = "StateSetTrainingStartTime"
 	StateSetTrainingEndTime         = "StateSetTrainingEndTime"
 	StateSetTrainingMaxParticipants = "StateSetTrainingMaxParticipants"
 	StateSetTrainingCarCategory     = "StateSetTrainingCarCategory"
+	StateSetTrainingRecurrence      = "StateSetTrainingRecurrence"
+	StateSetTrainingWeekdays        = "StateSetTrainingWeekdays"
+	StateSetTrainingRecurrenceEnd   = "StateSetTrainingRecurrenceEnd"
 	StateConfirmTrainingCreation    = "StateConfirmTrainingCreation"
+	StateSetTrainingCloneDate       = "StateSetTrainingCloneDate"
+
+	// Шаблоны тренировок
+	StateSetTemplateName       = "StateSetTemplateName"
+	StateConfirmTemplateDelete = "StateConfirmTemplateDelete"
 
 	StateConfirmTrainingRegistration = "StateConfirmTrainingRegistration"
 	StateConfirmTrainingDelete       = "StateConfirmTrainingDelete"
 
 	// Editing fields for existing training
-	StateEditTrainingCarCategory = "StateEditTrainingCarCategory"
+	StateEditTrainingCarCategory     = "StateEditTrainingCarCategory"
+	StateEditTrainingMaxParticipants = "StateEditTrainingMaxParticipants"
+	StateEditTrainingSkillLevel      = "StateEditTrainingSkillLevel"
+	StateSelectSeriesScope           = "StateSelectSeriesScope"
 
 	StateSelectTrackForRegistration        = "StateSelectTrackForRegistration"
 	StateSelectTrainerForRegistration      = "StateSelectTrainerForRegistration"
 	StateSelectTrainingTimeForRegistration = "StateSelectTrainingTimeForRegistration"
 
 	StateSuggestTraining = "StateSuggestTraining"
+
+	// Ввод времени кругов тренером
+	StateEnterLapTimes = "StateEnterLapTimes"
+	StateImportLapFile = "StateImportLapFile"
+
+	// Ввод результатов заезда для рейтинга
+	StateEnterHeatResults = "StateEnterHeatResults"
+
+	// Разбор тренировки для участника
+	StateSetFeedbackStrengths    = "StateSetFeedbackStrengths"
+	StateSetFeedbackImprovements = "StateSetFeedbackImprovements"
+	StateSetFeedbackVideo        = "StateSetFeedbackVideo"
+
+	// Комментарий участника к опросу после тренировки
+	StateSetSurveyComment = "StateSetSurveyComment"
+
+	// Пакеты тренировок и кредиты
+	StateSetPackageName    = "StateSetPackageName"
+	StateSetPackageCredits = "StateSetPackageCredits"
+	StateGrantCredits      = "StateGrantCredits"
+	// StateUserCredits - администратор открыл кредиты пользователя; продажа пакета идет ему
+	StateUserCredits = "StateUserCredits"
+
+	// Цены тренировок
+	StateEditTrainingPrice = "StateEditTrainingPrice"
+	StateSetCategoryPrice  = "StateSetCategoryPrice"
+
+	// Промокоды: создание администратором и ввод при записи
+	StateSetPromoCode        = "StateSetPromoCode"
+	StateSetPromoDiscount    = "StateSetPromoDiscount"
+	StateSetPromoValidity    = "StateSetPromoValidity"
+	StateSetPromoMaxUses     = "StateSetPromoMaxUses"
+	StateSetPromoTrack       = "StateSetPromoTrack"
+	StateSetPromoCarCategory = "StateSetPromoCarCategory"
+	StateEnterPromoCode      = "StateEnterPromoCode"
+
+	// Парк картов трассы
+	StateSetKart = "StateSetKart"
+	// StateAssignKart - тренер выбирает карт для участника
+	StateAssignKart = "StateAssignKart"
+
+	// Прокат экипировки
+	StateSetRentalStock = "StateSetRentalStock"
+	// StateSelectRental - участник выбирает экипировку к записи
+	StateSelectRental = "StateSelectRental"
+
+	// Справочник категорий машин
+	StateSetCarCategoryName        = "StateSetCarCategoryName"
+	StateSetCarCategoryDescription = "StateSetCarCategoryDescription"
+	StateSetCarCategoryMinAge      = "StateSetCarCategoryMinAge"
+	StateSetCarCategoryLicense     = "StateSetCarCategoryLicense"
+
+	// Окна доступности тренера и заявки на индивидуальные занятия
+	StateSelectAvailabilityTrack = "StateSelectAvailabilityTrack"
+	StateSetAvailability         = "StateSetAvailability"
+	StateSelectSessionTrack      = "StateSelectSessionTrack"
+	StateSelectSessionWindow     = "StateSelectSessionWindow"
+	StateSetSessionSlot          = "StateSetSessionSlot"
+
+	// Профили участников под аккаунтом и выбор участника при записи
+	StateSetParticipantName            = "StateSetParticipantName"
+	StateSetParticipantBirthDate       = "StateSetParticipantBirthDate"
+	StateConfirmParticipantConsent     = "StateConfirmParticipantConsent"
+	StateSelectRegistrationParticipant = "StateSelectRegistrationParticipant"
 )
 
 type State struct {
 	Type StateType
 	Data map[string]interface{}
+	// History - стек предыдущих шагов диалога для /back
+	History []State
+}
+
+// dialogMenus связывает шаги диалогов с меню, из которого диалог был начат.
+// Состояния, отсутствующие в карте, не считаются шагами диалога.
+var dialogMenus = map[StateType]string{
+	StateSetTrainerName:         "trainersMenu",
+	StateSetTrainerTgId:         "trainersMenu",
+	StateSetTrainerChatId:       "trainersMenu",
+	StateSetTrainerInfo:         "trainersMenu",
+	StateConfirmTrainerCreation: "trainersMenu",
+	StateEditTrainerName:        "trainersMenu",
+	StateEditTrainerTgId:        "trainersMenu",
+	StateEditTrainerInfo:        "trainersMenu",
+	StateConfirmTrainerDelete:   "trainersMenu",
+
+	StateSetTrackName:         "tracksMenu",
+	StateSetTrackInfo:         "tracksMenu",
+	StateConfirmTrackCreation: "tracksMenu",
+	StateEditTrackName:        "tracksMenu",
+	StateEditTrackInfo:        "tracksMenu",
+	StateConfirmTrackDelete:   "tracksMenu",
+	StateSetTrackClosure:      "tracksMenu",
+	StateEditTrackTimezone:    "tracksMenu",
+
+	StateSetTrainingTrack:            "scheduleMenu",
+	StateSetTrainingTrainer:          "scheduleMenu",
+	StateSetTrainingStartTime:        "scheduleMenu",
+	StateSetTrainingEndTime:          "scheduleMenu",
+	StateSetTrainingMaxParticipants:  "scheduleMenu",
+	StateSetTrainingCarCategory:      "scheduleMenu",
+	StateSetTrainingRecurrence:       "scheduleMenu",
+	StateSetTrainingWeekdays:         "scheduleMenu",
+	StateSetTrainingRecurrenceEnd:    "scheduleMenu",
+	StateConfirmTrainingCreation:     "scheduleMenu",
+	StateConfirmTrainingDelete:       "scheduleMenu",
+	StateEditTrainingCarCategory:     "scheduleMenu",
+	StateEditTrainingMaxParticipants: "scheduleMenu",
+	StateEditTrainingSkillLevel:      "scheduleMenu",
+	StateSelectSeriesScope:           "scheduleMenu",
+	StateSetTrainingCloneDate:        "scheduleMenu",
+	StateSetTemplateName:             "scheduleMenu",
+	StateConfirmTemplateDelete:       "scheduleMenu",
+
+	StateSetUserDataConsent:      "start",
+	StateSetUserName:             "start",
+	StateSetUserTgId:             "start",
+	StateConfirmUserRegistration: "start",
+
+	StateSelectTrackForRegistration:        "start",
+	StateSelectTrainerForRegistration:      "start",
+	StateSelectTrainingTimeForRegistration: "start",
+	StateConfirmTrainingRegistration:       "start",
+
+	StateSuggestTraining: "start",
+	StateSetUserTimezone: "start",
+	StateEnterLapTimes:   "start",
+	StateImportLapFile:   "start",
+
+	StateEnterHeatResults: "start",
+
+	StateSetFeedbackStrengths:    "start",
+	StateSetFeedbackImprovements: "start",
+	StateSetFeedbackVideo:        "start",
+	StateSetSurveyComment:        "start",
+
+	StateSetPackageName:    "admin",
+	StateSetPackageCredits: "admin",
+	StateGrantCredits:      "admin",
+
+	StateEditTrainingPrice: "scheduleMenu",
+	StateSetCategoryPrice:  "scheduleMenu",
+
+	StateSetPromoCode:        "admin",
+	StateSetPromoDiscount:    "admin",
+	StateSetPromoValidity:    "admin",
+	StateSetPromoMaxUses:     "admin",
+	StateSetPromoTrack:       "admin",
+	StateSetPromoCarCategory: "admin",
+	StateEnterPromoCode:      "start",
+
+	StateSetKart:        "tracksMenu",
+	StateSetRentalStock: "tracksMenu",
+
+	StateSetCarCategoryName:        "carCategories",
+	StateSetCarCategoryDescription: "carCategories",
+	StateSetCarCategoryMinAge:      "carCategories",
+	StateSetCarCategoryLicense:     "carCategories",
+
+	StateSelectAvailabilityTrack: "myAvailability",
+	StateSetAvailability:         "myAvailability",
+	StateSelectSessionTrack:      "start",
+	StateSelectSessionWindow:     "start",
+	StateSetSessionSlot:          "start",
+
+	StateSetParticipantName:            "myParticipants",
+	StateSetParticipantBirthDate:       "myParticipants",
+	StateConfirmParticipantConsent:     "myParticipants",
+	StateSelectRegistrationParticipant: "start",
+}
+
+// dialogEntries - первые шаги диалогов, с которых стек навигации начинается заново
+var dialogEntries = map[StateType]bool{
+	StateSetTrainerName:              true,
+	StateEditTrainerName:             true,
+	StateEditTrainerTgId:             true,
+	StateEditTrainerInfo:             true,
+	StateConfirmTrainerDelete:        true,
+	StateSetTrackName:                true,
+	StateEditTrackName:               true,
+	StateEditTrackInfo:               true,
+	StateConfirmTrackDelete:          true,
+	StateSetTrackClosure:             true,
+	StateEditTrackTimezone:           true,
+	StateSetTrainingTrack:            true,
+	StateConfirmTrainingDelete:       true,
+	StateEditTrainingCarCategory:     true,
+	StateEditTrainingMaxParticipants: true,
+	StateEditTrainingSkillLevel:      true,
+	StateSelectSeriesScope:           true,
+	StateSetTrainingCloneDate:        true,
+	StateSetTemplateName:             true,
+	StateConfirmTemplateDelete:       true,
+	StateSetUserDataConsent:          true,
+	StateSelectTrackForRegistration:  true,
+	StateSuggestTraining:             true,
+	StateSetUserTimezone:             true,
+	StateEnterLapTimes:               true,
+	StateImportLapFile:               true,
+	StateEnterHeatResults:            true,
+	StateSetFeedbackStrengths:        true,
+	StateSetSurveyComment:            true,
+	StateSetPackageName:              true,
+	StateGrantCredits:                true,
+	StateEditTrainingPrice:           true,
+	StateSetCategoryPrice:            true,
+	StateSetPromoCode:                true,
+	StateSetKart:                     true,
+	StateSetRentalStock:              true,
+	StateSetCarCategoryName:          true,
+	StateSelectAvailabilityTrack:     true,
+	StateSelectSessionTrack:          true,
+	StateSetParticipantName:          true,
+}
+
+// IsDialogState проверяет, является ли состояние шагом диалога
+func IsDialogState(stateType StateType) bool {
+	_, ok := dialogMenus[stateType]
+	return ok
+}
+
+// DialogMenu возвращает callback меню, в которое возвращает отмена диалога
+func DialogMenu(stateType StateType) string {
+	if menu, ok := dialogMenus[stateType]; ok {
+		return menu
+	}
+	return "start"
+}
+
+// Navigate переносит стек навигации из предыдущего состояния в новое.
+// Переход внутри одного диалога кладет предыдущий шаг на стек,
+// выход из диалога стек очищает.
+func Navigate(prev, next State) State {
+	if !IsDialogState(next.Type) {
+		next.History = nil
+		return next
+	}
+
+	if next.Type == prev.Type {
+		next.History = prev.History
+		return next
+	}
+
+	if dialogEntries[next.Type] || !IsDialogState(prev.Type) || DialogMenu(prev.Type) != DialogMenu(next.Type) {
+		next.History = nil
+		return next
+	}
+
+	history := make([]State, len(prev.History), len(prev.History)+1)
+	copy(history, prev.History)
+	next.History = append(history, State{Type: prev.Type, Data: prev.Data})
+	return next
+}
+
+// Back возвращает предыдущий шаг диалога с сохраненными данными
+func (s State) Back() (State, bool) {
+	if len(s.History) == 0 {
+		return State{}, false
+	}
+
+	prev := s.History[len(s.History)-1]
+	prev.History = s.History[:len(s.History)-1]
+	return prev, true
 }
 
 type TempTrainerData struct {
@@ -76,13 +353,27 @@ type TempUserData struct {
 	DataConsent bool
 }
 
+// TempParticipantData - данные нового профиля участника
+type TempParticipantData struct {
+	Name      string
+	BirthDate time.Time
+}
+
 type TempTrainingData struct {
 	TrainerID       uint
 	TrackID         uint
 	StartTime       string
 	EndTime         string
 	MaxParticipants int
-	CarCategory     string
+	CarCategoryID   *uint // nil - без категории
+	// Повторение: дни недели (0 - воскресенье) и ограничение серии
+	Recurring   bool
+	Weekdays    []int
+	RepeatUntil string
+	RepeatCount int
+	// Копирование: время начала и длительность из исходной тренировки или шаблона
+	TimeOfDay       string
+	DurationMinutes int
 }
 
 type TempRegistrationData struct {
@@ -90,6 +381,25 @@ type TempRegistrationData struct {
 	TrainerID uint
 }
 
+// TempPromoCodeData - промокод, который создает администратор
+type TempPromoCodeData struct {
+	Code          string
+	DiscountType  string
+	DiscountValue int
+	ValidFrom     string // ГГГГ-ММ-ДД, пусто - без ограничения
+	ValidUntil    string
+	MaxUses       int
+	TrackID       uint // 0 - любая трасса
+}
+
+// TempCarCategoryData - категория машин, которую создает или редактирует администратор
+type TempCarCategoryData struct {
+	ID          uint // 0 - новая категория
+	Name        string
+	Description string
+	MinAge      int
+}
+
 func NewState(stateType StateType, data map[string]interface{}) State {
 	if data == nil {
 		data = make(map[string]interface{})
@@ -181,6 +491,30 @@ func (s State) SetTempRegistrationData(data *TempRegistrationData) State {
 	return s
 }
 
+func (s State) GetTempPromoCodeData() *TempPromoCodeData {
+	if data, ok := s.Data["tempPromoCode"].(*TempPromoCodeData); ok {
+		return data
+	}
+	return &TempPromoCodeData{}
+}
+
+func (s State) SetTempPromoCodeData(data *TempPromoCodeData) State {
+	s.Data["tempPromoCode"] = data
+	return s
+}
+
+func (s State) GetTempCarCategoryData() *TempCarCategoryData {
+	if data, ok := s.Data["tempCarCategory"].(*TempCarCategoryData); ok {
+		return data
+	}
+	return &TempCarCategoryData{}
+}
+
+func (s State) SetTempCarCategoryData(data *TempCarCategoryData) State {
+	s.Data["tempCarCategory"] = data
+	return s
+}
+
 func SetStart() State {
 	return NewState(StateStart, nil)
 }
@@ -301,10 +635,58 @@ func SetConfirmTrainingCreation() State {
 	return NewState(StateConfirmTrainingCreation, nil)
 }
 
+// SetSetTrackClosure - ввод дня закрытия трассы trackId
+func SetSetTrackClosure(trackId uint) State {
+	return NewState(StateSetTrackClosure, map[string]interface{}{"id": trackId})
+}
+
+// SetEditTrackTimezone - ввод часового пояса трассы
+func SetEditTrackTimezone(trackId uint) State {
+	return NewState(StateEditTrackTimezone, map[string]interface{}{"id": trackId})
+}
+
+// SetSetUserTimezone - выбор или ввод часового пояса пользователя
+func SetSetUserTimezone() State {
+	return NewState(StateSetUserTimezone, nil)
+}
+
+// SetSetTrainingCloneDate - ввод даты копии тренировки или занятия из шаблона
+func SetSetTrainingCloneDate() State {
+	return NewState(StateSetTrainingCloneDate, nil)
+}
+
+// SetSetTemplateName - ввод названия шаблона из тренировки trainingId
+func SetSetTemplateName(trainingId uint) State {
+	return NewState(StateSetTemplateName, map[string]interface{}{"id": trainingId})
+}
+
+func SetConfirmTemplateDelete(templateId uint) State {
+	return NewState(StateConfirmTemplateDelete, map[string]interface{}{"id": templateId})
+}
+
 func SetConfirmTrainingRegistration(trainingId uint) State {
 	return NewState(StateConfirmTrainingRegistration, map[string]interface{}{"trainingId": trainingId})
 }
 
+// SetSelectRegistrationParticipant - выбор, за кого записаться на тренировку
+func SetSelectRegistrationParticipant(trainingId uint) State {
+	return NewState(StateSelectRegistrationParticipant, map[string]interface{}{"trainingId": trainingId})
+}
+
+// GetParticipantID возвращает профиль участника, за которого идет запись; 0 - запись за себя
+func (s State) GetParticipantID() uint {
+	if id, ok := s.Data["participantId"].(uint); ok {
+		return id
+	}
+	return 0
+}
+
+// WithParticipant сохраняет профиль участника, за которого идет запись
+func (s State) WithParticipant(participantId uint) State {
+	s.Data["participantId"] = participantId
+	return s
+}
+
 func SetConfirmTrainingDelete(trainingId uint) State {
 	return NewState(StateConfirmTrainingDelete, map[string]interface{}{"id": trainingId})
 }
@@ -313,6 +695,45 @@ func SetEditTrainingCarCategory(trainingId uint) State {
 	return NewState(StateEditTrainingCarCategory, map[string]interface{}{"id": trainingId})
 }
 
+func SetEditTrainingMaxParticipants(trainingId uint) State {
+	return NewState(StateEditTrainingMaxParticipants, map[string]interface{}{"id": trainingId})
+}
+
+func SetEditTrainingSkillLevel(trainingId uint) State {
+	return NewState(StateEditTrainingSkillLevel, map[string]interface{}{"id": trainingId})
+}
+
+func SetSetTrainingRecurrence() State {
+	return NewState(StateSetTrainingRecurrence, nil)
+}
+
+func SetSetTrainingWeekdays() State {
+	return NewState(StateSetTrainingWeekdays, nil)
+}
+
+func SetSetTrainingRecurrenceEnd() State {
+	return NewState(StateSetTrainingRecurrenceEnd, nil)
+}
+
+// SetSelectSeriesScope - выбор занятий серии, к которым применить действие action
+func SetSelectSeriesScope(trainingId uint, action string) State {
+	return NewState(StateSelectSeriesScope, map[string]interface{}{"id": trainingId, "action": action})
+}
+
+// GetSeriesScope возвращает выбранную область изменений серии
+func (s State) GetSeriesScope() string {
+	if scope, ok := s.Data["scope"].(string); ok {
+		return scope
+	}
+	return ""
+}
+
+// WithSeriesScope сохраняет область изменений серии в состоянии
+func (s State) WithSeriesScope(scope string) State {
+	s.Data["scope"] = scope
+	return s
+}
+
 func SetSelectTrackForRegistration() State {
 	return NewState(StateSelectTrackForRegistration, nil)
 }
@@ -328,3 +749,209 @@ func SetSelectTrainingTimeForRegistration() State {
 func SetSuggestTraining() State {
 	return NewState(StateSuggestTraining, nil)
 }
+
+// SetEnterLapTimes - ввод времени кругов участника тренировки
+func SetEnterLapTimes(registrationId uint) State {
+	return NewState(StateEnterLapTimes, map[string]interface{}{"id": registrationId})
+}
+
+// SetImportLapFile - загрузка CSV-файла логгера с кругами тренировки
+func SetImportLapFile(trainingId uint) State {
+	return NewState(StateImportLapFile, map[string]interface{}{"id": trainingId})
+}
+
+// SetEnterHeatResults - ввод порядка финиша заезда на тренировке
+func SetEnterHeatResults(trainingId uint) State {
+	return NewState(StateEnterHeatResults, map[string]interface{}{"id": trainingId})
+}
+
+// SetFeedbackStrengths - разбор тренировки: что получилось у участника
+func SetFeedbackStrengths(registrationId uint) State {
+	return NewState(StateSetFeedbackStrengths, map[string]interface{}{"id": registrationId})
+}
+
+// SetFeedbackImprovements - разбор тренировки: над чем работать
+func SetFeedbackImprovements(registrationId uint) State {
+	return NewState(StateSetFeedbackImprovements, map[string]interface{}{"id": registrationId})
+}
+
+// SetFeedbackVideo - разбор тренировки: ссылка на видеоразбор
+func SetFeedbackVideo(registrationId uint) State {
+	return NewState(StateSetFeedbackVideo, map[string]interface{}{"id": registrationId})
+}
+
+// SetSurveyComment - комментарий к опросу после тренировки
+func SetSurveyComment(surveyId uint) State {
+	return NewState(StateSetSurveyComment, map[string]interface{}{"id": surveyId})
+}
+
+// SetPackageName - название нового пакета тренировок
+func SetPackageName() State {
+	return NewState(StateSetPackageName, nil)
+}
+
+// SetPackageCredits - количество тренировок в новом пакете
+func SetPackageCredits(name string) State {
+	return NewState(StateSetPackageCredits, map[string]interface{}{"name": name})
+}
+
+// SetGrantCredits - начисление кредитов пользователю без пакета
+func SetGrantCredits(userId uint) State {
+	return NewState(StateGrantCredits, map[string]interface{}{"id": userId})
+}
+
+// SetUserCredits - просмотр кредитов пользователя администратором
+func SetUserCredits(userId uint) State {
+	return NewState(StateUserCredits, map[string]interface{}{"id": userId})
+}
+
+// SetEditTrainingPrice - собственная цена тренировки
+func SetEditTrainingPrice(trainingId uint) State {
+	return NewState(StateEditTrainingPrice, map[string]interface{}{"id": trainingId})
+}
+
+// SetCategoryPrice - цена категории машин
+func SetCategoryPrice() State {
+	return NewState(StateSetCategoryPrice, nil)
+}
+
+// SetPromoCode - код нового промокода
+func SetPromoCode() State {
+	return NewState(StateSetPromoCode, nil)
+}
+
+// SetPromoDiscount - скидка нового промокода
+func SetPromoDiscount() State {
+	return NewState(StateSetPromoDiscount, nil)
+}
+
+// SetPromoValidity - срок действия нового промокода
+func SetPromoValidity() State {
+	return NewState(StateSetPromoValidity, nil)
+}
+
+// SetPromoMaxUses - лимит использований нового промокода
+func SetPromoMaxUses() State {
+	return NewState(StateSetPromoMaxUses, nil)
+}
+
+// SetPromoTrack - трасса, на которой действует новый промокод
+func SetPromoTrack() State {
+	return NewState(StateSetPromoTrack, nil)
+}
+
+// SetPromoCarCategory - категория машин, для которой действует новый промокод
+func SetPromoCarCategory() State {
+	return NewState(StateSetPromoCarCategory, nil)
+}
+
+// SetEnterPromoCode - ввод промокода при подтверждении записи на тренировку
+func SetEnterPromoCode(trainingId uint) State {
+	return NewState(StateEnterPromoCode, map[string]interface{}{"trainingId": trainingId})
+}
+
+// GetPromoCodeID возвращает промокод, выбранный при подтверждении записи
+func (s State) GetPromoCodeID() uint {
+	if id, ok := s.Data["promoCodeId"].(uint); ok {
+		return id
+	}
+	return 0
+}
+
+// WithPromoCode сохраняет промокод, выбранный при подтверждении записи
+func (s State) WithPromoCode(promoCodeId uint) State {
+	s.Data["promoCodeId"] = promoCodeId
+	return s
+}
+
+// SetSetKart - добавление карта в парк трассы
+func SetSetKart(trackId uint) State {
+	return NewState(StateSetKart, map[string]interface{}{"id": trackId})
+}
+
+// SetAssignKart - выбор карта для записи на тренировку
+func SetAssignKart(registrationId uint) State {
+	return NewState(StateAssignKart, map[string]interface{}{"id": registrationId})
+}
+
+// SetSetRentalStock - ввод остатка экипировки на складе трассы
+func SetSetRentalStock(trackId uint) State {
+	return NewState(StateSetRentalStock, map[string]interface{}{"id": trackId})
+}
+
+// SetSelectRental - выбор экипировки к записи на тренировку
+func SetSelectRental(registrationId uint) State {
+	return NewState(StateSelectRental, map[string]interface{}{"id": registrationId})
+}
+
+// SetCarCategoryName - название новой категории машин
+func SetCarCategoryName() State {
+	return NewState(StateSetCarCategoryName, nil)
+}
+
+// SetCarCategoryDescription - описание категории машин
+func SetCarCategoryDescription() State {
+	return NewState(StateSetCarCategoryDescription, nil)
+}
+
+// SetCarCategoryMinAge - минимальный возраст пилота категории
+func SetCarCategoryMinAge() State {
+	return NewState(StateSetCarCategoryMinAge, nil)
+}
+
+// SetCarCategoryLicense - лицензия, которая нужна для категории
+func SetCarCategoryLicense() State {
+	return NewState(StateSetCarCategoryLicense, nil)
+}
+
+// SetSelectAvailabilityTrack - тренер выбирает трассу нового окна
+func SetSelectAvailabilityTrack() State {
+	return NewState(StateSelectAvailabilityTrack, nil)
+}
+
+// SetSetAvailability - тренер вводит дату и время окна на трассе
+func SetSetAvailability(trackId uint) State {
+	return NewState(StateSetAvailability, map[string]interface{}{"id": trackId})
+}
+
+// SetSelectSessionTrack - выбор трассы для индивидуального занятия
+func SetSelectSessionTrack() State {
+	return NewState(StateSelectSessionTrack, nil)
+}
+
+// SetSelectSessionWindow - выбор окна тренера на трассе
+func SetSelectSessionWindow(trackId uint) State {
+	return NewState(StateSelectSessionWindow, map[string]interface{}{"id": trackId})
+}
+
+// SetSetSessionSlot - ввод предлагаемого времени занятия в окне
+func SetSetSessionSlot(windowId uint) State {
+	return NewState(StateSetSessionSlot, map[string]interface{}{"id": windowId})
+}
+
+// SetSetParticipantName - ввод имени нового участника
+func SetSetParticipantName() State {
+	return NewState(StateSetParticipantName, nil)
+}
+
+// SetSetParticipantBirthDate - ввод даты рождения нового участника
+func SetSetParticipantBirthDate() State {
+	return NewState(StateSetParticipantBirthDate, nil)
+}
+
+// SetConfirmParticipantConsent - согласие представителя перед сохранением участника
+func SetConfirmParticipantConsent() State {
+	return NewState(StateConfirmParticipantConsent, nil)
+}
+
+func (s State) GetTempParticipantData() *TempParticipantData {
+	if data, ok := s.Data["tempParticipant"].(*TempParticipantData); ok {
+		return data
+	}
+	return &TempParticipantData{}
+}
+
+func (s State) SetTempParticipantData(data *TempParticipantData) State {
+	s.Data["tempParticipant"] = data
+	return s
+}
@@ -0,0 +1,75 @@
+package telegram
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"x.localhost/rvabot/internal/errors"
+	"x.localhost/rvabot/internal/logger"
+)
+
+// GetFile запрашивает путь к файлу на серверах Telegram по его file_id
+func GetFile(botUrl string, fileId string) (*File, error) {
+	resp, err := makeHTTPRequest("GET", botUrl+"/getFile?file_id="+url.QueryEscape(fileId), nil)
+	if err != nil {
+		logger.TelegramError("Получение файла: %v", err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var response fileResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		appErr := errors.NewTelegramError("Ошибка парсинга JSON", err)
+		logger.TelegramError("Разбор ответа getFile: %v", appErr)
+		return nil, appErr
+	}
+
+	if !response.Ok || response.Result.FilePath == "" {
+		logger.TelegramError("getFile (код %d): %s", resp.StatusCode, response.Description)
+		return nil, errors.NewTelegramError("Файл недоступен для скачивания", nil).WithCode(fmt.Sprintf("HTTP_%d", resp.StatusCode))
+	}
+
+	logger.TelegramInfo("Получение файла успешно: %d байт", response.Result.FileSize)
+	return &response.Result, nil
+}
+
+// DownloadFile скачивает файл, полученный через GetFile, не больше maxSize байт
+func DownloadFile(botUrl string, file *File, maxSize int64) ([]byte, error) {
+	if file.FileSize > maxSize {
+		return nil, errors.NewValidationError("Файл слишком большой", fmt.Sprintf("размер файла превышает %d байт", maxSize))
+	}
+
+	resp, err := makeHTTPRequest("GET", fileURL(botUrl, file.FilePath), nil)
+	if err != nil {
+		logger.TelegramError("Скачивание файла: %v", err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		logger.TelegramError("Скачивание файла (код %d)", resp.StatusCode)
+		return nil, errors.NewTelegramError("Ошибка скачивания файла", nil).WithCode(fmt.Sprintf("HTTP_%d", resp.StatusCode))
+	}
+
+	// Размер из getFile может отсутствовать, поэтому ограничиваем и само чтение
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, errors.NewNetworkError("Ошибка чтения файла", err)
+	}
+	if int64(len(data)) > maxSize {
+		return nil, errors.NewValidationError("Файл слишком большой", fmt.Sprintf("размер файла превышает %d байт", maxSize))
+	}
+
+	return data, nil
+}
+
+// fileURL строит адрес скачивания: файлы отдаются по пути /file/bot<token>/<file_path>
+func fileURL(botUrl string, filePath string) string {
+	if i := strings.LastIndex(botUrl, "/bot"); i != -1 {
+		return botUrl[:i] + "/file" + botUrl[i:] + "/" + filePath
+	}
+	return botUrl + "/" + filePath
+}
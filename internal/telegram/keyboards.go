@@ -2,8 +2,10 @@ package telegram
 
 import (
 	"fmt"
+	"time"
 
 	"x.localhost/rvabot/internal/database"
+	"x.localhost/rvabot/internal/timefmt"
 )
 
 // createBackButton создает кнопку "Назад"
@@ -22,6 +24,14 @@ func createCancelButton() inlineKeyboardButton {
 	}
 }
 
+// createStepBackButton создает кнопку возврата на предыдущий шаг диалога
+func createStepBackButton() inlineKeyboardButton {
+	return inlineKeyboardButton{
+		Text:         "🔙 Назад",
+		CallbackData: "back",
+	}
+}
+
 // createConfirmButton создает кнопку "Подтвердить"
 func createConfirmButton() inlineKeyboardButton {
 	return inlineKeyboardButton{
@@ -61,6 +71,16 @@ func createConfirmationKeyboard() inlineKeyboardMarkup {
 	return inlineKeyboardMarkup{
 		InlineKeyboard: [][]inlineKeyboardButton{
 			{createConfirmButton(), createCancelButton()},
+			{createStepBackButton()},
+		},
+	}
+}
+
+// createStepKeyboard создает клавиатуру шага диалога: назад по стеку и отмена
+func createStepKeyboard() inlineKeyboardMarkup {
+	return inlineKeyboardMarkup{
+		InlineKeyboard: [][]inlineKeyboardButton{
+			{createStepBackButton(), createCancelButton()},
 		},
 	}
 }
@@ -108,11 +128,44 @@ func CreateCancelKeyboard() inlineKeyboardMarkup {
 	return createKeyboardWithCancel()
 }
 
+func CreateStepKeyboard() inlineKeyboardMarkup {
+	return createStepKeyboard()
+}
+
+// CreateBackToMenuKeyboard создает клавиатуру возврата в меню по его callback
+func CreateBackToMenuKeyboard(menu string) inlineKeyboardMarkup {
+	if menu == "start" {
+		return CreateBaseKeyboard()
+	}
+	return createKeyboardWithBack(menu)
+}
+
 func CreateStartKeyboard(chatId int, repo database.ContentRepositoryInterface) inlineKeyboardMarkup {
 	keyboard := [][]inlineKeyboardButton{
 		{
 			{Text: "🏃‍♂️ Записаться на тренировку", CallbackData: "BookTraining"},
 		},
+		{
+			{Text: "🎯 Индивидуальное занятие", CallbackData: "individualSession"},
+		},
+		{
+			{Text: "📋 Мои записи", CallbackData: "myBookings"},
+		},
+		{
+			{Text: "📈 Мои результаты", CallbackData: "myResults"},
+		},
+		{
+			{Text: "📝 Разборы тренировок", CallbackData: "myFeedback"},
+		},
+		{
+			{Text: "💳 Мои кредиты", CallbackData: "myCredits"},
+		},
+		{
+			{Text: "👨‍👩‍👧 Мои участники", CallbackData: "myParticipants"},
+		},
+		{
+			{Text: "🕒 Часовой пояс", CallbackData: "userTimezone"},
+		},
 		{
 			{Text: "💡 Предложить тренировку", CallbackData: "suggestTraining"},
 		},
@@ -124,6 +177,12 @@ func CreateStartKeyboard(chatId int, repo database.ContentRepositoryInterface) i
 		},
 	}
 
+	if trainer, _ := repo.GetTrainerByChatId(chatId); trainer != nil {
+		keyboard = append(keyboard, []inlineKeyboardButton{
+			{Text: "🗓 Окна для занятий", CallbackData: "myAvailability"},
+		})
+	}
+
 	// Проверяем, является ли пользователь администратором
 	if database.IsAdmin(chatId, repo) {
 		keyboard = append(keyboard, []inlineKeyboardButton{
@@ -147,6 +206,17 @@ func CreateAdminKeyboard() inlineKeyboardMarkup {
 			{
 				{Text: "💬 Запросы тренировок", CallbackData: "trainingRequests"},
 			},
+			{
+				{Text: "⭐ Оценки тренировок", CallbackData: "surveyReport"},
+			},
+			{
+				{Text: "🎟 Пакеты и кредиты", CallbackData: "packages"},
+				{Text: "💸 Неоплаченные", CallbackData: "outstandingPayments"},
+			},
+			{
+				{Text: "🏷 Промокоды", CallbackData: "promoCodes"},
+				{Text: "🚗 Категории машин", CallbackData: "carCategories"},
+			},
 			{
 				{Text: "🏠 Главное меню", CallbackData: "start"},
 			},
@@ -201,6 +271,10 @@ func CreateTracksListWithActionsKeyboard(tracks []database.Track) inlineKeyboard
 		// Кнопки действий в отдельной строке
 		buttons = append(buttons, []inlineKeyboardButton{
 			{Text: "📄", CallbackData: fmt.Sprintf("editTrackInfo_%d", track.ID)},
+			{Text: "🚧", CallbackData: fmt.Sprintf("trackClosures_%d", track.ID)},
+			{Text: "🕒", CallbackData: fmt.Sprintf("editTrackTimezone_%d", track.ID)},
+			{Text: "🏎", CallbackData: fmt.Sprintf("trackKarts_%d", track.ID)},
+			{Text: "🪖", CallbackData: fmt.Sprintf("trackRentals_%d", track.ID)},
 			{Text: "🗑️", CallbackData: fmt.Sprintf("deleteTrack_%d", track.ID)},
 		})
 	}
@@ -213,12 +287,17 @@ func CreateTracksListWithActionsKeyboard(tracks []database.Track) inlineKeyboard
 	return inlineKeyboardMarkup{InlineKeyboard: buttons}
 }
 
-func CreateTrainingsListWithActionsKeyboard(trainings []database.Training) inlineKeyboardMarkup {
+// CreateTrainingsListWithActionsKeyboard - список тренировок для администратора,
+// время занятия выводится в поясе, который loc возвращает для трассы, а
+// категория - названием, которое возвращает category
+func CreateTrainingsListWithActionsKeyboard(trainings []database.Training, loc func(trackId uint) *time.Location, category func(id *uint) string) inlineKeyboardMarkup {
 	var buttons [][]inlineKeyboardButton
 
 	// Добавляем кнопку "Добавить тренировку" в начале
 	buttons = append(buttons, []inlineKeyboardButton{
 		{Text: "➕ Добавить тренировку", CallbackData: "createSchedule"},
+		{Text: "📑 Шаблоны", CallbackData: "trainingTemplates"},
+		{Text: "💰 Цены", CallbackData: "categoryPrices"},
 	})
 
 	// Добавляем кнопки для каждой тренировки
@@ -228,7 +307,7 @@ func CreateTrainingsListWithActionsKeyboard(trainings []database.Training) inlin
 			statusIcon = "🔴"
 		}
 		buttons = append(buttons, []inlineKeyboardButton{
-			{Text: fmt.Sprintf("%d. %s %s (%s)", i+1, statusIcon, training.StartTime.Format("02.01 15:04"), training.CarCategory), CallbackData: fmt.Sprintf("editTraining_%d", training.ID)},
+			{Text: fmt.Sprintf("%d. %s %s (%s)", i+1, statusIcon, timefmt.Short(training.StartTime, loc(training.TrackID)), category(training.CarCategoryID)), CallbackData: fmt.Sprintf("editTraining_%d", training.ID)},
 		})
 		// Кнопки действий в отдельной строке
 		buttons = append(buttons, []inlineKeyboardButton{
@@ -284,6 +363,33 @@ func CreateDataConsentKeyboard() inlineKeyboardMarkup {
 	}
 }
 
+// CreateParticipantsKeyboard - участники под аккаунтом пользователя
+func CreateParticipantsKeyboard(participants []database.Participant) inlineKeyboardMarkup {
+	var buttons [][]inlineKeyboardButton
+	if len(participants) < database.MaxParticipantsPerUser {
+		buttons = append(buttons, []inlineKeyboardButton{{Text: "➕ Добавить участника", CallbackData: "addParticipant"}})
+	}
+
+	for _, participant := range participants {
+		buttons = append(buttons, []inlineKeyboardButton{
+			{Text: "🗑️ " + participant.Name, CallbackData: fmt.Sprintf("deleteParticipant_%d", participant.ID)},
+		})
+	}
+
+	buttons = append(buttons, []inlineKeyboardButton{createHomeButton()})
+	return inlineKeyboardMarkup{InlineKeyboard: buttons}
+}
+
+// CreateGuardianConsentKeyboard - согласие представителя участника
+func CreateGuardianConsentKeyboard() inlineKeyboardMarkup {
+	return inlineKeyboardMarkup{
+		InlineKeyboard: [][]inlineKeyboardButton{
+			{{Text: "✅ Даю согласие", CallbackData: "guardianConsent"}},
+			{createStepBackButton(), createCancelButton()},
+		},
+	}
+}
+
 func CreateTrainerEditKeyboard(trainerId uint) inlineKeyboardMarkup {
 	return inlineKeyboardMarkup{
 		InlineKeyboard: [][]inlineKeyboardButton{
@@ -323,6 +429,40 @@ func CreateTrainingDeletionConfirmationKeyboard(trainingId uint) inlineKeyboardM
 	}
 }
 
+// CreateTrainingTemplatesKeyboard - список шаблонов: создание занятия по шаблону и удаление
+func CreateTrainingTemplatesKeyboard(templates []database.TrainingTemplate) inlineKeyboardMarkup {
+	var buttons [][]inlineKeyboardButton
+
+	for _, t := range templates {
+		buttons = append(buttons, []inlineKeyboardButton{
+			{Text: "📋 " + t.Name, CallbackData: fmt.Sprintf("useTrainingTemplate_%d", t.ID)},
+			{Text: "🗑️", CallbackData: fmt.Sprintf("deleteTrainingTemplate_%d", t.ID)},
+		})
+	}
+
+	buttons = append(buttons, []inlineKeyboardButton{
+		{Text: "🔙 Назад к расписанию", CallbackData: "scheduleMenu"},
+	})
+
+	return inlineKeyboardMarkup{InlineKeyboard: buttons}
+}
+
+func CreateTemplateDeletionConfirmationKeyboard(templateId uint) inlineKeyboardMarkup {
+	return inlineKeyboardMarkup{
+		InlineKeyboard: [][]inlineKeyboardButton{
+			{
+				{Text: "🗑️ Удалить", CallbackData: fmt.Sprintf("confirmDeleteTrainingTemplate_%d", templateId)},
+				{Text: "❌ Отменить", CallbackData: "trainingTemplates"},
+			},
+		},
+	}
+}
+
+// CreateBackToTemplatesKeyboard возвращает к списку шаблонов
+func CreateBackToTemplatesKeyboard() inlineKeyboardMarkup {
+	return createKeyboardWithBack("trainingTemplates")
+}
+
 func CreateTrackEditKeyboard(trackId uint) inlineKeyboardMarkup {
 	return inlineKeyboardMarkup{
 		InlineKeyboard: [][]inlineKeyboardButton{
@@ -337,6 +477,194 @@ func CreateTrackEditKeyboard(trackId uint) inlineKeyboardMarkup {
 	}
 }
 
+// CreateTrackClosuresKeyboard - дни закрытия трассы: добавление и удаление
+func CreateTrackClosuresKeyboard(trackId uint, closures []database.TrackClosure) inlineKeyboardMarkup {
+	var buttons [][]inlineKeyboardButton
+
+	buttons = append(buttons, []inlineKeyboardButton{
+		{Text: "➕ Добавить день закрытия", CallbackData: fmt.Sprintf("addTrackClosure_%d", trackId)},
+	})
+
+	for _, closure := range closures {
+		buttons = append(buttons, []inlineKeyboardButton{
+			{Text: "🗑️ " + timefmt.CalendarDate(closure.Date), CallbackData: fmt.Sprintf("deleteTrackClosure_%d", closure.ID)},
+		})
+	}
+
+	buttons = append(buttons, []inlineKeyboardButton{
+		{Text: "🔙 Назад к трассам", CallbackData: "tracksMenu"},
+	})
+
+	return inlineKeyboardMarkup{InlineKeyboard: buttons}
+}
+
+// CreateCarCategoriesKeyboard - справочник категорий машин: добавление, требования и удаление
+func CreateCarCategoriesKeyboard(categories []database.CarCategory) inlineKeyboardMarkup {
+	buttons := [][]inlineKeyboardButton{
+		{{Text: "➕ Добавить категорию", CallbackData: "createCarCategory"}},
+	}
+
+	for _, category := range categories {
+		buttons = append(buttons, []inlineKeyboardButton{
+			{Text: "✏️ " + category.Name, CallbackData: fmt.Sprintf("editCarCategory_%d", category.ID)},
+			{Text: "🗑️", CallbackData: fmt.Sprintf("deleteCarCategory_%d", category.ID)},
+		})
+	}
+
+	buttons = append(buttons, []inlineKeyboardButton{createBackButton("admin")})
+	return inlineKeyboardMarkup{InlineKeyboard: buttons}
+}
+
+// CreateTrainingCarCategoryKeyboard - выбор категории машин на шаге создания тренировки
+func CreateTrainingCarCategoryKeyboard(categories []database.CarCategory) inlineKeyboardMarkup {
+	buttons := carCategoryButtons(categories, "trainingCategory", "➖ Без категории")
+	buttons = append(buttons, []inlineKeyboardButton{createStepBackButton(), createCancelButton()})
+	return inlineKeyboardMarkup{InlineKeyboard: buttons}
+}
+
+// CreateEditTrainingCarCategoryKeyboard - выбор новой категории машин тренировки
+func CreateEditTrainingCarCategoryKeyboard(categories []database.CarCategory) inlineKeyboardMarkup {
+	buttons := carCategoryButtons(categories, "editCategory", "➖ Без категории")
+	buttons = append(buttons, []inlineKeyboardButton{createBackButton("scheduleMenu")})
+	return inlineKeyboardMarkup{InlineKeyboard: buttons}
+}
+
+// carCategoryButtons - категории справочника по три в ряд и вариант без
+// категории с ID 0 в первой строке
+func carCategoryButtons(categories []database.CarCategory, prefix string, noneText string) [][]inlineKeyboardButton {
+	buttons := [][]inlineKeyboardButton{
+		{{Text: noneText, CallbackData: prefix + "_0"}},
+	}
+
+	var row []inlineKeyboardButton
+	for _, category := range categories {
+		row = append(row, inlineKeyboardButton{Text: "🚗 " + category.Name, CallbackData: fmt.Sprintf("%s_%d", prefix, category.ID)})
+		if len(row) == 3 {
+			buttons = append(buttons, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		buttons = append(buttons, row)
+	}
+
+	return buttons
+}
+
+// CreateTrackKartsKeyboard - парк картов трассы: добавление, ремонт и списание
+func CreateTrackKartsKeyboard(trackId uint, karts []database.Kart) inlineKeyboardMarkup {
+	var buttons [][]inlineKeyboardButton
+
+	buttons = append(buttons, []inlineKeyboardButton{
+		{Text: "➕ Добавить карт", CallbackData: fmt.Sprintf("addKart_%d", trackId)},
+	})
+
+	for _, kart := range karts {
+		toggle := "🔧 В ремонт №" + kart.Number
+		if kart.Status == database.KartStatusMaintenance {
+			toggle = "✅ В строй №" + kart.Number
+		}
+		buttons = append(buttons, []inlineKeyboardButton{
+			{Text: toggle, CallbackData: fmt.Sprintf("toggleKart_%d", kart.ID)},
+			{Text: "🗑️", CallbackData: fmt.Sprintf("deleteKart_%d", kart.ID)},
+		})
+	}
+
+	buttons = append(buttons, []inlineKeyboardButton{
+		{Text: "🔙 Назад к трассам", CallbackData: "tracksMenu"},
+	})
+
+	return inlineKeyboardMarkup{InlineKeyboard: buttons}
+}
+
+func CreateBackToTrackKartsKeyboard(trackId uint) inlineKeyboardMarkup {
+	return createKeyboardWithBack(fmt.Sprintf("trackKarts_%d", trackId))
+}
+
+// CreateTrackRentalStockKeyboard - склад экипировки трассы
+func CreateTrackRentalStockKeyboard(trackId uint) inlineKeyboardMarkup {
+	return inlineKeyboardMarkup{
+		InlineKeyboard: [][]inlineKeyboardButton{
+			{{Text: "✏️ Изменить остаток", CallbackData: fmt.Sprintf("setRentalStock_%d", trackId)}},
+			{{Text: "🔙 Назад к трассам", CallbackData: "tracksMenu"}},
+		},
+	}
+}
+
+// CreateRentalItemsKeyboard - размеры экипировки для записи; выбранные отмечены галочкой.
+// Повторное нажатие на выбранный размер отменяет заказ.
+func CreateRentalItemsKeyboard(items []database.RentalAvailability, selected map[uint]bool, kindNames map[string]string) inlineKeyboardMarkup {
+	var buttons [][]inlineKeyboardButton
+
+	var row []inlineKeyboardButton
+	for i, item := range items {
+		if i > 0 && item.Kind != items[i-1].Kind && len(row) > 0 {
+			buttons = append(buttons, row)
+			row = nil
+		}
+
+		text := kindNames[item.Kind] + " " + item.Size
+		switch {
+		case selected[item.ID]:
+			text = "✅ " + text
+		case item.Available == 0:
+			text += " (нет)"
+		}
+		row = append(row, inlineKeyboardButton{Text: text, CallbackData: fmt.Sprintf("rentItem_%d", item.ID)})
+		if len(row) == 3 {
+			buttons = append(buttons, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		buttons = append(buttons, row)
+	}
+
+	buttons = append(buttons, []inlineKeyboardButton{createBackButton("myBookings")})
+	return inlineKeyboardMarkup{InlineKeyboard: buttons}
+}
+
+// CreateRegistrationRentalKeyboard - после записи предлагает взять экипировку в прокат
+func CreateRegistrationRentalKeyboard(registrationId uint) inlineKeyboardMarkup {
+	return inlineKeyboardMarkup{
+		InlineKeyboard: [][]inlineKeyboardButton{
+			{{Text: "🪖 Прокат экипировки", CallbackData: fmt.Sprintf("rentals_%d", registrationId)}},
+			{createHomeButton()},
+		},
+	}
+}
+
+// CreateUserTimezoneKeyboard - выбор часового пояса пользователя из распространенных
+func CreateUserTimezoneKeyboard(current string) inlineKeyboardMarkup {
+	var buttons [][]inlineKeyboardButton
+
+	for i := 0; i < len(timefmt.CommonZones); i += 2 {
+		var row []inlineKeyboardButton
+		for j := i; j < i+2 && j < len(timefmt.CommonZones); j++ {
+			text := timefmt.CommonZones[j]
+			if text == current {
+				text = "✅ " + text
+			}
+			row = append(row, inlineKeyboardButton{Text: text, CallbackData: fmt.Sprintf("setUserTimezone_%d", j)})
+		}
+		buttons = append(buttons, row)
+	}
+
+	if current != "" {
+		buttons = append(buttons, []inlineKeyboardButton{
+			{Text: "🏁 По времени трассы", CallbackData: "resetUserTimezone"},
+		})
+	}
+	buttons = append(buttons, []inlineKeyboardButton{createBackButton("start")})
+
+	return inlineKeyboardMarkup{InlineKeyboard: buttons}
+}
+
+// CreateBackToTrackClosuresKeyboard возвращает к дням закрытия трассы
+func CreateBackToTrackClosuresKeyboard(trackId uint) inlineKeyboardMarkup {
+	return createKeyboardWithBack(fmt.Sprintf("trackClosures_%d", trackId))
+}
+
 func CreateTrackDeletionConfirmationKeyboard(trackId uint) inlineKeyboardMarkup {
 	return inlineKeyboardMarkup{
 		InlineKeyboard: [][]inlineKeyboardButton{
@@ -355,10 +683,326 @@ func CreateTrainingRegistrationConfirmationKeyboard(trainingId uint) inlineKeybo
 				{Text: "✅ Записаться", CallbackData: fmt.Sprintf("confirmTrainingRegistration_%d", trainingId)},
 				{Text: "❌ Отменить", CallbackData: "cancel"},
 			},
+			{{Text: "🎟 Ввести промокод", CallbackData: fmt.Sprintf("enterPromo_%d", trainingId)}},
+			{createStepBackButton()},
+		},
+	}
+}
+
+// CreateRegistrationParticipantKeyboard - выбор, за кого записаться: за себя
+// или за одного из участников под аккаунтом
+func CreateRegistrationParticipantKeyboard(userName string, participants []database.Participant) inlineKeyboardMarkup {
+	buttons := [][]inlineKeyboardButton{
+		{{Text: "👤 " + userName + " (я)", CallbackData: "bookFor_0"}},
+	}
+	for _, participant := range participants {
+		buttons = append(buttons, []inlineKeyboardButton{
+			{Text: "🧒 " + participant.Name, CallbackData: fmt.Sprintf("bookFor_%d", participant.ID)},
+		})
+	}
+
+	buttons = append(buttons, []inlineKeyboardButton{createStepBackButton(), createCancelButton()})
+	return inlineKeyboardMarkup{InlineKeyboard: buttons}
+}
+
+// CreateWaitlistJoinKeyboard предлагает встать в лист ожидания заполненной тренировки
+func CreateWaitlistJoinKeyboard(trainingId uint) inlineKeyboardMarkup {
+	return inlineKeyboardMarkup{
+		InlineKeyboard: [][]inlineKeyboardButton{
+			{{Text: "📝 Встать в лист ожидания", CallbackData: fmt.Sprintf("joinWaitlist_%d", trainingId)}},
+			{createHomeButton()},
+		},
+	}
+}
+
+// CreateWaitlistOfferKeyboard - ответ на предложенное из листа ожидания место
+func CreateWaitlistOfferKeyboard(registrationId uint) inlineKeyboardMarkup {
+	return inlineKeyboardMarkup{
+		InlineKeyboard: [][]inlineKeyboardButton{
+			{
+				{Text: "✅ Занять место", CallbackData: fmt.Sprintf("acceptOffer_%d", registrationId)},
+				{Text: "❌ Отказаться", CallbackData: fmt.Sprintf("declineOffer_%d", registrationId)},
+			},
+		},
+	}
+}
+
+// CreateAttendanceChecklistKeyboard - отметка посещаемости: имя участника
+// с текущей отметкой, открывающее ввод времени кругов, и повышение уровня
+// пришедшего участника, если по этой тренировке он еще не повышен; кнопки
+// "был" / "не пришел" / "разбор" под ним, затем ввод результатов заезда и загрузка
+// CSV логгера. Кнопка возврата добавляется, если задан back; иначе (у тренера)
+// - переход к отметке оплат.
+func CreateAttendanceChecklistKeyboard(trainingId uint, registrations []database.TrainingRegistration, names map[uint]string, promoted map[uint]bool, back string) inlineKeyboardMarkup {
+	var buttons [][]inlineKeyboardButton
+
+	for _, reg := range registrations {
+		icon := "❔"
+		switch reg.Status {
+		case database.RegistrationStatusAttended:
+			icon = "✅"
+		case database.RegistrationStatusNoShow:
+			icon = "🚫"
+		}
+
+		nameRow := []inlineKeyboardButton{
+			{Text: icon + " " + names[reg.ID] + " ⏱", CallbackData: fmt.Sprintf("enterLaps_%d", reg.ID)},
+		}
+		if reg.Status == database.RegistrationStatusAttended && !promoted[reg.ID] {
+			nameRow = append(nameRow, inlineKeyboardButton{Text: "🎓 Повысить", CallbackData: fmt.Sprintf("promoteUser_%d", reg.ID)})
+		}
+		buttons = append(buttons, nameRow)
+		buttons = append(buttons, []inlineKeyboardButton{
+			{Text: "✅ Был", CallbackData: fmt.Sprintf("markAttended_%d", reg.ID)},
+			{Text: "🚫 Не пришел", CallbackData: fmt.Sprintf("markNoShow_%d", reg.ID)},
+			{Text: "📝 Разбор", CallbackData: fmt.Sprintf("writeFeedback_%d", reg.ID)},
+		})
+	}
+
+	buttons = append(buttons, []inlineKeyboardButton{
+		{Text: "🏆 Результаты заезда", CallbackData: fmt.Sprintf("heatResults_%d", trainingId)},
+		{Text: "📥 Загрузить CSV логгера", CallbackData: fmt.Sprintf("importLaps_%d", trainingId)},
+	})
+
+	if back != "" {
+		buttons = append(buttons, []inlineKeyboardButton{createBackButton(back)})
+	} else {
+		buttons = append(buttons, []inlineKeyboardButton{
+			{Text: "💰 Оплаты", CallbackData: fmt.Sprintf("viewRegistrations_%d", trainingId)},
+		})
+	}
+
+	return inlineKeyboardMarkup{InlineKeyboard: buttons}
+}
+
+// CreateMyResultsKeyboard - трассы с результатами пользователя для просмотра
+// прогресса и таблицы рейтинга
+func CreateMyResultsKeyboard(tracks []database.Track) inlineKeyboardMarkup {
+	var buttons [][]inlineKeyboardButton
+
+	for _, track := range tracks {
+		buttons = append(buttons, []inlineKeyboardButton{
+			{Text: "📊 " + track.Name, CallbackData: fmt.Sprintf("trackResults_%d", track.ID)},
+		})
+	}
+
+	buttons = append(buttons, []inlineKeyboardButton{{Text: "🏆 Таблицы рейтинга", CallbackData: "ratingCategories"}})
+	buttons = append(buttons, []inlineKeyboardButton{createBackButton("start")})
+
+	return inlineKeyboardMarkup{InlineKeyboard: buttons}
+}
+
+// CreateRatingCategoriesKeyboard - категории карта с таблицами рейтинга
+func CreateRatingCategoriesKeyboard(categories []database.RatingCategory) inlineKeyboardMarkup {
+	var buttons [][]inlineKeyboardButton
+
+	for _, category := range categories {
+		buttons = append(buttons, []inlineKeyboardButton{
+			{Text: fmt.Sprintf("🚗 %s (%d)", category.CarCategory, category.Players), CallbackData: fmt.Sprintf("ratingLeaderboard_%d", category.RatingID)},
+		})
+	}
+
+	buttons = append(buttons, []inlineKeyboardButton{createBackButton("myResults")})
+
+	return inlineKeyboardMarkup{InlineKeyboard: buttons}
+}
+
+// CreateMyFeedbackKeyboard - разборы тренировок пользователя с подписями из labels
+func CreateMyFeedbackKeyboard(feedback []database.TrainingFeedback, labels map[uint]string) inlineKeyboardMarkup {
+	var buttons [][]inlineKeyboardButton
+
+	for _, f := range feedback {
+		buttons = append(buttons, []inlineKeyboardButton{
+			{Text: "📝 " + labels[f.ID], CallbackData: fmt.Sprintf("viewFeedback_%d", f.ID)},
+		})
+	}
+
+	buttons = append(buttons, []inlineKeyboardButton{createBackButton("start")})
+
+	return inlineKeyboardMarkup{InlineKeyboard: buttons}
+}
+
+// CreateSurveyRatingKeyboard - оценка тренировки от 1 до 5
+func CreateSurveyRatingKeyboard(surveyId uint) inlineKeyboardMarkup {
+	var row []inlineKeyboardButton
+	for rating := 1; rating <= database.MaxSurveyRating; rating++ {
+		row = append(row, inlineKeyboardButton{
+			Text:         fmt.Sprintf("%d ⭐", rating),
+			CallbackData: fmt.Sprintf("rateSurvey%d_%d", rating, surveyId),
+		})
+	}
+
+	return inlineKeyboardMarkup{InlineKeyboard: [][]inlineKeyboardButton{row}}
+}
+
+// CreateSurveyRatedKeyboard - комментарий к оценке тренировки
+func CreateSurveyRatedKeyboard(surveyId uint) inlineKeyboardMarkup {
+	return inlineKeyboardMarkup{
+		InlineKeyboard: [][]inlineKeyboardButton{
+			{{Text: "💬 Оставить комментарий", CallbackData: fmt.Sprintf("surveyComment_%d", surveyId)}},
+			{createHomeButton()},
 		},
 	}
 }
 
+// CreateHeatSavedKeyboard - ввод следующего заезда или возврат к отметке посещаемости
+func CreateHeatSavedKeyboard(trainingId uint) inlineKeyboardMarkup {
+	return inlineKeyboardMarkup{
+		InlineKeyboard: [][]inlineKeyboardButton{
+			{{Text: "➕ Следующий заезд", CallbackData: fmt.Sprintf("heatResults_%d", trainingId)}},
+			{createBackButton(fmt.Sprintf("attendanceChecklist_%d", trainingId))},
+		},
+	}
+}
+
+// CreateTrainingRegistrationsKeyboard - участники тренировки с отметкой оплаты;
+// администратору - со ссылкой на историю посещений
+func CreateTrainingRegistrationsKeyboard(trainingId uint, registrations []database.TrainingRegistration, names map[uint]string, isAdmin bool, back string) inlineKeyboardMarkup {
+	var buttons [][]inlineKeyboardButton
+
+	for _, reg := range registrations {
+		if isAdmin {
+			buttons = append(buttons, []inlineKeyboardButton{
+				{Text: "👤 " + names[reg.ID], CallbackData: fmt.Sprintf("userAttendance_%d", reg.UserID)},
+			})
+		}
+
+		switch {
+		case reg.PaymentStatus == database.PaymentStatusPaid:
+			buttons = append(buttons, []inlineKeyboardButton{
+				{Text: "↩️ Возврат: " + names[reg.ID], CallbackData: fmt.Sprintf("refundPayment_%d", reg.ID)},
+			})
+		case database.IsPayableStatus(reg.Status):
+			buttons = append(buttons, []inlineKeyboardButton{
+				{Text: "💵 " + names[reg.ID], CallbackData: fmt.Sprintf("payCash_%d", reg.ID)},
+				{Text: "🏦 Перевод", CallbackData: fmt.Sprintf("payTransfer_%d", reg.ID)},
+			})
+		}
+	}
+
+	buttons = append(buttons, []inlineKeyboardButton{
+		{Text: "🏎 Карты", CallbackData: fmt.Sprintf("kartAssignments_%d", trainingId)},
+		{Text: "🪖 Экипировка", CallbackData: fmt.Sprintf("rentalPickList_%d", trainingId)},
+	})
+
+	// Тренер возвращается к посещаемости кнопкой "Назад"
+	if isAdmin {
+		buttons = append(buttons, []inlineKeyboardButton{
+			{Text: "📝 Посещаемость", CallbackData: fmt.Sprintf("attendanceChecklist_%d", trainingId)},
+		})
+	}
+	buttons = append(buttons, []inlineKeyboardButton{createBackButton(back)})
+
+	return inlineKeyboardMarkup{InlineKeyboard: buttons}
+}
+
+// CreateKartAssignmentsKeyboard - подтвержденные участники с назначенными картами
+func CreateKartAssignmentsKeyboard(trainingId uint, registrations []database.TrainingRegistration, names map[uint]string, kartNumbers map[uint]string) inlineKeyboardMarkup {
+	var buttons [][]inlineKeyboardButton
+
+	for _, reg := range registrations {
+		kart := "—"
+		if reg.KartID != nil {
+			kart = "списан"
+			if number, ok := kartNumbers[*reg.KartID]; ok {
+				kart = "№" + number
+			}
+		}
+		buttons = append(buttons, []inlineKeyboardButton{
+			{Text: fmt.Sprintf("🏎 %s: %s", names[reg.ID], kart), CallbackData: fmt.Sprintf("assignKart_%d", reg.ID)},
+		})
+	}
+
+	buttons = append(buttons, []inlineKeyboardButton{createBackButton(fmt.Sprintf("viewRegistrations_%d", trainingId))})
+	return inlineKeyboardMarkup{InlineKeyboard: buttons}
+}
+
+// CreateKartSelectionKeyboard - свободные карты для участника; снять назначение
+// можно, если карт уже назначен
+func CreateKartSelectionKeyboard(trainingId uint, karts []database.Kart, assigned bool) inlineKeyboardMarkup {
+	var buttons [][]inlineKeyboardButton
+
+	var row []inlineKeyboardButton
+	for _, kart := range karts {
+		row = append(row, inlineKeyboardButton{Text: "№" + kart.Number, CallbackData: fmt.Sprintf("setKart_%d", kart.ID)})
+		if len(row) == 4 {
+			buttons = append(buttons, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		buttons = append(buttons, row)
+	}
+
+	if assigned {
+		buttons = append(buttons, []inlineKeyboardButton{{Text: "🚫 Снять карт", CallbackData: "setKart_0"}})
+	}
+	buttons = append(buttons, []inlineKeyboardButton{createBackButton(fmt.Sprintf("kartAssignments_%d", trainingId))})
+
+	return inlineKeyboardMarkup{InlineKeyboard: buttons}
+}
+
+// CreateUserAttendanceKeyboard - из истории посещений к кредитам пользователя
+func CreateUserAttendanceKeyboard(userId uint) inlineKeyboardMarkup {
+	return inlineKeyboardMarkup{
+		InlineKeyboard: [][]inlineKeyboardButton{
+			{{Text: "💳 Кредиты", CallbackData: fmt.Sprintf("userCredits_%d", userId)}},
+			{createBackButton("scheduleMenu")},
+		},
+	}
+}
+
+// CreatePackagesKeyboard - пакеты тренировок: создание, архивирование и балансы участников
+func CreatePackagesKeyboard(packages []database.Package) inlineKeyboardMarkup {
+	buttons := [][]inlineKeyboardButton{
+		{{Text: "➕ Новый пакет", CallbackData: "createPackage"}},
+	}
+
+	for _, pkg := range packages {
+		buttons = append(buttons, []inlineKeyboardButton{
+			{Text: fmt.Sprintf("🗄 В архив: %s", pkg.Name), CallbackData: fmt.Sprintf("archivePackage_%d", pkg.ID)},
+		})
+	}
+
+	buttons = append(buttons, []inlineKeyboardButton{{Text: "👥 Балансы участников", CallbackData: "creditBalances"}})
+	buttons = append(buttons, []inlineKeyboardButton{createBackButton("admin")})
+
+	return inlineKeyboardMarkup{InlineKeyboard: buttons}
+}
+
+// CreateCreditBalancesKeyboard - участники со ссылкой на их кредиты
+func CreateCreditBalancesKeyboard(balances []database.UserCreditBalance) inlineKeyboardMarkup {
+	var buttons [][]inlineKeyboardButton
+
+	for _, b := range balances {
+		buttons = append(buttons, []inlineKeyboardButton{
+			{Text: fmt.Sprintf("👤 %s — %d", b.Name, b.Balance), CallbackData: fmt.Sprintf("userCredits_%d", b.UserID)},
+		})
+	}
+
+	buttons = append(buttons, []inlineKeyboardButton{createBackButton("packages")})
+
+	return inlineKeyboardMarkup{InlineKeyboard: buttons}
+}
+
+// CreateUserCreditsKeyboard - продажа пакетов и начисление кредитов пользователю
+func CreateUserCreditsKeyboard(userId uint, packages []database.Package) inlineKeyboardMarkup {
+	var buttons [][]inlineKeyboardButton
+
+	for _, pkg := range packages {
+		buttons = append(buttons, []inlineKeyboardButton{
+			{Text: fmt.Sprintf("🎟 Продать: %s (%d)", pkg.Name, pkg.Credits), CallbackData: fmt.Sprintf("sellPackage_%d", pkg.ID)},
+		})
+	}
+
+	buttons = append(buttons, []inlineKeyboardButton{
+		{Text: "🎁 Начислить кредиты", CallbackData: fmt.Sprintf("grantCredits_%d", userId)},
+	})
+	buttons = append(buttons, []inlineKeyboardButton{createBackButton("creditBalances")})
+
+	return inlineKeyboardMarkup{InlineKeyboard: buttons}
+}
+
 func CreateTrainingApprovalKeyboard(registrationId uint) inlineKeyboardMarkup {
 	return inlineKeyboardMarkup{
 		InlineKeyboard: [][]inlineKeyboardButton{
@@ -380,10 +1024,7 @@ func CreateTrainerSelectionForTrainingKeyboard(trainers []database.Trainer) inli
 		}})
 	}
 
-	buttons = append(buttons, []inlineKeyboardButton{
-		{Text: "🔙 Назад к расписанию", CallbackData: "scheduleMenu"},
-		{Text: "❌ Отменить", CallbackData: "cancel"},
-	})
+	buttons = append(buttons, []inlineKeyboardButton{createStepBackButton(), createCancelButton()})
 
 	return inlineKeyboardMarkup{InlineKeyboard: buttons}
 }
@@ -398,14 +1039,90 @@ func CreateTrackSelectionForTrainingKeyboard(tracks []database.Track) inlineKeyb
 		}})
 	}
 
-	buttons = append(buttons, []inlineKeyboardButton{
-		{Text: "🔙 Назад к расписанию", CallbackData: "scheduleMenu"},
-		{Text: "❌ Отменить", CallbackData: "cancel"},
-	})
+	buttons = append(buttons, []inlineKeyboardButton{createStepBackButton(), createCancelButton()})
 
 	return inlineKeyboardMarkup{InlineKeyboard: buttons}
 }
 
+// CreateTrainingRecurrenceKeyboard - выбор однократной или еженедельной тренировки
+func CreateTrainingRecurrenceKeyboard() inlineKeyboardMarkup {
+	return inlineKeyboardMarkup{
+		InlineKeyboard: [][]inlineKeyboardButton{
+			{
+				{Text: "1️⃣ Однократно", CallbackData: "recurrenceOnce"},
+				{Text: "🔁 Еженедельно", CallbackData: "recurrenceWeekly"},
+			},
+			{createStepBackButton(), createCancelButton()},
+		},
+	}
+}
+
+// weekdayButtons - дни недели в порядке с понедельника; значение - time.Weekday
+var weekdayButtons = []struct {
+	Day   int
+	Label string
+}{
+	{1, "Пн"}, {2, "Вт"}, {3, "Ср"}, {4, "Чт"}, {5, "Пт"}, {6, "Сб"}, {0, "Вс"},
+}
+
+// CreateWeekdaysKeyboard - переключатели дней недели для серии тренировок
+func CreateWeekdaysKeyboard(selected []int) inlineKeyboardMarkup {
+	isSelected := make(map[int]bool)
+	for _, d := range selected {
+		isSelected[d] = true
+	}
+
+	var row []inlineKeyboardButton
+	for _, wd := range weekdayButtons {
+		text := wd.Label
+		if isSelected[wd.Day] {
+			text = "✅" + wd.Label
+		}
+		row = append(row, inlineKeyboardButton{Text: text, CallbackData: fmt.Sprintf("toggleWeekday_%d", wd.Day)})
+	}
+
+	return inlineKeyboardMarkup{
+		InlineKeyboard: [][]inlineKeyboardButton{
+			row[:4],
+			row[4:],
+			{{Text: "➡️ Далее", CallbackData: "weekdaysDone"}},
+			{createStepBackButton(), createCancelButton()},
+		},
+	}
+}
+
+// CreateSeriesScopeKeyboard - к каким занятиям серии применить действие
+func CreateSeriesScopeKeyboard() inlineKeyboardMarkup {
+	return inlineKeyboardMarkup{
+		InlineKeyboard: [][]inlineKeyboardButton{
+			{{Text: "1️⃣ Только это занятие", CallbackData: "seriesScopeOne"}},
+			{{Text: "⏭ Это и следующие", CallbackData: "seriesScopeFollowing"}},
+			{{Text: "🔁 Все занятия серии", CallbackData: "seriesScopeAll"}},
+			{createCancelButton()},
+		},
+	}
+}
+
+// CreateEditTrainingSkillLevelKeyboard - варианты требований к уровню участников.
+// Код варианта - минимальный уровень * 10 + максимальный, 0 - без ограничения.
+func CreateEditTrainingSkillLevelKeyboard() inlineKeyboardMarkup {
+	return inlineKeyboardMarkup{
+		InlineKeyboard: [][]inlineKeyboardButton{
+			{{Text: "🌐 Любой уровень", CallbackData: "editSkillLevel_0"}},
+			{
+				{Text: "🟢 Только новички", CallbackData: "editSkillLevel_11"},
+				{Text: "🟢🟡 Новички и средний", CallbackData: "editSkillLevel_12"},
+			},
+			{
+				{Text: "🟡 Только средний", CallbackData: "editSkillLevel_22"},
+				{Text: "🟡🔴 Средний и выше", CallbackData: "editSkillLevel_20"},
+			},
+			{{Text: "🔴 Только продвинутые", CallbackData: "editSkillLevel_30"}},
+			{createBackButton("scheduleMenu")},
+		},
+	}
+}
+
 func CreateTrainingEditKeyboard(trainingId uint) inlineKeyboardMarkup {
 	return inlineKeyboardMarkup{
 		InlineKeyboard: [][]inlineKeyboardButton{
@@ -415,6 +1132,14 @@ func CreateTrainingEditKeyboard(trainingId uint) inlineKeyboardMarkup {
 			},
 			{
 				{Text: "🚗 Категория", CallbackData: fmt.Sprintf("editTrainingCategory_%d", trainingId)},
+				{Text: "💰 Цена", CallbackData: fmt.Sprintf("editTrainingPrice_%d", trainingId)},
+			},
+			{
+				{Text: "🎓 Уровень участников", CallbackData: fmt.Sprintf("editTrainingSkillLevel_%d", trainingId)},
+			},
+			{
+				{Text: "📋 Дублировать", CallbackData: fmt.Sprintf("duplicateTraining_%d", trainingId)},
+				{Text: "💾 В шаблоны", CallbackData: fmt.Sprintf("saveTrainingTemplate_%d", trainingId)},
 			},
 			{
 				{Text: "🔄 Активировать/Деактивировать", CallbackData: fmt.Sprintf("toggleTrainingStatus_%d", trainingId)},
@@ -456,30 +1181,70 @@ func CreateTrainerSelectionForRegistrationKeyboard(trainers []database.Trainer)
 		}})
 	}
 
-	buttons = append(buttons, []inlineKeyboardButton{
-		{Text: "🔙 Назад к выбору трассы", CallbackData: "backToTrackSelection"},
-	})
+	buttons = append(buttons, []inlineKeyboardButton{createStepBackButton(), createCancelButton()})
 
 	return inlineKeyboardMarkup{InlineKeyboard: buttons}
 }
 
-func CreateTrainingTimeSelectionKeyboard(trainings []database.Training) inlineKeyboardMarkup {
+// CreateTrainingTimeSelectionKeyboard - выбор времени тренировки при записи,
+// время выводится в поясе, который loc возвращает для трассы, а категория -
+// названием, которое возвращает category
+func CreateTrainingTimeSelectionKeyboard(trainings []database.Training, loc func(trackId uint) *time.Location, category func(id *uint) string) inlineKeyboardMarkup {
 	var buttons [][]inlineKeyboardButton
 
 	for _, t := range trainings {
 		buttons = append(buttons, []inlineKeyboardButton{{
-			Text:         fmt.Sprintf("%s (%s)", t.StartTime.Format("02.01 15:04"), t.CarCategory),
+			Text:         fmt.Sprintf("%s (%s)", timefmt.Short(t.StartTime, loc(t.TrackID)), category(t.CarCategoryID)),
 			CallbackData: fmt.Sprintf("selectTrainingTimeForRegistration_%d", t.ID),
 		}})
 	}
 
-	buttons = append(buttons, []inlineKeyboardButton{
-		{Text: "🔙 Назад к выбору тренера", CallbackData: "backToTrainerSelection"},
-	})
+	buttons = append(buttons, []inlineKeyboardButton{createStepBackButton(), createCancelButton()})
 
 	return inlineKeyboardMarkup{InlineKeyboard: buttons}
 }
 
+// CreateMyBookingsKeyboard - кнопки отмены для каждой записи из списка "Мои записи"
+// и заказа экипировки к записям, ожидающим подтверждения или подтвержденным
+func CreateMyBookingsKeyboard(bookings []database.TrainingRegistration) inlineKeyboardMarkup {
+	var buttons [][]inlineKeyboardButton
+
+	for i, b := range bookings {
+		row := []inlineKeyboardButton{{
+			Text:         fmt.Sprintf("❌ Отменить запись %d", i+1),
+			CallbackData: fmt.Sprintf("cancelBooking_%d", b.ID),
+		}}
+		if database.CanRequestRental(b.Status) {
+			row = append(row, inlineKeyboardButton{Text: "🪖 Прокат", CallbackData: fmt.Sprintf("rentals_%d", b.ID)})
+		}
+		buttons = append(buttons, row)
+	}
+
+	buttons = append(buttons, []inlineKeyboardButton{createHomeButton()})
+
+	return inlineKeyboardMarkup{InlineKeyboard: buttons}
+}
+
+// CreateBookingCancellationKeyboard - подтверждение отмены записи пользователем
+func CreateBookingCancellationKeyboard(registrationId uint) inlineKeyboardMarkup {
+	return inlineKeyboardMarkup{
+		InlineKeyboard: [][]inlineKeyboardButton{
+			{{Text: "✅ Да, отменить", CallbackData: fmt.Sprintf("confirmCancelBooking_%d", registrationId)}},
+			{createBackButton("myBookings")},
+		},
+	}
+}
+
+// CreateUserScheduleKeyboard - расписание пользователя со ссылкой на управление записями
+func CreateUserScheduleKeyboard() inlineKeyboardMarkup {
+	return inlineKeyboardMarkup{
+		InlineKeyboard: [][]inlineKeyboardButton{
+			{{Text: "📋 Мои записи", CallbackData: "myBookings"}},
+			{createBackButton("Info")},
+		},
+	}
+}
+
 func CreateTrainingRequestsKeyboard(requests []database.TrainingRequest) inlineKeyboardMarkup {
 	var buttons [][]inlineKeyboardButton
 
@@ -497,3 +1262,145 @@ func CreateTrainingRequestsKeyboard(requests []database.TrainingRequest) inlineK
 
 	return inlineKeyboardMarkup{InlineKeyboard: buttons}
 }
+
+// CreateCategoryPricesKeyboard - цены категорий машин
+func CreateCategoryPricesKeyboard() inlineKeyboardMarkup {
+	return inlineKeyboardMarkup{
+		InlineKeyboard: [][]inlineKeyboardButton{
+			{{Text: "✏️ Задать цену", CallbackData: "setCategoryPrice"}},
+			{createBackButton("scheduleMenu")},
+		},
+	}
+}
+
+// CreatePromoCodesKeyboard - действующие промокоды с кнопками отключения
+func CreatePromoCodesKeyboard(promos []database.PromoCode) inlineKeyboardMarkup {
+	buttons := [][]inlineKeyboardButton{
+		{{Text: "➕ Новый промокод", CallbackData: "createPromoCode"}},
+	}
+
+	for _, promo := range promos {
+		buttons = append(buttons, []inlineKeyboardButton{
+			{Text: "🚫 Отключить: " + promo.Code, CallbackData: fmt.Sprintf("deactivatePromo_%d", promo.ID)},
+		})
+	}
+
+	buttons = append(buttons, []inlineKeyboardButton{createBackButton("admin")})
+
+	return inlineKeyboardMarkup{InlineKeyboard: buttons}
+}
+
+// CreatePromoCarCategoryKeyboard - выбор категории машин, для которой действует промокод
+func CreatePromoCarCategoryKeyboard(categories []database.CarCategory) inlineKeyboardMarkup {
+	buttons := carCategoryButtons(categories, "promoCategory", "🌐 Любая категория")
+	buttons = append(buttons, []inlineKeyboardButton{createStepBackButton(), createCancelButton()})
+	return inlineKeyboardMarkup{InlineKeyboard: buttons}
+}
+
+// CreatePromoTrackKeyboard - выбор трассы, на которой действует промокод
+func CreatePromoTrackKeyboard(tracks []database.Track) inlineKeyboardMarkup {
+	buttons := [][]inlineKeyboardButton{
+		{{Text: "🌐 Любая трасса", CallbackData: "promoTrack_0"}},
+	}
+
+	for _, t := range tracks {
+		buttons = append(buttons, []inlineKeyboardButton{
+			{Text: t.Name, CallbackData: fmt.Sprintf("promoTrack_%d", t.ID)},
+		})
+	}
+
+	buttons = append(buttons, []inlineKeyboardButton{createStepBackButton(), createCancelButton()})
+
+	return inlineKeyboardMarkup{InlineKeyboard: buttons}
+}
+
+// windowTime выводит интервал окна или занятия, например "15.01 10:00-14:00"
+func windowTime(start, end time.Time, loc *time.Location) string {
+	return timefmt.Short(start, loc) + "-" + timefmt.Clock(end, loc)
+}
+
+// CreateTrainerAvailabilityKeyboard - окна тренера с кнопками удаления
+// и заявки на занятия, ожидающие ответа
+func CreateTrainerAvailabilityKeyboard(windows []database.TrainerAvailability, requests []database.SessionRequest, loc func(trackId uint) *time.Location) inlineKeyboardMarkup {
+	buttons := [][]inlineKeyboardButton{
+		{{Text: "➕ Добавить окно", CallbackData: "addAvailability"}},
+	}
+
+	for _, w := range windows {
+		buttons = append(buttons, []inlineKeyboardButton{
+			{Text: "🗑️ " + windowTime(w.StartTime, w.EndTime, loc(w.TrackID)), CallbackData: fmt.Sprintf("deleteAvailability_%d", w.ID)},
+		})
+	}
+
+	for _, r := range requests {
+		buttons = append(buttons, []inlineKeyboardButton{
+			{Text: "✅ " + windowTime(r.StartTime, r.EndTime, loc(r.TrackID)), CallbackData: fmt.Sprintf("acceptSession_%d", r.ID)},
+			{Text: "❌ Отклонить", CallbackData: fmt.Sprintf("declineSession_%d", r.ID)},
+		})
+	}
+
+	buttons = append(buttons, []inlineKeyboardButton{createHomeButton()})
+
+	return inlineKeyboardMarkup{InlineKeyboard: buttons}
+}
+
+// CreateAvailabilityTrackKeyboard - выбор трассы нового окна тренера
+func CreateAvailabilityTrackKeyboard(tracks []database.Track) inlineKeyboardMarkup {
+	var buttons [][]inlineKeyboardButton
+
+	for _, t := range tracks {
+		buttons = append(buttons, []inlineKeyboardButton{
+			{Text: "🏁 " + t.Name, CallbackData: fmt.Sprintf("availabilityTrack_%d", t.ID)},
+		})
+	}
+
+	buttons = append(buttons, []inlineKeyboardButton{createStepBackButton(), createCancelButton()})
+
+	return inlineKeyboardMarkup{InlineKeyboard: buttons}
+}
+
+func CreateBackToAvailabilityKeyboard() inlineKeyboardMarkup {
+	return createKeyboardWithBack("myAvailability")
+}
+
+// CreateSessionRequestKeyboard - ответ тренера на заявку на индивидуальное занятие
+func CreateSessionRequestKeyboard(requestId uint) inlineKeyboardMarkup {
+	return inlineKeyboardMarkup{
+		InlineKeyboard: [][]inlineKeyboardButton{
+			{
+				{Text: "✅ Принять", CallbackData: fmt.Sprintf("acceptSession_%d", requestId)},
+				{Text: "❌ Отклонить", CallbackData: fmt.Sprintf("declineSession_%d", requestId)},
+			},
+		},
+	}
+}
+
+// CreateSessionTrackKeyboard - трассы, на которых тренеры открыли окна
+func CreateSessionTrackKeyboard(tracks []database.Track) inlineKeyboardMarkup {
+	var buttons [][]inlineKeyboardButton
+
+	for _, t := range tracks {
+		buttons = append(buttons, []inlineKeyboardButton{
+			{Text: "🏁 " + t.Name, CallbackData: fmt.Sprintf("sessionTrack_%d", t.ID)},
+		})
+	}
+
+	buttons = append(buttons, []inlineKeyboardButton{createHomeButton()})
+
+	return inlineKeyboardMarkup{InlineKeyboard: buttons}
+}
+
+// CreateSessionWindowKeyboard - окна тренеров на трассе, время выводится в поясе loc
+func CreateSessionWindowKeyboard(windows []database.TrainerAvailability, trainerNames map[uint]string, loc *time.Location) inlineKeyboardMarkup {
+	var buttons [][]inlineKeyboardButton
+
+	for _, w := range windows {
+		buttons = append(buttons, []inlineKeyboardButton{
+			{Text: fmt.Sprintf("%s, %s", windowTime(w.StartTime, w.EndTime, loc), trainerNames[w.TrainerID]), CallbackData: fmt.Sprintf("sessionWindow_%d", w.ID)},
+		})
+	}
+
+	buttons = append(buttons, []inlineKeyboardButton{createStepBackButton(), createCancelButton()})
+
+	return inlineKeyboardMarkup{InlineKeyboard: buttons}
+}
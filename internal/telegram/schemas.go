@@ -11,10 +11,33 @@ type Update struct {
 }
 
 type Message struct {
-	MessageId int     `json:"message_id"`
-	Chat      Chat    `json:"chat"`
-	Text      string  `json:"text"`
-	Sticker   Sticker `json:"sticker"`
+	MessageId int       `json:"message_id"`
+	Chat      Chat      `json:"chat"`
+	Text      string    `json:"text"`
+	Sticker   Sticker   `json:"sticker"`
+	Document  *Document `json:"document,omitempty"`
+}
+
+// Document - файл, отправленный боту документом
+type Document struct {
+	FileId       string `json:"file_id"`
+	FileUniqueId string `json:"file_unique_id"`
+	FileName     string `json:"file_name"`
+	MimeType     string `json:"mime_type"`
+	FileSize     int64  `json:"file_size"`
+}
+
+// File - описание файла для скачивания, ответ метода getFile
+type File struct {
+	FileId   string `json:"file_id"`
+	FileSize int64  `json:"file_size"`
+	FilePath string `json:"file_path"`
+}
+
+type fileResponse struct {
+	Ok          bool   `json:"ok"`
+	Result      File   `json:"result"`
+	Description string `json:"description"`
 }
 
 type Sticker struct {
@@ -158,6 +158,14 @@ func EditMessage(botUrl string, chatID int, messageID int, text string, keyboard
 	return nil
 }
 
+// SendOrEditMessage редактирует сообщение, если оно известно, иначе отправляет новое
+func SendOrEditMessage(botUrl string, chatID int, messageID int, text string, keyboard inlineKeyboardMarkup) error {
+	if messageID == 0 {
+		return SendMessage(botUrl, chatID, text, keyboard)
+	}
+	return EditMessage(botUrl, chatID, messageID, text, keyboard)
+}
+
 func AnswerCallbackQuery(botUrl string, callbackID string) error {
 	body := map[string]string{"callback_query_id": callbackID}
 	jsonBody, _ := json.Marshal(body)
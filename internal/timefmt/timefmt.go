@@ -0,0 +1,195 @@
+// Package timefmt - единый сервис разбора и вывода времени.
+// В базе время хранится в UTC, ввод и вывод идут в часовом поясе трассы,
+// пользователя или академии по умолчанию.
+package timefmt
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	// База часовых поясов встраивается в бинарник: в alpine-образе ее нет
+	_ "time/tzdata"
+)
+
+// Форматы ввода даты и времени
+const (
+	InputLayout     = "2006-01-02 15:04"
+	DateInputLayout = "2006-01-02"
+	ClockLayout     = "15:04"
+)
+
+// DefaultAcademyTimezone - часовой пояс академии, если ACADEMY_TIMEZONE не задан
+const DefaultAcademyTimezone = "Europe/Moscow"
+
+// CommonZones - часовые пояса, предлагаемые пользователю кнопками
+var CommonZones = []string{
+	"Europe/Kaliningrad",
+	"Europe/Moscow",
+	"Europe/Samara",
+	"Asia/Yekaterinburg",
+	"Asia/Novosibirsk",
+	"Europe/Rome",
+	"Europe/Berlin",
+	"Asia/Dubai",
+}
+
+var (
+	mu      sync.RWMutex
+	academy = mustLoad(DefaultAcademyTimezone)
+)
+
+func mustLoad(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+	return loc
+}
+
+// Configure задает часовой пояс академии по IANA-имени
+func Configure(name string) error {
+	loc, err := LoadLocation(name)
+	if err != nil {
+		return err
+	}
+
+	mu.Lock()
+	academy = loc
+	mu.Unlock()
+	return nil
+}
+
+// Academy возвращает часовой пояс академии
+func Academy() *time.Location {
+	mu.RLock()
+	defer mu.RUnlock()
+	return academy
+}
+
+// LoadLocation загружает часовой пояс по IANA-имени, например Europe/Rome.
+// Пустое имя и Local не принимаются: результат не должен зависеть от сервера.
+func LoadLocation(name string) (*time.Location, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || name == "Local" {
+		return nil, fmt.Errorf("не указан часовой пояс")
+	}
+	return time.LoadLocation(name)
+}
+
+// Resolve возвращает первый корректный часовой пояс из names,
+// например пользователя, затем трассы, иначе пояс академии
+func Resolve(names ...string) *time.Location {
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		if loc, err := LoadLocation(name); err == nil {
+			return loc
+		}
+	}
+	return Academy()
+}
+
+func orAcademy(loc *time.Location) *time.Location {
+	if loc == nil {
+		return Academy()
+	}
+	return loc
+}
+
+// ParseDateTime разбирает ввод "2006-01-02 15:04" как местное время loc и возвращает момент в UTC
+func ParseDateTime(value string, loc *time.Location) (time.Time, error) {
+	t, err := time.ParseInLocation(InputLayout, strings.TrimSpace(value), orAcademy(loc))
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.UTC(), nil
+}
+
+// ParseDate разбирает ввод "2006-01-02" как начало дня в loc и возвращает момент в UTC
+func ParseDate(value string, loc *time.Location) (time.Time, error) {
+	t, err := time.ParseInLocation(DateInputLayout, strings.TrimSpace(value), orAcademy(loc))
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.UTC(), nil
+}
+
+// CalendarDay возвращает календарную дату момента t в loc как полночь UTC.
+// Так хранятся даты без времени, например дни закрытия трасс.
+func CalendarDay(t time.Time, loc *time.Location) time.Time {
+	local := t.In(orAcademy(loc))
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// Today возвращает сегодняшнюю дату в loc как полночь UTC
+func Today(loc *time.Location) time.Time {
+	return CalendarDay(time.Now(), loc)
+}
+
+// Input выводит время в формате ввода, например для повторного редактирования
+func Input(t time.Time, loc *time.Location) string {
+	return t.In(orAcademy(loc)).Format(InputLayout)
+}
+
+// DateTime выводит дату и время, например "15.01.2024 18:00"
+func DateTime(t time.Time, loc *time.Location) string {
+	return t.In(orAcademy(loc)).Format("02.01.2006 15:04") + zoneSuffix(t, loc)
+}
+
+// Short выводит дату без года и время, например "15.01 18:00"
+func Short(t time.Time, loc *time.Location) string {
+	return t.In(orAcademy(loc)).Format("02.01 15:04") + zoneSuffix(t, loc)
+}
+
+// Date выводит дату, например "15.01.2024"
+func Date(t time.Time, loc *time.Location) string {
+	return t.In(orAcademy(loc)).Format("02.01.2006")
+}
+
+// DayMonth выводит дату без года, например "15.01"
+func DayMonth(t time.Time, loc *time.Location) string {
+	return t.In(orAcademy(loc)).Format("02.01")
+}
+
+// Clock выводит только время, например "18:00"
+func Clock(t time.Time, loc *time.Location) string {
+	return t.In(orAcademy(loc)).Format(ClockLayout)
+}
+
+// CalendarDate выводит дату, хранящуюся полночью UTC, например "08.03.2024"
+func CalendarDate(day time.Time) string {
+	return day.UTC().Format("02.01.2006")
+}
+
+// LapTime выводит время круга в миллисекундах, например "1:02.345"
+func LapTime(ms int) string {
+	return fmt.Sprintf("%d:%02d.%03d", ms/60000, ms/1000%60, ms%1000)
+}
+
+// ZoneLabel описывает часовой пояс, например "Europe/Rome (CET)"
+func ZoneLabel(loc *time.Location) string {
+	loc = orAcademy(loc)
+	return loc.String() + " (" + abbreviation(time.Now(), loc) + ")"
+}
+
+// zoneSuffix помечает время не в поясе академии аббревиатурой пояса
+func zoneSuffix(t time.Time, loc *time.Location) string {
+	loc = orAcademy(loc)
+	if loc.String() == Academy().String() {
+		return ""
+	}
+	return " (" + abbreviation(t, loc) + ")"
+}
+
+// abbreviation возвращает аббревиатуру пояса, а для поясов без нее - смещение от UTC
+func abbreviation(t time.Time, loc *time.Location) string {
+	local := t.In(loc)
+	abbr, _ := local.Zone()
+	if abbr == "" || strings.HasPrefix(abbr, "+") || strings.HasPrefix(abbr, "-") {
+		return "UTC" + local.Format("-07:00")
+	}
+	return abbr
+}
@@ -2,10 +2,14 @@ package validation
 
 import (
 	"fmt"
+	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
+
+	"x.localhost/rvabot/internal/timefmt"
 )
 
 // ValidationError представляет ошибку валидации
@@ -264,7 +268,7 @@ func (v *Validator) ValidateTime(timeStr string) *ValidationResult {
 	}
 
 	// Парсим время для дополнительной проверки
-	_, err := time.Parse("15:04", timeStr)
+	_, err := time.Parse(timefmt.ClockLayout, timeStr)
 	if err != nil {
 		result.AddError("time", "неверное время")
 	}
@@ -289,7 +293,7 @@ func (v *Validator) ValidateDateTime(dateTimeStr string) *ValidationResult {
 	}
 
 	// Парсим дату и время для дополнительной проверки
-	_, err := time.Parse("2006-01-02 15:04", dateTimeStr)
+	_, err := time.Parse(timefmt.InputLayout, dateTimeStr)
 	if err != nil {
 		result.AddError("datetime", "неверная дата или время")
 	}
@@ -297,6 +301,108 @@ func (v *Validator) ValidateDateTime(dateTimeStr string) *ValidationResult {
 	return result
 }
 
+// clockRangeRegex - интервал времени в формате HH:MM-HH:MM
+var clockRangeRegex = regexp.MustCompile(`^(\d{1,2}:\d{2})\s*-\s*(\d{1,2}:\d{2})$`)
+
+// ValidateClockRange валидирует интервал времени в пределах суток в формате HH:MM-HH:MM
+// и возвращает время начала и окончания
+func (v *Validator) ValidateClockRange(rangeStr string) (string, string, *ValidationResult) {
+	result := &ValidationResult{IsValid: true}
+
+	match := clockRangeRegex.FindStringSubmatch(strings.TrimSpace(rangeStr))
+	if match == nil {
+		result.AddError("time_range", "неверный формат интервала. Используйте HH:MM-HH:MM")
+		return "", "", result
+	}
+
+	start, end := fmt.Sprintf("%05s", match[1]), fmt.Sprintf("%05s", match[2])
+	for _, clock := range []string{start, end} {
+		if timeResult := v.ValidateTime(clock); !timeResult.IsValid {
+			return "", "", timeResult
+		}
+	}
+	if end <= start {
+		result.AddError("time_range", "время окончания должно быть позже начала")
+	}
+
+	return start, end, result
+}
+
+// Допустимое время круга: короче или длиннее - почти наверняка опечатка
+const (
+	minLapTimeMs = 5 * 1000
+	maxLapTimeMs = 10 * 60 * 1000
+)
+
+// lapTimeRegex - время круга в формате M:SS.mmm
+var lapTimeRegex = regexp.MustCompile(`^(\d{1,2}):([0-5]\d)\.(\d{3})$`)
+
+// ParseLapTime разбирает время круга в формате M:SS.mmm и возвращает его в миллисекундах
+func (v *Validator) ParseLapTime(lapStr string) (int, *ValidationResult) {
+	result := &ValidationResult{IsValid: true}
+
+	// Проверяем обязательность
+	if requiredResult := v.validateRequired(lapStr, "lap_time"); !requiredResult.IsValid {
+		return 0, requiredResult
+	}
+
+	match := lapTimeRegex.FindStringSubmatch(lapStr)
+	if match == nil {
+		result.AddError("lap_time", fmt.Sprintf("неверный формат времени круга %q. Используйте М:СС.ммм", lapStr))
+		return 0, result
+	}
+
+	minutes, _ := strconv.Atoi(match[1])
+	seconds, _ := strconv.Atoi(match[2])
+	millis, _ := strconv.Atoi(match[3])
+	lapTimeMs := (minutes*60+seconds)*1000 + millis
+
+	if rangeResult := v.ValidateLapTimeMs(lapTimeMs); !rangeResult.IsValid {
+		return 0, rangeResult
+	}
+
+	return lapTimeMs, result
+}
+
+// ValidateLapTimeMs проверяет, что время круга в миллисекундах правдоподобно
+func (v *Validator) ValidateLapTimeMs(lapTimeMs int) *ValidationResult {
+	result := &ValidationResult{IsValid: true}
+
+	if lapTimeMs < minLapTimeMs || lapTimeMs > maxLapTimeMs {
+		result.AddError("lap_time", fmt.Sprintf("время круга %s должно быть от %s до %s",
+			timefmt.LapTime(lapTimeMs), timefmt.LapTime(minLapTimeMs), timefmt.LapTime(maxLapTimeMs)))
+	}
+
+	return result
+}
+
+// ValidateFeedbackNote валидирует заметку тренера в разборе тренировки
+func (v *Validator) ValidateFeedbackNote(note string) *ValidationResult {
+	result := &ValidationResult{IsValid: true}
+
+	if requiredResult := v.validateRequired(note, "note"); !requiredResult.IsValid {
+		return requiredResult
+	}
+
+	if utf8.RuneCountInString(note) > 1000 {
+		result.AddError("note", "не должно превышать 1000 символов")
+	}
+
+	return result
+}
+
+// ValidateVideoURL валидирует ссылку на видеоразбор
+func (v *Validator) ValidateVideoURL(videoURL string) *ValidationResult {
+	result := &ValidationResult{IsValid: true}
+
+	parsed, err := url.Parse(strings.TrimSpace(videoURL))
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		result.AddError("video_url", "должна быть ссылкой, начинающейся с http:// или https://")
+	}
+
+	return result
+}
+
 // ValidateMaxParticipants валидирует максимальное количество участников
 func (v *Validator) ValidateMaxParticipants(participantsStr string) *ValidationResult {
 	result := &ValidationResult{IsValid: true}
@@ -323,6 +429,219 @@ func (v *Validator) ValidateMaxParticipants(participantsStr string) *ValidationR
 	return result
 }
 
+// ValidatePackageName валидирует название пакета тренировок
+func (v *Validator) ValidatePackageName(name string) *ValidationResult {
+	result := &ValidationResult{IsValid: true}
+
+	// Проверяем обязательность
+	if requiredResult := v.validateRequired(name, "name"); !requiredResult.IsValid {
+		return requiredResult
+	}
+
+	// Проверяем длину
+	if lengthResult := v.validateStringLength(name, "name", 2, 100); !lengthResult.IsValid {
+		result.IsValid = false
+		result.Errors = append(result.Errors, lengthResult.Errors...)
+	}
+
+	return result
+}
+
+// ValidateCredits валидирует количество кредитов в пакете или начислении
+func (v *Validator) ValidateCredits(creditsStr string) *ValidationResult {
+	result := &ValidationResult{IsValid: true}
+
+	// Проверяем обязательность
+	if requiredResult := v.validateRequired(creditsStr, "credits"); !requiredResult.IsValid {
+		return requiredResult
+	}
+
+	credits, err := strconv.Atoi(creditsStr)
+	if err != nil {
+		result.AddError("credits", "количество тренировок должно быть числом")
+		return result
+	}
+
+	if credits < 1 {
+		result.AddError("credits", "количество тренировок должно быть больше 0")
+	}
+
+	if credits > 100 {
+		result.AddError("credits", "количество тренировок не должно превышать 100")
+	}
+
+	return result
+}
+
+// ValidatePrice валидирует цену тренировки в рублях; 0 означает, что цена не задана
+func (v *Validator) ValidatePrice(priceStr string) *ValidationResult {
+	result := &ValidationResult{IsValid: true}
+
+	// Проверяем обязательность
+	if requiredResult := v.validateRequired(priceStr, "price"); !requiredResult.IsValid {
+		return requiredResult
+	}
+
+	price, err := strconv.Atoi(priceStr)
+	if err != nil {
+		result.AddError("price", "цена должна быть целым числом рублей")
+		return result
+	}
+
+	if price < 0 {
+		result.AddError("price", "цена не может быть отрицательной")
+	}
+
+	if price > 1000000 {
+		result.AddError("price", "цена не должна превышать 1 000 000 ₽")
+	}
+
+	return result
+}
+
+// promoCodeRegex - код промокода после приведения к верхнему регистру
+var promoCodeRegex = regexp.MustCompile(`^[A-Z0-9]{3,20}$`)
+
+// ValidatePromoCode валидирует код промокода: латинские буквы и цифры
+func (v *Validator) ValidatePromoCode(code string) *ValidationResult {
+	result := &ValidationResult{IsValid: true}
+
+	// Проверяем обязательность
+	if requiredResult := v.validateRequired(code, "promo_code"); !requiredResult.IsValid {
+		return requiredResult
+	}
+
+	if !promoCodeRegex.MatchString(code) {
+		result.AddError("promo_code", "код должен состоять из 3-20 латинских букв и цифр")
+	}
+
+	return result
+}
+
+// ParseDiscount разбирает скидку промокода: "15%" - процент, "500" - рубли.
+// Возвращает признак процентной скидки и ее размер.
+func (v *Validator) ParseDiscount(discountStr string) (bool, int, *ValidationResult) {
+	result := &ValidationResult{IsValid: true}
+
+	// Проверяем обязательность
+	if requiredResult := v.validateRequired(discountStr, "discount"); !requiredResult.IsValid {
+		return false, 0, requiredResult
+	}
+
+	valueStr, percent := strings.CutSuffix(discountStr, "%")
+	value, err := strconv.Atoi(strings.TrimSpace(valueStr))
+	if err != nil {
+		result.AddError("discount", "скидка должна быть целым числом процентов или рублей")
+		return false, 0, result
+	}
+
+	switch {
+	case value < 1:
+		result.AddError("discount", "скидка должна быть больше 0")
+	case percent && value > 100:
+		result.AddError("discount", "скидка не может превышать 100%")
+	case !percent && value > 1000000:
+		result.AddError("discount", "скидка не должна превышать 1 000 000 ₽")
+	}
+
+	return percent, value, result
+}
+
+// ValidateCarCategoryName валидирует название категории машин
+func (v *Validator) ValidateCarCategoryName(name string) *ValidationResult {
+	result := &ValidationResult{IsValid: true}
+
+	// Проверяем обязательность
+	if requiredResult := v.validateRequired(name, "car_category"); !requiredResult.IsValid {
+		return requiredResult
+	}
+
+	if lengthResult := v.validateStringLength(name, "car_category", 1, 30); !lengthResult.IsValid {
+		result.IsValid = false
+		result.Errors = append(result.Errors, lengthResult.Errors...)
+	}
+	if strings.EqualFold(name, "N/A") {
+		result.AddError("car_category", "название N/A зарезервировано для тренировок без категории")
+	}
+
+	return result
+}
+
+// ValidateCarCategoryMinAge валидирует минимальный возраст пилота категории
+func (v *Validator) ValidateCarCategoryMinAge(ageStr string) (int, *ValidationResult) {
+	result := &ValidationResult{IsValid: true}
+
+	age, err := strconv.Atoi(ageStr)
+	switch {
+	case err != nil:
+		result.AddError("min_age", "возраст должен быть целым числом")
+	case age < 0 || age > 99:
+		result.AddError("min_age", "возраст должен быть от 0 до 99 лет")
+	}
+
+	return age, result
+}
+
+// ValidateBirthDate валидирует дату рождения участника в формате YYYY-MM-DD
+func (v *Validator) ValidateBirthDate(dateStr string) (time.Time, *ValidationResult) {
+	result := &ValidationResult{IsValid: true}
+
+	birthDate, err := time.Parse(timefmt.DateInputLayout, dateStr)
+	switch {
+	case err != nil:
+		result.AddError("birth_date", "неверный формат даты. Используйте YYYY-MM-DD")
+	case birthDate.After(time.Now()):
+		result.AddError("birth_date", "дата рождения не может быть в будущем")
+	case birthDate.Before(time.Now().AddDate(-100, 0, 0)):
+		result.AddError("birth_date", "проверьте год рождения")
+	}
+
+	return birthDate, result
+}
+
+// kartNumberRegex - бортовой номер карта
+var kartNumberRegex = regexp.MustCompile(`^[A-Za-z0-9-]{1,10}$`)
+
+// ValidateKartNumber валидирует бортовой номер карта
+func (v *Validator) ValidateKartNumber(number string) *ValidationResult {
+	result := &ValidationResult{IsValid: true}
+
+	// Проверяем обязательность
+	if requiredResult := v.validateRequired(number, "kart_number"); !requiredResult.IsValid {
+		return requiredResult
+	}
+
+	if !kartNumberRegex.MatchString(number) {
+		result.AddError("kart_number", "номер должен состоять из 1-10 латинских букв, цифр или дефисов")
+	}
+
+	return result
+}
+
+// rentalSizeRegex - размер прокатной экипировки
+var rentalSizeRegex = regexp.MustCompile(`^[A-Za-z0-9/-]{1,8}$`)
+
+// ValidateRentalStock валидирует размер и число штук экипировки на складе
+func (v *Validator) ValidateRentalStock(size, quantityStr string) (int, *ValidationResult) {
+	result := &ValidationResult{IsValid: true}
+
+	if !rentalSizeRegex.MatchString(size) {
+		result.AddError("rental_size", "размер должен состоять из 1-8 латинских букв, цифр, дефисов или дробей")
+	}
+
+	quantity, err := strconv.Atoi(quantityStr)
+	switch {
+	case err != nil:
+		result.AddError("rental_quantity", "количество должно быть целым числом")
+	case quantity < 0:
+		result.AddError("rental_quantity", "количество не может быть отрицательным")
+	case quantity > 1000:
+		result.AddError("rental_quantity", "количество не должно превышать 1000")
+	}
+
+	return quantity, result
+}
+
 // ValidateID валидирует ID
 func (v *Validator) ValidateID(idStr string) *ValidationResult {
 	result := &ValidationResult{IsValid: true}
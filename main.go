@@ -2,11 +2,15 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"strconv"
 	"time"
 
 	"x.localhost/rvabot/config"
+	"x.localhost/rvabot/internal/commands"
 	"x.localhost/rvabot/internal/database"
 	"x.localhost/rvabot/internal/errors"
 	"x.localhost/rvabot/internal/handler"
@@ -14,8 +18,10 @@ import (
 	"x.localhost/rvabot/internal/metrics"
 	"x.localhost/rvabot/internal/ratelimit"
 	"x.localhost/rvabot/internal/recovery"
+	"x.localhost/rvabot/internal/scheduler"
 	"x.localhost/rvabot/internal/shutdown"
 	"x.localhost/rvabot/internal/state"
+	"x.localhost/rvabot/internal/timefmt"
 
 	"github.com/joho/godotenv"
 )
@@ -27,6 +33,7 @@ type BotService struct {
 	repo            database.ContentRepositoryInterface
 	rateLimiter     *ratelimit.UserRateLimiter
 	stateManager    *state.Manager
+	scheduler       *scheduler.Scheduler
 	shutdownManager *shutdown.Manager
 	server          *http.Server
 }
@@ -60,6 +67,10 @@ func (bs *BotService) Initialize() error {
 	// Инициализируем state manager
 	bs.stateManager = state.NewManager(30*time.Minute, 5*time.Minute)
 
+	// Настраиваем запись на тренировки и фоновые задачи
+	commands.ConfigureBooking(bs.config.Booking)
+	bs.setupScheduler()
+
 	// Запускаем метрики
 	metrics.StartMetricsLogger(5 * time.Minute) // Логируем метрики каждые 5 минут
 
@@ -89,11 +100,29 @@ func (bs *BotService) setupHTTPServer() {
 	}
 }
 
+// setupScheduler регистрирует периодические фоновые задачи
+func (bs *BotService) setupScheduler() {
+	botUrl := bs.config.GetBotURL()
+	bs.scheduler = scheduler.New()
+	bs.scheduler.Register("waitlist_offers", time.Minute, func() {
+		commands.ProcessWaitlistOffers(botUrl, bs.repo)
+	})
+	bs.scheduler.Register("attendance_checklists", time.Minute, func() {
+		commands.ProcessAttendanceChecklists(botUrl, bs.repo)
+	})
+	bs.scheduler.Register("training_surveys", time.Minute, func() {
+		commands.ProcessTrainingSurveys(botUrl, bs.repo)
+	})
+}
+
 // setupShutdownHandlers настраивает обработчики shutdown
 func (bs *BotService) setupShutdownHandlers() {
 	// HTTP сервер
 	bs.shutdownManager.RegisterHandler(&httpShutdownHandler{server: bs.server})
 
+	// Планировщик фоновых задач
+	bs.shutdownManager.RegisterHandler(&schedulerShutdownHandler{scheduler: bs.scheduler})
+
 	// База данных
 	bs.shutdownManager.RegisterHandler(&databaseShutdownHandler{database: bs.database})
 
@@ -124,6 +153,9 @@ func (bs *BotService) Start() error {
 		handler.BotLoopWithComponents(botUrl, bs.repo, bs.rateLimiter, bs.stateManager, nil)
 	})
 
+	// Запускаем фоновые задачи
+	bs.scheduler.Start()
+
 	// Запускаем shutdown manager
 	bs.shutdownManager.Start()
 	logger.BotInfo("Бот запущен и готов к работе. Нажмите Ctrl+C для завершения...")
@@ -147,6 +179,20 @@ func (h *httpShutdownHandler) Shutdown(ctx context.Context) error {
 	return h.server.Shutdown(ctx)
 }
 
+// Scheduler shutdown handler
+type schedulerShutdownHandler struct {
+	scheduler *scheduler.Scheduler
+}
+
+func (h *schedulerShutdownHandler) Name() string {
+	return "scheduler"
+}
+
+func (h *schedulerShutdownHandler) Shutdown(ctx context.Context) error {
+	h.scheduler.Stop()
+	return nil
+}
+
 // Database shutdown handler
 type databaseShutdownHandler struct {
 	database *database.Database
@@ -189,18 +235,79 @@ func (h *rateLimiterShutdownHandler) Shutdown(ctx context.Context) error {
 	return nil
 }
 
+// runMigrateCommand выполняет подкоманду migrate up|down [N]|status
+func runMigrateCommand(cfg *config.Config, args []string) error {
+	db, err := database.Open(cfg.GetDatabaseDSN())
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	action := "up"
+	if len(args) > 0 {
+		action = args[0]
+	}
+
+	runner := db.Migrations()
+	switch action {
+	case "up":
+		return runner.Up()
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("неверное количество шагов отката: %s", args[1])
+			}
+		}
+		return runner.Down(steps)
+	case "status":
+		statuses, err := runner.Status()
+		if err != nil {
+			return err
+		}
+		for _, st := range statuses {
+			applied := "ожидает"
+			if st.Applied {
+				applied = "применена " + st.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%s\t%s\n", st.Version, st.Name, applied)
+		}
+		return nil
+	default:
+		return fmt.Errorf("неизвестная команда migrate %q (ожидается up, down или status)", action)
+	}
+}
+
 func main() {
 	// Загружаем переменные окружения
 	if err := godotenv.Load(); err != nil {
 		logger.Warn("MAIN", "Не удалось загрузить .env файл: %v", err)
 	}
 
+	// Подкоманда migrate работает только с базой данных и не требует токена бота.
+	// Часовой пояс академии нужен миграциям, переносящим время в UTC.
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		cfg := config.LoadDatabase()
+		if err := timefmt.Configure(cfg.Academy.Timezone); err != nil {
+			log.Fatalf("Неверный ACADEMY_TIMEZONE %q: %v", cfg.Academy.Timezone, err)
+		}
+		if err := runMigrateCommand(cfg, os.Args[2:]); err != nil {
+			log.Fatalf("Ошибка миграции: %v", err)
+		}
+		return
+	}
+
 	// Загружаем конфигурацию
 	cfg, err := config.Load()
 	if err != nil {
 		log.Fatalf("Ошибка загрузки конфигурации: %v", err)
 	}
 
+	if err := timefmt.Configure(cfg.Academy.Timezone); err != nil {
+		log.Fatalf("Неверный ACADEMY_TIMEZONE %q: %v", cfg.Academy.Timezone, err)
+	}
+
 	// Валидируем конфигурацию
 	if err := cfg.Validate(); err != nil {
 		log.Fatalf("Ошибка валидации конфигурации: %v", err)